@@ -881,6 +881,10 @@ func (fb *filterBackend) SubscribeReOrgEvent(ch chan<- core.ReOrgRollup) event.S
 	return nullSubscription()
 }
 
+func (fb *filterBackend) SubscribeForkChoiceEvent(ch chan<- core.ForkChoiceEvent) event.Subscription {
+	return nullSubscription()
+}
+
 func (fb *filterBackend) SubscribeMissingExternalBlockEvent(ch chan<- core.MissingExternalBlock) event.Subscription {
 	return nullSubscription()
 }
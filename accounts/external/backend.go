@@ -190,6 +190,22 @@ func (api *ExternalSigner) SignText(account accounts.Account, text []byte) ([]by
 	return signature, nil
 }
 
+// SignTypedData sends EIP-712 typed data to the external signer, which shows
+// the structured data when requesting approval instead of an opaque hash.
+func (api *ExternalSigner) SignTypedData(account accounts.Account, typedData apitypes.TypedData) ([]byte, error) {
+	var signature hexutil.Bytes
+	var signAddress = common.NewMixedcaseAddress(account.Address)
+	if err := api.client.Call(&signature, "account_signTypedData",
+		&signAddress, // Need to use the pointer here, because of how MarshalJSON is defined
+		typedData); err != nil {
+		return nil, err
+	}
+	if signature[64] == 27 || signature[64] == 28 {
+		signature[64] -= 27 // Transform V from Ethereum-legacy to 0/1
+	}
+	return signature, nil
+}
+
 // signTransactionResult represents the signinig result returned by clef.
 type signTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -0,0 +1,92 @@
+package multisig
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/accounts"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+)
+
+// Execution is an assembled call executing a proposal on its wallet.
+type Execution struct {
+	To   common.Address `json:"to"`
+	Data hexutil.Bytes  `json:"input"`
+}
+
+// PrivateMultisigAPI offers the assembly, signing and combination of multisig
+// wallet proposals with the accounts of the node.
+type PrivateMultisigAPI struct {
+	am      *accounts.Manager
+	chainID *big.Int
+}
+
+// NewPrivateMultisigAPI creates the multisig API signing with the accounts of
+// the given manager for the given chain.
+func NewPrivateMultisigAPI(am *accounts.Manager, chainID *big.Int) *PrivateMultisigAPI {
+	return &PrivateMultisigAPI{am: am, chainID: chainID}
+}
+
+// Propose returns an unsigned proposal, defaulting the chain ID to the one of
+// the node and dropping any signatures.
+func (api *PrivateMultisigAPI) Propose(proposal Proposal) *Proposal {
+	if proposal.ChainID == nil {
+		proposal.ChainID = (*hexutil.Big)(new(big.Int).Set(api.chainID))
+	}
+	proposal.Signatures = nil
+	return &proposal
+}
+
+// Hash returns the digest the owners sign for a proposal.
+func (api *PrivateMultisigAPI) Hash(proposal Proposal) common.Hash {
+	return proposal.Hash()
+}
+
+// Sign adds the signature of a local owner account to a proposal. The account
+// is unlocked with the passphrase if given, otherwise it has to be unlocked.
+func (api *PrivateMultisigAPI) Sign(owner common.Address, proposal Proposal, passphrase *string) (*Proposal, error) {
+	if proposal.ChainID == nil {
+		return nil, errors.New("missing chain ID in proposal")
+	}
+	account := accounts.Account{Address: owner}
+	wallet, err := api.am.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	var sig []byte
+	if passphrase != nil {
+		sig, err = wallet.SignDataWithPassphrase(account, *passphrase, accounts.MimetypeTypedData, proposal.preimage())
+	} else {
+		sig, err = wallet.SignData(account, accounts.MimetypeTypedData, proposal.preimage())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := proposal.Verify(); err != nil {
+		return nil, err
+	}
+	signer, err := proposal.AddSignature(sig)
+	if err != nil {
+		return nil, err
+	}
+	if signer != owner {
+		return nil, ErrInvalidSignature
+	}
+	return &proposal, nil
+}
+
+// Combine merges partially signed copies of the same proposal.
+func (api *PrivateMultisigAPI) Combine(proposals []*Proposal) (*Proposal, error) {
+	return Combine(proposals...)
+}
+
+// Assemble returns the call executing a proposal signed by at least threshold
+// owners, ready to be sent from any account (or the designated executor).
+func (api *PrivateMultisigAPI) Assemble(proposal Proposal, threshold hexutil.Uint64) (*Execution, error) {
+	data, err := proposal.Calldata(int(threshold))
+	if err != nil {
+		return nil, err
+	}
+	return &Execution{To: proposal.Wallet, Data: data}, nil
+}
@@ -0,0 +1,243 @@
+// Package multisig implements the offline assembly, partial signing and
+// combination of transactions executed by simple k-of-n multisig contract
+// wallets.
+//
+// The wallets follow the SimpleMultiSig interface: owners sign an EIP-712
+// digest of the transaction off-chain and anyone (or a designated executor)
+// submits the collected signatures in a single execute call. Proposals are
+// plain JSON documents, so they can be moved to air-gapped machines, signed
+// there and merged back once enough owners signed.
+package multisig
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/spruce-solutions/go-quai/accounts/abi"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/common/math"
+	"github.com/spruce-solutions/go-quai/crypto"
+)
+
+// WalletABI is the interface of the multisig wallets the proposals execute on.
+const WalletABI = `[
+	{"type":"function","name":"execute","stateMutability":"nonpayable","outputs":[],"inputs":[
+		{"name":"sigV","type":"uint8[]"},
+		{"name":"sigR","type":"bytes32[]"},
+		{"name":"sigS","type":"bytes32[]"},
+		{"name":"destination","type":"address"},
+		{"name":"value","type":"uint256"},
+		{"name":"data","type":"bytes"},
+		{"name":"executor","type":"address"},
+		{"name":"gasLimit","type":"uint256"}]},
+	{"type":"function","name":"nonce","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]},
+	{"type":"function","name":"threshold","stateMutability":"view","inputs":[],"outputs":[{"name":"","type":"uint256"}]}
+]`
+
+var (
+	// domainTypeHash, nameHash, versionHash and domainSalt make up the EIP-712
+	// domain of the multisig wallets.
+	domainTypeHash = crypto.Keccak256Hash([]byte("EIP712Domain(string name,string version,uint256 chainId,address verifyingContract,bytes32 salt)"))
+	nameHash       = crypto.Keccak256Hash([]byte("Simple MultiSig"))
+	versionHash    = crypto.Keccak256Hash([]byte("1"))
+	domainSalt     = common.HexToHash("0x251543af6a222378665a76fe38dbceae4871a070b7fdaf5c6c30cf758dc33cc0")
+
+	// txTypeHash is the EIP-712 type hash of a multisig transaction.
+	txTypeHash = crypto.Keccak256Hash([]byte("MultiSigTransaction(address destination,uint256 value,bytes data,uint256 nonce,address executor,uint256 gasLimit)"))
+
+	walletABI abi.ABI
+)
+
+var (
+	// ErrProposalMismatch is returned when combining proposals for different
+	// transactions.
+	ErrProposalMismatch = errors.New("proposals sign different transactions")
+
+	// ErrNotEnoughSignatures is returned when assembling a proposal signed by
+	// fewer owners than the wallet threshold.
+	ErrNotEnoughSignatures = errors.New("not enough signatures")
+
+	// ErrInvalidSignature is returned for malformed signatures and signatures
+	// not made by the claimed signer.
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
+func init() {
+	parsed, err := abi.JSON(strings.NewReader(WalletABI))
+	if err != nil {
+		panic(err)
+	}
+	walletABI = parsed
+}
+
+// Signature is an owner signature of a proposal, in the 65 byte [R || S || V]
+// form with V being 27 or 28.
+type Signature struct {
+	Signer    common.Address `json:"signer"`
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// Proposal is a multisig wallet transaction along with the owner signatures
+// collected so far.
+type Proposal struct {
+	ChainID     *hexutil.Big   `json:"chainId"`
+	Wallet      common.Address `json:"wallet"`
+	Destination common.Address `json:"destination"`
+	Value       *hexutil.Big   `json:"value"`
+	Data        hexutil.Bytes  `json:"data"`
+	Nonce       hexutil.Uint64 `json:"nonce"`    // wallet nonce the transaction executes at
+	Executor    common.Address `json:"executor"` // only account allowed to execute, zero for anyone
+	GasLimit    hexutil.Uint64 `json:"gasLimit"` // gas forwarded to the destination
+	Signatures  []Signature    `json:"signatures"`
+}
+
+// Hash returns the EIP-712 digest the owners sign.
+func (p *Proposal) Hash() common.Hash {
+	return crypto.Keccak256Hash(p.preimage())
+}
+
+// preimage returns the encoded EIP-712 message, the digest being its hash.
+func (p *Proposal) preimage() []byte {
+	domain := crypto.Keccak256(
+		domainTypeHash[:],
+		nameHash[:],
+		versionHash[:],
+		math.U256Bytes(bigOrZero(p.ChainID)),
+		common.LeftPadBytes(p.Wallet[:], 32),
+		domainSalt[:],
+	)
+	message := crypto.Keccak256(
+		txTypeHash[:],
+		common.LeftPadBytes(p.Destination[:], 32),
+		math.U256Bytes(bigOrZero(p.Value)),
+		crypto.Keccak256(p.Data),
+		math.U256Bytes(new(big.Int).SetUint64(uint64(p.Nonce))),
+		common.LeftPadBytes(p.Executor[:], 32),
+		math.U256Bytes(new(big.Int).SetUint64(uint64(p.GasLimit))),
+	)
+	return bytes.Join([][]byte{{0x19, 0x01}, domain, message}, nil)
+}
+
+// AddSignature verifies a signature of the proposal and records it, replacing
+// an earlier signature of the same owner. It returns the recovered signer.
+func (p *Proposal) AddSignature(sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("%w: length %d", ErrInvalidSignature, len(sig))
+	}
+	sig = common.CopyBytes(sig)
+	if sig[crypto.RecoveryIDOffset] < 27 {
+		sig[crypto.RecoveryIDOffset] += 27
+	}
+	if v := sig[crypto.RecoveryIDOffset]; v != 27 && v != 28 {
+		return common.Address{}, fmt.Errorf("%w: recovery id %d", ErrInvalidSignature, v)
+	}
+	recoverable := common.CopyBytes(sig)
+	recoverable[crypto.RecoveryIDOffset] -= 27
+
+	pub, err := crypto.SigToPub(p.Hash().Bytes(), recoverable)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	signer := crypto.PubkeyToAddress(*pub)
+	for i := range p.Signatures {
+		if p.Signatures[i].Signer == signer {
+			p.Signatures[i].Signature = sig
+			return signer, nil
+		}
+	}
+	p.Signatures = append(p.Signatures, Signature{Signer: signer, Signature: sig})
+	return signer, nil
+}
+
+// SignWithKey signs the proposal with a private key, as done on air-gapped
+// machines holding raw owner keys.
+func (p *Proposal) SignWithKey(key *ecdsa.PrivateKey) error {
+	sig, err := crypto.Sign(p.Hash().Bytes(), key)
+	if err != nil {
+		return err
+	}
+	_, err = p.AddSignature(sig)
+	return err
+}
+
+// Verify checks that every recorded signature was made by its claimed signer.
+func (p *Proposal) Verify() error {
+	check := &Proposal{ChainID: p.ChainID, Wallet: p.Wallet, Destination: p.Destination, Value: p.Value, Data: p.Data, Nonce: p.Nonce, Executor: p.Executor, GasLimit: p.GasLimit}
+	for _, sig := range p.Signatures {
+		signer, err := check.AddSignature(sig.Signature)
+		if err != nil {
+			return err
+		}
+		if signer != sig.Signer {
+			return fmt.Errorf("%w: signed by %x, claimed %x", ErrInvalidSignature, signer, sig.Signer)
+		}
+	}
+	return nil
+}
+
+// Combine merges the signatures of several copies of the same proposal, each
+// partially signed by different owners.
+func Combine(proposals ...*Proposal) (*Proposal, error) {
+	if len(proposals) == 0 {
+		return nil, errors.New("no proposals to combine")
+	}
+	base := *proposals[0]
+	base.Signatures = nil
+
+	hash := base.Hash()
+	for _, proposal := range proposals {
+		if proposal.Hash() != hash {
+			return nil, ErrProposalMismatch
+		}
+		if err := proposal.Verify(); err != nil {
+			return nil, err
+		}
+		for _, sig := range proposal.Signatures {
+			if _, err := base.AddSignature(sig.Signature); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &base, nil
+}
+
+// Calldata assembles the input of the wallet's execute call from the proposal
+// signatures, failing if fewer than threshold owners signed. The wallet demands
+// the signatures ordered by ascending signer address.
+func (p *Proposal) Calldata(threshold int) ([]byte, error) {
+	if err := p.Verify(); err != nil {
+		return nil, err
+	}
+	if len(p.Signatures) < threshold {
+		return nil, fmt.Errorf("%w: have %d, want %d", ErrNotEnoughSignatures, len(p.Signatures), threshold)
+	}
+	sigs := make([]Signature, len(p.Signatures))
+	copy(sigs, p.Signatures)
+	sort.Slice(sigs, func(i, j int) bool {
+		return bytes.Compare(sigs[i].Signer[:], sigs[j].Signer[:]) < 0
+	})
+	var (
+		sigV = make([]uint8, len(sigs))
+		sigR = make([][32]byte, len(sigs))
+		sigS = make([][32]byte, len(sigs))
+	)
+	for i, sig := range sigs {
+		copy(sigR[i][:], sig.Signature[:32])
+		copy(sigS[i][:], sig.Signature[32:64])
+		sigV[i] = sig.Signature[crypto.RecoveryIDOffset]
+	}
+	return walletABI.Pack("execute", sigV, sigR, sigS, p.Destination, bigOrZero(p.Value), []byte(p.Data), p.Executor, new(big.Int).SetUint64(uint64(p.GasLimit)))
+}
+
+func bigOrZero(b *hexutil.Big) *big.Int {
+	if b == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(b.ToInt())
+}
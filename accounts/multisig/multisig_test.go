@@ -0,0 +1,113 @@
+package multisig
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/crypto"
+)
+
+func testProposal() *Proposal {
+	return &Proposal{
+		ChainID:     (*hexutil.Big)(big.NewInt(9101)),
+		Wallet:      common.HexToAddress("0x1100000000000000000000000000000000000001"),
+		Destination: common.HexToAddress("0x1100000000000000000000000000000000000002"),
+		Value:       (*hexutil.Big)(big.NewInt(1000)),
+		Data:        []byte{0xde, 0xad},
+		Nonce:       3,
+		GasLimit:    100000,
+	}
+}
+
+// Tests that proposals signed separately, as on air-gapped machines, combine
+// into a single proposal carrying every signature in the order the wallet
+// expects.
+func TestCombineAndAssemble(t *testing.T) {
+	var (
+		owners  []common.Address
+		partial []*Proposal
+	)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		owners = append(owners, crypto.PubkeyToAddress(key.PublicKey))
+
+		// Round trip every partial proposal through its JSON form
+		proposal := testProposal()
+		if err := proposal.SignWithKey(key); err != nil {
+			t.Fatalf("failed to sign proposal: %v", err)
+		}
+		blob, err := json.Marshal(proposal)
+		if err != nil {
+			t.Fatalf("failed to encode proposal: %v", err)
+		}
+		decoded := new(Proposal)
+		if err := json.Unmarshal(blob, decoded); err != nil {
+			t.Fatalf("failed to decode proposal: %v", err)
+		}
+		partial = append(partial, decoded)
+	}
+	combined, err := Combine(partial...)
+	if err != nil {
+		t.Fatalf("failed to combine proposals: %v", err)
+	}
+	if len(combined.Signatures) != len(owners) {
+		t.Fatalf("signature count mismatch: have %d, want %d", len(combined.Signatures), len(owners))
+	}
+	if _, err := combined.Calldata(4); !errors.Is(err, ErrNotEnoughSignatures) {
+		t.Fatalf("assembled under threshold: %v", err)
+	}
+	data, err := combined.Calldata(3)
+	if err != nil {
+		t.Fatalf("failed to assemble execution: %v", err)
+	}
+	args, err := walletABI.Methods["execute"].Inputs.Unpack(data[4:])
+	if err != nil {
+		t.Fatalf("failed to unpack execution: %v", err)
+	}
+	sort.Slice(owners, func(i, j int) bool { return bytes.Compare(owners[i][:], owners[j][:]) < 0 })
+
+	var (
+		sigV = args[0].([]uint8)
+		sigR = args[1].([][32]byte)
+		sigS = args[2].([][32]byte)
+		hash = testProposal().Hash()
+	)
+	for i, owner := range owners {
+		sig := append(append(sigR[i][:], sigS[i][:]...), sigV[i]-27)
+		pub, err := crypto.SigToPub(hash[:], sig)
+		if err != nil {
+			t.Fatalf("signature %d: failed to recover: %v", i, err)
+		}
+		if signer := crypto.PubkeyToAddress(*pub); signer != owner {
+			t.Errorf("signature %d: signer mismatch: have %x, want %x", i, signer, owner)
+		}
+	}
+}
+
+// Tests that signatures over a different transaction are rejected.
+func TestCombineMismatch(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	a, b := testProposal(), testProposal()
+	b.Nonce++
+	if err := a.SignWithKey(key); err != nil {
+		t.Fatalf("failed to sign proposal: %v", err)
+	}
+	if err := b.SignWithKey(key); err != nil {
+		t.Fatalf("failed to sign proposal: %v", err)
+	}
+	if _, err := Combine(a, b); !errors.Is(err, ErrProposalMismatch) {
+		t.Fatalf("combined mismatching proposals: %v", err)
+	}
+	// Transplanting a signature makes it recover to someone else
+	a.Signatures[0].Signature = b.Signatures[0].Signature
+	if err := a.Verify(); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("accepted transplanted signature: %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+// Package attest implements the opt-in block attestation service, which signs
+// statements that a block is canonical in the local chain with a number of
+// prime confirmations using the node key. Exchanges can collect attestations
+// from several independent nodes and credit deposits once a quorum agrees.
+package attest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/p2p/enode"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/rlp"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// DefaultMaxConfirmations is the default number of prime confirmations after
+// which counting stops.
+const DefaultMaxConfirmations = 16
+
+// attestationDomain separates the attestation signing hashes from the hashes
+// of any other object signed with the node key.
+const attestationDomain = "quai block attestation"
+
+var (
+	attestMeter = metrics.NewRegisteredMeter("attest/signed", nil)
+
+	errNotCanonical = errors.New("block is not canonical")
+	errNoHeader     = errors.New("header not found")
+)
+
+// Config contains the settings of the attestation service.
+type Config struct {
+	MaxConfirmations uint64 // prime confirmations after which counting stops
+}
+
+// backend is the chain access needed by the attestation service.
+type backend interface {
+	ChainConfig() *params.ChainConfig
+	CurrentHeader() *types.Header
+	HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error)
+	HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error)
+	Engine() consensus.Engine
+}
+
+// Attestation is a statement signed by a node that a block is canonical in its
+// local chain, with the given number of prime confirmations at the time.
+type Attestation struct {
+	Location           hexutil.Bytes  `json:"location"`
+	Context            hexutil.Uint64 `json:"context"`
+	Number             hexutil.Uint64 `json:"number"`
+	Hash               common.Hash    `json:"hash"`
+	PrimeConfirmations hexutil.Uint64 `json:"primeConfirmations"` // capped at the node's maximum
+	Head               hexutil.Uint64 `json:"head"`               // local head height when attested
+	Timestamp          hexutil.Uint64 `json:"timestamp"`          // unix time of the attestation
+	Signer             enode.ID       `json:"signer"`
+	Signature          hexutil.Bytes  `json:"signature"`
+}
+
+// SigHash returns the hash signed by the attesting node.
+func (a *Attestation) SigHash() common.Hash {
+	enc, _ := rlp.EncodeToBytes([]interface{}{
+		attestationDomain,
+		[]byte(a.Location),
+		uint64(a.Context),
+		uint64(a.Number),
+		a.Hash,
+		uint64(a.PrimeConfirmations),
+		uint64(a.Head),
+		uint64(a.Timestamp),
+	})
+	return crypto.Keccak256Hash(enc)
+}
+
+// Verify checks that the attestation is signed by the node it names.
+func (a *Attestation) Verify() error {
+	if len(a.Signature) != crypto.SignatureLength {
+		return fmt.Errorf("invalid signature length %d", len(a.Signature))
+	}
+	pub, err := crypto.SigToPub(a.SigHash().Bytes(), a.Signature)
+	if err != nil {
+		return err
+	}
+	if signer := enode.PubkeyToIDV4(pub); signer != a.Signer {
+		return fmt.Errorf("signer mismatch: have %v, want %v", signer, a.Signer)
+	}
+	return nil
+}
+
+// Service signs attestations of the canonical blocks of the local chain.
+type Service struct {
+	config  Config
+	backend backend
+	key     *ecdsa.PrivateKey
+	id      enode.ID
+
+	order func(*types.Header) (int, error) // difficulty order of a header
+}
+
+// New creates the attestation service signing with the given node key and
+// registers its API with the node.
+func New(stack *node.Node, backend backend, key *ecdsa.PrivateKey, config Config) (*Service, error) {
+	if key == nil {
+		return nil, errors.New("node key not available")
+	}
+	if config.MaxConfirmations == 0 {
+		config.MaxConfirmations = DefaultMaxConfirmations
+	}
+	s := &Service{
+		config:  config,
+		backend: backend,
+		key:     key,
+		id:      enode.PubkeyToIDV4(&key.PublicKey),
+		order:   backend.Engine().GetDifficultyOrder,
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "quai",
+		Version:   "1.0",
+		Service:   &PublicAttestationAPI{s},
+		Public:    true,
+	}})
+	return s, nil
+}
+
+// Attest signs an attestation of the given block, which must be canonical. The
+// prime confirmations are the canonical blocks of prime order at or above its
+// height, up to the configured maximum.
+func (s *Service) Attest(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*Attestation, error) {
+	header, err := s.backend.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errNoHeader
+	}
+	var (
+		number = header.Number[types.QuaiNetworkContext].Uint64()
+		head   = s.backend.CurrentHeader().Number[types.QuaiNetworkContext].Uint64()
+		hash   = header.Hash()
+	)
+	if canonical, err := s.backend.HeaderByNumber(ctx, rpc.BlockNumber(number)); err != nil {
+		return nil, err
+	} else if canonical == nil || canonical.Hash() != hash {
+		return nil, errNotCanonical
+	}
+	var confirmations uint64
+	for n := number; n <= head && confirmations < s.config.MaxConfirmations; n++ {
+		current := header
+		if n != number {
+			if current, err = s.backend.HeaderByNumber(ctx, rpc.BlockNumber(n)); err != nil {
+				return nil, err
+			}
+			if current == nil {
+				break
+			}
+		}
+		order, err := s.order(current)
+		if err != nil {
+			return nil, fmt.Errorf("header #%d [%x]: %v", n, current.Hash(), err)
+		}
+		if order == params.PRIME {
+			confirmations++
+		}
+	}
+	// Make sure the block was not reorged out while counting
+	if canonical, err := s.backend.HeaderByNumber(ctx, rpc.BlockNumber(number)); err != nil {
+		return nil, err
+	} else if canonical == nil || canonical.Hash() != hash {
+		return nil, errNotCanonical
+	}
+	attestation := &Attestation{
+		Location:           common.CopyBytes(s.backend.ChainConfig().Location),
+		Context:            hexutil.Uint64(types.QuaiNetworkContext),
+		Number:             hexutil.Uint64(number),
+		Hash:               hash,
+		PrimeConfirmations: hexutil.Uint64(confirmations),
+		Head:               hexutil.Uint64(head),
+		Timestamp:          hexutil.Uint64(time.Now().Unix()),
+		Signer:             s.id,
+	}
+	sig, err := crypto.Sign(attestation.SigHash().Bytes(), s.key)
+	if err != nil {
+		return nil, err
+	}
+	attestation.Signature = sig
+	attestMeter.Mark(1)
+	return attestation, nil
+}
+
+// PublicAttestationAPI offers the block attestations over RPC.
+type PublicAttestationAPI struct {
+	s *Service
+}
+
+// GetBlockAttestation returns an attestation signed with the node key that the
+// given block is canonical, with its current prime confirmations.
+func (api *PublicAttestationAPI) GetBlockAttestation(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*Attestation, error) {
+	return api.s.Attest(ctx, blockNrOrHash)
+}
@@ -0,0 +1,102 @@
+package attest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/p2p/enode"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// testBackend serves a canonical chain of headers whose extra data holds their
+// difficulty order.
+type testBackend struct {
+	headers   []*types.Header // canonical chain
+	sidechain []*types.Header // non canonical headers served by hash
+}
+
+func newTestBackend(orders ...int) *testBackend {
+	b := new(testBackend)
+	for number, order := range orders {
+		header := types.NewEmptyHeader()
+		for i := 0; i < types.ContextDepth; i++ {
+			header.Number[i] = big.NewInt(int64(number))
+			header.Difficulty[i] = big.NewInt(1)
+		}
+		header.Extra[types.QuaiNetworkContext] = []byte{byte(order)}
+		b.headers = append(b.headers, header)
+	}
+	return b
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+func (b *testBackend) CurrentHeader() *types.Header     { return b.headers[len(b.headers)-1] }
+func (b *testBackend) Engine() consensus.Engine         { return nil }
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if int(number) >= len(b.headers) {
+		return nil, nil
+	}
+	return b.headers[number], nil
+}
+
+func (b *testBackend) HeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if number, ok := blockNrOrHash.Number(); ok {
+		return b.HeaderByNumber(ctx, number)
+	}
+	hash, _ := blockNrOrHash.Hash()
+	for _, header := range append(b.headers, b.sidechain...) {
+		if header.Hash() == hash {
+			return header, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestAttest(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	backend := newTestBackend(params.ZONE, params.ZONE, params.PRIME, params.REGION, params.PRIME, params.PRIME)
+	s := &Service{
+		config:  Config{MaxConfirmations: 2},
+		backend: backend,
+		key:     key,
+		id:      enode.PubkeyToIDV4(&key.PublicKey),
+		order: func(header *types.Header) (int, error) {
+			return int(header.Extra[types.QuaiNetworkContext][0]), nil
+		},
+	}
+	attestation, err := s.Attest(context.Background(), rpc.BlockNumberOrHashWithNumber(1))
+	if err != nil {
+		t.Fatalf("failed to attest: %v", err)
+	}
+	if attestation.Hash != backend.headers[1].Hash() || attestation.Number != 1 || attestation.Head != 5 {
+		t.Errorf("attested block mismatch: %+v", attestation)
+	}
+	if attestation.PrimeConfirmations != 2 {
+		t.Errorf("capped confirmations mismatch: have %d, want 2", attestation.PrimeConfirmations)
+	}
+	if err := attestation.Verify(); err != nil {
+		t.Errorf("valid attestation rejected: %v", err)
+	}
+	attestation.PrimeConfirmations = 3
+	if err := attestation.Verify(); err == nil {
+		t.Errorf("tampered attestation accepted")
+	}
+	// A prime block confirms itself
+	s.config.MaxConfirmations = 10
+	if attestation, err = s.Attest(context.Background(), rpc.BlockNumberOrHashWithNumber(4)); err != nil || attestation.PrimeConfirmations != 2 {
+		t.Errorf("prime block confirmations mismatch: %v, %v", attestation, err)
+	}
+	// Non canonical blocks are not attested
+	sibling := types.CopyHeader(backend.headers[1])
+	sibling.Time++
+	backend.sidechain = append(backend.sidechain, sibling)
+	if _, err := s.Attest(context.Background(), rpc.BlockNumberOrHashWithHash(sibling.Hash(), false)); err != errNotCanonical {
+		t.Errorf("sibling attestation error mismatch: have %v, want %v", err, errNotCanonical)
+	}
+}
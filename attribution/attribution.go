@@ -0,0 +1,299 @@
+// Package attribution implements an indexer of the extra-data tags miners put
+// in the blocks of the local chain, letting explorers attribute blocks to pools
+// and workers over RPC.
+package attribution
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// DefaultWindow is the default number of heights the index spans.
+	DefaultWindow = 8192
+
+	// DefaultDepth is the default number of confirmations after which a height
+	// is indexed, so reorgs rarely invalidate the index.
+	DefaultDepth = 7
+
+	// defaultLimit is the number of blocks returned by queries asking for none.
+	defaultLimit = 100
+)
+
+// Config contains the settings of the extra-data indexer.
+type Config struct {
+	Window uint64 // number of indexed heights
+	Depth  uint64 // confirmations before a height is indexed
+}
+
+// backend is the chain access needed by the extra-data indexer.
+type backend interface {
+	ChainDb() ethdb.Database
+	CurrentHeader() *types.Header
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Block is an indexed canonical block.
+type Block struct {
+	Number   hexutil.Uint64 `json:"number"`
+	Hash     common.Hash    `json:"hash"`
+	Coinbase common.Address `json:"coinbase"`
+	Tag      string         `json:"tag"`
+	Extra    hexutil.Bytes  `json:"extra"`
+}
+
+// Tag normalizes extra-data into the tag it is indexed under: the text for
+// printable extra-data, the hex encoding otherwise.
+func Tag(extra []byte) string {
+	text := strings.TrimRight(string(extra), "\x00")
+	if text == "" || !utf8.ValidString(text) {
+		return hexutil.Encode(extra)
+	}
+	for _, r := range text {
+		if !unicode.IsPrint(r) {
+			return hexutil.Encode(extra)
+		}
+	}
+	return text
+}
+
+// Indexer tracks the extra-data tags of a sliding window of canonical blocks.
+type Indexer struct {
+	config  Config
+	backend backend
+	db      ethdb.Database
+
+	lock   sync.RWMutex
+	blocks []*Block // indexed blocks, oldest first
+	next   uint64   // next height to index
+
+	headSub event.Subscription
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New creates the extra-data indexer and registers it with the node.
+func New(stack *node.Node, backend backend, config Config) *Indexer {
+	if config.Window == 0 {
+		config.Window = DefaultWindow
+	}
+	if config.Depth == 0 {
+		config.Depth = DefaultDepth
+	}
+	idx := &Indexer{
+		config:  config,
+		backend: backend,
+		db:      backend.ChainDb(),
+		quit:    make(chan struct{}),
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "quai",
+		Version:   "1.0",
+		Service:   &PublicAttributionAPI{idx},
+		Public:    true,
+	}})
+	stack.RegisterLifecycle(idx)
+	return idx
+}
+
+// Start implements node.Lifecycle, starting the indexing loop.
+func (idx *Indexer) Start() error {
+	headCh := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	idx.headSub = idx.backend.SubscribeChainHeadEvent(headCh)
+
+	idx.wg.Add(1)
+	go idx.loop(headCh)
+
+	log.Info("Extra-data indexer started", "window", idx.config.Window, "depth", idx.config.Depth)
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the indexing loop.
+func (idx *Indexer) Stop() error {
+	idx.headSub.Unsubscribe()
+	close(idx.quit)
+	idx.wg.Wait()
+	log.Info("Extra-data indexer stopped")
+	return nil
+}
+
+func (idx *Indexer) loop(headCh chan core.ChainHeadEvent) {
+	defer idx.wg.Done()
+
+	if head := idx.backend.CurrentHeader(); head != nil {
+		number := head.Number[types.QuaiNetworkContext].Uint64()
+		if number > idx.config.Depth+idx.config.Window {
+			idx.next = number - idx.config.Depth - idx.config.Window
+		}
+		idx.index(number)
+	}
+	for {
+		select {
+		case ev := <-headCh:
+			idx.index(ev.Block.Header().Number[types.QuaiNetworkContext].Uint64())
+		case <-idx.headSub.Err():
+			return
+		case <-idx.quit:
+			return
+		}
+	}
+}
+
+// index processes every height with enough confirmations below the given head.
+func (idx *Indexer) index(head uint64) {
+	if head < idx.config.Depth {
+		return
+	}
+	for ; idx.next <= head-idx.config.Depth; idx.next++ {
+		select {
+		case <-idx.quit:
+			return
+		default:
+		}
+		idx.process(idx.next)
+	}
+}
+
+// process indexes the canonical block at the given height.
+func (idx *Indexer) process(number uint64) {
+	hash := rawdb.ReadCanonicalHash(idx.db, number)
+	if hash == (common.Hash{}) {
+		return
+	}
+	header := rawdb.ReadHeader(idx.db, hash, number)
+	if header == nil {
+		return
+	}
+	extra := common.CopyBytes(header.Extra[types.QuaiNetworkContext])
+	block := &Block{
+		Number:   hexutil.Uint64(number),
+		Hash:     hash,
+		Coinbase: header.Coinbase[types.QuaiNetworkContext],
+		Tag:      Tag(extra),
+		Extra:    extra,
+	}
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	idx.blocks = append(idx.blocks, block)
+	if uint64(len(idx.blocks)) > idx.config.Window {
+		idx.blocks = idx.blocks[1:]
+	}
+}
+
+// TagStats is the share of the indexed blocks carrying a tag.
+type TagStats struct {
+	Tag       string           `json:"tag"`
+	Blocks    uint64           `json:"blocks"`
+	Share     float64          `json:"share"`
+	Coinbases []common.Address `json:"coinbases"` // distinct coinbases of the tagged blocks
+	Last      hexutil.Uint64   `json:"last"`      // number of the latest tagged block
+}
+
+// Stats are the extra-data tags of the indexed window.
+type Stats struct {
+	Context int            `json:"context"`
+	From    hexutil.Uint64 `json:"from"`
+	To      hexutil.Uint64 `json:"to"`
+	Blocks  uint64         `json:"blocks"`
+	Tags    []*TagStats    `json:"tags"` // sorted by block count, descending
+}
+
+// Stats aggregates the indexed blocks by tag.
+func (idx *Indexer) Stats() *Stats {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	stats := &Stats{Context: types.QuaiNetworkContext, Tags: []*TagStats{}}
+	if len(idx.blocks) == 0 {
+		return stats
+	}
+	stats.From = idx.blocks[0].Number
+	stats.To = idx.blocks[len(idx.blocks)-1].Number
+
+	var (
+		tags      = make(map[string]*TagStats)
+		coinbases = make(map[string]map[common.Address]struct{})
+	)
+	for _, block := range idx.blocks {
+		stats.Blocks++
+		tag := tags[block.Tag]
+		if tag == nil {
+			tag = &TagStats{Tag: block.Tag}
+			tags[block.Tag] = tag
+			coinbases[block.Tag] = make(map[common.Address]struct{})
+		}
+		tag.Blocks++
+		tag.Last = block.Number
+		if _, ok := coinbases[block.Tag][block.Coinbase]; !ok {
+			coinbases[block.Tag][block.Coinbase] = struct{}{}
+			tag.Coinbases = append(tag.Coinbases, block.Coinbase)
+		}
+	}
+	for _, tag := range tags {
+		tag.Share = float64(tag.Blocks) / float64(stats.Blocks)
+		stats.Tags = append(stats.Tags, tag)
+	}
+	sort.Slice(stats.Tags, func(i, j int) bool {
+		if stats.Tags[i].Blocks != stats.Tags[j].Blocks {
+			return stats.Tags[i].Blocks > stats.Tags[j].Blocks
+		}
+		return stats.Tags[i].Tag < stats.Tags[j].Tag
+	})
+	return stats
+}
+
+// Blocks returns up to limit of the most recent indexed blocks whose tag starts
+// with the given prefix, newest first. Templates leading with the pool name
+// thus allow querying a pool's blocks across all its workers.
+func (idx *Indexer) Blocks(prefix string, limit int) []*Block {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	blocks := []*Block{}
+	for i := len(idx.blocks) - 1; i >= 0 && len(blocks) < limit; i-- {
+		if strings.HasPrefix(idx.blocks[i].Tag, prefix) {
+			block := *idx.blocks[i]
+			blocks = append(blocks, &block)
+		}
+	}
+	return blocks
+}
+
+// PublicAttributionAPI offers the extra-data index over RPC.
+type PublicAttributionAPI struct {
+	idx *Indexer
+}
+
+// ExtraDataStats returns the extra-data tags of the indexed window along with
+// their share of the blocks and the coinbases they were mined with.
+func (api *PublicAttributionAPI) ExtraDataStats() *Stats {
+	return api.idx.Stats()
+}
+
+// BlocksByExtraData returns the most recent indexed blocks whose extra-data tag
+// starts with the given prefix, newest first. The limit defaults to 100.
+func (api *PublicAttributionAPI) BlocksByExtraData(prefix string, limit *int) []*Block {
+	n := defaultLimit
+	if limit != nil && *limit > 0 {
+		n = *limit
+	}
+	return api.idx.Blocks(prefix, n)
+}
@@ -0,0 +1,74 @@
+package attribution
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethdb"
+)
+
+// writeHeader stores a canonical header with the given coinbase and extra-data.
+func writeHeader(db ethdb.Database, number uint64, coinbase common.Address, extra []byte) {
+	header := types.NewEmptyHeader()
+	for i := 0; i < types.ContextDepth; i++ {
+		header.Number[i] = new(big.Int).SetUint64(number)
+		header.Difficulty[i] = big.NewInt(1)
+		header.NetworkDifficulty[i] = big.NewInt(1)
+		header.BaseFee[i] = big.NewInt(0)
+		header.Coinbase[i] = coinbase
+	}
+	header.Extra[types.QuaiNetworkContext] = extra
+
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteCanonicalHash(db, header.Hash(), number)
+}
+
+func TestTag(t *testing.T) {
+	tests := []struct {
+		extra []byte
+		tag   string
+	}{
+		{[]byte("pool/w1/1"), "pool/w1/1"},
+		{[]byte("pool\x00\x00"), "pool"},
+		{[]byte{0xc0, 0x01}, "0xc001"},
+		{[]byte("a\nb"), "0x610a62"},
+		{nil, "0x"},
+	}
+	for _, tt := range tests {
+		if tag := Tag(tt.extra); tag != tt.tag {
+			t.Errorf("tag of %x mismatch: have %q, want %q", tt.extra, tag, tt.tag)
+		}
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	writeHeader(db, 1, common.Address{0x01}, []byte("solo"))
+	writeHeader(db, 2, common.Address{0x0a}, []byte("pool/w1"))
+	writeHeader(db, 3, common.Address{0x0b}, []byte("pool/w2"))
+	writeHeader(db, 4, common.Address{0x0a}, []byte("pool/w1"))
+	writeHeader(db, 5, common.Address{0x01}, []byte("solo"))
+
+	idx := &Indexer{config: Config{Window: 4, Depth: 1}, db: db}
+	idx.next = 1
+	idx.index(6)
+
+	stats := idx.Stats()
+	if stats.From != 2 || stats.To != 5 || stats.Blocks != 4 {
+		t.Fatalf("window mismatch: have %d-%d with %d blocks, want 2-5 with 4", stats.From, stats.To, stats.Blocks)
+	}
+	if top := stats.Tags[0]; top.Tag != "pool/w1" || top.Blocks != 2 || top.Share != 0.5 || top.Last != 4 || len(top.Coinbases) != 1 {
+		t.Errorf("top tag mismatch: have %+v", top)
+	}
+	blocks := idx.Blocks("pool/", 10)
+	if len(blocks) != 3 || blocks[0].Number != 4 || blocks[2].Number != 2 {
+		t.Fatalf("pool blocks mismatch: have %+v", blocks)
+	}
+	if blocks := idx.Blocks("pool/", 1); len(blocks) != 1 || blocks[0].Tag != "pool/w1" {
+		t.Errorf("limited pool blocks mismatch: have %+v", blocks)
+	}
+}
@@ -0,0 +1,49 @@
+// blake3conform runs a work server external Blake3 miners can be pointed at to
+// validate their kernels against go-quai's proof-of-work verifier.
+package main
+
+import (
+	"flag"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/spruce-solutions/go-quai/cmd/utils"
+	"github.com/spruce-solutions/go-quai/consensus/blake3/conformance"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+func main() {
+	var (
+		listenAddr = flag.String("addr", "127.0.0.1:8548", "HTTP-RPC listen address")
+		difficulty = flag.Uint64("difficulty", conformance.DefaultDifficulty, "zone difficulty of the cases, region and prime are 4 and 16 times harder")
+		seed       = flag.Int64("seed", 1, "seed the case headers are derived from")
+		verbosity  = flag.Int("verbosity", int(log.LvlInfo), "log verbosity (0-5)")
+	)
+	flag.Parse()
+
+	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
+	glogger.Verbosity(log.Lvl(*verbosity))
+	log.Root().SetHandler(glogger)
+
+	suite, err := conformance.NewServer(*seed, *difficulty)
+	if err != nil {
+		utils.Fatalf("Failed to create conformance suite: %v", err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("eth", conformance.NewEthAPI(suite)); err != nil {
+		utils.Fatalf("Failed to register eth API: %v", err)
+	}
+	if err := server.RegisterName("blake3", conformance.NewAPI(suite)); err != nil {
+		utils.Fatalf("Failed to register blake3 API: %v", err)
+	}
+	listener, err := net.Listen("tcp", *listenAddr)
+	if err != nil {
+		utils.Fatalf("-addr: %v", err)
+	}
+	log.Info("Serving Blake3 conformance suite", "url", "http://"+listener.Addr().String(), "difficulty", *difficulty, "seed", *seed)
+	if err := http.Serve(listener, server); err != nil {
+		utils.Fatalf("HTTP server failed: %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/p2p/enode"
+	"github.com/spruce-solutions/go-quai/p2p/enr"
+)
+
+// unknownSlice is the statistics key used for nodes that do not advertise a
+// location in their record.
+const unknownSlice = "unknown"
+
+// sliceKey returns the canonical "region-zone" notation of a location.
+func sliceKey(loc []byte) string {
+	if len(loc) != 2 {
+		return unknownSlice
+	}
+	return fmt.Sprintf("%d-%d", loc[0], loc[1])
+}
+
+// parseSlices parses a comma separated list of "region-zone" pairs, e.g.
+// "1-1,1-2,2-0". A zone of 0 denotes a region node and "0-0" denotes prime.
+func parseSlices(s string) (map[string]bool, error) {
+	slices := make(map[string]bool)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.Split(item, "-")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid slice %q, want region-zone", item)
+		}
+		loc := make([]byte, 2)
+		for i, part := range parts {
+			n, err := strconv.ParseUint(part, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid slice %q: %v", item, err)
+			}
+			loc[i] = byte(n)
+		}
+		slices[sliceKey(loc)] = true
+	}
+	if len(slices) == 0 {
+		return nil, fmt.Errorf("no slices specified")
+	}
+	return slices, nil
+}
+
+// sliceFilter decides which table entries the bootnode serves to requesters and
+// keeps track of discovery statistics per slice.
+type sliceFilter struct {
+	allowed      map[string]bool // nil allows every slice
+	allowUnknown bool            // whether nodes without a location are served
+
+	lock     sync.Mutex
+	served   map[string]uint64
+	filtered map[string]uint64
+}
+
+func newSliceFilter(allowed map[string]bool, allowUnknown bool) *sliceFilter {
+	return &sliceFilter{
+		allowed:      allowed,
+		allowUnknown: allowUnknown,
+		served:       make(map[string]uint64),
+		filtered:     make(map[string]uint64),
+	}
+}
+
+// nodeSlice extracts the slice key advertised by the node record.
+func nodeSlice(n *enode.Node) string {
+	var loc enr.Location
+	if err := n.Load(&loc); err != nil {
+		return unknownSlice
+	}
+	return sliceKey(loc)
+}
+
+// serve implements the discover.Config ServeFilter hook.
+func (f *sliceFilter) serve(n *enode.Node) bool {
+	key := nodeSlice(n)
+
+	ok := f.allowed == nil || f.allowed[key] || (key == unknownSlice && f.allowUnknown)
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if ok {
+		f.served[key]++
+	} else {
+		f.filtered[key]++
+	}
+	return ok
+}
+
+// report logs the table composition and the served/filtered counters
+// accumulated since the last report, resetting the counters.
+func (f *sliceFilter) report(nodes []*enode.Node) {
+	table := make(map[string]int)
+	for _, n := range nodes {
+		table[nodeSlice(n)]++
+	}
+	f.lock.Lock()
+	served, filtered := f.served, f.filtered
+	f.served, f.filtered = make(map[string]uint64), make(map[string]uint64)
+	f.lock.Unlock()
+
+	keys := make(map[string]struct{})
+	for k := range table {
+		keys[k] = struct{}{}
+	}
+	for k := range served {
+		keys[k] = struct{}{}
+	}
+	for k := range filtered {
+		keys[k] = struct{}{}
+	}
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	log.Info("Discovery table stats", "nodes", len(nodes), "slices", len(table))
+	for _, k := range sorted {
+		log.Info("Discovery slice stats", "slice", k, "nodes", table[k], "served", served[k], "filtered", filtered[k])
+	}
+}
+
+// reportLoop periodically reports statistics until the process exits.
+func (f *sliceFilter) reportLoop(interval time.Duration, allNodes func() []*enode.Node) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		f.report(allNodes())
+	}
+}
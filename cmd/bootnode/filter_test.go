@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/p2p/enode"
+	"github.com/spruce-solutions/go-quai/p2p/enr"
+)
+
+func TestParseSlices(t *testing.T) {
+	slices, err := parseSlices("1-1, 1-2,2-0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"1-1", "1-2", "2-0"} {
+		if !slices[want] {
+			t.Errorf("slice %s missing from %v", want, slices)
+		}
+	}
+	for _, bad := range []string{"", "1", "1-2-3", "a-1", "1-300"} {
+		if _, err := parseSlices(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestSliceFilter(t *testing.T) {
+	newNode := func(loc []byte) *enode.Node {
+		key, _ := crypto.GenerateKey()
+		db, _ := enode.OpenDB("")
+		ln := enode.NewLocalNode(db, key)
+		if loc != nil {
+			ln.Set(enr.Location(loc))
+		}
+		return ln.Node()
+	}
+	var (
+		inSlice  = newNode([]byte{1, 1})
+		outSlice = newNode([]byte{2, 3})
+		unknown  = newNode(nil)
+	)
+	allowed, _ := parseSlices("1-1")
+
+	filter := newSliceFilter(allowed, true)
+	if !filter.serve(inSlice) {
+		t.Error("node in allowed slice was filtered")
+	}
+	if filter.serve(outSlice) {
+		t.Error("node outside allowed slices was served")
+	}
+	if !filter.serve(unknown) {
+		t.Error("node without location was filtered despite allowUnknown")
+	}
+	if filter.served["1-1"] != 1 || filter.filtered["2-3"] != 1 || filter.served[unknownSlice] != 1 {
+		t.Errorf("unexpected counters: served %v, filtered %v", filter.served, filter.filtered)
+	}
+	if newSliceFilter(allowed, false).serve(unknown) {
+		t.Error("node without location was served despite !allowUnknown")
+	}
+	if !newSliceFilter(nil, false).serve(outSlice) {
+		t.Error("unrestricted filter rejected node")
+	}
+}
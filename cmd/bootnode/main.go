@@ -43,6 +43,9 @@ func main() {
 		natdesc     = flag.String("nat", "none", "port mapping mechanism (any|none|upnp|pmp|extip:<IP>)")
 		netrestrict = flag.String("netrestrict", "", "restrict network communication to the given IP networks (CIDR masks)")
 		runv5       = flag.Bool("v5", false, "run a v5 topic discovery bootnode")
+		slices      = flag.String("slices", "", "only serve nodes of the given slices (comma separated region-zone pairs, e.g. 1-1,1-2)")
+		unknown     = flag.Bool("serveunknown", true, "serve nodes that do not advertise a slice location when -slices is set")
+		stats       = flag.Duration("stats", 0, "interval at which discovery statistics are reported (0 = disabled)")
 		verbosity   = flag.Int("verbosity", int(log.LvlInfo), "log verbosity (0-5)")
 		vmodule     = flag.String("vmodule", "", "log verbosity pattern")
 
@@ -99,6 +102,15 @@ func main() {
 		}
 	}
 
+	var allowed map[string]bool
+	if *slices != "" {
+		allowed, err = parseSlices(*slices)
+		if err != nil {
+			utils.Fatalf("-slices: %v", err)
+		}
+	}
+	filter := newSliceFilter(allowed, *unknown)
+
 	addr, err := net.ResolveUDPAddr("udp", *listenAddr)
 	if err != nil {
 		utils.Fatalf("-ResolveUDPAddr: %v", err)
@@ -125,15 +137,24 @@ func main() {
 	cfg := discover.Config{
 		PrivateKey:  nodeKey,
 		NetRestrict: restrictList,
+		ServeFilter: filter.serve,
 	}
+	var allNodes func() []*enode.Node
 	if *runv5 {
-		if _, err := discover.ListenV5(conn, ln, cfg); err != nil {
+		udp, err := discover.ListenV5(conn, ln, cfg)
+		if err != nil {
 			utils.Fatalf("%v", err)
 		}
+		allNodes = udp.AllNodes
 	} else {
-		if _, err := discover.ListenUDP(conn, ln, cfg); err != nil {
+		udp, err := discover.ListenUDP(conn, ln, cfg)
+		if err != nil {
 			utils.Fatalf("%v", err)
 		}
+		allNodes = udp.AllNodes
+	}
+	if *stats > 0 {
+		go filter.reportLoop(*stats, allNodes)
 	}
 
 	select {}
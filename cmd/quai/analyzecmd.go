@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spruce-solutions/go-quai/cmd/utils"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/state/snapshot"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/trie"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	analyzeTopFlag = cli.IntFlag{
+		Name:  "analyze.top",
+		Usage: "Number of contracts listed by storage slots and by storage growth",
+		Value: 20,
+	}
+	analyzeBaseFlag = cli.StringFlag{
+		Name:  "analyze.base",
+		Usage: "JSON report of a previous run to report the growth against",
+	}
+	analyzeOutputFlag = cli.StringFlag{
+		Name:  "analyze.output",
+		Usage: "File to write the JSON report to, for later use as analyze.base",
+	}
+
+	analyzeStateCommand = cli.Command{
+		Action:    utils.MigrateFlags(analyzeState),
+		Name:      "analyze-state",
+		Usage:     "Report the storage usage of the accounts in the snapshot",
+		ArgsUsage: "[<root>]",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.RegionFlag,
+			utils.ZoneFlag,
+			analyzeTopFlag,
+			analyzeBaseFlag,
+			analyzeOutputFlag,
+		},
+		Description: `
+The analyze-state command walks the snapshot of the given state root, the head
+state if none is given, and reports the contracts holding the most storage slots
+and the distribution of the contract code sizes.
+
+Passing the JSON report of a previous run with --analyze.base adds the growth of
+the state since then, along with the contracts whose storage grew the most.
+Reports keep the slot count of every contract with storage, so they are sized
+after the number of such contracts.
+
+Contracts are identified by the hash of their address, the address is resolved
+when its preimage is stored.`,
+	}
+)
+
+// codeSizeBuckets are the upper bounds of the code size distribution buckets,
+// the last bucket holding all larger codes.
+var codeSizeBuckets = []int{256, 1024, 4096, 8192, 16384, 24576}
+
+// contractUsage is the storage usage of a single contract.
+type contractUsage struct {
+	Hash     common.Hash     `json:"hash"`
+	Address  *common.Address `json:"address,omitempty"`
+	Slots    uint64          `json:"slots"`
+	Growth   int64           `json:"growth,omitempty"`
+	CodeSize int             `json:"codeSize"`
+}
+
+// codeSizeBucket is the number of contracts with code sizes within a bucket.
+type codeSizeBucket struct {
+	Label     string `json:"label"`
+	Contracts uint64 `json:"contracts"`
+}
+
+// stateAnalysis is the JSON document produced by the analyze-state command.
+type stateAnalysis struct {
+	Root      common.Hash            `json:"root"`
+	Time      time.Time              `json:"time"`
+	Accounts  uint64                 `json:"accounts"`
+	Contracts uint64                 `json:"contracts"`
+	Slots     uint64                 `json:"slots"`
+	Codes     uint64                 `json:"codes"`     // Number of distinct codes
+	CodeBytes uint64                 `json:"codeBytes"` // Size of the distinct codes
+	CodeSizes []codeSizeBucket       `json:"codeSizes"`
+	Top       []*contractUsage       `json:"top"`
+	Growth    []*contractUsage       `json:"growth,omitempty"`
+	Storage   map[common.Hash]uint64 `json:"storage"` // Slots of every contract with storage
+}
+
+// usageHeap is a min heap of contracts by a key, keeping the largest ones.
+type usageHeap struct {
+	items []*contractUsage
+	key   func(*contractUsage) int64
+}
+
+func (h *usageHeap) Len() int           { return len(h.items) }
+func (h *usageHeap) Less(i, j int) bool { return h.key(h.items[i]) < h.key(h.items[j]) }
+func (h *usageHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *usageHeap) Push(x interface{}) { h.items = append(h.items, x.(*contractUsage)) }
+func (h *usageHeap) Pop() (x interface{}) {
+	x, h.items = h.items[len(h.items)-1], h.items[:len(h.items)-1]
+	return x
+}
+
+// offer keeps the contract if it is among the n largest ones seen.
+func (h *usageHeap) offer(usage *contractUsage, n int) {
+	if n <= 0 {
+		return
+	}
+	if h.Len() < n {
+		heap.Push(h, usage)
+	} else if h.key(usage) > h.key(h.items[0]) {
+		h.items[0] = usage
+		heap.Fix(h, 0)
+	}
+}
+
+// sorted returns the kept contracts, largest first.
+func (h *usageHeap) sorted() []*contractUsage {
+	items := append([]*contractUsage{}, h.items...)
+	sort.Slice(items, func(i, j int) bool { return h.key(items[i]) > h.key(items[j]) })
+	return items
+}
+
+func analyzeState(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, true)
+	headBlock := rawdb.ReadHeadBlock(chaindb)
+	if headBlock == nil {
+		log.Error("Failed to load head block")
+		return errors.New("no head block")
+	}
+	if ctx.NArg() > 1 {
+		log.Error("Too many arguments given")
+		return errors.New("too many arguments")
+	}
+	root := headBlock.Root()
+	if ctx.NArg() == 1 {
+		var err error
+		if root, err = parseRoot(ctx.Args()[0]); err != nil {
+			log.Error("Failed to resolve state root", "err", err)
+			return err
+		}
+	}
+	var base *stateAnalysis
+	if file := ctx.String(analyzeBaseFlag.Name); file != "" {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		base = new(stateAnalysis)
+		if err := json.Unmarshal(blob, base); err != nil {
+			return fmt.Errorf("invalid base report %s: %v", file, err)
+		}
+	}
+	snaptree, err := snapshot.New(chaindb, trie.NewDatabase(chaindb), 256, root, false, false, false)
+	if err != nil {
+		log.Error("Failed to open snapshot tree", "err", err)
+		return err
+	}
+	report, err := analyzeSnapshot(chaindb, snaptree, root, ctx.Int(analyzeTopFlag.Name), base)
+	if err != nil {
+		return err
+	}
+	printStateAnalysis(report, base)
+
+	if file := ctx.String(analyzeOutputFlag.Name); file != "" {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(file, append(out, '\n'), 0644)
+	}
+	return nil
+}
+
+// analyzeSnapshot walks the accounts and storage of the snapshot, keeping the
+// top contracts by storage slots and, given a base report, by storage growth.
+func analyzeSnapshot(db ethdb.KeyValueReader, snaptree *snapshot.Tree, root common.Hash, top int, base *stateAnalysis) (*stateAnalysis, error) {
+	accIt, err := snaptree.AccountIterator(root, common.Hash{})
+	if err != nil {
+		return nil, err
+	}
+	defer accIt.Release()
+
+	var (
+		report = &stateAnalysis{
+			Root:      root,
+			Time:      time.Now().UTC(),
+			CodeSizes: make([]codeSizeBucket, len(codeSizeBuckets)+1),
+			Storage:   make(map[common.Hash]uint64),
+		}
+		codeSizes = make(map[common.Hash]int)
+		bySlots   = &usageHeap{key: func(u *contractUsage) int64 { return int64(u.Slots) }}
+		byGrowth  = &usageHeap{key: func(u *contractUsage) int64 { return u.Growth }}
+
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for i, limit := range codeSizeBuckets {
+		report.CodeSizes[i].Label = fmt.Sprintf("<= %d", limit)
+	}
+	report.CodeSizes[len(codeSizeBuckets)].Label = fmt.Sprintf("> %d", codeSizeBuckets[len(codeSizeBuckets)-1])
+
+	for accIt.Next() {
+		account, err := snapshot.FullAccount(accIt.Account())
+		if err != nil {
+			return nil, err
+		}
+		report.Accounts++
+		if bytes.Equal(account.CodeHash, emptyCode) && common.BytesToHash(account.Root) == emptyRoot {
+			continue
+		}
+		usage := &contractUsage{Hash: accIt.Hash()}
+		if !bytes.Equal(account.CodeHash, emptyCode) {
+			codeHash := common.BytesToHash(account.CodeHash)
+			size, ok := codeSizes[codeHash]
+			if !ok {
+				size = len(rawdb.ReadCode(db, codeHash))
+				codeSizes[codeHash] = size
+				report.Codes++
+				report.CodeBytes += uint64(size)
+			}
+			usage.CodeSize = size
+			report.CodeSizes[sort.SearchInts(codeSizeBuckets, size)].Contracts++
+		}
+		report.Contracts++
+
+		if common.BytesToHash(account.Root) != emptyRoot {
+			stIt, err := snaptree.StorageIterator(root, accIt.Hash(), common.Hash{})
+			if err != nil {
+				return nil, err
+			}
+			for stIt.Next() {
+				usage.Slots++
+			}
+			err = stIt.Error()
+			stIt.Release()
+			if err != nil {
+				return nil, err
+			}
+			report.Slots += usage.Slots
+			report.Storage[usage.Hash] = usage.Slots
+		}
+		if base != nil {
+			usage.Growth = int64(usage.Slots) - int64(base.Storage[usage.Hash])
+			byGrowth.offer(usage, top)
+		}
+		bySlots.offer(usage, top)
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Analyzing state", "at", accIt.Hash(), "accounts", report.Accounts, "contracts", report.Contracts,
+				"slots", report.Slots, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		return nil, err
+	}
+	report.Top = bySlots.sorted()
+	if base != nil {
+		report.Growth = byGrowth.sorted()
+	}
+	for _, usage := range append(report.Top, report.Growth...) {
+		if preimage := rawdb.ReadPreimage(db, usage.Hash); len(preimage) == common.AddressLength {
+			addr := common.BytesToAddress(preimage)
+			usage.Address = &addr
+		}
+	}
+	log.Info("Analyzed state", "root", root, "accounts", report.Accounts, "contracts", report.Contracts,
+		"slots", report.Slots, "elapsed", common.PrettyDuration(time.Since(start)))
+	return report, nil
+}
+
+// printStateAnalysis writes the report as tables to the standard output.
+func printStateAnalysis(report *stateAnalysis, base *stateAnalysis) {
+	name := func(usage *contractUsage) string {
+		if usage.Address != nil {
+			return usage.Address.Hex()
+		}
+		return usage.Hash.Hex()
+	}
+	fmt.Printf("State %x\n", report.Root)
+	totals := tablewriter.NewWriter(os.Stdout)
+	if base != nil {
+		totals.SetHeader([]string{"Metric", "Value", "Growth since " + base.Time.Format(time.RFC3339)})
+	} else {
+		totals.SetHeader([]string{"Metric", "Value"})
+	}
+	var past stateAnalysis
+	if base != nil {
+		past = *base
+	}
+	for _, row := range []struct {
+		name       string
+		have, past uint64
+	}{
+		{"Accounts", report.Accounts, past.Accounts},
+		{"Contracts", report.Contracts, past.Contracts},
+		{"Storage slots", report.Slots, past.Slots},
+		{"Distinct codes", report.Codes, past.Codes},
+		{"Distinct code bytes", report.CodeBytes, past.CodeBytes},
+	} {
+		values := []string{row.name, fmt.Sprint(row.have)}
+		if base != nil {
+			values = append(values, fmt.Sprintf("%+d", int64(row.have)-int64(row.past)))
+		}
+		totals.Append(values)
+	}
+	totals.Render()
+
+	fmt.Println("\nContracts by code size (bytes)")
+	sizes := tablewriter.NewWriter(os.Stdout)
+	sizes.SetHeader([]string{"Code size", "Contracts"})
+	for _, bucket := range report.CodeSizes {
+		sizes.Append([]string{bucket.Label, fmt.Sprint(bucket.Contracts)})
+	}
+	sizes.Render()
+
+	fmt.Println("\nContracts by storage slots")
+	topTable := tablewriter.NewWriter(os.Stdout)
+	topTable.SetHeader([]string{"Contract", "Slots", "Code size"})
+	for _, usage := range report.Top {
+		topTable.Append([]string{name(usage), fmt.Sprint(usage.Slots), fmt.Sprint(usage.CodeSize)})
+	}
+	topTable.Render()
+
+	if base != nil {
+		fmt.Println("\nContracts by storage growth")
+		growth := tablewriter.NewWriter(os.Stdout)
+		growth.SetHeader([]string{"Contract", "Slots", "Growth"})
+		for _, usage := range report.Growth {
+			growth.Append([]string{name(usage), fmt.Sprint(usage.Slots), fmt.Sprintf("%+d", usage.Growth)})
+		}
+		growth.Render()
+	}
+}
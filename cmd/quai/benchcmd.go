@@ -0,0 +1,393 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/cmd/utils"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	benchSuitesFlag = cli.StringFlag{
+		Name:  "bench.suites",
+		Usage: "Comma separated list of benchmark suites to run (import, state, pcrc, txpool)",
+		Value: "import,state,pcrc,txpool",
+	}
+	benchNumberFlag = cli.Uint64Flag{
+		Name:  "bench.number",
+		Usage: "Number of the last block to benchmark against, the current head if zero",
+	}
+	benchBlocksFlag = cli.IntFlag{
+		Name:  "bench.blocks",
+		Usage: "Number of blocks below bench.number used by the import, state and pcrc suites",
+		Value: 128,
+	}
+	benchTxsFlag = cli.IntFlag{
+		Name:  "bench.txs",
+		Usage: "Number of transactions injected by the txpool suite",
+		Value: 4096,
+	}
+	benchOutputFlag = cli.StringFlag{
+		Name:  "bench.output",
+		Usage: "File to write the JSON report to, standard output if empty",
+	}
+
+	benchCommand = cli.Command{
+		Action:   utils.MigrateFlags(runBench),
+		Name:     "bench",
+		Usage:    "Run reproducible benchmarks against a datadir snapshot",
+		Category: "MISCELLANEOUS COMMANDS",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.RopstenFlag,
+			utils.CacheFlag,
+			utils.RegionFlag,
+			utils.ZoneFlag,
+			benchSuitesFlag,
+			benchNumberFlag,
+			benchBlocksFlag,
+			benchTxsFlag,
+			benchOutputFlag,
+		},
+		Description: `
+The bench command measures block import, state access, PCRC evaluation and
+transaction pool throughput against the chain stored in the data directory.
+
+The block range is pinned with --bench.number and --bench.blocks, so running the
+command against the same snapshot yields comparable results across releases. No
+data is written to the database: blocks are re-processed on top of their parent
+state and the transaction pool runs on an in-memory copy of the head state.
+
+The report is emitted as JSON for regression tracking.`,
+	}
+)
+
+// benchResult is the outcome of a single benchmark suite.
+type benchResult struct {
+	Name     string        `json:"name"`
+	Ops      int           `json:"ops"`
+	Errors   int           `json:"errors"`
+	Duration time.Duration `json:"durationNs"`
+	NsPerOp  int64         `json:"nsPerOp"`
+	OpsPerS  float64       `json:"opsPerSecond"`
+}
+
+// benchReport is the JSON document produced by the bench command.
+type benchReport struct {
+	Version   string        `json:"version"`
+	GoVersion string        `json:"goVersion"`
+	OS        string        `json:"os"`
+	Arch      string        `json:"arch"`
+	Context   int           `json:"context"`
+	Number    uint64        `json:"number"`
+	Hash      common.Hash   `json:"hash"`
+	Blocks    int           `json:"blocks"`
+	Results   []benchResult `json:"results"`
+}
+
+// newBenchResult derives the per operation figures of a suite.
+func newBenchResult(name string, ops, errors int, elapsed time.Duration) benchResult {
+	result := benchResult{Name: name, Ops: ops, Errors: errors, Duration: elapsed}
+	if ops > 0 {
+		result.NsPerOp = elapsed.Nanoseconds() / int64(ops)
+	}
+	if elapsed > 0 {
+		result.OpsPerS = float64(ops) / elapsed.Seconds()
+	}
+	return result
+}
+
+func runBench(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+	defer chain.Stop()
+
+	head := chain.CurrentBlock()
+	if number := ctx.Uint64(benchNumberFlag.Name); number != 0 {
+		if head = chain.GetBlockByNumber(number); head == nil {
+			return fmt.Errorf("block %d not found", number)
+		}
+	}
+	blocks := loadBenchBlocks(chain, head, ctx.Int(benchBlocksFlag.Name))
+
+	report := &benchReport{
+		Version:   params.VersionWithMeta,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		Context:   types.QuaiNetworkContext,
+		Number:    head.NumberU64(),
+		Hash:      head.Hash(),
+		Blocks:    len(blocks),
+	}
+	for _, suite := range strings.Split(ctx.String(benchSuitesFlag.Name), ",") {
+		var result benchResult
+		switch suite = strings.TrimSpace(suite); suite {
+		case "import":
+			result = benchImport(chain, blocks)
+		case "state":
+			result = benchState(chain, head, blocks)
+		case "pcrc":
+			result = benchPCRC(chain, blocks)
+		case "txpool":
+			var err error
+			if result, err = benchTxPool(chain, head, ctx.Int(benchTxsFlag.Name)); err != nil {
+				return err
+			}
+		case "":
+			continue
+		default:
+			return fmt.Errorf("unknown benchmark suite %q", suite)
+		}
+		log.Info("Benchmark suite done", "suite", suite, "ops", result.Ops, "errors", result.Errors, "elapsed", common.PrettyDuration(result.Duration))
+		report.Results = append(report.Results, result)
+	}
+	out, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if file := ctx.String(benchOutputFlag.Name); file != "" {
+		return ioutil.WriteFile(file, append(out, '\n'), 0644)
+	}
+	fmt.Fprintln(os.Stdout, string(out))
+	return nil
+}
+
+// loadBenchBlocks returns up to n canonical blocks ending at head, oldest first.
+// The genesis block is never included as it has no parent to process it on.
+func loadBenchBlocks(chain *core.BlockChain, head *types.Block, n int) []*types.Block {
+	var blocks []*types.Block
+	for block := head; block != nil && block.NumberU64() > 0 && len(blocks) < n; {
+		blocks = append(blocks, block)
+		block = chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	}
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return blocks
+}
+
+// benchImport re-processes every block on top of its parent state, measuring
+// transaction execution and state root computation.
+func benchImport(chain *core.BlockChain, blocks []*types.Block) benchResult {
+	var (
+		ops, errs int
+		elapsed   time.Duration
+	)
+	for _, block := range blocks {
+		parent := chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+		if parent == nil {
+			errs++
+			continue
+		}
+		statedb, err := chain.StateAt(parent.Root())
+		if err != nil {
+			errs++
+			continue
+		}
+		start := time.Now()
+		if _, _, _, _, err := chain.Processor().Process(block, statedb, vm.Config{}); err != nil {
+			errs++
+		} else {
+			statedb.IntermediateRoot(chain.Config().DeleteEmptyAccounts(block.Number()))
+			ops++
+		}
+		elapsed += time.Since(start)
+	}
+	return newBenchResult("import", ops, errs, elapsed)
+}
+
+// benchState reads the accounts touched by the benchmarked blocks from the
+// head state.
+func benchState(chain *core.BlockChain, head *types.Block, blocks []*types.Block) benchResult {
+	statedb, err := chain.StateAt(head.Root())
+	if err != nil {
+		log.Warn("Head state unavailable", "number", head.NumberU64(), "err", err)
+		return newBenchResult("state", 0, 1, 0)
+	}
+	var (
+		seen  = make(map[common.Address]struct{})
+		addrs []common.Address
+	)
+	add := func(addr common.Address) {
+		if _, ok := seen[addr]; !ok {
+			seen[addr] = struct{}{}
+			addrs = append(addrs, addr)
+		}
+	}
+	for _, block := range blocks {
+		add(block.Coinbase())
+		signer := types.MakeSigner(chain.Config(), block.Number())
+		for _, tx := range block.Transactions() {
+			if from, err := types.Sender(signer, tx); err == nil {
+				add(from)
+			}
+			if tx.To() != nil {
+				add(*tx.To())
+			}
+		}
+	}
+	start := time.Now()
+	for _, addr := range addrs {
+		statedb.GetBalance(addr)
+		statedb.GetNonce(addr)
+		statedb.GetCodeHash(addr)
+	}
+	return newBenchResult("state", len(addrs), 0, time.Since(start))
+}
+
+// benchPCRC evaluates the previous coincident reference check of every block.
+func benchPCRC(chain *core.BlockChain, blocks []*types.Block) benchResult {
+	var (
+		ops, errs int
+		elapsed   time.Duration
+	)
+	for _, block := range blocks {
+		header := block.Header()
+		order, err := chain.Engine().GetDifficultyOrder(header)
+		if err != nil {
+			errs++
+			continue
+		}
+		start := time.Now()
+		if _, err := chain.PCRC(header, order); err != nil {
+			errs++
+		} else {
+			ops++
+		}
+		elapsed += time.Since(start)
+	}
+	return newBenchResult("pcrc", ops, errs, elapsed)
+}
+
+// benchTxPoolAccounts is the number of senders used by the txpool suite.
+const benchTxPoolAccounts = 64
+
+// benchPoolChain serves the transaction pool an in-memory copy of the head
+// state with the benchmark senders funded, leaving the database untouched.
+type benchPoolChain struct {
+	*core.BlockChain
+	head    *types.Block
+	statedb *state.StateDB
+	feed    event.Feed
+}
+
+func (bc *benchPoolChain) CurrentBlock() *types.Block { return bc.head }
+
+func (bc *benchPoolChain) StateAt(common.Hash) (*state.StateDB, error) {
+	return bc.statedb.Copy(), nil
+}
+
+func (bc *benchPoolChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return bc.feed.Subscribe(ch)
+}
+
+// benchKey derives a deterministic private key for the i-th benchmark sender,
+// grinding it until its address lies in the given address range, so that the
+// pool accepts its transactions.
+func benchKey(i int, idRange []int) *ecdsa.PrivateKey {
+	var seed [16]byte
+	binary.BigEndian.PutUint64(seed[:8], uint64(i))
+	for j := uint64(0); ; j++ {
+		binary.BigEndian.PutUint64(seed[8:], j)
+		key, err := crypto.ToECDSA(crypto.Keccak256(append([]byte("quai-bench"), seed[:]...)))
+		if err != nil {
+			continue
+		}
+		if prefix := int(crypto.PubkeyToAddress(key.PublicKey)[0]); prefix >= idRange[0] && prefix <= idRange[1] {
+			return key
+		}
+	}
+}
+
+// benchTxPool measures how fast the transaction pool validates and inserts
+// remote transactions from a fixed set of deterministic senders. Rejected
+// transactions would skew the figures, so any rejection fails the suite.
+func benchTxPool(chain *core.BlockChain, head *types.Block, n int) (benchResult, error) {
+	config := chain.Config()
+	idRange := config.ChainIDRange()
+	if len(idRange) != 2 {
+		return benchResult{}, fmt.Errorf("chain id %d has no address range", config.ChainID)
+	}
+	statedb, err := chain.StateAt(head.Root())
+	if err != nil {
+		return benchResult{}, fmt.Errorf("head state %d unavailable: %v", head.NumberU64(), err)
+	}
+	var (
+		keys   = make([]*ecdsa.PrivateKey, benchTxPoolAccounts)
+		funds  = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+		signer = types.LatestSigner(config)
+		price  = new(big.Int).Mul(head.BaseFee(), big.NewInt(2))
+		to     = common.Address{byte(idRange[0]), 0x01}
+	)
+	for i := range keys {
+		keys[i] = benchKey(i, idRange)
+		statedb.SetBalance(crypto.PubkeyToAddress(keys[i].PublicKey), funds)
+	}
+	txs := make([]*types.Transaction, 0, n)
+	for i := 0; i < n; i++ {
+		key := keys[i%len(keys)]
+		nonce := statedb.GetNonce(crypto.PubkeyToAddress(key.PublicKey)) + uint64(i/len(keys))
+		tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     nonce,
+			To:        &to,
+			Value:     big.NewInt(1),
+			Gas:       params.TxGas,
+			GasTipCap: price,
+			GasFeeCap: price,
+		})
+		if err != nil {
+			return benchResult{}, err
+		}
+		txs = append(txs, tx)
+	}
+	poolConfig := core.DefaultTxPoolConfig
+	poolConfig.NoLocals = true
+	poolConfig.AccountSlots = uint64(n/len(keys) + 1)
+	poolConfig.GlobalSlots = uint64(n + 1)
+
+	pool := core.NewTxPool(poolConfig, config, &benchPoolChain{BlockChain: chain, head: head, statedb: statedb})
+	defer pool.Stop()
+
+	var (
+		errs  int
+		first error
+	)
+	start := time.Now()
+	for _, err := range pool.AddRemotesSync(txs) {
+		if err != nil {
+			if errs++; first == nil {
+				first = err
+			}
+		}
+	}
+	elapsed := time.Since(start)
+	if errs > 0 {
+		return benchResult{}, fmt.Errorf("txpool rejected %d of %d transactions: %v", errs, len(txs), first)
+	}
+	return newBenchResult("txpool", len(txs), 0, elapsed), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus/blake3"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// Tests that the txpool suite runs on a generated zone chain without any of
+// its transactions being rejected by the pool.
+func TestBenchTxPool(t *testing.T) {
+	config := core.NetworkZoneConfig(params.TestChainConfig, 1, 1)
+	config.Context = types.QuaiNetworkContext
+
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = blake3.NewFaker()
+		gspec  = &core.Genesis{
+			Config:     config,
+			ParentHash: []common.Hash{{}, {}, {}},
+			Coinbase:   []common.Address{{}, {}, {}},
+			Number:     []*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(0)},
+			ExtraData:  [][]byte{nil, nil, nil},
+			GasLimit:   []uint64{params.GenesisGasLimit, params.GenesisGasLimit, params.GenesisGasLimit},
+			GasUsed:    []uint64{0, 0, 0},
+			Difficulty: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	chain, err := core.NewBlockChain(db, nil, config, "", nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	blocks, _ := core.GenerateChain(config, genesis, engine, db, 4, nil)
+	head := blocks[len(blocks)-1]
+
+	result, err := benchTxPool(chain, head, 4*benchTxPoolAccounts)
+	if err != nil {
+		t.Fatalf("txpool suite failed: %v", err)
+	}
+	if result.Errors != 0 {
+		t.Errorf("rejected transactions mismatch: have %d, want 0", result.Errors)
+	}
+	if result.Ops != 4*benchTxPoolAccounts {
+		t.Errorf("accepted transactions mismatch: have %d, want %d", result.Ops, 4*benchTxPoolAccounts)
+	}
+}
+
+// Tests that the benchmark senders lie in the address range of the chain.
+func TestBenchKeyRange(t *testing.T) {
+	idRange := core.NetworkZoneConfig(params.TestChainConfig, 2, 3).ChainIDRange()
+	for i := 0; i < benchTxPoolAccounts; i++ {
+		addr := crypto.PubkeyToAddress(benchKey(i, idRange).PublicKey)
+		if int(addr[0]) < idRange[0] || int(addr[0]) > idRange[1] {
+			t.Fatalf("sender %d out of range: have %#x, want [%#x, %#x]", i, addr[0], idRange[0], idRange[1])
+		}
+	}
+}
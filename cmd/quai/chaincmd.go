@@ -34,6 +34,7 @@ import (
 	"github.com/spruce-solutions/go-quai/core/state"
 	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/eth/downloader"
 	"github.com/spruce-solutions/go-quai/ethdb"
 	"github.com/spruce-solutions/go-quai/log"
 	"github.com/spruce-solutions/go-quai/metrics"
@@ -153,6 +154,72 @@ be gzipped.`,
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The export-preimages command export hash preimages to an RLP encoded stream`,
+	}
+	importSyncCommand = cli.Command{
+		Action:    utils.MigrateFlags(importSync),
+		Name:      "import-sync",
+		Usage:     "Import the progress of an unfinished chain sync",
+		ArgsUsage: "<syncfile>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The import-sync command imports the sync progress exported by export-sync, so
+the next synchronisation resumes it. Used together with the import command to
+bootstrap nodes from partially synced chain data.`,
+	}
+	exportSyncCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportSync),
+		Name:      "export-sync",
+		Usage:     "Export the progress of an unfinished chain sync",
+		ArgsUsage: "<syncfile>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-sync command exports the sync target, pivot and received ranges of an
+unfinished chain sync into a JSON file.`,
+	}
+	checkTdCommand = cli.Command{
+		Action:    utils.MigrateFlags(checkTd),
+		Name:      "check-td",
+		Usage:     "Check the stored total difficulties of the canonical chain",
+		ArgsUsage: "[<first> [<last>]]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+			utils.RegionFlag,
+			utils.ZoneFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The check-td command reports the canonical blocks from first (default genesis)
+to last (default head) whose total difficulty is missing or malformed.`,
+	}
+	repairTdCommand = cli.Command{
+		Action:    utils.MigrateFlags(repairTd),
+		Name:      "repair-td",
+		Usage:     "Recompute the missing total difficulties of the canonical chain",
+		ArgsUsage: "[<first> [<last>]]",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+			utils.RegionFlag,
+			utils.ZoneFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The repair-td command recomputes the missing or malformed total difficulties of
+the canonical blocks from first (default genesis) to last (default head) from
+their ancestors. It runs offline, without connecting to the dom and sub nodes.`,
 	}
 	dumpCommand = cli.Command{
 		Action:    utils.MigrateFlags(dump),
@@ -380,6 +447,54 @@ func exportPreimages(ctx *cli.Context) error {
 	return nil
 }
 
+// importSync imports the progress of an unfinished sync from the specified file.
+func importSync(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	in, err := os.Open(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Could not open sync file: %v", err)
+	}
+	defer in.Close()
+
+	if err := downloader.ImportSyncResume(db, in); err != nil {
+		utils.Fatalf("Import error: %v\n", err)
+	}
+	fmt.Println("Sync progress imported")
+	return nil
+}
+
+// exportSync exports the progress of an unfinished sync to the specified file.
+func exportSync(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	out, err := os.Create(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Could not create sync file: %v", err)
+	}
+	defer out.Close()
+
+	if err := downloader.ExportSyncResume(db, out); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Println("Sync progress exported")
+	return nil
+}
+
 func parseDumpConfig(ctx *cli.Context, stack *node.Node) (*state.DumpConfig, ethdb.Database, common.Hash, error) {
 	db := utils.MakeChainDatabase(ctx, stack, true)
 	var header *types.Header
@@ -438,6 +553,69 @@ func parseDumpConfig(ctx *cli.Context, stack *node.Node) (*state.DumpConfig, eth
 	return conf, db, header.Root[types.QuaiNetworkContext], nil
 }
 
+// parseTdRange parses the optional block range arguments of the td commands.
+func parseTdRange(ctx *cli.Context, hc *core.HeaderChain) (uint64, uint64) {
+	if len(ctx.Args()) > 2 {
+		utils.Fatalf("This command takes at most two arguments.")
+	}
+	first, last := uint64(0), hc.CurrentHeader().Number[types.QuaiNetworkContext].Uint64()
+	var err error
+	if len(ctx.Args()) > 0 {
+		if first, err = strconv.ParseUint(ctx.Args().Get(0), 10, 64); err != nil {
+			utils.Fatalf("Invalid first block number: %v", err)
+		}
+	}
+	if len(ctx.Args()) > 1 {
+		if last, err = strconv.ParseUint(ctx.Args().Get(1), 10, 64); err != nil {
+			utils.Fatalf("Invalid last block number: %v", err)
+		}
+	}
+	if first > last {
+		utils.Fatalf("First block #%d after last block #%d", first, last)
+	}
+	return first, last
+}
+
+func checkTd(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	hc, db := utils.MakeHeaderChain(ctx, stack)
+	defer db.Close()
+
+	first, last := parseTdRange(ctx, hc)
+	start := time.Now()
+	issues, err := hc.CheckTd(first, last)
+	for _, issue := range issues {
+		fmt.Printf("#%d [%x]: %v\n", issue.Number, issue.Hash, issue.Err)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Checked blocks #%d-#%d in %v, %d issues found.\n", first, last, common.PrettyDuration(time.Since(start)), len(issues))
+	if len(issues) > 0 {
+		return fmt.Errorf("%d total difficulties missing or malformed", len(issues))
+	}
+	return nil
+}
+
+func repairTd(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	hc, db := utils.MakeHeaderChain(ctx, stack)
+	defer db.Close()
+
+	first, last := parseTdRange(ctx, hc)
+	start := time.Now()
+	repaired, err := hc.RepairTd(first, last)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Repaired %d total difficulties of blocks #%d-#%d in %v.\n", repaired, first, last, common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
 func dump(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
 	defer stack.Close()
@@ -179,6 +179,49 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, backend, cfg.Ethstats.URL)
 	}
+	// Add the release checker if requested.
+	if ctx.GlobalIsSet(utils.ReleaseURLFlag.Name) {
+		utils.RegisterReleaseService(ctx, stack, backend, eth)
+	}
+	// Add the orphan indexer if requested.
+	if ctx.GlobalBool(utils.OrphanStatsFlag.Name) {
+		utils.RegisterOrphanStatsService(ctx, stack, backend)
+	}
+	// Add the block attestation service if requested.
+	if ctx.GlobalBool(utils.AttestFlag.Name) {
+		utils.RegisterAttestationService(ctx, stack, backend)
+	}
+	// Add the extra-data indexer if requested.
+	if ctx.GlobalBool(utils.ExtraIndexFlag.Name) {
+		utils.RegisterExtraIndexService(ctx, stack, backend)
+	}
+	// Add the contract metadata store if requested.
+	if ctx.GlobalIsSet(utils.ContractMetadataFlag.Name) {
+		if eth == nil {
+			utils.Fatalf("Contract metadata decoding does not work in light client mode.")
+		}
+		utils.RegisterContractMetadataService(ctx, stack, eth)
+	}
+	// Add the address label registry.
+	utils.RegisterAddressLabelService(stack, eth)
+	// Add the token indexer if requested.
+	if ctx.GlobalBool(utils.TokenIndexFlag.Name) {
+		utils.RegisterTokenIndexService(stack, backend)
+	}
+	// Add the transaction rebroadcaster if requested.
+	if ctx.GlobalBool(utils.RebroadcastFlag.Name) {
+		if eth == nil {
+			utils.Fatalf("Transaction rebroadcasting does not work in light client mode.")
+		}
+		utils.RegisterRebroadcastService(ctx, stack, eth)
+	}
+	// Load the plugins last, once the services of the node are registered.
+	if ctx.GlobalIsSet(utils.PluginsFlag.Name) {
+		if eth == nil {
+			utils.Fatalf("Plugins do not work in light client mode.")
+		}
+		utils.RegisterPlugins(ctx, stack, eth)
+	}
 	return stack, backend
 }
 
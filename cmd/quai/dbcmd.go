@@ -59,6 +59,7 @@ Remove blockchain and state databases`,
 			dbInspectCmd,
 			dbStatCmd,
 			dbCompactCmd,
+			dbCompressCmd,
 			dbGetCmd,
 			dbDeleteCmd,
 			dbPutCmd,
@@ -105,6 +106,28 @@ Remove blockchain and state databases`,
 		Description: `This command performs a database compaction. 
 WARNING: This operation may take a very long time to finish, and may cause database
 corruption if it is aborted during execution'!`,
+	}
+	dbDecompressFlag = cli.BoolFlag{
+		Name:  "decompress",
+		Usage: "Store the block bodies and receipts uncompressed instead",
+	}
+	dbCompressCmd = cli.Command{
+		Action: utils.MigrateFlags(dbCompress),
+		Name:   "compress",
+		Usage:  "Compress the block bodies and receipts in the key-value store",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.SyncModeFlag,
+			utils.MainnetFlag,
+			utils.RopstenFlag,
+			utils.CacheFlag,
+			utils.CacheDatabaseFlag,
+			dbDecompressFlag,
+		},
+		Description: `This command rewrites the block bodies and receipts in the key-value store
+compressed, or uncompressed if --decompress is given. Run it with the node stopped, then
+start the node with the matching --db.compress setting. Data in the freezer is always
+compressed and is not touched.`,
 	}
 	dbGetCmd = cli.Command{
 		Action:    utils.MigrateFlags(dbGet),
@@ -326,6 +349,25 @@ func dbCompact(ctx *cli.Context) error {
 	return nil
 }
 
+// dbCompress rewrites the block bodies and receipts in the key-value store
+// according to the requested compression.
+func dbCompress(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	rawdb.SetCompression(!ctx.Bool(dbDecompressFlag.Name))
+	count, before, after, err := rawdb.RecompressChainData(db)
+	if err != nil {
+		log.Error("Failed to rewrite chain data", "rewritten", count, "err", err)
+		return err
+	}
+	log.Info("Rewrote chain data", "compressed", !ctx.Bool(dbDecompressFlag.Name), "items", count, "before", before, "after", after)
+	return nil
+}
+
 // dbGet shows the value of a given database key
 func dbGet(ctx *cli.Context) error {
 	if ctx.NArg() != 1 {
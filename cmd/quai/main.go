@@ -79,10 +79,18 @@ var (
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
 		utils.SyncModeFlag,
+		utils.CheckpointAnchorFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
+		utils.SnapServeCapacityFlag,
+		utils.SnapServeQuotaFlag,
 		utils.TxLookupLimitFlag,
+		utils.MaxReorgDepthFlag,
+		utils.FutureBlocksFlag,
+		utils.FutureBlockHorizonFlag,
+		utils.InvariantCheckFlag,
+		utils.InternalTxIndexFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -95,17 +103,21 @@ var (
 		utils.LightNoSyncServeFlag,
 		utils.WhitelistFlag,
 		utils.BloomFilterSizeFlag,
+		utils.PruneCoincidentsFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
+		utils.DBCompressFlag,
 		utils.CacheTrieFlag,
 		utils.CacheTrieJournalFlag,
 		utils.CacheTrieRejournalFlag,
 		utils.CacheGCFlag,
 		utils.CacheSnapshotFlag,
+		utils.CachePCRCFlag,
 		utils.CacheNoPrefetchFlag,
 		utils.CachePreimagesFlag,
 		utils.ListenPortFlag,
 		utils.MaxPeersFlag,
+		utils.SyncMaxPeersFlag,
 		utils.MaxPendingPeersFlag,
 		utils.MiningEnabledFlag,
 		utils.MinerThreadsFlag,
@@ -115,12 +127,19 @@ var (
 		utils.MinerGasPriceFlag,
 		utils.MinerEtherbaseFlag,
 		utils.MinerExtraDataFlag,
+		utils.MinerExtraTemplateFlag,
+		utils.MinerExtraPoolFlag,
+		utils.MinerExtraWorkerFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerifyFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.ListenFamilyFlag,
+		utils.DialPolicyFlag,
+		utils.ProxyFlag,
+		utils.MessageTraceFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DNSDiscoveryFlag,
@@ -131,6 +150,25 @@ var (
 		utils.VMEnableDebugFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
+		utils.ReleaseURLFlag,
+		utils.ReleasePubKeysFlag,
+		utils.ReleaseIntervalFlag,
+		utils.ReleaseRefuseForkFlag,
+		utils.OrphanStatsFlag,
+		utils.OrphanStatsWindowFlag,
+		utils.OrphanStatsDepthFlag,
+		utils.AttestFlag,
+		utils.AttestMaxConfirmationsFlag,
+		utils.ExtraIndexFlag,
+		utils.ExtraIndexWindowFlag,
+		utils.ContractMetadataFlag,
+		utils.TokenIndexFlag,
+		utils.RebroadcastFlag,
+		utils.RebroadcastIntervalFlag,
+		utils.RebroadcastBumpAfterFlag,
+		utils.RebroadcastBumpPercentFlag,
+		utils.RebroadcastMaxGasPriceFlag,
+		utils.PluginsFlag,
 		utils.FakePoWFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
@@ -138,6 +176,23 @@ var (
 		utils.GpoMaxGasPriceFlag,
 		utils.GpoIgnoreGasPriceFlag,
 		utils.MinerNotifyFullFlag,
+		utils.MinerStratumFlag,
+		utils.MinerStratumDifficultyFlag,
+		utils.MinerThreadsAffinityFlag,
+		utils.MinerDutyCycleFlag,
+		utils.MinerImportPauseFlag,
+		utils.MinerPolicyFlag,
+		utils.MinerOrderingFlag,
+		utils.MinerNoSelfCheckFlag,
+		utils.MinerShareChainFlag,
+		utils.MinerShareRatioFlag,
+		utils.MinerShareWindowFlag,
+		utils.MinerTiebreakFlag,
+		utils.MinerTiebreakProbabilityFlag,
+		utils.MinerElectionURLFlag,
+		utils.MinerElectionKeyFlag,
+		utils.MinerElectionIDFlag,
+		utils.MinerElectionTTLFlag,
 		configFileFlag,
 		utils.CatalystFlag,
 	}
@@ -165,10 +220,24 @@ var (
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalTxFeeCapFlag,
 		utils.AllowUnprotectedTxs,
+		utils.RPCSlowThresholdFlag,
+		utils.RPCAuditLogFlag,
+		utils.RPCAuditLogMaxSizeFlag,
+		utils.RPCAuditLogBackupsFlag,
 		utils.RegionFlag,
 		utils.ZoneFlag,
 		utils.DomUrl,
 		utils.SubUrls,
+		utils.TxForwardFlag,
+		utils.ClientTLSCAFlag,
+		utils.ClientTLSCertFlag,
+		utils.ClientTLSKeyFlag,
+		utils.ClientKeepAliveFlag,
+		utils.ClientIdleConnsFlag,
+		utils.ClientIdleTimeoutFlag,
+		utils.ClientCallTimeoutFlag,
+		utils.ClientCallRetriesFlag,
+		utils.ClientHedgeDelayFlag,
 	}
 
 	metricsFlags = []cli.Flag{
@@ -201,12 +270,17 @@ func init() {
 		exportCommand,
 		importPreimagesCommand,
 		exportPreimagesCommand,
+		importSyncCommand,
+		exportSyncCommand,
 		removedbCommand,
 		dumpCommand,
+		checkTdCommand,
+		repairTdCommand,
 		dumpGenesisCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
+		signTxCommand,
 		// See consolecmd.go:
 		consoleCommand,
 		attachCommand,
@@ -225,6 +299,10 @@ func init() {
 		utils.ShowDeprecated,
 		// See snapshot.go
 		snapshotCommand,
+		// See benchcmd.go
+		benchCommand,
+		// See analyzecmd.go
+		analyzeStateCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/accounts/keystore"
+	"github.com/spruce-solutions/go-quai/cmd/utils"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/params"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	signTxChainIDFlag = cli.Uint64Flag{
+		Name:  "chainid",
+		Usage: "Chain ID of the context the transaction is sent in (overrides the transaction file)",
+	}
+	signTxCommand = cli.Command{
+		Action:    utils.MigrateFlags(signTx),
+		Name:      "sign-tx",
+		Usage:     "Sign a JSON transaction offline",
+		ArgsUsage: "<keyfile> <txfile>",
+		Category:  "ACCOUNT COMMANDS",
+		Flags: []cli.Flag{
+			utils.PasswordFileFlag,
+			signTxChainIDFlag,
+		},
+		Description: `
+Signs the transaction described by a JSON file with the key of an encrypted
+keyfile, without connecting to a node. The signed transaction is printed as
+raw RLP, ready to be submitted with quai_sendRawTransaction.
+
+The transaction file holds the fields of a transaction request:
+  {"to", "nonce", "gas", "gasPrice", "maxFeePerGas", "maxPriorityFeePerGas",
+   "value", "input", "accessList", "validUntil", "chainId"}
+A gasPrice produces a legacy (or access list) transaction, the fee fields a
+dynamic fee one, and validUntil an expiring one.
+
+The sender has to be operable in the context of the chain ID. A destination
+outside of that context turns the transaction into an external one, which is
+reported as a warning.`,
+	}
+)
+
+// signTxSpec is the JSON description of a transaction to sign.
+type signTxSpec struct {
+	To                   *common.Address   `json:"to"`
+	Nonce                *hexutil.Uint64   `json:"nonce"`
+	Gas                  *hexutil.Uint64   `json:"gas"`
+	GasPrice             *hexutil.Big      `json:"gasPrice"`
+	MaxFeePerGas         *hexutil.Big      `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big      `json:"maxPriorityFeePerGas"`
+	Value                *hexutil.Big      `json:"value"`
+	Data                 *hexutil.Bytes    `json:"data"`
+	Input                *hexutil.Bytes    `json:"input"`
+	AccessList           *types.AccessList `json:"accessList"`
+	ValidUntil           *hexutil.Uint64   `json:"validUntil"`
+	ChainID              *hexutil.Big      `json:"chainId"`
+}
+
+// txData assembles the transaction described by the spec.
+func (spec *signTxSpec) txData(chainID *big.Int) (types.TxData, error) {
+	if spec.Nonce == nil {
+		return nil, fmt.Errorf("missing nonce")
+	}
+	if spec.Gas == nil {
+		return nil, fmt.Errorf("missing gas")
+	}
+	var (
+		value = new(big.Int)
+		data  []byte
+		al    types.AccessList
+	)
+	if spec.Value != nil {
+		value = spec.Value.ToInt()
+	}
+	if spec.Input != nil {
+		data = *spec.Input
+	} else if spec.Data != nil {
+		data = *spec.Data
+	}
+	if spec.AccessList != nil {
+		al = *spec.AccessList
+	}
+	dynamic := spec.MaxFeePerGas != nil || spec.MaxPriorityFeePerGas != nil
+	switch {
+	case spec.GasPrice != nil && (dynamic || spec.ValidUntil != nil):
+		return nil, fmt.Errorf("gasPrice specified along with maxFeePerGas, maxPriorityFeePerGas or validUntil")
+	case spec.GasPrice == nil && (spec.MaxFeePerGas == nil || spec.MaxPriorityFeePerGas == nil):
+		return nil, fmt.Errorf("missing gasPrice or maxFeePerGas and maxPriorityFeePerGas")
+	case spec.ValidUntil != nil:
+		return &types.ExpiringTx{ChainID: chainID, Nonce: uint64(*spec.Nonce), GasTipCap: spec.MaxPriorityFeePerGas.ToInt(), GasFeeCap: spec.MaxFeePerGas.ToInt(), Gas: uint64(*spec.Gas), To: spec.To, Value: value, Data: data, AccessList: al, ValidUntil: uint64(*spec.ValidUntil)}, nil
+	case dynamic:
+		return &types.DynamicFeeTx{ChainID: chainID, Nonce: uint64(*spec.Nonce), GasTipCap: spec.MaxPriorityFeePerGas.ToInt(), GasFeeCap: spec.MaxFeePerGas.ToInt(), Gas: uint64(*spec.Gas), To: spec.To, Value: value, Data: data, AccessList: al}, nil
+	case spec.AccessList != nil:
+		return &types.AccessListTx{ChainID: chainID, Nonce: uint64(*spec.Nonce), GasPrice: spec.GasPrice.ToInt(), Gas: uint64(*spec.Gas), To: spec.To, Value: value, Data: data, AccessList: al}, nil
+	default:
+		return &types.LegacyTx{Nonce: uint64(*spec.Nonce), GasPrice: spec.GasPrice.ToInt(), Gas: uint64(*spec.Gas), To: spec.To, Value: value, Data: data}, nil
+	}
+}
+
+// inContext reports whether an address belongs to the context of a prefix range.
+func inContext(addr common.Address, idRange []int) bool {
+	prefix := int(addr.Bytes()[0])
+	return prefix >= idRange[0] && prefix <= idRange[1]
+}
+
+// signTx signs a JSON transaction with a keyfile and prints the raw transaction.
+func signTx(ctx *cli.Context) error {
+	if len(ctx.Args()) != 2 {
+		utils.Fatalf("This command requires a keyfile and a transaction file.")
+	}
+	keyfile, txfile := ctx.Args().Get(0), ctx.Args().Get(1)
+
+	blob, err := ioutil.ReadFile(txfile)
+	if err != nil {
+		utils.Fatalf("Failed to read the transaction file at '%s': %v", txfile, err)
+	}
+	spec := new(signTxSpec)
+	if err := json.Unmarshal(blob, spec); err != nil {
+		utils.Fatalf("Invalid transaction file '%s': %v", txfile, err)
+	}
+	var chainID *big.Int
+	switch {
+	case ctx.IsSet(signTxChainIDFlag.Name):
+		chainID = new(big.Int).SetUint64(ctx.Uint64(signTxChainIDFlag.Name))
+	case spec.ChainID != nil:
+		chainID = spec.ChainID.ToInt()
+	default:
+		utils.Fatalf("Missing chain ID, use --%s or set chainId in the transaction file", signTxChainIDFlag.Name)
+	}
+	idRange := params.LookupChainIDRange(chainID)
+	if len(idRange) != 2 {
+		utils.Fatalf("Unknown chain ID %v", chainID)
+	}
+	data, err := spec.txData(chainID)
+	if err != nil {
+		utils.Fatalf("Invalid transaction: %v", err)
+	}
+	// Decrypt the key and validate the contexts of the participants
+	keyjson, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		utils.Fatalf("Failed to read the keyfile at '%s': %v", keyfile, err)
+	}
+	passphrase := utils.GetPassPhraseWithList("", false, 0, utils.MakePasswordList(ctx))
+	key, err := keystore.DecryptKey(keyjson, passphrase)
+	if err != nil {
+		utils.Fatalf("Error decrypting key: %v", err)
+	}
+	if !inContext(key.Address, idRange) {
+		utils.Fatalf("Sender %s is not operable on chain %v", key.Address.Hex(), chainID)
+	}
+	if spec.To != nil && !inContext(*spec.To, idRange) {
+		log.Warn("Destination outside of the chain's context, sending an external transaction", "to", spec.To.Hex(), "chainid", chainID)
+	}
+	tx, err := types.SignNewTx(key.PrivateKey, types.NewLondonSigner(chainID), data)
+	if err != nil {
+		utils.Fatalf("Failed to sign transaction: %v", err)
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		utils.Fatalf("Failed to encode transaction: %v", err)
+	}
+	fmt.Println(hexutil.Encode(raw))
+	return nil
+}
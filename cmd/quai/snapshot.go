@@ -64,6 +64,7 @@ var (
 					utils.RopstenFlag,
 					utils.CacheTrieJournalFlag,
 					utils.BloomFilterSizeFlag,
+					utils.PruneCoincidentsFlag,
 				},
 				Description: `
 geth snapshot prune-state <state-root>
@@ -72,7 +73,10 @@ All trie nodes and contract codes that do not belong to the specified
 version state will be deleted from the database. After pruning, only
 two version states are available: genesis and the specific one.
 
-The default pruning target is the HEAD-127 state.
+The default pruning target is the HEAD-127 state. With "--prune.coincidents K"
+the states of the latest K blocks coincident with Prime are kept as well, which
+region and zone nodes flush to disk while importing. Reorgs never go deeper than
+them.
 
 WARNING: It's necessary to delete the trie clean cache after the pruning.
 If you specify another directory for the trie clean cache via "--cache.trie.journal"
@@ -171,7 +175,7 @@ func pruneState(ctx *cli.Context) error {
 	defer stack.Close()
 
 	chaindb := utils.MakeChainDatabase(ctx, stack, false)
-	pruner, err := pruner.NewPruner(chaindb, stack.ResolvePath(""), stack.ResolvePath(config.Eth.TrieCleanCacheJournal), ctx.GlobalUint64(utils.BloomFilterSizeFlag.Name))
+	pruner, err := pruner.NewPruner(chaindb, stack.ResolvePath(""), stack.ResolvePath(config.Eth.TrieCleanCacheJournal), ctx.GlobalUint64(utils.BloomFilterSizeFlag.Name), ctx.GlobalInt(utils.PruneCoincidentsFlag.Name))
 	if err != nil {
 		log.Error("Failed to open snapshot tree", "err", err)
 		return err
@@ -44,10 +44,37 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MainnetFlag,
 			utils.RopstenFlag,
 			utils.SyncModeFlag,
+			utils.CheckpointAnchorFlag,
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
 			utils.TxLookupLimitFlag,
+			utils.MaxReorgDepthFlag,
+			utils.FutureBlocksFlag,
+			utils.FutureBlockHorizonFlag,
+			utils.InvariantCheckFlag,
+			utils.SnapServeCapacityFlag,
+			utils.SnapServeQuotaFlag,
+			utils.InternalTxIndexFlag,
 			utils.EthStatsURLFlag,
+			utils.ReleaseURLFlag,
+			utils.ReleasePubKeysFlag,
+			utils.ReleaseIntervalFlag,
+			utils.ReleaseRefuseForkFlag,
+			utils.OrphanStatsFlag,
+			utils.OrphanStatsWindowFlag,
+			utils.OrphanStatsDepthFlag,
+			utils.AttestFlag,
+			utils.AttestMaxConfirmationsFlag,
+			utils.ExtraIndexFlag,
+			utils.ExtraIndexWindowFlag,
+			utils.ContractMetadataFlag,
+			utils.TokenIndexFlag,
+			utils.RebroadcastFlag,
+			utils.RebroadcastIntervalFlag,
+			utils.RebroadcastBumpAfterFlag,
+			utils.RebroadcastBumpPercentFlag,
+			utils.RebroadcastMaxGasPriceFlag,
+			utils.PluginsFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
 			utils.WhitelistFlag,
@@ -95,11 +122,13 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Flags: []cli.Flag{
 			utils.CacheFlag,
 			utils.CacheDatabaseFlag,
+			utils.DBCompressFlag,
 			utils.CacheTrieFlag,
 			utils.CacheTrieJournalFlag,
 			utils.CacheTrieRejournalFlag,
 			utils.CacheGCFlag,
 			utils.CacheSnapshotFlag,
+			utils.CachePCRCFlag,
 			utils.CacheNoPrefetchFlag,
 			utils.CachePreimagesFlag,
 		},
@@ -137,11 +166,25 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalTxFeeCapFlag,
 			utils.AllowUnprotectedTxs,
+			utils.RPCSlowThresholdFlag,
+			utils.RPCAuditLogFlag,
+			utils.RPCAuditLogMaxSizeFlag,
+			utils.RPCAuditLogBackupsFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
 			utils.DomUrl,
 			utils.SubUrls,
+			utils.TxForwardFlag,
+			utils.ClientTLSCAFlag,
+			utils.ClientTLSCertFlag,
+			utils.ClientTLSKeyFlag,
+			utils.ClientKeepAliveFlag,
+			utils.ClientIdleConnsFlag,
+			utils.ClientIdleTimeoutFlag,
+			utils.ClientCallTimeoutFlag,
+			utils.ClientCallRetriesFlag,
+			utils.ClientHedgeDelayFlag,
 		},
 	},
 	{
@@ -151,11 +194,16 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.DNSDiscoveryFlag,
 			utils.ListenPortFlag,
 			utils.MaxPeersFlag,
+			utils.SyncMaxPeersFlag,
 			utils.MaxPendingPeersFlag,
 			utils.NATFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
+			utils.ListenFamilyFlag,
+			utils.DialPolicyFlag,
+			utils.ProxyFlag,
+			utils.MessageTraceFlag,
 			utils.NodeKeyFileFlag,
 			utils.NodeKeyHexFlag,
 		},
@@ -167,10 +215,30 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerThreadsFlag,
 			utils.MinerNotifyFlag,
 			utils.MinerNotifyFullFlag,
+			utils.MinerStratumFlag,
+			utils.MinerStratumDifficultyFlag,
+			utils.MinerThreadsAffinityFlag,
+			utils.MinerDutyCycleFlag,
+			utils.MinerImportPauseFlag,
+			utils.MinerPolicyFlag,
+			utils.MinerOrderingFlag,
+			utils.MinerNoSelfCheckFlag,
+			utils.MinerShareChainFlag,
+			utils.MinerShareRatioFlag,
+			utils.MinerShareWindowFlag,
+			utils.MinerTiebreakFlag,
+			utils.MinerTiebreakProbabilityFlag,
+			utils.MinerElectionURLFlag,
+			utils.MinerElectionKeyFlag,
+			utils.MinerElectionIDFlag,
+			utils.MinerElectionTTLFlag,
 			utils.MinerGasPriceFlag,
 			utils.MinerGasLimitFlag,
 			utils.MinerEtherbaseFlag,
 			utils.MinerExtraDataFlag,
+			utils.MinerExtraTemplateFlag,
+			utils.MinerExtraPoolFlag,
+			utils.MinerExtraWorkerFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerifyFlag,
 		},
@@ -212,6 +280,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 		Flags: []cli.Flag{
 			utils.SnapshotFlag,
 			utils.BloomFilterSizeFlag,
+			utils.PruneCoincidentsFlag,
 			cli.HelpFlag,
 			utils.CatalystFlag,
 		},
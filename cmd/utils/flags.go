@@ -37,11 +37,14 @@ import (
 	gopsutil "github.com/shirou/gopsutil/mem"
 	"github.com/spruce-solutions/go-quai/accounts"
 	"github.com/spruce-solutions/go-quai/accounts/keystore"
+	"github.com/spruce-solutions/go-quai/attest"
+	"github.com/spruce-solutions/go-quai/attribution"
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/common/fdlimit"
 	"github.com/spruce-solutions/go-quai/consensus"
 	"github.com/spruce-solutions/go-quai/consensus/blake3"
 	"github.com/spruce-solutions/go-quai/consensus/clique"
+	"github.com/spruce-solutions/go-quai/contractmeta"
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/rawdb"
 	"github.com/spruce-solutions/go-quai/core/types"
@@ -52,23 +55,31 @@ import (
 	"github.com/spruce-solutions/go-quai/eth/ethconfig"
 	"github.com/spruce-solutions/go-quai/eth/gasprice"
 	"github.com/spruce-solutions/go-quai/eth/tracers"
+	"github.com/spruce-solutions/go-quai/ethclient/quaiclient"
 	"github.com/spruce-solutions/go-quai/ethdb"
 	"github.com/spruce-solutions/go-quai/ethstats"
 	"github.com/spruce-solutions/go-quai/graphql"
 	"github.com/spruce-solutions/go-quai/internal/ethapi"
 	"github.com/spruce-solutions/go-quai/internal/flags"
+	"github.com/spruce-solutions/go-quai/labels"
 	"github.com/spruce-solutions/go-quai/les"
 	"github.com/spruce-solutions/go-quai/log"
 	"github.com/spruce-solutions/go-quai/metrics"
 	"github.com/spruce-solutions/go-quai/metrics/exp"
 	"github.com/spruce-solutions/go-quai/metrics/influxdb"
 	"github.com/spruce-solutions/go-quai/miner"
+	"github.com/spruce-solutions/go-quai/miner/sharechain"
 	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/orphans"
 	"github.com/spruce-solutions/go-quai/p2p"
 	"github.com/spruce-solutions/go-quai/p2p/enode"
 	"github.com/spruce-solutions/go-quai/p2p/nat"
 	"github.com/spruce-solutions/go-quai/p2p/netutil"
 	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/plugins"
+	"github.com/spruce-solutions/go-quai/rebroadcast"
+	"github.com/spruce-solutions/go-quai/release"
+	"github.com/spruce-solutions/go-quai/tokens"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -198,9 +209,13 @@ var (
 	defaultSyncMode = ethconfig.Defaults.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", "snap" or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "snap", "light" or "checkpoint")`,
 		Value: &defaultSyncMode,
 	}
+	CheckpointAnchorFlag = cli.StringFlag{
+		Name:  "checkpoint.anchor",
+		Usage: "Hash of the trusted Prime block a zone node syncs forward from in checkpoint sync mode",
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
@@ -215,6 +230,40 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	MaxReorgDepthFlag = cli.Uint64Flag{
+		Name:  "maxreorgdepth",
+		Usage: "Depth of the zone reorgs refused unless the new head is anchored by a dominant chain block (0 = unlimited)",
+		Value: ethconfig.Defaults.MaxReorgDepth,
+	}
+	FutureBlocksFlag = cli.IntFlag{
+		Name:  "futureblocks",
+		Usage: "Number of blocks dated ahead of the local clock or missing their ancestors queued for later import (0 = default)",
+		Value: ethconfig.Defaults.FutureBlocks,
+	}
+	FutureBlockHorizonFlag = cli.DurationFlag{
+		Name:  "futureblocks.horizon",
+		Usage: "Maximum time a queued block may be dated ahead of the local clock (0 = default)",
+		Value: ethconfig.Defaults.FutureBlockHorizon,
+	}
+	InvariantCheckFlag = cli.DurationFlag{
+		Name:  "invariantcheck",
+		Usage: "Interval between the checks of the database invariants of the imported blocks, for soak testing (0 = disabled)",
+		Value: ethconfig.Defaults.InvariantCheckInterval,
+	}
+	SnapServeCapacityFlag = cli.Uint64Flag{
+		Name:  "snap.servecapacity",
+		Usage: "Outgoing bandwidth limit for serving snapshot data to all syncing peers (kilobytes/sec, 0 = unlimited)",
+		Value: ethconfig.Defaults.SnapServe.Capacity / 1024,
+	}
+	SnapServeQuotaFlag = cli.Uint64Flag{
+		Name:  "snap.servequota",
+		Usage: "Outgoing bandwidth limit for serving snapshot data to a single syncing peer (kilobytes/sec, 0 = unlimited)",
+		Value: ethconfig.Defaults.SnapServe.PeerQuota / 1024,
+	}
+	InternalTxIndexFlag = cli.BoolTFlag{
+		Name:  "internaltxindex",
+		Usage: `Enables indexing the value transfers of internal calls during import (default = enable)`,
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -223,11 +272,19 @@ var (
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>)",
 	}
+	TxForwardFlag = cli.StringFlag{
+		Name:  "txforward",
+		Usage: "Comma separated chain ID-to-URL mappings of the nodes transactions submitted for other locations are forwarded to (<chainid>=<url>)",
+	}
 	BloomFilterSizeFlag = cli.Uint64Flag{
 		Name:  "bloomfilter.size",
 		Usage: "Megabytes of memory allocated to bloom-filter for pruning",
 		Value: 2048,
 	}
+	PruneCoincidentsFlag = cli.IntFlag{
+		Name:  "prune.coincidents",
+		Usage: "Number of the latest Prime coincident states kept when pruning (0 = only the target state)",
+	}
 	OverrideLondonFlag = cli.Uint64Flag{
 		Name:  "override.london",
 		Usage: "Manually specify London fork-block, overriding the bundled setting",
@@ -340,6 +397,10 @@ var (
 		Usage: "Percentage of cache memory allowance to use for database io",
 		Value: 50,
 	}
+	DBCompressFlag = cli.BoolFlag{
+		Name:  "db.compress",
+		Usage: "Compress newly written block bodies and receipts in the key-value store (use 'quai db compress' to convert existing data)",
+	}
 	CacheTrieFlag = cli.IntFlag{
 		Name:  "cache.trie",
 		Usage: "Percentage of cache memory allowance to use for trie caching (default = 15% full mode, 30% archive mode)",
@@ -370,6 +431,11 @@ var (
 		Usage: "Percentage of cache memory allowance to use for snapshot caching (default = 10% full mode, 20% archive mode)",
 		Value: 10,
 	}
+	CachePCRCFlag = cli.IntFlag{
+		Name:  "cache.pcrc",
+		Usage: "Number of PCRC results cached, purged on reorgs",
+		Value: ethconfig.Defaults.PCRCCache,
+	}
 	CacheNoPrefetchFlag = cli.BoolFlag{
 		Name:  "cache.noprefetch",
 		Usage: "Disable heuristic state prefetch during block import (less CPU and disk IO, more time waiting for data)",
@@ -388,6 +454,72 @@ var (
 		Usage: "Number of CPU threads to use for mining",
 		Value: 0,
 	}
+	MinerThreadsAffinityFlag = cli.StringFlag{
+		Name:  "miner.threads-affinity",
+		Usage: "Comma separated list of CPUs to pin the CPU mining threads to",
+	}
+	MinerDutyCycleFlag = cli.IntFlag{
+		Name:  "miner.dutycycle",
+		Usage: "Percentage of time the CPU mining threads spend hashing (0 = unthrottled)",
+	}
+	MinerImportPauseFlag = cli.DurationFlag{
+		Name:  "miner.importpause",
+		Usage: "Time CPU mining is paused for during block import spikes (0 = disabled)",
+	}
+	MinerPolicyFlag = cli.StringFlag{
+		Name:  "miner.policy",
+		Usage: "JSON file with the local transaction policy applied to mined blocks",
+	}
+	MinerOrderingFlag = cli.StringFlag{
+		Name:  "miner.ordering",
+		Usage: "Ordering of the transactions in mined blocks (tip, fifo, fair or a Go plugin .so file)",
+		Value: miner.OrderingTip,
+	}
+	MinerNoSelfCheckFlag = cli.BoolFlag{
+		Name:  "miner.noselfcheck",
+		Usage: "Skip validating candidate blocks as block import would before sealing them",
+	}
+	MinerShareChainFlag = cli.BoolFlag{
+		Name:  "miner.sharechain",
+		Usage: "Mine in a decentralized pool, computing the split of block rewards over a share chain (settled by the block miners)",
+	}
+	MinerShareRatioFlag = cli.Uint64Flag{
+		Name:  "miner.sharechain.ratio",
+		Usage: "Ratio of the block difficulty to the share difficulty of the pool",
+		Value: sharechain.DefaultConfig.Ratio,
+	}
+	MinerShareWindowFlag = cli.IntFlag{
+		Name:  "miner.sharechain.window",
+		Usage: "Number of last shares the block rewards of the pool are split over",
+		Value: sharechain.DefaultConfig.Window,
+	}
+	MinerTiebreakFlag = cli.StringFlag{
+		Name:  "miner.tiebreak",
+		Usage: "Choice between heads of equal difficulty (random, keep-local, adopt, first-seen)",
+		Value: string(ethconfig.Defaults.Tiebreak),
+	}
+	MinerTiebreakProbabilityFlag = cli.Float64Flag{
+		Name:  "miner.tiebreak.probability",
+		Usage: "Probability of adopting the extern head of equal difficulty under the random tiebreak",
+		Value: ethconfig.Defaults.TiebreakProbability,
+	}
+	MinerElectionURLFlag = cli.StringFlag{
+		Name:  "miner.election",
+		Usage: "Lease store electing the active block producer among redundant miners (redis://[:password@]host:port[/db])",
+	}
+	MinerElectionKeyFlag = cli.StringFlag{
+		Name:  "miner.election.key",
+		Usage: "Key of the block producer lease shared by the redundant miners (default = per chain id)",
+	}
+	MinerElectionIDFlag = cli.StringFlag{
+		Name:  "miner.election.id",
+		Usage: "Id this miner holds the block producer lease under (default = hostname and pid)",
+	}
+	MinerElectionTTLFlag = cli.DurationFlag{
+		Name:  "miner.election.ttl",
+		Usage: "Time the block producer lease is held without renewal, bounding the failover time",
+		Value: 5 * time.Second,
+	}
 	MinerNotifyFlag = cli.StringFlag{
 		Name:  "miner.notify",
 		Usage: "Comma separated HTTP URL list to notify of new work packages",
@@ -396,6 +528,14 @@ var (
 		Name:  "miner.notify.full",
 		Usage: "Notify with pending block headers instead of work packages",
 	}
+	MinerStratumFlag = cli.StringFlag{
+		Name:  "miner.stratum",
+		Usage: "Listen address of the stratum server handing work to pools and mining devices (e.g. 0.0.0.0:3333)",
+	}
+	MinerStratumDifficultyFlag = cli.Uint64Flag{
+		Name:  "miner.stratum.difficulty",
+		Usage: "Difficulty of the shares accepted by the stratum server (0 = block solutions only)",
+	}
 	MinerGasLimitFlag = cli.Uint64Flag{
 		Name:  "miner.gaslimit",
 		Usage: "Target gas ceiling for mined blocks",
@@ -415,6 +555,18 @@ var (
 		Name:  "miner.extradata",
 		Usage: "Block extra data set by the miner (default = client version)",
 	}
+	MinerExtraTemplateFlag = cli.StringFlag{
+		Name:  "miner.extratemplate",
+		Usage: "Block extra data template with {pool}, {worker} and {version} variables (overrides --miner.extradata)",
+	}
+	MinerExtraPoolFlag = cli.StringFlag{
+		Name:  "miner.pool",
+		Usage: "Pool name substituted for {pool} in the extra data template",
+	}
+	MinerExtraWorkerFlag = cli.StringFlag{
+		Name:  "miner.worker",
+		Usage: "Worker id substituted for {worker} in the extra data template",
+	}
 	MinerRecommitIntervalFlag = cli.DurationFlag{
 		Name:  "miner.recommit",
 		Usage: "Time interval to recreate the block being mined",
@@ -463,6 +615,89 @@ var (
 		Name:  "quaistats",
 		Usage: "Reporting URL of a quaistats service (nodename:secret@host:port)",
 	}
+	ReleaseURLFlag = cli.StringFlag{
+		Name:  "release.url",
+		Usage: "URL of the signed release manifest to check for updates and unsupported forks (disabled if empty)",
+	}
+	ReleasePubKeysFlag = cli.StringFlag{
+		Name:  "release.pubkeys",
+		Usage: "Comma separated minisign public keys trusted to sign the release manifest",
+	}
+	ReleaseIntervalFlag = cli.DurationFlag{
+		Name:  "release.interval",
+		Usage: "Time between release manifest checks",
+		Value: time.Hour,
+	}
+	ReleaseRefuseForkFlag = cli.BoolFlag{
+		Name:  "release.refusefork",
+		Usage: "Refuse to mine blocks at or past a fork unsupported by this release",
+	}
+	OrphanStatsFlag = cli.BoolFlag{
+		Name:  "orphanstats",
+		Usage: "Enables the orphan indexer attributing orphaned blocks to their miners (quai_orphanStats)",
+	}
+	OrphanStatsWindowFlag = cli.Uint64Flag{
+		Name:  "orphanstats.window",
+		Usage: "Number of recent heights the orphan statistics span",
+		Value: orphans.DefaultWindow,
+	}
+	OrphanStatsDepthFlag = cli.Uint64Flag{
+		Name:  "orphanstats.depth",
+		Usage: "Confirmations after which a height is indexed by the orphan indexer",
+		Value: orphans.DefaultDepth,
+	}
+	AttestFlag = cli.BoolFlag{
+		Name:  "attest",
+		Usage: "Enables signing canonical block attestations with the node key (quai_getBlockAttestation)",
+	}
+	AttestMaxConfirmationsFlag = cli.Uint64Flag{
+		Name:  "attest.maxconfirmations",
+		Usage: "Number of prime confirmations after which attestations stop counting",
+		Value: attest.DefaultMaxConfirmations,
+	}
+	ExtraIndexFlag = cli.BoolFlag{
+		Name:  "extraindex",
+		Usage: "Enables the extra-data indexer attributing blocks to pools and workers (quai_extraDataStats)",
+	}
+	ExtraIndexWindowFlag = cli.Uint64Flag{
+		Name:  "extraindex.window",
+		Usage: "Number of recent canonical blocks the extra-data index spans",
+		Value: attribution.DefaultWindow,
+	}
+	ContractMetadataFlag = DirectoryFlag{
+		Name:  "contractmetadata",
+		Usage: "Directory of verified contract metadata used to decode traced calls and logs (metadata namespace)",
+	}
+	TokenIndexFlag = cli.BoolFlag{
+		Name:  "tokenindex",
+		Usage: "Enables the ERC-20/721 transfer indexer (quai_getTokenBalances, quai_getTokenTransfers)",
+	}
+	RebroadcastFlag = cli.BoolFlag{
+		Name:  "rebroadcast",
+		Usage: "Enables periodically rebroadcasting the local pending transactions (txpool_subscribe rebroadcasts)",
+	}
+	RebroadcastIntervalFlag = cli.DurationFlag{
+		Name:  "rebroadcast.interval",
+		Usage: "Time between rebroadcasts of the local pending transactions",
+		Value: rebroadcast.DefaultInterval,
+	}
+	RebroadcastBumpAfterFlag = cli.DurationFlag{
+		Name:  "rebroadcast.bumpafter",
+		Usage: "Time a local transaction may be pending before it is replaced with a higher gas price (0 = never)",
+	}
+	RebroadcastBumpPercentFlag = cli.Uint64Flag{
+		Name:  "rebroadcast.bumppercent",
+		Usage: "Gas price increase in percent of the replacements of stuck local transactions",
+		Value: rebroadcast.DefaultBumpPercent,
+	}
+	RebroadcastMaxGasPriceFlag = BigFlag{
+		Name:  "rebroadcast.maxgasprice",
+		Usage: "Highest gas price or fee cap a replacement of a stuck local transaction may pay (0 = no limit)",
+	}
+	PluginsFlag = cli.StringFlag{
+		Name:  "plugins",
+		Usage: "Comma separated list of Go plugins (.so) extending the node with APIs, tracers and transaction filters",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -569,6 +804,25 @@ var (
 		Name:  "rpc.allow-unprotected-txs",
 		Usage: "Allow for unprotected (non EIP155 signed) transactions to be submitted via RPC",
 	}
+	RPCSlowThresholdFlag = cli.DurationFlag{
+		Name:  "rpc.slowthreshold",
+		Usage: "Serving time above which RPC requests are logged as slow (0 = disabled)",
+		Value: node.DefaultConfig.RPCSlowThreshold,
+	}
+	RPCAuditLogFlag = cli.StringFlag{
+		Name:  "rpc.auditlog",
+		Usage: "File the admin, debug, miner and personal RPC calls are audited to (empty = disabled)",
+	}
+	RPCAuditLogMaxSizeFlag = cli.IntFlag{
+		Name:  "rpc.auditlog.maxsize",
+		Usage: "Size in megabytes above which the RPC audit log is rotated (0 = never rotated)",
+		Value: node.DefaultConfig.RPCAuditLogMaxSize,
+	}
+	RPCAuditLogBackupsFlag = cli.IntFlag{
+		Name:  "rpc.auditlog.backups",
+		Usage: "Number of rotated RPC audit log files kept",
+		Value: node.DefaultConfig.RPCAuditLogBackups,
+	}
 
 	// Network Settings
 	MaxPeersFlag = cli.IntFlag{
@@ -576,6 +830,11 @@ var (
 		Usage: "Maximum number of network peers (network disabled if set to 0)",
 		Value: node.DefaultConfig.P2P.MaxPeers,
 	}
+	SyncMaxPeersFlag = cli.IntFlag{
+		Name:  "maxpeers.sync",
+		Usage: "Maximum number of network peers while syncing, lowered to --maxpeers once synced",
+		Value: ethconfig.Defaults.SyncMaxPeers,
+	}
 	MaxPendingPeersFlag = cli.IntFlag{
 		Name:  "maxpendpeers",
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
@@ -616,6 +875,24 @@ var (
 		Name:  "netrestrict",
 		Usage: "Restricts network communication to the given IP networks (CIDR masks)",
 	}
+	ListenFamilyFlag = cli.StringFlag{
+		Name:  "listen.family",
+		Usage: `IP address families to listen on ("dual", "ipv4" or "ipv6")`,
+		Value: "dual",
+	}
+	DialPolicyFlag = cli.StringFlag{
+		Name:  "dial.family",
+		Usage: `IP address family to dial for peers advertising both ("prefer-ipv4", "prefer-ipv6", "ipv4" or "ipv6")`,
+		Value: "prefer-ipv4",
+	}
+	ProxyFlag = cli.StringFlag{
+		Name:  "proxy",
+		Usage: "SOCKS5 proxy URL to dial peers and the dom/sub nodes through (e.g. socks5://127.0.0.1:9050 for Tor)",
+	}
+	MessageTraceFlag = DirectoryFlag{
+		Name:  "p2p.trace",
+		Usage: "Directory to record the messages exchanged with every peer to, for replay in tests",
+	}
 	DNSDiscoveryFlag = cli.StringFlag{
 		Name:  "discovery.dns",
 		Usage: "Sets DNS discovery entry points (use \"\" to disable DNS)",
@@ -748,7 +1025,7 @@ var (
 	}
 	DomUrl = cli.StringFlag{
 		Name:  "dom.url",
-		Usage: "Dominant chain websocket url",
+		Usage: "Dominant chain websocket url, comma separated urls of equivalent nodes are failed over between",
 		Value: ethconfig.Defaults.DomUrl,
 	}
 	SubUrls = cli.StringFlag{
@@ -756,6 +1033,44 @@ var (
 		Usage: "Subordinate chain websocket urls",
 		Value: ethconfig.Defaults.DomUrl,
 	}
+	ClientTLSCAFlag = cli.StringFlag{
+		Name:  "client.tls.ca",
+		Usage: "PEM file of the CA certificates verifying the dom/sub nodes (default: system roots)",
+	}
+	ClientTLSCertFlag = cli.StringFlag{
+		Name:  "client.tls.cert",
+		Usage: "PEM file of the client certificate authenticating to the dom/sub nodes",
+	}
+	ClientTLSKeyFlag = cli.StringFlag{
+		Name:  "client.tls.key",
+		Usage: "PEM file of the key of the client certificate",
+	}
+	ClientKeepAliveFlag = cli.DurationFlag{
+		Name:  "client.keepalive",
+		Usage: "TCP keepalive period of the dom/sub node connections (0 = OS default)",
+	}
+	ClientIdleConnsFlag = cli.IntFlag{
+		Name:  "client.idleconns",
+		Usage: "Idle HTTP connections kept open per dom/sub node (0 = default)",
+	}
+	ClientIdleTimeoutFlag = cli.DurationFlag{
+		Name:  "client.idletimeout",
+		Usage: "Time idle HTTP connections to the dom/sub nodes are kept open (0 = unlimited)",
+	}
+	ClientCallTimeoutFlag = cli.DurationFlag{
+		Name:  "client.calltimeout",
+		Usage: "Deadline of a single call attempt to the dom nodes (0 = unlimited)",
+		Value: ethconfig.Defaults.Client.CallTimeout,
+	}
+	ClientCallRetriesFlag = cli.IntFlag{
+		Name:  "client.callretries",
+		Usage: "Times a failed call to the dom nodes is retried",
+		Value: ethconfig.Defaults.Client.CallRetries,
+	}
+	ClientHedgeDelayFlag = cli.DurationFlag{
+		Name:  "client.hedgedelay",
+		Usage: "Delay after which dom lookups are also sent to the next healthy dom node (0 = disabled)",
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -930,6 +1245,18 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.GlobalBool(AllowUnprotectedTxs.Name)
 	}
+	if ctx.GlobalIsSet(RPCSlowThresholdFlag.Name) {
+		cfg.RPCSlowThreshold = ctx.GlobalDuration(RPCSlowThresholdFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAuditLogFlag.Name) {
+		cfg.RPCAuditLog = ctx.GlobalString(RPCAuditLogFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAuditLogMaxSizeFlag.Name) {
+		cfg.RPCAuditLogMaxSize = ctx.GlobalInt(RPCAuditLogMaxSizeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAuditLogBackupsFlag.Name) {
+		cfg.RPCAuditLogBackups = ctx.GlobalInt(RPCAuditLogBackupsFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -1077,6 +1404,60 @@ func setEtherbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *ethconfig.Config
 	}
 }
 
+// setTiebreak applies the fork choice between heads of equal difficulty.
+func setTiebreak(ctx *cli.Context, policy *core.TiebreakPolicy, probability *float64) {
+	if ctx.GlobalIsSet(MinerTiebreakFlag.Name) {
+		parsed, err := core.ParseTiebreakPolicy(ctx.GlobalString(MinerTiebreakFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", MinerTiebreakFlag.Name, err)
+		}
+		*policy = parsed
+	}
+	if ctx.GlobalIsSet(MinerTiebreakProbabilityFlag.Name) {
+		*probability = ctx.GlobalFloat64(MinerTiebreakProbabilityFlag.Name)
+	}
+	if *probability < 0 || *probability > 1 {
+		Fatalf("Option %q must be between 0 and 1", MinerTiebreakProbabilityFlag.Name)
+	}
+}
+
+// setClient applies the connection settings of the dom and sub node clients.
+func setClient(ctx *cli.Context, cfg *quaiclient.Config) {
+	if ctx.GlobalIsSet(ProxyFlag.Name) {
+		cfg.Proxy = ctx.GlobalString(ProxyFlag.Name)
+	}
+	if ctx.GlobalIsSet(ClientTLSCAFlag.Name) {
+		cfg.TLSCA = ctx.GlobalString(ClientTLSCAFlag.Name)
+	}
+	if ctx.GlobalIsSet(ClientTLSCertFlag.Name) {
+		cfg.TLSCert = ctx.GlobalString(ClientTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(ClientTLSKeyFlag.Name) {
+		cfg.TLSKey = ctx.GlobalString(ClientTLSKeyFlag.Name)
+	}
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		Fatalf("Options %q and %q must be set together", ClientTLSCertFlag.Name, ClientTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(ClientKeepAliveFlag.Name) {
+		cfg.KeepAlive = ctx.GlobalDuration(ClientKeepAliveFlag.Name)
+	}
+	if ctx.GlobalIsSet(ClientIdleConnsFlag.Name) {
+		cfg.MaxIdleConns = ctx.GlobalInt(ClientIdleConnsFlag.Name)
+	}
+	if ctx.GlobalIsSet(ClientIdleTimeoutFlag.Name) {
+		cfg.IdleTimeout = ctx.GlobalDuration(ClientIdleTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(ClientCallTimeoutFlag.Name) {
+		cfg.CallTimeout = ctx.GlobalDuration(ClientCallTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(ClientCallRetriesFlag.Name) {
+		cfg.CallRetries = ctx.GlobalInt(ClientCallRetriesFlag.Name)
+	}
+	if ctx.GlobalIsSet(ClientHedgeDelayFlag.Name) {
+		cfg.HedgeDelay = ctx.GlobalDuration(ClientHedgeDelayFlag.Name)
+	}
+}
+
 // setDomUrl sets the dominant chain websocket url.
 func setDomUrl(ctx *cli.Context, cfg *ethconfig.Config) {
 	// only set the dom url if the node is not prime
@@ -1207,6 +1588,25 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	if ctx.GlobalIsSet(ListenFamilyFlag.Name) {
+		if err := cfg.ListenFamily.UnmarshalText([]byte(ctx.GlobalString(ListenFamilyFlag.Name))); err != nil {
+			Fatalf("Option %q: %v", ListenFamilyFlag.Name, err)
+		}
+	}
+	if ctx.GlobalIsSet(DialPolicyFlag.Name) {
+		if err := cfg.DialPolicy.UnmarshalText([]byte(ctx.GlobalString(DialPolicyFlag.Name))); err != nil {
+			Fatalf("Option %q: %v", DialPolicyFlag.Name, err)
+		}
+	}
+	if ctx.GlobalIsSet(ProxyFlag.Name) {
+		cfg.Proxy = ctx.GlobalString(ProxyFlag.Name)
+		if _, err := netutil.ProxyDialer(cfg.Proxy); err != nil {
+			Fatalf("Option %q: %v", ProxyFlag.Name, err)
+		}
+	}
+	if ctx.GlobalIsSet(MessageTraceFlag.Name) {
+		cfg.TraceDir = ctx.GlobalString(MessageTraceFlag.Name)
+	}
 
 	if ctx.GlobalBool(DeveloperFlag.Name) || ctx.GlobalBool(CatalystFlag.Name) {
 		// --dev mode can't use p2p networking.
@@ -1371,9 +1771,95 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 		cfg.Notify = strings.Split(ctx.GlobalString(MinerNotifyFlag.Name), ",")
 	}
 	cfg.NotifyFull = ctx.GlobalBool(MinerNotifyFullFlag.Name)
+	if ctx.GlobalIsSet(MinerStratumFlag.Name) {
+		cfg.Stratum = ctx.GlobalString(MinerStratumFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerStratumDifficultyFlag.Name) {
+		cfg.StratumDifficulty = ctx.GlobalUint64(MinerStratumDifficultyFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerThreadsAffinityFlag.Name) {
+		cfg.ThreadsAffinity = nil
+		for _, cpu := range strings.Split(ctx.GlobalString(MinerThreadsAffinityFlag.Name), ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(cpu))
+			if err != nil || id < 0 {
+				Fatalf("Invalid mining thread CPU %q", cpu)
+			}
+			cfg.ThreadsAffinity = append(cfg.ThreadsAffinity, id)
+		}
+	}
+	if ctx.GlobalIsSet(MinerDutyCycleFlag.Name) {
+		cfg.DutyCycle = ctx.GlobalInt(MinerDutyCycleFlag.Name)
+		if cfg.DutyCycle < 0 || cfg.DutyCycle > 100 {
+			Fatalf("Mining duty cycle must be between 0 and 100, got %d", cfg.DutyCycle)
+		}
+	}
+	if ctx.GlobalIsSet(MinerImportPauseFlag.Name) {
+		cfg.ImportPause = ctx.GlobalDuration(MinerImportPauseFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerElectionURLFlag.Name) {
+		cfg.ElectionURL = ctx.GlobalString(MinerElectionURLFlag.Name)
+		if _, err := miner.NewLeaseStore(cfg.ElectionURL); err != nil {
+			Fatalf("Option %q: %v", MinerElectionURLFlag.Name, err)
+		}
+	}
+	if ctx.GlobalIsSet(MinerElectionKeyFlag.Name) {
+		cfg.ElectionKey = ctx.GlobalString(MinerElectionKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerElectionIDFlag.Name) {
+		cfg.ElectionID = ctx.GlobalString(MinerElectionIDFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerElectionTTLFlag.Name) {
+		cfg.ElectionTTL = ctx.GlobalDuration(MinerElectionTTLFlag.Name)
+		if cfg.ElectionTTL < 100*time.Millisecond {
+			Fatalf("Option %q must be at least 100ms", MinerElectionTTLFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(MinerPolicyFlag.Name) {
+		policy, err := miner.LoadPolicy(ctx.GlobalString(MinerPolicyFlag.Name))
+		if err != nil {
+			Fatalf("Failed to load transaction policy: %v", err)
+		}
+		cfg.Policy = policy
+	}
+	if ctx.GlobalIsSet(MinerNoSelfCheckFlag.Name) {
+		cfg.NoSelfCheck = ctx.GlobalBool(MinerNoSelfCheckFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerOrderingFlag.Name) {
+		cfg.Ordering = ctx.GlobalString(MinerOrderingFlag.Name)
+		if _, err := miner.LoadOrdering(cfg.Ordering); err != nil {
+			Fatalf("Option %q: %v", MinerOrderingFlag.Name, err)
+		}
+	}
+	if ctx.GlobalBool(MinerShareChainFlag.Name) {
+		shares := sharechain.DefaultConfig
+		if ctx.GlobalIsSet(MinerShareRatioFlag.Name) {
+			shares.Ratio = ctx.GlobalUint64(MinerShareRatioFlag.Name)
+		}
+		if ctx.GlobalIsSet(MinerShareWindowFlag.Name) {
+			shares.Window = ctx.GlobalInt(MinerShareWindowFlag.Name)
+		}
+		if shares.Ratio == 0 || shares.Window <= 0 {
+			Fatalf("Options %q and %q must be positive", MinerShareRatioFlag.Name, MinerShareWindowFlag.Name)
+		}
+		cfg.ShareChain = &shares
+	}
 	if ctx.GlobalIsSet(MinerExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.GlobalString(MinerExtraDataFlag.Name))
 	}
+	if ctx.GlobalIsSet(MinerExtraTemplateFlag.Name) {
+		cfg.ExtraTemplate = ctx.GlobalString(MinerExtraTemplateFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerExtraPoolFlag.Name) {
+		cfg.ExtraPool = ctx.GlobalString(MinerExtraPoolFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerExtraWorkerFlag.Name) {
+		cfg.ExtraWorker = ctx.GlobalString(MinerExtraWorkerFlag.Name)
+	}
+	if cfg.ExtraTemplate != "" {
+		if _, err := miner.RenderExtra(cfg.ExtraTemplate, cfg.ExtraPool, cfg.ExtraWorker); err != nil {
+			Fatalf("Invalid extra data template: %v", err)
+		}
+	}
 	if ctx.GlobalIsSet(MinerGasLimitFlag.Name) {
 		cfg.GasCeil = ctx.GlobalUint64(MinerGasLimitFlag.Name)
 	}
@@ -1414,6 +1900,27 @@ func setWhitelist(ctx *cli.Context, cfg *ethconfig.Config) {
 	}
 }
 
+// setTxForward sets the nodes of the other locations transactions are
+// forwarded to. Repeated chain IDs are failed over between in the given order.
+func setTxForward(ctx *cli.Context, cfg *ethconfig.Config) {
+	forward := ctx.GlobalString(TxForwardFlag.Name)
+	if forward == "" {
+		return
+	}
+	cfg.TxForwardUrls = make(map[uint64][]string)
+	for _, entry := range strings.Split(forward, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			Fatalf("Invalid txforward entry: %s", entry)
+		}
+		chainID, err := strconv.ParseUint(parts[0], 0, 64)
+		if err != nil {
+			Fatalf("Invalid txforward chain ID %s: %v", parts[0], err)
+		}
+		cfg.TxForwardUrls[chainID] = append(cfg.TxForwardUrls[chainID], parts[1])
+	}
+}
+
 // CheckExclusive verifies that only a single instance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -1477,6 +1984,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setTxPool(ctx, &cfg.TxPool)
 	setMiner(ctx, &cfg.Miner)
 	setWhitelist(ctx, cfg)
+	setTxForward(ctx, cfg)
 	setLes(ctx, cfg)
 
 	// set the dominant chain websocket url
@@ -1485,6 +1993,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	// set the subordinate chain websocket urls
 	setSubUrls(ctx, cfg)
 
+	// set the connection settings of the dom and sub node clients
+	setClient(ctx, &cfg.Client)
+
+	// set the fork choice between heads of equal difficulty
+	setTiebreak(ctx, &cfg.Tiebreak, &cfg.TiebreakProbability)
+
 	// Cap the cache allowance and tune the garbage collector
 	mem, err := gopsutil.VirtualMemory()
 	if err == nil {
@@ -1508,6 +2022,14 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(SyncModeFlag.Name) {
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
 	}
+	if ctx.GlobalIsSet(CheckpointAnchorFlag.Name) {
+		if err := cfg.CheckpointAnchor.UnmarshalText([]byte(ctx.GlobalString(CheckpointAnchorFlag.Name))); err != nil {
+			Fatalf("Invalid checkpoint anchor %s: %v", ctx.GlobalString(CheckpointAnchorFlag.Name), err)
+		}
+	}
+	if cfg.SyncMode == downloader.CheckpointSync && cfg.CheckpointAnchor == (common.Hash{}) {
+		Fatalf("Option %q requires %q", "--syncmode checkpoint", CheckpointAnchorFlag.Name)
+	}
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -1518,6 +2040,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(AncientFlag.Name) {
 		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
 	}
+	if ctx.GlobalIsSet(DBCompressFlag.Name) {
+		cfg.DatabaseCompress = ctx.GlobalBool(DBCompressFlag.Name)
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1537,6 +2062,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(MaxReorgDepthFlag.Name) {
+		cfg.MaxReorgDepth = ctx.GlobalUint64(MaxReorgDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(FutureBlocksFlag.Name) {
+		cfg.FutureBlocks = ctx.GlobalInt(FutureBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(FutureBlockHorizonFlag.Name) {
+		cfg.FutureBlockHorizon = ctx.GlobalDuration(FutureBlockHorizonFlag.Name)
+	}
+	if ctx.GlobalIsSet(InvariantCheckFlag.Name) {
+		cfg.InvariantCheckInterval = ctx.GlobalDuration(InvariantCheckFlag.Name)
+	}
+	if ctx.GlobalIsSet(InternalTxIndexFlag.Name) {
+		cfg.NoInternalTxIndex = !ctx.GlobalBool(InternalTxIndexFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1552,6 +2092,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheSnapshotFlag.Name) {
 		cfg.SnapshotCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100
 	}
+	if ctx.GlobalIsSet(CachePCRCFlag.Name) {
+		cfg.PCRCCache = ctx.GlobalInt(CachePCRCFlag.Name)
+		if cfg.PCRCCache <= 0 {
+			Fatalf("Option %q must be positive", CachePCRCFlag.Name)
+		}
+	}
 	if !ctx.GlobalBool(SnapshotFlag.Name) {
 		// If snap-sync is requested, this flag is also required
 		if cfg.SyncMode == downloader.SnapSync {
@@ -1561,6 +2107,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 			cfg.SnapshotCache = 0 // Disabled
 		}
 	}
+	if ctx.GlobalIsSet(SyncMaxPeersFlag.Name) {
+		cfg.SyncMaxPeers = ctx.GlobalInt(SyncMaxPeersFlag.Name)
+	}
+	if ctx.GlobalIsSet(SnapServeCapacityFlag.Name) {
+		cfg.SnapServe.Capacity = ctx.GlobalUint64(SnapServeCapacityFlag.Name) * 1024
+	}
+	if ctx.GlobalIsSet(SnapServeQuotaFlag.Name) {
+		cfg.SnapServe.PeerQuota = ctx.GlobalUint64(SnapServeQuotaFlag.Name) * 1024
+	}
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
 		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
 	}
@@ -1742,6 +2297,111 @@ func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url strin
 	}
 }
 
+// RegisterReleaseService configures the release checker and registers it with
+// the node. If requested, the miner is prevented from sealing past forks the
+// running release does not support.
+func RegisterReleaseService(ctx *cli.Context, stack *node.Node, backend ethapi.Backend, eth *eth.Ethereum) {
+	var pubkeys []string
+	for _, key := range strings.Split(ctx.GlobalString(ReleasePubKeysFlag.Name), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			pubkeys = append(pubkeys, key)
+		}
+	}
+	service, err := release.New(stack, backend, release.Config{
+		URL:                   ctx.GlobalString(ReleaseURLFlag.Name),
+		PubKeys:               pubkeys,
+		Interval:              ctx.GlobalDuration(ReleaseIntervalFlag.Name),
+		RefuseUnsupportedFork: ctx.GlobalBool(ReleaseRefuseForkFlag.Name),
+	})
+	if err != nil {
+		Fatalf("Failed to register the release checker: %v", err)
+	}
+	if eth != nil {
+		eth.Miner().AddSealGuard(service.SealGuard)
+	}
+}
+
+// RegisterOrphanStatsService configures the orphan indexer and registers it
+// with the node.
+func RegisterOrphanStatsService(ctx *cli.Context, stack *node.Node, backend ethapi.Backend) {
+	orphans.New(stack, backend, orphans.Config{
+		Window: ctx.GlobalUint64(OrphanStatsWindowFlag.Name),
+		Depth:  ctx.GlobalUint64(OrphanStatsDepthFlag.Name),
+	})
+}
+
+// RegisterAttestationService configures the block attestation service signing
+// with the node key and registers it with the node.
+func RegisterAttestationService(ctx *cli.Context, stack *node.Node, backend ethapi.Backend) {
+	_, err := attest.New(stack, backend, stack.Server().PrivateKey, attest.Config{
+		MaxConfirmations: ctx.GlobalUint64(AttestMaxConfirmationsFlag.Name),
+	})
+	if err != nil {
+		Fatalf("Failed to register the attestation service: %v", err)
+	}
+}
+
+// RegisterExtraIndexService configures the extra-data indexer and registers it
+// with the node.
+func RegisterExtraIndexService(ctx *cli.Context, stack *node.Node, backend ethapi.Backend) {
+	attribution.New(stack, backend, attribution.Config{
+		Window: ctx.GlobalUint64(ExtraIndexWindowFlag.Name),
+	})
+}
+
+// RegisterContractMetadataService opens the contract metadata store, registers
+// its APIs with the node and installs it for decoding into the eth APIs.
+func RegisterContractMetadataService(ctx *cli.Context, stack *node.Node, eth *eth.Ethereum) {
+	store, err := contractmeta.New(stack, contractmeta.Config{
+		Dir: ctx.GlobalString(ContractMetadataFlag.Name),
+	})
+	if err != nil {
+		Fatalf("Failed to open the contract metadata store: %v", err)
+	}
+	eth.SetContractMetadata(store)
+}
+
+// RegisterAddressLabelService opens the address label registry, registers its
+// APIs with the node and installs it for annotating traces into the eth APIs,
+// if the node is a full node.
+func RegisterAddressLabelService(stack *node.Node, eth *eth.Ethereum) {
+	store, err := labels.New(stack)
+	if err != nil {
+		Fatalf("Failed to open the address label registry: %v", err)
+	}
+	if eth != nil {
+		eth.SetAddressLabels(store)
+	}
+}
+
+// RegisterTokenIndexService configures the token indexer and registers it with
+// the node.
+func RegisterTokenIndexService(stack *node.Node, backend ethapi.Backend) {
+	tokens.New(stack, backend, tokens.Config{})
+}
+
+// RegisterRebroadcastService configures the transaction rebroadcaster and
+// registers it with the node.
+func RegisterRebroadcastService(ctx *cli.Context, stack *node.Node, backend *eth.Ethereum) {
+	config := rebroadcast.Config{
+		Interval:    ctx.GlobalDuration(RebroadcastIntervalFlag.Name),
+		BumpAfter:   ctx.GlobalDuration(RebroadcastBumpAfterFlag.Name),
+		BumpPercent: ctx.GlobalUint64(RebroadcastBumpPercentFlag.Name),
+	}
+	if price := GlobalBig(ctx, RebroadcastMaxGasPriceFlag.Name); price != nil && price.Sign() > 0 {
+		config.MaxGasPrice = price
+	}
+	rebroadcast.New(stack, backend, config)
+}
+
+// RegisterPlugins loads the configured Go plugins into the node.
+func RegisterPlugins(ctx *cli.Context, stack *node.Node, backend *eth.Ethereum) {
+	paths := SplitAndTrim(ctx.GlobalString(PluginsFlag.Name))
+	if err := plugins.Load(stack, backend, paths); err != nil {
+		Fatalf("Failed to load plugins: %v", err)
+	}
+}
+
 // RegisterGraphQLService is a utility function to construct a new service and register it against a node.
 func RegisterGraphQLService(stack *node.Node, backend ethapi.Backend, cfg node.Config) {
 	if err := graphql.New(stack, backend, cfg.GraphQLCors, cfg.GraphQLVirtualHosts); err != nil {
@@ -1834,6 +2494,7 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.
 		err     error
 		chainDb ethdb.Database
 	)
+	rawdb.SetCompression(ctx.GlobalBool(DBCompressFlag.Name))
 	if ctx.GlobalString(SyncModeFlag.Name) == "light" {
 		name := "lightchaindata"
 		chainDb, err = stack.OpenDatabase(name, cache, handles, "", readonly)
@@ -1905,6 +2566,13 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		cache.TrieDirtyLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
 
+	setClient(ctx, &cache.Client)
+	cache.Tiebreak, cache.TiebreakProbability = ethconfig.Defaults.Tiebreak, ethconfig.Defaults.TiebreakProbability
+	setTiebreak(ctx, &cache.Tiebreak, &cache.TiebreakProbability)
+	cache.MaxReorgDepth = ctx.GlobalUint64(MaxReorgDepthFlag.Name)
+	cache.FutureBlockLimit = ctx.GlobalInt(FutureBlocksFlag.Name)
+	cache.FutureBlockHorizon = ctx.GlobalDuration(FutureBlockHorizonFlag.Name)
+
 	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
 
 	// TODO(rjl493456442) disable snapshot generation/wiping if the chain is read only.
@@ -1916,6 +2584,27 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	return chain, chainDb
 }
 
+// MakeHeaderChain creates a header chain over the chain database, for tools
+// working on the stored chain without connecting to the dom and sub nodes.
+func MakeHeaderChain(ctx *cli.Context, stack *node.Node) (*core.HeaderChain, ethdb.Database) {
+	chainDb := MakeChainDatabase(ctx, stack, false)
+	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
+	if err != nil {
+		Fatalf("%v", err)
+	}
+	var engine consensus.Engine
+	if config.Clique != nil {
+		engine = clique.New(config.Clique, chainDb)
+	} else {
+		engine, _ = blake3.New(blake3.Config{}, nil, false)
+	}
+	hc, err := core.NewHeaderChain(chainDb, config, engine, func() bool { return false })
+	if err != nil {
+		Fatalf("Can't create HeaderChain: %v", err)
+	}
+	return hc, chainDb
+}
+
 // MakeConsolePreloads retrieves the absolute paths for the console JavaScript
 // scripts to preload before starting.
 func MakeConsolePreloads(ctx *cli.Context) []string {
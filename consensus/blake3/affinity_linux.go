@@ -0,0 +1,14 @@
+//go:build linux
+// +build linux
+
+package blake3
+
+import "golang.org/x/sys/unix"
+
+// setAffinity pins the calling OS thread to the given CPU.
+func setAffinity(cpu int) error {
+	var set unix.CPUSet
+	set.Zero()
+	set.Set(cpu)
+	return unix.SchedSetaffinity(0, &set)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package blake3
+
+import "errors"
+
+// setAffinity is not supported outside of Linux.
+func setAffinity(cpu int) error {
+	return errors.New("thread affinity not supported on this platform")
+}
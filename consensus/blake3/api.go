@@ -33,30 +33,37 @@ type API struct {
 
 // GetWork returns a work package for external miner.
 //
-// The work package consists of 3 strings:
+// The work package consists of 8 strings:
 //   result[0] - 32 bytes hex encoded current block header pow-hash
-//   result[1] - 32 bytes hex encoded seed hash used for DAG
-//   result[2] - 32 bytes hex encoded boundary condition ("target"), 2^256/difficulty
-//   result[3] - hex encoded block number
-func (api *API) GetWork() ([4]string, error) {
+//   result[1] - 32 bytes hex encoded boundary condition ("target"), 2^256/difficulty
+//   result[2] - hex encoded block number
+//   result[3] - hex encoded seal encoding, with a zero nonce in its last 8 bytes
+//   result[4] - 32 bytes hex encoded Prime target, empty without Prime difficulty
+//   result[5] - 32 bytes hex encoded Region target, empty without Region difficulty
+//   result[6] - 32 bytes hex encoded Zone target, empty without Zone difficulty
+//   result[7] - hex encoded location the block is mined for
+//
+// The order of a solution is the most dominant context whose target it meets,
+// so miners can classify it before submitting.
+func (api *API) GetWork() ([8]string, error) {
 	if api.blake3.remote == nil {
-		return [4]string{}, errors.New("not supported")
+		return [8]string{}, errors.New("not supported")
 	}
 
 	var (
-		workCh = make(chan [4]string, 1)
+		workCh = make(chan [8]string, 1)
 		errc   = make(chan error, 1)
 	)
 	select {
 	case api.blake3.remote.fetchWorkCh <- &sealWork{errc: errc, res: workCh}:
 	case <-api.blake3.remote.exitCh:
-		return [4]string{}, errBlake3Stopped
+		return [8]string{}, errBlake3Stopped
 	}
 	select {
 	case work := <-workCh:
 		return work, nil
 	case err := <-errc:
-		return [4]string{}, err
+		return [8]string{}, err
 	}
 }
 
@@ -10,6 +10,7 @@ import (
 	"github.com/spruce-solutions/go-quai/common/hexutil"
 	"github.com/spruce-solutions/go-quai/common/math"
 	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/log"
 	"github.com/spruce-solutions/go-quai/metrics"
 	"github.com/spruce-solutions/go-quai/rpc"
@@ -23,10 +24,26 @@ type Config struct {
 	// >0 => exact no. of threads, up to max CPUs
 	MiningThreads int
 
+	// CPUs the local mining threads are pinned to, round robin. Empty leaves
+	// the scheduling to the operating system.
+	ThreadsAffinity []int
+
+	// Percentage of time the local mining threads spend hashing, values of 0
+	// or 100 disable throttling.
+	DutyCycle int
+
 	// When set, notifications sent by the remote sealer will
 	// be block header JSON objects instead of work package arrays.
 	NotifyFull bool
 
+	// Listen address of the stratum server handing work to pool software and
+	// mining devices, empty disables it.
+	StratumAddr string
+
+	// Difficulty of the shares accepted by the stratum server, 0 only accepts
+	// solutions of blocks of the chain.
+	StratumDifficulty uint64
+
 	// Logger object
 	Log log.Logger `toml:"-"`
 
@@ -36,6 +53,8 @@ type Config struct {
 
 // Blake3 a consensus engine based on the Blake3 hash function
 type Blake3 struct {
+	pausedUntil int64 // Unix nanoseconds until which the local mining threads are paused (atomic, first for alignment)
+
 	config Config
 
 	// Runtime state
@@ -66,6 +85,16 @@ func New(config Config, notify []string, noverify bool) (*Blake3, error) {
 	if nil != err {
 		return nil, err
 	}
+	var stratum *stratumServer
+	if config.StratumAddr != "" {
+		if stratum, err = listenStratum(config.StratumAddr, config.Log); err != nil {
+			return nil, err
+		}
+	}
+	// The remote sealer only runs if there are remote miners to hand work to
+	if stratum != nil || len(notify) > 0 {
+		blake3.remote = startRemoteSealer(blake3, notify, noverify, stratum)
+	}
 	return blake3, nil
 }
 
@@ -94,6 +123,9 @@ func (blake3 *Blake3) Close() error {
 		if blake3.remote == nil {
 			return
 		}
+		if blake3.remote.stratum != nil {
+			blake3.remote.stratum.close()
+		}
 		close(blake3.remote.requestExit)
 		<-blake3.remote.exitCh
 	})
@@ -131,6 +163,30 @@ func (blake3 *Blake3) SubmitHashrate(rate hexutil.Uint64, id common.Hash) bool {
 	return true
 }
 
+// PushWork hands the sealing block to the remote miners, the solutions they
+// find are sent to results.
+func (blake3 *Blake3) PushWork(block *types.Block, results chan<- *types.Block) {
+	if blake3.remote == nil {
+		return
+	}
+	select {
+	case blake3.remote.workCh <- &sealTask{block: block, results: results}:
+	case <-blake3.remote.exitCh:
+	}
+}
+
+// InvalidateWork withdraws the work handed out to remote miners, notifying them
+// it went stale with the given reason, until new work is pushed.
+func (blake3 *Blake3) InvalidateWork(reason string) {
+	if blake3.remote == nil {
+		return
+	}
+	select {
+	case blake3.remote.invalidateCh <- reason:
+	case <-blake3.remote.exitCh:
+	}
+}
+
 // APIs implements consensus.Engine, returning the user facing RPC APIs.
 func (blake3 *Blake3) APIs(chain consensus.ChainHeaderReader) []rpc.API {
 	// In order to ensure backward compatibility, we exposes ethash RPC APIs
@@ -148,5 +204,15 @@ func (blake3 *Blake3) APIs(chain consensus.ChainHeaderReader) []rpc.API {
 			Service:   &API{blake3},
 			Public:    true,
 		},
+		{
+			Namespace: "miner",
+			Version:   "1.0",
+			Service:   &MinerAPI{blake3},
+		},
+		{
+			Namespace: "debug",
+			Version:   "1.0",
+			Service:   &DebugAPI{chain},
+		},
 	}
 }
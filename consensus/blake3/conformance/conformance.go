@@ -0,0 +1,416 @@
+// Package conformance implements a work server external Blake3 miners can run
+// against to validate their kernels: the seal hashes they compute and the
+// solutions they submit are checked with go-quai's own verifier across the
+// prime, region and zone thresholds and a set of header encoding edge cases.
+package conformance
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+	"sync"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/consensus/blake3"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// DefaultDifficulty is the zone difficulty of the sealing cases, the region
+// and prime thresholds are 4 and 16 times harder.
+const DefaultDifficulty = 1 << 20
+
+var (
+	big2e256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
+
+	// edgeNonces are the nonces the reference vectors are computed for.
+	edgeNonces = []uint64{0, 1, 0xff, 0x100, 0xffffffff, 0x100000000, 0x7fffffffffffffff, 0xffffffffffffffff}
+
+	errUnknownWork = errors.New("unknown work package")
+)
+
+// Case is a sealing job of the conformance suite. The submitted solution must
+// satisfy the difficulty of the given context.
+type Case struct {
+	Name    string
+	Context int
+	Header  *types.Header
+}
+
+// result tracks the submissions made for a case.
+type result struct {
+	passed      bool
+	nonce       types.BlockNonce
+	order       int
+	submissions int
+	rejected    int
+}
+
+// Server serves the conformance suite. Cases are handed out as work in order,
+// moving on once a valid solution for the current case was submitted.
+type Server struct {
+	engine *blake3.Blake3
+	cases  []*Case
+	ids    map[common.Hash]int // work package id to case index
+
+	lock       sync.Mutex
+	current    int
+	results    []*result
+	checked    int // kernel hashes checked with CheckHash
+	mismatched int // kernel hashes not matching the verifier
+}
+
+// NewServer creates a conformance server with deterministic cases derived from
+// the seed and with the given zone difficulty.
+func NewServer(seed int64, difficulty uint64) (*Server, error) {
+	if difficulty == 0 {
+		difficulty = DefaultDifficulty
+	}
+	engine, err := blake3.New(blake3.Config{}, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		engine: engine,
+		cases:  makeCases(rand.New(rand.NewSource(seed)), difficulty),
+		ids:    make(map[common.Hash]int),
+	}
+	for i, c := range s.cases {
+		s.ids[s.workID(c.Header)] = i
+		s.results = append(s.results, &result{order: -1})
+	}
+	return s, nil
+}
+
+// makeCases assembles the threshold and header encoding cases.
+func makeCases(rng *rand.Rand, difficulty uint64) []*Case {
+	header := func() *types.Header {
+		h := types.NewEmptyHeader()
+		for i := 0; i < types.ContextDepth; i++ {
+			h.ParentHash[i] = randomHash(rng)
+			h.UncleHash[i] = types.EmptyUncleHash[i]
+			h.Coinbase[i] = common.BytesToAddress(randomHash(rng).Bytes())
+			h.Root[i] = randomHash(rng)
+			h.TxHash[i] = types.EmptyRootHash[i]
+			h.ReceiptHash[i] = types.EmptyRootHash[i]
+			h.Number[i] = big.NewInt(rng.Int63n(1 << 20))
+			h.GasLimit[i] = params.GenesisGasLimit
+			h.BaseFee[i] = big.NewInt(params.InitialBaseFee)
+			h.NetworkDifficulty[i] = new(big.Int)
+		}
+		h.Difficulty[params.ZONE] = new(big.Int).SetUint64(difficulty)
+		h.Difficulty[params.REGION] = new(big.Int).SetUint64(difficulty * 4)
+		h.Difficulty[params.PRIME] = new(big.Int).SetUint64(difficulty * 16)
+		h.Time = uint64(rng.Int63n(1 << 32))
+		h.Location = []byte{1, 2}
+		return h
+	}
+	noBaseFee := header()
+	noBaseFee.BaseFee = nil
+
+	maxExtra := header()
+	for i := range maxExtra.Extra {
+		maxExtra.Extra[i] = make([]byte, params.MaximumExtraDataSize)
+		rng.Read(maxExtra.Extra[i])
+	}
+	bigNumbers := header()
+	for i := range bigNumbers.Number {
+		bigNumbers.Number[i] = new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 64), big.NewInt(int64(i)))
+	}
+	return []*Case{
+		{Name: "zone-threshold", Context: params.ZONE, Header: header()},
+		{Name: "region-threshold", Context: params.REGION, Header: header()},
+		{Name: "prime-threshold", Context: params.PRIME, Header: header()},
+		{Name: "no-basefee", Context: params.ZONE, Header: noBaseFee},
+		{Name: "max-extra", Context: params.ZONE, Header: maxExtra},
+		{Name: "big-numbers", Context: params.ZONE, Header: bigNumbers},
+	}
+}
+
+func randomHash(rng *rand.Rand) (hash common.Hash) {
+	rng.Read(hash[:])
+	return hash
+}
+
+// workID is the identifier of a case's work package, the seal hash of its
+// header with a zero nonce.
+func (s *Server) workID(header *types.Header) common.Hash {
+	h := types.CopyHeader(header)
+	h.Nonce = types.BlockNonce{}
+	return s.engine.SealHash(h)
+}
+
+// Work is the work package of a case, carrying everything a kernel needs to
+// hash candidate nonces.
+type Work struct {
+	ID          common.Hash   `json:"id"`
+	Case        string        `json:"case"`
+	Context     int           `json:"context"`
+	Header      *types.Header `json:"header"`
+	Template    hexutil.Bytes `json:"template"`    // seal encoding with a zero nonce
+	NonceOffset int           `json:"nonceOffset"` // offset of the 8 big endian nonce bytes in the template
+	Targets     []common.Hash `json:"targets"`     // 2^256/difficulty of the prime, region and zone thresholds
+	Target      common.Hash   `json:"target"`      // threshold the solution must satisfy
+}
+
+// work assembles the work package of a case.
+func (s *Server) work(i int) *Work {
+	c := s.cases[i]
+	header := types.CopyHeader(c.Header)
+	header.Nonce = types.BlockNonce{}
+
+	template := s.engine.SealEncoding(header)
+	work := &Work{
+		ID:          s.workID(header),
+		Case:        c.Name,
+		Context:     c.Context,
+		Header:      header,
+		Template:    template,
+		NonceOffset: len(template) - len(types.BlockNonce{}),
+	}
+	for _, difficulty := range header.Difficulty {
+		work.Targets = append(work.Targets, common.BigToHash(new(big.Int).Div(big2e256, difficulty)))
+	}
+	work.Target = work.Targets[c.Context]
+	return work
+}
+
+// Vector is a reference seal hash of a case header at a fixed nonce.
+type Vector struct {
+	Case     string           `json:"case"`
+	Template hexutil.Bytes    `json:"template"` // seal encoding including the nonce
+	Nonce    types.BlockNonce `json:"nonce"`
+	Hash     common.Hash      `json:"hash"`
+	Order    int              `json:"order"` // highest context the hash satisfies, -1 if none
+}
+
+// Vectors returns the reference seal hashes of every case at the nonce edge cases.
+func (s *Server) Vectors() []*Vector {
+	var vectors []*Vector
+	for _, c := range s.cases {
+		for _, nonce := range edgeNonces {
+			header := types.CopyHeader(c.Header)
+			header.Nonce = types.EncodeNonce(nonce)
+			vectors = append(vectors, &Vector{
+				Case:     c.Name,
+				Template: s.engine.SealEncoding(header),
+				Nonce:    header.Nonce,
+				Hash:     s.engine.SealHash(header),
+				Order:    s.order(header),
+			})
+		}
+	}
+	return vectors
+}
+
+// order returns the highest context the header's seal satisfies, -1 if none.
+func (s *Server) order(header *types.Header) int {
+	order, err := s.engine.GetDifficultyOrder(header)
+	if err != nil {
+		return -1
+	}
+	return order
+}
+
+// GetWork returns the work package of the current case, the case work is
+// returned for is advanced on every valid solution.
+func (s *Server) GetWork() (*Work, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.current >= len(s.cases) {
+		return nil, errors.New("conformance suite completed")
+	}
+	return s.work(s.current), nil
+}
+
+// Submission is the verdict on a submitted solution.
+type Submission struct {
+	Accepted bool        `json:"accepted"`
+	Hash     common.Hash `json:"hash"`  // seal hash computed by the verifier
+	Order    int         `json:"order"` // highest context the solution satisfies, -1 if none
+	Reason   string      `json:"reason,omitempty"`
+}
+
+// Submit verifies a solution for the work package with the given id.
+func (s *Server) Submit(id common.Hash, nonce types.BlockNonce) (*Submission, error) {
+	i, ok := s.ids[id]
+	if !ok {
+		return nil, errUnknownWork
+	}
+	c := s.cases[i]
+	header := types.CopyHeader(c.Header)
+	header.Nonce = nonce
+
+	sub := &Submission{Hash: s.engine.SealHash(header), Order: s.order(header)}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := s.results[i]
+	res.submissions++
+	switch {
+	case sub.Order < 0 || sub.Order > c.Context:
+		sub.Reason = "solution does not satisfy the case threshold"
+		res.rejected++
+	default:
+		sub.Accepted = true
+		if !res.passed {
+			res.passed, res.nonce, res.order = true, nonce, sub.Order
+		}
+		if i == s.current {
+			s.current++
+		}
+	}
+	return sub, nil
+}
+
+// HashCheck is the outcome of comparing a kernel's hash with the verifier.
+type HashCheck struct {
+	Match    bool        `json:"match"`
+	Expected common.Hash `json:"expected"`
+	Order    int         `json:"order"`
+}
+
+// CheckHash compares the seal hash a kernel computed for a nonce of a work
+// package with the verifier's.
+func (s *Server) CheckHash(id common.Hash, nonce types.BlockNonce, hash common.Hash) (*HashCheck, error) {
+	i, ok := s.ids[id]
+	if !ok {
+		return nil, errUnknownWork
+	}
+	header := types.CopyHeader(s.cases[i].Header)
+	header.Nonce = nonce
+
+	check := &HashCheck{Expected: s.engine.SealHash(header), Order: s.order(header)}
+	check.Match = check.Expected == hash
+
+	s.lock.Lock()
+	s.checked++
+	if !check.Match {
+		s.mismatched++
+	}
+	s.lock.Unlock()
+	return check, nil
+}
+
+// CaseReport is the outcome of a single case.
+type CaseReport struct {
+	Name        string            `json:"name"`
+	Context     int               `json:"context"`
+	Passed      bool              `json:"passed"`
+	Nonce       *types.BlockNonce `json:"nonce,omitempty"` // first accepted solution
+	Order       int               `json:"order"`
+	Submissions int               `json:"submissions"`
+	Rejected    int               `json:"rejected"`
+}
+
+// Report is the outcome of the conformance suite so far.
+type Report struct {
+	Complete   bool          `json:"complete"`
+	Cases      []*CaseReport `json:"cases"`
+	Checked    int           `json:"checked"`
+	Mismatched int           `json:"mismatched"`
+}
+
+// Report summarizes the submissions and hash checks made so far.
+func (s *Server) Report() *Report {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	report := &Report{Complete: true, Checked: s.checked, Mismatched: s.mismatched}
+	for i, c := range s.cases {
+		res := s.results[i]
+		cr := &CaseReport{
+			Name:        c.Name,
+			Context:     c.Context,
+			Passed:      res.passed,
+			Order:       res.order,
+			Submissions: res.submissions,
+			Rejected:    res.rejected,
+		}
+		if res.passed {
+			nonce := res.nonce
+			cr.Nonce = &nonce
+		}
+		report.Complete = report.Complete && res.passed
+		report.Cases = append(report.Cases, cr)
+	}
+	return report
+}
+
+// EthAPI serves the getWork/submitWork methods remote miners already speak.
+type EthAPI struct {
+	s *Server
+}
+
+// NewEthAPI creates the eth namespace API of the server.
+func NewEthAPI(s *Server) *EthAPI {
+	return &EthAPI{s}
+}
+
+// GetWork returns the current case as a work package laid out as the one of
+// the remote sealer: the work id, the target of the case threshold, the block
+// number, the seal encoding template, the prime, region and zone targets and
+// the location.
+func (api *EthAPI) GetWork() ([8]string, error) {
+	work, err := api.s.GetWork()
+	if err != nil {
+		return [8]string{}, err
+	}
+	res := [8]string{
+		work.ID.Hex(),
+		work.Target.Hex(),
+		hexutil.EncodeBig(work.Header.Number[work.Context]),
+		work.Template.String(),
+	}
+	for i, target := range work.Targets {
+		if i < types.ContextDepth {
+			res[4+i] = target.Hex()
+		}
+	}
+	res[7] = hexutil.Encode(work.Header.Location)
+	return res, nil
+}
+
+// SubmitWork verifies a solution, returning whether it was accepted.
+func (api *EthAPI) SubmitWork(nonce types.BlockNonce, id, digest common.Hash) bool {
+	sub, err := api.s.Submit(id, nonce)
+	return err == nil && sub.Accepted
+}
+
+// API serves the conformance specific methods in the blake3 namespace.
+type API struct {
+	s *Server
+}
+
+// NewAPI creates the blake3 namespace API of the server.
+func NewAPI(s *Server) *API {
+	return &API{s}
+}
+
+// GetWork returns the full work package of the current case.
+func (api *API) GetWork() (*Work, error) {
+	return api.s.GetWork()
+}
+
+// SubmitWork verifies a solution, explaining rejections.
+func (api *API) SubmitWork(id common.Hash, nonce types.BlockNonce) (*Submission, error) {
+	return api.s.Submit(id, nonce)
+}
+
+// Vectors returns the reference seal hashes at the nonce edge cases.
+func (api *API) Vectors() []*Vector {
+	return api.s.Vectors()
+}
+
+// CheckHash compares a kernel computed seal hash with the verifier.
+func (api *API) CheckHash(id common.Hash, nonce types.BlockNonce, hash common.Hash) (*HashCheck, error) {
+	return api.s.CheckHash(id, nonce, hash)
+}
+
+// Report returns the outcome of the suite so far.
+func (api *API) Report() *Report {
+	return api.s.Report()
+}
@@ -0,0 +1,91 @@
+package conformance
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"lukechampine.com/blake3"
+)
+
+// Tests that a reference kernel, hashing the work template with the nonce
+// spliced in, agrees with the verifier and completes the suite.
+func TestConformance(t *testing.T) {
+	s, err := NewServer(1, 16)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	for _, v := range s.Vectors() {
+		if hash := common.Hash(blake3.Sum256(v.Template)); hash != v.Hash {
+			t.Errorf("case %s nonce %x: template hash %x, want %x", v.Case, v.Nonce, hash, v.Hash)
+		}
+	}
+	for {
+		work, err := s.GetWork()
+		if err != nil {
+			break
+		}
+		target := work.Target.Big()
+		template := append([]byte{}, work.Template...)
+		for nonce := uint64(0); ; nonce++ {
+			binary.BigEndian.PutUint64(template[work.NonceOffset:], nonce)
+			hash := common.Hash(blake3.Sum256(template))
+			if nonce < 4 {
+				check, err := s.CheckHash(work.ID, types.EncodeNonce(nonce), hash)
+				if err != nil || !check.Match {
+					t.Fatalf("case %s: kernel hash mismatch: %v", work.Case, err)
+				}
+			}
+			if hash.Big().Cmp(target) > 0 {
+				continue
+			}
+			sub, err := s.Submit(work.ID, types.EncodeNonce(nonce))
+			if err != nil || !sub.Accepted {
+				t.Fatalf("case %s: solution %d rejected: %v %v", work.Case, nonce, sub, err)
+			}
+			break
+		}
+	}
+	report := s.Report()
+	if !report.Complete || report.Mismatched != 0 {
+		t.Fatalf("suite incomplete: %+v", report)
+	}
+	for _, c := range report.Cases {
+		if c.Order > c.Context {
+			t.Errorf("case %s: accepted order %d above context %d", c.Name, c.Order, c.Context)
+		}
+	}
+}
+
+// Tests that the getWork package carries the thresholds of every context, so
+// that miners can classify solutions before submitting them.
+func TestEthAPIWorkThresholds(t *testing.T) {
+	s, err := NewServer(1, 16)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	work, err := s.GetWork()
+	if err != nil {
+		t.Fatalf("failed to get work: %v", err)
+	}
+	res, err := NewEthAPI(s).GetWork()
+	if err != nil {
+		t.Fatalf("failed to get work package: %v", err)
+	}
+	if res[0] != work.ID.Hex() || res[1] != work.Target.Hex() || res[3] != work.Template.String() {
+		t.Errorf("work package mismatch: %v", res)
+	}
+	for i, target := range work.Targets {
+		if res[4+i] != target.Hex() {
+			t.Errorf("context %d target mismatch: have %s, want %s", i, res[4+i], target.Hex())
+		}
+	}
+	if res[1] != res[4+work.Context] {
+		t.Errorf("case target %s is not the threshold of context %d", res[1], work.Context)
+	}
+	if res[7] != hexutil.Encode(work.Header.Location) {
+		t.Errorf("location mismatch: have %s, want %x", res[7], work.Header.Location)
+	}
+}
@@ -318,38 +318,40 @@ func CalcDifficulty(config *params.ChainConfig, time uint64, parent *types.Heade
 // difficulty that a new block should have when created at time given the parent
 // block's time and difficulty. The calculation uses the Frontier rules.
 func calcDifficultyFrontier(time uint64, parent *types.Header, context int) *big.Int {
-	diff := new(big.Int)
 	parentDifficulty := parent.Difficulty[context]
 	if parentDifficulty == nil {
 		return params.GenesisDifficulty[types.QuaiNetworkContext]
 	}
+	interval := new(big.Int).SetUint64(time)
+	interval.Sub(interval, new(big.Int).SetUint64(parent.Time))
 
-	adjust := new(big.Int).Div(parentDifficulty, params.DifficultyBoundDivisor[types.QuaiNetworkContext])
-	bigTime := new(big.Int)
-	bigParentTime := new(big.Int)
-
-	bigTime.SetUint64(time)
-	bigParentTime.SetUint64(parent.Time)
+	return adjustDifficulty(types.QuaiNetworkContext, parentDifficulty, parent.Number[context], interval)
+}
 
-	duration := params.DurationLimits[types.QuaiNetworkContext]
+// adjustDifficulty applies the Frontier rules with the parameters of the
+// context to the difficulty of the parent, found interval seconds after its own
+// parent.
+func adjustDifficulty(context int, parentDifficulty *big.Int, parentNumber *big.Int, interval *big.Int) *big.Int {
+	diff := new(big.Int)
+	adjust := new(big.Int).Div(parentDifficulty, params.DifficultyBoundDivisor[context])
 
-	if bigTime.Sub(bigTime, bigParentTime).Cmp(duration) < 0 {
+	if interval.Cmp(params.DurationLimits[context]) < 0 {
 		diff.Add(parentDifficulty, adjust)
 	} else {
 		diff.Sub(parentDifficulty, adjust)
 	}
-	if diff.Cmp(params.MinimumDifficulty[types.QuaiNetworkContext]) < 0 {
-		diff.Set(params.MinimumDifficulty[types.QuaiNetworkContext])
+	if diff.Cmp(params.MinimumDifficulty[context]) < 0 {
+		diff.Set(params.MinimumDifficulty[context])
 	}
 
-	periodCount := new(big.Int).Add(parent.Number[context], big1)
+	periodCount := new(big.Int).Add(parentNumber, big1)
 	periodCount.Div(periodCount, expDiffPeriod)
 	if periodCount.Cmp(big1) > 0 {
 		// diff = diff + 2^(periodCount - 2)
 		expDiff := periodCount.Sub(periodCount, big2)
 		expDiff.Exp(big2, expDiff, nil)
 		diff.Add(diff, expDiff)
-		diff = math.BigMax(diff, params.MinimumDifficulty[types.QuaiNetworkContext])
+		diff = math.BigMax(diff, params.MinimumDifficulty[context])
 	}
 	return diff
 }
@@ -804,7 +806,7 @@ func (blake3 *Blake3) TraceBranches(chain consensus.ChainHeaderReader, header *t
 func (blake3 *Blake3) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
 	// Accumulate any block and uncle rewards and commit the final state root
 	accumulateRewards(chain.Config(), state, header, uncles)
-	header.Root[types.QuaiNetworkContext] = state.IntermediateRoot(chain.Config().IsEIP158(header.Number[types.QuaiNetworkContext]))
+	header.Root[types.QuaiNetworkContext] = state.IntermediateRoot(chain.Config().DeleteEmptyAccounts(header.Number[types.QuaiNetworkContext]))
 }
 
 // FinalizeAndAssemble implements consensus.Engine, accumulating the block and
@@ -821,7 +823,15 @@ func (blake3 *Blake3) FinalizeAndAssemble(chain consensus.ChainHeaderReader, hea
 func (blake3 *Blake3) SealHash(header *types.Header) (hash common.Hash) {
 	hasher := blake3hash.New(32, nil)
 	hasher.Reset()
+	hasher.Write(blake3.SealEncoding(header))
+	hasher.Sum(hash[:0])
+	return hash
+}
 
+// SealEncoding returns the RLP encoding of the header fields hashed by SealHash.
+// The nonce is always encoded last as an 8 byte string, occupying the final 8
+// bytes of the encoding.
+func (blake3 *Blake3) SealEncoding(header *types.Header) []byte {
 	enc := []interface{}{
 		header.ParentHash,
 		header.UncleHash,
@@ -841,10 +851,12 @@ func (blake3 *Blake3) SealHash(header *types.Header) (hash common.Hash) {
 	if header.BaseFee != nil {
 		enc = append(enc, header.BaseFee)
 	}
+	if header.EtxRollupHash != nil {
+		enc = append(enc, header.EtxRollupHash)
+	}
 	enc = append(enc, header.Nonce)
-	rlp.Encode(hasher, enc)
-	hasher.Sum(hash[:0])
-	return hash
+	data, _ := rlp.EncodeToBytes(enc)
+	return data
 }
 
 // AccumulateRewards credits the coinbase of the given block with the mining
@@ -124,6 +124,8 @@ func (blake3 *Blake3) mine(block *types.Block, id int, seed uint64, abort chan s
 	)
 	logger := blake3.config.Log.New("miner", id)
 	logger.Trace("Started ethash search for new nonces", "seed", seed)
+	blake3.pinThread(id, logger)
+	throttle := blake3.newThrottle()
 	if blake3.config.Fakepow {
 		target = new(big.Int).Div(big2e256, fakeDifficulties[types.QuaiNetworkContext])
 	}
@@ -143,6 +145,9 @@ search:
 				blake3.hashrate.Mark(attempts)
 				attempts = 0
 			}
+			if attempts%throttleCheckInterval == 0 && !throttle.wait(abort) {
+				continue
+			}
 
 			// Set the new nonce and try again
 			header.Nonce = types.EncodeNonce(nonce)
@@ -251,6 +256,8 @@ func (blake3 *Blake3) mineHeader(header types.Header, id int, seed uint64, abort
 	)
 	logger := blake3.config.Log.New("miner", id)
 	logger.Trace("Started ethash search for new nonces", "seed", seed)
+	blake3.pinThread(id, logger)
+	throttle := blake3.newThrottle()
 search:
 	for {
 		select {
@@ -267,6 +274,9 @@ search:
 				blake3.hashrate.Mark(attempts)
 				attempts = 0
 			}
+			if attempts%throttleCheckInterval == 0 && !throttle.wait(abort) {
+				continue
+			}
 			// Set the new nonce and try again
 			header.Nonce = types.EncodeNonce(nonce)
 			blockhash := blake3.SealHash(&header)
@@ -302,7 +312,11 @@ type remoteSealer struct {
 	works        map[common.Hash]*types.Block
 	rates        map[common.Hash]hashrate
 	currentBlock *types.Block
-	currentWork  [4]string
+	currentWork  [8]string
+	invalidated  bool                                          // whether the current work went stale before new work arrived
+	workTime     time.Time                                     // time the current work package was created
+	shares       shareStats                                    // outcome of the submitted solutions
+	shareNonces  map[common.Hash]map[types.BlockNonce]struct{} // nonces submitted to the stratum server by pending work
 	notifyCtx    context.Context
 	cancelNotify context.CancelFunc // cancels all notification requests
 	reqWG        sync.WaitGroup     // tracks notification request goroutines
@@ -310,12 +324,16 @@ type remoteSealer struct {
 	blake3       *Blake3
 	noverify     bool
 	notifyURLs   []string
+	stratum      *stratumServer
 	results      chan<- *types.Block
-	workCh       chan *sealTask   // Notification channel to push new work and relative result channel to remote sealer
-	fetchWorkCh  chan *sealWork   // Channel used for remote sealer to fetch mining work
-	submitWorkCh chan *mineResult // Channel used for remote sealer to submit their mining result
-	fetchRateCh  chan chan uint64 // Channel used to gather submitted hash rate for local or remote sealer.
-	submitRateCh chan *hashrate   // Channel used for remote sealer to submit their mining hashrate
+	workCh       chan *sealTask     // Notification channel to push new work and relative result channel to remote sealer
+	fetchWorkCh  chan *sealWork     // Channel used for remote sealer to fetch mining work
+	submitWorkCh chan *mineResult   // Channel used for remote sealer to submit their mining result
+	fetchRateCh  chan chan uint64   // Channel used to gather submitted hash rate for local or remote sealer.
+	submitRateCh chan *hashrate     // Channel used for remote sealer to submit their mining hashrate
+	fetchStatsCh chan chan *Stats   // Channel used to gather the remote sealer statistics
+	invalidateCh chan string        // Channel used to invalidate the current work, with the reason
+	shareCh      chan *stratumShare // Channel used for stratum miners to submit their shares
 	requestExit  chan struct{}
 	exitCh       chan struct{}
 }
@@ -347,27 +365,35 @@ type hashrate struct {
 // sealWork wraps a seal work package for remote sealer.
 type sealWork struct {
 	errc chan error
-	res  chan [4]string
+	res  chan [8]string
 }
 
-func startRemoteSealer(blake3 *Blake3, urls []string, noverify bool) *remoteSealer {
+func startRemoteSealer(blake3 *Blake3, urls []string, noverify bool, stratum *stratumServer) *remoteSealer {
 	ctx, cancel := context.WithCancel(context.Background())
 	s := &remoteSealer{
 		blake3:       blake3,
 		noverify:     noverify,
 		notifyURLs:   urls,
+		stratum:      stratum,
 		notifyCtx:    ctx,
 		cancelNotify: cancel,
 		works:        make(map[common.Hash]*types.Block),
 		rates:        make(map[common.Hash]hashrate),
+		shareNonces:  make(map[common.Hash]map[types.BlockNonce]struct{}),
 		workCh:       make(chan *sealTask),
 		fetchWorkCh:  make(chan *sealWork),
 		submitWorkCh: make(chan *mineResult),
 		fetchRateCh:  make(chan chan uint64),
 		submitRateCh: make(chan *hashrate),
+		fetchStatsCh: make(chan chan *Stats),
+		invalidateCh: make(chan string),
+		shareCh:      make(chan *stratumShare),
 		requestExit:  make(chan struct{}),
 		exitCh:       make(chan struct{}),
 	}
+	if stratum != nil {
+		stratum.serve(s)
+	}
 	go s.loop()
 	return s
 }
@@ -391,10 +417,13 @@ func (s *remoteSealer) loop() {
 			s.results = work.results
 			s.makeWork(work.block)
 			s.notifyWork()
+			if s.stratum != nil {
+				s.stratum.notify(s.stratumJob())
+			}
 
 		case work := <-s.fetchWorkCh:
 			// Return current mining work to remote miner.
-			if s.currentBlock == nil {
+			if s.currentBlock == nil || s.invalidated {
 				work.errc <- errNoMiningWork
 			} else {
 				work.res <- s.currentWork
@@ -422,6 +451,22 @@ func (s *remoteSealer) loop() {
 			}
 			req <- total
 
+		case share := <-s.shareCh:
+			// Classify the share of a stratum miner, submitting block solutions.
+			order, err := s.submitShare(share.nonce, share.sealhash)
+			share.res <- &stratumShareResult{order: order, err: err}
+
+		case req := <-s.fetchStatsCh:
+			req <- s.stats()
+
+		case reason := <-s.invalidateCh:
+			// Withdraw the current work until new one arrives. Solutions to it
+			// are still accepted, the blocks are kept in the pending works.
+			if s.currentBlock != nil && !s.invalidated {
+				s.invalidated = true
+				s.notifyInvalidation(reason)
+			}
+
 		case <-ticker.C:
 			// Clear stale submitted hash rate.
 			for id, rate := range s.rates {
@@ -434,6 +479,7 @@ func (s *remoteSealer) loop() {
 				for hash, block := range s.works {
 					if block.NumberU64()+staleThreshold <= s.currentBlock.NumberU64() {
 						delete(s.works, hash)
+						delete(s.shareNonces, hash)
 					}
 				}
 			}
@@ -446,19 +492,36 @@ func (s *remoteSealer) loop() {
 
 // makeWork creates a work package for external miner.
 //
-// The work package consists of 3 strings:
+// The work package consists of 8 strings:
 //   result[0], 32 bytes hex encoded current block header pow-hash
-//   result[1], 32 bytes hex encoded seed hash used for DAG
-//   result[2], 32 bytes hex encoded boundary condition ("target"), 2^256/difficulty
-//   result[3], hex encoded block number
+//   result[1], 32 bytes hex encoded boundary condition ("target"), 2^256/difficulty
+//   result[2], hex encoded block number
+//   result[3], hex encoded seal encoding with a zero nonce
+//   result[4], 32 bytes hex encoded Prime target, empty without Prime difficulty
+//   result[5], 32 bytes hex encoded Region target, empty without Region difficulty
+//   result[6], 32 bytes hex encoded Zone target, empty without Zone difficulty
+//   result[7], hex encoded location of the block
 func (s *remoteSealer) makeWork(block *types.Block) {
 	hash := s.blake3.SealHash(block.Header())
 	s.currentWork[0] = hash.Hex()
 	s.currentWork[1] = common.BytesToHash(new(big.Int).Div(big2e256, block.Difficulty()).Bytes()).Hex()
 	s.currentWork[2] = hexutil.EncodeBig(block.Number())
 
+	header := block.Header()
+	header.Nonce = types.BlockNonce{}
+	s.currentWork[3] = hexutil.Encode(s.blake3.SealEncoding(header))
+	for i := 0; i < types.ContextDepth; i++ {
+		s.currentWork[4+i] = ""
+		if i < len(header.Difficulty) && header.Difficulty[i] != nil && header.Difficulty[i].Sign() > 0 {
+			s.currentWork[4+i] = encodeTarget(new(big.Int).Div(big2e256, header.Difficulty[i]))
+		}
+	}
+	s.currentWork[7] = hexutil.Encode(header.Location)
+
 	// Trace the seal work fetched by remote sealer.
 	s.currentBlock = block
+	s.invalidated = false
+	s.workTime = time.Now()
 	s.works[hash] = block
 }
 
@@ -482,7 +545,25 @@ func (s *remoteSealer) notifyWork() {
 	}
 }
 
-func (s *remoteSealer) sendNotification(ctx context.Context, url string, json []byte, work [4]string) {
+// workInvalidation is the notification payload withdrawing the current work.
+type workInvalidation struct {
+	Invalidate string `json:"invalidate"` // Pow-hash of the stale work
+	Reason     string `json:"reason"`
+}
+
+// notifyInvalidation notifies all the specified mining endpoints that the
+// current work went stale, so they stop working on it before new work arrives.
+func (s *remoteSealer) notifyInvalidation(reason string) {
+	work := s.currentWork
+	blob, _ := json.Marshal(&workInvalidation{Invalidate: work[0], Reason: reason})
+
+	s.reqWG.Add(len(s.notifyURLs))
+	for _, url := range s.notifyURLs {
+		go s.sendNotification(s.notifyCtx, url, blob, work)
+	}
+}
+
+func (s *remoteSealer) sendNotification(ctx context.Context, url string, json []byte, work [8]string) {
 	defer s.reqWG.Done()
 
 	req, err := http.NewRequest("POST", url, bytes.NewReader(json))
@@ -516,6 +597,7 @@ func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash,
 	block := s.works[sealhash]
 	if block == nil {
 		s.blake3.config.Log.Warn("Work submitted but none pending", "sealhash", sealhash, "curnumber", s.currentBlock.NumberU64())
+		s.shares.stale++
 		return false
 	}
 	// Verify the correctness of submitted result.
@@ -526,12 +608,14 @@ func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash,
 	if !s.noverify {
 		if err := s.blake3.verifySeal(header); err != nil {
 			s.blake3.config.Log.Warn("Invalid proof-of-work submitted", "sealhash", sealhash, "elapsed", common.PrettyDuration(time.Since(start)), "err", err)
+			s.shares.invalid++
 			return false
 		}
 	}
 	// Make sure the result channel is assigned.
 	if s.results == nil {
 		s.blake3.config.Log.Warn("Ethash result channel is empty, submitted mining result is rejected")
+		s.shares.rejected++
 		return false
 	}
 	s.blake3.config.Log.Trace("Verified correct proof-of-work", "sealhash", sealhash, "elapsed", common.PrettyDuration(time.Since(start)))
@@ -544,13 +628,16 @@ func (s *remoteSealer) submitWork(nonce types.BlockNonce, mixDigest common.Hash,
 		select {
 		case s.results <- solution:
 			s.blake3.config.Log.Debug("Work submitted is acceptable", "number", solution.NumberU64(), "sealhash", sealhash, "hash", solution.Hash())
+			s.shares.accept(s.blake3, header)
 			return true
 		default:
 			s.blake3.config.Log.Warn("Sealing result is not read by miner", "mode", "remote", "sealhash", sealhash)
+			s.shares.rejected++
 			return false
 		}
 	}
 	// The submitted block is too old to accept, drop it.
 	s.blake3.config.Log.Warn("Work submitted is too old", "number", solution.NumberU64(), "sealhash", sealhash, "hash", solution.Hash())
+	s.shares.stale++
 	return false
 }
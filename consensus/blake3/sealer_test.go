@@ -22,7 +22,6 @@ import (
 	"math/big"
 	"net/http"
 	"net/http/httptest"
-	"strconv"
 	"testing"
 	"time"
 
@@ -32,16 +31,57 @@ import (
 	"github.com/spruce-solutions/go-quai/log"
 )
 
+// sealTestHeader creates a header with the same parent, number and difficulty
+// in every context.
+func sealTestHeader(parent byte, number, difficulty int64) *types.Header {
+	header := types.NewEmptyHeader()
+	for i := 0; i < types.ContextDepth; i++ {
+		header.ParentHash[i] = common.BytesToHash([]byte{parent})
+		header.Number[i] = big.NewInt(number)
+		header.Difficulty[i] = big.NewInt(difficulty)
+	}
+	return header
+}
+
+// Tests that the remote sealer only runs if there are remote miners to hand
+// the work to.
+func TestRemoteSealerGating(t *testing.T) {
+	config := Config{Log: testlog.Logger(t, log.LvlWarn)}
+
+	blake3, _ := New(config, nil, false)
+	if blake3.remote != nil {
+		t.Errorf("remote sealer started without remote miners")
+	}
+	blake3.PushWork(types.NewBlockWithHeader(sealTestHeader(0x1, 1, 100)), nil)
+	blake3.Close()
+
+	blake3, _ = New(config, []string{"http://127.0.0.1:1"}, false)
+	if blake3.remote == nil {
+		t.Errorf("remote sealer not started for the notified miners")
+	}
+	blake3.Close()
+
+	config.StratumAddr = "127.0.0.1:0"
+	blake3, err := New(config, nil, false)
+	if err != nil {
+		t.Fatalf("failed to start the stratum server: %v", err)
+	}
+	if blake3.remote == nil || blake3.remote.stratum == nil {
+		t.Errorf("remote sealer not started for the stratum miners")
+	}
+	blake3.Close()
+}
+
 // Tests whether remote HTTP servers are correctly notified of new work.
 func TestRemoteNotify(t *testing.T) {
 	// Start a simple web server to capture notifications.
-	sink := make(chan [3]string)
+	sink := make(chan [8]string)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		blob, err := ioutil.ReadAll(req.Body)
 		if err != nil {
 			t.Errorf("failed to read miner notification: %v", err)
 		}
-		var work [3]string
+		var work [8]string
 		if err := json.Unmarshal(blob, &work); err != nil {
 			t.Errorf("failed to unmarshal miner notification: %v", err)
 		}
@@ -49,26 +89,26 @@ func TestRemoteNotify(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Create the custom ethash engine.
-	ethash := NewTester([]string{server.URL}, false)
-	defer ethash.Close()
+	// Create the custom blake3 engine.
+	blake3, _ := New(Config{Log: testlog.Logger(t, log.LvlWarn)}, []string{server.URL}, false)
+	defer blake3.Close()
 
 	// Stream a work task and ensure the notification bubbles out.
-	header := &types.Header{Number: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)}, Difficulty: []*big.Int{big.NewInt(100), big.NewInt(100), big.NewInt(100)}}
+	header := sealTestHeader(0x1, 1, 100)
 	block := types.NewBlockWithHeader(header)
 
-	ethash.Seal(nil, block, nil, nil)
+	blake3.PushWork(block, nil)
 	select {
 	case work := <-sink:
-		if want := ethash.SealHash(header).Hex(); work[0] != want {
+		if want := blake3.SealHash(header).Hex(); work[0] != want {
 			t.Errorf("work packet hash mismatch: have %s, want %s", work[0], want)
 		}
-		/*if want := common.BytesToHash(SeedHash(header.Number[types.QuaiNetworkContext].Uint64())).Hex(); work[1] != want {
-			t.Errorf("work packet seed mismatch: have %s, want %s", work[1], want)
-		}*/
 		target := new(big.Int).Div(new(big.Int).Lsh(big.NewInt(1), 256), header.Difficulty[types.QuaiNetworkContext])
-		if want := common.BytesToHash(target.Bytes()).Hex(); work[2] != want {
-			t.Errorf("work packet target mismatch: have %s, want %s", work[2], want)
+		if want := common.BytesToHash(target.Bytes()).Hex(); work[1] != want {
+			t.Errorf("work packet target mismatch: have %s, want %s", work[1], want)
+		}
+		if want := "0x1"; work[2] != want {
+			t.Errorf("work packet number mismatch: have %s, want %s", work[2], want)
 		}
 	case <-time.After(3 * time.Second):
 		t.Fatalf("notification timed out")
@@ -92,25 +132,28 @@ func TestRemoteNotifyFull(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Create the custom ethash engine.
+	// Create the custom blake3 engine.
 	config := Config{
 		NotifyFull: true,
 		Log:        testlog.Logger(t, log.LvlWarn),
 	}
 	blake3, _ := New(config, []string{server.URL}, false)
+	defer blake3.Close()
 
 	// Stream a work task and ensure the notification bubbles out.
-	header := &types.Header{Number: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)}, Difficulty: []*big.Int{big.NewInt(100), big.NewInt(100), big.NewInt(100)}}
+	header := sealTestHeader(0x1, 1, 100)
 	block := types.NewBlockWithHeader(header)
 
-	blake3.Seal(nil, block, nil, nil)
+	blake3.PushWork(block, nil)
 	select {
 	case work := <-sink:
-		if want := "0x" + strconv.FormatUint(header.Number[types.QuaiNetworkContext].Uint64(), 16); work["number"] != want {
+		numbers, _ := work["number"].([]interface{})
+		if want := float64(header.Number[types.QuaiNetworkContext].Uint64()); len(numbers) != types.ContextDepth || numbers[types.QuaiNetworkContext] != want {
 			t.Errorf("pending block number mismatch: have %v, want %v", work["number"], want)
 		}
-		if want := "0x" + header.Difficulty[types.QuaiNetworkContext].Text(16); work["difficulty"] != want {
-			t.Errorf("pending block difficulty mismatch: have %s, want %s", work["difficulty"], want)
+		difficulties, _ := work["difficulty"].([]interface{})
+		if want := float64(header.Difficulty[types.QuaiNetworkContext].Uint64()); len(difficulties) != types.ContextDepth || difficulties[types.QuaiNetworkContext] != want {
+			t.Errorf("pending block difficulty mismatch: have %v, want %v", work["difficulty"], want)
 		}
 	case <-time.After(3 * time.Second):
 		t.Fatalf("notification timed out")
@@ -121,13 +164,13 @@ func TestRemoteNotifyFull(t *testing.T) {
 // issues in the notifications.
 func TestRemoteMultiNotify(t *testing.T) {
 	// Start a simple web server to capture notifications.
-	sink := make(chan [3]string, 64)
+	sink := make(chan [8]string, 64)
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		blob, err := ioutil.ReadAll(req.Body)
 		if err != nil {
 			t.Errorf("failed to read miner notification: %v", err)
 		}
-		var work [3]string
+		var work [8]string
 		if err := json.Unmarshal(blob, &work); err != nil {
 			t.Errorf("failed to unmarshal miner notification: %v", err)
 		}
@@ -135,27 +178,18 @@ func TestRemoteMultiNotify(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Create the custom ethash engine.
-	ethash := NewTester([]string{server.URL}, false)
-	ethash.config.Log = testlog.Logger(t, log.LvlWarn)
-	defer ethash.Close()
-
-	// Provide a results reader.
-	// Otherwise the unread results will be logged asynchronously
-	// and this can happen after the test is finished, causing a panic.
-	results := make(chan *types.Block, cap(sink))
+	// Create the custom blake3 engine.
+	blake3, _ := New(Config{Log: testlog.Logger(t, log.LvlWarn)}, []string{server.URL}, false)
+	defer blake3.Close()
 
 	// Stream a lot of work task and ensure all the notifications bubble out.
 	for i := 0; i < cap(sink); i++ {
-		header := &types.Header{Number: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)}, Difficulty: []*big.Int{big.NewInt(100), big.NewInt(100), big.NewInt(100)}}
-		block := types.NewBlockWithHeader(header)
-		ethash.Seal(nil, block, results, nil)
+		block := types.NewBlockWithHeader(sealTestHeader(0x1, int64(i+1), 100))
+		blake3.PushWork(block, nil)
 	}
-
 	for i := 0; i < cap(sink); i++ {
 		select {
 		case <-sink:
-			<-results
 		case <-time.After(10 * time.Second):
 			t.Fatalf("notification %d timed out", i)
 		}
@@ -180,31 +214,22 @@ func TestRemoteMultiNotifyFull(t *testing.T) {
 	}))
 	defer server.Close()
 
-	// Create the custom ethash engine.
+	// Create the custom blake3 engine.
 	config := Config{
-		Fakepow:    true,
 		NotifyFull: true,
 		Log:        testlog.Logger(t, log.LvlWarn),
 	}
-	ethash, _ := New(config, []string{server.URL}, false)
-	defer ethash.Close()
-
-	// Provide a results reader.
-	// Otherwise the unread results will be logged asynchronously
-	// and this can happen after the test is finished, causing a panic.
-	results := make(chan *types.Block, cap(sink))
+	blake3, _ := New(config, []string{server.URL}, false)
+	defer blake3.Close()
 
 	// Stream a lot of work task and ensure all the notifications bubble out.
 	for i := 0; i < cap(sink); i++ {
-		header := &types.Header{Number: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)}, Difficulty: []*big.Int{big.NewInt(100), big.NewInt(100), big.NewInt(100)}}
-		block := types.NewBlockWithHeader(header)
-		ethash.Seal(nil, block, results, nil)
+		block := types.NewBlockWithHeader(sealTestHeader(0x1, int64(i+1), 100))
+		blake3.PushWork(block, nil)
 	}
-
 	for i := 0; i < cap(sink); i++ {
 		select {
 		case <-sink:
-			<-results
 		case <-time.After(10 * time.Second):
 			t.Fatalf("notification %d timed out", i)
 		}
@@ -213,9 +238,12 @@ func TestRemoteMultiNotifyFull(t *testing.T) {
 
 // Tests whether stale solutions are correctly processed.
 func TestStaleSubmission(t *testing.T) {
-	ethash := NewTester(nil, true)
-	defer ethash.Close()
-	api := &API{ethash}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	blake3, _ := New(Config{Log: testlog.Logger(t, log.LvlWarn)}, []string{server.URL}, true)
+	defer blake3.Close()
+	api := &API{blake3}
 
 	fakeNonce, fakeDigest := types.BlockNonce{0x01, 0x02, 0x03}, common.HexToHash("deadbeef")
 
@@ -227,7 +255,7 @@ func TestStaleSubmission(t *testing.T) {
 		// Case1: submit solution for the latest mining package
 		{
 			[]*types.Header{
-				{ParentHash: []common.Hash{common.BytesToHash([]byte{0xa}), common.BytesToHash([]byte{0xa}), common.BytesToHash([]byte{0xa})}, Number: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)}, Difficulty: []*big.Int{big.NewInt(100000000), big.NewInt(100000000), big.NewInt(100000000)}},
+				sealTestHeader(0xa, 1, 100000000),
 			},
 			0,
 			true,
@@ -235,8 +263,8 @@ func TestStaleSubmission(t *testing.T) {
 		// Case2: submit solution for the previous package but have same parent.
 		{
 			[]*types.Header{
-				{ParentHash: []common.Hash{common.BytesToHash([]byte{0xb}), common.BytesToHash([]byte{0xb}), common.BytesToHash([]byte{0xb})}, Number: []*big.Int{big.NewInt(2), big.NewInt(2), big.NewInt(2)}, Difficulty: []*big.Int{big.NewInt(100000000), big.NewInt(100000000), big.NewInt(100000000)}},
-				{ParentHash: []common.Hash{common.BytesToHash([]byte{0xb}), common.BytesToHash([]byte{0xb}), common.BytesToHash([]byte{0xb})}, Number: []*big.Int{big.NewInt(2), big.NewInt(2), big.NewInt(2)}, Difficulty: []*big.Int{big.NewInt(100000001), big.NewInt(100000001), big.NewInt(100000001)}},
+				sealTestHeader(0xb, 2, 100000000),
+				sealTestHeader(0xb, 2, 100000001),
 			},
 			0,
 			true,
@@ -244,8 +272,8 @@ func TestStaleSubmission(t *testing.T) {
 		// Case3: submit stale but acceptable solution
 		{
 			[]*types.Header{
-				{ParentHash: []common.Hash{common.BytesToHash([]byte{0xc}), common.BytesToHash([]byte{0xc}), common.BytesToHash([]byte{0xc})}, Number: []*big.Int{big.NewInt(3), big.NewInt(3), big.NewInt(3)}, Difficulty: []*big.Int{big.NewInt(100000000), big.NewInt(100000000), big.NewInt(100000000)}},
-				{ParentHash: []common.Hash{common.BytesToHash([]byte{0xd}), common.BytesToHash([]byte{0xd}), common.BytesToHash([]byte{0xd})}, Number: []*big.Int{big.NewInt(9), big.NewInt(9), big.NewInt(9)}, Difficulty: []*big.Int{big.NewInt(100000000), big.NewInt(100000000), big.NewInt(100000000)}},
+				sealTestHeader(0xc, 3, 100000000),
+				sealTestHeader(0xd, 9, 100000000),
 			},
 			0,
 			true,
@@ -253,8 +281,8 @@ func TestStaleSubmission(t *testing.T) {
 		// Case4: submit very old solution
 		{
 			[]*types.Header{
-				{ParentHash: []common.Hash{common.BytesToHash([]byte{0xe}), common.BytesToHash([]byte{0xe}), common.BytesToHash([]byte{0xe})}, Number: []*big.Int{big.NewInt(10), big.NewInt(10), big.NewInt(10)}, Difficulty: []*big.Int{big.NewInt(100000000), big.NewInt(100000000), big.NewInt(100000000)}},
-				{ParentHash: []common.Hash{common.BytesToHash([]byte{0xf}), common.BytesToHash([]byte{0xf}), common.BytesToHash([]byte{0xf})}, Number: []*big.Int{big.NewInt(17), big.NewInt(17), big.NewInt(17)}, Difficulty: []*big.Int{big.NewInt(100000000), big.NewInt(100000000), big.NewInt(100000000)}},
+				sealTestHeader(0xe, 10, 100000000),
+				sealTestHeader(0xf, 17, 100000000),
 			},
 			0,
 			false,
@@ -264,9 +292,9 @@ func TestStaleSubmission(t *testing.T) {
 
 	for id, c := range testcases {
 		for _, h := range c.headers {
-			ethash.Seal(nil, types.NewBlockWithHeader(h), results, nil)
+			blake3.PushWork(types.NewBlockWithHeader(h), results)
 		}
-		if res := api.SubmitWork(fakeNonce, ethash.SealHash(c.headers[c.submitIndex]), fakeDigest); res != c.submitRes {
+		if res := api.SubmitWork(fakeNonce, blake3.SealHash(c.headers[c.submitIndex]), fakeDigest); res != c.submitRes {
 			t.Errorf("case %d submit result mismatch, want %t, get %t", id+1, c.submitRes, res)
 		}
 		if !c.submitRes {
@@ -277,9 +305,6 @@ func TestStaleSubmission(t *testing.T) {
 			if res.Header().Nonce != fakeNonce {
 				t.Errorf("case %d block nonce mismatch, want %x, get %x", id+1, fakeNonce, res.Header().Nonce)
 			}
-			/*if res.Header().MixDigest[types.QuaiNetworkContext] != fakeDigest[0] {
-				t.Errorf("case %d block digest mismatch, want %x, get %x", id+1, fakeDigest, res.Header().MixDigest)
-			}*/
 			if res.Header().Difficulty[types.QuaiNetworkContext].Uint64() != c.headers[c.submitIndex].Difficulty[types.QuaiNetworkContext].Uint64() {
 				t.Errorf("case %d block difficulty mismatch, want %d, get %d", id+1, c.headers[c.submitIndex].Difficulty, res.Header().Difficulty)
 			}
@@ -290,7 +315,7 @@ func TestStaleSubmission(t *testing.T) {
 				t.Errorf("case %d block parent hash mismatch, want %s, get %s", id+1, c.headers[c.submitIndex].ParentHash[types.QuaiNetworkContext].Hex(), res.Header().ParentHash[types.QuaiNetworkContext].Hex())
 			}
 		case <-time.NewTimer(time.Second).C:
-			t.Errorf("case %d fetch ethash result timeout", id+1)
+			t.Errorf("case %d fetch blake3 result timeout", id+1)
 		}
 	}
 }
@@ -0,0 +1,173 @@
+package blake3
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// maxSimulatedBlocks caps the difficulty adjustments simulated per chain.
+const maxSimulatedBlocks = 1000
+
+// SimulationArgs is a hypothetical split of the hash rate across the locations
+// to simulate the difficulty adjustments of.
+type SimulationArgs struct {
+	// Hash rate in hashes per second mining every zone, by region. Region and
+	// Prime blocks are found by the hash rate of all the zones below them.
+	Hashrates [][]float64 `json:"hashrates"`
+
+	// Number of difficulty adjustments simulated per chain.
+	Blocks int `json:"blocks"`
+
+	// Starting difficulties overriding the ones of the start header, which
+	// every region and zone starts from otherwise.
+	PrimeDifficulty  *hexutil.Big     `json:"primeDifficulty,omitempty"`
+	RegionDifficulty []*hexutil.Big   `json:"regionDifficulty,omitempty"`
+	ZoneDifficulty   [][]*hexutil.Big `json:"zoneDifficulty,omitempty"`
+}
+
+// DifficultyStep is a simulated block of a chain.
+type DifficultyStep struct {
+	Number     *hexutil.Big `json:"number"`
+	Difficulty *hexutil.Big `json:"difficulty"`
+	BlockTime  float64      `json:"blockTime"` // expected seconds to find the block
+}
+
+// ChainSimulation is the simulated difficulty of a chain. Regions and zones
+// are numbered from 1, Prime has neither.
+type ChainSimulation struct {
+	Context  int               `json:"context"`
+	Region   int               `json:"region,omitempty"`
+	Zone     int               `json:"zone,omitempty"`
+	Hashrate float64           `json:"hashrate"` // hash rate finding the blocks of the chain
+	Blocks   []*DifficultyStep `json:"blocks"`
+}
+
+// DifficultySimulation is the outcome of a difficulty simulation.
+type DifficultySimulation struct {
+	Prime   *ChainSimulation     `json:"prime"`
+	Regions []*ChainSimulation   `json:"regions"`
+	Zones   [][]*ChainSimulation `json:"zones"`
+}
+
+// SimulateDifficulty simulates the next difficulty adjustments of the Prime,
+// Region and Zone chains from the start header under the hash rate split. Every
+// block is assumed to be found after the expected time at the difficulty of its
+// parent, so the simulation shows the trend rather than the variance of the
+// adjustments.
+func SimulateDifficulty(start *types.Header, args *SimulationArgs) (*DifficultySimulation, error) {
+	if args.Blocks <= 0 || args.Blocks > maxSimulatedBlocks {
+		return nil, fmt.Errorf("blocks must be between 1 and %d", maxSimulatedBlocks)
+	}
+	if len(args.Hashrates) == 0 {
+		return nil, errors.New("no hash rate given")
+	}
+	if len(start.Difficulty) < types.ContextDepth || len(start.Number) < types.ContextDepth {
+		return nil, errors.New("start header misses contexts")
+	}
+	startDifficulty := func(context int, override *hexutil.Big) (*big.Int, error) {
+		if override != nil {
+			return (*big.Int)(override), nil
+		}
+		if start.Difficulty[context] == nil {
+			return nil, fmt.Errorf("start header has no difficulty for context %d", context)
+		}
+		return start.Difficulty[context], nil
+	}
+	var (
+		sim   = &DifficultySimulation{Regions: make([]*ChainSimulation, len(args.Hashrates)), Zones: make([][]*ChainSimulation, len(args.Hashrates))}
+		total float64
+	)
+	for r, zones := range args.Hashrates {
+		if len(zones) == 0 {
+			return nil, fmt.Errorf("region %d has no zones", r+1)
+		}
+		var regional float64
+		sim.Zones[r] = make([]*ChainSimulation, len(zones))
+		for z, hashrate := range zones {
+			if hashrate <= 0 {
+				return nil, fmt.Errorf("zone %d-%d has no hash rate", r+1, z+1)
+			}
+			var override *hexutil.Big
+			if r < len(args.ZoneDifficulty) && z < len(args.ZoneDifficulty[r]) {
+				override = args.ZoneDifficulty[r][z]
+			}
+			difficulty, err := startDifficulty(params.ZONE, override)
+			if err != nil {
+				return nil, err
+			}
+			sim.Zones[r][z] = simulateChain(params.ZONE, difficulty, start.Number[params.ZONE], hashrate, args.Blocks)
+			sim.Zones[r][z].Region, sim.Zones[r][z].Zone = r+1, z+1
+			regional += hashrate
+		}
+		var override *hexutil.Big
+		if r < len(args.RegionDifficulty) {
+			override = args.RegionDifficulty[r]
+		}
+		difficulty, err := startDifficulty(params.REGION, override)
+		if err != nil {
+			return nil, err
+		}
+		sim.Regions[r] = simulateChain(params.REGION, difficulty, start.Number[params.REGION], regional, args.Blocks)
+		sim.Regions[r].Region = r + 1
+		total += regional
+	}
+	difficulty, err := startDifficulty(params.PRIME, args.PrimeDifficulty)
+	if err != nil {
+		return nil, err
+	}
+	sim.Prime = simulateChain(params.PRIME, difficulty, start.Number[params.PRIME], total, args.Blocks)
+	return sim, nil
+}
+
+// simulateChain simulates the blocks of a chain of the context mined with the
+// hash rate, starting with the difficulty and number of its head.
+func simulateChain(context int, difficulty *big.Int, number *big.Int, hashrate float64, blocks int) *ChainSimulation {
+	chain := &ChainSimulation{
+		Context:  context,
+		Hashrate: hashrate,
+		Blocks:   make([]*DifficultyStep, 0, blocks),
+	}
+	interval := blockTime(difficulty, hashrate)
+	for i := 0; i < blocks; i++ {
+		// Each block is assumed to be found the expected time at the difficulty
+		// of its parent after it
+		seconds, _ := new(big.Float).SetFloat64(interval).Int(nil)
+		difficulty = adjustDifficulty(context, difficulty, number, seconds)
+		number = new(big.Int).Add(number, big1)
+		interval = blockTime(difficulty, hashrate)
+
+		chain.Blocks = append(chain.Blocks, &DifficultyStep{
+			Number:     (*hexutil.Big)(number),
+			Difficulty: (*hexutil.Big)(difficulty),
+			BlockTime:  interval,
+		})
+	}
+	return chain
+}
+
+// blockTime returns the expected seconds to find a block at the difficulty,
+// the expected number of hashes being the difficulty itself.
+func blockTime(difficulty *big.Int, hashrate float64) float64 {
+	seconds, _ := new(big.Float).Quo(new(big.Float).SetInt(difficulty), big.NewFloat(hashrate)).Float64()
+	return seconds
+}
+
+// DebugAPI exposes the difficulty simulation in the debug namespace.
+type DebugAPI struct {
+	chain consensus.ChainHeaderReader
+}
+
+// SimulateDifficulty simulates the next difficulty adjustments of the Prime,
+// Region and Zone chains from the current head under the hash rate split.
+func (api *DebugAPI) SimulateDifficulty(args SimulationArgs) (*DifficultySimulation, error) {
+	if api.chain == nil {
+		return nil, errors.New("no chain available")
+	}
+	return SimulateDifficulty(api.chain.CurrentHeader(), &args)
+}
@@ -0,0 +1,121 @@
+package blake3
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// simulationStart returns a head at the same height and difficulty in every
+// context.
+func simulationStart(number int64, difficulty *big.Int) *types.Header {
+	header := types.NewEmptyHeader()
+	for ctx := range header.Number {
+		header.Number[ctx] = big.NewInt(number)
+		header.Difficulty[ctx] = new(big.Int).Set(difficulty)
+	}
+	return header
+}
+
+// Tests that the simulation rejects invalid arguments, including the bounds of
+// the simulated blocks.
+func TestSimulateDifficultyArgs(t *testing.T) {
+	start := simulationStart(10, big.NewInt(1<<20))
+	hashrates := [][]float64{{1e6}}
+
+	tests := []struct {
+		name  string
+		start *types.Header
+		args  SimulationArgs
+		ok    bool
+	}{
+		{"no blocks", start, SimulationArgs{Hashrates: hashrates}, false},
+		{"negative blocks", start, SimulationArgs{Hashrates: hashrates, Blocks: -1}, false},
+		{"one block", start, SimulationArgs{Hashrates: hashrates, Blocks: 1}, true},
+		{"maximum blocks", start, SimulationArgs{Hashrates: hashrates, Blocks: maxSimulatedBlocks}, true},
+		{"too many blocks", start, SimulationArgs{Hashrates: hashrates, Blocks: maxSimulatedBlocks + 1}, false},
+		{"no hash rate", start, SimulationArgs{Blocks: 1}, false},
+		{"no zones", start, SimulationArgs{Hashrates: [][]float64{{1e6}, {}}, Blocks: 1}, false},
+		{"idle zone", start, SimulationArgs{Hashrates: [][]float64{{1e6, 0}}, Blocks: 1}, false},
+		{"missing contexts", &types.Header{Number: []*big.Int{big.NewInt(1)}, Difficulty: []*big.Int{big.NewInt(1)}}, SimulationArgs{Hashrates: hashrates, Blocks: 1}, false},
+		{"missing difficulty", types.NewEmptyHeader(), SimulationArgs{Hashrates: hashrates, Blocks: 1}, false},
+	}
+	for _, tt := range tests {
+		sim, err := SimulateDifficulty(tt.start, &tt.args)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: error mismatch: have %v, want ok %v", tt.name, err, tt.ok)
+			continue
+		}
+		if tt.ok && len(sim.Prime.Blocks) != tt.args.Blocks {
+			t.Errorf("%s: simulated blocks mismatch: have %d, want %d", tt.name, len(sim.Prime.Blocks), tt.args.Blocks)
+		}
+	}
+}
+
+// Tests that the simulated chains follow the difficulty adjustment of their
+// context, with the hash rates of the zones adding up in the dominant chains.
+func TestSimulateDifficulty(t *testing.T) {
+	var (
+		start  = simulationStart(10, big.NewInt(1<<20))
+		blocks = 16
+	)
+	args := &SimulationArgs{
+		Hashrates:       [][]float64{{1e6, 1}, {2e6}},
+		Blocks:          blocks,
+		PrimeDifficulty: (*hexutil.Big)(big.NewInt(1 << 40)),
+		ZoneDifficulty:  [][]*hexutil.Big{{nil, (*hexutil.Big)(big.NewInt(131172))}},
+	}
+	sim, err := SimulateDifficulty(start, args)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	if len(sim.Regions) != 2 || len(sim.Zones) != 2 || len(sim.Zones[0]) != 2 || len(sim.Zones[1]) != 1 {
+		t.Fatalf("simulated chains mismatch: %d regions, zones %d", len(sim.Regions), len(sim.Zones))
+	}
+	if sim.Prime.Hashrate != 3e6+1 || sim.Regions[0].Hashrate != 1e6+1 || sim.Regions[1].Hashrate != 2e6 {
+		t.Errorf("aggregated hash rates mismatch: prime %v, regions %v, %v", sim.Prime.Hashrate, sim.Regions[0].Hashrate, sim.Regions[1].Hashrate)
+	}
+	if zone := sim.Zones[0][1]; zone.Context != params.ZONE || zone.Region != 1 || zone.Zone != 2 {
+		t.Errorf("zone numbering mismatch: context %d, region %d, zone %d", zone.Context, zone.Region, zone.Zone)
+	}
+	// Every chain replays the adjustment of its context
+	check := func(name string, chain *ChainSimulation, context int, difficulty *big.Int) {
+		t.Helper()
+
+		number := big.NewInt(10)
+		for i, step := range chain.Blocks {
+			interval, _ := new(big.Float).SetFloat64(blockTime(difficulty, chain.Hashrate)).Int(nil)
+			difficulty = adjustDifficulty(context, difficulty, number, interval)
+			number = new(big.Int).Add(number, big1)
+
+			if (*big.Int)(step.Number).Cmp(number) != 0 {
+				t.Errorf("%s block %d: number mismatch: have %v, want %v", name, i, step.Number, number)
+			}
+			if (*big.Int)(step.Difficulty).Cmp(difficulty) != 0 {
+				t.Errorf("%s block %d: difficulty mismatch: have %v, want %v", name, i, step.Difficulty, difficulty)
+			}
+		}
+	}
+	check("prime", sim.Prime, params.PRIME, big.NewInt(1<<40))
+	check("region 1", sim.Regions[0], params.REGION, big.NewInt(1<<20))
+	check("zone 1-1", sim.Zones[0][0], params.ZONE, big.NewInt(1<<20))
+	check("zone 1-2", sim.Zones[0][1], params.ZONE, big.NewInt(131172))
+
+	// Fast zones get harder, slow ones easier down to the minimum
+	fast, slow := sim.Zones[0][0].Blocks, sim.Zones[0][1].Blocks
+	if (*big.Int)(fast[0].Difficulty).Cmp(big.NewInt(1<<20+1<<9)) != 0 {
+		t.Errorf("fast zone first difficulty mismatch: have %v, want %v", fast[0].Difficulty, 1<<20+1<<9)
+	}
+	if (*big.Int)(fast[blocks-1].Difficulty).Cmp(big.NewInt(1<<20)) <= 0 {
+		t.Errorf("fast zone difficulty not raised: %v", fast[blocks-1].Difficulty)
+	}
+	if (*big.Int)(slow[blocks-1].Difficulty).Cmp(params.MinimumDifficulty[params.ZONE]) != 0 {
+		t.Errorf("slow zone difficulty not at the minimum: %v", slow[blocks-1].Difficulty)
+	}
+	if fast[0].BlockTime >= fast[blocks-1].BlockTime {
+		t.Errorf("fast zone block time not rising: %v -> %v", fast[0].BlockTime, fast[blocks-1].BlockTime)
+	}
+}
@@ -0,0 +1,113 @@
+package blake3
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+)
+
+// shareStats counts the outcome of the solutions submitted by remote miners.
+// It is only accessed from the remote sealer loop.
+type shareStats struct {
+	accepted []uint64 // accepted solutions by the order they satisfy
+	stale    uint64   // solutions for work no longer pending or too old
+	invalid  uint64   // solutions failing proof-of-work verification
+	rejected uint64   // valid solutions the miner could not take
+	shares   uint64   // stratum shares below the difficulty of the chain
+}
+
+// accept records an accepted solution at the highest context it satisfies.
+func (s *shareStats) accept(blake3 *Blake3, header *types.Header) {
+	order, err := blake3.GetDifficultyOrder(header)
+	if err != nil || order < 0 || order >= types.ContextDepth {
+		order = types.ContextDepth - 1
+	}
+	if s.accepted == nil {
+		s.accepted = make([]uint64, types.ContextDepth)
+	}
+	s.accepted[order]++
+}
+
+// WorkerStats is the hash rate last reported by a remote worker.
+type WorkerStats struct {
+	ID       common.Hash    `json:"id"`
+	Hashrate hexutil.Uint64 `json:"hashrate"`
+	LastSeen time.Time      `json:"lastSeen"`
+}
+
+// Stats is the mining performance summary returned by miner_stats.
+type Stats struct {
+	Hashrate       hexutil.Uint64 `json:"hashrate"`       // measured local and reported remote hash rate
+	RemoteHashrate hexutil.Uint64 `json:"remoteHashrate"` // sum of the hash rates reported by workers
+	Workers        []*WorkerStats `json:"workers"`
+
+	// Accepted solutions indexed by context, a prime solution is counted once
+	// at prime even though it satisfies the region and zone thresholds too.
+	Accepted []hexutil.Uint64 `json:"accepted"`
+	Stale    hexutil.Uint64   `json:"stale"`
+	Invalid  hexutil.Uint64   `json:"invalid"`
+	Rejected hexutil.Uint64   `json:"rejected"`
+
+	// Shares of stratum miners meeting the share difficulty but not the one
+	// of the chain.
+	Shares hexutil.Uint64 `json:"shares"`
+
+	WorkNumber *hexutil.Big `json:"workNumber,omitempty"` // number of the current work package
+	WorkAge    float64      `json:"workAge"`              // seconds since the current work package was created
+}
+
+// stats assembles the remote sealer statistics. It must be called from the loop.
+func (s *remoteSealer) stats() *Stats {
+	stats := &Stats{
+		Hashrate: hexutil.Uint64(s.blake3.Hashrate()),
+		Workers:  make([]*WorkerStats, 0, len(s.rates)),
+		Accepted: make([]hexutil.Uint64, types.ContextDepth),
+		Stale:    hexutil.Uint64(s.shares.stale),
+		Invalid:  hexutil.Uint64(s.shares.invalid),
+		Rejected: hexutil.Uint64(s.shares.rejected),
+		Shares:   hexutil.Uint64(s.shares.shares),
+	}
+	var remote uint64
+	for id, rate := range s.rates {
+		remote += rate.rate
+		stats.Workers = append(stats.Workers, &WorkerStats{ID: id, Hashrate: hexutil.Uint64(rate.rate), LastSeen: rate.ping})
+	}
+	sort.Slice(stats.Workers, func(i, j int) bool {
+		return stats.Workers[i].Hashrate > stats.Workers[j].Hashrate
+	})
+	stats.RemoteHashrate = hexutil.Uint64(remote)
+	stats.Hashrate += stats.RemoteHashrate
+
+	for i, n := range s.shares.accepted {
+		stats.Accepted[i] = hexutil.Uint64(n)
+	}
+	if s.currentBlock != nil {
+		stats.WorkNumber = (*hexutil.Big)(s.currentBlock.Number())
+		stats.WorkAge = time.Since(s.workTime).Seconds()
+	}
+	return stats
+}
+
+// MinerAPI exposes the remote mining statistics of the blake3 engine.
+type MinerAPI struct {
+	blake3 *Blake3
+}
+
+// Stats returns the hash rate reported by the connected workers, the accepted,
+// stale and rejected solutions and the age of the current work package.
+func (api *MinerAPI) Stats() (*Stats, error) {
+	if api.blake3.remote == nil {
+		return nil, errors.New("not supported")
+	}
+	req := make(chan *Stats, 1)
+	select {
+	case api.blake3.remote.fetchStatsCh <- req:
+	case <-api.blake3.remote.exitCh:
+		return nil, errBlake3Stopped
+	}
+	return <-req, nil
+}
@@ -0,0 +1,487 @@
+package blake3
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+)
+
+// The stratum server hands the work of the remote sealer to pool software and
+// mining devices over line delimited JSON-RPC, as in stratum v1. Stratum v2 is
+// not spoken, its binary framing and encrypted transport are left to a proxy
+// translating to this endpoint.
+//
+// The work is notified as
+//
+//	mining.notify [job id, seal encoding, prime target, region target, zone target, number, clean]
+//
+// where the seal encoding has a zero nonce in its last 8 bytes and the targets
+// of the contexts without difficulty are null. The 8 byte nonce of a solution
+// starts with the extranonce handed out on subscription and is submitted as
+//
+//	mining.submit [worker, job id, nonce]
+//
+// either in full or without the extranonce. Shares satisfying the difficulty of
+// a context the node seals blocks of are submitted as blocks and counted by the
+// most dominant context they satisfy, the others only have to meet the share
+// target sent with mining.set_target.
+const (
+	stratumExtranonceSize = 2                // bytes of the nonce fixed per connection
+	stratumMaxLine        = 4096             // maximum length of a request
+	stratumIdleTimeout    = 10 * time.Minute // time a connection may stay silent
+	stratumWriteTimeout   = 10 * time.Second // time a message may take to be written
+	stratumSendQueue      = 16               // messages queued per connection before dropping it
+)
+
+// stratumError is an error reported to stratum miners, encoded as the usual
+// [code, message, traceback] triple.
+type stratumError struct {
+	code    int
+	message string
+}
+
+func (e *stratumError) Error() string { return e.message }
+
+func (e *stratumError) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{e.code, e.message, nil})
+}
+
+var (
+	errStratumUnknownMethod = &stratumError{20, "unknown method"}
+	errStratumMalformed     = &stratumError{20, "malformed submission"}
+	errStratumRejected      = &stratumError{20, "solution rejected"}
+	errStratumStopped       = &stratumError{20, "stratum server stopped"}
+	errStratumJobNotFound   = &stratumError{21, "job not found"}
+	errStratumDuplicate     = &stratumError{22, "duplicate share"}
+	errStratumLowDifficulty = &stratumError{23, "low difficulty share"}
+	errStratumUnauthorized  = &stratumError{24, "unauthorized worker"}
+	errStratumNotSubscribed = &stratumError{25, "not subscribed"}
+)
+
+// stratumRequest is a request of a stratum miner.
+type stratumRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params []interface{}   `json:"params"`
+}
+
+// stratumResponse is the reply to a stratum request.
+type stratumResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result interface{}     `json:"result"`
+	Error  *stratumError   `json:"error"`
+}
+
+// stratumNotification is a message pushed to stratum miners, without id.
+type stratumNotification struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params []interface{}   `json:"params"`
+}
+
+// stratumJob is the work of the remote sealer as notified to stratum miners.
+type stratumJob struct {
+	id       string        // seal hash of the work, without 0x prefix
+	template string        // seal encoding with a zero nonce
+	targets  []interface{} // targets by context, nil for contexts without difficulty
+	share    string        // target of the shares
+	number   string
+}
+
+// stratumShare is a nonce submitted by a stratum miner for a job.
+type stratumShare struct {
+	nonce    types.BlockNonce
+	sealhash common.Hash
+	res      chan *stratumShareResult
+}
+
+// stratumShareResult is the outcome of a share, the order is the most dominant
+// context of the block it sealed or types.ContextDepth for plain shares.
+type stratumShareResult struct {
+	order int
+	err   error
+}
+
+// stratumJob assembles the stratum job of the current work. It must be called
+// from the loop.
+func (s *remoteSealer) stratumJob() *stratumJob {
+	job := &stratumJob{
+		id:       strings.TrimPrefix(s.currentWork[0], "0x"),
+		template: s.currentWork[3],
+		targets:  make([]interface{}, types.ContextDepth),
+		share:    encodeTarget(s.shareTarget(s.currentBlock)),
+		number:   s.currentWork[2],
+	}
+	for i := range job.targets {
+		if target := s.currentWork[4+i]; target != "" {
+			job.targets[i] = target
+		}
+	}
+	return job
+}
+
+// shareTarget returns the target of the shares of the block, the one of its
+// difficulty unless a share difficulty is configured.
+func (s *remoteSealer) shareTarget(block *types.Block) *big.Int {
+	difficulty := block.Difficulty()
+	if s.blake3.config.StratumDifficulty > 0 {
+		difficulty = new(big.Int).SetUint64(s.blake3.config.StratumDifficulty)
+	}
+	if difficulty == nil || difficulty.Sign() <= 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Div(big2e256, difficulty)
+}
+
+// encodeTarget encodes the target as 32 byte hex, the target 2^256 of the unit
+// difficulty is capped to fit.
+func encodeTarget(target *big.Int) string {
+	if target.Cmp(big2e256) >= 0 {
+		target = new(big.Int).Sub(big2e256, common.Big1)
+	}
+	return common.BytesToHash(target.Bytes()).Hex()
+}
+
+// submitShare classifies a share of a stratum miner, submitting it as block if
+// it satisfies the difficulty of a context the node seals blocks of. It returns
+// the most dominant context satisfied or types.ContextDepth for plain shares.
+func (s *remoteSealer) submitShare(nonce types.BlockNonce, sealhash common.Hash) (int, error) {
+	block := s.works[sealhash]
+	if s.currentBlock == nil || block == nil {
+		s.shares.stale++
+		return 0, errStratumJobNotFound
+	}
+	nonces := s.shareNonces[sealhash]
+	if nonces == nil {
+		nonces = make(map[types.BlockNonce]struct{})
+		s.shareNonces[sealhash] = nonces
+	}
+	if _, ok := nonces[nonce]; ok {
+		s.shares.invalid++
+		return 0, errStratumDuplicate
+	}
+	nonces[nonce] = struct{}{}
+
+	header := block.Header()
+	header.Nonce = nonce
+	if order, err := s.blake3.GetDifficultyOrder(header); err == nil && order <= types.QuaiNetworkContext {
+		if !s.submitWork(nonce, common.Hash{}, sealhash) {
+			return order, errStratumRejected
+		}
+		return order, nil
+	}
+	if new(big.Int).SetBytes(s.blake3.SealHash(header).Bytes()).Cmp(s.shareTarget(block)) > 0 {
+		s.shares.invalid++
+		return 0, errStratumLowDifficulty
+	}
+	s.shares.shares++
+	return types.ContextDepth, nil
+}
+
+// stratumServer accepts the connections of stratum miners.
+type stratumServer struct {
+	listener net.Listener
+	log      log.Logger
+	sealer   *remoteSealer
+
+	lock       sync.Mutex
+	conns      map[*stratumConn]struct{}
+	job        *stratumJob // last notified job
+	extranonce uint16      // extranonce of the next connection
+	closed     bool
+	wg         sync.WaitGroup
+}
+
+// stratumConn is the connection of a stratum miner.
+type stratumConn struct {
+	conn       net.Conn
+	extranonce [stratumExtranonceSize]byte
+	out        chan []byte
+	closing    chan struct{}
+	closeOnce  sync.Once
+
+	subscribed bool   // written by the reader with the server lock held
+	worker     string // authorized worker, only accessed by the reader
+}
+
+// listenStratum opens the listener of the stratum server, it serves once the
+// remote sealer is started.
+func listenStratum(addr string, logger log.Logger) (*stratumServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Stratum server started", "addr", listener.Addr())
+	return &stratumServer{
+		listener: listener,
+		log:      logger,
+		conns:    make(map[*stratumConn]struct{}),
+	}, nil
+}
+
+// serve starts accepting the stratum miners of the remote sealer.
+func (srv *stratumServer) serve(sealer *remoteSealer) {
+	srv.sealer = sealer
+	srv.wg.Add(1)
+	go srv.accept()
+}
+
+// close disconnects the stratum miners and stops accepting new ones.
+func (srv *stratumServer) close() {
+	srv.lock.Lock()
+	srv.closed = true
+	srv.listener.Close()
+	for c := range srv.conns {
+		c.close()
+	}
+	srv.lock.Unlock()
+
+	srv.wg.Wait()
+}
+
+// notify hands the job to the subscribed stratum miners, superseding the
+// previous ones.
+func (srv *stratumServer) notify(job *stratumJob) {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	srv.job = job
+	for c := range srv.conns {
+		if c.subscribed {
+			c.sendJob(job)
+		}
+	}
+}
+
+func (srv *stratumServer) accept() {
+	defer srv.wg.Done()
+
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			srv.lock.Lock()
+			closed := srv.closed
+			srv.lock.Unlock()
+			if !closed {
+				srv.log.Warn("Stratum server stopped accepting", "err", err)
+			}
+			return
+		}
+		srv.lock.Lock()
+		if srv.closed {
+			srv.lock.Unlock()
+			conn.Close()
+			return
+		}
+		c := &stratumConn{
+			conn:    conn,
+			out:     make(chan []byte, stratumSendQueue),
+			closing: make(chan struct{}),
+		}
+		c.extranonce[0], c.extranonce[1] = byte(srv.extranonce>>8), byte(srv.extranonce)
+		srv.extranonce++
+		srv.conns[c] = struct{}{}
+		srv.wg.Add(2)
+		srv.lock.Unlock()
+
+		srv.log.Debug("Stratum miner connected", "addr", conn.RemoteAddr(), "extranonce", hex.EncodeToString(c.extranonce[:]))
+		go srv.read(c)
+		go srv.write(c)
+	}
+}
+
+// read serves the requests of the stratum miner until it disconnects.
+func (srv *stratumServer) read(c *stratumConn) {
+	defer srv.wg.Done()
+	defer func() {
+		c.close()
+		srv.lock.Lock()
+		delete(srv.conns, c)
+		srv.lock.Unlock()
+		srv.log.Debug("Stratum miner disconnected", "addr", c.conn.RemoteAddr(), "worker", c.worker)
+	}()
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 0, 512), stratumMaxLine)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(stratumIdleTimeout))
+		if !scanner.Scan() {
+			return
+		}
+		var req stratumRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			srv.log.Debug("Malformed stratum request", "addr", c.conn.RemoteAddr(), "err", err)
+			return
+		}
+		srv.handle(c, &req)
+	}
+}
+
+// write sends the queued messages to the stratum miner.
+func (srv *stratumServer) write(c *stratumConn) {
+	defer srv.wg.Done()
+
+	for {
+		select {
+		case blob := <-c.out:
+			c.conn.SetWriteDeadline(time.Now().Add(stratumWriteTimeout))
+			if _, err := c.conn.Write(blob); err != nil {
+				c.close()
+				return
+			}
+		case <-c.closing:
+			return
+		}
+	}
+}
+
+// handle answers a request of the stratum miner.
+func (srv *stratumServer) handle(c *stratumConn, req *stratumRequest) {
+	switch req.Method {
+	case "mining.subscribe":
+		// Reply and hand out the current job atomically with respect to new
+		// jobs, so the miner doesn't start on a superseded one.
+		srv.lock.Lock()
+		defer srv.lock.Unlock()
+
+		c.subscribed = true
+		id := hex.EncodeToString(c.extranonce[:])
+		c.reply(req, []interface{}{
+			[]interface{}{[]string{"mining.set_target", id}, []string{"mining.notify", id}},
+			id,
+			len(types.BlockNonce{}) - stratumExtranonceSize,
+		}, nil)
+		if srv.job != nil {
+			c.sendJob(srv.job)
+		}
+
+	case "mining.authorize":
+		worker := stringParam(req.Params, 0)
+		if worker == "" {
+			c.reply(req, nil, errStratumUnauthorized)
+			return
+		}
+		c.worker = worker
+		c.reply(req, true, nil)
+
+	case "mining.extranonce.subscribe":
+		// The extranonce is fixed for the connection, there is nothing to
+		// subscribe to.
+		c.reply(req, true, nil)
+
+	case "mining.submit":
+		order, err := srv.submit(c, req.Params)
+		if err != nil {
+			srv.log.Debug("Rejected stratum share", "worker", c.worker, "err", err)
+			c.reply(req, nil, err.(*stratumError))
+			return
+		}
+		srv.log.Trace("Accepted stratum share", "worker", c.worker, "order", order)
+		c.reply(req, true, nil)
+
+	default:
+		c.reply(req, nil, errStratumUnknownMethod)
+	}
+}
+
+// submit hands a share of the stratum miner to the remote sealer.
+func (srv *stratumServer) submit(c *stratumConn, params []interface{}) (int, error) {
+	if !c.subscribed {
+		return 0, errStratumNotSubscribed
+	}
+	if c.worker == "" {
+		return 0, errStratumUnauthorized
+	}
+	sealhash, err := hex.DecodeString(strings.TrimPrefix(stringParam(params, 1), "0x"))
+	if err != nil || len(sealhash) != common.HashLength {
+		return 0, errStratumJobNotFound
+	}
+	blob, err := hex.DecodeString(strings.TrimPrefix(stringParam(params, 2), "0x"))
+	if err != nil {
+		return 0, errStratumMalformed
+	}
+	var nonce types.BlockNonce
+	switch len(blob) {
+	case len(nonce) - stratumExtranonceSize:
+		copy(nonce[:], c.extranonce[:])
+		copy(nonce[stratumExtranonceSize:], blob)
+	case len(nonce):
+		if string(blob[:stratumExtranonceSize]) != string(c.extranonce[:]) {
+			return 0, errStratumMalformed
+		}
+		copy(nonce[:], blob)
+	default:
+		return 0, errStratumMalformed
+	}
+	share := &stratumShare{
+		nonce:    nonce,
+		sealhash: common.BytesToHash(sealhash),
+		res:      make(chan *stratumShareResult, 1),
+	}
+	select {
+	case srv.sealer.shareCh <- share:
+	case <-srv.sealer.exitCh:
+		return 0, errStratumStopped
+	}
+	res := <-share.res
+	return res.order, res.err
+}
+
+// sendJob queues the target and the job for the stratum miner.
+func (c *stratumConn) sendJob(job *stratumJob) {
+	c.send(&stratumNotification{
+		Method: "mining.set_target",
+		Params: []interface{}{job.share},
+	})
+	params := []interface{}{job.id, job.template}
+	params = append(params, job.targets...)
+	params = append(params, job.number, true)
+	c.send(&stratumNotification{
+		Method: "mining.notify",
+		Params: params,
+	})
+}
+
+// reply queues the response to a request of the stratum miner.
+func (c *stratumConn) reply(req *stratumRequest, result interface{}, err *stratumError) {
+	c.send(&stratumResponse{ID: req.ID, Result: result, Error: err})
+}
+
+// send queues a message for the stratum miner, dropping miners too slow to
+// keep up.
+func (c *stratumConn) send(msg interface{}) {
+	blob, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	select {
+	case c.out <- append(blob, '\n'):
+	default:
+		c.close()
+	}
+}
+
+func (c *stratumConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.closing)
+		c.conn.Close()
+	})
+}
+
+// stringParam returns the string parameter at the index, empty if missing.
+func stringParam(params []interface{}, index int) string {
+	if index < len(params) {
+		if s, ok := params[index].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,291 @@
+package blake3
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/internal/testlog"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// unreachableDifficulty is a difficulty no share meets.
+var unreachableDifficulty = new(big.Int).Lsh(common.Big1, 255)
+
+// setSealingContext switches the context the node seals blocks of for the
+// duration of the test.
+func setSealingContext(t *testing.T, ctx int) {
+	prev := types.QuaiNetworkContext
+	types.QuaiNetworkContext = ctx
+	t.Cleanup(func() { types.QuaiNetworkContext = prev })
+}
+
+// shareTestBlock creates a block with the difficulties given per context, a
+// difficulty of one being met by any nonce.
+func shareTestBlock(number int64, difficulties ...*big.Int) *types.Block {
+	header := types.NewEmptyHeader()
+	for i := range difficulties {
+		header.Number[i] = big.NewInt(number)
+		header.Difficulty[i] = difficulties[i]
+	}
+	return types.NewBlockWithHeader(header)
+}
+
+// newShareTestSealer creates a remote sealer classifying shares without its
+// loop running, with the given share difficulty.
+func newShareTestSealer(t *testing.T, shareDifficulty uint64) (*remoteSealer, chan *types.Block) {
+	results := make(chan *types.Block, 8)
+	s := &remoteSealer{
+		blake3: &Blake3{config: Config{
+			Log:               testlog.Logger(t, log.LvlError),
+			StratumDifficulty: shareDifficulty,
+		}},
+		works:       make(map[common.Hash]*types.Block),
+		rates:       make(map[common.Hash]hashrate),
+		shareNonces: make(map[common.Hash]map[types.BlockNonce]struct{}),
+		results:     results,
+	}
+	return s, results
+}
+
+// Tests that the parameters of mining.submit are validated and the nonce is
+// completed with the extranonce of the connection.
+func TestStratumSubmitParams(t *testing.T) {
+	sealer := &remoteSealer{shareCh: make(chan *stratumShare), exitCh: make(chan struct{})}
+	srv := &stratumServer{sealer: sealer}
+
+	received := make(chan *stratumShare, 1)
+	go func() {
+		for share := range sealer.shareCh {
+			received <- share
+			share.res <- &stratumShareResult{order: types.ContextDepth}
+		}
+	}()
+	defer close(sealer.shareCh)
+
+	var (
+		job   = hex.EncodeToString(common.Hash{0x01}.Bytes())
+		conn  = &stratumConn{extranonce: [stratumExtranonceSize]byte{0x12, 0x34}, subscribed: true, worker: "rig"}
+		nonce = types.BlockNonce{0x12, 0x34, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	)
+	tests := []struct {
+		name   string
+		conn   *stratumConn
+		params []interface{}
+		err    error
+	}{
+		{"no params", conn, nil, errStratumJobNotFound},
+		{"worker only", conn, []interface{}{"rig"}, errStratumJobNotFound},
+		{"bad hex job", conn, []interface{}{"rig", "0xzz", "010203040506"}, errStratumJobNotFound},
+		{"short job", conn, []interface{}{"rig", job[2:], "010203040506"}, errStratumJobNotFound},
+		{"no nonce", conn, []interface{}{"rig", job}, errStratumMalformed},
+		{"numeric nonce", conn, []interface{}{"rig", job, 123456}, errStratumMalformed},
+		{"bad hex nonce", conn, []interface{}{"rig", job, "0x01020304050g"}, errStratumMalformed},
+		{"short nonce", conn, []interface{}{"rig", job, "0102030405"}, errStratumMalformed},
+		{"long nonce", conn, []interface{}{"rig", job, "123401020304050607"}, errStratumMalformed},
+		{"foreign extranonce", conn, []interface{}{"rig", job, "4321010203040506"}, errStratumMalformed},
+		{"unsubscribed", &stratumConn{worker: "rig"}, []interface{}{"rig", job, "010203040506"}, errStratumNotSubscribed},
+		{"unauthorized", &stratumConn{subscribed: true}, []interface{}{"rig", job, "010203040506"}, errStratumUnauthorized},
+		{"nonce without extranonce", conn, []interface{}{"rig", job, "010203040506"}, nil},
+		{"full nonce", conn, []interface{}{"rig", "0x" + job, "0x1234010203040506"}, nil},
+	}
+	for _, tt := range tests {
+		order, err := srv.submit(tt.conn, tt.params)
+		if err != tt.err {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if tt.err != nil {
+			continue
+		}
+		share := <-received
+		if share.nonce != nonce || share.sealhash != (common.Hash{0x01}) {
+			t.Errorf("%s: share mismatch: have %x for %x, want %x for %x", tt.name, share.nonce, share.sealhash, nonce, common.Hash{0x01})
+		}
+		if order != types.ContextDepth {
+			t.Errorf("%s: order mismatch: have %d, want %d", tt.name, order, types.ContextDepth)
+		}
+	}
+	// Shares of a stopped sealer are refused
+	close(sealer.exitCh)
+	srv.sealer = &remoteSealer{shareCh: make(chan *stratumShare), exitCh: sealer.exitCh}
+	if _, err := srv.submit(conn, []interface{}{"rig", job, "010203040506"}); err != errStratumStopped {
+		t.Errorf("stopped sealer error mismatch: have %v, want %v", err, errStratumStopped)
+	}
+}
+
+// Tests that shares are classified by the most dominant context they satisfy,
+// submitted as blocks only for the contexts the node seals.
+func TestStratumShareOrder(t *testing.T) {
+	var (
+		easy = common.Big1
+		hard = unreachableDifficulty
+	)
+	tests := []struct {
+		name    string
+		context int
+		share   uint64
+		block   *types.Block
+		order   int
+		err     error
+		sealed  bool
+	}{
+		{"prime block", params.ZONE, 1, shareTestBlock(1, easy, easy, easy), params.PRIME, nil, true},
+		{"region block", params.ZONE, 1, shareTestBlock(2, hard, easy, easy), params.REGION, nil, true},
+		{"zone block", params.ZONE, 1, shareTestBlock(3, hard, hard, easy), params.ZONE, nil, true},
+		{"plain share", params.ZONE, 1, shareTestBlock(4, hard, hard, hard), types.ContextDepth, nil, false},
+		{"low difficulty share", params.ZONE, 1 << 63, shareTestBlock(5, hard, hard, hard), 0, errStratumLowDifficulty, false},
+		{"zone share of a region node", params.REGION, 1, shareTestBlock(6, hard, hard, easy), types.ContextDepth, nil, false},
+		{"region block of a region node", params.REGION, 1, shareTestBlock(7, hard, easy, easy), params.REGION, nil, true},
+	}
+	for _, tt := range tests {
+		setSealingContext(t, tt.context)
+		s, results := newShareTestSealer(t, tt.share)
+		s.makeWork(tt.block)
+
+		nonce := types.BlockNonce{0x01}
+		order, err := s.submitShare(nonce, s.blake3.SealHash(tt.block.Header()))
+		if order != tt.order || err != tt.err {
+			t.Errorf("%s: result mismatch: have order %d, %v, want %d, %v", tt.name, order, err, tt.order, tt.err)
+		}
+		select {
+		case block := <-results:
+			if !tt.sealed {
+				t.Errorf("%s: share submitted as block", tt.name)
+			} else if block.Nonce() != nonce.Uint64() {
+				t.Errorf("%s: sealed nonce mismatch: have %x, want %x", tt.name, block.Nonce(), nonce)
+			}
+		default:
+			if tt.sealed {
+				t.Errorf("%s: block not submitted", tt.name)
+			}
+		}
+		if shares := s.shares.shares; (shares == 1) != (tt.order == types.ContextDepth) {
+			t.Errorf("%s: share count mismatch: have %d", tt.name, shares)
+		}
+	}
+}
+
+// Tests that a nonce is accepted once per job, and that shares of unknown jobs
+// are counted as stale.
+func TestStratumDuplicateShare(t *testing.T) {
+	setSealingContext(t, params.ZONE)
+
+	s, _ := newShareTestSealer(t, 1)
+	var (
+		first  = shareTestBlock(1, unreachableDifficulty, unreachableDifficulty, unreachableDifficulty)
+		second = shareTestBlock(2, unreachableDifficulty, unreachableDifficulty, unreachableDifficulty)
+	)
+	s.makeWork(first)
+	s.makeWork(second)
+
+	firstHash, secondHash := s.blake3.SealHash(first.Header()), s.blake3.SealHash(second.Header())
+	nonce := types.BlockNonce{0x01}
+	if _, err := s.submitShare(nonce, firstHash); err != nil {
+		t.Fatalf("first share rejected: %v", err)
+	}
+	if _, err := s.submitShare(nonce, firstHash); err != errStratumDuplicate {
+		t.Fatalf("duplicate share error mismatch: have %v, want %v", err, errStratumDuplicate)
+	}
+	// The nonce may be reused for other jobs, other nonces for the same job
+	if _, err := s.submitShare(nonce, secondHash); err != nil {
+		t.Errorf("nonce of another job rejected: %v", err)
+	}
+	if _, err := s.submitShare(types.BlockNonce{0x02}, firstHash); err != nil {
+		t.Errorf("other nonce of the job rejected: %v", err)
+	}
+	if _, err := s.submitShare(nonce, common.Hash{0xff}); err != errStratumJobNotFound {
+		t.Errorf("unknown job error mismatch: have %v, want %v", err, errStratumJobNotFound)
+	}
+	if s.shares.shares != 3 || s.shares.invalid != 1 || s.shares.stale != 1 {
+		t.Errorf("share counts mismatch: %d shares, %d invalid, %d stale, want 3, 1, 1", s.shares.shares, s.shares.invalid, s.shares.stale)
+	}
+}
+
+// Tests a stratum session over the network: subscription, authorization, the
+// job notification and the submission of a block solution.
+func TestStratumSession(t *testing.T) {
+	setSealingContext(t, params.ZONE)
+
+	blake3, err := New(Config{StratumAddr: "127.0.0.1:0", Log: testlog.Logger(t, log.LvlError)}, nil, true)
+	if err != nil {
+		t.Fatalf("failed to start the stratum server: %v", err)
+	}
+	defer blake3.Close()
+
+	conn, err := net.Dial("tcp", blake3.remote.stratum.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	request := func(id int, method string, params ...interface{}) {
+		blob, _ := json.Marshal(map[string]interface{}{"id": id, "method": method, "params": params})
+		if _, err := conn.Write(append(blob, '\n')); err != nil {
+			t.Fatalf("failed to send %s: %v", method, err)
+		}
+	}
+	read := func() map[string]interface{} {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("failed to read message: %v", err)
+		}
+		var msg map[string]interface{}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			t.Fatalf("malformed message %q: %v", line, err)
+		}
+		return msg
+	}
+	request(1, "mining.subscribe")
+	if reply := read(); reply["error"] != nil {
+		t.Fatalf("subscription failed: %v", reply)
+	}
+	request(2, "mining.authorize", "rig", "")
+	if reply := read(); reply["result"] != true {
+		t.Fatalf("authorization failed: %v", reply)
+	}
+	results := make(chan *types.Block, 1)
+	block := shareTestBlock(1, unreachableDifficulty, common.Big1, common.Big1)
+	blake3.PushWork(block, results)
+
+	if msg := read(); msg["method"] != "mining.set_target" {
+		t.Fatalf("share target not notified: %v", msg)
+	}
+	notify := read()
+	params, _ := notify["params"].([]interface{})
+	if notify["method"] != "mining.notify" || len(params) != 7 {
+		t.Fatalf("job not notified: %v", notify)
+	}
+	if want := hex.EncodeToString(blake3.SealHash(block.Header()).Bytes()); params[0] != want {
+		t.Fatalf("job id mismatch: have %v, want %s", params[0], want)
+	}
+	if want := encodeTarget(new(big.Int).Div(big2e256, unreachableDifficulty)); params[2] != want || params[4] != encodeTarget(big2e256) {
+		t.Errorf("context targets mismatch: %v", params[2:5])
+	}
+	request(3, "mining.submit", "rig", params[0], "010203040506")
+	if reply := read(); reply["result"] != true {
+		t.Fatalf("solution rejected: %v", reply)
+	}
+	select {
+	case sealed := <-results:
+		if want := (types.BlockNonce{0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}); sealed.Nonce() != want.Uint64() {
+			t.Errorf("sealed nonce mismatch: have %x, want %x", sealed.Nonce(), want)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("solution not sealed")
+	}
+	request(4, "mining.submit", "rig", params[0], "010203040506")
+	reply := read()
+	if errs, _ := reply["error"].([]interface{}); len(errs) == 0 || errs[0] != float64(errStratumDuplicate.code) {
+		t.Fatalf("duplicate solution error mismatch: %v", reply)
+	}
+}
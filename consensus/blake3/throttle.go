@@ -0,0 +1,104 @@
+package blake3
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/log"
+)
+
+const (
+	// dutyPeriod is the period over which the duty cycle of a throttled mining
+	// thread is enforced.
+	dutyPeriod = 100 * time.Millisecond
+
+	// throttleCheckInterval is the number of nonces a mining thread tries
+	// between checking its duty cycle and pauses.
+	throttleCheckInterval = 1 << 12
+)
+
+// Pause suspends the local mining threads for at least the given duration, e.g.
+// to leave the CPU to block import. Overlapping pauses extend each other.
+func (blake3 *Blake3) Pause(d time.Duration) {
+	until := time.Now().Add(d).UnixNano()
+	for {
+		current := atomic.LoadInt64(&blake3.pausedUntil)
+		if current >= until || atomic.CompareAndSwapInt64(&blake3.pausedUntil, current, until) {
+			return
+		}
+	}
+}
+
+// pinThread locks the calling mining goroutine to its OS thread and pins that
+// to the CPU configured for the thread id. The goroutine is meant to exit
+// without unlocking, so the pinned OS thread is discarded with it.
+func (blake3 *Blake3) pinThread(id int, logger log.Logger) {
+	cpus := blake3.config.ThreadsAffinity
+	if len(cpus) == 0 {
+		return
+	}
+	runtime.LockOSThread()
+
+	cpu := cpus[id%len(cpus)]
+	if err := setAffinity(cpu); err != nil {
+		logger.Warn("Failed to set mining thread affinity", "cpu", cpu, "err", err)
+		return
+	}
+	logger.Trace("Pinned mining thread", "cpu", cpu)
+}
+
+// throttle enforces the duty cycle and pauses of a single mining thread.
+type throttle struct {
+	blake3 *Blake3
+	duty   time.Duration // busy time per period, zero if unthrottled
+	start  time.Time     // start of the current period
+}
+
+// newThrottle creates the throttle of a mining thread from the configured
+// duty cycle percentage.
+func (blake3 *Blake3) newThrottle() *throttle {
+	t := &throttle{blake3: blake3, start: time.Now()}
+	if duty := blake3.config.DutyCycle; duty > 0 && duty < 100 {
+		t.duty = dutyPeriod * time.Duration(duty) / 100
+	}
+	return t
+}
+
+// wait blocks the mining thread while mining is paused or the busy time of the
+// current period is used up. It returns false if mining was aborted meanwhile.
+func (t *throttle) wait(abort chan struct{}) bool {
+	if until := atomic.LoadInt64(&t.blake3.pausedUntil); until > 0 {
+		if d := time.Until(time.Unix(0, until)); d > 0 {
+			if !sleep(d, abort) {
+				return false
+			}
+			t.start = time.Now()
+		}
+	}
+	if t.duty == 0 {
+		return true
+	}
+	elapsed := time.Since(t.start)
+	if elapsed < t.duty {
+		return true
+	}
+	if rest := dutyPeriod - elapsed; rest > 0 && !sleep(rest, abort) {
+		return false
+	}
+	t.start = time.Now()
+	return true
+}
+
+// sleep waits for the given duration, returning false if aborted before.
+func sleep(d time.Duration, abort chan struct{}) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-abort:
+		return false
+	}
+}
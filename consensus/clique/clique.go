@@ -570,7 +570,7 @@ func (c *Clique) Prepare(chain consensus.ChainHeaderReader, header *types.Header
 // rewards given.
 func (c *Clique) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
-	header.Root[types.QuaiNetworkContext] = state.IntermediateRoot(chain.Config().IsEIP158(header.Number[types.QuaiNetworkContext]))
+	header.Root[types.QuaiNetworkContext] = state.IntermediateRoot(chain.Config().DeleteEmptyAccounts(header.Number[types.QuaiNetworkContext]))
 	header.UncleHash[types.QuaiNetworkContext] = types.CalcUncleHash(nil)
 }
 
@@ -50,4 +50,42 @@ var (
 
 	// ErrPCCOPFailed is returned if PCCOP fails and we add the terminal header to future block.
 	ErrPCCOPFailed = errors.New("pccop failed")
+
+	// ErrNonCanonicalDom is returned if a chain is not being built on the canonical
+	// chain of the dominant context.
+	ErrNonCanonicalDom = errors.New("chain is not being built on canonical dom")
+
+	// ErrTwist is returned if the coincident blocks along the paths of a header
+	// don't match, i.e. the chains of the contexts are twisted.
+	ErrTwist = errors.New("there exists a twist")
+)
+
+// Machine-readable reasons of the slice check failures, reported over RPC.
+const (
+	ReasonSliceNotSynced  = "slice-not-synced"
+	ReasonNonCanonicalDom = "non-canonical-dom"
+	ReasonTwist           = "twist"
 )
+
+var sliceErrors = map[string]error{
+	ReasonSliceNotSynced:  ErrSliceNotSynced,
+	ReasonNonCanonicalDom: ErrNonCanonicalDom,
+	ReasonTwist:           ErrTwist,
+}
+
+// FailureReason returns the reason of a slice check failure, or an empty string
+// if the error isn't one.
+func FailureReason(err error) string {
+	for reason, sentinel := range sliceErrors {
+		if errors.Is(err, sentinel) {
+			return reason
+		}
+	}
+	return ""
+}
+
+// ReasonError returns the error of a slice check failure reason, or nil if the
+// reason is unknown.
+func ReasonError(reason string) error {
+	return sliceErrors[reason]
+}
@@ -0,0 +1,402 @@
+// Package contractmeta implements a store of verified contract metadata, the
+// ABI and source hash of deployed contracts, which the tracing and log APIs use
+// to decode calls and events into named methods and arguments.
+//
+// Metadata is registered over the private RPC endpoint or dropped as JSON files
+// into the store directory. External verification services can be integrated
+// by implementing the Store interface.
+package contractmeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spruce-solutions/go-quai/accounts/abi"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+var (
+	// ErrUnknownMethod is returned when decoding a call to a method missing from
+	// the contract ABI.
+	ErrUnknownMethod = errors.New("unknown method")
+
+	// ErrUnknownEvent is returned when decoding a log of an event missing from
+	// the contract ABI, or of an anonymous event.
+	ErrUnknownEvent = errors.New("unknown event")
+)
+
+// Store provides the metadata of verified contracts.
+type Store interface {
+	// Metadata returns the metadata of the contract at an address, nil if the
+	// contract is not verified.
+	Metadata(addr common.Address) *Metadata
+}
+
+// Backend is implemented by API backends offering contract metadata to the
+// APIs decoding with it.
+type Backend interface {
+	// ContractMetadata returns the metadata store, nil if there is none.
+	ContractMetadata() Store
+}
+
+// Metadata is the verified metadata of a deployed contract.
+type Metadata struct {
+	Address    common.Address  `json:"address"`
+	Name       string          `json:"name,omitempty"`
+	ABI        json.RawMessage `json:"abi"`
+	SourceHash common.Hash     `json:"sourceHash"` // hash of the verified source, as published by the verifier
+
+	abi abi.ABI
+}
+
+// parse parses the ABI of the metadata.
+func (m *Metadata) parse() error {
+	if len(m.ABI) == 0 {
+		return errors.New("missing abi")
+	}
+	parsed, err := abi.JSON(strings.NewReader(string(m.ABI)))
+	if err != nil {
+		return fmt.Errorf("invalid abi: %v", err)
+	}
+	m.abi = parsed
+	return nil
+}
+
+// Call is a contract call decoded with the ABI of the callee.
+type Call struct {
+	Contract  string                 `json:"contract,omitempty"`
+	Method    string                 `json:"method"`
+	Signature string                 `json:"signature"`
+	Args      map[string]interface{} `json:"args"`
+}
+
+// Event is a log decoded with the ABI of the emitting contract.
+type Event struct {
+	Contract  string                 `json:"contract,omitempty"`
+	Event     string                 `json:"event"`
+	Signature string                 `json:"signature"`
+	Args      map[string]interface{} `json:"args"`
+}
+
+// DecodeCall decodes the input of a call to the contract.
+func (m *Metadata) DecodeCall(input []byte) (*Call, error) {
+	if len(input) < 4 {
+		return nil, ErrUnknownMethod
+	}
+	method, err := m.abi.MethodById(input[:4])
+	if err != nil {
+		return nil, ErrUnknownMethod
+	}
+	args := make(map[string]interface{})
+	if err := method.Inputs.UnpackIntoMap(args, input[4:]); err != nil {
+		return nil, err
+	}
+	return &Call{Contract: m.Name, Method: method.Name, Signature: method.Sig, Args: format(args)}, nil
+}
+
+// DecodeLog decodes a log emitted by the contract.
+func (m *Metadata) DecodeLog(l *types.Log) (*Event, error) {
+	if len(l.Topics) == 0 {
+		return nil, ErrUnknownEvent
+	}
+	event, err := m.abi.EventByID(l.Topics[0])
+	if err != nil {
+		return nil, ErrUnknownEvent
+	}
+	args := make(map[string]interface{})
+	if err := event.Inputs.NonIndexed().UnpackIntoMap(args, l.Data); err != nil {
+		return nil, err
+	}
+	var indexed abi.Arguments
+	for _, arg := range event.Inputs {
+		if arg.Indexed {
+			indexed = append(indexed, arg)
+		}
+	}
+	if err := abi.ParseTopicsIntoMap(args, indexed, l.Topics[1:]); err != nil {
+		return nil, err
+	}
+	return &Event{Contract: m.Name, Event: event.Name, Signature: event.Sig, Args: format(args)}, nil
+}
+
+// DecodeCall decodes a call with the metadata of the callee, returning nil if
+// the callee is not verified or the call does not match its ABI.
+func DecodeCall(store Store, to common.Address, input []byte) *Call {
+	if store == nil {
+		return nil
+	}
+	if m := store.Metadata(to); m != nil {
+		if call, err := m.DecodeCall(input); err == nil {
+			return call
+		}
+	}
+	return nil
+}
+
+// DecodeLog decodes a log with the metadata of the emitter, returning nil if
+// the emitter is not verified or the log does not match its ABI.
+func DecodeLog(store Store, l *types.Log) *Event {
+	if store == nil {
+		return nil
+	}
+	if m := store.Metadata(l.Address); m != nil {
+		if event, err := m.DecodeLog(l); err == nil {
+			return event
+		}
+	}
+	return nil
+}
+
+// format converts decoded arguments into their JSON friendly form: byte arrays
+// and slices are hex encoded and integers printed in decimal, so they survive
+// javascript clients.
+func format(args map[string]interface{}) map[string]interface{} {
+	for name, arg := range args {
+		args[name] = formatValue(reflect.ValueOf(arg))
+	}
+	return args
+}
+
+func formatValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	switch value := v.Interface().(type) {
+	case *big.Int:
+		return value.String()
+	case common.Address:
+		return value
+	case []byte:
+		return hexutil.Bytes(value)
+	}
+	switch v.Kind() {
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return hexutil.Bytes(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = formatValue(v.Index(i))
+		}
+		return items
+	case reflect.Struct:
+		fields := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := field.Tag.Get("json")
+			if name == "" {
+				name = field.Name
+			}
+			fields[name] = formatValue(v.Field(i))
+		}
+		return fields
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprintf("%d", v.Int())
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprintf("%d", v.Uint())
+	}
+	return v.Interface()
+}
+
+// Config contains the settings of the contract metadata store.
+type Config struct {
+	Dir string // directory holding the metadata files
+}
+
+// DirStore is a Store keeping the metadata as one JSON file per contract in a
+// directory.
+type DirStore struct {
+	dir string
+
+	lock      sync.RWMutex
+	contracts map[common.Address]*Metadata
+}
+
+// NewDirStore creates a metadata store in the given directory, loading the
+// metadata files already present.
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	store := &DirStore{dir: dir, contracts: make(map[common.Address]*Metadata)}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		m := new(Metadata)
+		if err := json.Unmarshal(blob, m); err != nil {
+			log.Warn("Skipping invalid contract metadata", "file", file, "err", err)
+			continue
+		}
+		if err := m.parse(); err != nil {
+			log.Warn("Skipping invalid contract metadata", "file", file, "err", err)
+			continue
+		}
+		store.contracts[m.Address] = m
+	}
+	return store, nil
+}
+
+// New creates the contract metadata store and registers its APIs with the node.
+func New(stack *node.Node, config Config) (*DirStore, error) {
+	store, err := NewDirStore(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "metadata",
+		Version:   "1.0",
+		Service:   &PublicMetadataAPI{store},
+		Public:    true,
+	}, {
+		Namespace: "metadata",
+		Version:   "1.0",
+		Service:   &PrivateMetadataAPI{store},
+	}})
+	log.Info("Loaded contract metadata", "dir", config.Dir, "contracts", len(store.contracts))
+	return store, nil
+}
+
+// Metadata implements Store.
+func (s *DirStore) Metadata(addr common.Address) *Metadata {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.contracts[addr]
+}
+
+// Contracts returns the addresses of the verified contracts.
+func (s *DirStore) Contracts() []common.Address {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	addrs := make([]common.Address, 0, len(s.contracts))
+	for addr := range s.contracts {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	return addrs
+}
+
+// path returns the file holding the metadata of a contract.
+func (s *DirStore) path(addr common.Address) string {
+	return filepath.Join(s.dir, strings.ToLower(addr.Hex())+".json")
+}
+
+// Register validates and stores the metadata of a contract, replacing any
+// earlier metadata of the same contract.
+func (s *DirStore) Register(m *Metadata) error {
+	if err := m.parse(); err != nil {
+		return err
+	}
+	blob, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Write through a temporary file so a crash never leaves a truncated file
+	tmp := s.path(m.Address) + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path(m.Address)); err != nil {
+		return err
+	}
+	s.contracts[m.Address] = m
+	return nil
+}
+
+// Remove deletes the metadata of a contract.
+func (s *DirStore) Remove(addr common.Address) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.contracts[addr]; !ok {
+		return fmt.Errorf("no metadata for %s", addr.Hex())
+	}
+	if err := os.Remove(s.path(addr)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	delete(s.contracts, addr)
+	return nil
+}
+
+// PublicMetadataAPI offers the verified contract metadata over RPC.
+type PublicMetadataAPI struct {
+	store *DirStore
+}
+
+// Contracts returns the addresses of the verified contracts.
+func (api *PublicMetadataAPI) Contracts() []common.Address {
+	return api.store.Contracts()
+}
+
+// Get returns the metadata of a verified contract.
+func (api *PublicMetadataAPI) Get(addr common.Address) (*Metadata, error) {
+	m := api.store.Metadata(addr)
+	if m == nil {
+		return nil, fmt.Errorf("no metadata for %s", addr.Hex())
+	}
+	return m, nil
+}
+
+// DecodeCall decodes the input of a call to a verified contract.
+func (api *PublicMetadataAPI) DecodeCall(to common.Address, input hexutil.Bytes) (*Call, error) {
+	m := api.store.Metadata(to)
+	if m == nil {
+		return nil, fmt.Errorf("no metadata for %s", to.Hex())
+	}
+	return m.DecodeCall(input)
+}
+
+// DecodeLog decodes the topics and data of a log emitted by a verified contract.
+func (api *PublicMetadataAPI) DecodeLog(addr common.Address, topics []common.Hash, data hexutil.Bytes) (*Event, error) {
+	m := api.store.Metadata(addr)
+	if m == nil {
+		return nil, fmt.Errorf("no metadata for %s", addr.Hex())
+	}
+	return m.DecodeLog(&types.Log{Address: addr, Topics: topics, Data: data})
+}
+
+// PrivateMetadataAPI offers the registration of contract metadata over the
+// authenticated RPC endpoints.
+type PrivateMetadataAPI struct {
+	store *DirStore
+}
+
+// Register stores the verified metadata of a contract.
+func (api *PrivateMetadataAPI) Register(m Metadata) error {
+	return api.store.Register(&m)
+}
+
+// Remove deletes the metadata of a contract.
+func (api *PrivateMetadataAPI) Remove(addr common.Address) error {
+	return api.store.Remove(addr)
+}
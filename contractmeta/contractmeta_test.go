@@ -0,0 +1,97 @@
+package contractmeta
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/crypto"
+)
+
+const tokenABI = `[
+	{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"amount","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]},
+	{"type":"event","name":"Transfer","inputs":[{"name":"from","type":"address","indexed":true},{"name":"to","type":"address","indexed":true},{"name":"amount","type":"uint256","indexed":false}]}
+]`
+
+func TestDecode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "contractmeta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := NewDirStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	var (
+		token = common.HexToAddress("0x1100000000000000000000000000000000000001")
+		from  = common.HexToAddress("0x1100000000000000000000000000000000000002")
+		to    = common.HexToAddress("0x1100000000000000000000000000000000000003")
+	)
+	if err := store.Register(&Metadata{Address: token, Name: "Token", ABI: []byte("[{]")}); err == nil {
+		t.Fatalf("registered invalid abi")
+	}
+	if err := store.Register(&Metadata{Address: token, Name: "Token", ABI: []byte(tokenABI)}); err != nil {
+		t.Fatalf("failed to register metadata: %v", err)
+	}
+	// Reopen the store to decode with the persisted metadata
+	if store, err = NewDirStore(dir); err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	input := append(crypto.Keccak256([]byte("transfer(address,uint256)"))[:4], append(common.LeftPadBytes(to[:], 32), common.LeftPadBytes(big.NewInt(42).Bytes(), 32)...)...)
+	call := DecodeCall(store, token, input)
+	if call == nil {
+		t.Fatalf("failed to decode call")
+	}
+	if call.Method != "transfer" || call.Contract != "Token" || call.Args["to"] != to || call.Args["amount"] != "42" {
+		t.Errorf("decoded call mismatch: %+v", call)
+	}
+	if DecodeCall(store, from, input) != nil {
+		t.Errorf("decoded call to unverified contract")
+	}
+	event := DecodeLog(store, &types.Log{
+		Address: token,
+		Topics:  []common.Hash{crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)")), from.Hash(), to.Hash()},
+		Data:    common.LeftPadBytes(big.NewInt(42).Bytes(), 32),
+	})
+	if event == nil {
+		t.Fatalf("failed to decode log")
+	}
+	if event.Event != "Transfer" || event.Args["from"] != from || event.Args["to"] != to || event.Args["amount"] != "42" {
+		t.Errorf("decoded event mismatch: %+v", event)
+	}
+	if err := store.Remove(token); err != nil {
+		t.Fatalf("failed to remove metadata: %v", err)
+	}
+	if store, _ = NewDirStore(dir); store.Metadata(token) != nil {
+		t.Errorf("removed metadata persisted")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	args := format(map[string]interface{}{
+		"hash":  [4]byte{1, 2, 3, 4},
+		"list":  []*big.Int{big.NewInt(1), big.NewInt(2)},
+		"small": uint8(7),
+		"tuple": struct {
+			Value *big.Int `json:"value"`
+		}{big.NewInt(3)},
+	})
+	if h, ok := args["hash"].(hexutil.Bytes); !ok || h.String() != "0x01020304" {
+		t.Errorf("byte array mismatch: %v", args["hash"])
+	}
+	if l := args["list"].([]interface{}); len(l) != 2 || l[1] != "2" {
+		t.Errorf("list mismatch: %v", args["list"])
+	}
+	if args["small"] != "7" {
+		t.Errorf("small integer mismatch: %v", args["small"])
+	}
+	if tuple := args["tuple"].(map[string]interface{}); tuple["value"] != "3" {
+		t.Errorf("tuple mismatch: %v", args["tuple"])
+	}
+}
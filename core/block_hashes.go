@@ -0,0 +1,38 @@
+package core
+
+import (
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// ApplyBlockHashHistory records the parent of a block in the block hash history
+// before the transactions of the block are executed. If the parent is a
+// coincident block, it is recorded as a block of the dominant contexts too.
+// The parent is nil if it is not available, and the engine is nil if the
+// coincidence of the parent is not to be checked.
+func ApplyBlockHashHistory(config *params.ChainConfig, engine consensus.Engine, parent *types.Header, header *types.Header, statedb vm.StateDB) {
+	context := types.QuaiNetworkContext
+	if !config.IsBlockHashWindow(header.Number[context]) || header.Number[context].Sign() == 0 {
+		return
+	}
+	var (
+		number = header.Number[context].Uint64() - 1
+		window = config.BlockHashHistoryWindow()
+	)
+	vm.WriteBlockHashHistory(statedb, context, number, window, header.ParentHash[context])
+
+	if parent == nil || engine == nil {
+		return
+	}
+	order, err := engine.GetDifficultyOrder(parent)
+	if err != nil || order < 0 {
+		return
+	}
+	for dom := order; dom < context; dom++ {
+		if len(parent.Number) > dom && parent.Number[dom] != nil {
+			vm.WriteBlockHashHistory(statedb, dom, parent.Number[dom].Uint64(), window, parent.Hash())
+		}
+	}
+}
@@ -96,7 +96,7 @@ func (v *BlockValidator) ValidateState(block *types.Block, statedb *state.StateD
 	}
 	// Validate the state root against the received state root and throw
 	// an error if they don't match.
-	if root := statedb.IntermediateRoot(v.config.IsEIP158(header.Number[types.QuaiNetworkContext])); header.Root[types.QuaiNetworkContext] != root {
+	if root := statedb.IntermediateRoot(v.config.DeleteEmptyAccounts(header.Number[types.QuaiNetworkContext])); header.Root[types.QuaiNetworkContext] != root {
 		return fmt.Errorf("invalid merkle root (remote: %x local: %x)", header.Root, root)
 	}
 	return nil
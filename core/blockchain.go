@@ -25,7 +25,7 @@ import (
 	"io"
 	"math/big"
 	"runtime"
-	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -40,6 +40,7 @@ import (
 	"github.com/spruce-solutions/go-quai/core/rawdb"
 	"github.com/spruce-solutions/go-quai/core/state"
 	"github.com/spruce-solutions/go-quai/core/state/snapshot"
+	"github.com/spruce-solutions/go-quai/core/twistindex"
 	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/core/vm"
 	"github.com/spruce-solutions/go-quai/ethclient/quaiclient"
@@ -94,11 +95,13 @@ const (
 	blockCacheLimit     = 256
 	receiptsCacheLimit  = 32
 	txLookupCacheLimit  = 1024
-	maxFutureBlocks     = 256
-	maxTimeFutureBlocks = 30
 	TriesInMemory       = 128
 	extBlockQueueLimit  = 1024
 
+	// orderedEventChanSize is the size of the channels of the subscriptions
+	// filtering chain events by the order of their block.
+	orderedEventChanSize = 64
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	//
 	// Changelog:
@@ -140,8 +143,23 @@ type CacheConfig struct {
 
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 
+	InternalTxIndex bool // Whether to index the value transfers of internal calls during import
+
 	ExternalBlockLimit   int    // Memory allowance (MB) to use for caching trie nodes in memory
 	ExternalBlockJournal string // Disk journal for saving clean cache entries.
+
+	PCRCCacheLimit int // Number of PCRC results cached (0 = default)
+
+	FutureBlockLimit   int           // Number of future blocks queued for later import (0 = default)
+	FutureBlockHorizon time.Duration // Maximum time a queued future block may be dated ahead (0 = default)
+
+	Client quaiclient.Config // Connection settings of the dominant and subordinate chain clients
+
+	Tiebreak            TiebreakPolicy // Rule selecting between heads of equal height and difficulty
+	TiebreakProbability float64        // Probability of adopting the extern head under the random tiebreak
+	MaxReorgDepth       uint64         // Depth of the zone reorgs refused unless anchored by the dominant chain (0 = unlimited)
+
+	InvariantCheckInterval time.Duration // Interval between the invariant checks of the imported canonical blocks (0 = disabled)
 }
 
 // defaultCacheConfig are the default caching values if none are specified by the
@@ -210,9 +228,11 @@ type BlockChain struct {
 	receiptsCache      *lru.Cache       // Cache for the most recent receipts per block
 	blockCache         *lru.Cache       // Cache for the most recent entire blocks
 	txLookupCache      *lru.Cache       // Cache for the most recent transaction lookup data.
-	futureBlocks       *lru.Cache       // future blocks are blocks added for later processing
+	futureBlocks       *futureQueue     // future blocks are blocks added for later processing
+	pcrcQueue          *pcrcQueue       // blocks parked until their slice is synced for PCRC
 	externalBlockQueue *lru.Cache       // Queue for external blocks
 	externalBlocks     *fastcache.Cache // blocks that need to be applied externally
+	etxPool            *EtxPool         // external transactions destined to this chain not yet applied
 
 	quit          chan struct{}  // blockchain quit channel
 	wg            sync.WaitGroup // chain processing wait group for shutting down
@@ -228,7 +248,7 @@ type BlockChain struct {
 
 	shouldPreserve func(*types.Block) bool // Function used to determine whether should preserve the given block.
 
-	domClient  *quaiclient.Client   // domClient is used to check if a given dominant block in the chain is canonical in dominant chain.
+	domClient  *DomClient           // domClient is used to check if a given dominant block in the chain is canonical in dominant chain.
 	subClients []*quaiclient.Client // subClinets is used to check is a coincident block is valid in the subordinate context
 }
 
@@ -244,7 +264,6 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	receiptsCache, _ := lru.New(receiptsCacheLimit)
 	blockCache, _ := lru.New(blockCacheLimit)
 	txLookupCache, _ := lru.New(txLookupCacheLimit)
-	futureBlocks, _ := lru.New(maxFutureBlocks)
 	externalBlockQueue, _ := lru.New(extBlockQueueLimit)
 
 	var externalBlocks *fastcache.Cache
@@ -270,28 +289,30 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		receiptsCache:      receiptsCache,
 		blockCache:         blockCache,
 		txLookupCache:      txLookupCache,
-		futureBlocks:       futureBlocks,
+		futureBlocks:       newFutureQueue(cacheConfig.FutureBlockLimit, cacheConfig.FutureBlockHorizon),
+		pcrcQueue:          newPCRCQueue(),
 		externalBlocks:     externalBlocks,
 		externalBlockQueue: externalBlockQueue,
+		etxPool:            NewEtxPool(chainConfig),
 		engine:             engine,
 		vmConfig:           vmConfig,
 	}
 
-	bc.forker = NewForkChoice(bc, shouldPreserve)
+	bc.forker = NewForkChoice(bc, shouldPreserve, cacheConfig.Tiebreak, cacheConfig.TiebreakProbability, cacheConfig.MaxReorgDepth)
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.prefetcher = newStatePrefetcher(chainConfig, bc, engine)
 	bc.processor = NewStateProcessor(chainConfig, bc, engine)
 
 	// only set the domClient if the chain is not prime
 	if types.QuaiNetworkContext != params.PRIME {
-		bc.domClient = MakeDomClient(domClientUrl)
+		bc.domClient = MakeDomClient(domClientUrl, cacheConfig.Client)
 	}
 
 	bc.subClients = make([]*quaiclient.Client, 3)
 	// only set the subClients if the chain is not region
 	if types.QuaiNetworkContext != params.ZONE {
 		go func() {
-			bc.subClients = MakeSubClients(subClientUrls)
+			bc.subClients = MakeSubClients(subClientUrls, cacheConfig.Client)
 		}()
 	}
 
@@ -300,6 +321,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err != nil {
 		return nil, err
 	}
+	bc.hc.SetPCRCCacheLimit(cacheConfig.PCRCCacheLimit)
 	bc.genesisBlock = bc.GetBlockByNumber(0)
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
@@ -428,6 +450,16 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		bc.wg.Add(1)
 		go bc.maintainTxIndex(txIndexBlock)
 	}
+	// Prefetch dominant blocks ahead of coincident boundaries
+	if bc.domClient != nil {
+		bc.wg.Add(1)
+		go bc.prefetchCoincident()
+	}
+	// Verify the database invariants of the imported blocks for soak testing
+	if bc.cacheConfig.InvariantCheckInterval > 0 {
+		bc.wg.Add(1)
+		go bc.checkInvariantsLoop(bc.cacheConfig.InvariantCheckInterval)
+	}
 	// If periodic cache journal is required, spin it up.
 	if bc.cacheConfig.TrieCleanRejournal > 0 {
 		if bc.cacheConfig.TrieCleanRejournal < time.Minute {
@@ -520,26 +552,30 @@ func (bc *BlockChain) loadLastState() error {
 	return nil
 }
 
-// MakeDomClient creates the quaiclient for the given domurl
-func MakeDomClient(domurl string) *quaiclient.Client {
+// MakeDomClient creates the managed dominant chain client for the given domurl
+// with the given connection settings. The domurl may list several comma
+// separated endpoints of the dominant chain, calls failing over between them in
+// the given order.
+func MakeDomClient(domurl string, config quaiclient.Config) *DomClient {
 	if domurl == "" {
 		log.Crit("dom client url is empty")
 	}
-	domClient, err := quaiclient.Dial(domurl)
+	failover, err := quaiclient.DialFailover(context.Background(), strings.Split(domurl, ","), config)
 	if err != nil {
 		log.Crit("Error connecting to the dominant go-quai client", "err", err)
 	}
-	return domClient
+	return NewDomClient(failover)
 }
 
-// MakeSubClients creates the quaiclient for the given suburls
-func MakeSubClients(suburls []string) []*quaiclient.Client {
+// MakeSubClients creates the quaiclient for the given suburls with the given
+// connection settings.
+func MakeSubClients(suburls []string, config quaiclient.Config) []*quaiclient.Client {
 	subClients := make([]*quaiclient.Client, 3)
 	for i, suburl := range suburls {
 		if suburl == "" {
 			log.Warn("sub client url is empty")
 		}
-		subClient, err := quaiclient.Dial(suburl)
+		subClient, err := quaiclient.DialContextWithConfig(context.Background(), suburl, config)
 		if err != nil {
 			log.Crit("Error connecting to the subordinate go-quai client for index", "index", i, " err ", err)
 		}
@@ -694,8 +730,10 @@ func (bc *BlockChain) SetHeadBeyondRoot(head uint64, root common.Hash) (uint64,
 	bc.receiptsCache.Purge()
 	bc.blockCache.Purge()
 	bc.txLookupCache.Purge()
-	bc.futureBlocks.Purge()
+	bc.futureBlocks.purge()
 	bc.externalBlockQueue.Purge()
+	bc.etxPool.Clear()
+	bc.hc.purgePCRC()
 
 	return rootNumber, bc.loadLastState()
 }
@@ -1092,6 +1130,10 @@ func (bc *BlockChain) Stop() {
 	bc.StopInsert()
 	bc.wg.Wait()
 
+	if bc.domClient != nil {
+		bc.domClient.Close()
+	}
+
 	// Ensure that the entirety of the state snapshot is journalled to disk.
 	var snapBase common.Hash
 	if bc.snaps != nil {
@@ -1158,24 +1200,6 @@ func (bc *BlockChain) insertStopped() bool {
 	return atomic.LoadInt32(&bc.procInterrupt) == 1
 }
 
-func (bc *BlockChain) procFutureBlocks() {
-	blocks := make([]*types.Block, 0, bc.futureBlocks.Len())
-	for _, hash := range bc.futureBlocks.Keys() {
-		if block, exist := bc.futureBlocks.Peek(hash); exist {
-			blocks = append(blocks, block.(*types.Block))
-		}
-	}
-	if len(blocks) > 0 {
-		sort.Slice(blocks, func(i, j int) bool {
-			return blocks[i].NumberU64() < blocks[j].NumberU64()
-		})
-		// Insert one by one as chain insertion needs contiguous ancestry between blocks
-		for i := range blocks {
-			bc.InsertChain(blocks[i : i+1])
-		}
-	}
-}
-
 // WriteStatus status of write
 type WriteStatus byte
 
@@ -1262,8 +1286,8 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 		if first.NumberU64() == 1 {
 			if frozen, _ := bc.db.Ancients(); frozen == 0 {
 				b := bc.genesisBlock
-				td := bc.genesisBlock.Difficulty()
-				writeSize, err := rawdb.WriteAncientBlocks(bc.db, []*types.Block{b}, []types.Receipts{nil}, td)
+				td := bc.GetTd(b.Hash(), 0)
+				writeSize, err := rawdb.WriteAncientBlocks(bc.db, []*types.Block{b}, []types.Receipts{nil}, [][]*big.Int{td})
 				size += writeSize
 				if err != nil {
 					log.Error("Error writing genesis to ancients", "err", err)
@@ -1279,9 +1303,15 @@ func (bc *BlockChain) InsertReceiptChain(blockChain types.Blocks, receiptChain [
 			return 0, fmt.Errorf("containing header #%d [%x..] unknown", last.Number(), last.Hash().Bytes()[:4])
 		}
 
-		// Write all chain data to ancients.
-		td := bc.GetTd(first.Hash(), first.NumberU64())
-		writeSize, err := rawdb.WriteAncientBlocks(bc.db, blockChain, receiptChain, td[types.QuaiNetworkContext])
+		// Write all chain data to ancients, along the total difficulties the header
+		// chain calculated across the contexts.
+		tds := make([][]*big.Int, len(blockChain))
+		for i, block := range blockChain {
+			if tds[i] = bc.GetTd(block.Hash(), block.NumberU64()); tds[i] == nil {
+				return 0, fmt.Errorf("total difficulty of #%d [%x..] unknown", block.Number(), block.Hash().Bytes()[:4])
+			}
+		}
+		writeSize, err := rawdb.WriteAncientBlocks(bc.db, blockChain, receiptChain, tds)
 		size += writeSize
 		if err != nil {
 			log.Error("Error importing chain data to ancients", "err", err)
@@ -1584,6 +1614,12 @@ func (bc *BlockChain) CalcTd(header *types.Header) ([]*big.Int, error) {
 	return externTd, nil
 }
 
+// CalcTdBatch calculates the TDs of a contiguous range of headers in one pass,
+// as the header chain does when importing them.
+func (bc *BlockChain) CalcTdBatch(headers []*types.Header) ([][]*big.Int, error) {
+	return bc.hc.CalcTdBatch(headers)
+}
+
 // writeBlockWithState writes the block and all associated state to the database,
 // but is expects the chain mutex to be held.
 func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB, linkExtBlocks []*types.ExternalBlock) error {
@@ -1606,7 +1642,7 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 		log.Crit("Failed to write block into disk", "err", err)
 	}
 	// Commit all cached state changes into underlying memory database.
-	root, err := state.Commit(bc.chainConfig.IsEIP158(block.Number()))
+	root, err := state.Commit(bc.chainConfig.DeleteEmptyAccounts(block.Number()))
 	if err != nil {
 		return err
 	}
@@ -1708,10 +1744,10 @@ func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types
 	if status == CanonStatTy {
 		bc.writeHeadBlock(block)
 	}
-	bc.futureBlocks.Remove(block.Hash())
+	bc.futureBlocks.remove(block.Hash())
 
 	if status == CanonStatTy {
-		bc.chainFeed.Send(ChainEvent{Block: block, Hash: block.Hash(), Logs: logs})
+		bc.chainFeed.Send(bc.chainEvent(block, logs))
 		if len(logs) > 0 {
 			bc.logsFeed.Send(logs)
 		}
@@ -1721,11 +1757,11 @@ func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types
 		// we will fire an accumulated ChainHeadEvent and disable fire
 		// event here.
 		if emitHeadEvent {
-			bc.chainHeadFeed.Send(ChainHeadEvent{Block: block})
+			bc.chainHeadFeed.Send(bc.chainHeadEvent(block))
 		}
 	} else {
 		bc.chainUncleFeed.Send(block.Header())
-		bc.chainSideFeed.Send(ChainSideEvent{Block: block})
+		bc.chainSideFeed.Send(bc.chainSideEvent(block))
 	}
 	return status, nil
 }
@@ -1734,14 +1770,7 @@ func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types
 // accepted for future processing, and returns an error if the block is too far
 // ahead and was not added.
 func (bc *BlockChain) addFutureBlock(block *types.Block) error {
-	max := uint64(time.Now().Unix() + maxTimeFutureBlocks)
-	if block.Time() > max {
-		return fmt.Errorf("future block timestamp %v > allowed %v", block.Time(), max)
-	}
-	if !bc.futureBlocks.Contains(block.Hash()) {
-		bc.futureBlocks.Add(block.Hash(), block)
-	}
-	return nil
+	return bc.futureBlocks.add(block)
 }
 
 // GetBlockStatus returns the status of the block for a given header
@@ -1779,6 +1808,7 @@ func (bc *BlockChain) AddExternalBlock(block *types.ExternalBlock) error {
 		log.Crit("Failed to RLP encode external block", "err", err)
 	}
 	bc.externalBlocks.Set(block.CacheKey(), data)
+	bc.etxPool.AddBlock(block, false)
 	return nil
 }
 
@@ -1829,6 +1859,8 @@ func (bc *BlockChain) ReOrgRollBack(header *types.Header, validHeaders []*types.
 			return ret
 		}
 	)
+	// The coincident blocks cached PCRC results lead to may not be canonical anymore
+	bc.hc.purgePCRC()
 
 	if header != nil {
 		// get the commonBlock
@@ -1862,7 +1894,10 @@ func (bc *BlockChain) ReOrgRollBack(header *types.Header, validHeaders []*types.
 		}
 		// writing the head to the blockchain state
 		bc.writeHeadBlock(commonBlock)
-		bc.futureBlocks.Remove(commonBlock.Hash())
+		bc.futureBlocks.remove(commonBlock.Hash())
+
+		// Blocks coincident with Prime on the rolled back chain must not be frozen
+		rawdb.LowerFreezerHorizon(bc.db, bc.db, commonBlock.NumberU64())
 
 		// get all the receipts and extract the logs from it
 		receipts := bc.GetReceiptsByHash(commonBlock.Hash())
@@ -1872,8 +1907,8 @@ func (bc *BlockChain) ReOrgRollBack(header *types.Header, validHeaders []*types.
 			logs = append(logs, receipt.Logs...)
 		}
 		// send a chain event so that it updates the pending header
-		bc.chainFeed.Send(ChainEvent{Block: commonBlock, Hash: commonBlock.Hash(), Logs: logs})
-		bc.chainHeadFeed.Send(ChainHeadEvent{Block: commonBlock})
+		bc.chainFeed.Send(bc.chainEvent(commonBlock, logs))
+		bc.chainHeadFeed.Send(bc.chainHeadEvent(commonBlock))
 
 		log.Info("Header is now rolled back and the current head is at block with ", "Hash ", bc.CurrentBlock().Hash(), " Number ", bc.CurrentBlock().NumberU64())
 
@@ -1921,6 +1956,9 @@ func (bc *BlockChain) InsertChain(chain types.Blocks) (int, error) {
 	bc.blockProcFeed.Send(true)
 	defer bc.blockProcFeed.Send(false)
 
+	for _, block := range chain {
+		bc.forker.MarkSeen(block.Hash())
+	}
 	// Remove already known canon-blocks
 	var (
 		block, prev *types.Block
@@ -1988,7 +2026,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool, setHead
 	// Fire a single chain head event if we've progressed the chain
 	defer func() {
 		if lastCanon != nil && bc.CurrentBlock().Hash() == lastCanon.Hash() {
-			bc.chainHeadFeed.Send(ChainHeadEvent{lastCanon})
+			bc.chainHeadFeed.Send(bc.chainHeadEvent(lastCanon))
 		}
 	}()
 	// Start the parallel header verifier
@@ -2082,7 +2120,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool, setHead
 
 		// Some other error occurred, abort
 	case err != nil && !errors.Is(err, ErrKnownBlock):
-		bc.futureBlocks.Remove(block.Hash())
+		bc.futureBlocks.remove(block.Hash())
 		stats.ignored += len(it.chain)
 		bc.reportBlock(block, nil, err)
 		return it.index, err
@@ -2190,12 +2228,19 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool, setHead
 		// Process block using the parent state as reference point
 		substart := time.Now()
 
-		// Process our block and retrieve external blocks.
-		receipts, logs, usedGas, externalBlocks, err := bc.processor.Process(block, statedb, bc.vmConfig)
+		// Process our block and retrieve external blocks, collecting the internal
+		// transactions along if requested.
+		vmConfig := bc.vmConfig
+		var itxTracer *internalTxTracer
+		if bc.cacheConfig.InternalTxIndex {
+			itxTracer = newInternalTxTracer()
+			vmConfig.CallTracer = itxTracer
+		}
+		receipts, logs, usedGas, externalBlocks, err := bc.processor.Process(block, statedb, vmConfig)
 		if err != nil {
 			bc.reportBlock(block, receipts, err)
 			atomic.StoreUint32(&followupInterrupt, 1)
-			bc.futureBlocks.Remove(block.Hash())
+			bc.futureBlocks.remove(block.Hash())
 			return it.index, err
 		}
 
@@ -2213,6 +2258,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool, setHead
 
 		err = bc.forker.UntwistAndTrim(block.Header())
 		if err != nil {
+			bc.hc.recordTwist(block.Header(), order, twistindex.StageForkChoice, err)
 			return it.index, nil
 		}
 
@@ -2222,13 +2268,20 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool, setHead
 			status := bc.domClient.GetBlockStatus(context.Background(), block.Header())
 			// If the header is cononical break else keep looking
 			if status != quaiclient.CanonStatTy {
-				return it.index, errors.New("cannot append non-canonical dom block in sub")
+				return it.index, fmt.Errorf("cannot append block in sub: %w", consensus.ErrNonCanonicalDom)
 			}
 		}
 
 		_, err = bc.PCRC(block.Header(), order)
 		fmt.Println("PCRC", err)
+		if errors.Is(err, consensus.ErrSliceNotSynced) {
+			// Park the block and its descendants until the slice catches up
+			bc.pcrcQueue.park(block.Header().ParentHash[order], chain[it.index:])
+			log.Debug("Parked block until slice is synced", "number", block.Number(), "hash", block.Hash(), "blocks", len(chain)-it.index)
+			return it.index, nil
+		}
 		if err != nil {
+			bc.hc.recordTwist(block.Header(), order, twistindex.StageImport, err)
 			return it.index, nil
 		}
 
@@ -2269,6 +2322,9 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool, setHead
 
 		// Write the block to the chain and get the status.
 		substart = time.Now()
+		if itxTracer != nil {
+			rawdb.WriteInternalTxs(bc.db, block.Hash(), block.NumberU64(), itxTracer.finalise(receipts))
+		}
 		var status WriteStatus
 		if !setHead {
 			// Don't set the head, only insert the block
@@ -2299,7 +2355,14 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool, setHead
 
 		switch status {
 		case CanonStatTy:
+			if order == params.PRIME {
+				bc.anchorCoincident(block)
+			}
 			bc.StoreExternalBlocks(linkExtBlocks)
+			bc.etxPool.Applied(externalBlocks)
+			for _, extBlock := range linkExtBlocks {
+				bc.etxPool.AddBlock(extBlock, true)
+			}
 			log.Info("Inserted new block", "number", block.Header().Number, "hash", block.Hash(), "loc", block.Header().Location, "extBlocks", len(externalBlocks),
 				"uncles", len(block.Uncles()), "txs", len(block.Transactions()), "gas", block.GasUsed(),
 				"elapsed", common.PrettyDuration(time.Since(start)),
@@ -2349,6 +2412,16 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals bool, setHead
 	return it.index, err
 }
 
+// DomCanonical returns whether the coincident header is canonical in the
+// dominant chain. Headers can't be told canonical without a reachable dominant
+// chain.
+func (bc *BlockChain) DomCanonical(header *types.Header) (bool, error) {
+	if bc.domClient == nil {
+		return false, nil
+	}
+	return bc.domClient.GetBlockStatus(context.Background(), header) == quaiclient.CanonStatTy, nil
+}
+
 func (bc *BlockChain) DomReorgNeeded(header *types.Header) (bool, error) {
 	terminalHeader, err := bc.PreviousCanonicalCoincidentOnPath(header, header.Location, types.QuaiNetworkContext-1, types.QuaiNetworkContext, true)
 
@@ -2622,6 +2695,9 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		rebirthLogs [][]*types.Log
 	)
 
+	// The coincident blocks cached PCRC results lead to may not be canonical anymore
+	bc.hc.purgePCRC()
+
 	// Reduce the longer chain to the same number as the shorter one
 	if oldBlock.NumberU64() > newBlock.NumberU64() {
 		// Old chain is longer, gather all transactions and logs as deleted ones
@@ -2724,6 +2800,8 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 		}
 		rawdb.DeleteCanonicalHash(indexesBatch, i)
 	}
+	// Blocks coincident with Prime on the dropped chain must not be frozen
+	rawdb.LowerFreezerHorizon(bc.db, indexesBatch, commonBlock.NumberU64())
 	if err := indexesBatch.Write(); err != nil {
 		log.Crit("Failed to delete useless indexes", "err", err)
 	}
@@ -2739,15 +2817,63 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 	}
 	if len(oldChain) > 0 {
 		for i := len(oldChain) - 1; i >= 0; i-- {
-			bc.chainSideFeed.Send(ChainSideEvent{Block: oldChain[i]})
+			bc.chainSideFeed.Send(bc.chainSideEvent(oldChain[i]))
 		}
 	}
 	// Once the common block is found, the reorg data is sent to the reOrg feed
 	bc.reOrgFeed.Send(ReOrgRollup{ReOrgHeader: commonBlock.Header(), OldChainHeaders: bc.getAllHeaders(oldChain), NewChainHeaders: bc.getAllHeaders(newChain)})
 
+	if len(oldChain) > 0 && len(newChain) > 0 {
+		bc.journalReorg(commonBlock, oldChain, newChain)
+	}
 	return nil
 }
 
+// anchorCoincident records the canonical block coincident with Prime, which
+// anchors the chain below it. Subordinate chains also flush its state to disk,
+// state pruning keeps the states of the latest anchors.
+func (bc *BlockChain) anchorCoincident(block *types.Block) {
+	rawdb.WriteFreezerHorizon(bc.db, block.NumberU64())
+	rawdb.WritePrimeCoincident(bc.db, block.NumberU64(), block.Hash())
+
+	if types.QuaiNetworkContext == params.PRIME || bc.cacheConfig.TrieDirtyDisabled {
+		return
+	}
+	if err := bc.stateCache.TrieDB().Commit(block.Root(), false, nil); err != nil {
+		log.Error("Failed to commit prime coincident state", "number", block.NumberU64(), "hash", block.Hash(), "err", err)
+	}
+}
+
+// journalReorg records the reorg from the old to the new chain, both given head
+// first, in the reorg journal.
+func (bc *BlockChain) journalReorg(commonBlock *types.Block, oldChain, newChain types.Blocks) {
+	entry := &rawdb.ReorgEntry{
+		Time:     uint64(time.Now().Unix()),
+		Context:  uint64(types.QuaiNetworkContext),
+		Number:   commonBlock.NumberU64(),
+		Ancestor: commonBlock.Hash(),
+		OldTd:    bc.contextTd(oldChain[0]),
+		NewTd:    bc.contextTd(newChain[0]),
+	}
+	for _, block := range oldChain {
+		entry.Dropped = append(entry.Dropped, block.Hash())
+	}
+	for _, block := range newChain {
+		entry.Adopted = append(entry.Adopted, block.Hash())
+	}
+	rawdb.WriteReorgEntry(bc.db, entry)
+}
+
+// contextTd retrieves the total difficulty of the block in the chain's context,
+// zero if it's unknown.
+func (bc *BlockChain) contextTd(block *types.Block) *big.Int {
+	td := bc.GetTd(block.Hash(), block.NumberU64())
+	if len(td) <= types.QuaiNetworkContext || td[types.QuaiNetworkContext] == nil {
+		return new(big.Int)
+	}
+	return td[types.QuaiNetworkContext]
+}
+
 // skipBlock returns 'true', if the block being imported can be skipped over, meaning
 // that the block does not need to be processed but can be considered already fully 'done'.
 func (bc *BlockChain) skipBlock(err error, it *insertIterator) bool {
@@ -2797,6 +2923,7 @@ func (bc *BlockChain) update() {
 		select {
 		case <-futureTimer.C:
 			bc.procFutureBlocks()
+			bc.procParkedBlocks()
 		case <-bc.quit:
 			return
 		}
@@ -3048,7 +3175,7 @@ func (bc *BlockChain) GetExternalBlock(hash common.Hash, location []byte, contex
 // requestExternalBlock sends an external block event to the missingExternalBlockFeed in order to be fulfilled by a manager or client.
 func (bc *BlockChain) requestExternalBlock(hash common.Hash, blockContext uint64) *types.ExternalBlock {
 	if bc.domClient != nil {
-		extBlock := FindExternalBlock(bc.domClient, hash, blockContext)
+		extBlock := FindExternalBlock(bc.domClient.Client(), hash, blockContext)
 		if extBlock != nil {
 			return extBlock
 		}
@@ -3362,7 +3489,36 @@ func (bc *BlockChain) HLCR(localDifficulties []*big.Int, externDifficulties []*b
 // NOTE: note that it only guarantees linked & untwisted back to the prime terminus, assuming the
 // prime termini match. To check deeper than that, you need to iteratively apply PCRC to get that guarantee.
 func (bc *BlockChain) PCRC(header *types.Header, headerOrder int) (types.PCRCTermini, error) {
+	key := pcrcKey{hash: header.Hash(), order: headerOrder}
+	if termini, ok := bc.hc.cachedPCRC(key); ok {
+		return termini, nil
+	}
+	termini, err := bc.pcrc(header, headerOrder)
+	if err == nil && pcrcCacheable(header, headerOrder, false) {
+		bc.hc.cachePCRC(key, termini)
+	}
+	return termini, err
+}
+
+// pcrcCacheable reports whether the PCRC or PCCRC termini of the header can be
+// cached until the next local reorg. Outside of a zone the termini come from
+// the subordinate chain, whose reorgs never purge the cache, or are left empty
+// while the subordinate client is missing, so they are recomputed each time.
+// In a zone the PCRC only asks the dominant chain whether it knows of the
+// terminus, but the PCCRC asks whether the terminus is canonical there, which
+// a dominant reorg may change.
+func pcrcCacheable(header *types.Header, headerOrder int, cross bool) bool {
+	if header.Number[types.QuaiNetworkContext].Sign() == 0 {
+		return true
+	}
+	if types.QuaiNetworkContext != params.ZONE {
+		return false
+	}
+	return !cross || headerOrder == params.ZONE
+}
 
+// pcrc runs the PCRC of the header, bypassing the cache.
+func (bc *BlockChain) pcrc(header *types.Header, headerOrder int) (types.PCRCTermini, error) {
 	if header.Number[types.QuaiNetworkContext].Cmp(big.NewInt(0)) == 0 {
 		return types.PCRCTermini{}, nil
 	}
@@ -3414,11 +3570,11 @@ func (bc *BlockChain) PCRC(header *types.Header, headerOrder int) (types.PCRCTer
 
 		if (PTP.Hash() != PCRCTermini.PTR) && (PCRCTermini.PTR != PCRCTermini.PTZ) && (PCRCTermini.PTZ != PTP.Hash()) {
 			fmt.Println("PTP", PTP.Hash(), "PTR", PCRCTermini.PTR, "PTZ", PCRCTermini.PTZ)
-			return types.PCRCTermini{}, errors.New("there exists a Prime twist (PTP != PTR != PTZ")
+			return types.PCRCTermini{}, fmt.Errorf("%w (PTP != PTR != PTZ)", errPrimeTwist)
 		}
 		if PRTP.Hash() != PCRCTermini.PRTR {
 			fmt.Println("PRTP", PRTP.Hash(), PCRCTermini.PRTR)
-			return types.PCRCTermini{}, errors.New("there exists a Prime twist (PRTP != PRTR")
+			return types.PCRCTermini{}, fmt.Errorf("%w (PRTP != PRTR)", errPrimeTwist)
 		}
 
 		return PCRCTermini, nil
@@ -3446,7 +3602,7 @@ func (bc *BlockChain) PCRC(header *types.Header, headerOrder int) (types.PCRCTer
 
 		if RTR.Hash() != PCRCTermini.RTZ {
 			fmt.Println("RTR", RTR.Number, RTR.Hash(), "RTZ", PCRCTermini.RTZ)
-			return types.PCRCTermini{}, errors.New("there exists a Region twist (RTR != RTZ)")
+			return types.PCRCTermini{}, fmt.Errorf("%w (RTR != RTZ)", errRegionTwist)
 		}
 		if headerOrder < params.REGION {
 			fmt.Println("PCRC Running PTR")
@@ -3548,7 +3704,19 @@ func (bc *BlockChain) PreviousValidCoincidentOnPath(header *types.Header, slice
 // NOTE: note that it only guarantees linked & untwisted back to the prime terminus, assuming the
 // prime termini match. To check deeper than that, you need to iteratively apply PCRC to get that guarantee.
 func (bc *BlockChain) PCCRC(header *types.Header, headerOrder int) (types.PCRCTermini, error) {
+	key := pcrcKey{hash: header.Hash(), order: headerOrder, cross: true}
+	if termini, ok := bc.hc.cachedPCRC(key); ok {
+		return termini, nil
+	}
+	termini, err := bc.pccrc(header, headerOrder)
+	if err == nil && pcrcCacheable(header, headerOrder, true) {
+		bc.hc.cachePCRC(key, termini)
+	}
+	return termini, err
+}
 
+// pccrc runs the PCCRC of the header, bypassing the cache.
+func (bc *BlockChain) pccrc(header *types.Header, headerOrder int) (types.PCRCTermini, error) {
 	if header.Number[types.QuaiNetworkContext].Cmp(big.NewInt(0)) == 0 {
 		return types.PCRCTermini{}, nil
 	}
@@ -3599,11 +3767,11 @@ func (bc *BlockChain) PCCRC(header *types.Header, headerOrder int) (types.PCRCTe
 
 		if (PTP.Hash() != PCRCTermini.PTR) && (PCRCTermini.PTR != PCRCTermini.PTZ) && (PCRCTermini.PTZ != PTP.Hash()) {
 			fmt.Println("PTP", PTP.Hash(), "PTR", PCRCTermini.PTR, "PTZ", PCRCTermini.PTZ)
-			return types.PCRCTermini{}, errors.New("there exists a Prime twist (PTP != PTR != PTZ")
+			return types.PCRCTermini{}, fmt.Errorf("%w (PTP != PTR != PTZ)", errPrimeTwist)
 		}
 		if PRTP.Hash() != PCRCTermini.PRTR {
 			fmt.Println("PRTP", PRTP.Hash(), PCRCTermini.PRTR)
-			return types.PCRCTermini{}, errors.New("there exists a Prime twist (PRTP != PRTR")
+			return types.PCRCTermini{}, fmt.Errorf("%w (PRTP != PRTR)", errPrimeTwist)
 		}
 
 		return PCRCTermini, nil
@@ -3632,7 +3800,7 @@ func (bc *BlockChain) PCCRC(header *types.Header, headerOrder int) (types.PCRCTe
 
 		if RTR.Hash() != PCRCTermini.RTZ {
 			fmt.Println("RTR", RTR.Number, RTR.Hash(), "RTZ", PCRCTermini.RTZ)
-			return types.PCRCTermini{}, errors.New("there exists a Region twist (RTR != RTZ)")
+			return types.PCRCTermini{}, fmt.Errorf("%w (RTR != RTZ)", errRegionTwist)
 		}
 		if headerOrder < params.REGION {
 			fmt.Println("PCCRC Running PTR")
@@ -3723,7 +3891,7 @@ func (bc *BlockChain) PreviousCanonicalCoincidentOnPath(header *types.Header, sl
 				}
 			case quaiclient.SideStatTy:
 				bc.ReOrgRollBack(prevTerminalHeader, []*types.Header{}, []*types.Header{})
-				return prevTerminalHeader, errors.New("PCCOP has found chain is not being built on canonical dom")
+				return prevTerminalHeader, consensus.ErrNonCanonicalDom
 			default:
 				if prevTerminalHeader.Hash() != header.Hash() {
 					return nil, errors.New("subordinate terminus mismatch")
@@ -3766,7 +3934,7 @@ func (bc *BlockChain) CheckDominantBlock(block *types.Block) error {
 		block := types.NewBlockWithHeader(extBlock.Header()).WithBody(extBlock.Transactions(), extBlock.Uncles())
 		sealed := block.WithSeal(block.Header())
 		log.Debug("Sending dominant block", "number", block.Header().Number, "hash", block.Hash())
-		go bc.domClient.SendMinedBlock(context.Background(), sealed, true, true)
+		go bc.domClient.Client().SendMinedBlock(context.Background(), sealed, true, true)
 	}
 
 	return nil
@@ -3887,6 +4055,34 @@ func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 // Engine retrieves the blockchain's consensus engine.
 func (bc *BlockChain) Engine() consensus.Engine { return bc.engine }
 
+// Twists retrieves the index of the subordinate blocks rejected for twisting the
+// chains of the dominant contexts.
+func (bc *BlockChain) Twists() *twistindex.Index { return bc.hc.Twists() }
+
+// DomClientStatus retrieves the health of the dominant chain endpoints, or nil
+// if the chain has no dominant chain.
+func (bc *BlockChain) DomClientStatus() []quaiclient.EndpointStatus {
+	if bc.domClient == nil {
+		return nil
+	}
+	return bc.domClient.Status()
+}
+
+// DomClientHealth retrieves the state of the connection to the dominant chain,
+// or nil if the chain has no dominant chain.
+func (bc *BlockChain) DomClientHealth() *DomClientHealth {
+	if bc.domClient == nil {
+		return nil
+	}
+	return bc.domClient.Health()
+}
+
+// EtxPool returns the pool of external transactions destined to this chain
+// not yet applied.
+func (bc *BlockChain) EtxPool() *EtxPool {
+	return bc.etxPool
+}
+
 // SubscribeRemovedLogsEvent registers a subscription of RemovedLogsEvent.
 func (bc *BlockChain) SubscribeRemovedLogsEvent(ch chan<- RemovedLogsEvent) event.Subscription {
 	return bc.scope.Track(bc.rmLogsFeed.Subscribe(ch))
@@ -3902,6 +4098,24 @@ func (bc *BlockChain) SubscribeReOrgEvent(ch chan<- ReOrgRollup) event.Subscript
 	return bc.scope.Track(bc.reOrgFeed.Subscribe(ch))
 }
 
+// SubscribeForkChoiceEvent registers a subscription of ForkChoiceEvent, posted
+// each time the fork choice selects a new head.
+func (bc *BlockChain) SubscribeForkChoiceEvent(ch chan<- ForkChoiceEvent) event.Subscription {
+	return bc.scope.Track(bc.forker.SubscribeForkChoiceEvent(ch))
+}
+
+// SubscribeDomHeadEvent registers a subscription of DomHeadEvent, posted each
+// time the head of the dominant chain changes. Nothing is posted in prime.
+func (bc *BlockChain) SubscribeDomHeadEvent(ch chan<- DomHeadEvent) event.Subscription {
+	if bc.domClient == nil {
+		return bc.scope.Track(event.NewSubscription(func(quit <-chan struct{}) error {
+			<-quit
+			return nil
+		}))
+	}
+	return bc.scope.Track(bc.domClient.SubscribeHead(ch))
+}
+
 func (bc *BlockChain) SubscribeMissingExternalBlockEvent(ch chan<- MissingExternalBlock) event.Subscription {
 	return bc.scope.Track(bc.missingExternalBlockFeed.Subscribe(ch))
 }
@@ -3916,6 +4130,113 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Su
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeChainEventByOrder registers a subscription of the ChainEvents of
+// blocks with an order of at most maxOrder, e.g. params.PRIME for the blocks
+// coincident with Prime only.
+func (bc *BlockChain) SubscribeChainEventByOrder(ch chan<- ChainEvent, maxOrder int) event.Subscription {
+	events := make(chan ChainEvent, orderedEventChanSize)
+	sub := bc.chainFeed.Subscribe(events)
+	return bc.scope.Track(event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-events:
+				if ev.Order > maxOrder {
+					continue
+				}
+				select {
+				case ch <- ev:
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}))
+}
+
+// SubscribeChainHeadEventByOrder registers a subscription of the
+// ChainHeadEvents of blocks with an order of at most maxOrder.
+func (bc *BlockChain) SubscribeChainHeadEventByOrder(ch chan<- ChainHeadEvent, maxOrder int) event.Subscription {
+	events := make(chan ChainHeadEvent, orderedEventChanSize)
+	sub := bc.chainHeadFeed.Subscribe(events)
+	return bc.scope.Track(event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-events:
+				if ev.Order > maxOrder {
+					continue
+				}
+				select {
+				case ch <- ev:
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}))
+}
+
+// SubscribeChainSideEventByOrder registers a subscription of the
+// ChainSideEvents of blocks with an order of at most maxOrder.
+func (bc *BlockChain) SubscribeChainSideEventByOrder(ch chan<- ChainSideEvent, maxOrder int) event.Subscription {
+	events := make(chan ChainSideEvent, orderedEventChanSize)
+	sub := bc.chainSideFeed.Subscribe(events)
+	return bc.scope.Track(event.NewSubscription(func(quit <-chan struct{}) error {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-events:
+				if ev.Order > maxOrder {
+					continue
+				}
+				select {
+				case ch <- ev:
+				case <-quit:
+					return nil
+				}
+			case err := <-sub.Err():
+				return err
+			case <-quit:
+				return nil
+			}
+		}
+	}))
+}
+
+// blockOrder returns the order of the block, the most dominant context it's
+// coincident in, or the context of the chain if it can't be determined.
+func (bc *BlockChain) blockOrder(block *types.Block) int {
+	order, err := bc.engine.GetDifficultyOrder(block.Header())
+	if err != nil {
+		return types.QuaiNetworkContext
+	}
+	return order
+}
+
+// chainEvent creates the ChainEvent of the canonical block.
+func (bc *BlockChain) chainEvent(block *types.Block, logs []*types.Log) ChainEvent {
+	return ChainEvent{Block: block, Hash: block.Hash(), Logs: logs, Context: types.QuaiNetworkContext, Order: bc.blockOrder(block)}
+}
+
+// chainHeadEvent creates the ChainHeadEvent of the new head.
+func (bc *BlockChain) chainHeadEvent(block *types.Block) ChainHeadEvent {
+	return ChainHeadEvent{Block: block, Context: types.QuaiNetworkContext, Order: bc.blockOrder(block)}
+}
+
+// chainSideEvent creates the ChainSideEvent of the side block.
+func (bc *BlockChain) chainSideEvent(block *types.Block) ChainSideEvent {
+	return ChainSideEvent{Block: block, Context: types.QuaiNetworkContext, Order: bc.blockOrder(block)}
+}
+
 // SubscribeChainUncleEvent registers a subscription of an uncled header.
 func (bc *BlockChain) SubscribeChainUncleEvent(ch chan<- *types.Header) event.Subscription {
 	return bc.scope.Track(bc.chainUncleFeed.Subscribe(ch))
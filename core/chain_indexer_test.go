@@ -92,8 +92,11 @@ func testChainIndexer(t *testing.T, count int) {
 	}
 	// inject inserts a new random canonical header into the database directly
 	inject := func(number uint64) {
-		header := &types.Header{Number: []*big.Int{big.NewInt(int64(number)), big.NewInt(int64(number)), big.NewInt(int64(number))},
-			Extra: [][]byte{big.NewInt(rand.Int63()).Bytes(), big.NewInt(rand.Int63()).Bytes(), big.NewInt(rand.Int63()).Bytes()}}
+		header := types.NewEmptyHeader()
+		for i := range header.Number {
+			header.Number[i] = big.NewInt(int64(number))
+			header.Extra[i] = big.NewInt(rand.Int63()).Bytes()
+		}
 		if number > 0 {
 			header.ParentHash[types.QuaiNetworkContext] = rawdb.ReadCanonicalHash(db, number-1)
 		}
@@ -209,6 +209,7 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		b := &BlockGen{i: i, chain: blocks, parent: parent, statedb: statedb, config: config, engine: engine}
 		location := []byte{1, 1}
 		b.header = makeHeader(chainreader, parent, statedb, b.engine, location)
+		ApplySystemChanges(config, b.engine, parent.Header(), b.header, statedb)
 
 		// Execute any user modifications to the block
 		if gen != nil {
@@ -216,10 +217,11 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 		}
 		if b.engine != nil {
 			// Finalize and seal the block
+			MarkStateAccess(config, b.header, statedb)
 			block, _ := b.engine.FinalizeAndAssemble(chainreader, b.header, statedb, b.txs, b.uncles, b.receipts)
 
 			// Write state changes to db
-			root, err := statedb.Commit(config.IsEIP158(b.header.Number[types.QuaiNetworkContext]))
+			root, err := statedb.Commit(config.DeleteEmptyAccounts(b.header.Number[types.QuaiNetworkContext]))
 			if err != nil {
 				panic(fmt.Sprintf("state write error: %v", err))
 			}
@@ -0,0 +1,378 @@
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/consensus/misc"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// NetworkGenConfig describes the shape of the hierarchy produced by
+// GenerateNetwork.
+type NetworkGenConfig struct {
+	Regions int // number of regions, defaults to params.FullerOntology[0]
+	Zones   int // number of zones per region, defaults to params.FullerOntology[1]
+	Blocks  int // number of blocks generated in every zone
+
+	// RegionInterval makes every RegionInterval-th block of a zone coincident
+	// with its region and PrimeInterval makes every PrimeInterval-th block of
+	// a region coincident with prime. Zero disables coincident blocks.
+	RegionInterval int
+	PrimeInterval  int
+
+	// ETXs is the number of external transactions every zone block emits,
+	// each sending ETXValue from the ETX sender of the zone to the next zone
+	// in generation order. The senders have to be funded in the genesis, see
+	// NetworkETXSender. An ETX is routed once its zone mines a coincident
+	// block dominating both zones, and is settled by the next block of the
+	// destination zone, see NetworkBlocks.Settled.
+	ETXs     int
+	ETXValue *big.Int
+
+	// Gen is called for every generated block, allowing transactions to be
+	// added. The location is the {region, zone} pair of the block and i its
+	// index within the zone. Transactions have to be signed for the chain ID
+	// of the zone, see NetworkZoneConfig.
+	Gen func(location []byte, i int, b *BlockGen)
+}
+
+// NetworkBlocks holds the chains generated by GenerateNetwork.
+type NetworkBlocks struct {
+	// Zones and Receipts hold the blocks of every zone in generation order,
+	// keyed by the "region-zone" notation of the zone location.
+	Zones    map[string][]*types.Block
+	Receipts map[string][]types.Receipts
+
+	// Regions holds the region coincident blocks of every region, keyed by
+	// the "region-0" notation, and Prime the prime coincident blocks.
+	Regions map[string][]*types.Block
+	Prime   []*types.Block
+
+	// External holds the external blocks every dominant chain receives from
+	// its subordinates, keyed by the location of the receiving chain
+	// ("0-0" for prime, "region-0" for a region).
+	External map[string][]*types.ExternalBlock
+
+	// Order is the intended difficulty order of every generated block.
+	Order map[common.Hash]int
+
+	// ETXs holds the external transactions emitted by every zone, keyed by
+	// the location of the emitting zone. Settled maps the ETXs applied by a
+	// destination zone to the hash of the block applying them. The others are
+	// still pending, or are skipped by the destination like the state
+	// processor does.
+	ETXs    map[string]types.Transactions
+	Settled map[common.Hash]common.Hash
+}
+
+// networkETX is an external transaction waiting to be routed to or settled by
+// its destination zone.
+type networkETX struct {
+	tx     *types.Transaction
+	dest   string               // Location of the destination zone
+	region int                  // Region of the destination zone
+	block  *types.ExternalBlock // Block of the emitting zone including the transaction
+}
+
+// networkKey returns the "region-zone" notation of a location.
+func networkKey(region, zone int) string {
+	return fmt.Sprintf("%d-%d", region, zone)
+}
+
+// NetworkZoneConfig returns the chain config the blocks of a zone are executed
+// with: config with the testnet chain ID and the location of the zone, so its
+// accounts are the ones of the address range of the zone.
+func NetworkZoneConfig(config *params.ChainConfig, region, zone int) *params.ChainConfig {
+	zoneConfig := *config
+	zoneConfig.ChainID = big.NewInt(int64(12000 + 100*region + zone))
+	zoneConfig.Context = params.ZONE
+	zoneConfig.Location = []byte{byte(region), byte(zone)}
+	return &zoneConfig
+}
+
+// NetworkETXSender returns the deterministic key sending the external
+// transactions of a zone, its address lies in the address range of the zone.
+func NetworkETXSender(region, zone int) (*ecdsa.PrivateKey, common.Address) {
+	idRange := params.LookupChainIDRange(big.NewInt(int64(12000 + 100*region + zone)))
+	seed := make([]byte, 16)
+	binary.BigEndian.PutUint32(seed[0:], uint32(region))
+	binary.BigEndian.PutUint32(seed[4:], uint32(zone))
+	for i := uint64(0); ; i++ {
+		binary.BigEndian.PutUint64(seed[8:], i)
+		key, err := crypto.ToECDSA(crypto.Keccak256(seed))
+		if err != nil {
+			continue
+		}
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		if int(addr[0]) >= idRange[0] && int(addr[0]) <= idRange[1] {
+			return key, addr
+		}
+	}
+}
+
+// NetworkETXRecipient returns the account of the destination zone receiving
+// the external transactions emitted by a zone.
+func NetworkETXRecipient(region, zone, destRegion, destZone int) common.Address {
+	idRange := params.LookupChainIDRange(big.NewInt(int64(12000 + 100*destRegion + destZone)))
+	return common.Address{byte(idRange[0]), 0xe7, byte(region), byte(zone)}
+}
+
+// Zone returns the blocks generated in the given zone.
+func (nb *NetworkBlocks) Zone(region, zone int) []*types.Block {
+	return nb.Zones[networkKey(region, zone)]
+}
+
+// Region returns the region coincident blocks of the given region.
+func (nb *NetworkBlocks) Region(region int) []*types.Block {
+	return nb.Regions[networkKey(region, 0)]
+}
+
+// GenerateNetwork creates a consistent set of prime, region and zone chains
+// rooted at the given genesis block. Blocks are generated round-robin across
+// all zones and every block carries the parent hashes and numbers of all three
+// contexts, so the result can be fed to fork choice, slice sync and settlement
+// tests without running real miners.
+//
+// Coincident blocks are chosen by the configured intervals rather than by the
+// proof of work, see NetworkBlocks.Order. As with GenerateChain, inserting the
+// blocks into a BlockChain requires a non-validating proof of work. For the
+// same reason, external transactions are routed along the intended coincident
+// blocks instead of being traced by the consensus engine.
+//
+// GenerateNetwork switches types.QuaiNetworkContext to the zone context while
+// executing blocks and must not be run concurrently with other chain code.
+func GenerateNetwork(config *params.ChainConfig, genesis *types.Block, engine consensus.Engine, db ethdb.Database, spec NetworkGenConfig) *NetworkBlocks {
+	if config == nil {
+		config = params.TestChainConfig
+	}
+	if spec.Regions == 0 {
+		spec.Regions = params.FullerOntology[0]
+	}
+	if spec.Zones == 0 {
+		spec.Zones = params.FullerOntology[1]
+	}
+	prevContext := types.QuaiNetworkContext
+	types.QuaiNetworkContext = params.ZONE
+	defer func() { types.QuaiNetworkContext = prevContext }()
+
+	var (
+		chainreader = &fakeChainReader{config: config}
+		result      = &NetworkBlocks{
+			Zones:    make(map[string][]*types.Block),
+			Receipts: make(map[string][]types.Receipts),
+			Regions:  make(map[string][]*types.Block),
+			External: make(map[string][]*types.ExternalBlock),
+			Order:    make(map[common.Hash]int),
+			ETXs:     make(map[string]types.Transactions),
+			Settled:  make(map[common.Hash]common.Hash),
+		}
+		primeHead   = genesis
+		regionHeads = make(map[int]*types.Block)
+
+		emitted = make(map[string][]*networkETX) // ETXs waiting for a coincident block of their zone
+		routed  = make(map[string][]*networkETX) // ETXs waiting to be settled by their destination zone
+	)
+	for r := 1; r <= spec.Regions; r++ {
+		regionHeads[r] = genesis
+	}
+	for i := 0; i < spec.Blocks; i++ {
+		for r := 1; r <= spec.Regions; r++ {
+			for z := 1; z <= spec.Zones; z++ {
+				key := networkKey(r, z)
+
+				parent := genesis
+				if zone := result.Zones[key]; len(zone) > 0 {
+					parent = zone[len(zone)-1]
+				}
+				order := params.ZONE
+				if spec.RegionInterval > 0 && (i+1)%spec.RegionInterval == 0 {
+					order = params.REGION
+					regionKey := networkKey(r, 0)
+					if spec.PrimeInterval > 0 && (len(result.Regions[regionKey])+1)%spec.PrimeInterval == 0 {
+						order = params.PRIME
+					}
+				}
+				statedb, err := state.New(parent.Header().Root[params.ZONE], state.NewDatabase(db), nil)
+				if err != nil {
+					panic(err)
+				}
+				zoneConfig := NetworkZoneConfig(config, r, z)
+				parents := []*types.Block{primeHead, regionHeads[r], parent}
+				b := &BlockGen{i: i, chain: result.Zones[key], parent: parent, statedb: statedb, config: zoneConfig, engine: engine}
+				b.header = makeNetworkHeader(chainreader, parents, engine, []byte{byte(r), byte(z)})
+
+				// Settle the ETXs routed to the zone before its own transactions,
+				// as the state processor does
+				settled := settleNetworkETXs(zoneConfig, b, routed[key])
+				delete(routed, key)
+				if zoneConfig.IsEtxRollup(b.header.Number[params.ZONE]) {
+					b.header.EtxRollupHash = make([]common.Hash, types.ContextDepth)
+					b.header.EtxRollupHash[params.ZONE] = EtxRollupHash(settled)
+				}
+				// Emit the ETXs of the zone to the next one
+				destRegion, destZone := r, z+1
+				if destZone > spec.Zones {
+					destRegion, destZone = r%spec.Regions+1, 1
+				}
+				etxs := emitNetworkETXs(zoneConfig, b, spec.ETXs, spec.ETXValue, NetworkETXRecipient(r, z, destRegion, destZone))
+
+				if spec.Gen != nil {
+					spec.Gen([]byte{byte(r), byte(z)}, i, b)
+				}
+				block, _ := engine.FinalizeAndAssemble(chainreader, b.header, statedb, b.txs, b.uncles, b.receipts)
+
+				root, err := statedb.Commit(config.DeleteEmptyAccounts(b.header.Number[params.ZONE]))
+				if err != nil {
+					panic(fmt.Sprintf("state write error: %v", err))
+				}
+				if err := statedb.Database().TrieDB().Commit(root, false, nil); err != nil {
+					panic(fmt.Sprintf("trie write error: %v", err))
+				}
+				result.Zones[key] = append(result.Zones[key], block)
+				result.Receipts[key] = append(result.Receipts[key], b.receipts)
+				result.Order[block.Hash()] = order
+
+				for _, tx := range settled {
+					result.Settled[tx.Hash()] = block.Hash()
+				}
+				if len(etxs) > 0 {
+					external := newGeneratedExternalBlock(block, b.receipts)
+					for _, tx := range etxs {
+						emitted[key] = append(emitted[key], &networkETX{tx: tx, dest: networkKey(destRegion, destZone), region: destRegion, block: external})
+					}
+					result.ETXs[key] = append(result.ETXs[key], etxs...)
+				}
+				// Route the ETXs the coincident block makes visible to their
+				// destination: region blocks the ones within the region, prime
+				// blocks all of them.
+				if order <= params.REGION {
+					var pending []*networkETX
+					for _, etx := range emitted[key] {
+						if order == params.PRIME || etx.region == r {
+							routed[etx.dest] = append(routed[etx.dest], etx)
+						} else {
+							pending = append(pending, etx)
+						}
+					}
+					emitted[key] = pending
+				}
+				// Coincident blocks advance the dominant chains and are sent
+				// to them as external blocks.
+				if order <= params.REGION {
+					regionKey := networkKey(r, 0)
+					regionHeads[r] = block
+					result.Regions[regionKey] = append(result.Regions[regionKey], block)
+					result.External[regionKey] = append(result.External[regionKey], newGeneratedExternalBlock(block, b.receipts))
+				}
+				if order == params.PRIME {
+					primeKey := networkKey(0, 0)
+					primeHead = block
+					result.Prime = append(result.Prime, block)
+					result.External[primeKey] = append(result.External[primeKey], newGeneratedExternalBlock(block, b.receipts))
+				}
+			}
+		}
+	}
+	return result
+}
+
+// settleNetworkETXs applies the routed ETXs to the generated block of their
+// destination zone the way the state processor does, returning the ones
+// applied. ETXs the processor skips are left out.
+func settleNetworkETXs(config *params.ChainConfig, b *BlockGen, etxs []*networkETX) types.Transactions {
+	var (
+		settled types.Transactions
+		signer  = types.MakeSigner(config, b.header.Number[params.ZONE])
+		vmenv   = vm.NewEVM(NewEVMBlockContext(b.header, nil, &b.header.Coinbase[params.ZONE]), vm.TxContext{}, b.statedb, config, vm.Config{})
+	)
+	for _, etx := range etxs {
+		msg, err := etx.tx.AsMessage(signer, b.header.BaseFee[params.ZONE])
+		if err != nil {
+			panic(err)
+		}
+		if !IsApplicableEtx(config, msg, etx.tx) {
+			continue
+		}
+		b.statedb.Prepare(etx.tx.Hash(), len(b.receipts))
+		receipt, err := applyExternalTransaction(msg, config, nil, nil, nil, b.statedb, b.header.Number[params.ZONE], common.Hash{}, etx.block, etx.tx, &b.header.GasUsed[params.ZONE], vmenv)
+		if err != nil {
+			panic(err)
+		}
+		b.receipts = append(b.receipts, receipt)
+		settled = append(settled, etx.tx)
+	}
+	return settled
+}
+
+// emitNetworkETXs adds n transactions sending value from the ETX sender of the
+// zone to the recipient in another zone to the generated block.
+func emitNetworkETXs(config *params.ChainConfig, b *BlockGen, n int, value *big.Int, to common.Address) types.Transactions {
+	if n == 0 {
+		return nil
+	}
+	if value == nil {
+		value = common.Big1
+	}
+	key, from := NetworkETXSender(int(config.Location[0]), int(config.Location[1]))
+	signer := types.MakeSigner(config, b.header.Number[params.ZONE])
+
+	etxs := make(types.Transactions, 0, n)
+	for i := 0; i < n; i++ {
+		tx, err := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   config.ChainID,
+			Nonce:     b.statedb.GetNonce(from),
+			GasTipCap: common.Big0,
+			GasFeeCap: b.header.BaseFee[params.ZONE],
+			Gas:       params.TxGas,
+			To:        &to,
+			Value:     value,
+		}), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		b.AddTx(tx)
+		etxs = append(etxs, tx)
+	}
+	return etxs
+}
+
+// makeNetworkHeader creates the header of a zone block whose dominant chain
+// heads are given in parents, ordered prime, region, zone.
+func makeNetworkHeader(chain consensus.ChainReader, parents []*types.Block, engine consensus.Engine, location []byte) *types.Header {
+	parent := parents[params.ZONE]
+	time := parent.Time() + 10 // block time is fixed at 10 seconds
+	baseFee := misc.CalcBaseFee(chain.Config(), parent.Header(), chain.GetHeaderByNumber, chain.GetUnclesInChain, chain.GetGasUsedInChain)
+
+	header := types.NewEmptyHeader()
+	copy(header.UncleHash, types.EmptyUncleHash)
+	copy(header.TxHash, types.EmptyRootHash)
+	copy(header.ReceiptHash, types.EmptyRootHash)
+	header.Time = time
+	header.BaseFee = []*big.Int{baseFee, baseFee, baseFee}
+	header.GasLimit = []uint64{params.MinGasLimit, params.MinGasLimit, params.MinGasLimit}
+	header.Location = location
+
+	for ctx, dom := range parents {
+		domHeader := dom.Header()
+		header.ParentHash[ctx] = dom.Hash()
+		header.Number[ctx] = new(big.Int).Add(domHeader.Number[ctx], common.Big1)
+		header.Difficulty[ctx] = engine.CalcDifficulty(chain, time, domHeader, ctx)
+	}
+	return header
+}
+
+// newGeneratedExternalBlock wraps a generated zone block as it is sent to the
+// dominant chains.
+func newGeneratedExternalBlock(block *types.Block, receipts types.Receipts) *types.ExternalBlock {
+	return types.NewExternalBlockWithHeader(block.Header()).WithBody(block.Transactions(), block.Uncles(), receipts, big.NewInt(int64(params.ZONE)))
+}
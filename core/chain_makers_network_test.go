@@ -0,0 +1,194 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus/blake3"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// newNetworkGenesis commits a genesis funding the ETX senders of every zone.
+func newNetworkGenesis(regions, zones int) *Genesis {
+	alloc := make(GenesisAlloc)
+	for r := 1; r <= regions; r++ {
+		for z := 1; z <= zones; z++ {
+			_, addr := NetworkETXSender(r, z)
+			alloc[addr] = GenesisAccount{Balance: big.NewInt(params.Ether)}
+		}
+	}
+	return &Genesis{
+		Config:     params.TestChainConfig,
+		Alloc:      alloc,
+		ParentHash: []common.Hash{{}, {}, {}},
+		Coinbase:   []common.Address{{}, {}, {}},
+		Number:     []*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(0)},
+		ExtraData:  [][]byte{nil, nil, nil},
+		GasLimit:   []uint64{params.GenesisGasLimit, params.GenesisGasLimit, params.GenesisGasLimit},
+		GasUsed:    []uint64{0, 0, 0},
+		Difficulty: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)},
+	}
+}
+
+func TestGenerateNetwork(t *testing.T) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		gspec = &Genesis{
+			Config:     params.TestChainConfig,
+			ParentHash: []common.Hash{{}, {}, {}},
+			Coinbase:   []common.Address{{}, {}, {}},
+			Number:     []*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(0)},
+			ExtraData:  [][]byte{nil, nil, nil},
+			GasLimit:   []uint64{params.GenesisGasLimit, params.GenesisGasLimit, params.GenesisGasLimit},
+			GasUsed:    []uint64{0, 0, 0},
+			Difficulty: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	blocks := GenerateNetwork(gspec.Config, genesis, blake3.NewFaker(), db, NetworkGenConfig{
+		Regions:        2,
+		Zones:          2,
+		Blocks:         6,
+		RegionInterval: 2,
+		PrimeInterval:  3,
+	})
+	for r := 1; r <= 2; r++ {
+		for z := 1; z <= 2; z++ {
+			zone := blocks.Zone(r, z)
+			if len(zone) != 6 {
+				t.Fatalf("zone %d-%d: block count mismatch: have %d, want 6", r, z, len(zone))
+			}
+			parent := genesis
+			for i, block := range zone {
+				if have := block.Header().ParentHash[params.ZONE]; have != parent.Hash() {
+					t.Fatalf("zone %d-%d block %d: parent mismatch: have %x, want %x", r, z, i, have, parent.Hash())
+				}
+				if have := block.Header().Number[params.ZONE].Uint64(); have != uint64(i+1) {
+					t.Fatalf("zone %d-%d block %d: number mismatch: have %d, want %d", r, z, i, have, i+1)
+				}
+				parent = block
+			}
+		}
+		// Every second zone block is a region block, 3 per zone
+		region := blocks.Region(r)
+		if len(region) != 6 {
+			t.Fatalf("region %d: block count mismatch: have %d, want 6", r, len(region))
+		}
+		for i, block := range region {
+			if have := block.Header().Number[params.REGION].Uint64(); have != uint64(i+1) {
+				t.Fatalf("region %d block %d: number mismatch: have %d, want %d", r, i, have, i+1)
+			}
+			if i > 0 && block.Header().ParentHash[params.REGION] != region[i-1].Hash() {
+				t.Fatalf("region %d block %d: parent mismatch", r, i)
+			}
+			if order := blocks.Order[block.Hash()]; order > params.REGION {
+				t.Fatalf("region %d block %d: order mismatch: have %d", r, i, order)
+			}
+		}
+		if have := len(blocks.External[networkKey(r, 0)]); have != 6 {
+			t.Fatalf("region %d: external block count mismatch: have %d, want 6", r, have)
+		}
+	}
+	// Every third region block is a prime block, 2 per region
+	if len(blocks.Prime) != 4 {
+		t.Fatalf("prime block count mismatch: have %d, want 4", len(blocks.Prime))
+	}
+	for i, block := range blocks.Prime {
+		if have := block.Header().Number[params.PRIME].Uint64(); have != uint64(i+1) {
+			t.Fatalf("prime block %d: number mismatch: have %d, want %d", i, have, i+1)
+		}
+		if i > 0 && block.Header().ParentHash[params.PRIME] != blocks.Prime[i-1].Hash() {
+			t.Fatalf("prime block %d: parent mismatch", i)
+		}
+	}
+	if have := len(blocks.External[networkKey(0, 0)]); have != 4 {
+		t.Fatalf("prime external block count mismatch: have %d, want 4", have)
+	}
+}
+
+func TestGenerateNetworkETXs(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		gspec   = newNetworkGenesis(2, 2)
+		genesis = gspec.MustCommit(db)
+		value   = big.NewInt(1000)
+	)
+	blocks := GenerateNetwork(gspec.Config, genesis, blake3.NewFaker(), db, NetworkGenConfig{
+		Regions:        2,
+		Zones:          2,
+		Blocks:         6,
+		RegionInterval: 2,
+		PrimeInterval:  2,
+		ETXs:           2,
+		ETXValue:       value,
+	})
+	// Zones are generated in the order 1-1, 1-2, 2-1, 2-2, each sending to the
+	// next one. Every second block is a region block and every second region
+	// block, the ones of the second zones, a prime block.
+	tests := []struct {
+		src, dest [2]int
+		routed    int
+	}{
+		{[2]int{1, 1}, [2]int{1, 2}, 12}, // Routed by region blocks, settled within the round
+		{[2]int{1, 2}, [2]int{2, 1}, 12}, // Routed by prime blocks, settled within the round
+		{[2]int{2, 1}, [2]int{2, 2}, 12}, // Routed by region blocks, settled within the round
+		{[2]int{2, 2}, [2]int{1, 1}, 8},  // Routed by prime blocks, settled the next round
+	}
+	for _, tt := range tests {
+		src := networkKey(tt.src[0], tt.src[1])
+		etxs := blocks.ETXs[src]
+		if len(etxs) != 12 {
+			t.Fatalf("zone %s: emitted etx count mismatch: have %d, want 12", src, len(etxs))
+		}
+		// Routed ETXs settle in order in blocks of their destination zone,
+		// unless the state processor of the zone would skip them
+		config := NetworkZoneConfig(gspec.Config, tt.dest[0], tt.dest[1])
+		destBlocks := make(map[common.Hash]int)
+		for i, block := range blocks.Zone(tt.dest[0], tt.dest[1]) {
+			destBlocks[block.Hash()] = i
+		}
+		var settled, last int
+		for i, tx := range etxs {
+			hash, ok := blocks.Settled[tx.Hash()]
+			msg, err := tx.AsMessage(types.MakeSigner(config, common.Big1), nil)
+			if err != nil {
+				t.Fatalf("zone %s: etx %d: failed to derive message: %v", src, i, err)
+			}
+			applicable := IsApplicableEtx(config, msg, tx)
+			if i >= tt.routed || !applicable {
+				if ok {
+					t.Fatalf("zone %s: etx %d: settled while pending or inapplicable", src, i)
+				}
+				continue
+			}
+			if !ok {
+				t.Fatalf("zone %s: etx %d: routed but not settled", src, i)
+			}
+			index, ok := destBlocks[hash]
+			if !ok {
+				t.Fatalf("zone %s: etx %d: settled outside of zone %v", src, i, tt.dest)
+			}
+			if index < last {
+				t.Fatalf("zone %s: etx %d: settled out of order", src, i)
+			}
+			last, settled = index, settled+1
+		}
+		if settled == 0 {
+			t.Errorf("zone %s: no etx settled", src)
+		}
+		// The recipient is credited with every settled ETX
+		head := blocks.Zone(tt.dest[0], tt.dest[1])[5]
+		statedb, err := state.New(head.Header().Root[params.ZONE], state.NewDatabase(db), nil)
+		if err != nil {
+			t.Fatalf("zone %v: failed to open state: %v", tt.dest, err)
+		}
+		have := statedb.GetBalance(NetworkETXRecipient(tt.src[0], tt.src[1], tt.dest[0], tt.dest[1]))
+		if want := new(big.Int).Mul(value, big.NewInt(int64(settled))); have.Cmp(want) != 0 {
+			t.Errorf("zone %s: recipient balance mismatch: have %v, want %v", src, have, want)
+		}
+	}
+}
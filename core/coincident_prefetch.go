@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+const (
+	// prefetchTimeout is the time a prefetch of dominant blocks may take.
+	prefetchTimeout = 5 * time.Second
+
+	// prefetchThreshold is the fraction, in percent, of the average gap between
+	// coincident blocks of an order after which the boundary is considered near.
+	prefetchThreshold = 75
+)
+
+var (
+	prefetchMeter     = metrics.NewRegisteredMeter("chain/coincident/prefetch", nil)
+	prefetchHitMeter  = metrics.NewRegisteredMeter("chain/coincident/prefetch/hits", nil)
+	prefetchFailMeter = metrics.NewRegisteredMeter("chain/coincident/prefetch/fails", nil)
+)
+
+// coincidentStats tracks the distance between the coincident blocks of every
+// dominant order on the local chain.
+type coincidentStats struct {
+	last []uint64 // Number of the last block of every order
+	gap  []uint64 // Moving average of the gap between blocks of every order
+}
+
+func newCoincidentStats() *coincidentStats {
+	return &coincidentStats{
+		last: make([]uint64, types.QuaiNetworkContext),
+		gap:  make([]uint64, types.QuaiNetworkContext),
+	}
+}
+
+// add records a new head of the given difficulty order.
+func (s *coincidentStats) add(number uint64, order int) {
+	for o := order; o < len(s.last); o++ {
+		if s.last[o] != 0 && number > s.last[o] {
+			gap := number - s.last[o]
+			if s.gap[o] == 0 {
+				s.gap[o] = gap
+			} else {
+				s.gap[o] = (s.gap[o]*7 + gap) / 8
+			}
+		}
+		s.last[o] = number
+	}
+}
+
+// near returns whether the head is close to the next coincident block of any
+// dominant order.
+func (s *coincidentStats) near(number uint64) bool {
+	for o := range s.last {
+		if s.gap[o] == 0 || number < s.last[o] {
+			continue
+		}
+		if (number-s.last[o])*100 >= s.gap[o]*prefetchThreshold {
+			return true
+		}
+	}
+	return false
+}
+
+// prefetchCoincident follows the chain head and, as it nears a coincident
+// boundary, fetches the candidate dominant blocks into the external block cache,
+// so PCRC at the boundary resolves without a synchronous round trip.
+func (bc *BlockChain) prefetchCoincident() {
+	defer bc.wg.Done()
+
+	var (
+		stats  = newCoincidentStats()
+		last   common.Hash // Dominant head prefetched last
+		headCh = make(chan ChainHeadEvent, 1)
+	)
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-headCh:
+			header := ev.Block.Header()
+			order, err := bc.engine.GetDifficultyOrder(header)
+			if err != nil {
+				continue
+			}
+			number := header.Number[types.QuaiNetworkContext].Uint64()
+			stats.add(number, order)
+			if order < types.QuaiNetworkContext || !stats.near(number) {
+				continue
+			}
+			last = bc.prefetchDominant(last)
+
+		case <-sub.Err():
+			return
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// prefetchDominant fetches the head of the dominant chain and its previous
+// coincident blocks of every dominant context into the external block cache,
+// unless the head is the one prefetched last. It returns the prefetched head.
+func (bc *BlockChain) prefetchDominant(last common.Hash) common.Hash {
+	ctx, cancel := context.WithTimeout(context.Background(), prefetchTimeout)
+	defer cancel()
+
+	client := bc.domClient.Client()
+	if client == nil {
+		return last
+	}
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		prefetchFailMeter.Mark(1)
+		log.Debug("Failed to prefetch dominant head", "err", err)
+		return last
+	}
+	if head.Hash() == last {
+		return last
+	}
+	headOrder, err := bc.engine.GetDifficultyOrder(head)
+	if err != nil {
+		return last
+	}
+	for c := 0; c < types.QuaiNetworkContext; c++ {
+		hash := head.ParentHash[c]
+		if headOrder <= c {
+			hash = head.Hash()
+		}
+		if cached, _ := bc.GetExternalBlockByHashAndContext(hash, c); cached != nil {
+			prefetchHitMeter.Mark(1)
+			continue
+		}
+		if ctx.Err() != nil {
+			prefetchFailMeter.Mark(1)
+			return last
+		}
+		extBlock := FindExternalBlock(client, hash, uint64(c))
+		if extBlock == nil {
+			prefetchFailMeter.Mark(1)
+			continue
+		}
+		bc.AddExternalBlock(extBlock)
+		prefetchMeter.Mark(1)
+		log.Debug("Prefetched coincident candidate", "context", c, "number", extBlock.Header().Number, "hash", hash)
+	}
+	return head.Hash()
+}
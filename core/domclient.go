@@ -0,0 +1,308 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	quai "github.com/spruce-solutions/go-quai"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/ethclient/quaiclient"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+const (
+	// domClientCheckInterval is the interval the connection to the dominant
+	// chain is checked at.
+	domClientCheckInterval = time.Second
+
+	// domQueryTimeout is the time a query to the dominant chain may take,
+	// including waiting for the connection to be reestablished.
+	domQueryTimeout = 30 * time.Second
+
+	// domHeadTimeout is the time polling the head of the dominant chain may take.
+	domHeadTimeout = time.Second
+
+	// domQueryBackoff is the initial delay before retrying a failed query,
+	// doubled on every retry up to domQueryMaxBackoff.
+	domQueryBackoff    = 500 * time.Millisecond
+	domQueryMaxBackoff = 5 * time.Second
+
+	// maxPendingDomQueries is the number of queries waiting for the connection
+	// to the dominant chain, further ones failing immediately.
+	maxPendingDomQueries = 256
+)
+
+var (
+	domDisconnectMeter = metrics.NewRegisteredMeter("chain/domclient/disconnects", nil)
+	domRetryMeter      = metrics.NewRegisteredMeter("chain/domclient/retries", nil)
+	domRejectMeter     = metrics.NewRegisteredMeter("chain/domclient/rejects", nil)
+	domPendingGauge    = metrics.NewRegisteredGauge("chain/domclient/pending", nil)
+
+	errDomQueueFull = errors.New("too many queries waiting for the dominant chain")
+	errDomClosed    = errors.New("dominant chain client closed")
+)
+
+// DomClientHealth is the state of the connection to the dominant chain.
+type DomClientHealth struct {
+	Connected  bool                        `json:"connected"`  // Whether any dominant endpoint is healthy
+	Since      time.Time                   `json:"since"`      // Time the connection state last changed
+	Reconnects uint64                      `json:"reconnects"` // Times the connection was reestablished
+	Pending    int                         `json:"pending"`    // Queries waiting for the connection
+	Endpoints  []quaiclient.EndpointStatus `json:"endpoints"`
+}
+
+// DomClient is the managed connection to the dominant chain. It follows the
+// health of the dominant endpoints, holding the PCRC and HLCR queries made
+// while they are all down until the connection is reestablished, and retries
+// failed queries with backoff. The head of the dominant chain is polled along,
+// changes being posted to the subscribers.
+type DomClient struct {
+	failover *quaiclient.Failover
+
+	head     common.Hash // Last polled head of the dominant chain
+	headFeed event.Feed
+
+	connected  bool
+	since      time.Time
+	reconnects uint64
+	pending    int
+	up         chan struct{} // Closed while connected, waited on by held queries
+	lock       sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewDomClient starts managing the connection of the failover client.
+func NewDomClient(failover *quaiclient.Failover) *DomClient {
+	d := &DomClient{
+		failover: failover,
+		up:       make(chan struct{}),
+		quit:     make(chan struct{}),
+	}
+	d.update()
+	d.wg.Add(1)
+	go d.loop()
+	return d
+}
+
+// Client returns the client of the active dominant endpoint.
+func (d *DomClient) Client() *quaiclient.Client {
+	return d.failover.Client()
+}
+
+// Status returns the health of the dominant endpoints.
+func (d *DomClient) Status() []quaiclient.EndpointStatus {
+	return d.failover.Status()
+}
+
+// Health returns the state of the connection to the dominant chain.
+func (d *DomClient) Health() *DomClientHealth {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return &DomClientHealth{
+		Connected:  d.connected,
+		Since:      d.since,
+		Reconnects: d.reconnects,
+		Pending:    d.pending,
+		Endpoints:  d.failover.Status(),
+	}
+}
+
+// SubscribeHead registers a subscription of DomHeadEvent.
+func (d *DomClient) SubscribeHead(ch chan<- DomHeadEvent) event.Subscription {
+	return d.headFeed.Subscribe(ch)
+}
+
+// Close releases the held queries and closes the dominant endpoints.
+func (d *DomClient) Close() {
+	close(d.quit)
+	d.wg.Wait()
+	d.failover.Close()
+}
+
+// GetBlockStatus queries the status of the header on the dominant chain,
+// NonStatTy being returned if the query failed.
+func (d *DomClient) GetBlockStatus(ctx context.Context, header *types.Header) quaiclient.WriteStatus {
+	status := quaiclient.NonStatTy
+	err := d.query(ctx, func(ctx context.Context) (err error) {
+		status, err = d.failover.GetBlockStatus(ctx, header)
+		return err
+	})
+	if err != nil {
+		log.Debug("Failed to query block status from dominant chain", "hash", header.Hash(), "err", err)
+		return quaiclient.NonStatTy
+	}
+	return status
+}
+
+// HLCRReorg asks the dominant chain whether it reorgs to the block.
+func (d *DomClient) HLCRReorg(ctx context.Context, block *types.Block) (bool, error) {
+	var reorg bool
+	err := d.query(ctx, func(ctx context.Context) (err error) {
+		reorg, err = d.failover.HLCRReorg(ctx, block)
+		return err
+	})
+	return reorg, err
+}
+
+// HeaderByHash looks up the header with the given hash on the dominant chain,
+// nil being returned if the dominant chain doesn't know it.
+func (d *DomClient) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	var header *types.Header
+	err := d.query(ctx, func(ctx context.Context) (err error) {
+		header, err = d.failover.HeaderByHash(ctx, hash)
+		if errors.Is(err, quai.NotFound) {
+			return nil
+		}
+		return err
+	})
+	return header, err
+}
+
+// query runs fn once the dominant chain is connected, retrying it with backoff
+// until it succeeds, the node itself returns an error or the query times out.
+func (d *DomClient) query(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, domQueryTimeout)
+	defer cancel()
+
+	backoff := domQueryBackoff
+	for {
+		if err := d.wait(ctx); err != nil {
+			return err
+		}
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		case <-d.quit:
+			return err
+		}
+		domRetryMeter.Mark(1)
+		if backoff *= 2; backoff > domQueryMaxBackoff {
+			backoff = domQueryMaxBackoff
+		}
+	}
+}
+
+// wait blocks while the dominant chain is disconnected, failing if too many
+// queries are already waiting.
+func (d *DomClient) wait(ctx context.Context) error {
+	d.lock.Lock()
+	if d.connected {
+		d.lock.Unlock()
+		return nil
+	}
+	if d.pending >= maxPendingDomQueries {
+		d.lock.Unlock()
+		domRejectMeter.Mark(1)
+		return errDomQueueFull
+	}
+	d.pending++
+	domPendingGauge.Update(int64(d.pending))
+	up := d.up
+	d.lock.Unlock()
+
+	defer func() {
+		d.lock.Lock()
+		d.pending--
+		domPendingGauge.Update(int64(d.pending))
+		d.lock.Unlock()
+	}()
+	select {
+	case <-up:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.quit:
+		return errDomClosed
+	}
+}
+
+func (d *DomClient) loop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(domClientCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if d.update() {
+				d.pollHead()
+			}
+		case <-d.quit:
+			return
+		}
+	}
+}
+
+// update refreshes the connection state from the health of the endpoints,
+// releasing the held queries once the connection is reestablished. It reports
+// whether the dominant chain is connected.
+func (d *DomClient) update() bool {
+	var connected bool
+	for _, status := range d.failover.Status() {
+		connected = connected || status.Healthy
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if connected == d.connected && !d.since.IsZero() {
+		return connected
+	}
+	first := d.since.IsZero()
+	d.connected, d.since = connected, time.Now()
+	if connected {
+		if !first {
+			d.reconnects++
+			log.Info("Dominant chain connection reestablished", "pending", d.pending)
+		}
+		close(d.up)
+		return true
+	}
+	d.up = make(chan struct{})
+	if !first {
+		domDisconnectMeter.Mark(1)
+		log.Warn("Dominant chain connection lost, holding queries until it's reestablished")
+	}
+	return false
+}
+
+// pollHead fetches the head of the dominant chain, posting it if it changed
+// since the last poll. The first head polled is only recorded.
+func (d *DomClient) pollHead() {
+	ctx, cancel := context.WithTimeout(context.Background(), domHeadTimeout)
+	defer cancel()
+
+	header, err := d.failover.Client().HeaderByNumber(ctx, nil)
+	if err != nil {
+		log.Trace("Failed to poll dominant chain head", "err", err)
+		return
+	}
+	hash := header.Hash()
+	if hash == d.head {
+		return
+	}
+	first := d.head == (common.Hash{})
+	d.head = hash
+	if !first {
+		log.Debug("Dominant chain head changed", "number", header.Number, "hash", hash)
+		d.headFeed.Send(DomHeadEvent{Header: header})
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 
 	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
 )
 
 var (
@@ -32,6 +33,10 @@ var (
 	// ErrNoGenesis is returned when there is no Genesis Block.
 	ErrNoGenesis = errors.New("genesis not found in chain")
 
+	// ErrEtxRollup is returned if the etx rollup hash of a block doesn't match
+	// the external transactions it applies.
+	ErrEtxRollup = errors.New("etx rollup mismatch")
+
 	errSideChainReceipts = errors.New("side blocks can't be accepted as ancient chain data")
 )
 
@@ -74,6 +79,18 @@ var (
 	// current network configuration.
 	ErrTxTypeNotSupported = types.ErrTxTypeNotSupported
 
+	// ErrTxExpired is returned if a transaction is included after the last block
+	// of its validity window.
+	ErrTxExpired = types.ErrTxExpired
+
+	// ErrAccountExpired is returned if the sender or the recipient of a transaction
+	// was removed by the state expiry and not revived since.
+	ErrAccountExpired = vm.ErrAccountExpired
+
+	// ErrInvalidRevival is returned if a revival transaction revives an account
+	// which is not expired or proves a state other than the expired one.
+	ErrInvalidRevival = errors.New("invalid state expiry revival")
+
 	// ErrTipAboveFeeCap is a sanity error to ensure no one is able to specify a
 	// transaction with a tip higher than the total fee cap.
 	ErrTipAboveFeeCap = errors.New("max priority fee per gas higher than max fee per gas")
@@ -95,4 +112,12 @@ var (
 
 	// ErrSenderNoEOA is returned if the sender of a transaction is a contract.
 	ErrSenderInoperable = errors.New("sender is in inoperable state")
+
+	// ErrWrongLocation is returned if the transaction is signed for the chain of
+	// another location.
+	ErrWrongLocation = errors.New("transaction signed for another location")
+
+	// ErrCrossLocationCall is returned if the transaction calls an address of
+	// another location, only plain value transfers can cross locations.
+	ErrCrossLocationCall = errors.New("contract call to another location")
 )
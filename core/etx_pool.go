@@ -0,0 +1,265 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/trie"
+)
+
+const (
+	// etxPoolSlots is the number of external transactions tracked at once, the
+	// oldest ones being evicted beyond it, awaiting inclusion first.
+	etxPoolSlots = 4096
+
+	// etxLifetime is the time an external transaction is tracked without
+	// being applied before it's evicted.
+	etxLifetime = time.Hour
+)
+
+var (
+	etxQueuedGauge  = metrics.NewRegisteredGauge("chain/etxpool/queued", nil)
+	etxPendingGauge = metrics.NewRegisteredGauge("chain/etxpool/pending", nil)
+	etxAppliedMeter = metrics.NewRegisteredMeter("chain/etxpool/applied", nil)
+	etxEvictedMeter = metrics.NewRegisteredMeter("chain/etxpool/evicted", nil)
+	etxInvalidMeter = metrics.NewRegisteredMeter("chain/etxpool/invalid", nil)
+
+	errEtxContext = errors.New("external block context out of range")
+	errEtxTxHash  = errors.New("external block transactions don't match its header")
+	errEtxFailed  = errors.New("external transaction failed in its origin block")
+)
+
+// EtxEntry is an external transaction destined to this chain tracked by the
+// pool until it's applied.
+type EtxEntry struct {
+	Hash        common.Hash     `json:"hash"`
+	From        common.Address  `json:"from"`
+	To          *common.Address `json:"to"`
+	Value       *hexutil.Big    `json:"value"`
+	Gas         hexutil.Uint64  `json:"gas"`
+	Origin      hexutil.Uint    `json:"origin"`      // Context of the origin block
+	Location    hexutil.Bytes   `json:"location"`    // Location of the origin block
+	BlockHash   common.Hash     `json:"blockHash"`   // Hash of the origin block
+	BlockNumber *hexutil.Big    `json:"blockNumber"` // Number of the origin block in its context
+	Included    bool            `json:"included"`    // Whether a coincident block linked the origin block into this chain
+	Since       time.Time       `json:"since"`       // Time the transaction was first seen
+
+	tx *types.Transaction
+}
+
+// Transaction returns the external transaction.
+func (e *EtxEntry) Transaction() *types.Transaction {
+	return e.tx
+}
+
+// EtxPool tracks the external transactions destined to this chain from the
+// time their origin block is seen until they're applied, apart from the local
+// transaction pool. Transactions are queued while their origin block is only
+// known from the external block cache and pending once a coincident block of
+// the canonical chain links it in, to be applied by the next block.
+type EtxPool struct {
+	config *params.ChainConfig
+
+	all  map[common.Hash]*EtxEntry
+	lock sync.RWMutex
+}
+
+// NewEtxPool creates an empty external transaction pool.
+func NewEtxPool(config *params.ChainConfig) *EtxPool {
+	return &EtxPool{
+		config: config,
+		all:    make(map[common.Hash]*EtxEntry),
+	}
+}
+
+// AddBlock tracks the external transactions of the block destined to this
+// chain, marking them included if the block is linked in by a coincident
+// block. Blocks failing validation are rejected as a whole.
+func (p *EtxPool) AddBlock(block *types.ExternalBlock, included bool) error {
+	entries, err := p.validate(block)
+	if err != nil {
+		etxInvalidMeter.Mark(1)
+		log.Debug("Rejected external block from etx pool", "hash", block.Hash(), "context", block.Context(), "err", err)
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, entry := range entries {
+		if known := p.all[entry.Hash]; known != nil {
+			known.Included = known.Included || included
+			continue
+		}
+		entry.Included = included
+		p.all[entry.Hash] = entry
+	}
+	p.evict()
+	return nil
+}
+
+// Applied drops the external transactions of the blocks applied by a block of
+// the canonical chain.
+func (p *EtxPool) Applied(blocks []*types.ExternalBlock) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, block := range blocks {
+		for _, tx := range block.Transactions() {
+			if _, ok := p.all[tx.Hash()]; ok {
+				delete(p.all, tx.Hash())
+				etxAppliedMeter.Mark(1)
+			}
+		}
+	}
+	p.updateGauges()
+}
+
+// Content returns the pending and queued external transactions, oldest first.
+func (p *EtxPool) Content() (pending []*EtxEntry, queued []*EtxEntry) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.evict()
+	for _, entry := range p.sorted() {
+		cpy := *entry
+		if entry.Included {
+			pending = append(pending, &cpy)
+		} else {
+			queued = append(queued, &cpy)
+		}
+	}
+	return pending, queued
+}
+
+// Get returns the tracked external transaction with the hash, nil if unknown.
+func (p *EtxPool) Get(hash common.Hash) *EtxEntry {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if entry := p.all[hash]; entry != nil {
+		cpy := *entry
+		return &cpy
+	}
+	return nil
+}
+
+// Clear drops all tracked external transactions.
+func (p *EtxPool) Clear() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.all = make(map[common.Hash]*EtxEntry)
+	p.updateGauges()
+}
+
+// validate checks the origin of the block, proving its transactions against
+// its header in its context, and returns the successful ones destined to this
+// chain.
+func (p *EtxPool) validate(block *types.ExternalBlock) ([]*EtxEntry, error) {
+	if block.Context() == nil || block.Context().Sign() < 0 || block.Context().Int64() >= int64(types.ContextDepth) {
+		return nil, errEtxContext
+	}
+	context := int(block.Context().Int64())
+	header := block.Header()
+	if len(header.TxHash) <= context || len(header.Number) <= context {
+		return nil, errEtxContext
+	}
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash[context] {
+		return nil, fmt.Errorf("%w: have %x, want %x", errEtxTxHash, hash, header.TxHash[context])
+	}
+	var (
+		signer  = types.MakeSigner(p.config, header.Number[context])
+		entries []*EtxEntry
+		now     = time.Now()
+	)
+	for _, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer, nil)
+		if err != nil || !IsApplicableEtx(p.config, msg, tx) {
+			continue
+		}
+		if receipt := block.ReceiptForTransaction(tx); receipt.Status != types.ReceiptStatusSuccessful {
+			return nil, fmt.Errorf("%w: %x", errEtxFailed, tx.Hash())
+		}
+		entries = append(entries, &EtxEntry{
+			Hash:        tx.Hash(),
+			From:        msg.From(),
+			To:          tx.To(),
+			Value:       (*hexutil.Big)(tx.Value()),
+			Gas:         hexutil.Uint64(tx.Gas()),
+			Origin:      hexutil.Uint(context),
+			Location:    common.CopyBytes(header.Location),
+			BlockHash:   block.Hash(),
+			BlockNumber: (*hexutil.Big)(new(big.Int).Set(header.Number[context])),
+			Since:       now,
+			tx:          tx,
+		})
+	}
+	return entries, nil
+}
+
+// evict drops the transactions past their lifetime, then the oldest ones over
+// the pool capacity, queued before pending ones. The lock must be held.
+func (p *EtxPool) evict() {
+	var evicted int
+	for hash, entry := range p.all {
+		if time.Since(entry.Since) > etxLifetime {
+			delete(p.all, hash)
+			evicted++
+		}
+	}
+	if overflow := len(p.all) - etxPoolSlots; overflow > 0 {
+		entries := p.sorted()
+		sort.SliceStable(entries, func(i, j int) bool {
+			return !entries[i].Included && entries[j].Included
+		})
+		for _, entry := range entries[:overflow] {
+			delete(p.all, entry.Hash)
+		}
+		evicted += overflow
+	}
+	if evicted > 0 {
+		etxEvictedMeter.Mark(int64(evicted))
+		log.Debug("Evicted external transactions", "count", evicted, "tracked", len(p.all))
+	}
+	p.updateGauges()
+}
+
+// sorted returns the tracked transactions, oldest first. The lock must be held.
+func (p *EtxPool) sorted() []*EtxEntry {
+	entries := make([]*EtxEntry, 0, len(p.all))
+	for _, entry := range p.all {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Since.Equal(entries[j].Since) {
+			return entries[i].Since.Before(entries[j].Since)
+		}
+		return entries[i].Hash.Hex() < entries[j].Hash.Hex()
+	})
+	return entries
+}
+
+// updateGauges reports the pool size. The lock must be held.
+func (p *EtxPool) updateGauges() {
+	var pending int
+	for _, entry := range p.all {
+		if entry.Included {
+			pending++
+		}
+	}
+	etxPendingGauge.Update(int64(pending))
+	etxQueuedGauge.Update(int64(len(p.all) - pending))
+}
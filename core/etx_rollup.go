@@ -0,0 +1,39 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/trie"
+)
+
+// EtxRollupHash returns the commitment to the external transactions a block
+// applies from the external blocks of its coincident window, the root of their
+// trie in the order they're applied.
+func EtxRollupHash(etxs types.Transactions) common.Hash {
+	return types.DeriveSha(etxs, trie.NewStackTrie(nil))
+}
+
+// IsApplicableEtx reports whether the transaction of an external block is
+// applied by a block of this chain. The state processor and the miner both
+// select external transactions with it, so the rollup committed to by a mined
+// block is the one verified on import.
+func IsApplicableEtx(config *params.ChainConfig, msg types.Message, tx *types.Transaction) bool {
+	return msg.FromExternal() && params.CheckETxChainID(config.ChainID, tx.ChainId())
+}
+
+// verifyEtxRollup checks that the header commits to the external transactions
+// applied by its block, so blocks can't leave out the pending cross-chain
+// transactions of their window.
+func verifyEtxRollup(header *types.Header, etxs types.Transactions) error {
+	want := EtxRollupHash(etxs)
+	if len(header.EtxRollupHash) <= types.QuaiNetworkContext {
+		return fmt.Errorf("%w: missing, want %x", ErrEtxRollup, want)
+	}
+	if have := header.EtxRollupHash[types.QuaiNetworkContext]; have != want {
+		return fmt.Errorf("%w: have %x, want %x (%d etxs)", ErrEtxRollup, have, want, len(etxs))
+	}
+	return nil
+}
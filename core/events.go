@@ -17,12 +17,17 @@
 package core
 
 import (
+	"math/big"
+
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/core/types"
 )
 
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
-type NewTxsEvent struct{ Txs []*types.Transaction }
+type NewTxsEvent struct {
+	Txs     []*types.Transaction
+	Context int // Context of the chain the pool belongs to
+}
 
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
@@ -30,10 +35,13 @@ type NewMinedBlockEvent struct{ Block *types.Block }
 // RemovedLogsEvent is posted when a reorg happens
 type RemovedLogsEvent struct{ Logs []*types.Log }
 
+// ChainEvent is posted when a block is inserted into the canonical chain.
 type ChainEvent struct {
-	Block *types.Block
-	Hash  common.Hash
-	Logs  []*types.Log
+	Block   *types.Block
+	Hash    common.Hash
+	Logs    []*types.Log
+	Context int // Context of the chain the block was inserted in
+	Order   int // Order of the block, the most dominant context it's coincident in
 }
 
 type ReOrgRollup struct {
@@ -43,8 +51,13 @@ type ReOrgRollup struct {
 	NewSubs         []common.Hash
 	NewSubContext   int
 }
+
+// ChainSideEvent is posted when a block is inserted into a side chain or
+// dropped from the canonical chain by a reorg.
 type ChainSideEvent struct {
-	Block *types.Block
+	Block   *types.Block
+	Context int // Context of the chain the block was inserted in
+	Order   int // Order of the block, the most dominant context it's coincident in
 }
 
 type MissingExternalBlock struct {
@@ -53,4 +66,62 @@ type MissingExternalBlock struct {
 	Context  int
 }
 
-type ChainHeadEvent struct{ Block *types.Block }
+// ChainHeadEvent is posted when the head of the canonical chain changes. Heads
+// inserted in a batch are only posted once for the last block.
+type ChainHeadEvent struct {
+	Block   *types.Block
+	Context int // Context of the chain the block was inserted in
+	Order   int // Order of the block, the most dominant context it's coincident in
+}
+
+// DomHeadEvent is posted when the head of the dominant chain changes, so work
+// linking to the previous one as coincident parent went stale.
+type DomHeadEvent struct{ Header *types.Header }
+
+// ForkChoiceReason is the rule the fork choice selected a new head by.
+type ForkChoiceReason string
+
+const (
+	// ForkChoiceHLCR selects the head with the greater difficulty tuple.
+	ForkChoiceHLCR ForkChoiceReason = "hlcr"
+
+	// ForkChoiceEqualTdLower selects the lower head of two with equal difficulty.
+	ForkChoiceEqualTdLower ForkChoiceReason = "equal-td-lower"
+
+	// ForkChoiceEqualTdPreserve selects the head preserved by the miner of two at
+	// equal height and difficulty.
+	ForkChoiceEqualTdPreserve ForkChoiceReason = "equal-td-preserve"
+
+	// ForkChoiceEqualTdCoinflip selects one of two heads at equal height and
+	// difficulty at random.
+	ForkChoiceEqualTdCoinflip ForkChoiceReason = "equal-td-coinflip"
+
+	// ForkChoiceEqualTdKeepLocal keeps the local head of two at equal height
+	// and difficulty.
+	ForkChoiceEqualTdKeepLocal ForkChoiceReason = "equal-td-keep-local"
+
+	// ForkChoiceEqualTdAdopt selects the extern head of two at equal height and
+	// difficulty.
+	ForkChoiceEqualTdAdopt ForkChoiceReason = "equal-td-adopt"
+
+	// ForkChoiceEqualTdFirstSeen selects the head received first of two at
+	// equal height and difficulty.
+	ForkChoiceEqualTdFirstSeen ForkChoiceReason = "equal-td-first-seen"
+
+	// ForkChoiceDomReorg selects the head the dominant chain reorganised to.
+	ForkChoiceDomReorg ForkChoiceReason = "dom-reorg"
+
+	// ForkChoiceDomAnchored selects a head reorging deeper than the limit, as a
+	// coincident block canonical in the dominant chain anchors it.
+	ForkChoiceDomAnchored ForkChoiceReason = "dom-anchored"
+)
+
+// ForkChoiceEvent is posted when the fork choice selects a new head.
+type ForkChoiceEvent struct {
+	Context int              `json:"context"` // Context of the chain the head was selected in
+	OldHead *types.Header    `json:"oldHead"`
+	NewHead *types.Header    `json:"newHead"`
+	OldTd   []*big.Int       `json:"oldTd"`
+	NewTd   []*big.Int       `json:"newTd"`
+	Reason  ForkChoiceReason `json:"reason"`
+}
@@ -22,11 +22,16 @@ import (
 	"fmt"
 	"math/big"
 	mrand "math/rand"
+	"time"
 
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/common/math"
+	"github.com/spruce-solutions/go-quai/consensus"
 	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/event"
 	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
 	"github.com/spruce-solutions/go-quai/params"
 )
 
@@ -43,15 +48,24 @@ type ChainReader interface {
 	// CalcTd calculates the TD of the given header using PCRC and CalcHLCRNetDifficulty.
 	CalcTd(header *types.Header) ([]*big.Int, error)
 
+	// CalcTdBatch calculates the TDs of a contiguous range of headers in one pass.
+	CalcTdBatch(headers []*types.Header) ([][]*big.Int, error)
+
 	// GetBlockByHash retrieves a block from the database by hash, caching it if found.
 	GetBlockByHash(hash common.Hash) *types.Block
 
+	// GetHeader retrieves a block header from the database by hash and number.
+	GetHeader(hash common.Hash, number uint64) *types.Header
+
 	// HLCR does hierarchical comparison of two difficulty tuples and returns true if second tuple is greater than the first
 	HLCR(localDifficulties []*big.Int, externDifficulties []*big.Int) bool
 
 	// DomReorgNeeded checks the dominant chain for the reorg status.
 	DomReorgNeeded(header *types.Header) (bool, error)
 
+	// DomCanonical checks whether a coincident header is canonical in the dominant chain.
+	DomCanonical(header *types.Header) (bool, error)
+
 	// PCCRC The purpose of the Previous Coincident Reference Check (PCRC) is to establish
 	PCCRC(header *types.Header, headerOrder int) (types.PCRCTermini, error)
 
@@ -59,6 +73,40 @@ type ChainReader interface {
 	GetDifficultyOrder(header *types.Header) (int, error)
 }
 
+// firstSeenLimit is the number of block arrival times kept for the first-seen
+// tiebreak.
+const firstSeenLimit = 4096
+
+// reorgRefusedMeter counts the reorgs refused for exceeding the depth limit.
+var reorgRefusedMeter = metrics.NewRegisteredMeter("chain/reorg/refused", nil)
+
+// TiebreakPolicy is the rule selecting between two heads at equal height and
+// total difficulty.
+type TiebreakPolicy string
+
+const (
+	// TiebreakRandom adopts the extern head with the configured probability.
+	TiebreakRandom TiebreakPolicy = "random"
+
+	// TiebreakKeepLocal always keeps the local head.
+	TiebreakKeepLocal TiebreakPolicy = "keep-local"
+
+	// TiebreakAdopt always adopts the extern head.
+	TiebreakAdopt TiebreakPolicy = "adopt"
+
+	// TiebreakFirstSeen keeps the head the node received first.
+	TiebreakFirstSeen TiebreakPolicy = "first-seen"
+)
+
+// ParseTiebreakPolicy validates the name of a tiebreak policy.
+func ParseTiebreakPolicy(name string) (TiebreakPolicy, error) {
+	switch policy := TiebreakPolicy(name); policy {
+	case TiebreakRandom, TiebreakKeepLocal, TiebreakAdopt, TiebreakFirstSeen:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown tiebreak policy %q, want one of %s, %s, %s or %s", name, TiebreakRandom, TiebreakKeepLocal, TiebreakAdopt, TiebreakFirstSeen)
+}
+
 // ForkChoice is the fork chooser based on the highest total difficulty of the
 // chain(the fork choice used in the eth1) and the external fork choice (the fork
 // choice used in the eth2). This main goal of this ForkChoice is not only for
@@ -73,18 +121,68 @@ type ForkChoice struct {
 	// local td is equal to the extern one. It can be nil for light
 	// client
 	preserve func(header *types.Header) bool
+
+	tiebreak    TiebreakPolicy // Rule selecting between heads of equal height and difficulty
+	probability float64        // Probability of adopting the extern head under the random policy
+	seen        *lru.Cache     // Time the recent blocks were first received at
+
+	maxReorgDepth uint64 // Depth of the zone reorgs refused unless anchored by the dominant chain (0 = unlimited)
+
+	feed event.Feed // Feed of the heads selected, with the reason
 }
 
-func NewForkChoice(chainReader ChainReader, preserve func(header *types.Header) bool) *ForkChoice {
+func NewForkChoice(chainReader ChainReader, preserve func(header *types.Header) bool, tiebreak TiebreakPolicy, probability float64, maxReorgDepth uint64) *ForkChoice {
 	// Seed a fast but crypto originating random generator
 	seed, err := crand.Int(crand.Reader, big.NewInt(math.MaxInt64))
 	if err != nil {
 		log.Crit("Failed to initialize random seed", "err", err)
 	}
+	if tiebreak == "" {
+		tiebreak, probability = TiebreakRandom, 0.5
+	}
+	seen, _ := lru.New(firstSeenLimit)
 	return &ForkChoice{
-		chain:    chainReader,
-		rand:     mrand.New(mrand.NewSource(seed.Int64())),
-		preserve: preserve,
+		chain:         chainReader,
+		rand:          mrand.New(mrand.NewSource(seed.Int64())),
+		preserve:      preserve,
+		tiebreak:      tiebreak,
+		probability:   probability,
+		seen:          seen,
+		maxReorgDepth: maxReorgDepth,
+	}
+}
+
+// MarkSeen records the arrival time of the block, unless it was seen before.
+func (f *ForkChoice) MarkSeen(hash common.Hash) {
+	f.seen.ContainsOrAdd(hash, time.Now())
+}
+
+// seenBefore returns whether the extern block was received before the local
+// one. The local head is kept unless the arrivals of both blocks are recorded.
+func (f *ForkChoice) seenBefore(extern, local common.Hash) bool {
+	localSeen, ok := f.seen.Get(local)
+	if !ok {
+		return false
+	}
+	externSeen, ok := f.seen.Get(extern)
+	if !ok {
+		return false
+	}
+	return externSeen.(time.Time).Before(localSeen.(time.Time))
+}
+
+// breakTie returns whether the extern head is adopted over the local one of
+// equal height and difficulty under the tiebreak policy.
+func (f *ForkChoice) breakTie(current, header *types.Header) (bool, ForkChoiceReason) {
+	switch f.tiebreak {
+	case TiebreakKeepLocal:
+		return false, ForkChoiceEqualTdKeepLocal
+	case TiebreakAdopt:
+		return true, ForkChoiceEqualTdAdopt
+	case TiebreakFirstSeen:
+		return f.seenBefore(header.Hash(), current.Hash()), ForkChoiceEqualTdFirstSeen
+	default:
+		return f.rand.Float64() < f.probability, ForkChoiceEqualTdCoinflip
 	}
 }
 
@@ -115,17 +213,25 @@ func (f *ForkChoice) ReorgNeeded(current *types.Header, header *types.Header) (b
 	// Second clause in the if statement reduces the vulnerability to selfish mining.
 	// Please refer to http://www.cs.cornell.edu/~ie53/publications/btcProcFC.pdf
 	reorg := f.chain.HLCR(localTd, externTd)
+	reason := ForkChoiceHLCR
 	equalTd := externTd[0].Cmp(localTd[0]) == 0 && externTd[1].Cmp(localTd[1]) == 0 && externTd[2].Cmp(localTd[2]) == 0
 	if !reorg && equalTd {
 		number, headNumber := header.Number[types.QuaiNetworkContext].Uint64(), current.Number[types.QuaiNetworkContext].Uint64()
 		if number < headNumber {
-			reorg = true
+			reorg, reason = true, ForkChoiceEqualTdLower
 		} else if number == headNumber {
 			var currentPreserve, externPreserve bool
 			if f.preserve != nil {
 				currentPreserve, externPreserve = f.preserve(current), f.preserve(header)
 			}
-			reorg = !currentPreserve && (externPreserve || f.rand.Float64() < 0.5)
+			switch {
+			case currentPreserve:
+				reorg, reason = false, ForkChoiceEqualTdPreserve
+			case externPreserve:
+				reorg, reason = true, ForkChoiceEqualTdPreserve
+			default:
+				reorg, reason = f.breakTie(current, header)
+			}
 		}
 	}
 
@@ -138,9 +244,103 @@ func (f *ForkChoice) ReorgNeeded(current *types.Header, header *types.Header) (b
 	// 	reorg = domReorg
 	// }
 
+	if reorg && f.maxReorgDepth > 0 && types.QuaiNetworkContext == params.ZONE {
+		depth, anchored, err := f.reorgDepth(current, header)
+		if err != nil {
+			return false, err
+		}
+		if depth > f.maxReorgDepth {
+			if !anchored {
+				reorgRefusedMeter.Mark(1)
+				log.Warn("Refused reorg exceeding the depth limit", "depth", depth, "limit", f.maxReorgDepth,
+					"number", header.Number[types.QuaiNetworkContext], "hash", header.Hash())
+				return false, nil
+			}
+			reason = ForkChoiceDomAnchored
+		}
+	}
+
+	if reorg {
+		f.feed.Send(ForkChoiceEvent{
+			Context: types.QuaiNetworkContext,
+			OldHead: current,
+			NewHead: header,
+			OldTd:   localTd,
+			NewTd:   externTd,
+			Reason:  reason,
+		})
+	}
 	return reorg, nil
 }
 
+// reorgDepth returns the number of blocks of the local chain dropped by a reorg
+// to the extern head, and whether the extern chain is anchored by a coincident
+// block above the common ancestor that the dominant chain holds canonical, the
+// anchor carrying its finality. Once the depth exceeds the limit without an
+// anchor, the walk stops and the depth reached is returned.
+func (f *ForkChoice) reorgDepth(current, header *types.Header) (uint64, bool, error) {
+	var (
+		ctx      = types.QuaiNetworkContext
+		depth    uint64
+		anchored bool
+	)
+	parent := func(header *types.Header) (*types.Header, error) {
+		number := header.Number[ctx].Uint64()
+		if number == 0 {
+			return nil, errors.New("reorg without common ancestor")
+		}
+		parent := f.chain.GetHeader(header.ParentHash[ctx], number-1)
+		if parent == nil {
+			return nil, fmt.Errorf("missing ancestor %d of reorg", number-1)
+		}
+		return parent, nil
+	}
+	extern := func(header *types.Header) (*types.Header, error) {
+		if !anchored {
+			order, err := f.chain.GetDifficultyOrder(header)
+			if err != nil {
+				return nil, err
+			}
+			if order < ctx {
+				if anchored, err = f.chain.DomCanonical(header); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return parent(header)
+	}
+	exceeded := func() bool {
+		return !anchored && f.maxReorgDepth > 0 && depth > f.maxReorgDepth
+	}
+	var err error
+	for header.Number[ctx].Cmp(current.Number[ctx]) > 0 {
+		if header, err = extern(header); err != nil {
+			return 0, false, err
+		}
+	}
+	for current.Number[ctx].Cmp(header.Number[ctx]) > 0 && !exceeded() {
+		if current, err = parent(current); err != nil {
+			return 0, false, err
+		}
+		depth++
+	}
+	for current.Hash() != header.Hash() && !exceeded() {
+		if header, err = extern(header); err != nil {
+			return 0, false, err
+		}
+		if current, err = parent(current); err != nil {
+			return 0, false, err
+		}
+		depth++
+	}
+	return depth, anchored, nil
+}
+
+// SubscribeForkChoiceEvent registers a subscription of ForkChoiceEvent.
+func (f *ForkChoice) SubscribeForkChoiceEvent(ch chan<- ForkChoiceEvent) event.Subscription {
+	return f.feed.Subscribe(ch)
+}
+
 func (f *ForkChoice) UntwistAndTrim(header *types.Header) error {
 	headerOrder, err := f.chain.GetDifficultyOrder(header)
 	if err != nil {
@@ -148,19 +348,12 @@ func (f *ForkChoice) UntwistAndTrim(header *types.Header) error {
 	}
 
 	_, err = f.chain.PCCRC(header, headerOrder)
-	fmt.Println("PCCRC", err)
-
-	if err != nil {
-		if err.Error() == "slice is not synced" {
-			fmt.Println("PCCRC", err)
-			log.Debug("Slice not synced, no nothing", "hash", header.Hash())
-			return nil
-		} else if err.Error() == "PCCOP has found chain is not being built on canonical dom" {
-			return nil
-		} else {
-			fmt.Println("PCCRC", err)
-			return err
-		}
+	switch {
+	case errors.Is(err, consensus.ErrSliceNotSynced):
+		log.Debug("Slice not synced, no nothing", "hash", header.Hash())
+		return nil
+	case errors.Is(err, consensus.ErrNonCanonicalDom):
+		return nil
 	}
-	return nil
+	return err
 }
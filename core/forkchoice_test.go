@@ -0,0 +1,373 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	mrand "math/rand"
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// forkTestChain is a chain reader over a set of headers, with the difficulty
+// tuples and orders of the headers, and the coincident ones the dominant chain
+// holds canonical, set by the tests.
+type forkTestChain struct {
+	ChainReader // Unused methods panic
+
+	headers   map[common.Hash]*types.Header
+	tds       map[common.Hash][]*big.Int
+	orders    map[common.Hash]int
+	canonical map[common.Hash]bool
+	domErr    error
+}
+
+func newForkTestChain() *forkTestChain {
+	return &forkTestChain{
+		headers:   make(map[common.Hash]*types.Header),
+		tds:       make(map[common.Hash][]*big.Int),
+		orders:    make(map[common.Hash]int),
+		canonical: make(map[common.Hash]bool),
+	}
+}
+
+// extend adds n headers on top of the parent, the fork byte telling apart the
+// headers of different forks, and returns them. Every header adds one to the
+// difficulty of the fork.
+func (c *forkTestChain) extend(parent *types.Header, n int, fork byte) []*types.Header {
+	headers := make([]*types.Header, n)
+	for i := range headers {
+		header := types.NewEmptyHeader()
+		for ctx := range header.Number {
+			header.Number[ctx] = new(big.Int).Add(parent.Number[ctx], common.Big1)
+			header.ParentHash[ctx] = parent.Hash()
+			header.Extra[ctx] = []byte{fork}
+		}
+		td := make([]*big.Int, types.ContextDepth)
+		for ctx := range td {
+			td[ctx] = new(big.Int).Set(c.tds[parent.Hash()][ctx])
+		}
+		td[types.QuaiNetworkContext].Add(td[types.QuaiNetworkContext], common.Big1)
+
+		c.headers[header.Hash()] = header
+		c.tds[header.Hash()] = td
+		headers[i], parent = header, header
+	}
+	return headers
+}
+
+// genesis adds a genesis header of no difficulty.
+func (c *forkTestChain) genesis() *types.Header {
+	header := types.NewEmptyHeader()
+	for ctx := range header.Number {
+		header.Number[ctx] = new(big.Int)
+	}
+	c.headers[header.Hash()] = header
+	c.tds[header.Hash()] = []*big.Int{new(big.Int), new(big.Int), new(big.Int)}
+	return header
+}
+
+func (c *forkTestChain) GetTd(hash common.Hash, number uint64) []*big.Int {
+	return c.tds[hash]
+}
+
+func (c *forkTestChain) CalcTd(header *types.Header) ([]*big.Int, error) {
+	return c.tds[header.Hash()], nil
+}
+
+func (c *forkTestChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.headers[hash]
+}
+
+func (c *forkTestChain) HLCR(local []*big.Int, extern []*big.Int) bool {
+	for ctx := range local {
+		if cmp := local[ctx].Cmp(extern[ctx]); cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return false
+}
+
+func (c *forkTestChain) DomCanonical(header *types.Header) (bool, error) {
+	return c.canonical[header.Hash()], c.domErr
+}
+
+func (c *forkTestChain) GetDifficultyOrder(header *types.Header) (int, error) {
+	if order, ok := c.orders[header.Hash()]; ok {
+		return order, nil
+	}
+	return types.QuaiNetworkContext, nil
+}
+
+// Tests that the depth of a reorg is the number of local blocks dropped, for
+// extern chains shorter, as long as and longer than the local one.
+func TestReorgDepth(t *testing.T) {
+	chain := newForkTestChain()
+	genesis := chain.genesis()
+	local := chain.extend(genesis, 10, 0)
+
+	tests := []struct {
+		fork   int // Number of the fork point
+		extern int // Number of extern blocks on top of the fork point
+		depth  uint64
+	}{
+		{fork: 10, extern: 1, depth: 0},
+		{fork: 9, extern: 1, depth: 1},
+		{fork: 5, extern: 2, depth: 5},
+		{fork: 5, extern: 5, depth: 5},
+		{fork: 5, extern: 8, depth: 5},
+		{fork: 0, extern: 3, depth: 10},
+	}
+	forker := NewForkChoice(chain, nil, TiebreakKeepLocal, 0, 0)
+	for i, tt := range tests {
+		parent := genesis
+		if tt.fork > 0 {
+			parent = local[tt.fork-1]
+		}
+		extern := chain.extend(parent, tt.extern, byte(i+1))
+
+		depth, anchored, err := forker.reorgDepth(local[len(local)-1], extern[len(extern)-1])
+		if err != nil {
+			t.Fatalf("test %d: failed to compute depth: %v", i, err)
+		}
+		if depth != tt.depth || anchored {
+			t.Errorf("test %d: depth mismatch: have %d (anchored %v), want %d", i, depth, anchored, tt.depth)
+		}
+	}
+}
+
+// Tests that the depth of a reorg fails if the extern chain misses ancestors.
+func TestReorgDepthMissingAncestor(t *testing.T) {
+	chain := newForkTestChain()
+	genesis := chain.genesis()
+	local := chain.extend(genesis, 10, 0)
+	extern := chain.extend(local[4], 8, 1)
+
+	delete(chain.headers, extern[2].Hash())
+
+	forker := NewForkChoice(chain, nil, TiebreakKeepLocal, 0, 0)
+	if _, _, err := forker.reorgDepth(local[len(local)-1], extern[len(extern)-1]); err == nil {
+		t.Fatalf("depth computed without ancestors")
+	}
+}
+
+// Tests that zone reorgs deeper than the limit are refused unless a coincident
+// block above the common ancestor, canonical in the dominant chain, anchors
+// them.
+func TestReorgNeededDepthLimit(t *testing.T) {
+	defer func(context int) { types.QuaiNetworkContext = context }(types.QuaiNetworkContext)
+	types.QuaiNetworkContext = params.ZONE
+
+	chain := newForkTestChain()
+	genesis := chain.genesis()
+	local := chain.extend(genesis, 10, 0)
+	current := local[len(local)-1]
+
+	var (
+		shallow  = chain.extend(local[7], 4, 1) // Drops 2 blocks
+		deep     = chain.extend(local[4], 6, 2) // Drops 5 blocks
+		anchored = chain.extend(local[4], 6, 3) // Drops 5 blocks, anchored by a region block
+		orphaned = chain.extend(local[4], 6, 4) // Drops 5 blocks, region block not canonical in the dominant chain
+		missing  = chain.extend(local[4], 6, 5) // Drops 5 blocks, misses an ancestor
+	)
+	chain.orders[anchored[2].Hash()] = params.REGION
+	chain.canonical[anchored[2].Hash()] = true
+	chain.orders[orphaned[2].Hash()] = params.REGION
+	delete(chain.headers, missing[1].Hash())
+
+	tests := []struct {
+		name   string
+		head   *types.Header
+		reorg  bool
+		reason ForkChoiceReason
+		err    bool
+	}{
+		{name: "shallow", head: shallow[len(shallow)-1], reorg: true, reason: ForkChoiceHLCR},
+		{name: "deep", head: deep[len(deep)-1], reorg: false},
+		{name: "anchored", head: anchored[len(anchored)-1], reorg: true, reason: ForkChoiceDomAnchored},
+		{name: "orphaned", head: orphaned[len(orphaned)-1], reorg: false},
+		{name: "missing", head: missing[len(missing)-1], err: true},
+	}
+	for _, tt := range tests {
+		forker := NewForkChoice(chain, nil, TiebreakKeepLocal, 0, 3)
+		events := make(chan ForkChoiceEvent, 1)
+		sub := forker.SubscribeForkChoiceEvent(events)
+
+		reorg, err := forker.ReorgNeeded(current, tt.head)
+		sub.Unsubscribe()
+
+		if (err != nil) != tt.err {
+			t.Errorf("%s: error mismatch: have %v, want error %v", tt.name, err, tt.err)
+			continue
+		}
+		if reorg != tt.reorg {
+			t.Errorf("%s: reorg mismatch: have %v, want %v", tt.name, reorg, tt.reorg)
+			continue
+		}
+		if reorg {
+			if ev := <-events; ev.Reason != tt.reason {
+				t.Errorf("%s: reason mismatch: have %s, want %s", tt.name, ev.Reason, tt.reason)
+			}
+		}
+	}
+	// Without a limit, deep reorgs are applied
+	forker := NewForkChoice(chain, nil, TiebreakKeepLocal, 0, 0)
+	if reorg, err := forker.ReorgNeeded(current, deep[len(deep)-1]); err != nil || !reorg {
+		t.Errorf("unlimited: have reorg %v, err %v, want reorg", reorg, err)
+	}
+}
+
+// Tests that the walk of a reorg stops once past the depth limit without an
+// anchor, and that failing to reach the dominant chain fails the walk.
+func TestReorgDepthLimitStop(t *testing.T) {
+	defer func(context int) { types.QuaiNetworkContext = context }(types.QuaiNetworkContext)
+	types.QuaiNetworkContext = params.ZONE
+
+	chain := newForkTestChain()
+	genesis := chain.genesis()
+	local := chain.extend(genesis, 10, 0)
+	current := local[len(local)-1]
+
+	// The missing ancestor and the anchor lie below the limit, out of the walk
+	deep := chain.extend(local[0], 12, 1)
+	delete(chain.headers, deep[1].Hash())
+	chain.orders[deep[2].Hash()] = params.REGION
+	chain.canonical[deep[2].Hash()] = true
+
+	forker := NewForkChoice(chain, nil, TiebreakKeepLocal, 0, 3)
+	depth, anchored, err := forker.reorgDepth(current, deep[len(deep)-1])
+	if err != nil {
+		t.Fatalf("walk went past the limit: %v", err)
+	}
+	if depth != 4 || anchored {
+		t.Errorf("depth mismatch: have %d (anchored %v), want 4", depth, anchored)
+	}
+	// Coincident blocks in the walk are looked up in the dominant chain
+	chain.orders[deep[9].Hash()] = params.REGION
+	chain.domErr = errors.New("dominant chain unreachable")
+	if _, _, err := forker.reorgDepth(current, deep[len(deep)-1]); !errors.Is(err, chain.domErr) {
+		t.Errorf("error mismatch: have %v, want %v", err, chain.domErr)
+	}
+}
+
+// Tests that the depth limit only applies to zone chains.
+func TestReorgNeededDepthLimitZoneOnly(t *testing.T) {
+	chain := newForkTestChain()
+	genesis := chain.genesis()
+	local := chain.extend(genesis, 10, 0)
+	deep := chain.extend(local[0], 12, 1)
+
+	forker := NewForkChoice(chain, nil, TiebreakKeepLocal, 0, 3)
+	reorg, err := forker.ReorgNeeded(local[len(local)-1], deep[len(deep)-1])
+	if err != nil || !reorg {
+		t.Fatalf("have reorg %v, err %v, want reorg", reorg, err)
+	}
+}
+
+// Tests the head selected between two of equal height and difficulty under
+// every tiebreak policy, and that the heads preserved by the miner win.
+func TestReorgNeededTiebreak(t *testing.T) {
+	chain := newForkTestChain()
+	genesis := chain.genesis()
+	local := chain.extend(genesis, 2, 0)[1]
+	extern := chain.extend(genesis, 2, 1)[1]
+
+	preserveLocal := func(header *types.Header) bool { return header.Hash() == local.Hash() }
+	preserveExtern := func(header *types.Header) bool { return header.Hash() == extern.Hash() }
+
+	tests := []struct {
+		name        string
+		policy      TiebreakPolicy
+		probability float64
+		preserve    func(header *types.Header) bool
+		seen        []*types.Header // Arrival order of the heads
+		reorg       bool
+		reason      ForkChoiceReason
+	}{
+		{name: "keep-local", policy: TiebreakKeepLocal, reorg: false},
+		{name: "adopt", policy: TiebreakAdopt, reorg: true, reason: ForkChoiceEqualTdAdopt},
+		{name: "random-never", policy: TiebreakRandom, probability: 0, reorg: false},
+		{name: "random-always", policy: TiebreakRandom, probability: 1, reorg: true, reason: ForkChoiceEqualTdCoinflip},
+		{name: "first-seen-local", policy: TiebreakFirstSeen, seen: []*types.Header{local, extern}, reorg: false},
+		{name: "first-seen-extern", policy: TiebreakFirstSeen, seen: []*types.Header{extern, local}, reorg: true, reason: ForkChoiceEqualTdFirstSeen},
+		{name: "first-seen-unknown-local", policy: TiebreakFirstSeen, seen: []*types.Header{extern}, reorg: false},
+		{name: "first-seen-unknown-extern", policy: TiebreakFirstSeen, seen: []*types.Header{local}, reorg: false},
+		{name: "preserve-local", policy: TiebreakAdopt, preserve: preserveLocal, reorg: false},
+		{name: "preserve-extern", policy: TiebreakKeepLocal, preserve: preserveExtern, reorg: true, reason: ForkChoiceEqualTdPreserve},
+	}
+	for _, tt := range tests {
+		forker := NewForkChoice(chain, tt.preserve, tt.policy, tt.probability, 0)
+		for _, header := range tt.seen {
+			forker.MarkSeen(header.Hash())
+			time.Sleep(time.Millisecond)
+		}
+		events := make(chan ForkChoiceEvent, 1)
+		sub := forker.SubscribeForkChoiceEvent(events)
+
+		reorg, err := forker.ReorgNeeded(local, extern)
+		sub.Unsubscribe()
+
+		if err != nil {
+			t.Errorf("%s: failed to choose fork: %v", tt.name, err)
+			continue
+		}
+		if reorg != tt.reorg {
+			t.Errorf("%s: reorg mismatch: have %v, want %v", tt.name, reorg, tt.reorg)
+			continue
+		}
+		if reorg {
+			if ev := <-events; ev.Reason != tt.reason {
+				t.Errorf("%s: reason mismatch: have %s, want %s", tt.name, ev.Reason, tt.reason)
+			}
+		}
+	}
+}
+
+// Tests that the random tiebreak adopts the extern head with the configured
+// probability and keeps the reason of the policies when the local head is kept.
+func TestBreakTie(t *testing.T) {
+	chain := newForkTestChain()
+	genesis := chain.genesis()
+	local := chain.extend(genesis, 1, 0)[0]
+	extern := chain.extend(genesis, 1, 1)[0]
+
+	tests := []struct {
+		policy TiebreakPolicy
+		reason ForkChoiceReason
+	}{
+		{TiebreakKeepLocal, ForkChoiceEqualTdKeepLocal},
+		{TiebreakAdopt, ForkChoiceEqualTdAdopt},
+		{TiebreakFirstSeen, ForkChoiceEqualTdFirstSeen},
+		{TiebreakRandom, ForkChoiceEqualTdCoinflip},
+	}
+	for _, tt := range tests {
+		forker := NewForkChoice(chain, nil, tt.policy, 0.5, 0)
+		if _, reason := forker.breakTie(local, extern); reason != tt.reason {
+			t.Errorf("%s: reason mismatch: have %s, want %s", tt.policy, reason, tt.reason)
+		}
+	}
+	// The adoption rate stays within 5 standard deviations of the probability
+	const trials = 10000
+	for _, probability := range []float64{0.1, 0.5, 0.9} {
+		forker := NewForkChoice(chain, nil, TiebreakRandom, probability, 0)
+		forker.rand = mrand.New(mrand.NewSource(1))
+
+		var adopted int
+		for i := 0; i < trials; i++ {
+			if adopt, _ := forker.breakTie(local, extern); adopt {
+				adopted++
+			}
+		}
+		var (
+			want  = probability * trials
+			bound = 5 * math.Sqrt(trials*probability*(1-probability))
+		)
+		if diff := math.Abs(float64(adopted) - want); diff > bound {
+			t.Errorf("probability %v: adopted %d of %d, want %v ± %v", probability, adopted, trials, want, bound)
+		}
+	}
+}
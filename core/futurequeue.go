@@ -0,0 +1,167 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+const (
+	// defaultFutureBlockLimit is the number of blocks queued for later import
+	// if not configured.
+	defaultFutureBlockLimit = 256
+
+	// defaultFutureBlockHorizon is how far ahead of the local clock a block may
+	// be dated to be queued if not configured.
+	defaultFutureBlockHorizon = 30 * time.Second
+)
+
+var (
+	futureBlockGauge      = metrics.NewRegisteredGauge("chain/future/queued", nil)
+	futureBlockMeter      = metrics.NewRegisteredMeter("chain/future/queues", nil)
+	futureInjectMeter     = metrics.NewRegisteredMeter("chain/future/injects", nil)
+	futureDropMeter       = metrics.NewRegisteredMeter("chain/future/drops", nil)
+	futureRejectMeter     = metrics.NewRegisteredMeter("chain/future/rejects", nil)
+	futureBlockDelayTimer = metrics.NewRegisteredTimer("chain/future/delay", nil)
+)
+
+// futureBlock is a block queued until its timestamp is valid or its ancestors
+// are imported.
+type futureBlock struct {
+	block  *types.Block
+	due    time.Time // Time the block is re-injected at
+	queued time.Time // Time the block was queued at
+}
+
+// futureQueue schedules the blocks dated ahead of the local clock, or missing
+// their ancestors, for import once their timestamp is valid. It is bounded in
+// size and in how far ahead blocks may be dated, so peers with skewed clocks
+// can't grow it. Beyond the limit, the blocks due last are dropped first.
+type futureQueue struct {
+	blocks  map[common.Hash]*futureBlock
+	limit   int           // Maximum number of queued blocks
+	horizon time.Duration // Maximum time a block may be dated ahead of the local clock
+	lock    sync.Mutex
+}
+
+// newFutureQueue creates a queue of the limit and horizon, zero meaning the
+// defaults.
+func newFutureQueue(limit int, horizon time.Duration) *futureQueue {
+	if limit <= 0 {
+		limit = defaultFutureBlockLimit
+	}
+	if horizon <= 0 {
+		horizon = defaultFutureBlockHorizon
+	}
+	return &futureQueue{
+		blocks:  make(map[common.Hash]*futureBlock),
+		limit:   limit,
+		horizon: horizon,
+	}
+}
+
+// add schedules the block for import at its timestamp, or right away if the
+// timestamp is already valid. It fails if the block is dated beyond the
+// horizon. Blocks already queued keep their schedule.
+func (q *futureQueue) add(block *types.Block) error {
+	now := time.Now()
+	due := time.Unix(int64(block.Time()), 0)
+	if max := now.Add(q.horizon); due.After(max) {
+		futureRejectMeter.Mark(1)
+		return fmt.Errorf("future block timestamp %v > allowed %v", block.Time(), max.Unix())
+	}
+	if due.Before(now) {
+		due = now
+	}
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if _, ok := q.blocks[block.Hash()]; ok {
+		return nil
+	}
+	q.blocks[block.Hash()] = &futureBlock{block: block, due: due, queued: now}
+	futureBlockMeter.Mark(1)
+	for len(q.blocks) > q.limit {
+		q.dropLatest()
+	}
+	futureBlockGauge.Update(int64(len(q.blocks)))
+	return nil
+}
+
+// dropLatest drops the block due last, the highest numbered one among the
+// blocks due at the same time. The lock must be held.
+func (q *futureQueue) dropLatest() {
+	var latest *futureBlock
+	for _, f := range q.blocks {
+		if latest == nil || f.due.After(latest.due) || (f.due.Equal(latest.due) && f.block.NumberU64() > latest.block.NumberU64()) {
+			latest = f
+		}
+	}
+	if latest != nil {
+		delete(q.blocks, latest.block.Hash())
+		futureDropMeter.Mark(1)
+		log.Debug("Dropped future block", "number", latest.block.Number(), "hash", latest.block.Hash(), "due", latest.due)
+	}
+}
+
+// remove drops the block from the queue, if queued.
+func (q *futureQueue) remove(hash common.Hash) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	delete(q.blocks, hash)
+	futureBlockGauge.Update(int64(len(q.blocks)))
+}
+
+// purge drops all the queued blocks.
+func (q *futureQueue) purge() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.blocks = make(map[common.Hash]*futureBlock)
+	futureBlockGauge.Update(0)
+}
+
+// due removes the blocks due by now from the queue, returning them in number
+// order.
+func (q *futureQueue) due(now time.Time) types.Blocks {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	var blocks types.Blocks
+	for hash, f := range q.blocks {
+		if f.due.After(now) {
+			continue
+		}
+		blocks = append(blocks, f.block)
+		delete(q.blocks, hash)
+		futureBlockDelayTimer.UpdateSince(f.queued)
+	}
+	futureBlockGauge.Update(int64(len(q.blocks)))
+
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].NumberU64() < blocks[j].NumberU64()
+	})
+	return blocks
+}
+
+// procFutureBlocks re-injects the future blocks due by now. Blocks still
+// missing their ancestors are queued again by the import.
+func (bc *BlockChain) procFutureBlocks() {
+	blocks := bc.futureBlocks.due(time.Now())
+	if len(blocks) == 0 {
+		return
+	}
+	futureInjectMeter.Mark(int64(len(blocks)))
+
+	// Insert one by one as chain insertion needs contiguous ancestry between blocks
+	for i := range blocks {
+		bc.InsertChain(blocks[i : i+1])
+	}
+}
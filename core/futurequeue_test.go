@@ -0,0 +1,125 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+)
+
+// newFutureTestBlock creates a block of the number dated at the time, the
+// parent telling apart blocks of the same number.
+func newFutureTestBlock(number uint64, time time.Time, parent byte) *types.Block {
+	header := types.NewEmptyHeader()
+	for i := range header.Number {
+		header.Number[i] = new(big.Int).SetUint64(number)
+		header.ParentHash[i] = common.Hash{parent}
+	}
+	header.Time = uint64(time.Unix())
+	return types.NewBlockWithHeader(header)
+}
+
+// Tests that blocks dated beyond the horizon are rejected and the ones within
+// it are due at their timestamp.
+func TestFutureQueueHorizon(t *testing.T) {
+	queue := newFutureQueue(16, time.Minute)
+	now := time.Now()
+
+	if err := queue.add(newFutureTestBlock(1, now.Add(2*time.Minute), 0)); err == nil {
+		t.Fatalf("block beyond the horizon queued")
+	}
+	block := newFutureTestBlock(1, now.Add(30*time.Second), 0)
+	if err := queue.add(block); err != nil {
+		t.Fatalf("failed to queue block within the horizon: %v", err)
+	}
+	if due := queue.due(now); len(due) != 0 {
+		t.Fatalf("block due before its timestamp: %v", due)
+	}
+	due := queue.due(now.Add(31 * time.Second))
+	if len(due) != 1 || due[0].Hash() != block.Hash() {
+		t.Fatalf("queued block not due at its timestamp: %v", due)
+	}
+	if len(queue.blocks) != 0 {
+		t.Fatalf("due block left in the queue")
+	}
+}
+
+// Tests that the blocks due last are dropped beyond the limit, the highest
+// numbered ones first among the blocks due at the same time.
+func TestFutureQueueLimit(t *testing.T) {
+	queue := newFutureQueue(3, time.Minute)
+	now := time.Now()
+
+	var (
+		early  = newFutureTestBlock(5, now.Add(10*time.Second), 0)
+		low    = newFutureTestBlock(1, now.Add(20*time.Second), 0)
+		high   = newFutureTestBlock(2, now.Add(20*time.Second), 0)
+		latest = newFutureTestBlock(3, now.Add(40*time.Second), 0)
+	)
+	for _, block := range []*types.Block{early, low, high, latest} {
+		if err := queue.add(block); err != nil {
+			t.Fatalf("failed to queue block %d: %v", block.NumberU64(), err)
+		}
+	}
+	if _, ok := queue.blocks[latest.Hash()]; ok {
+		t.Fatalf("block due last not dropped")
+	}
+	// Among the blocks due at the same time, the highest numbered is dropped
+	if err := queue.add(newFutureTestBlock(4, now.Add(5*time.Second), 0)); err != nil {
+		t.Fatalf("failed to queue block: %v", err)
+	}
+	if _, ok := queue.blocks[high.Hash()]; ok {
+		t.Fatalf("highest numbered block due last not dropped")
+	}
+	for _, block := range []*types.Block{early, low} {
+		if _, ok := queue.blocks[block.Hash()]; !ok {
+			t.Errorf("block %d dropped", block.NumberU64())
+		}
+	}
+	if len(queue.blocks) != 3 {
+		t.Errorf("queue size mismatch: have %d, want 3", len(queue.blocks))
+	}
+}
+
+// Tests that the due blocks are returned in number order, whatever their
+// timestamps and the order they were queued in.
+func TestFutureQueueDueOrder(t *testing.T) {
+	queue := newFutureQueue(16, time.Minute)
+	now := time.Now()
+
+	for i, number := range []uint64{7, 3, 9, 1, 5} {
+		if err := queue.add(newFutureTestBlock(number, now.Add(time.Duration(10-i)*time.Second), byte(i))); err != nil {
+			t.Fatalf("failed to queue block %d: %v", number, err)
+		}
+	}
+	due := queue.due(now.Add(time.Minute))
+	if len(due) != 5 {
+		t.Fatalf("due count mismatch: have %d, want 5", len(due))
+	}
+	for i := 1; i < len(due); i++ {
+		if due[i-1].NumberU64() > due[i].NumberU64() {
+			t.Fatalf("due blocks out of order: %d before %d", due[i-1].NumberU64(), due[i].NumberU64())
+		}
+	}
+}
+
+// Tests that blocks with a valid timestamp, queued for their missing ancestors,
+// are due right away and keep their schedule when queued again.
+func TestFutureQueueMissingAncestor(t *testing.T) {
+	queue := newFutureQueue(16, time.Minute)
+	now := time.Now()
+
+	block := newFutureTestBlock(1, now.Add(-time.Minute), 0)
+	if err := queue.add(block); err != nil {
+		t.Fatalf("failed to queue block: %v", err)
+	}
+	if err := queue.add(block); err != nil {
+		t.Fatalf("failed to queue block again: %v", err)
+	}
+	due := queue.due(time.Now())
+	if len(due) != 1 || due[0].Hash() != block.Hash() {
+		t.Fatalf("block missing its ancestors not due: %v", due)
+	}
+}
@@ -253,7 +253,8 @@ func (g *Genesis) configOrDefault(ghash common.Hash) *params.ChainConfig {
 }
 
 // ToBlock creates the genesis block and writes state of a genesis specification
-// to the given database (or discards it if nil).
+// to the given database (or discards it if nil). Header fields left out of the
+// specification default for every context.
 func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 	if db == nil {
 		db = rawdb.NewMemoryDatabase()
@@ -293,6 +294,27 @@ func (g *Genesis) ToBlock(db ethdb.Database) *types.Block {
 	if len(g.GasLimit) == 0 {
 		head.GasLimit = []uint64{params.GenesisGasLimit, params.GenesisGasLimit, params.GenesisGasLimit}
 	}
+	if len(g.Number) == 0 {
+		head.Number = []*big.Int{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	if len(g.ParentHash) == 0 {
+		head.ParentHash = make([]common.Hash, types.ContextDepth)
+	}
+	if len(g.ExtraData) == 0 {
+		head.Extra = make([][]byte, types.ContextDepth)
+	}
+	if len(g.GasUsed) == 0 {
+		head.GasUsed = make([]uint64, types.ContextDepth)
+	}
+	if len(g.Difficulty) == 0 {
+		head.Difficulty = make([]*big.Int, types.ContextDepth)
+		for i := range head.Difficulty {
+			head.Difficulty[i] = new(big.Int).Set(params.GenesisDifficulty[i])
+		}
+	}
+	if len(g.Coinbase) == 0 {
+		head.Coinbase = make([]common.Address, types.ContextDepth)
+	}
 
 	statedb.Commit(false)
 	statedb.Database().TrieDB().Commit(root, true, nil)
@@ -317,7 +339,7 @@ func (g *Genesis) Commit(db ethdb.Database) (*types.Block, error) {
 	if config.Clique != nil && len(block.Extra()) == 0 {
 		return nil, errors.New("can't start clique chain without signers")
 	}
-	rawdb.WriteTd(db, block.Hash(), block.NumberU64(), g.Difficulty)
+	rawdb.WriteTd(db, block.Hash(), block.NumberU64(), block.Header().Difficulty)
 	rawdb.WriteBlock(db, block)
 	rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), nil)
 	rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
@@ -341,8 +363,14 @@ func (g *Genesis) MustCommit(db ethdb.Database) *types.Block {
 // GenesisBlockForTesting creates and writes a block in which addr has the given wei balance.
 func GenesisBlockForTesting(db ethdb.Database, addr common.Address, balance *big.Int) *types.Block {
 	g := Genesis{
-		Alloc:   GenesisAlloc{addr: {Balance: balance}},
-		BaseFee: []*big.Int{big.NewInt(params.InitialBaseFee), big.NewInt(params.InitialBaseFee), big.NewInt(params.InitialBaseFee)},
+		Alloc:      GenesisAlloc{addr: {Balance: balance}},
+		ParentHash: []common.Hash{{}, {}, {}},
+		Coinbase:   []common.Address{{}, {}, {}},
+		Number:     []*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(0)},
+		ExtraData:  [][]byte{nil, nil, nil},
+		GasUsed:    []uint64{0, 0, 0},
+		Difficulty: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)},
+		BaseFee:    []*big.Int{big.NewInt(params.InitialBaseFee), big.NewInt(params.InitialBaseFee), big.NewInt(params.InitialBaseFee)},
 	}
 	return g.MustCommit(db)
 }
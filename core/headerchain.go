@@ -24,6 +24,7 @@ import (
 	"math"
 	"math/big"
 	mrand "math/rand"
+	"runtime"
 	"sync/atomic"
 	"time"
 
@@ -31,18 +32,38 @@ import (
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/consensus"
 	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/twistindex"
 	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/ethdb"
 	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
 	"github.com/spruce-solutions/go-quai/params"
 )
 
+var (
+	errRegionTwist = fmt.Errorf("%w in region", consensus.ErrTwist)
+	errPrimeTwist  = fmt.Errorf("%w in prime", consensus.ErrTwist)
+)
+
 const (
 	headerCacheLimit = 512
 	tdCacheLimit     = 1024
 	numberCacheLimit = 2048
+	pcrcCacheLimit   = 1024
+)
+
+var (
+	pcrcCacheHitMeter  = metrics.NewRegisteredMeter("chain/pcrc/cache/hits", nil)
+	pcrcCacheMissMeter = metrics.NewRegisteredMeter("chain/pcrc/cache/misses", nil)
 )
 
+// pcrcKey identifies a cached PCRC or PCCRC result.
+type pcrcKey struct {
+	hash  common.Hash
+	order int
+	cross bool // Whether the result is of the PCCRC, checking the subordinate slices too
+}
+
 // HeaderChain implements the basic block header chain logic that is shared by
 // core.BlockChain and light.LightChain. It is not usable in itself, only as
 // a part of either structure.
@@ -68,6 +89,9 @@ type HeaderChain struct {
 	headerCache *lru.Cache // Cache for the most recent block headers
 	tdCache     *lru.Cache // Cache for the most recent block total difficulties
 	numberCache *lru.Cache // Cache for the most recent block numbers
+	pcrcCache   *lru.Cache // Cache for the most recent PCRC termini, purged on reorg
+
+	twists *twistindex.Index // Subordinate blocks rejected for twisting the dominant chains
 
 	procInterrupt func() bool
 
@@ -81,6 +105,7 @@ func NewHeaderChain(chainDb ethdb.Database, config *params.ChainConfig, engine c
 	headerCache, _ := lru.New(headerCacheLimit)
 	tdCache, _ := lru.New(tdCacheLimit)
 	numberCache, _ := lru.New(numberCacheLimit)
+	pcrcCache, _ := lru.New(pcrcCacheLimit)
 
 	// Seed a fast but crypto originating random generator
 	seed, err := crand.Int(crand.Reader, big.NewInt(math.MaxInt64))
@@ -94,6 +119,8 @@ func NewHeaderChain(chainDb ethdb.Database, config *params.ChainConfig, engine c
 		headerCache:   headerCache,
 		tdCache:       tdCache,
 		numberCache:   numberCache,
+		pcrcCache:     pcrcCache,
+		twists:        twistindex.New(chainDb),
 		procInterrupt: procInterrupt,
 		rand:          mrand.New(mrand.NewSource(seed.Int64())),
 		engine:        engine,
@@ -166,6 +193,11 @@ func (hc *HeaderChain) writeHeaders(headers []*types.Header) (result *headerWrit
 		firstInserted = -1         // Index of the first non-ignored header
 	)
 
+	tds, err := hc.CalcTdBatch(headers)
+	if err != nil {
+		return &headerWriteResult{}, errors.New("error calculating the total td for the header")
+	}
+
 	batch := hc.chainDb.NewBatch()
 	parentKnown := true // Set to true to force hc.HasHeader check the first iteration
 	for i, header := range headers {
@@ -179,10 +211,7 @@ func (hc *HeaderChain) writeHeaders(headers []*types.Header) (result *headerWrit
 			hash = header.Hash()
 		}
 
-		newTd, err = hc.CalcTd(header)
-		if err != nil {
-			return &headerWriteResult{}, errors.New("error calculating the total td for the header")
-		}
+		newTd = tds[i]
 
 		number := header.Number[types.QuaiNetworkContext].Uint64()
 
@@ -350,6 +379,10 @@ func (hc *HeaderChain) ValidateHeaderChain(chain []*types.Header, checkFreq int)
 	abort, results := hc.engine.VerifyHeaders(hc, chain, seals)
 	defer close(abort)
 
+	// Run the order checks alongside on their own pool
+	orderAbort, orderResults := hc.verifyOrders(chain, seals, runtime.GOMAXPROCS(0))
+	defer close(orderAbort)
+
 	// Iterate over the headers and ensure they all check out
 	for i := range chain {
 		// If the chain is terminating, stop processing blocks
@@ -361,6 +394,9 @@ func (hc *HeaderChain) ValidateHeaderChain(chain []*types.Header, checkFreq int)
 		if err := <-results; err != nil {
 			return i, err
 		}
+		if err := <-orderResults; err != nil {
+			return i, err
+		}
 	}
 
 	return 0, nil
@@ -715,19 +751,24 @@ func (hc *HeaderChain) HLCR(localDifficulties []*big.Int, externDifficulties []*
 
 // CalcTd calculates the TD of the given header using PCRC and CalcHLCRNetDifficulty.
 func (hc *HeaderChain) CalcTd(header *types.Header) ([]*big.Int, error) {
+	return hc.calcTd(hc, header)
+}
+
+// calcTd calculates the TD of the given header, looking up its ancestors in chain.
+func (hc *HeaderChain) calcTd(chain tdChainReader, header *types.Header) ([]*big.Int, error) {
 	// Check PCRC for the external block and return the terminal hash and net difficulties
-	externTerminalHeader, err := hc.Engine().PreviousCoincidentOnPath(hc, header, header.Location, params.PRIME, params.ZONE, true)
+	externTerminalHeader, err := hc.Engine().PreviousCoincidentOnPath(chain, header, header.Location, params.PRIME, params.ZONE, true)
 	if err != nil {
 		return nil, err
 	}
 
 	// Use HLCR to compute net total difficulty
-	externNd, err := hc.CalcHLCRNetDifficulty(externTerminalHeader.Hash(), header)
+	externNd, err := hc.calcHLCRNetDifficulty(chain, externTerminalHeader.Hash(), header)
 	if err != nil {
 		return nil, err
 	}
 
-	externTd, err := hc.NdToTd(externTerminalHeader, externNd)
+	externTd, err := hc.ndToTd(chain, externTerminalHeader, externNd)
 	if err != nil {
 		return nil, err
 	}
@@ -737,6 +778,27 @@ func (hc *HeaderChain) CalcTd(header *types.Header) ([]*big.Int, error) {
 
 // NdToTd returns the total difficulty for a header given the net difficulty
 func (hc *HeaderChain) NdToTd(header *types.Header, nD []*big.Int) ([]*big.Int, error) {
+	return hc.ndToTd(hc, header, nD)
+}
+
+// ndToTd returns the total difficulty for a header given the net difficulty,
+// looking up its ancestors in chain.
+func (hc *HeaderChain) ndToTd(chain tdChainReader, header *types.Header, nD []*big.Int) ([]*big.Int, error) {
+	k, err := hc.tdOffset(chain, header)
+	if err != nil {
+		return nil, err
+	}
+	// adding the common total difficulty to the net
+	nD[0].Add(nD[0], k)
+	nD[1].Add(nD[1], k)
+	nD[2].Add(nD[2], k)
+
+	return nD, nil
+}
+
+// tdOffset returns the common total difficulty added to the net difficulties
+// measured from the given prime terminus.
+func (hc *HeaderChain) tdOffset(chain tdChainReader, header *types.Header) (*big.Int, error) {
 	if header == nil {
 		return nil, errors.New("header provided to ndtotd is nil")
 	}
@@ -747,10 +809,10 @@ func (hc *HeaderChain) NdToTd(header *types.Header, nD []*big.Int) ([]*big.Int,
 	var prevExternTerminus *types.Header
 	var err error
 	for {
-		if hc.GetBlockNumber(header.Hash()) != nil {
+		if chain.GetBlockNumber(header.Hash()) != nil {
 			break
 		}
-		prevExternTerminus, err = hc.Engine().PreviousCoincidentOnPath(hc, header, header.Location, params.PRIME, params.PRIME, true)
+		prevExternTerminus, err = hc.Engine().PreviousCoincidentOnPath(chain, header, header.Location, params.PRIME, params.PRIME, true)
 		if err != nil {
 			return nil, err
 		}
@@ -764,10 +826,10 @@ func (hc *HeaderChain) NdToTd(header *types.Header, nD []*big.Int) ([]*big.Int,
 			break
 		}
 		// Get previous header on local chain by hash
-		prevHeader := hc.GetHeaderByHash(header.ParentHash[params.PRIME])
+		prevHeader := chain.GetHeaderByHash(header.ParentHash[params.PRIME])
 		if prevHeader == nil {
 			// Get previous header on external chain by hash
-			prevExtBlock, err := hc.GetExternalBlock(header.ParentHash[params.PRIME], header.Location, uint64(params.PRIME))
+			prevExtBlock, err := chain.GetExternalBlock(header.ParentHash[params.PRIME], header.Location, uint64(params.PRIME))
 			if err != nil {
 				return nil, err
 			}
@@ -779,14 +841,9 @@ func (hc *HeaderChain) NdToTd(header *types.Header, nD []*big.Int) ([]*big.Int,
 	// subtract the terminal block difficulty
 	k.Sub(k, header.Difficulty[0])
 
-	k.Add(k, hc.GetTdByHash(header.Hash())[params.PRIME])
-
-	// adding the common total difficulty to the net
-	nD[0].Add(nD[0], k)
-	nD[1].Add(nD[1], k)
-	nD[2].Add(nD[2], k)
+	k.Add(k, chain.GetTdByHash(header.Hash())[params.PRIME])
 
-	return nD, nil
+	return k, nil
 }
 
 // The purpose of the Previous Coincident Reference Check (PCRC) is to establish
@@ -794,7 +851,12 @@ func (hc *HeaderChain) NdToTd(header *types.Header, nD []*big.Int) ([]*big.Int,
 // NOTE: note that it only guarantees linked & untwisted back to the prime terminus, assuming the
 // prime termini match. To check deeper than that, you need to iteratively apply PCRC to get that guarantee.
 func (hc *HeaderChain) PCRC(header *types.Header) (common.Hash, error) {
+	return hc.pcrc(hc, header)
+}
 
+// pcrc runs the Previous Coincident Reference Check, looking up the ancestors
+// of the header in chain.
+func (hc *HeaderChain) pcrc(chain consensus.ChainHeaderReader, header *types.Header) (common.Hash, error) {
 	if header.Number[types.QuaiNetworkContext].Cmp(big.NewInt(0)) == 0 {
 		return hc.config.GenesisHashes[0], nil
 	}
@@ -804,41 +866,41 @@ func (hc *HeaderChain) PCRC(header *types.Header) (common.Hash, error) {
 	// Region twist check
 	// RTZ -- Region coincident along zone path
 	// RTR -- Region coincident along region path
-	RTZ, err := hc.Engine().PreviousCoincidentOnPath(hc, header, slice, params.REGION, params.ZONE, true)
+	RTZ, err := hc.Engine().PreviousCoincidentOnPath(chain, header, slice, params.REGION, params.ZONE, true)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	RTR, err := hc.Engine().PreviousCoincidentOnPath(hc, header, slice, params.REGION, params.REGION, true)
+	RTR, err := hc.Engine().PreviousCoincidentOnPath(chain, header, slice, params.REGION, params.REGION, true)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
 	if RTZ.Hash() != RTR.Hash() {
-		return common.Hash{}, errors.New("there exists a region twist")
+		return common.Hash{}, errRegionTwist
 	}
 
 	// Prime twist check
 	// PTZ -- Prime coincident along zone path
 	// PTR -- Prime coincident along region path
 	// PTP -- Prime coincident along prime path
-	PTZ, err := hc.Engine().PreviousCoincidentOnPath(hc, header, slice, params.PRIME, params.ZONE, true)
+	PTZ, err := hc.Engine().PreviousCoincidentOnPath(chain, header, slice, params.PRIME, params.ZONE, true)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	PTR, err := hc.Engine().PreviousCoincidentOnPath(hc, header, slice, params.PRIME, params.REGION, true)
+	PTR, err := hc.Engine().PreviousCoincidentOnPath(chain, header, slice, params.PRIME, params.REGION, true)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
-	PTP, err := hc.Engine().PreviousCoincidentOnPath(hc, header, slice, params.PRIME, params.PRIME, true)
+	PTP, err := hc.Engine().PreviousCoincidentOnPath(chain, header, slice, params.PRIME, params.PRIME, true)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
 	if PTZ.Hash() != PTR.Hash() || PTR.Hash() != PTP.Hash() || PTP.Hash() != PTZ.Hash() {
-		return common.Hash{}, errors.New("there exists a prime twist")
+		return common.Hash{}, errPrimeTwist
 	}
 
 	return PTP.Hash(), nil
@@ -848,6 +910,12 @@ func (hc *HeaderChain) PCRC(header *types.Header) (common.Hash, error) {
 // The netDifficulties parameter inputs the nets of instantaneous difficulties from the terminus block.
 // By correctly summing the net difficulties we have obtained the proper array to be compared in HLCR.
 func (hc *HeaderChain) CalcHLCRNetDifficulty(terminalHash common.Hash, header *types.Header) ([]*big.Int, error) {
+	return hc.calcHLCRNetDifficulty(hc, terminalHash, header)
+}
+
+// calcHLCRNetDifficulty calculates the net difficulty from previous prime,
+// looking up the ancestors of the header in chain.
+func (hc *HeaderChain) calcHLCRNetDifficulty(chain tdChainReader, terminalHash common.Hash, header *types.Header) ([]*big.Int, error) {
 
 	if (terminalHash == common.Hash{}) {
 		return nil, errors.New("one or many of the  terminal hashes were nil")
@@ -878,10 +946,10 @@ func (hc *HeaderChain) CalcHLCRNetDifficulty(terminalHash common.Hash, header *t
 		}
 
 		// Get previous header on local chain by hash
-		prevHeader := hc.GetHeaderByHash(header.ParentHash[order])
+		prevHeader := chain.GetHeaderByHash(header.ParentHash[order])
 		if prevHeader == nil {
 			// Get previous header on external chain by hash
-			prevExtBlock, err := hc.GetExternalBlock(header.ParentHash[order], header.Location, uint64(order))
+			prevExtBlock, err := chain.GetExternalBlock(header.ParentHash[order], header.Location, uint64(order))
 			if err != nil {
 				return nil, err
 			}
@@ -894,6 +962,58 @@ func (hc *HeaderChain) CalcHLCRNetDifficulty(terminalHash common.Hash, header *t
 	return []*big.Int{primeNd, regionNd, zoneNd}, nil
 }
 
+// recordTwist records the header in the twist index if the error rejects it
+// for twisting the chains of the dominant contexts.
+func (hc *HeaderChain) recordTwist(header *types.Header, order int, stage string, err error) {
+	if !errors.Is(err, consensus.ErrTwist) {
+		return
+	}
+	var reason string
+	switch {
+	case errors.Is(err, errRegionTwist):
+		reason = twistindex.ReasonRegion
+	case errors.Is(err, errPrimeTwist):
+		reason = twistindex.ReasonPrime
+	}
+	if hc.twists.Add(header, order, stage, reason, err) {
+		log.Debug("Recorded twisted block", "number", header.Number, "hash", header.Hash(), "order", order, "stage", stage, "err", err)
+	}
+}
+
+// Twists returns the index of the subordinate blocks rejected for twisting the
+// chains of the dominant contexts.
+func (hc *HeaderChain) Twists() *twistindex.Index {
+	return hc.twists
+}
+
+// cachedPCRC returns the cached PCRC termini of the key, if any.
+func (hc *HeaderChain) cachedPCRC(key pcrcKey) (types.PCRCTermini, bool) {
+	if termini, ok := hc.pcrcCache.Get(key); ok {
+		pcrcCacheHitMeter.Mark(1)
+		return termini.(types.PCRCTermini), true
+	}
+	pcrcCacheMissMeter.Mark(1)
+	return types.PCRCTermini{}, false
+}
+
+// cachePCRC caches the PCRC termini of the key.
+func (hc *HeaderChain) cachePCRC(key pcrcKey, termini types.PCRCTermini) {
+	hc.pcrcCache.Add(key, termini)
+}
+
+// purgePCRC drops the cached PCRC termini, which may change with the canonical
+// status of the coincident blocks they lead to.
+func (hc *HeaderChain) purgePCRC() {
+	hc.pcrcCache.Purge()
+}
+
+// SetPCRCCacheLimit resizes the PCRC termini cache to the number of entries.
+func (hc *HeaderChain) SetPCRCCacheLimit(limit int) {
+	if limit > 0 {
+		hc.pcrcCache.Resize(limit)
+	}
+}
+
 // SetGenesis sets a new genesis block header for the chain
 func (hc *HeaderChain) SetGenesis(head *types.Header) {
 	hc.genesisHeader = head
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/twistindex"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+// orderVerifyWindow is the number of headers ahead of the first unverified one
+// the order checks may run, bounding the memory held by finished checks.
+const orderVerifyWindow = 256
+
+var (
+	orderVerifyTimer   = metrics.NewRegisteredTimer("chain/headers/orders", nil)
+	orderDeferredMeter = metrics.NewRegisteredMeter("chain/headers/orders/deferred", nil)
+)
+
+// headerBatchReader overlays a contiguous range of headers being verified on
+// the header chain, so checks walking back from a header see its ancestors in
+// the range. External blocks are only read from the database. It's read-only
+// once created, safe for concurrent use.
+type headerBatchReader struct {
+	*HeaderChain
+	headers map[common.Hash]*types.Header
+}
+
+func newHeaderBatchReader(hc *HeaderChain, headers []*types.Header) *headerBatchReader {
+	r := &headerBatchReader{
+		HeaderChain: hc,
+		headers:     make(map[common.Hash]*types.Header, len(headers)),
+	}
+	for _, header := range headers {
+		r.headers[header.Hash()] = header
+	}
+	return r
+}
+
+func (r *headerBatchReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header, ok := r.headers[hash]; ok {
+		return header
+	}
+	return r.HeaderChain.GetHeader(hash, number)
+}
+
+func (r *headerBatchReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	if header, ok := r.headers[hash]; ok {
+		return header
+	}
+	return r.HeaderChain.GetHeaderByHash(hash)
+}
+
+func (r *headerBatchReader) GetExternalBlock(hash common.Hash, location []byte, context uint64) (*types.ExternalBlock, error) {
+	return rawdb.ReadExternalBlock(r.chainDb, hash, context), nil
+}
+
+// verifyOrders runs the order checks of a contiguous range of headers on a
+// pool of workers, in parallel to the seal verification of the engine. Every
+// header must satisfy the difficulty of some context, and coincident ones must
+// not twist the chains of the dominant contexts. Twist checks lacking dominant
+// data are deferred to block import, where PCRC is enforced.
+//
+// The difficulty order is only trusted for headers whose seals are verified,
+// the others are left to block import as well, like the seals skipped below a
+// checkpoint anchor.
+//
+// The results are delivered in order, no check running more than
+// orderVerifyWindow headers ahead of the first undelivered result.
+func (hc *HeaderChain) verifyOrders(headers []*types.Header, seals []bool, workers int) (chan<- struct{}, <-chan error) {
+	var (
+		abort   = make(chan struct{})
+		results = make(chan error, len(headers))
+	)
+	if len(headers) == 0 {
+		return abort, results
+	}
+	if len(headers) < workers {
+		workers = len(headers)
+	}
+	var (
+		reader = newHeaderBatchReader(hc, headers)
+		inputs = make(chan int)
+		done   = make(chan int, workers)
+		errs   = make([]error, len(headers))
+	)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for index := range inputs {
+				errs[index] = hc.verifyOrder(reader, headers[index])
+				done <- index
+			}
+		}()
+	}
+	go func() {
+		defer close(inputs)
+
+		var (
+			in, out = 0, 0
+			checked = make([]bool, len(headers))
+		)
+		for {
+			// Pass over the headers with unverified seals, delivering all
+			// results available
+			for ; in < len(headers) && in < out+orderVerifyWindow && !seals[in]; in++ {
+				checked[in] = true
+			}
+			for ; out < len(headers) && checked[out]; out++ {
+				results <- errs[out]
+				errs[out] = nil
+			}
+			if out == len(headers) {
+				return
+			}
+			// Only hand out headers within the window of the first undelivered
+			feed := inputs
+			if in == len(headers) || in >= out+orderVerifyWindow {
+				feed = nil
+			} else if !seals[in] {
+				continue
+			}
+			select {
+			case feed <- in:
+				in++
+			case index := <-done:
+				checked[index] = true
+			case <-abort:
+				return
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyOrder checks the order of a single header.
+func (hc *HeaderChain) verifyOrder(reader *headerBatchReader, header *types.Header) error {
+	defer func(start time.Time) { orderVerifyTimer.UpdateSince(start) }(time.Now())
+
+	order, err := hc.engine.GetDifficultyOrder(header)
+	if err != nil {
+		return err
+	}
+	if order < 0 || order > types.QuaiNetworkContext {
+		return fmt.Errorf("invalid order %d for context %d", order, types.QuaiNetworkContext)
+	}
+	if order == types.QuaiNetworkContext {
+		return nil
+	}
+	if _, err := hc.pcrc(reader, header); err != nil {
+		if errors.Is(err, consensus.ErrTwist) {
+			hc.recordTwist(header, order, twistindex.StageVerify, err)
+			return err
+		}
+		orderDeferredMeter.Mark(1)
+		log.Trace("Deferred twist check of coincident header", "number", header.Number, "hash", header.Hash(), "err", err)
+	}
+	return nil
+}
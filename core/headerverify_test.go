@@ -0,0 +1,122 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus/blake3"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// orderEngine is a fake proof of work placing every header in the context of
+// the node, failing the ones in fail. The seal is still hashed, so the checks
+// cost what they do with a real engine.
+type orderEngine struct {
+	*blake3.Blake3
+	fail  map[common.Hash]bool
+	calls int32
+}
+
+func (e *orderEngine) GetDifficultyOrder(header *types.Header) (int, error) {
+	atomic.AddInt32(&e.calls, 1)
+	e.Blake3.GetDifficultyOrder(header)
+	if e.fail[header.Hash()] {
+		return -1, errors.New("block does not satisfy minimum difficulty")
+	}
+	return types.QuaiNetworkContext, nil
+}
+
+// newOrderTestChain creates a header chain verifying orders with engine and n
+// headers to verify on top of its genesis.
+func newOrderTestChain(t testing.TB, engine *orderEngine, n int) (*HeaderChain, []*types.Header) {
+	db := rawdb.NewMemoryDatabase()
+	genesis := newNetworkGenesis(0, 0).MustCommit(db)
+
+	hc, err := NewHeaderChain(db, params.TestChainConfig, engine, func() bool { return false })
+	if err != nil {
+		t.Fatalf("failed to create header chain: %v", err)
+	}
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, blake3.NewFaker(), db, n, nil)
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	return hc, headers
+}
+
+// Tests that the order checks only run for the headers whose seals are
+// verified, and that their results are delivered in order.
+func TestVerifyOrders(t *testing.T) {
+	engine := &orderEngine{Blake3: blake3.NewFaker(), fail: make(map[common.Hash]bool)}
+	hc, headers := newOrderTestChain(t, engine, 2*orderVerifyWindow)
+
+	seals := make([]bool, len(headers))
+	for i := 0; i < len(seals); i += 3 {
+		seals[i] = true
+	}
+	// Only the sealed header failing its order is reported
+	engine.fail[headers[300].Hash()] = true
+	engine.fail[headers[301].Hash()] = true
+	engine.fail[headers[len(headers)-1].Hash()] = true
+
+	abort, results := hc.verifyOrders(headers, seals, 4)
+	defer close(abort)
+
+	for i := range headers {
+		err := <-results
+		if want := seals[i] && engine.fail[headers[i].Hash()]; (err != nil) != want {
+			t.Fatalf("header %d: error mismatch: have %v, want error %v", i, err, want)
+		}
+	}
+	if have, want := int(atomic.LoadInt32(&engine.calls)), (len(headers)+2)/3; have != want {
+		t.Errorf("order check count mismatch: have %d, want %d", have, want)
+	}
+}
+
+// Tests that no order checks run when no seals are verified, as for the headers
+// below a checkpoint anchor.
+func TestVerifyOrdersUnsealed(t *testing.T) {
+	engine := &orderEngine{Blake3: blake3.NewFaker()}
+	hc, headers := newOrderTestChain(t, engine, 16)
+
+	abort, results := hc.verifyOrders(headers, make([]bool, len(headers)), 4)
+	defer close(abort)
+
+	for i := range headers {
+		if err := <-results; err != nil {
+			t.Fatalf("header %d: unexpected error: %v", i, err)
+		}
+	}
+	if calls := atomic.LoadInt32(&engine.calls); calls != 0 {
+		t.Errorf("order checks run for unsealed headers: %d", calls)
+	}
+}
+
+// BenchmarkVerifyOrders compares the latency of verifying the orders of a
+// batch of fully sealed headers on pools of increasing size. The gain is bound
+// by GOMAXPROCS.
+func BenchmarkVerifyOrders(b *testing.B) {
+	engine := &orderEngine{Blake3: blake3.NewFaker()}
+	hc, headers := newOrderTestChain(b, engine, 2048)
+
+	seals := make([]bool, len(headers))
+	for i := range seals {
+		seals[i] = true
+	}
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				abort, results := hc.verifyOrders(headers, seals, workers)
+				for range headers {
+					<-results
+				}
+				close(abort)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+)
+
+// txIndexer is the part of the state database revealing the index of the
+// transaction being executed.
+type txIndexer interface {
+	TxIndex() int
+}
+
+// internalTxFrame is a call frame entered while executing a transaction along
+// with the value transfers made in it so far, kept until the frame returns.
+type internalTxFrame struct {
+	transfers []*types.InternalTransaction
+}
+
+// internalTxTracer is a vm.CallTracer collecting the value transfers of the
+// internal calls of a block's transactions. Transfers are attached to their
+// frame and only kept once every frame up to the transaction returned without
+// reverting.
+type internalTxTracer struct {
+	txIndex int
+	frames  []*internalTxFrame
+	itxs    []*types.InternalTransaction
+}
+
+// newInternalTxTracer creates a tracer collecting the internal transactions of
+// a block.
+func newInternalTxTracer() *internalTxTracer {
+	return new(internalTxTracer)
+}
+
+// CaptureEnter implements vm.CallTracer.
+func (t *internalTxTracer) CaptureEnter(env *vm.EVM, typ vm.OpCode, from common.Address, to common.Address, value *big.Int) {
+	depth := len(t.frames)
+	if depth == 0 {
+		// The transaction itself is entered, record which one the transfers belong to
+		if indexer, ok := env.StateDB.(txIndexer); ok {
+			t.txIndex = indexer.TxIndex()
+		}
+	}
+	frame := new(internalTxFrame)
+	t.frames = append(t.frames, frame)
+
+	if depth == 0 || value == nil || value.Sign() == 0 {
+		return
+	}
+	var kind string
+	switch typ {
+	case vm.CALL:
+		kind = types.InternalCall
+	case vm.CREATE:
+		kind = types.InternalCreate
+	case vm.CREATE2:
+		kind = types.InternalCreate2
+	case vm.SELFDESTRUCT:
+		kind = types.InternalSelfDestruct
+	default:
+		return // CALLCODE keeps the value with the caller
+	}
+	frame.transfers = append(frame.transfers, &types.InternalTransaction{
+		TxIndex: uint(t.txIndex),
+		Kind:    kind,
+		From:    from,
+		To:      to,
+		Value:   new(big.Int).Set(value),
+		Depth:   uint(depth),
+	})
+}
+
+// CaptureExit implements vm.CallTracer.
+func (t *internalTxTracer) CaptureExit(err error) {
+	if len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+	if err != nil {
+		return // reverted, along with every transfer made in the frame
+	}
+	if len(t.frames) == 0 {
+		t.itxs = append(t.itxs, frame.transfers...)
+		return
+	}
+	parent := t.frames[len(t.frames)-1]
+	parent.transfers = append(parent.transfers, frame.transfers...)
+}
+
+// finalise returns the collected internal transactions, filling in the hashes
+// of the transactions they were made by.
+func (t *internalTxTracer) finalise(receipts types.Receipts) []*types.InternalTransaction {
+	itxs := make([]*types.InternalTransaction, 0, len(t.itxs))
+	for _, itx := range t.itxs {
+		if int(itx.TxIndex) < len(receipts) {
+			itx.TxHash = receipts[itx.TxIndex].TxHash
+			itxs = append(itxs, itx)
+		}
+	}
+	return itxs
+}
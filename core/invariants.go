@@ -0,0 +1,248 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+// invariantCheckBatch is the maximum number of blocks verified by a run of the
+// background invariant checker, which catches up with the head over the next
+// runs.
+const invariantCheckBatch = 1024
+
+var invariantViolationMeter = metrics.NewRegisteredMeter("chain/invariants/violations", nil)
+
+// Names of the database invariants verified by CheckInvariants.
+const (
+	InvariantCanonicalContinuity = "canonical-continuity"
+	InvariantTdMonotonicity      = "td-monotonicity"
+	InvariantReceipts            = "receipts"
+	InvariantTxIndex             = "tx-index"
+	InvariantExternalBlocks      = "external-blocks"
+	InvariantEtxAccounting       = "etx-accounting"
+)
+
+// InvariantViolation describes a broken database invariant at a canonical block.
+type InvariantViolation struct {
+	Invariant string      `json:"invariant"`
+	Number    uint64      `json:"number"`
+	Hash      common.Hash `json:"hash"`
+	Reason    string      `json:"reason"`
+}
+
+// CheckInvariants walks the canonical chain from the block numbered from up to
+// and including the block numbered to and verifies that:
+//   - every block links to its canonical parent,
+//   - the total difficulty tuple strictly increases under HLCR,
+//   - every block has one receipt per transaction,
+//   - the transaction lookup entries point at the including block,
+//   - the external blocks referenced by coincident blocks are available,
+//     either settled locally or retrievable from the dom or sub chains,
+//   - every external transaction emitted to this chain by those blocks is
+//     settled by the block or pending in the etx pool, and every external
+//     transaction settled by the block was emitted by one of them.
+//
+// The check is meant for long running soak tests and returns every violation
+// found rather than stopping at the first one.
+func (bc *BlockChain) CheckInvariants(from, to uint64) []InvariantViolation {
+	var (
+		violations []InvariantViolation
+		parent     *types.Block
+		parentTd   []*big.Int
+		indexTail  = rawdb.ReadTxIndexTail(bc.db)
+	)
+	report := func(invariant string, block *types.Block, number uint64, format string, args ...interface{}) {
+		violation := InvariantViolation{Invariant: invariant, Number: number, Reason: fmt.Sprintf(format, args...)}
+		if block != nil {
+			violation.Hash = block.Hash()
+		}
+		violations = append(violations, violation)
+	}
+	if from > 0 {
+		parent = bc.GetBlockByNumber(from - 1)
+		if parent != nil {
+			parentTd = bc.GetTd(parent.Hash(), parent.NumberU64())
+		}
+	}
+	for number := from; number <= to; number++ {
+		block := bc.GetBlockByNumber(number)
+		if block == nil {
+			report(InvariantCanonicalContinuity, nil, number, "canonical block missing")
+			parent, parentTd = nil, nil
+			continue
+		}
+		// Canonical continuity
+		if parent != nil && block.ParentHash() != parent.Hash() {
+			report(InvariantCanonicalContinuity, block, number, "parent hash %x does not match canonical parent %x", block.ParentHash(), parent.Hash())
+		}
+		// TD monotonicity, the child must be heavier than its parent under HLCR
+		td := bc.GetTd(block.Hash(), number)
+		if !completeTd(td) {
+			report(InvariantTdMonotonicity, block, number, "total difficulty missing")
+		} else if parent != nil && completeTd(parentTd) && !bc.HLCR(parentTd, td) {
+			report(InvariantTdMonotonicity, block, number, "total difficulty %v not heavier than parent %v", td, parentTd)
+		}
+		// Receipt consistency
+		if number > 0 {
+			receipts := bc.GetReceiptsByHash(block.Hash())
+			if len(receipts) != len(block.Transactions()) {
+				report(InvariantReceipts, block, number, "have %d receipts for %d transactions", len(receipts), len(block.Transactions()))
+			}
+		}
+		// Transaction index consistency, only within the indexed range
+		if indexTail != nil && number >= *indexTail {
+			for _, tx := range block.Transactions() {
+				entry := rawdb.ReadTxLookupEntry(bc.db, tx.Hash())
+				if entry == nil {
+					report(InvariantTxIndex, block, number, "transaction %x not indexed", tx.Hash())
+				} else if *entry != number {
+					report(InvariantTxIndex, block, number, "transaction %x indexed at block %d", tx.Hash(), *entry)
+				}
+			}
+		}
+		// External blocks applied by the block must be available and their
+		// transactions to this chain settled or pending, traced as the state
+		// processor does
+		if number > 1 {
+			externalBlocks, err := bc.engine.GetExternalBlocks(bc, block.Header(), false)
+			if err != nil {
+				report(InvariantExternalBlocks, block, number, "external blocks unavailable: %v", err)
+			} else {
+				emitted, settled := bc.blockEtxs(block, externalBlocks)
+				unsettled, unemitted := accountEtxs(emitted, settled, bc.isEtxPending)
+				for _, hash := range unsettled {
+					report(InvariantEtxAccounting, block, number, "external transaction %x neither settled nor pending", hash)
+				}
+				for _, hash := range unemitted {
+					report(InvariantEtxAccounting, block, number, "external transaction %x settled without being emitted", hash)
+				}
+			}
+		}
+		parent, parentTd = block, td
+	}
+	return violations
+}
+
+// completeTd reports whether the total difficulty tuple has a value in every
+// context, the header chain returning empty tuples for unknown blocks.
+func completeTd(td []*big.Int) bool {
+	if len(td) != types.ContextDepth {
+		return false
+	}
+	for _, difficulty := range td {
+		if difficulty == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// blockEtxs returns the hashes of the external transactions emitted to this
+// chain by the external blocks applied by the block, selected as the state
+// processor does, and of the external transactions the block settles.
+func (bc *BlockChain) blockEtxs(block *types.Block, externalBlocks []*types.ExternalBlock) (emitted []common.Hash, settled []common.Hash) {
+	var (
+		header = block.Header()
+		signer = types.MakeSigner(bc.chainConfig, header.Number[types.QuaiNetworkContext])
+	)
+	for _, externalBlock := range externalBlocks {
+		for _, tx := range externalBlock.Transactions() {
+			msg, err := tx.AsMessage(signer, header.BaseFee[types.QuaiNetworkContext])
+			if err == nil && IsApplicableEtx(bc.chainConfig, msg, tx) {
+				emitted = append(emitted, tx.Hash())
+			}
+		}
+	}
+	for _, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer, header.BaseFee[types.QuaiNetworkContext])
+		if err == nil && msg.FromExternal() {
+			settled = append(settled, tx.Hash())
+		}
+	}
+	return emitted, settled
+}
+
+// isEtxPending reports whether the external transaction is pending in the etx
+// pool, linked in by a coincident block and awaiting the next block.
+func (bc *BlockChain) isEtxPending(hash common.Hash) bool {
+	entry := bc.etxPool.Get(hash)
+	return entry != nil && entry.Included
+}
+
+// accountEtxs balances the external transactions emitted to a block against
+// the ones it settles, returning the emitted ones neither settled nor pending
+// and the settled ones never emitted.
+func accountEtxs(emitted []common.Hash, settled []common.Hash, pending func(common.Hash) bool) (unsettled []common.Hash, unemitted []common.Hash) {
+	emittedSet := make(map[common.Hash]struct{}, len(emitted))
+	for _, hash := range emitted {
+		emittedSet[hash] = struct{}{}
+	}
+	settledSet := make(map[common.Hash]struct{}, len(settled))
+	for _, hash := range settled {
+		settledSet[hash] = struct{}{}
+		if _, ok := emittedSet[hash]; !ok {
+			unemitted = append(unemitted, hash)
+		}
+	}
+	for _, hash := range emitted {
+		if _, ok := settledSet[hash]; !ok && !pending(hash) {
+			unsettled = append(unsettled, hash)
+		}
+	}
+	return unsettled, unemitted
+}
+
+// checkInvariantsLoop periodically verifies the database invariants of the
+// canonical blocks imported since the last run, starting from the head at the
+// time it's started, and reports the violations found.
+func (bc *BlockChain) checkInvariantsLoop(interval time.Duration) {
+	defer bc.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var (
+		head = bc.CurrentBlock()
+		next = head.NumberU64()  // First canonical block not verified yet
+		last = head.ParentHash() // Last block verified
+	)
+	for {
+		select {
+		case <-ticker.C:
+			// Verify again from the common ancestor if the last verified
+			// blocks were reorged out
+			for next > 0 && bc.GetCanonicalHash(next-1) != last {
+				header := bc.GetHeader(last, next-1)
+				if header == nil {
+					break
+				}
+				last = header.ParentHash[types.QuaiNetworkContext]
+				next--
+			}
+			head := bc.CurrentBlock().NumberU64()
+			if next > head {
+				continue
+			}
+			to := head
+			if to-next >= invariantCheckBatch {
+				to = next + invariantCheckBatch - 1
+			}
+			violations := bc.CheckInvariants(next, to)
+			for _, violation := range violations {
+				log.Error("Database invariant violated", "invariant", violation.Invariant, "number", violation.Number, "hash", violation.Hash, "reason", violation.Reason)
+			}
+			invariantViolationMeter.Mark(int64(len(violations)))
+			next, last = to+1, bc.GetCanonicalHash(to)
+
+		case <-bc.quit:
+			return
+		}
+	}
+}
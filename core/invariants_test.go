@@ -0,0 +1,246 @@
+package core
+
+import (
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+	"testing/quick"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/consensus/blake3"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// invariantEngine is a fake proof of work returning fixed external blocks for
+// the blocks applying them, standing in for the blocks traced from the
+// dominant chains.
+type invariantEngine struct {
+	*blake3.Blake3
+	external map[common.Hash][]*types.ExternalBlock
+}
+
+func (e *invariantEngine) GetExternalBlocks(chain consensus.ChainHeaderReader, header *types.Header, logging bool) ([]*types.ExternalBlock, error) {
+	return e.external[header.Hash()], nil
+}
+
+// newInvariantTestChain creates a chain of zone 1-2 with n blocks transferring
+// value in every block, written to the database the way the import does.
+func newInvariantTestChain(t *testing.T, n int) (*BlockChain, *invariantEngine, ethdb.Database, []*types.Block) {
+	config := NetworkZoneConfig(params.TestChainConfig, 1, 2)
+	config.Context = types.QuaiNetworkContext
+
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		key, addr = NetworkETXSender(1, 2)
+		gspec     = newNetworkGenesis(1, 2)
+		engine    = &invariantEngine{Blake3: blake3.NewFaker(), external: make(map[common.Hash][]*types.ExternalBlock)}
+	)
+	gspec.Config = config
+	genesis := gspec.MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, config, "", nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	signer := types.LatestSigner(config)
+	blocks, receipts := GenerateChain(config, genesis, blake3.NewFaker(), db, n, func(i int, gen *BlockGen) {
+		tx, err := types.SignNewTx(key, signer, &types.AccessListTx{
+			ChainID:  config.ChainID,
+			Nonce:    gen.TxNonce(addr),
+			To:       &addr,
+			Value:    big.NewInt(1),
+			Gas:      params.TxGas,
+			GasPrice: gen.header.BaseFee[types.QuaiNetworkContext],
+		})
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		gen.AddTx(tx)
+	})
+	td := chain.GetTd(genesis.Hash(), 0)
+	for i, block := range blocks {
+		next := make([]*big.Int, len(td))
+		for ctx := range td {
+			next[ctx] = new(big.Int).Add(td[ctx], block.Header().Difficulty[ctx])
+		}
+		td = next
+
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+		rawdb.WriteTd(db, block.Hash(), block.NumberU64(), td)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteTxLookupEntriesByBlock(db, block)
+	}
+	rawdb.WriteTxIndexTail(db, 0)
+
+	return chain, engine, db, append([]*types.Block{genesis}, blocks...)
+}
+
+// Tests that an intact chain satisfies every invariant.
+func TestCheckInvariants(t *testing.T) {
+	chain, _, _, blocks := newInvariantTestChain(t, 8)
+	defer chain.Stop()
+
+	if violations := chain.CheckInvariants(0, uint64(len(blocks)-1)); len(violations) != 0 {
+		t.Fatalf("violations in intact chain: %v", violations)
+	}
+}
+
+// Tests that a single corruption of the database anywhere in the chain is
+// reported by the invariant it breaks, at the corrupted block only.
+func TestCheckInvariantsCorruption(t *testing.T) {
+	corruptions := []struct {
+		invariant string
+		corrupt   func(db ethdb.Database, block *types.Block, parentTd []*big.Int)
+	}{
+		{InvariantCanonicalContinuity, func(db ethdb.Database, block *types.Block, parentTd []*big.Int) {
+			rawdb.DeleteCanonicalHash(db, block.NumberU64())
+		}},
+		{InvariantTdMonotonicity, func(db ethdb.Database, block *types.Block, parentTd []*big.Int) {
+			rawdb.WriteTd(db, block.Hash(), block.NumberU64(), parentTd)
+		}},
+		{InvariantTdMonotonicity, func(db ethdb.Database, block *types.Block, parentTd []*big.Int) {
+			rawdb.DeleteTd(db, block.Hash(), block.NumberU64())
+		}},
+		{InvariantReceipts, func(db ethdb.Database, block *types.Block, parentTd []*big.Int) {
+			rawdb.DeleteReceipts(db, block.Hash(), block.NumberU64())
+		}},
+		{InvariantTxIndex, func(db ethdb.Database, block *types.Block, parentTd []*big.Int) {
+			rawdb.DeleteTxLookupEntry(db, block.Transactions()[0].Hash())
+		}},
+	}
+	const blocks = 8
+
+	property := func(kind uint8, at uint8) bool {
+		var (
+			corruption = corruptions[int(kind)%len(corruptions)]
+			number     = 1 + uint64(at)%blocks
+		)
+		chain, _, db, chainBlocks := newInvariantTestChain(t, blocks)
+		defer chain.Stop()
+
+		parent := chainBlocks[number-1]
+		corruption.corrupt(db, chainBlocks[number], rawdb.ReadTd(db, parent.Hash(), parent.NumberU64()))
+
+		violations := chain.CheckInvariants(0, blocks)
+		if len(violations) != 1 {
+			t.Logf("corrupted %s at block %d: have violations %v", corruption.invariant, number, violations)
+			return false
+		}
+		if violations[0].Invariant != corruption.invariant || violations[0].Number != number {
+			t.Logf("corrupted %s at block %d: have violation %+v", corruption.invariant, number, violations[0])
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 25}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Tests that the external transactions emitted to the chain are reported
+// unless settled by the block applying them or pending in the etx pool.
+func TestCheckInvariantsEtxAccounting(t *testing.T) {
+	chain, engine, _, blocks := newInvariantTestChain(t, 4)
+	defer chain.Stop()
+
+	// Emit ETXs from zone 1-1 to zone 1-2 applied by the third block, which
+	// doesn't settle them
+	var (
+		srcDB    = rawdb.NewMemoryDatabase()
+		gspec    = newNetworkGenesis(1, 2)
+		genesis  = gspec.MustCommit(srcDB)
+		settling = blocks[3]
+	)
+	network := GenerateNetwork(params.TestChainConfig, genesis, blake3.NewFaker(), srcDB, NetworkGenConfig{
+		Regions:  1,
+		Zones:    2,
+		Blocks:   1,
+		ETXs:     2,
+		ETXValue: big.NewInt(1000),
+	})
+	source := network.Zone(1, 1)[0]
+	external := types.NewExternalBlockWithHeader(source.Header()).WithBody(source.Transactions(), source.Uncles(), network.Receipts["1-1"][0], big.NewInt(int64(params.ZONE)))
+	engine.external[settling.Hash()] = []*types.ExternalBlock{external}
+
+	violations := chain.CheckInvariants(0, uint64(len(blocks)-1))
+	if len(violations) != len(source.Transactions()) {
+		t.Fatalf("violation count mismatch: have %d, want %d: %v", len(violations), len(source.Transactions()), violations)
+	}
+	for _, violation := range violations {
+		if violation.Invariant != InvariantEtxAccounting || violation.Hash != settling.Hash() {
+			t.Fatalf("unexpected violation: %+v", violation)
+		}
+	}
+	// Pending ETXs are accounted for
+	if err := chain.EtxPool().AddBlock(external, true); err != nil {
+		t.Fatalf("failed to add external block to etx pool: %v", err)
+	}
+	if violations := chain.CheckInvariants(0, uint64(len(blocks)-1)); len(violations) != 0 {
+		t.Fatalf("violations with pending etxs: %v", violations)
+	}
+}
+
+// Tests that the external transactions emitted to a block balance with the
+// ones it settles and the pending ones, against a model of the accounting.
+func TestAccountEtxs(t *testing.T) {
+	// Every hash is emitted, settled and pending according to its bits
+	property := func(seed int64, n uint8) bool {
+		var (
+			rng                      = rand.New(rand.NewSource(seed))
+			emitted, settled         []common.Hash
+			pending                  = make(map[common.Hash]bool)
+			wantUnsettled, wantExtra []common.Hash
+		)
+		for i := 0; i < int(n); i++ {
+			var hash common.Hash
+			rng.Read(hash[:])
+
+			isEmitted, isSettled, isPending := rng.Intn(2) == 0, rng.Intn(2) == 0, rng.Intn(2) == 0
+			if isEmitted {
+				emitted = append(emitted, hash)
+			}
+			if isSettled {
+				settled = append(settled, hash)
+			}
+			pending[hash] = isPending
+
+			if isEmitted && !isSettled && !isPending {
+				wantUnsettled = append(wantUnsettled, hash)
+			}
+			if isSettled && !isEmitted {
+				wantExtra = append(wantExtra, hash)
+			}
+		}
+		unsettled, unemitted := accountEtxs(emitted, settled, func(hash common.Hash) bool { return pending[hash] })
+		return sameHashes(unsettled, wantUnsettled) && sameHashes(unemitted, wantExtra)
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sameHashes reports whether the two lists hold the same hashes.
+func sameHashes(a, b []common.Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sorted := func(hashes []common.Hash) []common.Hash {
+		cpy := append([]common.Hash{}, hashes...)
+		sort.Slice(cpy, func(i, j int) bool { return cpy[i].Hex() < cpy[j].Hex() })
+		return cpy
+	}
+	a, b = sorted(a), sorted(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
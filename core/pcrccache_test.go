@@ -0,0 +1,131 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/consensus/blake3"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// terminusEngine resolves every coincident terminus to the genesis header.
+type terminusEngine struct {
+	*blake3.Blake3
+	genesis *types.Header
+}
+
+func (e *terminusEngine) PreviousCoincidentOnPath(chain consensus.ChainHeaderReader, header *types.Header, slice []byte, order, path int, fullSliceEqual bool) (*types.Header, error) {
+	return e.genesis, nil
+}
+
+// newPCRCCacheTestChain creates a chain with no dominant nor subordinate
+// clients, along with its genesis block.
+func newPCRCCacheTestChain(t *testing.T) (*BlockChain, *types.Block) {
+	db := rawdb.NewMemoryDatabase()
+	genesis := newNetworkGenesis(0, 0).MustCommit(db)
+
+	config := *params.TestChainConfig
+	config.GenesisHashes = []common.Hash{genesis.Hash(), genesis.Hash(), genesis.Hash()}
+
+	chain, err := NewBlockChain(db, nil, &config, "", nil, &terminusEngine{Blake3: blake3.NewFaker(), genesis: genesis.Header()}, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	return chain, genesis
+}
+
+// Tests that the termini a zone derives on its own are cached, and that cached
+// termini are served without running the check again.
+func TestPCRCCacheHit(t *testing.T) {
+	chain, _ := newPCRCCacheTestChain(t)
+	defer chain.Stop()
+
+	defer func(context int) { types.QuaiNetworkContext = context }(types.QuaiNetworkContext)
+	types.QuaiNetworkContext = params.ZONE
+
+	header := newFutureTestBlock(5, time.Now(), 1).Header()
+	if _, err := chain.PCRC(header, params.ZONE); err != nil {
+		t.Fatalf("failed to run PCRC: %v", err)
+	}
+	if !chain.hc.pcrcCache.Contains(pcrcKey{hash: header.Hash(), order: params.ZONE}) {
+		t.Errorf("PCRC termini not cached")
+	}
+	if _, err := chain.PCCRC(header, params.ZONE); err != nil {
+		t.Fatalf("failed to run PCCRC: %v", err)
+	}
+	if !chain.hc.pcrcCache.Contains(pcrcKey{hash: header.Hash(), order: params.ZONE, cross: true}) {
+		t.Errorf("PCCRC termini not cached")
+	}
+	// A cached result is returned as is, the check itself would leave it empty
+	cached := types.PCRCTermini{PTZ: common.Hash{0x01}, RTZ: common.Hash{0x02}}
+	chain.hc.cachePCRC(pcrcKey{hash: header.Hash(), order: params.ZONE}, cached)
+
+	termini, err := chain.PCRC(header, params.ZONE)
+	if err != nil {
+		t.Fatalf("failed to run PCRC: %v", err)
+	}
+	if termini != cached {
+		t.Errorf("cached termini mismatch: have %+v, want %+v", termini, cached)
+	}
+}
+
+// Tests that the termini left empty for a missing subordinate client are not
+// cached, so that they are checked again once the subordinate is reachable.
+func TestPCRCCacheNoSubClient(t *testing.T) {
+	chain, _ := newPCRCCacheTestChain(t)
+	defer chain.Stop()
+
+	header := newFutureTestBlock(5, time.Now(), 1).Header()
+	header.Location = []byte{1, 1}
+
+	termini, err := chain.PCRC(header, params.PRIME)
+	if err != nil {
+		t.Fatalf("failed to run PCRC: %v", err)
+	}
+	if termini != (types.PCRCTermini{}) {
+		t.Errorf("termini mismatch: have %+v, want none", termini)
+	}
+	if _, err := chain.PCCRC(header, params.PRIME); err != nil {
+		t.Fatalf("failed to run PCCRC: %v", err)
+	}
+	if n := chain.hc.pcrcCache.Len(); n != 0 {
+		t.Errorf("termini of a missing subordinate cached: %d entries", n)
+	}
+}
+
+// Tests that the cached termini are dropped on reorg and when rewinding the
+// chain.
+func TestPCRCCachePurge(t *testing.T) {
+	chain, genesis := newPCRCCacheTestChain(t)
+	defer chain.Stop()
+
+	var (
+		key      = pcrcKey{hash: common.Hash{0x01}, order: params.ZONE}
+		old, _   = GenerateChain(params.TestChainConfig, genesis, blake3.NewFaker(), chain.db, 2, nil)
+		fresh, _ = GenerateChain(params.TestChainConfig, genesis, blake3.NewFaker(), chain.db, 3, func(i int, b *BlockGen) {
+			b.SetCoinbase(common.Address{0x01})
+		})
+	)
+	for _, block := range append(old, fresh...) {
+		rawdb.WriteBlock(chain.db, block)
+	}
+	chain.hc.cachePCRC(key, types.PCRCTermini{})
+	if err := chain.reorg(old[len(old)-1], fresh[len(fresh)-1]); err != nil {
+		t.Fatalf("failed to reorg: %v", err)
+	}
+	if chain.hc.pcrcCache.Contains(key) {
+		t.Errorf("cached termini kept over a reorg")
+	}
+	chain.hc.cachePCRC(key, types.PCRCTermini{})
+	if err := chain.SetHead(0); err != nil {
+		t.Fatalf("failed to rewind: %v", err)
+	}
+	if chain.hc.pcrcCache.Contains(key) {
+		t.Errorf("cached termini kept over a rewind")
+	}
+}
@@ -0,0 +1,217 @@
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+const (
+	// maxParkedBlocks is the number of blocks parked awaiting PCRC, the oldest
+	// being dropped beyond.
+	maxParkedBlocks = 512
+
+	// parkedBlockTimeout is the time a block stays parked before being dropped.
+	parkedBlockTimeout = 10 * time.Minute
+)
+
+var (
+	parkedBlockGauge     = metrics.NewRegisteredGauge("chain/pcrc/parked", nil)
+	parkedBlockMeter     = metrics.NewRegisteredMeter("chain/pcrc/parks", nil)
+	parkedResumeMeter    = metrics.NewRegisteredMeter("chain/pcrc/resumes", nil)
+	parkedDropMeter      = metrics.NewRegisteredMeter("chain/pcrc/drops", nil)
+	parkedBlockWaitTimer = metrics.NewRegisteredTimer("chain/pcrc/wait", nil)
+)
+
+// parkedBlock is a block awaiting its slice to sync for PCRC to pass.
+type parkedBlock struct {
+	block  *types.Block
+	key    common.Hash // Previous coincident block the slice has not synced to
+	parked time.Time   // Time the block was parked at
+}
+
+// pcrcQueue holds the blocks failing PCRC because the slice is not synced yet,
+// keyed by the previous coincident block of their order. Blocks with the same
+// key are re-evaluated together, in number order, once the dominant head
+// advances.
+type pcrcQueue struct {
+	blocks map[common.Hash][]*parkedBlock // Parked blocks by key, in number order
+	hashes map[common.Hash]struct{}       // Hashes of the parked blocks
+	head   uint64                         // Dominant head at the last re-evaluation
+	lock   sync.Mutex
+}
+
+func newPCRCQueue() *pcrcQueue {
+	return &pcrcQueue{
+		blocks: make(map[common.Hash][]*parkedBlock),
+		hashes: make(map[common.Hash]struct{}),
+	}
+}
+
+// park adds the blocks to the queue under the key, dropping the oldest parked
+// blocks if the queue overflows. Blocks already parked are ignored.
+func (q *pcrcQueue) park(key common.Hash, blocks types.Blocks) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	now := time.Now()
+	for _, block := range blocks {
+		if _, ok := q.hashes[block.Hash()]; ok {
+			continue
+		}
+		q.hashes[block.Hash()] = struct{}{}
+		q.blocks[key] = append(q.blocks[key], &parkedBlock{block: block, key: key, parked: now})
+		parkedBlockMeter.Mark(1)
+	}
+	sort.SliceStable(q.blocks[key], func(i, j int) bool {
+		return q.blocks[key][i].block.NumberU64() < q.blocks[key][j].block.NumberU64()
+	})
+	for len(q.hashes) > maxParkedBlocks {
+		q.dropOldest()
+	}
+	parkedBlockGauge.Update(int64(len(q.hashes)))
+}
+
+// dropOldest drops the block parked for the longest time. The lock must be held.
+func (q *pcrcQueue) dropOldest() {
+	var oldest *parkedBlock
+	for _, parked := range q.blocks {
+		for _, p := range parked {
+			if oldest == nil || p.parked.Before(oldest.parked) {
+				oldest = p
+			}
+		}
+	}
+	if oldest != nil {
+		q.remove(oldest)
+	}
+}
+
+// remove drops the parked block from the queue. The lock must be held.
+func (q *pcrcQueue) remove(p *parkedBlock) {
+	parked := q.blocks[p.key]
+	for i := range parked {
+		if parked[i] == p {
+			parked = append(parked[:i], parked[i+1:]...)
+			break
+		}
+	}
+	if len(parked) == 0 {
+		delete(q.blocks, p.key)
+	} else {
+		q.blocks[p.key] = parked
+	}
+	delete(q.hashes, p.block.Hash())
+	parkedDropMeter.Mark(1)
+	log.Debug("Dropped block parked for PCRC", "number", p.block.Number(), "hash", p.block.Hash(), "waited", common.PrettyDuration(time.Since(p.parked)))
+}
+
+// expire drops the blocks parked for longer than the timeout.
+func (q *pcrcQueue) expire() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for _, parked := range q.blocks {
+		for _, p := range append([]*parkedBlock(nil), parked...) {
+			if time.Since(p.parked) > parkedBlockTimeout {
+				q.remove(p)
+			}
+		}
+	}
+	parkedBlockGauge.Update(int64(len(q.hashes)))
+}
+
+// advance records the dominant head, returning whether it moved since the last
+// call and blocks are parked.
+func (q *pcrcQueue) advance(head uint64) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if head <= q.head {
+		return false
+	}
+	q.head = head
+	return len(q.hashes) > 0
+}
+
+// firsts returns the lowest parked block of every key.
+func (q *pcrcQueue) firsts() map[common.Hash]*types.Block {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	firsts := make(map[common.Hash]*types.Block, len(q.blocks))
+	for key, parked := range q.blocks {
+		firsts[key] = parked[0].block
+	}
+	return firsts
+}
+
+// take removes the blocks parked under the key from the queue, returning them
+// in number order.
+func (q *pcrcQueue) take(key common.Hash) types.Blocks {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	var blocks types.Blocks
+	for _, p := range q.blocks[key] {
+		blocks = append(blocks, p.block)
+		delete(q.hashes, p.block.Hash())
+		parkedBlockWaitTimer.UpdateSince(p.parked)
+	}
+	delete(q.blocks, key)
+	parkedBlockGauge.Update(int64(len(q.hashes)))
+	return blocks
+}
+
+// dominantHead returns the head number of the dominant chain, the one of the
+// local chain in prime.
+func (bc *BlockChain) dominantHead() uint64 {
+	if bc.domClient == nil {
+		return bc.CurrentBlock().NumberU64()
+	}
+	var head uint64
+	for _, status := range bc.domClient.Status() {
+		if status.Healthy && uint64(status.Head) > head {
+			head = uint64(status.Head)
+		}
+	}
+	return head
+}
+
+// procParkedBlocks re-evaluates PCRC for the parked blocks once the dominant
+// head advanced, importing the ones of every key passing it.
+func (bc *BlockChain) procParkedBlocks() {
+	bc.pcrcQueue.expire()
+	if !bc.pcrcQueue.advance(bc.dominantHead()) {
+		return
+	}
+	for key, block := range bc.pcrcQueue.firsts() {
+		order, err := bc.engine.GetDifficultyOrder(block.Header())
+		if err != nil {
+			continue
+		}
+		if _, err := bc.PCRC(block.Header(), order); errors.Is(err, consensus.ErrSliceNotSynced) {
+			continue
+		}
+		blocks := bc.pcrcQueue.take(key)
+		if len(blocks) == 0 {
+			continue
+		}
+		parkedResumeMeter.Mark(int64(len(blocks)))
+		log.Info("Resuming blocks parked for PCRC", "key", key, "count", len(blocks), "number", blocks[0].Number(), "hash", blocks[0].Hash())
+
+		// Insert one by one as blocks parked under the same key may be siblings
+		for i := range blocks {
+			if _, err := bc.InsertChain(blocks[i : i+1]); err != nil {
+				log.Debug("Failed to import block parked for PCRC", "number", blocks[i].Number(), "hash", blocks[i].Hash(), "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus/blake3"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// parkedHashes returns the hashes of the blocks parked under the key, in queue
+// order.
+func parkedHashes(q *pcrcQueue, key common.Hash) []common.Hash {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	var hashes []common.Hash
+	for _, p := range q.blocks[key] {
+		hashes = append(hashes, p.block.Hash())
+	}
+	return hashes
+}
+
+// Tests that parked blocks are kept in number order under their key, once.
+func TestPCRCQueuePark(t *testing.T) {
+	var (
+		queue = newPCRCQueue()
+		key   = common.Hash{0x01}
+		now   = time.Now()
+		b1    = newFutureTestBlock(1, now, 0)
+		b2    = newFutureTestBlock(2, now, 0)
+		b3    = newFutureTestBlock(3, now, 0)
+	)
+	queue.park(key, types.Blocks{b3, b1})
+	queue.park(key, types.Blocks{b2, b1})
+
+	want := []common.Hash{b1.Hash(), b2.Hash(), b3.Hash()}
+	have := parkedHashes(queue, key)
+	if len(have) != len(want) {
+		t.Fatalf("parked count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Fatalf("parked block %d mismatch: have %x, want %x", i, have[i], want[i])
+		}
+	}
+	if first := queue.firsts()[key]; first.Hash() != b1.Hash() {
+		t.Fatalf("first parked block mismatch: have %x, want %x", first.Hash(), b1.Hash())
+	}
+	blocks := queue.take(key)
+	if len(blocks) != 3 || blocks[0].Hash() != b1.Hash() || blocks[2].Hash() != b3.Hash() {
+		t.Fatalf("taken blocks mismatch: %v", blocks)
+	}
+	if len(queue.hashes) != 0 || len(queue.blocks) != 0 {
+		t.Fatalf("blocks left parked after take")
+	}
+}
+
+// Tests that blocks parked for longer than the timeout are dropped.
+func TestPCRCQueueExpire(t *testing.T) {
+	var (
+		queue = newPCRCQueue()
+		now   = time.Now()
+		stale = newFutureTestBlock(1, now, 0)
+		fresh = newFutureTestBlock(2, now, 0)
+	)
+	queue.park(common.Hash{0x01}, types.Blocks{stale})
+	queue.park(common.Hash{0x02}, types.Blocks{fresh})
+	queue.blocks[common.Hash{0x01}][0].parked = now.Add(-parkedBlockTimeout - time.Second)
+
+	queue.expire()
+	if _, ok := queue.hashes[stale.Hash()]; ok {
+		t.Errorf("stale block not expired")
+	}
+	if _, ok := queue.blocks[common.Hash{0x01}]; ok {
+		t.Errorf("key of the stale block left behind")
+	}
+	if _, ok := queue.hashes[fresh.Hash()]; !ok {
+		t.Errorf("fresh block expired")
+	}
+}
+
+// Tests that the block parked for the longest time is dropped once the queue
+// overflows.
+func TestPCRCQueueDropOldest(t *testing.T) {
+	var (
+		queue  = newPCRCQueue()
+		now    = time.Now()
+		oldest = newFutureTestBlock(1, now, 0)
+	)
+	queue.park(common.Hash{0x01}, types.Blocks{oldest})
+	queue.blocks[common.Hash{0x01}][0].parked = now.Add(-time.Minute)
+
+	blocks := make(types.Blocks, maxParkedBlocks)
+	for i := range blocks {
+		blocks[i] = newFutureTestBlock(uint64(i+2), now, 0)
+	}
+	queue.park(common.Hash{0x02}, blocks)
+
+	if len(queue.hashes) != maxParkedBlocks {
+		t.Fatalf("parked count mismatch: have %d, want %d", len(queue.hashes), maxParkedBlocks)
+	}
+	if _, ok := queue.hashes[oldest.Hash()]; ok {
+		t.Fatalf("oldest block not dropped")
+	}
+	if _, ok := queue.blocks[common.Hash{0x01}]; ok {
+		t.Fatalf("key of the oldest block left behind")
+	}
+}
+
+// parkEngine is a fake proof of work failing the difficulty order of the
+// blocks in fail.
+type parkEngine struct {
+	*blake3.Blake3
+	fail map[common.Hash]bool
+}
+
+func (e *parkEngine) GetDifficultyOrder(header *types.Header) (int, error) {
+	if e.fail[header.Hash()] {
+		return -1, errors.New("block does not satisfy minimum difficulty")
+	}
+	return types.QuaiNetworkContext, nil
+}
+
+// Tests that parked blocks are only re-evaluated once the dominant head
+// advances, and that the blocks of the keys passing PCRC are resumed while the
+// ones of keys PCRC can't be evaluated for stay parked.
+func TestPCRCQueueResume(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = newNetworkGenesis(0, 0).MustCommit(db)
+		engine  = &parkEngine{Blake3: blake3.NewFaker(), fail: make(map[common.Hash]bool)}
+	)
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, "", nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var (
+		now     = time.Now()
+		synced  = common.Hash{0x01}
+		pending = common.Hash{0x02}
+		s1      = newFutureTestBlock(5, now, 1)
+		s2      = newFutureTestBlock(6, now, 1)
+		p1      = newFutureTestBlock(5, now, 2)
+	)
+	chain.hc.cachePCRC(pcrcKey{hash: s1.Hash(), order: types.QuaiNetworkContext}, types.PCRCTermini{})
+	engine.fail[p1.Hash()] = true
+
+	chain.pcrcQueue.park(synced, types.Blocks{s1, s2})
+	chain.pcrcQueue.park(pending, types.Blocks{p1})
+
+	// Nothing is re-evaluated before the dominant head advances
+	chain.procParkedBlocks()
+	if len(chain.pcrcQueue.hashes) != 3 {
+		t.Fatalf("parked blocks resumed before the dominant head advanced")
+	}
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, blake3.NewFaker(), db, 1, nil)
+	chain.currentBlock.Store(blocks[0])
+
+	chain.procParkedBlocks()
+	if hashes := parkedHashes(chain.pcrcQueue, synced); len(hashes) != 0 {
+		t.Errorf("blocks passing PCRC left parked: %d", len(hashes))
+	}
+	if hashes := parkedHashes(chain.pcrcQueue, pending); len(hashes) != 1 || hashes[0] != p1.Hash() {
+		t.Errorf("blocks not evaluated resumed: %v", hashes)
+	}
+}
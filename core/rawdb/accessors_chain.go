@@ -247,6 +247,116 @@ func WriteLastPivotNumber(db ethdb.KeyValueWriter, pivot uint64) {
 	}
 }
 
+// ReadFreezerHorizon retrieves the number of the latest canonical block
+// coincident with Prime, nil if none was imported yet.
+func ReadFreezerHorizon(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(freezerHorizonKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteFreezerHorizon stores the number of the latest canonical block coincident
+// with Prime.
+func WriteFreezerHorizon(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(freezerHorizonKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store freezer horizon", "err", err)
+	}
+}
+
+// LowerFreezerHorizon moves the freezer horizon back to the given number if it's
+// above it, the blocks coincident with Prime past it being dropped by a reorg.
+func LowerFreezerHorizon(db ethdb.KeyValueReader, w ethdb.KeyValueWriter, number uint64) {
+	if horizon := ReadFreezerHorizon(db); horizon != nil && *horizon > number {
+		WriteFreezerHorizon(w, number)
+	}
+}
+
+// ReadPrimeCoincidents retrieves the hashes of the canonical blocks coincident
+// with Prime, newest first, up to the limit (0 = all). Blocks dropped by reorgs
+// are skipped.
+func ReadPrimeCoincidents(db ethdb.Database, limit int) []common.Hash {
+	var hashes []common.Hash
+
+	it := db.NewIterator(primeCoincidentPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(primeCoincidentPrefix)+8 {
+			continue
+		}
+		hash := common.BytesToHash(it.Value())
+		if ReadCanonicalHash(db, binary.BigEndian.Uint64(key[len(primeCoincidentPrefix):])) != hash {
+			continue
+		}
+		hashes = append(hashes, hash)
+	}
+	for i, j := 0, len(hashes)-1; i < j; i, j = i+1, j-1 {
+		hashes[i], hashes[j] = hashes[j], hashes[i]
+	}
+	if limit > 0 && len(hashes) > limit {
+		hashes = hashes[:limit]
+	}
+	return hashes
+}
+
+// WritePrimeCoincident stores the hash of the canonical block coincident with
+// Prime at the number.
+func WritePrimeCoincident(db ethdb.KeyValueWriter, number uint64, hash common.Hash) {
+	if err := db.Put(primeCoincidentKey(number), hash.Bytes()); err != nil {
+		log.Crit("Failed to store prime coincident block", "err", err)
+	}
+}
+
+// DeletePrimeCoincidents removes the Prime coincident blocks below the number.
+func DeletePrimeCoincidents(db ethdb.KeyValueStore, number uint64) {
+	batch := db.NewBatch()
+
+	it := db.NewIterator(primeCoincidentPrefix, nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(primeCoincidentPrefix)+8 {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(primeCoincidentPrefix):]) >= number {
+			break
+		}
+		if err := batch.Delete(key); err != nil {
+			log.Crit("Failed to delete prime coincident block", "err", err)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete prime coincident blocks", "err", err)
+	}
+}
+
+// ReadSyncResume retrieves the serialized progress of an interrupted chain
+// synchronisation.
+func ReadSyncResume(db ethdb.KeyValueReader) []byte {
+	data, _ := db.Get(syncResumeKey)
+	return data
+}
+
+// WriteSyncResume stores the serialized progress of an unfinished chain
+// synchronisation.
+func WriteSyncResume(db ethdb.KeyValueWriter, resume []byte) {
+	if err := db.Put(syncResumeKey, resume); err != nil {
+		log.Crit("Failed to store sync resume metadata", "err", err)
+	}
+}
+
+// DeleteSyncResume deletes the progress of a finished chain synchronisation.
+func DeleteSyncResume(db ethdb.KeyValueWriter) {
+	if err := db.Delete(syncResumeKey); err != nil {
+		log.Crit("Failed to remove sync resume metadata", "err", err)
+	}
+}
+
 // ReadFastTrieProgress retrieves the number of tries nodes fast synced to allow
 // reporting correct numbers across restarts.
 func ReadFastTrieProgress(db ethdb.KeyValueReader) uint64 {
@@ -446,7 +556,12 @@ func ReadBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue
 	// Then try to look up the data in leveldb.
 	data, _ = db.Get(blockBodyKey(number, hash))
 	if len(data) > 0 {
-		return data
+		body, err := decompressValue(data)
+		if err != nil {
+			log.Error("Invalid compressed block body", "hash", hash, "number", number, "err", err)
+			return nil
+		}
+		return body
 	}
 	// In the background freezer is moving data from leveldb to flatten files.
 	// So during the first check for ancient db, the data is not yet in there,
@@ -480,6 +595,14 @@ func ReadCanonicalBodyRLP(db ethdb.Reader, number uint64) rlp.RawValue {
 	if len(data) == 0 {
 		// Need to get the hash
 		data, _ = db.Get(blockBodyKey(number, ReadCanonicalHash(db, number)))
+		if len(data) > 0 {
+			body, err := decompressValue(data)
+			if err != nil {
+				log.Error("Invalid compressed block body", "number", number, "err", err)
+				return nil
+			}
+			return body
+		}
 		// In the background freezer is moving data from leveldb to flatten files.
 		// So during the first check for ancient db, the data is not yet in there,
 		// but when we reach into leveldb, the data was already moved. That would
@@ -491,9 +614,10 @@ func ReadCanonicalBodyRLP(db ethdb.Reader, number uint64) rlp.RawValue {
 	return data
 }
 
-// WriteBodyRLP stores an RLP encoded block body into the database.
+// WriteBodyRLP stores an RLP encoded block body into the database, compressed
+// if enabled.
 func WriteBodyRLP(db ethdb.KeyValueWriter, hash common.Hash, number uint64, rlp rlp.RawValue) {
-	if err := db.Put(blockBodyKey(number, hash), rlp); err != nil {
+	if err := db.Put(blockBodyKey(number, hash), compressValue(rlp)); err != nil {
 		log.Crit("Failed to store block body", "err", err)
 	}
 }
@@ -569,7 +693,8 @@ func DeleteBody(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	}
 }
 
-// ReadTdRLP retrieves a block's total difficulty corresponding to the hash in RLP encoding.
+// ReadTdRLP retrieves a block's total difficulty corresponding to the hash in its
+// storage encoding.
 func ReadTdRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	// First try to look up the data in ancient database. Extra hash
 	// comparison is necessary since ancient database only maintains
@@ -600,23 +725,91 @@ func ReadTdRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	return nil // Can't find the data anywhere.
 }
 
-// ReadTd retrieves a block's total difficulty corresponding to the hash.
-func ReadTd(db ethdb.Reader, hash common.Hash, number uint64) []*big.Int {
+// TdFormatVersion is the version of the total difficulty storage format. Total
+// difficulties are stored as the version byte followed by the RLP list of the
+// difficulties of all contexts. Legacy entries, the bare RLP list, are still
+// decoded, as the frozen ones are never rewritten.
+const TdFormatVersion = 1
+
+var (
+	// ErrTdMissing is returned if no total difficulty is stored for a block.
+	ErrTdMissing = errors.New("total difficulty missing")
+
+	// ErrTdMalformed is returned if the stored total difficulty of a block
+	// can't be decoded or doesn't cover all contexts.
+	ErrTdMalformed = errors.New("total difficulty malformed")
+)
+
+// encodeTd encodes a total difficulty in the current storage format.
+func encodeTd(td []*big.Int) ([]byte, error) {
+	data, err := rlp.EncodeToBytes(td)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{TdFormatVersion}, data...), nil
+}
+
+// decodeTd decodes a stored total difficulty of any storage format, returning
+// the format version it was stored in.
+func decodeTd(data []byte) ([]*big.Int, uint64, error) {
+	var version uint64
+	// A legacy entry is an RLP list, never starting with the version byte
+	if len(data) > 1 && data[0] == TdFormatVersion {
+		data, version = data[1:], TdFormatVersion
+	}
+	var td []*big.Int
+	if err := rlp.DecodeBytes(data, &td); err != nil {
+		return nil, version, fmt.Errorf("%w: %v", ErrTdMalformed, err)
+	}
+	if len(td) != types.ContextDepth {
+		return nil, version, fmt.Errorf("%w: %d contexts, want %d", ErrTdMalformed, len(td), types.ContextDepth)
+	}
+	for i := range td {
+		if td[i] == nil {
+			return nil, version, fmt.Errorf("%w: context %d empty", ErrTdMalformed, i)
+		}
+	}
+	return td, version, nil
+}
+
+// readTd retrieves and decodes a block's total difficulty. A malformed frozen
+// entry is superseded by a repaired one in the key-value store.
+func readTd(db ethdb.Reader, hash common.Hash, number uint64) ([]*big.Int, error) {
 	data := ReadTdRLP(db, hash, number)
 	if len(data) == 0 {
-		return nil
+		return nil, ErrTdMissing
+	}
+	td, _, err := decodeTd(data)
+	if err != nil {
+		if repaired, _ := db.Get(headerTDKey(number, hash)); len(repaired) > 0 && !bytes.Equal(repaired, data) {
+			td, _, err = decodeTd(repaired)
+		}
 	}
-	td := new([]*big.Int)
-	if err := rlp.Decode(bytes.NewReader(data), td); err != nil {
-		log.Error("Invalid block total difficulty RLP", "hash", hash, "err", err)
+	return td, err
+}
+
+// ReadTd retrieves a block's total difficulty corresponding to the hash.
+func ReadTd(db ethdb.Reader, hash common.Hash, number uint64) []*big.Int {
+	td, err := readTd(db, hash, number)
+	if err != nil {
+		if err != ErrTdMissing {
+			log.Error("Invalid block total difficulty", "hash", hash, "number", number, "err", err)
+		}
 		return nil
 	}
-	return *td
+	return td
+}
+
+// CheckTd verifies that the total difficulty of a block is stored and covers
+// all contexts, returning ErrTdMissing or ErrTdMalformed otherwise.
+func CheckTd(db ethdb.Reader, hash common.Hash, number uint64) error {
+	_, err := readTd(db, hash, number)
+	return err
 }
 
 // WriteTd stores the total difficulty of a block into the database.
 func WriteTd(db ethdb.KeyValueWriter, hash common.Hash, number uint64, td []*big.Int) {
-	data, err := rlp.EncodeToBytes(td)
+	data, err := encodeTd(td)
 	if err != nil {
 		log.Crit("Failed to RLP encode block total difficulty", "err", err)
 	}
@@ -659,7 +852,12 @@ func ReadReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawVa
 	// Then try to look up the data in leveldb.
 	data, _ = db.Get(blockReceiptsKey(number, hash))
 	if len(data) > 0 {
-		return data
+		receipts, err := decompressValue(data)
+		if err != nil {
+			log.Error("Invalid compressed block receipts", "hash", hash, "number", number, "err", err)
+			return nil
+		}
+		return receipts
 	}
 	// In the background freezer is moving data from leveldb to flatten files.
 	// So during the first check for ancient db, the data is not yet in there,
@@ -734,7 +932,7 @@ func WriteReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64, rec
 		log.Crit("Failed to encode block receipts", "err", err)
 	}
 	// Store the flattened receipt slice
-	if err := db.Put(blockReceiptsKey(number, hash), bytes); err != nil {
+	if err := db.Put(blockReceiptsKey(number, hash), compressValue(bytes)); err != nil {
 		log.Crit("Failed to store block receipts", "err", err)
 	}
 }
@@ -789,6 +987,7 @@ func deriveLogFields(receipts []*receiptLogs, hash common.Hash, number uint64, t
 			receipts[i].Logs[j].TxHash = txHash
 			receipts[i].Logs[j].TxIndex = uint(i)
 			receipts[i].Logs[j].Index = logIndex
+			receipts[i].Logs[j].ID = types.NewLogID(hash, uint(i), logIndex)
 			logIndex++
 		}
 	}
@@ -873,23 +1072,18 @@ func WriteExternalBlock(db ethdb.KeyValueWriter, block *types.ExternalBlock) {
 }
 
 // WriteAncientBlock writes entire block data into ancient store and returns the total written size.
-func WriteAncientBlocks(db ethdb.AncientWriter, blocks []*types.Block, receipts []types.Receipts, td *big.Int) (int64, error) {
-	var (
-		tdSum      = new(big.Int).Set(td)
-		stReceipts []*types.ReceiptForStorage
-	)
+// The total difficulties of the blocks are given in all contexts, as they can't
+// be summed up from the block difficulties across coincident blocks.
+func WriteAncientBlocks(db ethdb.AncientWriter, blocks []*types.Block, receipts []types.Receipts, tds [][]*big.Int) (int64, error) {
+	var stReceipts []*types.ReceiptForStorage
 	return db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
 		for i, block := range blocks {
-			// Convert receipts to storage format and sum up total difficulty.
+			// Convert receipts to storage format.
 			stReceipts = stReceipts[:0]
 			for _, receipt := range receipts[i] {
 				stReceipts = append(stReceipts, (*types.ReceiptForStorage)(receipt))
 			}
-			header := block.Header()
-			if i > 0 {
-				tdSum.Add(tdSum, header.Difficulty[types.QuaiNetworkContext])
-			}
-			if err := writeAncientBlock(op, block, header, stReceipts, tdSum); err != nil {
+			if err := writeAncientBlock(op, block, block.Header(), stReceipts, tds[i]); err != nil {
 				return err
 			}
 		}
@@ -897,7 +1091,7 @@ func WriteAncientBlocks(db ethdb.AncientWriter, blocks []*types.Block, receipts
 	})
 }
 
-func writeAncientBlock(op ethdb.AncientWriteOp, block *types.Block, header *types.Header, receipts []*types.ReceiptForStorage, td *big.Int) error {
+func writeAncientBlock(op ethdb.AncientWriteOp, block *types.Block, header *types.Header, receipts []*types.ReceiptForStorage, td []*big.Int) error {
 	num := block.NumberU64()
 	if err := op.AppendRaw(freezerHashTable, num, block.Hash().Bytes()); err != nil {
 		return fmt.Errorf("can't add block %d hash: %v", num, err)
@@ -911,7 +1105,11 @@ func writeAncientBlock(op ethdb.AncientWriteOp, block *types.Block, header *type
 	if err := op.Append(freezerReceiptTable, num, receipts); err != nil {
 		return fmt.Errorf("can't append block %d receipts: %v", num, err)
 	}
-	if err := op.Append(freezerDifficultyTable, num, td); err != nil {
+	data, err := encodeTd(td)
+	if err != nil {
+		return fmt.Errorf("can't encode block %d total difficulty: %v", num, err)
+	}
+	if err := op.AppendRaw(freezerDifficultyTable, num, data); err != nil {
 		return fmt.Errorf("can't append block %d total difficulty: %v", num, err)
 	}
 	return nil
@@ -920,6 +1118,7 @@ func writeAncientBlock(op ethdb.AncientWriteOp, block *types.Block, header *type
 // DeleteBlock removes all block data associated with a hash.
 func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteReceipts(db, hash, number)
+	DeleteInternalTxs(db, hash, number)
 	DeleteHeader(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
@@ -1030,6 +1229,81 @@ func DeleteBadBlocks(db ethdb.KeyValueWriter) {
 	}
 }
 
+// reorgJournalToKeep is the number of reorgs kept in the journal, older ones
+// being dropped.
+const reorgJournalToKeep = 1024
+
+// ReorgEntry is a reorg executed by the local chain.
+type ReorgEntry struct {
+	Time     uint64        // Time the reorg was executed at
+	Context  uint64        // Context of the reorged chain
+	Number   uint64        // Number of the common ancestor
+	Ancestor common.Hash   // Hash of the common ancestor
+	Dropped  []common.Hash // Blocks dropped from the canonical chain, old head first
+	Adopted  []common.Hash // Blocks adopted into the canonical chain, new head first
+	OldTd    *big.Int      // Total difficulty of the old head
+	NewTd    *big.Int      // Total difficulty of the new head
+}
+
+// readReorgJournalHead retrieves the sequence number of the next reorg journal
+// entry.
+func readReorgJournalHead(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(reorgJournalHeadKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// ReadReorgJournal retrieves the most recent journaled reorgs, at most limit if
+// it's non-zero. The reorgs are returned newest first.
+func ReadReorgJournal(db ethdb.KeyValueReader, limit int) []*ReorgEntry {
+	var entries []*ReorgEntry
+	for seq := readReorgJournalHead(db); seq > 0; seq-- {
+		if limit > 0 && len(entries) >= limit {
+			break
+		}
+		blob, err := db.Get(reorgJournalKey(seq - 1))
+		if err != nil {
+			break
+		}
+		entry := new(ReorgEntry)
+		if err := rlp.DecodeBytes(blob, entry); err != nil {
+			log.Error("Invalid reorg journal entry RLP", "seq", seq-1, "err", err)
+			break
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// WriteReorgEntry appends the reorg to the journal, dropping the oldest entry
+// if the journal is full.
+func WriteReorgEntry(db ethdb.KeyValueStore, entry *ReorgEntry) {
+	data, err := rlp.EncodeToBytes(entry)
+	if err != nil {
+		log.Crit("Failed to encode reorg journal entry", "err", err)
+	}
+	var (
+		seq   = readReorgJournalHead(db)
+		batch = db.NewBatch()
+	)
+	if err := batch.Put(reorgJournalKey(seq), data); err != nil {
+		log.Crit("Failed to store reorg journal entry", "err", err)
+	}
+	if seq >= reorgJournalToKeep {
+		if err := batch.Delete(reorgJournalKey(seq - reorgJournalToKeep)); err != nil {
+			log.Crit("Failed to delete reorg journal entry", "err", err)
+		}
+	}
+	if err := batch.Put(reorgJournalHeadKey, encodeBlockNumber(seq+1)); err != nil {
+		log.Crit("Failed to store reorg journal head", "err", err)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write reorg journal entry", "err", err)
+	}
+}
+
 // FindCommonAncestor returns the last common ancestor of two block headers
 func FindCommonAncestor(db ethdb.Reader, a, b *types.Header) *types.Header {
 	for bn := b.Number[types.QuaiNetworkContext].Uint64(); a.Number[types.QuaiNetworkContext].Uint64() > bn; {
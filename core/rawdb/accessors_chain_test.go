@@ -19,6 +19,7 @@ package rawdb
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -267,7 +268,7 @@ func TestTdStorage(t *testing.T) {
 	db := NewMemoryDatabase()
 
 	// Create a test TD to move around the database and make sure it's really new
-	hash, td := common.Hash{}, big.NewInt(314)
+	hash, td := common.Hash{}, []*big.Int{big.NewInt(314), big.NewInt(315), big.NewInt(316)}
 	if entry := ReadTd(db, hash, 0); entry != nil {
 		t.Fatalf("Non existent TD returned: %v", entry)
 	}
@@ -275,7 +276,7 @@ func TestTdStorage(t *testing.T) {
 	WriteTd(db, hash, 0, td)
 	if entry := ReadTd(db, hash, 0); entry == nil {
 		t.Fatalf("Stored TD not found")
-	} else if entry.Cmp(td) != 0 {
+	} else if !reflect.DeepEqual(entry, td) {
 		t.Fatalf("Retrieved TD mismatch: have %v, want %v", entry, td)
 	}
 	// Delete the TD and verify the execution
@@ -285,6 +286,102 @@ func TestTdStorage(t *testing.T) {
 	}
 }
 
+// Tests that total difficulties are decoded from both the legacy and the
+// versioned storage format, and that partial ones are rejected.
+func TestTdDecoding(t *testing.T) {
+	td := []*big.Int{big.NewInt(314), big.NewInt(315), big.NewInt(316)}
+
+	legacy, err := rlp.EncodeToBytes(td)
+	if err != nil {
+		t.Fatalf("failed to encode legacy TD: %v", err)
+	}
+	versioned, err := encodeTd(td)
+	if err != nil {
+		t.Fatalf("failed to encode TD: %v", err)
+	}
+	partial, _ := rlp.EncodeToBytes(td[:2])
+	empty, _ := rlp.EncodeToBytes([]*big.Int{})
+
+	tests := []struct {
+		data    []byte
+		version uint64
+		fail    bool
+	}{
+		{data: legacy, version: 0},
+		{data: versioned, version: TdFormatVersion},
+		{data: partial, version: 0, fail: true},
+		{data: append([]byte{TdFormatVersion}, partial...), version: TdFormatVersion, fail: true},
+		{data: empty, version: 0, fail: true},
+		{data: []byte{0xff}, version: 0, fail: true},
+	}
+	for i, tt := range tests {
+		have, version, err := decodeTd(tt.data)
+		if version != tt.version {
+			t.Errorf("test %d: version mismatch: have %d, want %d", i, version, tt.version)
+		}
+		if tt.fail {
+			if !errors.Is(err, ErrTdMalformed) {
+				t.Errorf("test %d: error mismatch: have %v, want %v", i, err, ErrTdMalformed)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: failed to decode TD: %v", i, err)
+		} else if !reflect.DeepEqual(have, td) {
+			t.Errorf("test %d: TD mismatch: have %v, want %v", i, have, td)
+		}
+	}
+}
+
+// Tests that a malformed frozen total difficulty is superseded by a repaired
+// one written into the key-value store.
+func TestTdFrozenRepair(t *testing.T) {
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), frdir, "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend")
+	}
+	defer db.Close()
+
+	block := types.NewBlockWithHeader(&types.Header{
+		Number:      []*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(0)},
+		Extra:       [][]byte{[]byte("test header"), []byte("test header"), []byte("test header")},
+		UncleHash:   types.EmptyUncleHash,
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+	})
+	hash, number := block.Hash(), block.NumberU64()
+
+	// Freeze a total difficulty missing a context
+	WriteAncientBlocks(db, []*types.Block{block}, []types.Receipts{nil}, [][]*big.Int{{big.NewInt(100), big.NewInt(100)}})
+	if err := CheckTd(db, hash, number); !errors.Is(err, ErrTdMalformed) {
+		t.Fatalf("frozen TD error mismatch: have %v, want %v", err, ErrTdMalformed)
+	}
+	if entry := ReadTd(db, hash, number); entry != nil {
+		t.Fatalf("malformed TD returned: %v", entry)
+	}
+	// Repair it in the key-value store and verify it takes precedence
+	td := []*big.Int{big.NewInt(100), big.NewInt(100), big.NewInt(100)}
+	WriteTd(db, hash, number, td)
+
+	if err := CheckTd(db, hash, number); err != nil {
+		t.Fatalf("repaired TD rejected: %v", err)
+	}
+	if entry := ReadTd(db, hash, number); !reflect.DeepEqual(entry, td) {
+		t.Fatalf("repaired TD mismatch: have %v, want %v", entry, td)
+	}
+	// A malformed repair doesn't hide the malformed frozen entry
+	db.Put(headerTDKey(number, hash), []byte{0xff})
+	if err := CheckTd(db, hash, number); !errors.Is(err, ErrTdMalformed) {
+		t.Fatalf("malformed repair error mismatch: have %v, want %v", err, ErrTdMalformed)
+	}
+}
+
 // Tests that canonical numbers can be mapped to hashes and retrieved.
 func TestCanonicalMappingStorage(t *testing.T) {
 	db := NewMemoryDatabase()
@@ -403,8 +500,13 @@ func TestBlockReceiptStorage(t *testing.T) {
 	if rs := ReadReceipts(db, hash, 0, params.TestChainConfig); rs != nil {
 		t.Fatalf("receipts returned when body was deleted: %v", rs)
 	}
-	// Ensure that receipts without metadata can be returned without the block body too
-	if err := checkReceiptsRLP(ReadRawReceipts(db, hash, 0), receipts); err != nil {
+	// Ensure that receipts without metadata can be returned without the block body too.
+	// The transaction hashes are part of the consensus encoding, but not stored.
+	raw := ReadRawReceipts(db, hash, 0)
+	for i, receipt := range raw {
+		receipt.TxHash = body.Transactions[i].Hash()
+	}
+	if err := checkReceiptsRLP(raw, receipts); err != nil {
 		t.Fatalf(err.Error())
 	}
 	// Sanity check that body alone without the receipt is a full purge
@@ -473,7 +575,7 @@ func TestAncientStorage(t *testing.T) {
 	}
 
 	// Write and verify the header in the database
-	WriteAncientBlocks(db, []*types.Block{block}, []types.Receipts{nil}, big.NewInt(100))
+	WriteAncientBlocks(db, []*types.Block{block}, []types.Receipts{nil}, [][]*big.Int{{big.NewInt(100), big.NewInt(100), big.NewInt(100)}})
 
 	if blob := ReadHeaderRLP(db, hash, number); len(blob) == 0 {
 		t.Fatalf("no header returned")
@@ -526,7 +628,7 @@ func TestCanonicalHashIteration(t *testing.T) {
 	// Fill database with testing data.
 	for i := uint64(1); i <= 8; i++ {
 		WriteCanonicalHash(db, common.Hash{}, i)
-		WriteTd(db, common.Hash{}, i, big.NewInt(10)) // Write some interferential data
+		WriteTd(db, common.Hash{}, i, []*big.Int{big.NewInt(10), big.NewInt(10), big.NewInt(10)}) // Write some interferential data
 	}
 	for i, c := range cases {
 		numbers, _ := ReadAllCanonicalHashes(db, c.from, c.to, c.limit)
@@ -602,7 +704,10 @@ func BenchmarkWriteAncientBlocks(b *testing.B) {
 	// The benchmark loop writes batches of blocks, but note that the total block count is
 	// b.N. This means the resulting ns/op measurement is the time it takes to write a
 	// single block and its associated data.
-	var td = big.NewInt(55)
+	var tds = make([][]*big.Int, batchSize)
+	for i := range tds {
+		tds[i] = []*big.Int{big.NewInt(55), big.NewInt(55), big.NewInt(55)}
+	}
 	var totalSize int64
 	for i := 0; i < b.N; i += batchSize {
 		length := batchSize
@@ -612,7 +717,7 @@ func BenchmarkWriteAncientBlocks(b *testing.B) {
 
 		blocks := allBlocks[i : i+length]
 		receipts := batchReceipts[:length]
-		writeSize, err := WriteAncientBlocks(db, blocks, receipts, td)
+		writeSize, err := WriteAncientBlocks(db, blocks, receipts, tds[:length])
 		if err != nil {
 			b.Fatal(err)
 		}
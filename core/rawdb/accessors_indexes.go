@@ -175,3 +175,43 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// ReadInternalTxs retrieves the internal transactions made while executing the
+// transactions of a block, nil if the block was not indexed.
+func ReadInternalTxs(db ethdb.KeyValueReader, hash common.Hash, number uint64) []*types.InternalTransaction {
+	data, _ := db.Get(internalTxsKey(number, hash))
+	if len(data) == 0 {
+		// Fall back to the legacy layout until the schema migration moved it
+		if data, _ = db.Get(legacyInternalTxsKey(number, hash)); len(data) == 0 {
+			return nil
+		}
+	}
+	itxs := []*types.InternalTransaction{}
+	if err := rlp.DecodeBytes(data, &itxs); err != nil {
+		log.Error("Invalid internal transaction array RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return itxs
+}
+
+// WriteInternalTxs stores the internal transactions made while executing the
+// transactions of a block.
+func WriteInternalTxs(db ethdb.KeyValueWriter, hash common.Hash, number uint64, itxs []*types.InternalTransaction) {
+	data, err := rlp.EncodeToBytes(itxs)
+	if err != nil {
+		log.Crit("Failed to encode internal transactions", "err", err)
+	}
+	if err := db.Put(internalTxsKey(number, hash), data); err != nil {
+		log.Crit("Failed to store internal transactions", "err", err)
+	}
+}
+
+// DeleteInternalTxs removes the internal transactions of a block.
+func DeleteInternalTxs(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(internalTxsKey(number, hash)); err != nil {
+		log.Crit("Failed to delete internal transactions", "err", err)
+	}
+	if err := db.Delete(legacyInternalTxsKey(number, hash)); err != nil {
+		log.Crit("Failed to delete internal transactions", "err", err)
+	}
+}
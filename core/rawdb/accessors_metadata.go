@@ -53,6 +53,57 @@ func WriteDatabaseVersion(db ethdb.KeyValueWriter, version uint64) {
 	}
 }
 
+// ReadSchemaVersion retrieves the version of the last schema migration applied
+// to the database, zero if none was.
+func ReadSchemaVersion(db ethdb.KeyValueReader) uint64 {
+	var version uint64
+
+	enc, _ := db.Get(schemaVersionKey)
+	if len(enc) == 0 {
+		return 0
+	}
+	if err := rlp.DecodeBytes(enc, &version); err != nil {
+		return 0
+	}
+	return version
+}
+
+// WriteSchemaVersion stores the version of the last schema migration applied to
+// the database.
+func WriteSchemaVersion(db ethdb.KeyValueWriter, version uint64) {
+	enc, err := rlp.EncodeToBytes(version)
+	if err != nil {
+		log.Crit("Failed to encode schema version", "err", err)
+	}
+	if err = db.Put(schemaVersionKey, enc); err != nil {
+		log.Crit("Failed to store the schema version", "err", err)
+	}
+}
+
+// ReadMigrationMarker retrieves the marker an interrupted schema migration
+// resumes from, nil if the migration was not started.
+func ReadMigrationMarker(db ethdb.KeyValueReader, version uint64) []byte {
+	marker, _ := db.Get(migrationKey(version))
+	if len(marker) == 0 {
+		return nil
+	}
+	return marker
+}
+
+// WriteMigrationMarker stores the marker a schema migration resumes from.
+func WriteMigrationMarker(db ethdb.KeyValueWriter, version uint64, marker []byte) {
+	if err := db.Put(migrationKey(version), marker); err != nil {
+		log.Crit("Failed to store the schema migration marker", "err", err)
+	}
+}
+
+// DeleteMigrationMarker removes the marker of a finished schema migration.
+func DeleteMigrationMarker(db ethdb.KeyValueWriter, version uint64) {
+	if err := db.Delete(migrationKey(version)); err != nil {
+		log.Crit("Failed to delete the schema migration marker", "err", err)
+	}
+}
+
 // ReadChainConfig retrieves the consensus settings based on the given genesis hash.
 func ReadChainConfig(db ethdb.KeyValueReader, hash common.Hash) *params.ChainConfig {
 	data, _ := db.Get(configKey(hash))
@@ -0,0 +1,118 @@
+package rawdb
+
+import (
+	"bytes"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/log"
+)
+
+// compressedMarker prefixes the snappy compressed block bodies and receipts in
+// the key-value store. Their RLP encoding is a list, starting with 0xc0 or above.
+const compressedMarker = 0x01
+
+// compression is set if block bodies and receipts are compressed when written
+// to the key-value store.
+var compression uint32
+
+// SetCompression sets whether block bodies and receipts are compressed when
+// written to the key-value store. Compressed data is read regardless, but can't
+// be read by releases without compression support. The ancient store always
+// compresses them.
+func SetCompression(enabled bool) {
+	if enabled {
+		atomic.StoreUint32(&compression, 1)
+	} else {
+		atomic.StoreUint32(&compression, 0)
+	}
+}
+
+// compressionEnabled reports whether block bodies and receipts are compressed.
+func compressionEnabled() bool {
+	return atomic.LoadUint32(&compression) == 1
+}
+
+// compressValue compresses the RLP encoded value if compression is enabled and
+// it saves space.
+func compressValue(data []byte) []byte {
+	if !compressionEnabled() || len(data) == 0 {
+		return data
+	}
+	enc := make([]byte, 1+snappy.MaxEncodedLen(len(data)))
+	enc[0] = compressedMarker
+	enc = enc[:1+len(snappy.Encode(enc[1:], data))]
+	if len(enc) >= len(data) {
+		return data
+	}
+	return enc
+}
+
+// decompressValue returns the RLP encoding of the value read from the key-value
+// store.
+func decompressValue(data []byte) ([]byte, error) {
+	if len(data) == 0 || data[0] != compressedMarker {
+		return data, nil
+	}
+	return snappy.Decode(nil, data[1:])
+}
+
+// RecompressChainData rewrites the block bodies and receipts in the key-value
+// store compressed or uncompressed, according to SetCompression. It returns
+// the number of rewritten values and the sizes of the data before and after.
+func RecompressChainData(db ethdb.KeyValueStore) (int, common.StorageSize, common.StorageSize, error) {
+	var (
+		count         int
+		before, after common.StorageSize
+		logged        = time.Now()
+	)
+	for _, prefix := range [][]byte{blockBodyPrefix, blockReceiptsPrefix} {
+		batch := db.NewBatch()
+		it := db.NewIterator(prefix, nil)
+		for it.Next() {
+			key, value := it.Key(), it.Value()
+			if len(key) != len(prefix)+8+common.HashLength {
+				continue
+			}
+			data, err := decompressValue(value)
+			if err != nil {
+				it.Release()
+				return count, before, after, err
+			}
+			data = compressValue(data)
+			before += common.StorageSize(len(value))
+			after += common.StorageSize(len(data))
+			if bytes.Equal(data, value) {
+				continue
+			}
+			if err := batch.Put(common.CopyBytes(key), data); err != nil {
+				it.Release()
+				return count, before, after, err
+			}
+			count++
+			if batch.ValueSize() >= ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return count, before, after, err
+				}
+				batch.Reset()
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Recompressing chain data", "rewritten", count, "before", before, "after", after)
+				logged = time.Now()
+			}
+		}
+		err := it.Error()
+		it.Release()
+		if err != nil {
+			return count, before, after, err
+		}
+		if err := batch.Write(); err != nil {
+			return count, before, after, err
+		}
+	}
+	return count, before, after, nil
+}
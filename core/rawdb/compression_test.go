@@ -0,0 +1,231 @@
+package rawdb
+
+import (
+	"bytes"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/rlp"
+)
+
+// setCompression switches the compression for the duration of the test.
+func setCompression(t *testing.T, enabled bool) {
+	prev := compressionEnabled()
+	SetCompression(enabled)
+	t.Cleanup(func() { SetCompression(prev) })
+}
+
+// makeCompressibleBlock creates the header, the RLP encoded body and receipts
+// of a block, the latter two repetitive enough to compress.
+func makeCompressibleBlock(t *testing.T, number uint64) (*types.Header, rlp.RawValue, rlp.RawValue) {
+	header := types.NewEmptyHeader()
+	header.Number[types.QuaiNetworkContext] = new(big.Int).SetUint64(number)
+
+	uncles := make([]*types.Header, 8)
+	for i := range uncles {
+		uncles[i] = &types.Header{Extra: [][]byte{bytes.Repeat([]byte("uncle"), 16)}}
+	}
+	body, err := rlp.EncodeToBytes(&types.Body{Uncles: uncles})
+	if err != nil {
+		t.Fatalf("failed to encode body: %v", err)
+	}
+	receipts := make([]*types.ReceiptForStorage, 8)
+	for i := range receipts {
+		receipts[i] = &types.ReceiptForStorage{
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(i),
+			Logs:              []*types.Log{{Address: common.Address{0x11}, Data: bytes.Repeat([]byte{0xaa}, 64)}},
+		}
+	}
+	encReceipts, err := rlp.EncodeToBytes(receipts)
+	if err != nil {
+		t.Fatalf("failed to encode receipts: %v", err)
+	}
+	return header, body, encReceipts
+}
+
+// writeCompressibleBlock stores a canonical block, returning its body and
+// receipts as they should be read back.
+func writeCompressibleBlock(t *testing.T, db ethdb.Database, number uint64) (common.Hash, rlp.RawValue, rlp.RawValue) {
+	header, body, receipts := makeCompressibleBlock(t, number)
+	hash := header.Hash()
+
+	WriteHeader(db, header)
+	WriteCanonicalHash(db, hash, number)
+	WriteTd(db, hash, number, []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)})
+	WriteBodyRLP(db, hash, number, body)
+	if err := db.Put(blockReceiptsKey(number, hash), compressValue(receipts)); err != nil {
+		t.Fatalf("failed to store receipts: %v", err)
+	}
+	return hash, body, receipts
+}
+
+// Tests that values are compressed only when enabled and worth it, and read
+// back as they were written.
+func TestCompressionRoundTrip(t *testing.T) {
+	_, body, _ := makeCompressibleBlock(t, 0)
+	short := []byte{0xc2, 0x01, 0x02}
+
+	setCompression(t, false)
+	if enc := compressValue(body); !bytes.Equal(enc, body) {
+		t.Fatalf("value compressed while disabled")
+	}
+	SetCompression(true)
+	enc := compressValue(body)
+	if enc[0] != compressedMarker || len(enc) >= len(body) {
+		t.Fatalf("value not compressed: %d bytes, marker %#x", len(enc), enc[0])
+	}
+	if dec, err := decompressValue(enc); err != nil || !bytes.Equal(dec, body) {
+		t.Fatalf("round trip mismatch: have %x, %v, want %x", dec, err, body)
+	}
+	// Values not getting smaller and empty values are kept as they are
+	for _, value := range [][]byte{short, {}} {
+		if enc := compressValue(value); !bytes.Equal(enc, value) {
+			t.Errorf("value %x stored as %x", value, enc)
+		}
+		if dec, err := decompressValue(value); err != nil || !bytes.Equal(dec, value) {
+			t.Errorf("value %x read as %x, %v", value, dec, err)
+		}
+	}
+	// Corrupt compressed values fail to decode
+	if _, err := decompressValue([]byte{compressedMarker, 0xff, 0xff}); err == nil {
+		t.Errorf("corrupt value decoded")
+	}
+}
+
+// Tests that plain and compressed bodies and receipts stored side by side are
+// all read as plain RLP.
+func TestCompressionMixedReads(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	setCompression(t, false)
+	plainHash, plainBody, plainReceipts := writeCompressibleBlock(t, db, 1)
+	SetCompression(true)
+	compHash, compBody, compReceipts := writeCompressibleBlock(t, db, 2)
+
+	if data, _ := db.Get(blockBodyKey(1, plainHash)); !bytes.Equal(data, plainBody) {
+		t.Fatalf("body stored compressed while disabled")
+	}
+	if data, _ := db.Get(blockBodyKey(2, compHash)); data[0] != compressedMarker {
+		t.Fatalf("body stored plain while enabled")
+	}
+	// Reads don't depend on the current setting
+	for _, enabled := range []bool{true, false} {
+		SetCompression(enabled)
+
+		if body := ReadBodyRLP(db, plainHash, 1); !bytes.Equal(body, plainBody) {
+			t.Errorf("compression %v: plain body mismatch", enabled)
+		}
+		if body := ReadBodyRLP(db, compHash, 2); !bytes.Equal(body, compBody) {
+			t.Errorf("compression %v: compressed body mismatch", enabled)
+		}
+		if body := ReadCanonicalBodyRLP(db, 1); !bytes.Equal(body, plainBody) {
+			t.Errorf("compression %v: plain canonical body mismatch", enabled)
+		}
+		if body := ReadCanonicalBodyRLP(db, 2); !bytes.Equal(body, compBody) {
+			t.Errorf("compression %v: compressed canonical body mismatch", enabled)
+		}
+		if receipts := ReadReceiptsRLP(db, plainHash, 1); !bytes.Equal(receipts, plainReceipts) {
+			t.Errorf("compression %v: plain receipts mismatch", enabled)
+		}
+		if receipts := ReadReceiptsRLP(db, compHash, 2); !bytes.Equal(receipts, compReceipts) {
+			t.Errorf("compression %v: compressed receipts mismatch", enabled)
+		}
+		if body := ReadBody(db, compHash, 2); body == nil || len(body.Uncles) != 8 {
+			t.Errorf("compression %v: compressed body not decoded", enabled)
+		}
+	}
+}
+
+// Tests that the stored chain data is rewritten compressed and back, without
+// the values read changing.
+func TestRecompressChainData(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	setCompression(t, false)
+	var (
+		hashes   []common.Hash
+		bodies   []rlp.RawValue
+		receipts []rlp.RawValue
+	)
+	for number := uint64(0); number < 4; number++ {
+		hash, body, receipt := writeCompressibleBlock(t, db, number)
+		hashes, bodies, receipts = append(hashes, hash), append(bodies, body), append(receipts, receipt)
+	}
+	check := func(compressed bool) {
+		t.Helper()
+		for i, hash := range hashes {
+			number := uint64(i)
+			if data, _ := db.Get(blockBodyKey(number, hash)); (data[0] == compressedMarker) != compressed {
+				t.Errorf("block %d: body compression mismatch: want %v", i, compressed)
+			}
+			if data, _ := db.Get(blockReceiptsKey(number, hash)); (data[0] == compressedMarker) != compressed {
+				t.Errorf("block %d: receipts compression mismatch: want %v", i, compressed)
+			}
+			if body := ReadBodyRLP(db, hash, number); !bytes.Equal(body, bodies[i]) {
+				t.Errorf("block %d: body mismatch", i)
+			}
+			if receipt := ReadReceiptsRLP(db, hash, number); !bytes.Equal(receipt, receipts[i]) {
+				t.Errorf("block %d: receipts mismatch", i)
+			}
+		}
+	}
+	// Compress the plain data, a second pass having nothing left to do
+	SetCompression(true)
+	count, before, after, err := RecompressChainData(db)
+	if err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+	if count != 2*len(hashes) || after >= before {
+		t.Errorf("compression result mismatch: %d rewritten, %v -> %v", count, before, after)
+	}
+	check(true)
+	if count, _, _, _ := RecompressChainData(db); count != 0 {
+		t.Errorf("compressed data rewritten: %d", count)
+	}
+	// Decompress it back
+	SetCompression(false)
+	count, before, after, err = RecompressChainData(db)
+	if err != nil {
+		t.Fatalf("failed to decompress: %v", err)
+	}
+	if count != 2*len(hashes) || after <= before {
+		t.Errorf("decompression result mismatch: %d rewritten, %v -> %v", count, before, after)
+	}
+	check(false)
+}
+
+// Tests that compressed bodies and receipts are moved to the ancient store as
+// plain RLP.
+func TestFreezeCompressed(t *testing.T) {
+	db := NewMemoryDatabase()
+	setCompression(t, true)
+
+	var (
+		bodies   []rlp.RawValue
+		receipts []rlp.RawValue
+	)
+	for number := uint64(0); number < 4; number++ {
+		_, body, receipt := writeCompressibleBlock(t, db, number)
+		bodies, receipts = append(bodies, body), append(receipts, receipt)
+	}
+	f, dir := newFreezerForTesting(t, FreezerNoSnappy)
+	defer os.RemoveAll(dir)
+	defer f.Close()
+
+	if _, err := f.freezeRange(&nofreezedb{KeyValueStore: db}, 0, 3); err != nil {
+		t.Fatalf("failed to freeze: %v", err)
+	}
+	for i := range bodies {
+		if body, _ := f.Ancient(freezerBodiesTable, uint64(i)); !bytes.Equal(body, bodies[i]) {
+			t.Errorf("block %d: frozen body mismatch: have %x, want %x", i, body, bodies[i])
+		}
+		if receipt, _ := f.Ancient(freezerReceiptTable, uint64(i)); !bytes.Equal(receipt, receipts[i]) {
+			t.Errorf("block %d: frozen receipts mismatch: have %x, want %x", i, receipt, receipts[i])
+		}
+	}
+}
@@ -391,6 +391,19 @@ func (f *freezer) freeze(db ethdb.KeyValueStore) {
 			first, _ = f.Ancients()
 			limit    = *number - threshold
 		)
+		// Subordinate chains are only final up to their latest block coincident
+		// with Prime, don't freeze past it
+		if types.QuaiNetworkContext != params.PRIME {
+			horizon := ReadFreezerHorizon(nfdb)
+			if horizon == nil || *horizon < first {
+				log.Debug("Prime coincident horizon not advanced", "horizon", horizon, "frozen", first)
+				backoff = true
+				continue
+			}
+			if *horizon < limit {
+				limit = *horizon
+			}
+		}
 		if limit-first > freezerBatchLimit {
 			limit = first + freezerBatchLimit
 		}
@@ -0,0 +1,129 @@
+package rawdb
+
+import (
+	"os"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// setContext switches the network context for the duration of the test.
+func setContext(t *testing.T, context int) {
+	prev := types.QuaiNetworkContext
+	types.QuaiNetworkContext = context
+	t.Cleanup(func() { types.QuaiNetworkContext = prev })
+}
+
+// startHorizonFreezer writes a canonical chain up to the head and starts a
+// freezer on it keeping no recent blocks, returning a function running one
+// freezing iteration.
+func startHorizonFreezer(t *testing.T, db ethdb.Database, head uint64) (*freezer, func()) {
+	var hash common.Hash
+	for number := uint64(0); number <= head; number++ {
+		hash, _, _ = writeCompressibleBlock(t, db, number)
+	}
+	WriteHeadBlockHash(db, hash)
+
+	f, dir := newFreezerForTesting(t, FreezerNoSnappy)
+	t.Cleanup(func() {
+		f.Close()
+		os.RemoveAll(dir)
+	})
+	f.threshold = 0
+
+	f.wg.Add(1)
+	go func() {
+		f.freeze(db)
+		f.wg.Done()
+	}()
+	trigger := func() {
+		done := make(chan struct{})
+		f.trigger <- done
+		<-done
+	}
+	// The first iteration runs without being triggered, wait for it
+	trigger()
+	return f, trigger
+}
+
+// checkFrozen verifies the number of blocks moved to the ancient store.
+func checkFrozen(t *testing.T, f *freezer, want uint64) {
+	t.Helper()
+
+	if frozen, _ := f.Ancients(); frozen != want {
+		t.Fatalf("frozen blocks mismatch: have %d, want %d", frozen, want)
+	}
+}
+
+// Tests that subordinate chains freeze no block above the Prime coincident
+// horizon, catching up as it advances.
+func TestFreezerHorizon(t *testing.T) {
+	setContext(t, params.ZONE)
+
+	db := NewMemoryDatabase()
+	WriteFreezerHorizon(db, 3)
+	f, trigger := startHorizonFreezer(t, db, 7)
+	checkFrozen(t, f, 4)
+
+	if hash := ReadCanonicalHash(db, 4); hash == (common.Hash{}) {
+		t.Fatalf("block above the horizon removed from the active database")
+	}
+	WriteFreezerHorizon(db, 6)
+	trigger()
+	checkFrozen(t, f, 7)
+}
+
+// Tests that lowering the horizon on a reorg stops the freezer below the
+// dropped coincident blocks.
+func TestFreezerHorizonReorg(t *testing.T) {
+	setContext(t, params.REGION)
+
+	db := NewMemoryDatabase()
+	WriteFreezerHorizon(db, 2)
+	f, trigger := startHorizonFreezer(t, db, 7)
+	checkFrozen(t, f, 3)
+
+	// Lowering only ever moves the horizon back
+	LowerFreezerHorizon(db, db, 5)
+	if horizon := ReadFreezerHorizon(db); horizon == nil || *horizon != 2 {
+		t.Fatalf("horizon raised by a reorg: have %v, want 2", horizon)
+	}
+	WriteFreezerHorizon(db, 6)
+	LowerFreezerHorizon(db, db, 4)
+	if horizon := ReadFreezerHorizon(db); horizon == nil || *horizon != 4 {
+		t.Fatalf("horizon not lowered by a reorg: have %v, want 4", horizon)
+	}
+	trigger()
+	checkFrozen(t, f, 5)
+}
+
+// Tests that databases upgraded without a horizon freeze nothing on the
+// subordinate chains until the next coincident block is imported.
+func TestFreezerHorizonMissing(t *testing.T) {
+	setContext(t, params.ZONE)
+
+	db := NewMemoryDatabase()
+	f, trigger := startHorizonFreezer(t, db, 7)
+	checkFrozen(t, f, 0)
+
+	LowerFreezerHorizon(db, db, 3)
+	if horizon := ReadFreezerHorizon(db); horizon != nil {
+		t.Fatalf("missing horizon created by a reorg: %d", *horizon)
+	}
+	WriteFreezerHorizon(db, 5)
+	trigger()
+	checkFrozen(t, f, 6)
+}
+
+// Tests that Prime, final on its own, freezes regardless of the horizon.
+func TestFreezerHorizonPrime(t *testing.T) {
+	setContext(t, params.PRIME)
+
+	db := NewMemoryDatabase()
+	WriteFreezerHorizon(db, 2)
+	f, _ := startHorizonFreezer(t, db, 7)
+	checkFrozen(t, f, 8)
+}
@@ -101,6 +101,7 @@ type freezerTable struct {
 
 	head   *os.File            // File descriptor for the data head of the table
 	files  map[uint32]*os.File // open files
+	maps   map[uint32][]byte   // read-only memory mappings of the files before the head
 	headId uint32              // number of the currently active head file
 	tailId uint32              // number of the earliest file
 	index  *os.File            // File descriptor for the indexEntry file of the table
@@ -185,6 +186,7 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 	tab := &freezerTable{
 		index:         offsets,
 		files:         make(map[uint32]*os.File),
+		maps:          make(map[uint32][]byte),
 		readMeter:     readMeter,
 		writeMeter:    writeMeter,
 		sizeGauge:     sizeGauge,
@@ -333,6 +335,7 @@ func (t *freezerTable) preopen() (err error) {
 		if _, err = t.openFile(i, openFreezerFileForReadOnly); err != nil {
 			return err
 		}
+		t.mapFile(i)
 	}
 	// Open head in read/write
 	t.head, err = t.openFile(t.headId, openFreezerFileForAppend)
@@ -414,6 +417,9 @@ func (t *freezerTable) Close() error {
 	}
 	t.index = nil
 
+	for num := range t.maps {
+		t.unmapFile(num)
+	}
 	for _, f := range t.files {
 		if err := f.Close(); err != nil {
 			errs = append(errs, err)
@@ -446,9 +452,42 @@ func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error
 	return f, err
 }
 
+// mapFile maps a file opened for reading into memory, so that retrievals slice
+// into the mapping instead of reading into buffers. Files that cannot be mapped
+// are read through their descriptors. Assumes that the caller holds the write
+// lock.
+func (t *freezerTable) mapFile(num uint32) {
+	f, exist := t.files[num]
+	if !exist {
+		return
+	}
+	stat, err := f.Stat()
+	if err != nil || stat.Size() == 0 || int64(int(stat.Size())) != stat.Size() {
+		return
+	}
+	data, err := mmapFile(f, int(stat.Size()))
+	if err != nil {
+		t.logger.Debug("Failed to map freezer file", "file", num, "err", err)
+		return
+	}
+	t.maps[num] = data
+}
+
+// unmapFile releases the memory mapping of a file, if it was mapped. Assumes
+// that the caller holds the write lock.
+func (t *freezerTable) unmapFile(num uint32) {
+	if data, exist := t.maps[num]; exist {
+		delete(t.maps, num)
+		if err := munmapFile(data); err != nil {
+			t.logger.Warn("Failed to unmap freezer file", "file", num, "err", err)
+		}
+	}
+}
+
 // releaseFile closes a file, and removes it from the open file cache.
 // Assumes that the caller holds the write lock
 func (t *freezerTable) releaseFile(num uint32) {
+	t.unmapFile(num)
 	if f, exist := t.files[num]; exist {
 		delete(t.files, num)
 		f.Close()
@@ -459,6 +498,7 @@ func (t *freezerTable) releaseFile(num uint32) {
 func (t *freezerTable) releaseFilesAfter(num uint32, remove bool) {
 	for fnum, f := range t.files {
 		if fnum > num {
+			t.unmapFile(fnum)
 			delete(t.files, fnum)
 			f.Close()
 			if remove {
@@ -519,11 +559,19 @@ func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
 // 'maxBytes' argument. However, if the 'maxBytes' is smaller than the size of one
 // item, it _will_ return one element and possibly overflow the maxBytes.
 func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, error) {
+	// The raw data might be sliced from a file mapping, keep it mapped until the
+	// items are decompressed or copied out.
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
 	// First we read the 'raw' data, which might be compressed.
-	diskData, sizes, err := t.retrieveItems(start, count, maxBytes)
+	diskData, sizes, mapped, err := t.retrieveItems(start, count, maxBytes)
 	if err != nil {
 		return nil, err
 	}
+	if mapped && t.noCompression {
+		diskData = common.CopyBytes(diskData)
+	}
 	var (
 		output     = make([][]byte, 0, count)
 		offset     int // offset for reading
@@ -556,34 +604,53 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 
 // retrieveItems reads up to 'count' items from the table. It reads at least
 // one item, but otherwise avoids reading more than maxBytes bytes.
-// It returns the (potentially compressed) data, and the sizes.
-func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []int, error) {
-	t.lock.RLock()
-	defer t.lock.RUnlock()
-
+// It returns the (potentially compressed) data, and the sizes. If the data lies
+// within a single mapped file, it is sliced from the mapping without copying,
+// which is reported and only valid while the caller holds the read lock.
+func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []int, bool, error) {
 	// Ensure the table and the item is accessible
 	if t.index == nil || t.head == nil {
-		return nil, nil, errClosed
+		return nil, nil, false, errClosed
 	}
 	itemCount := atomic.LoadUint64(&t.items) // max number
 	// Ensure the start is written, not deleted from the tail, and that the
 	// caller actually wants something
 	if itemCount <= start || uint64(t.itemOffset) > start || count == 0 {
-		return nil, nil, errOutOfBounds
+		return nil, nil, false, errOutOfBounds
 	}
 	if start+count > itemCount {
 		count = itemCount - start
 	}
 	var (
-		output     = make([]byte, maxBytes) // Buffer to read data into
-		outputSize int                      // Used size of that buffer
+		output     []byte // Buffer to read data into, allocated on the first copying read
+		outputSize int    // Used size of that buffer
+		direct     []byte // Data sliced from a file mapping, if the first read was mapped
 	)
 	// readData is a helper method to read a single data item from disk.
 	readData := func(fileId, start uint32, length int) error {
+		if data, exist := t.maps[fileId]; exist && outputSize == 0 && direct == nil && int(start)+length <= len(data) {
+			direct = data[start : int(start)+length]
+			return nil
+		}
+		if output == nil {
+			output = make([]byte, maxBytes)
+		}
+		// The data spans multiple files, move the mapped part into the buffer
+		if direct != nil {
+			if len(output) < len(direct) {
+				output = make([]byte, len(direct))
+			}
+			outputSize = copy(output, direct)
+			direct = nil
+		}
 		// In case a small limit is used, and the elements are large, may need to
 		// realloc the read-buffer when reading the first (and only) item.
-		if len(output) < length {
-			output = make([]byte, length)
+		if len(output) < outputSize+length {
+			output = append(output[:outputSize], make([]byte, length)...)
+		}
+		if data, exist := t.maps[fileId]; exist && int(start)+length <= len(data) {
+			outputSize += copy(output[outputSize:], data[start:int(start)+length])
+			return nil
 		}
 		dataFile, exist := t.files[fileId]
 		if !exist {
@@ -598,7 +665,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	// Read all the indexes in one go
 	indices, err := t.getIndices(start, count)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, false, err
 	}
 	var (
 		sizes      []int               // The sizes for each element
@@ -617,7 +684,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 			// If we have unread data in the first file, we need to do that read now.
 			if unreadSize > 0 {
 				if err := readData(firstIndex.filenum, readStart, unreadSize); err != nil {
-					return nil, nil, err
+					return nil, nil, false, err
 				}
 				unreadSize = 0
 			}
@@ -628,7 +695,7 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 			// read this last item, but we need to do the deferred reads now.
 			if unreadSize > 0 {
 				if err := readData(secondIndex.filenum, readStart, unreadSize); err != nil {
-					return nil, nil, err
+					return nil, nil, false, err
 				}
 			}
 			break
@@ -640,12 +707,15 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		if i == len(indices)-2 || uint64(totalSize) > maxBytes {
 			// Last item, need to do the read now
 			if err := readData(secondIndex.filenum, readStart, unreadSize); err != nil {
-				return nil, nil, err
+				return nil, nil, false, err
 			}
 			break
 		}
 	}
-	return output[:outputSize], sizes, nil
+	if direct != nil {
+		return direct, sizes, true, nil
+	}
+	return output[:outputSize], sizes, false, nil
 }
 
 // has returns an indicator whether the specified number data
@@ -691,6 +761,7 @@ func (t *freezerTable) advanceHead() error {
 	// Close old file, and reopen in RDONLY mode.
 	t.releaseFile(t.headId)
 	t.openFile(t.headId, openFreezerFileForReadOnly)
+	t.mapFile(t.headId)
 
 	// Swap out the current head.
 	t.head = newHead
@@ -829,3 +829,88 @@ func TestSequentialReadByteLimit(t *testing.T) {
 		}
 	}
 }
+
+// TestFreezerMappedReads tests that the items of the files before the head are
+// read through their memory mappings, and that the retrieved items stay valid
+// after the mappings are released.
+func TestFreezerMappedReads(t *testing.T) {
+	for _, noCompression := range []bool{true, false} {
+		rm, wm, sg := metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
+		fname := fmt.Sprintf("mapped-%d", rand.Uint64())
+		{ // Fill table, writing 10 bytes 30 times
+			f, err := newTable(os.TempDir(), fname, rm, wm, sg, 100, noCompression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			writeChunks(t, f, 30, 10)
+			f.Close()
+		}
+		f, err := newTable(os.TempDir(), fname, rm, wm, sg, 100, noCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, err := mmapFile(f.head, 1); err == nil {
+			munmapFile(data)
+			if len(f.maps) != int(f.headId-f.tailId) {
+				t.Fatalf("noCompression %v: mapped file count mismatch: have %d, want %d", noCompression, len(f.maps), f.headId-f.tailId)
+			}
+		}
+		items, err := f.RetrieveItems(0, 30, 100000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		single, err := f.Retrieve(5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if len(f.maps) != 0 {
+			t.Fatalf("noCompression %v: mappings not released", noCompression)
+		}
+		if have, want := len(items), 30; have != want {
+			t.Fatalf("noCompression %v: want %d items, have %d", noCompression, want, have)
+		}
+		for i, have := range items {
+			if want := getChunk(10, i); !bytes.Equal(want, have) {
+				t.Fatalf("noCompression %v: data corruption item %d: have\n%x\n, want \n%x\n", noCompression, i, have, want)
+			}
+		}
+		if want := getChunk(10, 5); !bytes.Equal(want, single) {
+			t.Fatalf("noCompression %v: data corruption: have\n%x\n, want \n%x\n", noCompression, single, want)
+		}
+	}
+}
+
+// BenchmarkFreezerRetrieve measures the retrieval of single items spread over
+// the files of a table.
+func BenchmarkFreezerRetrieve(b *testing.B) {
+	rm, wm, sg := metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
+	fname := fmt.Sprintf("bench-retrieve-%d", rand.Uint64())
+
+	f, err := newTable(os.TempDir(), fname, rm, wm, sg, 1<<16, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	batch := f.newBatch()
+	for i := 0; i < 1000; i++ {
+		if err := batch.AppendRaw(uint64(i), getChunk(1024, i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := batch.commit(); err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+
+	if f, err = newTable(os.TempDir(), fname, rm, wm, sg, 1<<16, false); err != nil {
+		b.Fatal(err)
+	}
+	defer f.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.Retrieve(uint64(i % 1000)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
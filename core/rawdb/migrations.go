@@ -0,0 +1,240 @@
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/log"
+)
+
+// Migration is a resumable online migration of the database schema. Migrations
+// run in the background while the node operates, so the accessors of migrated
+// data must handle both the old and the new layout until the migration is done.
+type Migration struct {
+	Version uint64 // Schema version the migration upgrades to
+	Name    string // Description of the migration for the logs
+
+	// Step migrates a chunk of the data, starting at the marker returned by the
+	// previous step, nil for the first one. The changes are written into the
+	// batch, which is committed together with the returned marker, so that an
+	// interrupted migration resumes where it left off. A nil marker is returned
+	// once the migration is done.
+	Step func(db ethdb.KeyValueStore, batch ethdb.Batch, marker []byte) ([]byte, error)
+}
+
+// migrations is the list of all schema migrations, in the order of their
+// versions. Migrations must never be removed or reordered, only appended.
+var migrations = []*Migration{
+	{
+		Version: 1,
+		Name:    "Move internal transactions out of the chain index key space",
+		Step:    migrateInternalTxs,
+	},
+	{
+		Version: 2,
+		Name:    "Re-encode total difficulties in the versioned format",
+		Step:    migrateTdFormat,
+	},
+}
+
+// SchemaVersion is the schema version of the databases written by this version
+// of the node, the version of the last migration.
+var SchemaVersion = migrations[len(migrations)-1].Version
+
+// migrationBatchSize is the amount of data a migration step should move before
+// committing its progress.
+const migrationBatchSize = ethdb.IdealBatchSize
+
+// Migrator runs the pending schema migrations of a database in the background.
+type Migrator struct {
+	db         ethdb.KeyValueStore
+	migrations []*Migration
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewMigrator creates a migrator upgrading the database to SchemaVersion.
+func NewMigrator(db ethdb.KeyValueStore) *Migrator {
+	return newMigrator(db, migrations)
+}
+
+// newMigrator creates a migrator running the given migrations, which must be
+// ordered by ascending versions.
+func newMigrator(db ethdb.KeyValueStore, migrations []*Migration) *Migrator {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			panic(fmt.Sprintf("schema migration %d out of order", migrations[i].Version))
+		}
+	}
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		quit:       make(chan struct{}),
+	}
+}
+
+// Check returns an error if the database was written in a schema newer than
+// the last migration known, which this version of the node can't read.
+func (m *Migrator) Check() error {
+	var latest uint64
+	if len(m.migrations) > 0 {
+		latest = m.migrations[len(m.migrations)-1].Version
+	}
+	if version := ReadSchemaVersion(m.db); version > latest {
+		return fmt.Errorf("database schema is v%d, only up to v%d is supported", version, latest)
+	}
+	return nil
+}
+
+// Pending returns the migrations not yet applied to the database.
+func (m *Migrator) Pending() []*Migration {
+	version := ReadSchemaVersion(m.db)
+
+	var pending []*Migration
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// Start runs the pending migrations in a background goroutine.
+func (m *Migrator) Start() {
+	pending := m.Pending()
+	if len(pending) == 0 {
+		return
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		for _, migration := range pending {
+			if err := m.run(migration); err != nil {
+				if err != errMigrationInterrupted {
+					log.Error("Schema migration failed", "version", migration.Version, "name", migration.Name, "err", err)
+				}
+				return
+			}
+		}
+	}()
+}
+
+// Stop interrupts the running migration, keeping its progress for resuming it
+// on the next start.
+func (m *Migrator) Stop() {
+	close(m.quit)
+	m.wg.Wait()
+}
+
+// errMigrationInterrupted is returned by run if the migrator was stopped.
+var errMigrationInterrupted = errors.New("migration interrupted")
+
+// run applies a migration, resuming it from its persisted marker.
+func (m *Migrator) run(migration *Migration) error {
+	var (
+		marker = ReadMigrationMarker(m.db, migration.Version)
+		start  = time.Now()
+		logged = time.Now()
+		steps  int
+	)
+	if marker == nil {
+		log.Info("Starting schema migration", "version", migration.Version, "name", migration.Name)
+	} else {
+		log.Info("Resuming schema migration", "version", migration.Version, "name", migration.Name, "marker", common.Bytes2Hex(marker))
+	}
+	for {
+		select {
+		case <-m.quit:
+			log.Info("Interrupted schema migration", "version", migration.Version, "steps", steps)
+			return errMigrationInterrupted
+		default:
+		}
+		batch := m.db.NewBatch()
+		next, err := migration.Step(m.db, batch, marker)
+		if err != nil {
+			return err
+		}
+		if next == nil {
+			DeleteMigrationMarker(batch, migration.Version)
+			WriteSchemaVersion(batch, migration.Version)
+		} else {
+			WriteMigrationMarker(batch, migration.Version, next)
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		if next == nil {
+			log.Info("Finished schema migration", "version", migration.Version, "steps", steps+1, "elapsed", common.PrettyDuration(time.Since(start)))
+			return nil
+		}
+		marker, steps = next, steps+1
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Migrating database schema", "version", migration.Version, "steps", steps, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+}
+
+// migrateInternalTxs moves the internal transactions from the legacy prefix
+// in the chain index key space into their own data item prefix.
+func migrateInternalTxs(db ethdb.KeyValueStore, batch ethdb.Batch, marker []byte) ([]byte, error) {
+	it := db.NewIterator(legacyInternalTxsPrefix, marker)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(legacyInternalTxsPrefix)+8+common.HashLength {
+			continue
+		}
+		if batch.ValueSize() >= migrationBatchSize {
+			return common.CopyBytes(key[len(legacyInternalTxsPrefix):]), nil
+		}
+		if err := batch.Put(append(common.CopyBytes(internalTxsPrefix), key[len(legacyInternalTxsPrefix):]...), it.Value()); err != nil {
+			return nil, err
+		}
+		if err := batch.Delete(key); err != nil {
+			return nil, err
+		}
+	}
+	return nil, it.Error()
+}
+
+// migrateTdFormat re-encodes the legacy total difficulties in the key-value
+// store in the versioned format. Malformed entries are left for repair.
+func migrateTdFormat(db ethdb.KeyValueStore, batch ethdb.Batch, marker []byte) ([]byte, error) {
+	it := db.NewIterator(headerPrefix, marker)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(headerPrefix)+8+common.HashLength+len(headerTDSuffix) || !bytes.HasSuffix(key, headerTDSuffix) {
+			continue
+		}
+		if batch.ValueSize() >= migrationBatchSize {
+			return common.CopyBytes(key[len(headerPrefix):]), nil
+		}
+		td, version, err := decodeTd(it.Value())
+		if err != nil {
+			log.Debug("Skipping malformed total difficulty", "key", common.Bytes2Hex(key), "err", err)
+			continue
+		}
+		if version == TdFormatVersion {
+			continue
+		}
+		data, err := encodeTd(td)
+		if err != nil {
+			return nil, err
+		}
+		if err := batch.Put(key, data); err != nil {
+			return nil, err
+		}
+	}
+	return nil, it.Error()
+}
@@ -0,0 +1,261 @@
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/rlp"
+)
+
+// Tests that the total difficulty migration re-encodes all legacy entries in
+// the versioned format across several steps, leaving the versioned and the
+// malformed ones untouched.
+func TestMigrateTdFormat(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	// Write enough legacy entries to need several steps, interleaved with
+	// versioned and malformed ones
+	var (
+		entries   = uint64(migrationBatchSize / 4)
+		legacy    = make(map[common.Hash][]*big.Int)
+		versioned = make(map[common.Hash][]byte)
+		malformed = make(map[common.Hash][]byte)
+	)
+	for i := uint64(0); i < entries; i++ {
+		hash := common.BytesToHash(new(big.Int).SetUint64(i + 1).Bytes())
+		td := []*big.Int{new(big.Int).SetUint64(i), new(big.Int).SetUint64(i + 1), new(big.Int).SetUint64(i + 2)}
+
+		switch i % 16 {
+		case 0:
+			WriteTd(db, hash, i, td)
+			versioned[hash], _ = db.Get(headerTDKey(i, hash))
+		case 1:
+			data, _ := rlp.EncodeToBytes(td[:2])
+			db.Put(headerTDKey(i, hash), data)
+			malformed[hash] = data
+		default:
+			data, _ := rlp.EncodeToBytes(td)
+			db.Put(headerTDKey(i, hash), data)
+			legacy[hash] = td
+		}
+		// Unrelated entries under the header prefix are skipped
+		WriteCanonicalHash(db, hash, i)
+	}
+	var (
+		marker []byte
+		steps  int
+	)
+	for {
+		batch := db.NewBatch()
+		next, err := migrateTdFormat(db, batch, marker)
+		if err != nil {
+			t.Fatalf("step %d: migration failed: %v", steps, err)
+		}
+		if err := batch.Write(); err != nil {
+			t.Fatalf("step %d: failed to write batch: %v", steps, err)
+		}
+		steps++
+		if next == nil {
+			break
+		}
+		if marker != nil && bytes.Compare(next, marker) <= 0 {
+			t.Fatalf("step %d: marker not advancing: have %x, previous %x", steps, next, marker)
+		}
+		marker = next
+	}
+	if steps < 2 {
+		t.Errorf("migration done in %d steps, want several", steps)
+	}
+	for i := uint64(0); i < entries; i++ {
+		hash := common.BytesToHash(new(big.Int).SetUint64(i + 1).Bytes())
+		data, _ := db.Get(headerTDKey(i, hash))
+
+		switch {
+		case legacy[hash] != nil:
+			td, version, err := decodeTd(data)
+			if err != nil || version != TdFormatVersion {
+				t.Fatalf("entry %d: not migrated: version %d, err %v", i, version, err)
+			}
+			if !reflect.DeepEqual(td, legacy[hash]) {
+				t.Fatalf("entry %d: TD mismatch: have %v, want %v", i, td, legacy[hash])
+			}
+		case versioned[hash] != nil:
+			if !bytes.Equal(data, versioned[hash]) {
+				t.Fatalf("entry %d: versioned entry rewritten: have %x, want %x", i, data, versioned[hash])
+			}
+		default:
+			if !bytes.Equal(data, malformed[hash]) {
+				t.Fatalf("entry %d: malformed entry rewritten: have %x, want %x", i, data, malformed[hash])
+			}
+		}
+		if have := ReadCanonicalHash(db, i); have != hash {
+			t.Fatalf("entry %d: canonical hash mismatch: have %x, want %x", i, have, hash)
+		}
+	}
+}
+
+// testMigration moves the entries under the "old-" prefix to the "new-" one,
+// two per step, recording the marker every step started from.
+type testMigration struct {
+	markers [][]byte
+	onStep  func(step int) // Called after every step, if set
+}
+
+func (m *testMigration) step(db ethdb.KeyValueStore, batch ethdb.Batch, marker []byte) ([]byte, error) {
+	m.markers = append(m.markers, common.CopyBytes(marker))
+	if m.onStep != nil {
+		defer m.onStep(len(m.markers))
+	}
+	it := db.NewIterator([]byte("old-"), marker)
+	defer it.Release()
+
+	for moved := 0; it.Next(); moved++ {
+		key := it.Key()
+		if moved == 2 {
+			return common.CopyBytes(key[len("old-"):]), nil
+		}
+		batch.Put(append([]byte("new-"), key[len("old-"):]...), it.Value())
+		batch.Delete(key)
+	}
+	return nil, it.Error()
+}
+
+// writeTestEntries stores the entries migrated by a testMigration.
+func writeTestEntries(db ethdb.KeyValueWriter, n int) {
+	for i := 0; i < n; i++ {
+		db.Put([]byte(fmt.Sprintf("old-%02d", i)), []byte{byte(i)})
+	}
+}
+
+// checkTestEntries verifies that all entries were moved by a testMigration.
+func checkTestEntries(t *testing.T, db ethdb.KeyValueReader, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		if ok, _ := db.Has([]byte(fmt.Sprintf("old-%02d", i))); ok {
+			t.Errorf("entry %d: not moved", i)
+		}
+		if value, _ := db.Get([]byte(fmt.Sprintf("new-%02d", i))); !bytes.Equal(value, []byte{byte(i)}) {
+			t.Errorf("entry %d: migrated value mismatch: have %x, want %x", i, value, []byte{byte(i)})
+		}
+	}
+}
+
+// Tests that an interrupted migration keeps its progress and resumes from it,
+// recording the schema version only once done.
+func TestMigratorResume(t *testing.T) {
+	db := NewMemoryDatabase()
+	writeTestEntries(db, 10)
+
+	// Interrupt the migration after its second step
+	first := &testMigration{}
+	m := newMigrator(db, []*Migration{{Version: 1, Name: "test", Step: first.step}})
+	first.onStep = func(step int) {
+		if step == 2 {
+			close(m.quit)
+		}
+	}
+	m.Start()
+	m.wg.Wait()
+
+	if len(first.markers) != 2 {
+		t.Fatalf("interrupted migration steps mismatch: have %d, want 2", len(first.markers))
+	}
+	if marker := ReadMigrationMarker(db, 1); !bytes.Equal(marker, []byte("04")) {
+		t.Fatalf("persisted marker mismatch: have %q, want %q", marker, "04")
+	}
+	if version := ReadSchemaVersion(db); version != 0 {
+		t.Fatalf("schema version recorded by an interrupted migration: %d", version)
+	}
+	// Restart the migration, which must pick up from the marker
+	second := &testMigration{}
+	m = newMigrator(db, []*Migration{{Version: 1, Name: "test", Step: second.step}})
+	m.Start()
+	m.wg.Wait()
+
+	if len(second.markers) == 0 || !bytes.Equal(second.markers[0], []byte("04")) {
+		t.Fatalf("migration not resumed from the marker: %q", second.markers)
+	}
+	checkTestEntries(t, db, 10)
+
+	if marker := ReadMigrationMarker(db, 1); marker != nil {
+		t.Errorf("marker kept after the migration: %q", marker)
+	}
+	if version := ReadSchemaVersion(db); version != 1 {
+		t.Errorf("schema version mismatch: have %d, want 1", version)
+	}
+	if pending := m.Pending(); len(pending) != 0 {
+		t.Errorf("migrations pending after completion: %d", len(pending))
+	}
+}
+
+// Tests that only the migrations above the schema version run, each recording
+// its version as it completes and a failure stopping the later ones.
+func TestMigratorVersions(t *testing.T) {
+	db := NewMemoryDatabase()
+	writeTestEntries(db, 4)
+	WriteSchemaVersion(db, 1)
+
+	var (
+		applied = &testMigration{}
+		skipped = &testMigration{}
+		failure = errors.New("migration failed")
+		later   = &testMigration{}
+	)
+	m := newMigrator(db, []*Migration{
+		{Version: 1, Name: "applied", Step: skipped.step},
+		{Version: 3, Name: "pending", Step: applied.step},
+		{Version: 4, Name: "failing", Step: func(ethdb.KeyValueStore, ethdb.Batch, []byte) ([]byte, error) { return nil, failure }},
+		{Version: 5, Name: "later", Step: later.step},
+	})
+	if pending := m.Pending(); len(pending) != 3 || pending[0].Version != 3 {
+		t.Fatalf("pending migrations mismatch: %d, first %+v", len(pending), pending[0])
+	}
+	m.Start()
+	m.wg.Wait()
+
+	if len(skipped.markers) != 0 {
+		t.Errorf("applied migration run again")
+	}
+	checkTestEntries(t, db, 4)
+	if len(later.markers) != 0 {
+		t.Errorf("migration run after a failure")
+	}
+	if version := ReadSchemaVersion(db); version != 3 {
+		t.Errorf("schema version mismatch: have %d, want 3", version)
+	}
+	if err := m.Check(); err != nil {
+		t.Errorf("older schema refused: %v", err)
+	}
+}
+
+// Tests that databases written in a schema newer than the last migration are
+// refused, and unsorted migrations rejected.
+func TestMigratorNewerSchema(t *testing.T) {
+	db := NewMemoryDatabase()
+	WriteSchemaVersion(db, SchemaVersion+1)
+
+	m := NewMigrator(db)
+	if err := m.Check(); err == nil {
+		t.Fatalf("newer schema v%d accepted", SchemaVersion+1)
+	}
+	if pending := m.Pending(); len(pending) != 0 {
+		t.Fatalf("migrations pending on a newer schema: %d", len(pending))
+	}
+	WriteSchemaVersion(db, SchemaVersion)
+	if err := m.Check(); err != nil {
+		t.Fatalf("current schema refused: %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("unsorted migrations accepted")
+		}
+	}()
+	newMigrator(db, []*Migration{{Version: 2}, {Version: 1}})
+}
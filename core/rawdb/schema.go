@@ -30,6 +30,9 @@ var (
 	// databaseVersionKey tracks the current database version.
 	databaseVersionKey = []byte("DatabaseVersion")
 
+	// schemaVersionKey tracks the version of the last schema migration applied.
+	schemaVersionKey = []byte("SchemaVersion")
+
 	// headHeaderKey tracks the latest known header's hash.
 	headHeaderKey = []byte("LastHeader")
 
@@ -42,6 +45,9 @@ var (
 	// lastPivotKey tracks the last pivot block used by fast sync (to reenable on sethead).
 	lastPivotKey = []byte("LastPivot")
 
+	// syncResumeKey tracks the progress of an unfinished chain sync across restarts.
+	syncResumeKey = []byte("SyncResume")
+
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
@@ -72,6 +78,13 @@ var (
 	// badBlockKey tracks the list of bad blocks seen by local
 	badBlockKey = []byte("InvalidBlock")
 
+	// reorgJournalHeadKey tracks the sequence number of the next reorg journal entry.
+	reorgJournalHeadKey = []byte("ReorgJournalHead")
+
+	// freezerHorizonKey tracks the number of the latest canonical block coincident
+	// with Prime, above which subordinate chain segments are not frozen.
+	freezerHorizonKey = []byte("FreezerHorizon")
+
 	// uncleanShutdownKey tracks the list of local crashes
 	uncleanShutdownKey = []byte("unclean-shutdown") // config prefix for the db
 
@@ -91,6 +104,15 @@ var (
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
 
+	internalTxsPrefix       = []byte("I")  // internalTxsPrefix + num (uint64 big endian) + hash -> internal transactions
+	legacyInternalTxsPrefix = []byte("it") // legacyInternalTxsPrefix + num (uint64 big endian) + hash -> internal transactions, before schema version 1
+
+	migrationPrefix = []byte("SchemaMigration-") // migrationPrefix + version (uint64 big endian) -> migration resume marker
+
+	reorgJournalPrefix = []byte("ReorgJournal-") // reorgJournalPrefix + seq (uint64 big endian) -> reorg journal entry
+
+	primeCoincidentPrefix = []byte("PrimeCoincident-") // primeCoincidentPrefix + num (uint64 big endian) -> hash of the block coincident with Prime
+
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
 
@@ -188,6 +210,31 @@ func blockReceiptsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockReceiptsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// internalTxsKey = internalTxsPrefix + num (uint64 big endian) + hash
+func internalTxsKey(number uint64, hash common.Hash) []byte {
+	return append(append(internalTxsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// legacyInternalTxsKey = legacyInternalTxsPrefix + num (uint64 big endian) + hash
+func legacyInternalTxsKey(number uint64, hash common.Hash) []byte {
+	return append(append(legacyInternalTxsPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
+// migrationKey = migrationPrefix + version (uint64 big endian)
+func migrationKey(version uint64) []byte {
+	return append(migrationPrefix, encodeBlockNumber(version)...)
+}
+
+// reorgJournalKey = reorgJournalPrefix + seq (uint64 big endian)
+func reorgJournalKey(seq uint64) []byte {
+	return append(reorgJournalPrefix, encodeBlockNumber(seq)...)
+}
+
+// primeCoincidentKey = primeCoincidentPrefix + num (uint64 big endian)
+func primeCoincidentKey(number uint64) []byte {
+	return append(primeCoincidentPrefix, encodeBlockNumber(number)...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
@@ -130,6 +130,10 @@ type (
 	touchChange struct {
 		account *common.Address
 	}
+	// Changes to the accessed accounts
+	accountAccessChange struct {
+		address *common.Address
+	}
 	// Changes to the access list
 	accessListAddAccountChange struct {
 		address *common.Address
@@ -243,6 +247,14 @@ func (ch addPreimageChange) dirtied() *common.Address {
 	return nil
 }
 
+func (ch accountAccessChange) revert(s *StateDB) {
+	delete(s.accessed, *ch.address)
+}
+
+func (ch accountAccessChange) dirtied() *common.Address {
+	return nil
+}
+
 func (ch accessListAddAccountChange) revert(s *StateDB) {
 	/*
 		One important invariant here, is that whenever a (addr, slot) is added, if the
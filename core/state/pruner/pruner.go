@@ -74,6 +74,9 @@ var (
 // the whole pruning work. It's recommended to run this offline tool
 // periodically in order to release the disk usage and improve the
 // disk read performance to some extent.
+//
+// The states of the latest blocks coincident with Prime can be kept
+// too, reorgs never go deeper than them.
 type Pruner struct {
 	db            ethdb.Database
 	stateBloom    *stateBloom
@@ -81,10 +84,12 @@ type Pruner struct {
 	trieCachePath string
 	headHeader    *types.Header
 	snaptree      *snapshot.Tree
+	coincidents   int // Number of the latest Prime coincident states kept
 }
 
-// NewPruner creates the pruner instance.
-func NewPruner(db ethdb.Database, datadir, trieCachePath string, bloomSize uint64) (*Pruner, error) {
+// NewPruner creates the pruner instance, keeping the states of the given number
+// of the latest blocks coincident with Prime besides the target state.
+func NewPruner(db ethdb.Database, datadir, trieCachePath string, bloomSize uint64, coincidents int) (*Pruner, error) {
 	headBlock := rawdb.ReadHeadBlock(db)
 	if headBlock == nil {
 		return nil, errors.New("Failed to load head block")
@@ -109,6 +114,7 @@ func NewPruner(db ethdb.Database, datadir, trieCachePath string, bloomSize uint6
 		trieCachePath: trieCachePath,
 		headHeader:    headBlock.Header(),
 		snaptree:      snaptree,
+		coincidents:   coincidents,
 	}, nil
 }
 
@@ -305,6 +311,9 @@ func (p *Pruner) Prune(root common.Hash) error {
 	// state is picked for usage.
 	deleteCleanTrieCache(p.trieCachePath)
 
+	// Resolve the Prime coincident states to keep besides the target
+	anchors, oldest := p.coincidentRoots()
+
 	// All the state roots of the middle layer should be forcibly pruned,
 	// otherwise the dangling state will be left.
 	middleRoots := make(map[common.Hash]struct{})
@@ -312,6 +321,9 @@ func (p *Pruner) Prune(root common.Hash) error {
 		if layer.Root() == root {
 			break
 		}
+		if _, ok := anchors[layer.Root()]; ok {
+			continue
+		}
 		middleRoots[layer.Root()] = struct{}{}
 	}
 	// Traverse the target state, re-construct the whole state trie and
@@ -325,6 +337,15 @@ func (p *Pruner) Prune(root common.Hash) error {
 	if err := extractGenesis(p.db, p.stateBloom); err != nil {
 		return err
 	}
+	// Traverse the Prime coincident states too
+	for anchor := range anchors {
+		if anchor == root {
+			continue
+		}
+		if err := extractState(p.db, anchor, p.stateBloom); err != nil {
+			return err
+		}
+	}
 	filterName := bloomFilterName(p.datadir, root)
 
 	log.Info("Writing state bloom to disk", "name", filterName)
@@ -332,7 +353,45 @@ func (p *Pruner) Prune(root common.Hash) error {
 		return err
 	}
 	log.Info("State bloom filter committed", "name", filterName)
-	return prune(p.snaptree, root, p.db, p.stateBloom, filterName, middleRoots, start)
+	if err := prune(p.snaptree, root, p.db, p.stateBloom, filterName, middleRoots, start); err != nil {
+		return err
+	}
+	// Forget the Prime coincident blocks whose states are gone
+	if oldest != nil {
+		rawdb.DeletePrimeCoincidents(p.db, *oldest)
+	}
+	return nil
+}
+
+// coincidentRoots returns the state roots of the latest blocks coincident with
+// Prime available on disk and the number of the oldest one.
+func (p *Pruner) coincidentRoots() (map[common.Hash]struct{}, *uint64) {
+	var (
+		roots  = make(map[common.Hash]struct{})
+		oldest *uint64
+	)
+	if p.coincidents <= 0 {
+		return roots, nil
+	}
+	for _, hash := range rawdb.ReadPrimeCoincidents(p.db, p.coincidents) {
+		number := rawdb.ReadHeaderNumber(p.db, hash)
+		if number == nil {
+			continue
+		}
+		header := rawdb.ReadHeader(p.db, hash, *number)
+		if header == nil {
+			continue
+		}
+		root := header.Root[types.QuaiNetworkContext]
+		if blob := rawdb.ReadTrieNode(p.db, root); len(blob) == 0 {
+			log.Warn("Prime coincident state missing", "number", *number, "hash", hash, "root", root)
+			continue
+		}
+		roots[root] = struct{}{}
+		oldest = number
+	}
+	log.Info("Keeping Prime coincident states", "states", len(roots), "requested", p.coincidents)
+	return roots, oldest
 }
 
 // RecoverPruning will resume the pruning procedure during the system restart.
@@ -410,7 +469,12 @@ func extractGenesis(db ethdb.Database, stateBloom *stateBloom) error {
 	if genesis == nil {
 		return errors.New("missing genesis block")
 	}
-	t, err := trie.NewSecure(genesis.Root(), trie.NewDatabase(db))
+	return extractState(db, genesis.Root(), stateBloom)
+}
+
+// extractState puts all the trie nodes and codes of the state into the bloom.
+func extractState(db ethdb.Database, root common.Hash, stateBloom *stateBloom) error {
+	t, err := trie.NewSecure(root, trie.NewDatabase(db))
 	if err != nil {
 		return err
 	}
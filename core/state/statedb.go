@@ -18,6 +18,7 @@
 package state
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"math/big"
@@ -99,6 +100,11 @@ type StateDB struct {
 	// Per-transaction access list
 	accessList *accessList
 
+	// Accounts accessed since the state was created, for the state expiry.
+	// Only tracked once enabled for the blocks of the state expiry.
+	accessed    map[common.Address]struct{}
+	trackAccess bool
+
 	// Journal of state modifications. This is the backbone of
 	// Snapshot and RevertToSnapshot.
 	journal        *journal
@@ -142,6 +148,7 @@ func New(root common.Hash, db Database, snaps *snapshot.Tree) (*StateDB, error)
 		preimages:           make(map[common.Hash][]byte),
 		journal:             newJournal(),
 		accessList:          newAccessList(),
+		accessed:            make(map[common.Address]struct{}),
 		hasher:              crypto.NewKeccakState(),
 	}
 	if sdb.snaps != nil {
@@ -201,6 +208,7 @@ func (s *StateDB) GetLogs(hash common.Hash, blockHash common.Hash) []*types.Log
 	logs := s.logs[hash]
 	for _, l := range logs {
 		l.BlockHash = blockHash
+		l.ID = types.NewLogID(blockHash, l.TxIndex, l.Index)
 	}
 	return logs
 }
@@ -501,6 +509,12 @@ func (s *StateDB) getStateObject(addr common.Address) *stateObject {
 // flag set. This is needed by the state journal to revert to the correct s-
 // destructed object instead of wiping all knowledge about the state object.
 func (s *StateDB) getDeletedStateObject(addr common.Address) *stateObject {
+	if s.trackAccess {
+		if _, ok := s.accessed[addr]; !ok {
+			s.accessed[addr] = struct{}{}
+			s.journal.append(accountAccessChange{&addr})
+		}
+	}
 	// Prefer live objects if any is available
 	if obj := s.stateObjects[addr]; obj != nil {
 		return obj
@@ -596,6 +610,43 @@ func (s *StateDB) createObject(addr common.Address) (newobj, prev *stateObject)
 	return newobj, nil
 }
 
+// ReviveAccount recreates an account removed by the state expiry from its last
+// state, as proven against the state root it expired at. The storage trie and
+// the code of the account must still be in the database. Any balance credited
+// to the account since its removal is carried over.
+func (s *StateDB) ReviveAccount(addr common.Address, account *types.StateAccount) {
+	newObj, prev := s.createObject(addr)
+
+	balance := new(big.Int).Set(account.Balance)
+	if prev != nil {
+		balance.Add(balance, prev.data.Balance)
+	}
+	newObj.data.Nonce = account.Nonce
+	newObj.data.Balance = balance
+	newObj.data.Root = account.Root
+	newObj.data.CodeHash = common.CopyBytes(account.CodeHash)
+}
+
+// SetTrackAccess enables or disables tracking the accounts accessed, see
+// AccessedAccounts.
+func (s *StateDB) SetTrackAccess(track bool) {
+	s.trackAccess = track
+}
+
+// AccessedAccounts returns the accounts accessed since the state was created,
+// in the order of their addresses. Accesses are only tracked while enabled
+// with SetTrackAccess.
+func (s *StateDB) AccessedAccounts() []common.Address {
+	accounts := make([]common.Address, 0, len(s.accessed))
+	for addr := range s.accessed {
+		accounts = append(accounts, addr)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return bytes.Compare(accounts[i][:], accounts[j][:]) < 0
+	})
+	return accounts
+}
+
 // CreateAccount explicitly creates a state object. If a state object with the address
 // already exists the balance is carried over to the new account.
 //
@@ -708,6 +759,12 @@ func (s *StateDB) Copy() *StateDB {
 	// to not blow up if we ever decide copy it in the middle of a transaction
 	state.accessList = s.accessList.Copy()
 
+	state.accessed = make(map[common.Address]struct{}, len(s.accessed))
+	for addr := range s.accessed {
+		state.accessed[addr] = struct{}{}
+	}
+	state.trackAccess = s.trackAccess
+
 	// If there's a prefetcher running, make an inactive copy of it that can
 	// only access data but does not actively preload (since the user will not
 	// know that they need to explicitly terminate an active copy).
@@ -915,3 +915,30 @@ func TestStateDBAccessList(t *testing.T) {
 		t.Fatalf("expected empty, got %d", got)
 	}
 }
+
+// Tests that the accessed accounts are only tracked once enabled, and that
+// reverting drops the accesses made since the snapshot.
+func TestStateDBAccessedAccounts(t *testing.T) {
+	state, _ := New(common.Hash{}, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	addr := func(b byte) common.Address { return common.Address{b} }
+
+	state.GetBalance(addr(1))
+	if accessed := state.AccessedAccounts(); len(accessed) != 0 {
+		t.Fatalf("accesses tracked while disabled: %v", accessed)
+	}
+	state.SetTrackAccess(true)
+	state.GetBalance(addr(2))
+	state.AddBalance(addr(1), big.NewInt(1))
+
+	snapshot := state.Snapshot()
+	state.GetNonce(addr(3))
+	state.RevertToSnapshot(snapshot)
+
+	want := []common.Address{addr(1), addr(2)}
+	if have := state.AccessedAccounts(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("accessed accounts mismatch: have %v, want %v", have, want)
+	}
+	if have := state.Copy().AccessedAccounts(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("copied accessed accounts mismatch: have %v, want %v", have, want)
+	}
+}
@@ -0,0 +1,186 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/ethdb/memorydb"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/rlp"
+	"github.com/spruce-solutions/go-quai/trie"
+)
+
+var (
+	stateExpiryMarkedMeter  = metrics.NewRegisteredMeter("chain/expiry/marked", nil)
+	stateExpiryExpiredMeter = metrics.NewRegisteredMeter("chain/expiry/expired", nil)
+	stateExpiryRevivedMeter = metrics.NewRegisteredMeter("chain/expiry/revived", nil)
+)
+
+// ApplyStateExpiry sweeps the state before the transactions of a block. At the
+// start of every state expiry epoch it records the state root the epoch before
+// ended with. The accounts last accessed in the epoch before that one are then
+// removed, which can only be revived with a proof against the recorded root.
+// Every block removes at most params.StateExpirySweepLimit accounts, the rest
+// being carried forward to the next blocks.
+//
+// The state tracks the accounts accessed by the block only while the state
+// expiry is active, see MarkStateAccess.
+func ApplyStateExpiry(config *params.ChainConfig, parent *types.Header, header *types.Header, statedb vm.StateDB) {
+	context := types.QuaiNetworkContext
+	number := header.Number[context]
+	if tracker, ok := statedb.(interface{ SetTrackAccess(bool) }); ok {
+		tracker.SetTrackAccess(config.IsStateExpiry(number))
+	}
+	if !config.IsStateExpiry(number) || parent == nil {
+		return
+	}
+	epoch := config.ExpiryEpoch(number.Uint64())
+	if epoch == 0 {
+		return
+	}
+	if config.IsExpiryEpochStart(number) {
+		vm.WriteExpiryRoot(statedb, epoch-1, parent.Root[context])
+	}
+	if epoch < 2 {
+		return
+	}
+	expired := sweepExpiredAccounts(statedb, epoch-2, params.StateExpirySweepLimit)
+	if expired == 0 {
+		return
+	}
+	// Remove the expired accounts before the first transaction of the block
+	if finaliser, ok := statedb.(interface{ Finalise(bool) }); ok {
+		finaliser.Finalise(true)
+	}
+	stateExpiryExpiredMeter.Mark(int64(expired))
+	log.Debug("Swept expired state", "epoch", epoch, "expired", expired, "number", number)
+}
+
+// sweepExpiredAccounts goes through up to limit accounts marked in the epochs
+// not swept yet, up to the last one given, and removes the ones not accessed
+// since. Every epoch done counts against the limit too, so that the epochs
+// without accounts are skipped at a bounded cost. It returns the number of
+// accounts removed.
+func sweepExpiredAccounts(statedb vm.StateDB, last uint64, limit int) int {
+	var (
+		epoch, index = vm.ReadExpirySweep(statedb)
+		start        = epoch
+		startIndex   = index
+		expired      int
+	)
+	for ; limit > 0 && epoch <= last; limit-- {
+		if index >= vm.ReadExpiryEpochCount(statedb, epoch) {
+			epoch, index = epoch+1, 0
+			continue
+		}
+		addr := vm.ReadExpiryEpochAccount(statedb, epoch, index)
+		vm.DeleteExpiryEpochAccount(statedb, epoch, index)
+		index++
+
+		// Accounts accessed again are listed in a later epoch too
+		if accessed, ok := vm.ReadAccessEpoch(statedb, addr); !ok || accessed != epoch {
+			continue
+		}
+		vm.WriteExpired(statedb, addr, epoch+1)
+		statedb.Suicide(addr)
+		expired++
+	}
+	if epoch != start || index != startIndex {
+		vm.WriteExpirySweep(statedb, epoch, index)
+	}
+	return expired
+}
+
+// MarkStateAccess marks the accounts accessed by a block as live in the state
+// expiry epoch of the block. It must run after the last transaction of the
+// block and before the block rewards are paid.
+func MarkStateAccess(config *params.ChainConfig, header *types.Header, statedb *state.StateDB) {
+	number := header.Number[types.QuaiNetworkContext]
+	if !config.IsStateExpiry(number) {
+		return
+	}
+	var (
+		epoch  = config.ExpiryEpoch(number.Uint64())
+		system = stateExpirySystemAccounts(config)
+		marked int
+	)
+	for _, addr := range statedb.AccessedAccounts() {
+		if system[addr] || !statedb.Exist(addr) || vm.IsAccountExpired(statedb, addr) {
+			continue
+		}
+		if last, ok := vm.ReadAccessEpoch(statedb, addr); ok && last == epoch {
+			continue
+		}
+		vm.WriteAccessEpoch(statedb, addr, epoch)
+		marked++
+	}
+	stateExpiryMarkedMeter.Mark(int64(marked))
+}
+
+// stateExpirySystemAccounts returns the accounts the state expiry never
+// removes.
+func stateExpirySystemAccounts(config *params.ChainConfig) map[common.Address]bool {
+	system := map[common.Address]bool{
+		params.StateExpiryAddress:  true,
+		vm.BlockHashHistoryAddress: true,
+	}
+	for _, contract := range config.SystemContracts {
+		system[contract.Address] = true
+	}
+	return system
+}
+
+// checkStateExpiry rejects transactions sent from or to expired accounts and
+// verifies the proof of revival transactions, returning the revived account.
+func checkStateExpiry(config *params.ChainConfig, blockNumber *big.Int, msg types.Message, tx *types.Transaction, statedb *state.StateDB) (*types.StateAccount, error) {
+	addr, proof := tx.Revival()
+	if !config.IsStateExpiry(blockNumber) {
+		if addr != nil {
+			return nil, ErrTxTypeNotSupported
+		}
+		return nil, nil
+	}
+	if vm.IsAccountExpired(statedb, msg.From()) {
+		return nil, fmt.Errorf("%w: sender %v", ErrAccountExpired, msg.From())
+	}
+	if to := msg.To(); to != nil && vm.IsAccountExpired(statedb, *to) {
+		return nil, fmt.Errorf("%w: recipient %v", ErrAccountExpired, *to)
+	}
+	if addr == nil {
+		return nil, nil
+	}
+	epoch, expired := vm.ReadExpiredEpoch(statedb, *addr)
+	if !expired {
+		return nil, fmt.Errorf("%w: account %v not expired", ErrInvalidRevival, *addr)
+	}
+	nodes := memorydb.New()
+	for _, node := range proof {
+		nodes.Put(crypto.Keccak256(node), node)
+	}
+	blob, err := trie.VerifyProof(vm.ReadExpiryRoot(statedb, epoch), crypto.Keccak256(addr.Bytes()), nodes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRevival, err)
+	}
+	if len(blob) == 0 {
+		return nil, fmt.Errorf("%w: account %v not in expiry state", ErrInvalidRevival, *addr)
+	}
+	account := new(types.StateAccount)
+	if err := rlp.DecodeBytes(blob, account); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRevival, err)
+	}
+	return account, nil
+}
+
+// reviveAccount recreates an expired account from its proven state.
+func reviveAccount(statedb *state.StateDB, addr common.Address, account *types.StateAccount) {
+	statedb.ReviveAccount(addr, account)
+	vm.DeleteExpired(statedb, addr)
+	stateExpiryRevivedMeter.Mark(1)
+}
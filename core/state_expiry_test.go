@@ -0,0 +1,168 @@
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// expiryTestChain runs the state expiry of blocks over a state, without any
+// block being assembled.
+type expiryTestChain struct {
+	t      *testing.T
+	config *params.ChainConfig
+	db     state.Database
+
+	parent *types.Header
+	state  *state.StateDB
+}
+
+func newExpiryTestChain(t *testing.T, epochLength uint64) *expiryTestChain {
+	config := *params.TestChainConfig
+	config.StateExpiry = &params.StateExpiryConfig{Block: big.NewInt(0), EpochLength: epochLength}
+
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, db, nil)
+	return &expiryTestChain{t: t, config: &config, db: db, state: statedb}
+}
+
+// block applies the state expiry of the next block, runs the accesses of its
+// transactions, marks them and commits the state.
+func (c *expiryTestChain) block(access func(statedb *state.StateDB)) *types.Header {
+	ctx := types.QuaiNetworkContext
+
+	header := types.NewEmptyHeader()
+	header.Number[ctx] = big.NewInt(0)
+	if c.parent != nil {
+		header.Number[ctx].Add(c.parent.Number[ctx], common.Big1)
+	}
+	ApplyStateExpiry(c.config, c.parent, header, c.state)
+	if access != nil {
+		access(c.state)
+	}
+	MarkStateAccess(c.config, header, c.state)
+
+	root, err := c.state.Commit(true)
+	if err != nil {
+		c.t.Fatalf("block %d: failed to commit state: %v", header.Number[ctx], err)
+	}
+	header.Root[ctx] = root
+	if c.state, err = state.New(root, c.db, nil); err != nil {
+		c.t.Fatalf("block %d: failed to open state: %v", header.Number[ctx], err)
+	}
+	c.parent = header
+	return header
+}
+
+// Tests an account through the state expiry: marked as accessed, swept once
+// idle for an epoch, then revived with a proof against the recorded root.
+func TestStateExpiryRevival(t *testing.T) {
+	var (
+		chain  = newExpiryTestChain(t, 2)
+		alice  = common.HexToAddress("0x1100000000000000000000000000000000000001")
+		bob    = common.HexToAddress("0x1100000000000000000000000000000000000002")
+		sender = common.HexToAddress("0x1100000000000000000000000000000000000003")
+	)
+	// Epoch 0 touches both accounts, epoch 1 bob only
+	chain.block(func(statedb *state.StateDB) {
+		statedb.AddBalance(alice, big.NewInt(100))
+		statedb.AddBalance(bob, big.NewInt(200))
+	})
+	chain.block(nil)
+	chain.block(func(statedb *state.StateDB) { statedb.AddBalance(bob, big.NewInt(1)) })
+	last := chain.block(nil)
+
+	// The first block of epoch 2 records the root of epoch 1 and sweeps alice
+	head := chain.block(nil)
+	if root := vm.ReadExpiryRoot(chain.state, 1); root != last.Root[types.QuaiNetworkContext] {
+		t.Fatalf("expiry root mismatch: have %x, want %x", root, last.Root[types.QuaiNetworkContext])
+	}
+	if chain.state.Exist(alice) || !vm.IsAccountExpired(chain.state, alice) {
+		t.Fatalf("idle account not expired")
+	}
+	if !chain.state.Exist(bob) || vm.IsAccountExpired(chain.state, bob) {
+		t.Fatalf("accessed account expired")
+	}
+	// Expired accounts can't send until revived
+	number := new(big.Int).Add(head.Number[types.QuaiNetworkContext], common.Big1)
+	transfer := types.NewTx(&types.DynamicFeeTx{ChainID: chain.config.ChainID})
+	msg := types.NewMessage(alice, &bob, 0, new(big.Int), 0, new(big.Int), new(big.Int), new(big.Int), nil, nil, true)
+	if _, err := checkStateExpiry(chain.config, number, msg, transfer, chain.state); !errors.Is(err, ErrAccountExpired) {
+		t.Fatalf("expired sender error mismatch: have %v, want %v", err, ErrAccountExpired)
+	}
+	// Proofs against the current state are rejected, the recorded root accepted
+	revive := func(statedb *state.StateDB) (*types.StateAccount, error) {
+		proof, err := statedb.GetProof(alice)
+		if err != nil {
+			t.Fatalf("failed to prove account: %v", err)
+		}
+		tx := types.NewTx(&types.ReviveTx{ChainID: chain.config.ChainID, Account: alice, Proof: proof})
+		msg := types.NewMessage(sender, nil, 0, new(big.Int), 0, new(big.Int), new(big.Int), new(big.Int), nil, nil, true)
+		return checkStateExpiry(chain.config, number, msg, tx, chain.state)
+	}
+	if _, err := revive(chain.state); !errors.Is(err, ErrInvalidRevival) {
+		t.Fatalf("revival against the current state error mismatch: have %v, want %v", err, ErrInvalidRevival)
+	}
+	expiry, err := state.New(vm.ReadExpiryRoot(chain.state, 1), chain.db, nil)
+	if err != nil {
+		t.Fatalf("failed to open expiry state: %v", err)
+	}
+	account, err := revive(expiry)
+	if err != nil {
+		t.Fatalf("revival failed: %v", err)
+	}
+	reviveAccount(chain.state, alice, account)
+	if balance := chain.state.GetBalance(alice); balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("revived balance mismatch: have %v, want 100", balance)
+	}
+	if vm.IsAccountExpired(chain.state, alice) {
+		t.Errorf("revived account still expired")
+	}
+}
+
+// Tests that the sweep stops at the limit and carries the remaining accounts
+// forward to the next blocks, moving on to the next epoch once done.
+func TestStateExpirySweepLimit(t *testing.T) {
+	chain := newExpiryTestChain(t, 4)
+
+	accounts := make([]common.Address, 5)
+	for i := range accounts {
+		accounts[i] = common.BigToAddress(big.NewInt(int64(0x1000 + i)))
+		chain.state.AddBalance(accounts[i], common.Big1)
+	}
+	// Three accounts idle since epoch 0, two since epoch 1
+	for i, addr := range accounts {
+		vm.WriteAccessEpoch(chain.state, addr, uint64(i/3))
+	}
+	for i, want := range []int{2, 1, 0} {
+		if expired := sweepExpiredAccounts(chain.state, 0, 2); expired != want {
+			t.Errorf("epoch 0 sweep %d: expired mismatch: have %d, want %d", i, expired, want)
+		}
+	}
+	if epoch, index := vm.ReadExpirySweep(chain.state); epoch != 1 || index != 0 {
+		t.Errorf("sweep progress mismatch: have %d/%d, want 1/0", epoch, index)
+	}
+	if count := vm.ReadExpiryEpochCount(chain.state, 0); count != 0 {
+		t.Errorf("swept epoch still has %d accounts", count)
+	}
+	// An account accessed again is kept, the others expire
+	vm.WriteAccessEpoch(chain.state, accounts[4], 2)
+	if expired := sweepExpiredAccounts(chain.state, 1, params.StateExpirySweepLimit); expired != 1 {
+		t.Errorf("epoch 1 sweep: expired mismatch: have %d, want 1", expired)
+	}
+	for i, addr := range accounts {
+		if expired := vm.IsAccountExpired(chain.state, addr); expired != (i < 4) {
+			t.Errorf("account %d: expired mismatch: have %v, want %v", i, expired, i < 4)
+		}
+	}
+	if count := vm.ReadExpiryEpochCount(chain.state, 1); count != 0 {
+		t.Errorf("swept epoch still has %d accounts", count)
+	}
+}
@@ -73,6 +73,12 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	blockContext := NewEVMBlockContext(header, p.bc, nil)
 	vmenv := vm.NewEVM(blockContext, vm.TxContext{}, statedb, p.config, cfg)
 
+	var parent *types.Header
+	if systemChangesReadParent(p.config, blockNumber) {
+		parent = p.bc.GetHeader(block.ParentHash(), blockNumber.Uint64()-1)
+	}
+	ApplySystemChanges(p.config, p.engine, parent, header, statedb)
+
 	// Gather external blocks and apply transactions, need to trace own local external block cache based on cache to validate.
 	i := 0
 	externalBlocks, err := p.engine.GetExternalBlocks(p.bc, header, true)
@@ -80,7 +86,7 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		return nil, nil, uint64(0), nil, err
 	}
 
-	etxs := 0
+	var etxs types.Transactions
 	for _, externalBlock := range externalBlocks {
 		externalBlock.Receipts().DeriveFields(p.config, externalBlock.Hash(), externalBlock.Header().Number[externalBlock.Context().Int64()].Uint64(), externalBlock.Transactions())
 
@@ -97,7 +103,7 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 				return nil, nil, 0, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
 			}
 
-			if !msg.FromExternal() || !params.CheckETxChainID(p.config.ChainID, tx.ChainId()) {
+			if !IsApplicableEtx(p.config, msg, tx) {
 				continue
 			}
 			fmt.Println("Applying etx", tx.Hash().Hex(), msg.From(), msg.To(), msg.Value())
@@ -109,11 +115,17 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 			}
 			receipts = append(receipts, receipt)
 			allLogs = append(allLogs, receipt.Logs...)
-			etxs += 1
+			etxs = append(etxs, tx)
 			i++
 		}
 	}
 
+	if p.config.IsEtxRollup(blockNumber) {
+		if err := verifyEtxRollup(header, etxs); err != nil {
+			return nil, nil, 0, nil, err
+		}
+	}
+
 	// Iterate over and process the individual transactions.
 	for _, tx := range block.Transactions() {
 		msg, err := tx.AsMessage(types.MakeSigner(p.config, header.Number[types.QuaiNetworkContext]), header.BaseFee[types.QuaiNetworkContext])
@@ -134,7 +146,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		i++
 	}
 
-	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
+	// Mark the accessed state live, then finalize the block, applying any consensus
+	// engine specific extras (e.g. block rewards)
+	MarkStateAccess(p.config, header, statedb)
 	p.engine.Finalize(p.bc, header, statedb, block.Transactions(), block.Uncles())
 
 	return receipts, allLogs, *usedGas, externalBlocks, nil
@@ -145,12 +159,27 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 		return nil, ErrSenderInoperable
 	}
 
+	// Reject expiring transactions until their fork activates
+	if tx.Type() == types.ExpiringTxType && !config.IsExpiringTx(blockNumber) {
+		return nil, ErrTxTypeNotSupported
+	}
+	// Reject transactions past their validity window
+	if tx.Expired(blockNumber.Uint64()) {
+		return nil, fmt.Errorf("%w: valid until %d, block %d", ErrTxExpired, tx.ValidUntil(), blockNumber)
+	}
+
 	// Validate Address Operability
 	idRange := config.ChainIDRange()
 	if int(msg.From().Bytes()[0]) < idRange[0] || int(msg.From().Bytes()[0]) > idRange[1] {
 		return nil, ErrSenderInoperable
 	}
 
+	// Reject transactions of expired accounts and invalid revivals
+	revived, err := checkStateExpiry(config, blockNumber, msg, tx, statedb)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a new context to be used in the EVM environment.
 	txContext := NewEVMTxContext(msg)
 	evm.Reset(txContext, statedb)
@@ -160,13 +189,17 @@ func applyTransaction(msg types.Message, config *params.ChainConfig, bc ChainCon
 	if err != nil {
 		return nil, err
 	}
+	if revived != nil {
+		addr, _ := tx.Revival()
+		reviveAccount(statedb, *addr, revived)
+	}
 
 	// Update the state with pending changes.
 	var root []byte
 	if config.IsByzantium(blockNumber) {
 		statedb.Finalise(true)
 	} else {
-		root = statedb.IntermediateRoot(config.IsEIP158(blockNumber)).Bytes()
+		root = statedb.IntermediateRoot(config.DeleteEmptyAccounts(blockNumber)).Bytes()
 	}
 	*usedGas += result.UsedGas
 
@@ -244,7 +277,7 @@ func applyExternalTransaction(msg types.Message, config *params.ChainConfig, bc
 	if config.IsByzantium(blockNumber) {
 		statedb.Finalise(true)
 	} else {
-		statedb.IntermediateRoot(config.IsEIP158(blockNumber)).Bytes()
+		statedb.IntermediateRoot(config.DeleteEmptyAccounts(blockNumber)).Bytes()
 	}
 
 	return receipt, nil
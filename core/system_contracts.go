@@ -0,0 +1,50 @@
+package core
+
+import (
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// ApplySystemChanges applies the state changes the chain makes at the start of
+// a block irrespective of its transactions: it deploys the system contracts
+// scheduled at the block, records the parent in the block hash history and
+// sweeps the expired state. It must run before any transaction of the block,
+// external ones included.
+func ApplySystemChanges(config *params.ChainConfig, engine consensus.Engine, parent *types.Header, header *types.Header, statedb vm.StateDB) {
+	ApplySystemContracts(config, header, statedb)
+	ApplyBlockHashHistory(config, engine, parent, header, statedb)
+	ApplyStateExpiry(config, parent, header, statedb)
+}
+
+// systemChangesReadParent reports whether the system changes at a block read
+// its parent header, for the block hash history or at the start of an expiry
+// epoch. Otherwise they are applied with a nil parent.
+func systemChangesReadParent(config *params.ChainConfig, number *big.Int) bool {
+	return config.IsBlockHashWindow(number) || config.IsExpiryEpochStart(number)
+}
+
+// ApplySystemContracts deploys or upgrades the system contracts scheduled at
+// the start of a block.
+func ApplySystemContracts(config *params.ChainConfig, header *types.Header, statedb vm.StateDB) {
+	for _, contract := range config.SystemContractsAt(header.Number[types.QuaiNetworkContext]) {
+		if !statedb.Exist(contract.Address) {
+			statedb.CreateAccount(contract.Address)
+		}
+		// Contracts have a nonce of one, keeping them apart from empty accounts
+		if statedb.GetNonce(contract.Address) == 0 {
+			statedb.SetNonce(contract.Address, 1)
+		}
+		if contract.Code != nil {
+			statedb.SetCode(contract.Address, contract.Code)
+		}
+		for key, value := range contract.Storage {
+			statedb.SetState(contract.Address, key, value)
+		}
+		log.Debug("Deployed system contract", "name", contract.Name, "address", contract.Address, "number", header.Number[types.QuaiNetworkContext])
+	}
+}
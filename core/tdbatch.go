@@ -0,0 +1,158 @@
+package core
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// tdChainReader is the chain access needed to calculate total difficulties.
+type tdChainReader interface {
+	consensus.ChainHeaderReader
+
+	// GetBlockNumber retrieves the block number belonging to the given hash.
+	GetBlockNumber(hash common.Hash) *uint64
+
+	// GetTdByHash retrieves a block's total difficulty by hash.
+	GetTdByHash(hash common.Hash) []*big.Int
+}
+
+// tdBatchReader overlays the headers of a range whose total difficulties were
+// already calculated on the header chain, so the calculation for the rest of
+// the range sees them before anything is written to the database.
+type tdBatchReader struct {
+	*HeaderChain
+	headers map[common.Hash]*types.Header
+	tds     map[common.Hash][]*big.Int
+}
+
+func newTdBatchReader(hc *HeaderChain, size int) *tdBatchReader {
+	return &tdBatchReader{
+		HeaderChain: hc,
+		headers:     make(map[common.Hash]*types.Header, size),
+		tds:         make(map[common.Hash][]*big.Int, size),
+	}
+}
+
+// add makes a header and its total difficulty visible to the calculation.
+func (r *tdBatchReader) add(header *types.Header, td []*big.Int) {
+	hash := header.Hash()
+	r.headers[hash] = header
+	r.tds[hash] = td
+}
+
+func (r *tdBatchReader) GetBlockNumber(hash common.Hash) *uint64 {
+	if header, ok := r.headers[hash]; ok {
+		number := header.Number[types.QuaiNetworkContext].Uint64()
+		return &number
+	}
+	return r.HeaderChain.GetBlockNumber(hash)
+}
+
+func (r *tdBatchReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header, ok := r.headers[hash]; ok {
+		return header
+	}
+	return r.HeaderChain.GetHeader(hash, number)
+}
+
+func (r *tdBatchReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	if header, ok := r.headers[hash]; ok {
+		return header
+	}
+	return r.HeaderChain.GetHeaderByHash(hash)
+}
+
+func (r *tdBatchReader) GetTdByHash(hash common.Hash) []*big.Int {
+	if td, ok := r.tds[hash]; ok {
+		return td
+	}
+	return r.HeaderChain.GetTdByHash(hash)
+}
+
+// CalcTdBatch calculates the TDs of a contiguous range of headers, as CalcTd
+// would once the preceding headers of the range are imported. Zone order
+// headers extending their parent in the range inherit its prime terminus and
+// net difficulty instead of walking the chain back to the terminus again, and
+// the common total difficulty of every terminus is only looked up once.
+func (hc *HeaderChain) CalcTdBatch(headers []*types.Header) ([][]*big.Int, error) {
+	var (
+		chain   = newTdBatchReader(hc, len(headers))
+		tds     = make([][]*big.Int, len(headers))
+		offsets = make(map[common.Hash]*big.Int) // Common total difficulties of the termini
+
+		terminal *types.Header // Prime terminus of the previous header
+		nd       []*big.Int    // Net difficulty of the previous header from its terminus
+	)
+	for i, header := range headers {
+		var inherit bool
+		if i > 0 && terminal != nil {
+			parent := headers[i-1]
+			inherit = header.ParentHash[params.ZONE] == parent.Hash() && bytes.Equal(header.Location, parent.Location) && header.Number[params.ZONE].Cmp(common.Big1) > 0
+		}
+		if inherit {
+			// The parent is the terminus if it's prime coincident in the slice
+			parent := headers[i-1]
+			parentOrder, err := hc.engine.GetDifficultyOrder(parent)
+			if err != nil {
+				return nil, err
+			}
+			if parentOrder <= params.PRIME {
+				terminal, nd = parent, addNetDifficulty(nil, parent, parentOrder)
+			}
+			order, err := hc.engine.GetDifficultyOrder(header)
+			if err != nil {
+				return nil, err
+			}
+			// Only zone order headers step back to the parent, others jump along
+			// the dominant chains and have to be walked
+			if order == params.ZONE {
+				nd = addNetDifficulty(nd, header, order)
+			} else if nd, err = hc.calcHLCRNetDifficulty(chain, terminal.Hash(), header); err != nil {
+				return nil, err
+			}
+		} else {
+			var err error
+			if terminal, err = hc.engine.PreviousCoincidentOnPath(chain, header, header.Location, params.PRIME, params.ZONE, true); err != nil {
+				return nil, err
+			}
+			if nd, err = hc.calcHLCRNetDifficulty(chain, terminal.Hash(), header); err != nil {
+				return nil, err
+			}
+		}
+		k, ok := offsets[terminal.Hash()]
+		if !ok {
+			var err error
+			if k, err = hc.tdOffset(chain, terminal); err != nil {
+				return nil, err
+			}
+			offsets[terminal.Hash()] = k
+		}
+		tds[i] = []*big.Int{
+			new(big.Int).Add(nd[0], k),
+			new(big.Int).Add(nd[1], k),
+			new(big.Int).Add(nd[2], k),
+		}
+		chain.add(header, tds[i])
+	}
+	return tds, nil
+}
+
+// addNetDifficulty returns the net difficulty tuple extended by a header of the
+// given order, leaving the original untouched.
+func addNetDifficulty(nd []*big.Int, header *types.Header, order int) []*big.Int {
+	extended := []*big.Int{new(big.Int), new(big.Int), new(big.Int)}
+	if nd != nil {
+		for i := range extended {
+			extended[i].Set(nd[i])
+		}
+	}
+	for i := order; i <= params.ZONE; i++ {
+		extended[i].Add(extended[i], header.Difficulty[order])
+	}
+	return extended
+}
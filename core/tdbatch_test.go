@@ -0,0 +1,133 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/consensus/blake3"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// tdTestEngine assigns the difficulty orders of the headers from a table and
+// walks the coincident paths through the local chain.
+type tdTestEngine struct {
+	*blake3.Blake3
+	orders map[common.Hash]int
+}
+
+func (e *tdTestEngine) GetDifficultyOrder(header *types.Header) (int, error) {
+	if order, ok := e.orders[header.Hash()]; ok {
+		return order, nil
+	}
+	return types.QuaiNetworkContext, nil
+}
+
+func (e *tdTestEngine) PreviousCoincidentOnPath(chain consensus.ChainHeaderReader, header *types.Header, slice []byte, order, path int, fullSliceEqual bool) (*types.Header, error) {
+	for {
+		if header.Number[path].Cmp(common.Big1) <= 0 {
+			return chain.GetHeaderByHash(chain.Config().GenesisHashes[0]), nil
+		}
+		if header = chain.GetHeaderByHash(header.ParentHash[path]); header == nil {
+			return nil, consensus.ErrSliceNotSynced
+		}
+		if headerOrder, _ := e.GetDifficultyOrder(header); headerOrder <= order {
+			return header, nil
+		}
+	}
+}
+
+// newTdTestHeaders creates a chain of headers of the given difficulty orders on
+// top of the genesis, each pointing in every context to the last ancestor of
+// that context.
+func newTdTestHeaders(engine *tdTestEngine, genesis *types.Header, orders []int) []*types.Header {
+	var (
+		headers = make([]*types.Header, len(orders))
+		last    = []*types.Header{genesis, genesis, genesis}
+	)
+	for i, order := range orders {
+		header := types.NewEmptyHeader()
+		for ctx := range header.Number {
+			header.ParentHash[ctx] = last[ctx].Hash()
+			header.Number[ctx] = new(big.Int).Add(last[ctx].Number[ctx], common.Big1)
+			header.Difficulty[ctx] = big.NewInt(int64(1000*(types.ContextDepth-ctx) + i))
+		}
+		header.Location = []byte{1, 1}
+		engine.orders[header.Hash()] = order
+
+		for ctx := order; ctx < types.ContextDepth; ctx++ {
+			last[ctx] = header
+		}
+		headers[i] = header
+	}
+	return headers
+}
+
+// testTdBatch checks that the batch TDs of the headers from start on match the
+// TDs CalcTd assigns them one by one, once the preceding headers are imported.
+func testTdBatch(t *testing.T, context int, orders []int, start int) {
+	defer func(context int) { types.QuaiNetworkContext = context }(types.QuaiNetworkContext)
+	types.QuaiNetworkContext = context
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		genesis = newNetworkGenesis(0, 0).MustCommit(db)
+		engine  = &tdTestEngine{Blake3: blake3.NewFaker(), orders: make(map[common.Hash]int)}
+		config  = *params.TestChainConfig
+	)
+	config.GenesisHashes = []common.Hash{genesis.Hash(), genesis.Hash(), genesis.Hash()}
+
+	hc, err := NewHeaderChain(db, &config, engine, func() bool { return false })
+	if err != nil {
+		t.Fatalf("failed to create header chain: %v", err)
+	}
+	headers := newTdTestHeaders(engine, genesis.Header(), orders)
+
+	importHeader := func(header *types.Header) []*big.Int {
+		td, err := hc.CalcTd(header)
+		if err != nil {
+			t.Fatalf("failed to calculate td of header %d: %v", header.Number[context], err)
+		}
+		rawdb.WriteHeader(db, header)
+		rawdb.WriteTd(db, header.Hash(), header.Number[context].Uint64(), td)
+		return td
+	}
+	for _, header := range headers[:start] {
+		importHeader(header)
+	}
+	tds, err := hc.CalcTdBatch(headers[start:])
+	if err != nil {
+		t.Fatalf("failed to calculate batch tds: %v", err)
+	}
+	for i, header := range headers[start:] {
+		want := importHeader(header)
+		for ctx := range want {
+			if tds[i][ctx].Cmp(want[ctx]) != 0 {
+				t.Fatalf("header %d (order %d): td mismatch: have %v, want %v", start+i, orders[start+i], tds[i], want)
+			}
+		}
+	}
+}
+
+// Tests that the batch TDs of a zone range spanning prime and region
+// coincident blocks match the ones of a sequential import.
+func TestTdBatchZone(t *testing.T) {
+	orders := []int{2, 2, 0, 2, 1, 2, 2, 0, 1, 2, 2, 2, 0, 0, 2, 1, 2, 2}
+
+	t.Run("genesis", func(t *testing.T) { testTdBatch(t, params.ZONE, orders, 0) })
+	t.Run("terminus", func(t *testing.T) { testTdBatch(t, params.ZONE, orders, 3) })
+	t.Run("mid-terminus", func(t *testing.T) { testTdBatch(t, params.ZONE, orders, 5) })
+	t.Run("coincident", func(t *testing.T) { testTdBatch(t, params.ZONE, orders, 12) })
+}
+
+// Tests that the batch TDs of a region range, whose blocks are all region or
+// prime coincident, match the ones of a sequential import.
+func TestTdBatchRegion(t *testing.T) {
+	orders := []int{1, 1, 0, 1, 1, 1, 0, 0, 1, 1}
+
+	t.Run("genesis", func(t *testing.T) { testTdBatch(t, params.REGION, orders, 0) })
+	t.Run("mid-terminus", func(t *testing.T) { testTdBatch(t, params.REGION, orders, 4) })
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+)
+
+// tdRepairBatch is the number of consecutive headers whose total difficulties
+// are recomputed at once.
+const tdRepairBatch = 1024
+
+// TdIssue is a canonical block whose stored total difficulty is unusable.
+type TdIssue struct {
+	Number uint64
+	Hash   common.Hash
+	Err    error // rawdb.ErrTdMissing or rawdb.ErrTdMalformed
+}
+
+// CheckTd walks the canonical chain from first to last, returning the blocks
+// whose total difficulty is missing or malformed.
+func (hc *HeaderChain) CheckTd(first, last uint64) ([]TdIssue, error) {
+	var (
+		issues []TdIssue
+		logged = time.Now()
+	)
+	for number := first; number <= last; number++ {
+		hash := rawdb.ReadCanonicalHash(hc.chainDb, number)
+		if hash == (common.Hash{}) {
+			return issues, fmt.Errorf("canonical block #%d missing", number)
+		}
+		if err := rawdb.CheckTd(hc.chainDb, hash, number); err != nil {
+			issues = append(issues, TdIssue{Number: number, Hash: hash, Err: err})
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Checking total difficulties", "number", number, "last", last, "issues", len(issues))
+			logged = time.Now()
+		}
+	}
+	return issues, nil
+}
+
+// RepairTd recomputes the missing or malformed total difficulties of the
+// canonical blocks from first to last from their ancestors, returning the
+// number of blocks repaired. Side chain blocks are left untouched.
+func (hc *HeaderChain) RepairTd(first, last uint64) (int, error) {
+	issues, err := hc.CheckTd(first, last)
+	if err != nil {
+		return 0, err
+	}
+	var repaired int
+	for len(issues) > 0 {
+		// Gather the next run of consecutive broken blocks, its parent being
+		// either intact or repaired already
+		run := []*types.Header{}
+		for len(issues) > 0 && len(run) < tdRepairBatch {
+			if len(run) > 0 && issues[0].Number != run[len(run)-1].Number[types.QuaiNetworkContext].Uint64()+1 {
+				break
+			}
+			header := hc.GetHeader(issues[0].Hash, issues[0].Number)
+			if header == nil {
+				return repaired, fmt.Errorf("header #%d [%x..] missing", issues[0].Number, issues[0].Hash.Bytes()[:4])
+			}
+			run, issues = append(run, header), issues[1:]
+		}
+		var tds [][]*big.Int
+		if number := run[0].Number[types.QuaiNetworkContext].Uint64(); number == 0 {
+			// The genesis total difficulty is its difficulty
+			tds = append(tds, run[0].Difficulty)
+			if len(run) > 1 {
+				rest, err := hc.CalcTdBatch(run[1:])
+				if err != nil {
+					return repaired, err
+				}
+				tds = append(tds, rest...)
+			}
+		} else if tds, err = hc.CalcTdBatch(run); err != nil {
+			return repaired, fmt.Errorf("failed to recompute total difficulty from #%d: %v", number, err)
+		}
+		batch := hc.chainDb.NewBatch()
+		for i, header := range run {
+			hash, number := header.Hash(), header.Number[types.QuaiNetworkContext].Uint64()
+			rawdb.WriteTd(batch, hash, number, tds[i])
+			hc.tdCache.Remove(hash)
+			log.Debug("Repaired total difficulty", "number", number, "hash", hash, "td", tds[i])
+		}
+		if err := batch.Write(); err != nil {
+			return repaired, err
+		}
+		repaired += len(run)
+		log.Info("Repaired total difficulties", "first", run[0].Number[types.QuaiNetworkContext], "count", len(run), "total", repaired)
+	}
+	return repaired, nil
+}
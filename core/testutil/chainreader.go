@@ -0,0 +1,398 @@
+// Package testutil provides scriptable test doubles of the chain, so the fork
+// choice and the consensus code can be exercised deterministically, without
+// assembling real hierarchies of Prime, Region and Zone chains.
+//
+// The package doesn't depend on core, so the tests of core can use it too.
+package testutil
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// pcrcOutcome is the scripted outcome of a PCRC.
+type pcrcOutcome struct {
+	termini types.PCRCTermini
+	err     error
+}
+
+// domCanonicalOutcome is the scripted canonical status of a coincident block
+// in the dominant chain.
+type domCanonicalOutcome struct {
+	canonical bool
+	err       error
+}
+
+// domReorgOutcome is the scripted answer of the dominant chain to a reorg.
+type domReorgOutcome struct {
+	reorg bool
+	err   error
+}
+
+// ChainReader is an in-memory chain whose total difficulties, PCRC outcomes,
+// difficulty orders and dominant reorg decisions and canonical statuses are
+// scripted per block hash.
+// It implements core.ChainReader and consensus.ChainReader.
+//
+// Queries for a block without a scripted answer fail, so a test notices paths
+// it didn't expect to be taken.
+type ChainReader struct {
+	config *params.ChainConfig
+
+	headers   map[common.Hash]*types.Header
+	blocks    map[common.Hash]*types.Block
+	canonical map[uint64]common.Hash
+	current   *types.Header
+
+	tds      map[common.Hash][]*big.Int
+	orders   map[common.Hash]int
+	pcrcs    map[common.Hash]pcrcOutcome
+	domReorg map[common.Hash]domReorgOutcome
+	domCanon map[common.Hash]domCanonicalOutcome
+
+	lock sync.RWMutex
+}
+
+var _ consensus.ChainReader = (*ChainReader)(nil)
+
+// NewChainReader creates an empty chain of the configuration, defaulting to
+// params.TestChainConfig.
+func NewChainReader(config *params.ChainConfig) *ChainReader {
+	if config == nil {
+		config = params.TestChainConfig
+	}
+	return &ChainReader{
+		config:    config,
+		headers:   make(map[common.Hash]*types.Header),
+		blocks:    make(map[common.Hash]*types.Block),
+		canonical: make(map[uint64]common.Hash),
+		tds:       make(map[common.Hash][]*big.Int),
+		orders:    make(map[common.Hash]int),
+		pcrcs:     make(map[common.Hash]pcrcOutcome),
+		domReorg:  make(map[common.Hash]domReorgOutcome),
+		domCanon:  make(map[common.Hash]domCanonicalOutcome),
+	}
+}
+
+// number returns the number of the header in the running context.
+func number(header *types.Header) uint64 {
+	return header.Number[types.QuaiNetworkContext].Uint64()
+}
+
+// AddHeader adds the header to the chain without making it canonical.
+func (cr *ChainReader) AddHeader(header *types.Header) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	cr.headers[header.Hash()] = header
+}
+
+// AddBlock adds the block and its header to the chain without making it
+// canonical.
+func (cr *ChainReader) AddBlock(block *types.Block) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	cr.blocks[block.Hash()] = block
+	cr.headers[block.Hash()] = block.Header()
+}
+
+// SetHead adds the header and makes it the current head, marking it and its
+// known ancestors canonical.
+func (cr *ChainReader) SetHead(header *types.Header) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	cr.current = header
+	for header != nil {
+		hash := header.Hash()
+		cr.headers[hash] = header
+		cr.canonical[number(header)] = hash
+		if number(header) == 0 {
+			break
+		}
+		header = cr.headers[header.ParentHash[types.QuaiNetworkContext]]
+	}
+}
+
+// SetTd scripts the total difficulty of the block, returned by both GetTd and
+// CalcTd.
+func (cr *ChainReader) SetTd(hash common.Hash, td []*big.Int) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	cr.tds[hash] = td
+}
+
+// SetDifficultyOrder scripts the difficulty order of the block.
+func (cr *ChainReader) SetDifficultyOrder(hash common.Hash, order int) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	cr.orders[hash] = order
+}
+
+// SetPCRC scripts the outcome of the PCRC of the block, at any order.
+func (cr *ChainReader) SetPCRC(hash common.Hash, termini types.PCRCTermini, err error) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	cr.pcrcs[hash] = pcrcOutcome{termini: termini, err: err}
+}
+
+// SetDomReorg scripts the answer of the dominant chain to a reorg to the block.
+func (cr *ChainReader) SetDomReorg(hash common.Hash, reorg bool, err error) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	cr.domReorg[hash] = domReorgOutcome{reorg: reorg, err: err}
+}
+
+// SetDomCanonical scripts the canonical status of the block in the dominant
+// chain.
+func (cr *ChainReader) SetDomCanonical(hash common.Hash, canonical bool, err error) {
+	cr.lock.Lock()
+	defer cr.lock.Unlock()
+
+	cr.domCanon[hash] = domCanonicalOutcome{canonical: canonical, err: err}
+}
+
+// Config returns the chain configuration.
+func (cr *ChainReader) Config() *params.ChainConfig {
+	return cr.config
+}
+
+// CurrentHeader returns the head set by SetHead.
+func (cr *ChainReader) CurrentHeader() *types.Header {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	return cr.current
+}
+
+// GetHeader returns the header of the hash, if its number matches.
+func (cr *ChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	header := cr.GetHeaderByHash(hash)
+	if header == nil || header.Number[types.QuaiNetworkContext].Uint64() != number {
+		return nil
+	}
+	return header
+}
+
+// GetHeaderByHash returns the header of the hash.
+func (cr *ChainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	return cr.headers[hash]
+}
+
+// GetHeaderByNumber returns the canonical header of the number.
+func (cr *ChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	hash, ok := cr.canonical[number]
+	if !ok {
+		return nil
+	}
+	return cr.headers[hash]
+}
+
+// GetBlock returns the block of the hash, if its number matches.
+func (cr *ChainReader) GetBlock(hash common.Hash, number uint64) *types.Block {
+	block := cr.GetBlockByHash(hash)
+	if block == nil || block.NumberU64() != number {
+		return nil
+	}
+	return block
+}
+
+// GetBlockByHash returns the block of the hash.
+func (cr *ChainReader) GetBlockByHash(hash common.Hash) *types.Block {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	return cr.blocks[hash]
+}
+
+// GetTd returns the scripted total difficulty of the block, nil if there is
+// none.
+func (cr *ChainReader) GetTd(hash common.Hash, number uint64) []*big.Int {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	return copyTd(cr.tds[hash])
+}
+
+// CalcTd returns the scripted total difficulty of the header.
+func (cr *ChainReader) CalcTd(header *types.Header) ([]*big.Int, error) {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	td, ok := cr.tds[header.Hash()]
+	if !ok {
+		return nil, fmt.Errorf("no td scripted for %x", header.Hash())
+	}
+	return copyTd(td), nil
+}
+
+// CalcTdBatch returns the scripted total difficulties of the headers.
+func (cr *ChainReader) CalcTdBatch(headers []*types.Header) ([][]*big.Int, error) {
+	tds := make([][]*big.Int, len(headers))
+	for i, header := range headers {
+		td, err := cr.CalcTd(header)
+		if err != nil {
+			return nil, err
+		}
+		tds[i] = td
+	}
+	return tds, nil
+}
+
+// copyTd copies the total difficulty, so callers adding to it don't change the
+// script.
+func copyTd(td []*big.Int) []*big.Int {
+	if td == nil {
+		return nil
+	}
+	cpy := make([]*big.Int, len(td))
+	for i, d := range td {
+		if d != nil {
+			cpy[i] = new(big.Int).Set(d)
+		}
+	}
+	return cpy
+}
+
+// HLCR does hierarchical comparison of two difficulty tuples and returns true
+// if second tuple is greater than the first, like the blockchain.
+func (cr *ChainReader) HLCR(localDifficulties []*big.Int, externDifficulties []*big.Int) bool {
+	if len(localDifficulties) == 0 || len(externDifficulties) == 0 {
+		return false
+	}
+	for i := 0; i < types.ContextDepth; i++ {
+		if cmp := localDifficulties[i].Cmp(externDifficulties[i]); cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return false
+}
+
+// DomReorgNeeded returns the scripted answer of the dominant chain.
+func (cr *ChainReader) DomReorgNeeded(header *types.Header) (bool, error) {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	outcome, ok := cr.domReorg[header.Hash()]
+	if !ok {
+		return false, fmt.Errorf("no dom reorg scripted for %x", header.Hash())
+	}
+	return outcome.reorg, outcome.err
+}
+
+// DomCanonical returns the scripted canonical status of the header in the
+// dominant chain.
+func (cr *ChainReader) DomCanonical(header *types.Header) (bool, error) {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	outcome, ok := cr.domCanon[header.Hash()]
+	if !ok {
+		return false, fmt.Errorf("no dom canonical status scripted for %x", header.Hash())
+	}
+	return outcome.canonical, outcome.err
+}
+
+// PCRC returns the scripted outcome of the PCRC of the header.
+func (cr *ChainReader) PCRC(header *types.Header, headerOrder int) (types.PCRCTermini, error) {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	outcome, ok := cr.pcrcs[header.Hash()]
+	if !ok {
+		return types.PCRCTermini{}, fmt.Errorf("no PCRC scripted for %x", header.Hash())
+	}
+	return outcome.termini, outcome.err
+}
+
+// PCCRC returns the scripted outcome of the PCRC of the header.
+func (cr *ChainReader) PCCRC(header *types.Header, headerOrder int) (types.PCRCTermini, error) {
+	return cr.PCRC(header, headerOrder)
+}
+
+// GetDifficultyOrder returns the scripted difficulty order of the header.
+func (cr *ChainReader) GetDifficultyOrder(header *types.Header) (int, error) {
+	cr.lock.RLock()
+	defer cr.lock.RUnlock()
+
+	order, ok := cr.orders[header.Hash()]
+	if !ok {
+		return 0, fmt.Errorf("no difficulty order scripted for %x", header.Hash())
+	}
+	return order, nil
+}
+
+func (cr *ChainReader) GetExternalBlock(hash common.Hash, location []byte, context uint64) (*types.ExternalBlock, error) {
+	return nil, errors.New("no external blocks")
+}
+func (cr *ChainReader) GetExternalBlocks(header *types.Header) ([]*types.ExternalBlock, error) {
+	return nil, nil
+}
+func (cr *ChainReader) GetLinkExternalBlocks(header *types.Header) ([]*types.ExternalBlock, error) {
+	return nil, nil
+}
+func (cr *ChainReader) QueueAndRetrieveExtBlocks(blocks []*types.ExternalBlock, header *types.Header) []*types.ExternalBlock {
+	return nil
+}
+func (cr *ChainReader) GetUnclesInChain(block *types.Block, length int) []*types.Header { return nil }
+func (cr *ChainReader) GetGasUsedInChain(block *types.Block, length int) int64          { return 0 }
+
+// CheckContext checks to make sure the range of a context or order is valid
+func (cr *ChainReader) CheckContext(context int) error {
+	if context < 0 || context > len(params.FullerOntology) {
+		return errors.New("the provided path is outside the allowable range")
+	}
+	return nil
+}
+
+// CheckLocationRange checks to make sure the range of r and z are valid
+func (cr *ChainReader) CheckLocationRange(location []byte) error {
+	if int(location[0]) < 1 || int(location[0]) > params.FullerOntology[0] {
+		return errors.New("the provided location is outside the allowable region range")
+	}
+	if int(location[1]) < 1 || int(location[1]) > params.FullerOntology[1] {
+		return errors.New("the provided location is outside the allowable zone range")
+	}
+	return nil
+}
+
+// MakeHeaders creates n linked headers of the running context on top of the
+// parent, or from genesis if the parent is nil. The seed tells apart headers of
+// competing forks at the same numbers.
+func MakeHeaders(parent *types.Header, n int, seed uint64) []*types.Header {
+	headers := make([]*types.Header, n)
+	for i := range headers {
+		header := types.NewEmptyHeader()
+		for ctx := 0; ctx < types.ContextDepth; ctx++ {
+			header.Number[ctx] = new(big.Int)
+			header.Difficulty[ctx] = big.NewInt(1)
+		}
+		if parent != nil {
+			header.ParentHash[types.QuaiNetworkContext] = parent.Hash()
+			header.Number[types.QuaiNetworkContext].Add(parent.Number[types.QuaiNetworkContext], common.Big1)
+			header.Time = parent.Time + 1
+		}
+		header.Location = []byte{1, 1}
+		header.Extra[types.QuaiNetworkContext] = new(big.Int).SetUint64(seed).Bytes()
+		headers[i], parent = header, header
+	}
+	return headers
+}
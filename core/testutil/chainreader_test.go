@@ -0,0 +1,84 @@
+package testutil_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/testutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+)
+
+var _ core.ChainReader = (*testutil.ChainReader)(nil)
+
+func td(prime, region, zone int64) []*big.Int {
+	return []*big.Int{big.NewInt(prime), big.NewInt(region), big.NewInt(zone)}
+}
+
+func TestForkChoiceHLCR(t *testing.T) {
+	chain := testutil.NewChainReader(nil)
+	genesis := testutil.MakeHeaders(nil, 1, 0)[0]
+	local := testutil.MakeHeaders(genesis, 2, 1)
+	extern := testutil.MakeHeaders(genesis, 2, 2)
+	chain.SetHead(local[1])
+
+	tests := []struct {
+		local, extern []*big.Int
+		reorg         bool
+	}{
+		{td(1, 5, 9), td(2, 0, 0), true},  // Prime difficulty dominates
+		{td(2, 0, 0), td(1, 5, 9), false}, // even when behind in lower contexts
+		{td(1, 5, 9), td(1, 6, 0), true},  // Region decides on equal Prime
+		{td(1, 5, 9), td(1, 5, 8), false}, // Zone decides on equal Prime and Region
+	}
+	for i, tt := range tests {
+		chain.SetTd(local[1].Hash(), tt.local)
+		chain.SetTd(extern[1].Hash(), tt.extern)
+
+		forker := core.NewForkChoice(chain, nil, core.TiebreakKeepLocal, 0, 0)
+		reorg, err := forker.ReorgNeeded(local[1], extern[1])
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %v", i, err)
+		}
+		if reorg != tt.reorg {
+			t.Errorf("test %d: reorg mismatch: have %v, want %v", i, reorg, tt.reorg)
+		}
+	}
+}
+
+func TestForkChoiceMissingTd(t *testing.T) {
+	chain := testutil.NewChainReader(nil)
+	headers := testutil.MakeHeaders(nil, 2, 0)
+	chain.SetHead(headers[0])
+	chain.SetTd(headers[0].Hash(), td(1, 1, 1))
+
+	forker := core.NewForkChoice(chain, nil, core.TiebreakKeepLocal, 0, 0)
+	if _, err := forker.ReorgNeeded(headers[0], headers[1]); err == nil {
+		t.Fatal("reorg to a header without td succeeded")
+	}
+}
+
+func TestForkChoiceUntwistAndTrim(t *testing.T) {
+	chain := testutil.NewChainReader(nil)
+	header := testutil.MakeHeaders(nil, 1, 0)[0]
+	chain.SetDifficultyOrder(header.Hash(), 2)
+	forker := core.NewForkChoice(chain, nil, core.TiebreakKeepLocal, 0, 0)
+
+	errTwisted := errors.New("twisted")
+	tests := []struct {
+		err, want error
+	}{
+		{nil, nil},
+		{consensus.ErrSliceNotSynced, nil},
+		{consensus.ErrNonCanonicalDom, nil},
+		{errTwisted, errTwisted},
+	}
+	for i, tt := range tests {
+		chain.SetPCRC(header.Hash(), types.PCRCTermini{}, tt.err)
+		if err := forker.UntwistAndTrim(header); err != tt.want {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package twistindex records the subordinate blocks rejected by the Previous
+// Coincident Reference Check (PCRC) because the chains of the contexts along
+// their paths are twisted, along with the reason of the rejection, for the
+// study of the merged-mining hierarchy.
+package twistindex
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/rlp"
+)
+
+// twistsToKeep is the number of twists kept in the index, older ones being
+// dropped.
+const twistsToKeep = 4096
+
+// Stages of the chain the twist was detected at.
+const (
+	StageVerify     = "verify"     // header verification
+	StageImport     = "import"     // PCRC on block import
+	StageForkChoice = "forkchoice" // PCCRC untwisting the fork choice
+)
+
+// Reasons of the rejection, naming the context whose coincident blocks
+// mismatch.
+const (
+	ReasonRegion = "region"
+	ReasonPrime  = "prime"
+)
+
+var (
+	twistHeadKey    = []byte("TwistHead")  // twistHeadKey tracks the sequence number of the next twist
+	twistPrefix     = []byte("Twist-")     // twistPrefix + seq (uint64 big endian) -> twist
+	twistHashPrefix = []byte("TwistHash-") // twistHashPrefix + hash -> seq (uint64 big endian)
+
+	twistMeter = metrics.NewRegisteredMeter("chain/twists", nil)
+)
+
+// Twist is a subordinate block rejected for twisting the chains of the
+// dominant contexts.
+type Twist struct {
+	Hash     common.Hash
+	Number   []*big.Int // Numbers of the header in every context
+	Location []byte
+	Order    uint64 // Order of the header's difficulty
+	Context  uint64 // Context of the chain rejecting the block
+	Stage    string
+	Reason   string
+	Error    string
+	Time     uint64 // Time the twist was recorded at
+}
+
+// Index is a bounded, persistent index of the twists detected by the chain,
+// each block being recorded once.
+type Index struct {
+	db   ethdb.KeyValueStore
+	lock sync.Mutex
+}
+
+// New creates the twist index stored in the database.
+func New(db ethdb.KeyValueStore) *Index {
+	return &Index{db: db}
+}
+
+func encodeSeq(seq uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, seq)
+	return enc
+}
+
+func twistKey(seq uint64) []byte {
+	return append(append([]byte{}, twistPrefix...), encodeSeq(seq)...)
+}
+
+func twistHashKey(hash common.Hash) []byte {
+	return append(append([]byte{}, twistHashPrefix...), hash.Bytes()...)
+}
+
+// head retrieves the sequence number of the next twist.
+func (idx *Index) head() uint64 {
+	data, _ := idx.db.Get(twistHeadKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// read retrieves the twist of the sequence number, nil if it's not indexed.
+func (idx *Index) read(seq uint64) *Twist {
+	blob, err := idx.db.Get(twistKey(seq))
+	if err != nil {
+		return nil
+	}
+	twist := new(Twist)
+	if err := rlp.DecodeBytes(blob, twist); err != nil {
+		log.Error("Invalid twist RLP", "seq", seq, "err", err)
+		return nil
+	}
+	return twist
+}
+
+// Add records the rejection of the header at the stage, returning whether it
+// was recorded, which it's not if the header already was. The oldest twist is
+// dropped if the index is full.
+func (idx *Index) Add(header *types.Header, order int, stage, reason string, err error) bool {
+	twist := &Twist{
+		Hash:     header.Hash(),
+		Number:   header.Number,
+		Location: header.Location,
+		Order:    uint64(order),
+		Context:  uint64(types.QuaiNetworkContext),
+		Stage:    stage,
+		Reason:   reason,
+		Error:    err.Error(),
+		Time:     uint64(time.Now().Unix()),
+	}
+	data, err := rlp.EncodeToBytes(twist)
+	if err != nil {
+		log.Error("Failed to encode twist", "hash", twist.Hash, "err", err)
+		return false
+	}
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if ok, _ := idx.db.Has(twistHashKey(twist.Hash)); ok {
+		return false
+	}
+	var (
+		seq   = idx.head()
+		batch = idx.db.NewBatch()
+	)
+	batch.Put(twistKey(seq), data)
+	batch.Put(twistHashKey(twist.Hash), encodeSeq(seq))
+	if seq >= twistsToKeep {
+		if old := idx.read(seq - twistsToKeep); old != nil {
+			batch.Delete(twistHashKey(old.Hash))
+		}
+		batch.Delete(twistKey(seq - twistsToKeep))
+	}
+	batch.Put(twistHeadKey, encodeSeq(seq+1))
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write twist", "hash", twist.Hash, "err", err)
+		return false
+	}
+	twistMeter.Mark(1)
+	return true
+}
+
+// Get retrieves the twist of the block hash, nil if it's not indexed.
+func (idx *Index) Get(hash common.Hash) *Twist {
+	data, err := idx.db.Get(twistHashKey(hash))
+	if err != nil || len(data) != 8 {
+		return nil
+	}
+	return idx.read(binary.BigEndian.Uint64(data))
+}
+
+// Twists retrieves the most recent twists, newest first, at most limit if it's
+// non-zero. Only the twists detected at the stage are returned if it's given.
+func (idx *Index) Twists(limit int, stage string) []*Twist {
+	var twists []*Twist
+	for seq := idx.head(); seq > 0; seq-- {
+		if limit > 0 && len(twists) >= limit {
+			break
+		}
+		twist := idx.read(seq - 1)
+		if twist == nil {
+			break
+		}
+		if stage == "" || twist.Stage == stage {
+			twists = append(twists, twist)
+		}
+	}
+	return twists
+}
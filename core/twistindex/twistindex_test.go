@@ -0,0 +1,86 @@
+package twistindex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethdb/memorydb"
+)
+
+var errTestTwist = errors.New("there exists a twist in prime")
+
+func testHeader(number int64) *types.Header {
+	return &types.Header{
+		ParentHash:        make([]common.Hash, 3),
+		Number:            []*big.Int{big.NewInt(number), big.NewInt(number), big.NewInt(number)},
+		Extra:             make([][]byte, 3),
+		BaseFee:           []*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(0)},
+		GasLimit:          make([]uint64, 3),
+		Coinbase:          make([]common.Address, 3),
+		Difficulty:        []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)},
+		NetworkDifficulty: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)},
+		Root:              make([]common.Hash, 3),
+		TxHash:            make([]common.Hash, 3),
+		ReceiptHash:       make([]common.Hash, 3),
+		UncleHash:         make([]common.Hash, 3),
+		GasUsed:           make([]uint64, 3),
+		Bloom:             make([]types.Bloom, 3),
+		Location:          []byte{1, 1},
+	}
+}
+
+// Tests that twists are recorded once, retrieved newest first and filtered by
+// stage.
+func TestIndexTwists(t *testing.T) {
+	idx := New(memorydb.New())
+
+	first, second := testHeader(1), testHeader(2)
+	if !idx.Add(first, 1, StageVerify, ReasonPrime, errTestTwist) {
+		t.Fatal("failed to record twist")
+	}
+	if idx.Add(first, 1, StageImport, ReasonPrime, errTestTwist) {
+		t.Fatal("twist recorded twice")
+	}
+	if !idx.Add(second, 0, StageImport, ReasonRegion, errTestTwist) {
+		t.Fatal("failed to record second twist")
+	}
+	twists := idx.Twists(0, "")
+	if len(twists) != 2 || twists[0].Hash != second.Hash() || twists[1].Hash != first.Hash() {
+		t.Fatalf("twists mismatch: have %d, want newest first", len(twists))
+	}
+	if twist := twists[1]; twist.Stage != StageVerify || twist.Reason != ReasonPrime || twist.Error != errTestTwist.Error() || twist.Order != 1 {
+		t.Fatalf("twist mismatch: %+v", twist)
+	}
+	if twists := idx.Twists(1, ""); len(twists) != 1 || twists[0].Hash != second.Hash() {
+		t.Fatalf("limited twists mismatch: %v", twists)
+	}
+	if twists := idx.Twists(0, StageVerify); len(twists) != 1 || twists[0].Hash != first.Hash() {
+		t.Fatalf("stage twists mismatch: %v", twists)
+	}
+	if twist := idx.Get(second.Hash()); twist == nil || twist.Number[0].Int64() != 2 {
+		t.Fatalf("twist by hash mismatch: %v", twist)
+	}
+}
+
+// Tests that the oldest twists are dropped once the index is full.
+func TestIndexLimit(t *testing.T) {
+	idx := New(memorydb.New())
+
+	for i := int64(0); i < twistsToKeep+2; i++ {
+		idx.Add(testHeader(i), 0, StageImport, ReasonPrime, errTestTwist)
+	}
+	if twists := idx.Twists(0, ""); len(twists) != twistsToKeep {
+		t.Fatalf("twist count mismatch: have %d, want %d", len(twists), twistsToKeep)
+	}
+	for i := int64(0); i < 2; i++ {
+		if idx.Get(testHeader(i).Hash()) != nil {
+			t.Fatalf("dropped twist %d still indexed", i)
+		}
+	}
+	if idx.Get(testHeader(2).Hash()) == nil {
+		t.Fatal("oldest kept twist missing")
+	}
+}
@@ -360,6 +360,29 @@ func (l *txList) Filter(costLimit *big.Int, gasLimit uint64) (types.Transactions
 	return removed, invalids
 }
 
+// Expire removes all transactions of the list which may no longer be included
+// in a block of the given number. Every removed transaction is returned, along
+// with any transaction invalidated due to the removal (strict mode only).
+func (l *txList) Expire(number uint64) (types.Transactions, types.Transactions) {
+	removed := l.txs.filter(func(tx *types.Transaction) bool { return tx.Expired(number) })
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	var invalids types.Transactions
+	// If the list was strict, filter anything above the lowest nonce
+	if l.strict {
+		lowest := uint64(math.MaxUint64)
+		for _, tx := range removed {
+			if nonce := tx.Nonce(); lowest > nonce {
+				lowest = nonce
+			}
+		}
+		invalids = l.txs.filter(func(tx *types.Transaction) bool { return tx.Nonce() > lowest })
+	}
+	l.txs.reheap()
+	return removed, invalids
+}
+
 // Cap places a hard limit on the number of items, returning all transactions
 // exceeding that limit.
 func (l *txList) Cap(threshold int) types.Transactions {
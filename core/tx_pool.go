@@ -18,6 +18,7 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sort"
@@ -30,6 +31,7 @@ import (
 	"github.com/spruce-solutions/go-quai/consensus/misc"
 	"github.com/spruce-solutions/go-quai/core/state"
 	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
 	"github.com/spruce-solutions/go-quai/event"
 	"github.com/spruce-solutions/go-quai/log"
 	"github.com/spruce-solutions/go-quai/metrics"
@@ -98,6 +100,7 @@ var (
 	pendingReplaceMeter   = metrics.NewRegisteredMeter("txpool/pending/replace", nil)
 	pendingRateLimitMeter = metrics.NewRegisteredMeter("txpool/pending/ratelimit", nil) // Dropped due to rate limiting
 	pendingNofundsMeter   = metrics.NewRegisteredMeter("txpool/pending/nofunds", nil)   // Dropped due to out-of-funds
+	pendingExpiredMeter   = metrics.NewRegisteredMeter("txpool/pending/expired", nil)   // Dropped due to validity window
 
 	// Metrics for the queued pool
 	queuedDiscardMeter   = metrics.NewRegisteredMeter("txpool/queued/discard", nil)
@@ -105,6 +108,7 @@ var (
 	queuedRateLimitMeter = metrics.NewRegisteredMeter("txpool/queued/ratelimit", nil) // Dropped due to rate limiting
 	queuedNofundsMeter   = metrics.NewRegisteredMeter("txpool/queued/nofunds", nil)   // Dropped due to out-of-funds
 	queuedEvictionMeter  = metrics.NewRegisteredMeter("txpool/queued/eviction", nil)  // Dropped due to lifetime
+	queuedExpiredMeter   = metrics.NewRegisteredMeter("txpool/queued/expired", nil)   // Dropped due to validity window
 
 	// General tx metrics
 	knownTxMeter       = metrics.NewRegisteredMeter("txpool/known", nil)
@@ -243,16 +247,21 @@ type TxPool struct {
 	signer      types.Signer
 	mu          sync.RWMutex
 
-	istanbul bool // Fork indicator whether we are in the istanbul stage.
-	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
-	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
+	istanbul   bool // Fork indicator whether we are in the istanbul stage.
+	eip2718    bool // Fork indicator whether we are using EIP-2718 type transactions.
+	eip1559    bool // Fork indicator whether we are using EIP-1559 type transactions.
+	expiringTx bool // Fork indicator whether we are accepting expiring transactions.
 
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
+	pendingNumber uint64         // Number of the next block for transaction expiry
+
+	callable map[common.Address]struct{} // Precompiles and system contracts callable outside the location
 
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
+	filters []TxFilter  // Additional admission rules, run after the validation
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -273,6 +282,11 @@ type TxPool struct {
 	changesSinceReorg int // A counter for how many drops we've performed in-between reorg.
 }
 
+// TxFilter is an additional admission rule of the transaction pool, rejecting
+// the transaction with the returned error. It is called with the pool locked and
+// must not call back into it.
+type TxFilter func(tx *types.Transaction, local bool) error
+
 type txpoolResetRequest struct {
 	oldHead, newHead *types.Header
 }
@@ -314,16 +328,11 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 	pool.wg.Add(1)
 	go pool.scheduleReorgLoop()
 
-	// If local transactions and journaling is enabled, load from disk
+	// If local transactions and journaling is enabled, load from disk in the
+	// background, transactions are accepted meanwhile
 	if !config.NoLocals && config.Journal != "" {
-		pool.journal = newTxJournal(config.Journal)
-
-		if err := pool.journal.load(pool.AddLocals); err != nil {
-			log.Warn("Failed to load transaction journal", "err", err)
-		}
-		if err := pool.journal.rotate(pool.local()); err != nil {
-			log.Warn("Failed to rotate transaction journal", "err", err)
-		}
+		pool.wg.Add(1)
+		go pool.loadJournal(newTxJournal(config.Journal))
 	}
 
 	// Subscribe events from blockchain and start the main event loop.
@@ -402,17 +411,35 @@ func (pool *TxPool) loop() {
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
+			pool.mu.Lock()
 			if pool.journal != nil {
-				pool.mu.Lock()
 				if err := pool.journal.rotate(pool.local()); err != nil {
 					log.Warn("Failed to rotate local tx journal", "err", err)
 				}
-				pool.mu.Unlock()
 			}
+			pool.mu.Unlock()
 		}
 	}
 }
 
+// loadJournal loads the local transactions of the journal into the pool, then
+// starts journaling into it. The local transactions added while loading are not
+// journaled individually, the rotation writes them all out.
+func (pool *TxPool) loadJournal(journal *txJournal) {
+	defer pool.wg.Done()
+
+	if err := journal.load(pool.AddLocals); err != nil {
+		log.Warn("Failed to load transaction journal", "err", err)
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if err := journal.rotate(pool.local()); err != nil {
+		log.Warn("Failed to rotate transaction journal", "err", err)
+	}
+	pool.journal = journal
+}
+
 // Stop terminates the transaction pool.
 func (pool *TxPool) Stop() {
 	// Unsubscribe all subscriptions registered from txpool
@@ -592,7 +619,19 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrTxTypeNotSupported
 	}
 	// Reject dynamic fee transactions until EIP-1559 activates.
-	if !pool.eip1559 && tx.Type() == types.DynamicFeeTxType {
+	if !pool.eip1559 && (tx.Type() == types.DynamicFeeTxType || tx.Type() == types.ExpiringTxType) {
+		return ErrTxTypeNotSupported
+	}
+	// Reject expiring transactions until their fork activates.
+	if !pool.expiringTx && tx.Type() == types.ExpiringTxType {
+		return ErrTxTypeNotSupported
+	}
+	// Reject transactions which may no longer be included in the next block
+	if tx.Expired(pool.pendingNumber) {
+		return ErrTxExpired
+	}
+	// Reject revival transactions until the state expiry activates
+	if tx.Type() == types.ReviveTxType && !pool.chainconfig.IsStateExpiry(new(big.Int).SetUint64(pool.pendingNumber)) {
 		return ErrTxTypeNotSupported
 	}
 	// Reject transactions over defined size to prevent DOS attacks
@@ -645,15 +684,62 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	if tx.Gas() < intrGas {
 		return ErrIntrinsicGas
 	}
+	if err := pool.validateLocation(tx, from); err != nil {
+		return err
+	}
+	for _, filter := range pool.filters {
+		if err := filter(tx, local); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddFilter adds an admission rule applied to the transactions entering the
+// pool after it. Transactions already pooled are not filtered.
+func (pool *TxPool) AddFilter(filter TxFilter) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
 
+	pool.filters = append(pool.filters, filter)
+}
+
+// validateLocation checks that the transaction and its sender belong to the
+// location of the chain, and that it only calls addresses of the location or
+// the precompiles and system contracts active at the next block. The errors
+// name the location the transaction needs to be sent to.
+func (pool *TxPool) validateLocation(tx *types.Transaction, from common.Address) error {
+	chainID := pool.chainconfig.ChainID
+	if tx.ChainId().Cmp(chainID) != 0 {
+		return fmt.Errorf("%w: signed for %s (chain id %d), this node serves %s (chain id %d)", ErrWrongLocation,
+			params.LocationName(tx.ChainId()), tx.ChainId(), params.LocationName(chainID), chainID)
+	}
 	// Validate Address Operability
 	idRange := pool.chainconfig.ChainIDRange()
 
 	if int(from.Bytes()[0]) < idRange[0] || int(from.Bytes()[0]) > idRange[1] {
-		return ErrSenderInoperable
+		if home := params.LookupAddressChainID(chainID, from.Bytes()[0]); home != nil {
+			return fmt.Errorf("%w: sender %x belongs to %s (chain id %d), this node serves %s", ErrSenderInoperable,
+				from, params.LocationName(home), home, params.LocationName(chainID))
+		}
+		return fmt.Errorf("%w: sender %x belongs to no location", ErrSenderInoperable, from)
 	}
-
-	return nil
+	// Value transfers to other locations become external transactions, calls
+	// would execute against an empty account here
+	to := tx.To()
+	if to == nil || len(tx.Data()) == 0 {
+		return nil
+	}
+	if prefix := int(to.Bytes()[0]); prefix >= idRange[0] && prefix <= idRange[1] {
+		return nil
+	}
+	if _, ok := pool.callable[*to]; ok {
+		return nil
+	}
+	if home := params.LookupAddressChainID(chainID, to.Bytes()[0]); home != nil {
+		return fmt.Errorf("%w: recipient %x belongs to %s (chain id %d)", ErrCrossLocationCall, *to, params.LocationName(home), home)
+	}
+	return fmt.Errorf("%w: recipient %x belongs to no location", ErrCrossLocationCall, *to)
 }
 
 // add validates a transaction and inserts it into the non-executable queue for later
@@ -1220,7 +1306,7 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 		for _, set := range events {
 			txs = append(txs, set.Flatten()...)
 		}
-		pool.txFeed.Send(NewTxsEvent{txs})
+		pool.txFeed.Send(NewTxsEvent{Txs: txs, Context: types.QuaiNetworkContext})
 	}
 }
 
@@ -1332,6 +1418,19 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.istanbul = pool.chainconfig.IsIstanbul(next)
 	pool.eip2718 = pool.chainconfig.IsBerlin(next)
 	pool.eip1559 = true
+	pool.expiringTx = pool.chainconfig.IsExpiringTx(next)
+	pool.pendingNumber = next.Uint64()
+
+	// Update the addresses callable by the next block outside the location
+	pool.callable = make(map[common.Address]struct{})
+	for _, precompile := range vm.ActivePrecompiles(pool.chainconfig.Rules(next)) {
+		pool.callable[precompile] = struct{}{}
+	}
+	for _, contract := range pool.chainconfig.SystemContracts {
+		if contract.Block != nil && contract.Block.Cmp(next) <= 0 {
+			pool.callable[contract.Address] = struct{}{}
+		}
+	}
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1363,6 +1462,15 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
 
+		// Drop all transactions whose validity window has passed
+		expired, _ := list.Expire(pool.pendingNumber)
+		for _, tx := range expired {
+			hash := tx.Hash()
+			pool.all.Remove(hash)
+		}
+		log.Trace("Removed expired queued transactions", "count", len(expired))
+		queuedExpiredMeter.Mark(int64(len(expired)))
+
 		// Gather all executable transactions and promote them
 		readies := list.Ready(pool.pendingNonces.get(addr))
 		for _, tx := range readies {
@@ -1386,10 +1494,10 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 			queuedRateLimitMeter.Mark(int64(len(caps)))
 		}
 		// Mark all the items dropped as removed
-		pool.priced.Removed(len(forwards) + len(drops) + len(caps))
-		queuedGauge.Dec(int64(len(forwards) + len(drops) + len(caps)))
+		pool.priced.Removed(len(forwards) + len(drops) + len(expired) + len(caps))
+		queuedGauge.Dec(int64(len(forwards) + len(drops) + len(expired) + len(caps)))
 		if pool.locals.contains(addr) {
-			localGauge.Dec(int64(len(forwards) + len(drops) + len(caps)))
+			localGauge.Dec(int64(len(forwards) + len(drops) + len(expired) + len(caps)))
 		}
 		// Delete the entire queue entry if it became empty.
 		if list.Empty() {
@@ -1560,6 +1668,16 @@ func (pool *TxPool) demoteUnexecutables() {
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
+		// Drop all transactions whose validity window has passed, and queue any invalids back for later
+		expired, gapped := list.Expire(pool.pendingNumber)
+		for _, tx := range expired {
+			hash := tx.Hash()
+			log.Trace("Removed expired pending transaction", "hash", hash)
+			pool.all.Remove(hash)
+		}
+		pendingExpiredMeter.Mark(int64(len(expired)))
+		invalids = append(invalids, gapped...)
+
 		for _, tx := range invalids {
 			hash := tx.Hash()
 			log.Trace("Demoting pending transaction", "hash", hash)
@@ -1567,9 +1685,9 @@ func (pool *TxPool) demoteUnexecutables() {
 			// Internal shuffle shouldn't touch the lookup set.
 			pool.enqueueTx(hash, tx, false, false)
 		}
-		pendingGauge.Dec(int64(len(olds) + len(drops) + len(invalids)))
+		pendingGauge.Dec(int64(len(olds) + len(drops) + len(expired) + len(invalids)))
 		if pool.locals.contains(addr) {
-			localGauge.Dec(int64(len(olds) + len(drops) + len(invalids)))
+			localGauge.Dec(int64(len(olds) + len(drops) + len(expired) + len(invalids)))
 		}
 		// If there's a gap in front, alert (should never happen) and postpone all transactions
 		if list.Len() > 0 && list.txs.Get(nonce) == nil {
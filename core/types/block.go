@@ -98,6 +98,10 @@ type Header struct {
 
 	// BaseFee was added by EIP-1559 and is ignored in legacy headers.
 	BaseFee []*big.Int `json:"baseFeePerGas" rlp:"optional"`
+
+	// EtxRollupHash commits to the external transactions applied by the block,
+	// set once the etx rollup fork is active.
+	EtxRollupHash []common.Hash `json:"etxRollupHash" rlp:"optional"`
 }
 
 // field type overrides for gencodec
@@ -150,6 +154,9 @@ func TotalBitLen(array []*big.Int) int {
 // that the unbounded fields are stuffed with junk data to add processing
 // overhead
 func (h *Header) SanityCheck() error {
+	if len(h.EtxRollupHash) > ContextDepth {
+		return fmt.Errorf("too many etx rollup hashes: %d", len(h.EtxRollupHash))
+	}
 	for i := 0; i < ContextDepth; i++ {
 		if h.Number[i] != nil && !h.Number[i].IsUint64() {
 			return fmt.Errorf("too large block number: bitlen %d", h.Number[i].BitLen())
@@ -493,10 +500,13 @@ func CopyHeader(h *Header) *Header {
 		if len(h.BaseFee) > i && h.BaseFee != nil && h.BaseFee[i] != nil {
 			cpy.BaseFee[i] = new(big.Int).Set(h.BaseFee[i])
 		}
-		if len(h.Extra) > i {
+	}
+	// The extra data must not be copied into the slice shared with h
+	if h.Extra != nil {
+		cpy.Extra = make([][]byte, len(h.Extra))
+		for i := range h.Extra {
 			if len(h.Extra[i]) > 0 {
-				cpy.Extra[i] = make([]byte, len(h.Extra[i]))
-				copy(cpy.Extra[i], h.Extra[i])
+				cpy.Extra[i] = common.CopyBytes(h.Extra[i])
 			}
 		}
 	}
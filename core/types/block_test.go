@@ -289,6 +289,26 @@ func TestUncleHash(t *testing.T) {
 	}
 }
 
+// Tests that copying a header keeps the extra data of the original intact and
+// doesn't share it with the copy.
+func TestCopyHeaderExtra(t *testing.T) {
+	h := NewEmptyHeader()
+	h.Extra[ContextDepth-1] = []byte{0x01, 0x02}
+
+	cpy := CopyHeader(h)
+	if !bytes.Equal(h.Extra[ContextDepth-1], []byte{0x01, 0x02}) {
+		t.Fatalf("original extra data modified: %x", h.Extra[ContextDepth-1])
+	}
+	if !bytes.Equal(cpy.Extra[ContextDepth-1], []byte{0x01, 0x02}) {
+		t.Fatalf("copied extra data mismatch: have %x, want 0102", cpy.Extra[ContextDepth-1])
+	}
+	cpy.Extra[ContextDepth-1][0] = 0xff
+	cpy.Extra[0] = []byte{0x03}
+	if h.Extra[ContextDepth-1][0] != 0x01 || h.Extra[0] != nil {
+		t.Errorf("extra data shared with the copy")
+	}
+}
+
 var benchBuffer = bytes.NewBuffer(make([]byte, 0, 32000))
 
 func BenchmarkEncodeBlock(b *testing.B) {
@@ -0,0 +1,92 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common"
+)
+
+// ExpiringTx is a dynamic fee transaction which may only be included in blocks
+// up to and including the ValidUntil block number of the local chain, bounding
+// how long it can remain pending.
+type ExpiringTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *common.Address `rlp:"nil"` // nil means contract creation
+	Value      *big.Int
+	Data       []byte
+	AccessList AccessList
+	ValidUntil uint64 // last block number the transaction may be included in
+
+	// Signature values
+	V *big.Int `json:"v" gencodec:"required"`
+	R *big.Int `json:"r" gencodec:"required"`
+	S *big.Int `json:"s" gencodec:"required"`
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *ExpiringTx) copy() TxData {
+	cpy := &ExpiringTx{
+		Nonce:      tx.Nonce,
+		To:         copyAddressPtr(tx.To),
+		Data:       common.CopyBytes(tx.Data),
+		Gas:        tx.Gas,
+		ValidUntil: tx.ValidUntil,
+		// These are copied below.
+		AccessList: make(AccessList, len(tx.AccessList)),
+		Value:      new(big.Int),
+		ChainID:    new(big.Int),
+		GasTipCap:  new(big.Int),
+		GasFeeCap:  new(big.Int),
+		V:          new(big.Int),
+		R:          new(big.Int),
+		S:          new(big.Int),
+	}
+	copy(cpy.AccessList, tx.AccessList)
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.GasTipCap != nil {
+		cpy.GasTipCap.Set(tx.GasTipCap)
+	}
+	if tx.GasFeeCap != nil {
+		cpy.GasFeeCap.Set(tx.GasFeeCap)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+func (tx *ExpiringTx) txType() byte           { return ExpiringTxType }
+func (tx *ExpiringTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *ExpiringTx) accessList() AccessList { return tx.AccessList }
+func (tx *ExpiringTx) data() []byte           { return tx.Data }
+func (tx *ExpiringTx) gas() uint64            { return tx.Gas }
+func (tx *ExpiringTx) gasFeeCap() *big.Int    { return tx.GasFeeCap }
+func (tx *ExpiringTx) gasTipCap() *big.Int    { return tx.GasTipCap }
+func (tx *ExpiringTx) gasPrice() *big.Int     { return tx.GasFeeCap }
+func (tx *ExpiringTx) value() *big.Int        { return tx.Value }
+func (tx *ExpiringTx) nonce() uint64          { return tx.Nonce }
+func (tx *ExpiringTx) to() *common.Address    { return tx.To }
+
+func (tx *ExpiringTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *ExpiringTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
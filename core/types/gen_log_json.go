@@ -23,6 +23,7 @@ func (l Log) MarshalJSON() ([]byte, error) {
 		TxIndex     hexutil.Uint   `json:"transactionIndex"`
 		BlockHash   common.Hash    `json:"blockHash"`
 		Index       hexutil.Uint   `json:"logIndex"`
+		ID          LogID          `json:"id"`
 		Removed     bool           `json:"removed"`
 	}
 	var enc Log
@@ -34,6 +35,7 @@ func (l Log) MarshalJSON() ([]byte, error) {
 	enc.TxIndex = hexutil.Uint(l.TxIndex)
 	enc.BlockHash = l.BlockHash
 	enc.Index = hexutil.Uint(l.Index)
+	enc.ID = l.ID
 	enc.Removed = l.Removed
 	return json.Marshal(&enc)
 }
@@ -49,6 +51,7 @@ func (l *Log) UnmarshalJSON(input []byte) error {
 		TxIndex     *hexutil.Uint   `json:"transactionIndex"`
 		BlockHash   *common.Hash    `json:"blockHash"`
 		Index       *hexutil.Uint   `json:"logIndex"`
+		ID          *LogID          `json:"id"`
 		Removed     *bool           `json:"removed"`
 	}
 	var dec Log
@@ -83,6 +86,9 @@ func (l *Log) UnmarshalJSON(input []byte) error {
 	if dec.Index != nil {
 		l.Index = uint(*dec.Index)
 	}
+	if dec.ID != nil {
+		l.ID = *dec.ID
+	}
 	if dec.Removed != nil {
 		l.Removed = *dec.Removed
 	}
@@ -0,0 +1,28 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common"
+)
+
+// Kinds of internal transactions.
+const (
+	InternalCall         = "call"
+	InternalCreate       = "create"
+	InternalCreate2      = "create2"
+	InternalSelfDestruct = "selfdestruct"
+)
+
+// InternalTransaction is a value transfer made by a contract while executing a
+// transaction, as opposed to the transfer of the transaction itself. Transfers
+// of reverted call frames are not internal transactions.
+type InternalTransaction struct {
+	TxHash  common.Hash
+	TxIndex uint
+	Kind    string
+	From    common.Address
+	To      common.Address
+	Value   *big.Int
+	Depth   uint // call depth of the transferring frame, 1 for calls made by the transaction's callee
+}
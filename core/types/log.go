@@ -17,6 +17,7 @@
 package types
 
 import (
+	"encoding/binary"
 	"io"
 
 	"github.com/spruce-solutions/go-quai/common"
@@ -49,12 +50,59 @@ type Log struct {
 	BlockHash common.Hash `json:"blockHash"`
 	// index of the log in the block
 	Index uint `json:"logIndex"`
+	// identifier composed of the block hash, transaction index and log index
+	ID LogID `json:"id"`
 
 	// The Removed field is true if this log was reverted due to a chain reorganisation.
 	// You must pay attention to this field if you receive logs through a filter query.
 	Removed bool `json:"removed"`
 }
 
+// LogID identifies a log by the hash of the block it was emitted in, the index
+// of its transaction in the block and its index in the block. Replays of the
+// same block yield the same identifiers, letting consumers deduplicate logs
+// across reorgs.
+type LogID [common.HashLength + 8]byte
+
+// NewLogID returns the identifier of the log at the given position.
+func NewLogID(blockHash common.Hash, txIndex uint, index uint) LogID {
+	var id LogID
+	copy(id[:], blockHash[:])
+	binary.BigEndian.PutUint32(id[common.HashLength:], uint32(txIndex))
+	binary.BigEndian.PutUint32(id[common.HashLength+4:], uint32(index))
+	return id
+}
+
+// BlockHash returns the hash of the block the log was emitted in.
+func (id LogID) BlockHash() common.Hash {
+	return common.BytesToHash(id[:common.HashLength])
+}
+
+// TxIndex returns the index of the transaction emitting the log in the block.
+func (id LogID) TxIndex() uint {
+	return uint(binary.BigEndian.Uint32(id[common.HashLength:]))
+}
+
+// Index returns the index of the log in the block.
+func (id LogID) Index() uint {
+	return uint(binary.BigEndian.Uint32(id[common.HashLength+4:]))
+}
+
+// String implements fmt.Stringer.
+func (id LogID) String() string {
+	return hexutil.Encode(id[:])
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (id LogID) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(id[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (id *LogID) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("LogID", input, id[:])
+}
+
 type logMarshaling struct {
 	Data        hexutil.Bytes
 	BlockNumber hexutil.Uint64
@@ -130,3 +130,22 @@ func checkError(t *testing.T, testname string, got, want error) bool {
 	}
 	return false
 }
+
+func TestLogID(t *testing.T) {
+	hash := common.HexToHash("0x656c34545f90a730a19008c0e7a7cd4fb3895064b48d6d69761bd5abad681056")
+	id := NewLogID(hash, 3, 17)
+	if id.BlockHash() != hash || id.TxIndex() != 3 || id.Index() != 17 {
+		t.Fatalf("log id fields mismatch: have %x, %d, %d", id.BlockHash(), id.TxIndex(), id.Index())
+	}
+	enc, err := json.Marshal(&Log{Topics: []common.Hash{}, TxHash: common.Hash{1}, BlockHash: hash, TxIndex: 3, Index: 17, ID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dec Log
+	if err := json.Unmarshal(enc, &dec); err != nil {
+		t.Fatal(err)
+	}
+	if dec.ID != id {
+		t.Errorf("decoded log id mismatch: have %v, want %v", dec.ID, id)
+	}
+}
@@ -177,7 +177,7 @@ func (r *Receipt) DecodeRLP(s *rlp.Stream) error {
 			return errEmptyTypedReceipt
 		}
 		r.Type = b[0]
-		if r.Type == AccessListTxType || r.Type == DynamicFeeTxType {
+		if r.Type == AccessListTxType || r.Type == DynamicFeeTxType || r.Type == ExpiringTxType || r.Type == ReviveTxType {
 			var dec receiptRLP
 			if err := rlp.DecodeBytes(b[1:], &dec); err != nil {
 				return err
@@ -346,6 +346,12 @@ func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 	case DynamicFeeTxType:
 		w.WriteByte(DynamicFeeTxType)
 		rlp.Encode(w, data)
+	case ExpiringTxType:
+		w.WriteByte(ExpiringTxType)
+		rlp.Encode(w, data)
+	case ReviveTxType:
+		w.WriteByte(ReviveTxType)
+		rlp.Encode(w, data)
 	default:
 		// For unsupported types, write nothing. Since this is for
 		// DeriveSha, the error will be caught matching the derived hash
@@ -391,6 +397,7 @@ func (r Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, num
 			r[i].Logs[j].TxHash = r[i].TxHash
 			r[i].Logs[j].TxIndex = uint(i)
 			r[i].Logs[j].Index = logIndex
+			r[i].Logs[j].ID = NewLogID(hash, uint(i), logIndex)
 			logIndex++
 		}
 	}
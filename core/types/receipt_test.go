@@ -279,6 +279,9 @@ func TestDeriveFields(t *testing.T) {
 			if receipts[i].Logs[j].Index != logIndex {
 				t.Errorf("receipts[%d].Logs[%d].Index = %d, want %d", i, j, receipts[i].Logs[j].Index, logIndex)
 			}
+			if receipts[i].Logs[j].ID != NewLogID(hash, uint(i), logIndex) {
+				t.Errorf("receipts[%d].Logs[%d].ID = %v, want %v", i, j, receipts[i].Logs[j].ID, NewLogID(hash, uint(i), logIndex))
+			}
 			logIndex++
 		}
 	}
@@ -0,0 +1,94 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/rlp"
+)
+
+// ReviveTx is a dynamic fee transaction reviving an account removed by the
+// state expiry. The proof is the account proof of the account against the
+// state root recorded for its expiry, as returned by eth_getProof. Anyone may
+// pay for the revival of any account. The transaction is sent to the state
+// expiry registry without value, with the proof as its data, so that the proof
+// is paid for as calldata.
+type ReviveTx struct {
+	ChainID   *big.Int
+	Nonce     uint64
+	GasTipCap *big.Int
+	GasFeeCap *big.Int
+	Gas       uint64
+	Account   common.Address // account to revive
+	Proof     [][]byte       // account proof against the expiry root
+
+	// Signature values
+	V *big.Int `json:"v" gencodec:"required"`
+	R *big.Int `json:"r" gencodec:"required"`
+	S *big.Int `json:"s" gencodec:"required"`
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *ReviveTx) copy() TxData {
+	cpy := &ReviveTx{
+		Nonce:   tx.Nonce,
+		Gas:     tx.Gas,
+		Account: tx.Account,
+		// These are copied below.
+		Proof:     make([][]byte, len(tx.Proof)),
+		ChainID:   new(big.Int),
+		GasTipCap: new(big.Int),
+		GasFeeCap: new(big.Int),
+		V:         new(big.Int),
+		R:         new(big.Int),
+		S:         new(big.Int),
+	}
+	for i, node := range tx.Proof {
+		cpy.Proof[i] = common.CopyBytes(node)
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.GasTipCap != nil {
+		cpy.GasTipCap.Set(tx.GasTipCap)
+	}
+	if tx.GasFeeCap != nil {
+		cpy.GasFeeCap.Set(tx.GasFeeCap)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+func (tx *ReviveTx) txType() byte           { return ReviveTxType }
+func (tx *ReviveTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *ReviveTx) accessList() AccessList { return nil }
+func (tx *ReviveTx) gas() uint64            { return tx.Gas }
+func (tx *ReviveTx) gasFeeCap() *big.Int    { return tx.GasFeeCap }
+func (tx *ReviveTx) gasTipCap() *big.Int    { return tx.GasTipCap }
+func (tx *ReviveTx) gasPrice() *big.Int     { return tx.GasFeeCap }
+func (tx *ReviveTx) value() *big.Int        { return new(big.Int) }
+func (tx *ReviveTx) nonce() uint64          { return tx.Nonce }
+func (tx *ReviveTx) to() *common.Address    { return &params.StateExpiryAddress }
+
+func (tx *ReviveTx) data() []byte {
+	data, _ := rlp.EncodeToBytes(tx.Proof)
+	return data
+}
+
+func (tx *ReviveTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *ReviveTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
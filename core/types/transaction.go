@@ -38,6 +38,7 @@ var (
 	ErrInvalidTxType        = errors.New("transaction type not valid in this context")
 	ErrTxTypeNotSupported   = errors.New("transaction type not supported")
 	ErrGasFeeCapTooLow      = errors.New("fee cap less than base fee")
+	ErrTxExpired            = errors.New("transaction expired")
 	errEmptyTypedTx         = errors.New("empty typed transaction bytes")
 )
 
@@ -47,6 +48,8 @@ const (
 	AccessListTxType
 	DynamicFeeTxType
 	ExternalTxType
+	ExpiringTxType
+	ReviveTxType
 )
 
 // Transaction is an Ethereum transaction.
@@ -69,7 +72,7 @@ func NewTx(inner TxData) *Transaction {
 
 // TxData is the underlying data of a transaction.
 //
-// This is implemented by DynamicFeeTx, LegacyTx and AccessListTx.
+// This is implemented by DynamicFeeTx, ExpiringTx, ReviveTx, LegacyTx and AccessListTx.
 type TxData interface {
 	txType() byte // returns the type ID
 	copy() TxData // creates a deep copy and initializes all fields
@@ -188,6 +191,14 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 		var inner DynamicFeeTx
 		err := rlp.DecodeBytes(b[1:], &inner)
 		return &inner, err
+	case ExpiringTxType:
+		var inner ExpiringTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
+	case ReviveTxType:
+		var inner ReviveTx
+		err := rlp.DecodeBytes(b[1:], &inner)
+		return &inner, err
 	default:
 		return nil, ErrTxTypeNotSupported
 	}
@@ -283,6 +294,32 @@ func (tx *Transaction) Value() *big.Int { return new(big.Int).Set(tx.inner.value
 // Nonce returns the sender account nonce of the transaction.
 func (tx *Transaction) Nonce() uint64 { return tx.inner.nonce() }
 
+// ValidUntil returns the last block number the transaction may be included in,
+// zero if the transaction does not expire.
+func (tx *Transaction) ValidUntil() uint64 {
+	if inner, ok := tx.inner.(*ExpiringTx); ok {
+		return inner.ValidUntil
+	}
+	return 0
+}
+
+// Expired reports whether the transaction may no longer be included in a block
+// with the given number.
+func (tx *Transaction) Expired(number uint64) bool {
+	validUntil := tx.ValidUntil()
+	return validUntil != 0 && number > validUntil
+}
+
+// Revival returns the account revived by the transaction and its proof, nil if
+// the transaction is not a revival transaction.
+func (tx *Transaction) Revival() (*common.Address, [][]byte) {
+	if inner, ok := tx.inner.(*ReviveTx); ok {
+		account := inner.Account
+		return &account, inner.Proof
+	}
+	return nil, nil
+}
+
 // To returns the recipient address of the transaction.
 // For contract-creation transactions, To returns nil.
 func (tx *Transaction) To() *common.Address {
@@ -360,6 +397,11 @@ func (tx *Transaction) EffectiveGasTipIntCmp(other *big.Int, baseFee *big.Int) i
 	return tx.EffectiveGasTipValue(baseFee).Cmp(other)
 }
 
+// Time returns the time the transaction was first seen locally.
+func (tx *Transaction) Time() time.Time {
+	return tx.time
+}
+
 // Hash returns the transaction hash.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
@@ -29,6 +29,7 @@ import (
 
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/params"
 	"github.com/spruce-solutions/go-quai/rlp"
 )
 
@@ -527,3 +528,107 @@ func assertEqual(orig *Transaction, cpy *Transaction) error {
 	}
 	return nil
 }
+
+func TestExpiringTransaction(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	var (
+		chainID   = big.NewInt(9101)
+		signer    = NewLondonSigner(chainID)
+		recipient = common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87")
+	)
+	tx, err := SignNewTx(key, signer, &ExpiringTx{
+		ChainID:    chainID,
+		Nonce:      1,
+		To:         &recipient,
+		Gas:        21000,
+		GasTipCap:  big.NewInt(1),
+		GasFeeCap:  big.NewInt(10),
+		ValidUntil: 100,
+	})
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if from, err := Sender(signer, tx); err != nil || from != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("sender mismatch: have %x, err %v", from, err)
+	}
+	for _, number := range []uint64{0, 99, 100} {
+		if tx.Expired(number) {
+			t.Errorf("transaction expired at block %d", number)
+		}
+	}
+	if !tx.Expired(101) {
+		t.Errorf("transaction not expired after its validity window")
+	}
+	// The validity window must survive encoding and be covered by the signature
+	for _, coder := range []func(*Transaction) (*Transaction, error){encodeDecodeBinary, encodeDecodeJSON} {
+		parsed, err := coder(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Type() != ExpiringTxType || parsed.ValidUntil() != 100 || parsed.Hash() != tx.Hash() {
+			t.Fatalf("decoded transaction mismatch: type %d, valid until %d", parsed.Type(), parsed.ValidUntil())
+		}
+	}
+	if a, b := signer.Hash(tx), signer.Hash(NewTx(&ExpiringTx{ChainID: chainID, Nonce: 1, To: &recipient, Gas: 21000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), ValidUntil: 200})); a == b {
+		t.Errorf("signing hash does not cover the validity window")
+	}
+	// Transactions without a validity window never expire
+	legacy := NewTransaction(0, recipient, common.Big0, 21000, common.Big1, nil)
+	if legacy.Expired(^uint64(0)) {
+		t.Errorf("transaction without validity window expired")
+	}
+}
+
+func TestReviveTransaction(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+	var (
+		chainID = big.NewInt(9101)
+		signer  = NewLondonSigner(chainID)
+		account = common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87")
+		proof   = [][]byte{{0xc0, 0x01}, {0xc0, 0x02}}
+	)
+	tx, err := SignNewTx(key, signer, &ReviveTx{
+		ChainID:   chainID,
+		Nonce:     1,
+		Gas:       50000,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Account:   account,
+		Proof:     proof,
+	})
+	if err != nil {
+		t.Fatalf("could not sign transaction: %v", err)
+	}
+	if from, err := Sender(signer, tx); err != nil || from != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("sender mismatch: have %x, err %v", from, err)
+	}
+	// Revivals are sent to the registry, paying for the proof as calldata
+	if to := tx.To(); to == nil || *to != params.StateExpiryAddress || tx.Value().Sign() != 0 {
+		t.Fatalf("revival recipient mismatch: have %v, value %v", to, tx.Value())
+	}
+	if data, _ := rlp.EncodeToBytes(proof); !bytes.Equal(tx.Data(), data) {
+		t.Fatalf("revival data mismatch: have %x, want %x", tx.Data(), data)
+	}
+	for _, coder := range []func(*Transaction) (*Transaction, error){encodeDecodeBinary, encodeDecodeJSON} {
+		parsed, err := coder(tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		revived, parsedProof := parsed.Revival()
+		if parsed.Type() != ReviveTxType || revived == nil || *revived != account || len(parsedProof) != len(proof) || parsed.Hash() != tx.Hash() {
+			t.Fatalf("decoded transaction mismatch: type %d, account %v", parsed.Type(), revived)
+		}
+	}
+	if a, b := signer.Hash(tx), signer.Hash(NewTx(&ReviveTx{ChainID: chainID, Nonce: 1, Gas: 50000, GasTipCap: big.NewInt(1), GasFeeCap: big.NewInt(10), Account: common.Address{1}, Proof: proof})); a == b {
+		t.Errorf("signing hash does not cover the revived account")
+	}
+	if revived, _ := NewTransaction(0, account, common.Big0, 21000, common.Big1, nil).Revival(); revived != nil {
+		t.Errorf("legacy transaction reported as revival")
+	}
+}
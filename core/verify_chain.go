@@ -0,0 +1,59 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/trie"
+)
+
+// Names of the roots verified by VerifyBlockRoots and by re-execution.
+const (
+	RootHeader  = "header"
+	RootTx      = "tx-root"
+	RootUncle   = "uncle-hash"
+	RootReceipt = "receipt-root"
+	RootState   = "state-root"
+)
+
+// RootMismatch describes a stored canonical block whose data does not match
+// a root committed to in its header.
+type RootMismatch struct {
+	Root   string      `json:"root"`
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Reason string      `json:"reason"`
+}
+
+// VerifyBlockRoots re-derives the transaction root, uncle hash and receipt root
+// of the stored canonical block with the given number and compares them to the
+// ones committed in its header, returning every mismatch found.
+func (bc *BlockChain) VerifyBlockRoots(number uint64) []RootMismatch {
+	block := bc.GetBlockByNumber(number)
+	if block == nil {
+		return []RootMismatch{{Root: RootHeader, Number: number, Reason: "canonical block missing"}}
+	}
+	var (
+		mismatches []RootMismatch
+		header     = block.Header()
+	)
+	report := func(root string, format string, args ...interface{}) {
+		mismatches = append(mismatches, RootMismatch{Root: root, Number: number, Hash: block.Hash(), Reason: fmt.Sprintf(format, args...)})
+	}
+	if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash[types.QuaiNetworkContext] {
+		report(RootTx, "have %x, header %x", hash, header.TxHash[types.QuaiNetworkContext])
+	}
+	if hash := types.CalcUncleHash(block.Uncles()); hash != header.UncleHash[types.QuaiNetworkContext] {
+		report(RootUncle, "have %x, header %x", hash, header.UncleHash[types.QuaiNetworkContext])
+	}
+	if number > 0 {
+		receipts := bc.GetReceiptsByHash(block.Hash())
+		if len(receipts) != len(block.Transactions()) {
+			report(RootReceipt, "have %d receipts for %d transactions", len(receipts), len(block.Transactions()))
+		} else if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != header.ReceiptHash[types.QuaiNetworkContext] {
+			report(RootReceipt, "have %x, header %x", hash, header.ReceiptHash[types.QuaiNetworkContext])
+		}
+	}
+	return mismatches
+}
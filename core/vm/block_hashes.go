@@ -0,0 +1,151 @@
+package vm
+
+import (
+	"encoding/binary"
+
+	"github.com/holiman/uint256"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// BlockHashHistoryAddress is the system account whose storage holds the block
+// hash history once the block hash window fork is active. The history is a
+// ring buffer per context, written at the start of every block, so it follows
+// the chain the block is built on and is reorg safe.
+var BlockHashHistoryAddress = common.HexToAddress("0x00000000000000000000000000000000000000fb")
+
+// blockHashHistorySlots returns the storage slots holding the hash of a block
+// of the given context in the history, and the number of the block stored in
+// it. Blocks a window apart share their slots.
+func blockHashHistorySlots(context int, number uint64, window uint64) (common.Hash, common.Hash) {
+	var hashSlot, numberSlot common.Hash
+	hashSlot[0], numberSlot[0] = byte(context), byte(context)
+	numberSlot[1] = 1
+	binary.BigEndian.PutUint64(hashSlot[common.HashLength-8:], number%window)
+	binary.BigEndian.PutUint64(numberSlot[common.HashLength-8:], number%window)
+	return hashSlot, numberSlot
+}
+
+// latestBlockHashHistorySlot returns the storage slot holding the number of the
+// latest block of the given context in the history.
+func latestBlockHashHistorySlot(context int) common.Hash {
+	var slot common.Hash
+	slot[0], slot[1] = byte(context), 2
+	return slot
+}
+
+// ReadLatestBlockHashHistory returns the number and the hash of the latest block
+// of the given context in the block hash history, false if there is none.
+func ReadLatestBlockHashHistory(db StateDB, context int, window uint64) (uint64, common.Hash, bool) {
+	stored := db.GetState(BlockHashHistoryAddress, latestBlockHashHistorySlot(context))
+	latest := binary.BigEndian.Uint64(stored[common.HashLength-8:])
+	if latest == 0 {
+		return 0, common.Hash{}, false
+	}
+	return latest - 1, ReadBlockHashHistory(db, context, latest-1, window), true
+}
+
+// ReadBlockHashHistory returns the hash of a block of the given context from
+// the block hash history, zero if it is not in it.
+func ReadBlockHashHistory(db StateDB, context int, number uint64, window uint64) common.Hash {
+	hashSlot, numberSlot := blockHashHistorySlots(context, number, window)
+
+	// The numbers are stored incremented, so that unused slots never match
+	stored := db.GetState(BlockHashHistoryAddress, numberSlot)
+	if binary.BigEndian.Uint64(stored[common.HashLength-8:]) != number+1 {
+		return common.Hash{}
+	}
+	return db.GetState(BlockHashHistoryAddress, hashSlot)
+}
+
+// WriteBlockHashHistory stores the hash of a block of the given context in the
+// block hash history, replacing the block a window earlier.
+func WriteBlockHashHistory(db StateDB, context int, number uint64, window uint64, hash common.Hash) {
+	// Keep the system account non-empty, so it is not deleted as an empty account
+	if db.GetNonce(BlockHashHistoryAddress) == 0 {
+		db.SetNonce(BlockHashHistoryAddress, 1)
+	}
+	hashSlot, numberSlot := blockHashHistorySlots(context, number, window)
+
+	var stored common.Hash
+	binary.BigEndian.PutUint64(stored[common.HashLength-8:], number+1)
+	db.SetState(BlockHashHistoryAddress, numberSlot, stored)
+	db.SetState(BlockHashHistoryAddress, hashSlot, hash)
+
+	latest := db.GetState(BlockHashHistoryAddress, latestBlockHashHistorySlot(context))
+	if binary.BigEndian.Uint64(latest[common.HashLength-8:]) <= number {
+		db.SetState(BlockHashHistoryAddress, latestBlockHashHistorySlot(context), stored)
+	}
+}
+
+// enableBlockHashWindow makes BLOCKHASH serve the block hash history beyond the
+// 256 most recent blocks.
+func enableBlockHashWindow(jt *JumpTable) {
+	jt[BLOCKHASH].execute = opBlockhashWindow
+	jt[BLOCKHASH].dynamicGas = gasBlockhashWindow
+}
+
+// inBlockHashHistory reports whether BLOCKHASH of the given number is served
+// from the block hash history rather than from the recent headers.
+func inBlockHashHistory(evm *EVM, num *uint256.Int) bool {
+	num64, overflow := num.Uint64WithOverflow()
+	if overflow {
+		return false
+	}
+	upper := evm.Context.BlockNumber.Uint64()
+	return num64 < upper && upper-num64 > 256 && upper-num64 <= evm.chainConfig.BlockHashHistoryWindow()
+}
+
+func gasBlockhashWindow(evm *EVM, contract *Contract, stack *Stack, mem *Memory, memorySize uint64) (uint64, error) {
+	if inBlockHashHistory(evm, stack.peek()) {
+		return params.BlockHashHistoryGas, nil
+	}
+	return 0, nil
+}
+
+func opBlockhashWindow(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	num := scope.Stack.peek()
+	if !inBlockHashHistory(interpreter.evm, num) {
+		return opBlockhash(pc, interpreter, scope)
+	}
+	hash := ReadBlockHashHistory(interpreter.evm.StateDB, types.QuaiNetworkContext, num.Uint64(), interpreter.evm.chainConfig.BlockHashHistoryWindow())
+	num.SetBytes(hash.Bytes())
+	return nil, nil
+}
+
+// domBlockHash is a precompiled contract returning the hash of a block of this
+// context or of a dominant one from the block hash history. The dominant blocks
+// in the history are the ones coincident with blocks of this chain.
+//
+// The input is the context and the number of the block as two 32 byte words,
+// the output is the hash of the block, zero if it is not in the history.
+type domBlockHash struct {
+	evm *EVM
+}
+
+// bind implements statefulPrecompiledContract.
+func (c *domBlockHash) bind(evm *EVM) PrecompiledContract {
+	return &domBlockHash{evm: evm}
+}
+
+// RequiredGas implements PrecompiledContract.
+func (c *domBlockHash) RequiredGas(input []byte) uint64 {
+	return params.BlockHashHistoryGas
+}
+
+// Run implements PrecompiledContract.
+func (c *domBlockHash) Run(input []byte) ([]byte, error) {
+	input = common.RightPadBytes(input, 64)
+
+	context, overflow := new(uint256.Int).SetBytes(input[:32]).Uint64WithOverflow()
+	if overflow || context > uint64(types.QuaiNetworkContext) {
+		return make([]byte, 32), nil
+	}
+	number, overflow := new(uint256.Int).SetBytes(input[32:64]).Uint64WithOverflow()
+	if overflow {
+		return make([]byte, 32), nil
+	}
+	hash := ReadBlockHashHistory(c.evm.StateDB, int(context), number, c.evm.chainConfig.BlockHashHistoryWindow())
+	return hash.Bytes(), nil
+}
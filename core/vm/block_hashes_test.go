@@ -0,0 +1,85 @@
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+func TestBlockHashHistory(t *testing.T) {
+	defer func(context int) { types.QuaiNetworkContext = context }(types.QuaiNetworkContext)
+	types.QuaiNetworkContext = params.ZONE
+
+	config := *params.TestChainConfig
+	config.BlockHashWindowBlock, config.BlockHashWindow = big.NewInt(0), 1024
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	for number := uint64(0); number < 2000; number++ {
+		WriteBlockHashHistory(statedb, params.ZONE, number, config.BlockHashWindow, common.BigToHash(new(big.Int).SetUint64(number+1)))
+	}
+	WriteBlockHashHistory(statedb, params.PRIME, 7, config.BlockHashWindow, common.HexToHash("0x07"))
+	statedb.Finalise(true)
+
+	// BLOCKHASH of the number pushed as a two byte word, returning the hash
+	contract := common.BytesToAddress([]byte("contract"))
+	statedb.SetCode(contract, hexutil.MustDecode("0x60003560f01c4060005260206000f3"))
+
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		GetHash:     func(n uint64) common.Hash { return common.HexToHash("0xff") },
+		BlockNumber: big.NewInt(2000),
+	}
+	call := func(config *params.ChainConfig, addr common.Address, input []byte) common.Hash {
+		vmenv := NewEVM(vmctx, TxContext{}, statedb, config, Config{})
+		ret, _, err := vmenv.Call(AccountRef(common.Address{}), addr, input, 100000, new(big.Int))
+		if err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+		return common.BytesToHash(ret)
+	}
+	for _, tt := range []struct {
+		number uint64
+		want   common.Hash
+	}{
+		{1999, common.HexToHash("0xff")}, // recent block, served from the headers
+		{1744, common.HexToHash("0xff")},
+		{1743, common.BigToHash(big.NewInt(1744))}, // served from the history
+		{976, common.BigToHash(big.NewInt(977))},
+		{975, common.Hash{}}, // outside the window
+		{2000, common.Hash{}},
+	} {
+		input := new(big.Int).SetUint64(tt.number).FillBytes(make([]byte, 2))
+		if have := call(&config, contract, input); have != tt.want {
+			t.Errorf("BLOCKHASH(%d) mismatch: have %x, want %x", tt.number, have, tt.want)
+		}
+		if tt.number == 1743 {
+			if have := call(params.TestChainConfig, contract, input); have != (common.Hash{}) {
+				t.Errorf("BLOCKHASH(%d) served from the history before the fork: %x", tt.number, have)
+			}
+		}
+	}
+	precompile := common.BytesToAddress([]byte{0x20})
+	for _, tt := range []struct {
+		context int
+		number  uint64
+		want    common.Hash
+	}{
+		{params.PRIME, 7, common.HexToHash("0x07")},
+		{params.PRIME, 7 + 1024, common.Hash{}}, // shares the slot, must not match
+		{params.REGION, 7, common.Hash{}},
+		{params.ZONE, 1000, common.BigToHash(big.NewInt(1001))},
+		{3, 7, common.Hash{}},
+	} {
+		input := append(common.BigToHash(big.NewInt(int64(tt.context))).Bytes(), common.BigToHash(new(big.Int).SetUint64(tt.number)).Bytes()...)
+		if have := call(&config, precompile, input); have != tt.want {
+			t.Errorf("dominant block hash %d/%d mismatch: have %x, want %x", tt.context, tt.number, have, tt.want)
+		}
+	}
+}
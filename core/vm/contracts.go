@@ -42,6 +42,15 @@ type PrecompiledContract interface {
 	Run(input []byte) ([]byte, error) // Run runs the precompiled contract
 }
 
+// statefulPrecompiledContract is a precompiled contract reading the state of
+// the EVM it runs in.
+type statefulPrecompiledContract interface {
+	PrecompiledContract
+
+	// bind returns the contract bound to the EVM running it.
+	bind(evm *EVM) PrecompiledContract
+}
+
 // PrecompiledContractsHomestead contains the default set of pre-compiled Ethereum
 // contracts used in the Frontier and Homestead releases.
 var PrecompiledContractsHomestead = map[common.Address]PrecompiledContract{
@@ -106,11 +115,19 @@ var PrecompiledContractsBLS = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{18}): &bls12381MapG2{},
 }
 
+// PrecompiledContractsBlockHashWindow contains the set of pre-compiled Quai
+// contracts enabled by the block hash window fork on top of the Berlin ones.
+var PrecompiledContractsBlockHashWindow = map[common.Address]PrecompiledContract{
+	common.BytesToAddress([]byte{0x20}): &domBlockHash{},
+	common.BytesToAddress([]byte{0x21}): &randomBeacon{},
+}
+
 var (
-	PrecompiledAddressesBerlin    []common.Address
-	PrecompiledAddressesIstanbul  []common.Address
-	PrecompiledAddressesByzantium []common.Address
-	PrecompiledAddressesHomestead []common.Address
+	PrecompiledAddressesBlockHashWindow []common.Address
+	PrecompiledAddressesBerlin          []common.Address
+	PrecompiledAddressesIstanbul        []common.Address
+	PrecompiledAddressesByzantium       []common.Address
+	PrecompiledAddressesHomestead       []common.Address
 )
 
 func init() {
@@ -126,11 +143,17 @@ func init() {
 	for k := range PrecompiledContractsBerlin {
 		PrecompiledAddressesBerlin = append(PrecompiledAddressesBerlin, k)
 	}
+	PrecompiledAddressesBlockHashWindow = append(PrecompiledAddressesBlockHashWindow, PrecompiledAddressesBerlin...)
+	for k := range PrecompiledContractsBlockHashWindow {
+		PrecompiledAddressesBlockHashWindow = append(PrecompiledAddressesBlockHashWindow, k)
+	}
 }
 
 // ActivePrecompiles returns the precompiles enabled with the current configuration.
 func ActivePrecompiles(rules params.Rules) []common.Address {
 	switch {
+	case rules.IsBlockHashWindow:
+		return PrecompiledAddressesBlockHashWindow
 	case rules.IsBerlin:
 		return PrecompiledAddressesBerlin
 	case rules.IsIstanbul:
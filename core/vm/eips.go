@@ -147,6 +147,17 @@ func enable2929(jt *JumpTable) {
 	jt[SELFDESTRUCT].dynamicGas = gasSelfdestructEIP2929
 }
 
+// setWarmStorageReadCost replaces the WARM_STORAGE_READ_COST charged as constant
+// gas by the EIP-2929 account accessing opcodes. The operations are copied, as
+// the jump tables of the forks share them.
+func setWarmStorageReadCost(jt *JumpTable, cost uint64) {
+	for _, op := range []OpCode{EXTCODECOPY, EXTCODESIZE, EXTCODEHASH, BALANCE, CALL, CALLCODE, STATICCALL, DELEGATECALL} {
+		patched := *jt[op]
+		patched.constantGas = cost
+		jt[op] = &patched
+	}
+}
+
 // enable3529 enabled "EIP-3529: Reduction in refunds":
 // - Removes refunds for selfdestructs
 // - Reduces refunds for SSTORE
@@ -54,6 +54,12 @@ func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 		precompiles = PrecompiledContractsHomestead
 	}
 	p, ok := precompiles[addr]
+	if !ok && evm.chainRules.IsBlockHashWindow {
+		p, ok = PrecompiledContractsBlockHashWindow[addr]
+	}
+	if stateful, isStateful := p.(statefulPrecompiledContract); isStateful {
+		p = stateful.bind(evm)
+	}
 	return p, ok
 }
 
@@ -172,6 +178,10 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the account was removed by the state expiry
+	if evm.accountExpired(addr) {
+		return nil, gas, ErrAccountExpired
+	}
 	// Fail if we're trying to transfer more than the available balance
 	if value.Sign() != 0 && !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, gas, ErrInsufficientBalance
@@ -192,6 +202,10 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	}
 	evm.Context.Transfer(evm.StateDB, caller.Address(), addr, value)
 
+	if evm.Config.CallTracer != nil {
+		evm.Config.CallTracer.CaptureEnter(evm, CALL, caller.Address(), addr, value)
+		defer func() { evm.Config.CallTracer.CaptureExit(err) }()
+	}
 	// Capture the tracer start/end events in debug mode
 	if evm.Config.Debug {
 		if evm.depth == 0 {
@@ -256,6 +270,10 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the account was removed by the state expiry
+	if evm.accountExpired(addr) {
+		return nil, gas, ErrAccountExpired
+	}
 	// Fail if we're trying to transfer more than the available balance
 	// Note although it's noop to transfer X ether to caller itself. But
 	// if caller doesn't have enough balance, it would be an error to allow
@@ -266,6 +284,10 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 	var snapshot = evm.StateDB.Snapshot()
 
 	// Invoke tracer hooks that signal entering/exiting a call frame
+	if evm.Config.CallTracer != nil {
+		evm.Config.CallTracer.CaptureEnter(evm, CALLCODE, caller.Address(), addr, value)
+		defer func() { evm.Config.CallTracer.CaptureExit(err) }()
+	}
 	if evm.Config.Debug {
 		evm.Config.Tracer.CaptureEnter(CALLCODE, caller.Address(), addr, input, gas, value)
 		defer func(startGas uint64) {
@@ -307,9 +329,17 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the account was removed by the state expiry
+	if evm.accountExpired(addr) {
+		return nil, gas, ErrAccountExpired
+	}
 	var snapshot = evm.StateDB.Snapshot()
 
 	// Invoke tracer hooks that signal entering/exiting a call frame
+	if evm.Config.CallTracer != nil {
+		evm.Config.CallTracer.CaptureEnter(evm, DELEGATECALL, caller.Address(), addr, nil)
+		defer func() { evm.Config.CallTracer.CaptureExit(err) }()
+	}
 	if evm.Config.Debug {
 		evm.Config.Tracer.CaptureEnter(DELEGATECALL, caller.Address(), addr, input, gas, nil)
 		defer func(startGas uint64) {
@@ -349,6 +379,10 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
 	}
+	// Fail if the account was removed by the state expiry
+	if evm.accountExpired(addr) {
+		return nil, gas, ErrAccountExpired
+	}
 	// We take a snapshot here. This is a bit counter-intuitive, and could probably be skipped.
 	// However, even a staticcall is considered a 'touch'. On mainnet, static calls were introduced
 	// after all empty accounts were deleted, so this is not required. However, if we omit this,
@@ -363,6 +397,10 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	evm.StateDB.AddBalance(addr, big0)
 
 	// Invoke tracer hooks that signal entering/exiting a call frame
+	if evm.Config.CallTracer != nil {
+		evm.Config.CallTracer.CaptureEnter(evm, STATICCALL, caller.Address(), addr, nil)
+		defer func() { evm.Config.CallTracer.CaptureExit(err) }()
+	}
 	if evm.Config.Debug {
 		evm.Config.Tracer.CaptureEnter(STATICCALL, caller.Address(), addr, input, gas, nil)
 		defer func(startGas uint64) {
@@ -415,6 +453,9 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, common.Address{}, gas, ErrDepth
 	}
+	if evm.accountExpired(address) {
+		return nil, common.Address{}, gas, ErrAccountExpired
+	}
 	if !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
 		return nil, common.Address{}, gas, ErrInsufficientBalance
 	}
@@ -447,6 +488,9 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 		return nil, address, gas, nil
 	}
 
+	if evm.Config.CallTracer != nil {
+		evm.Config.CallTracer.CaptureEnter(evm, typ, caller.Address(), address, value)
+	}
 	if evm.Config.Debug {
 		if evm.depth == 0 {
 			evm.Config.Tracer.CaptureStart(evm, caller.Address(), address, true, codeAndHash.code, gas, value)
@@ -492,6 +536,9 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 		}
 	}
 
+	if evm.Config.CallTracer != nil {
+		evm.Config.CallTracer.CaptureExit(err)
+	}
 	if evm.Config.Debug {
 		if evm.depth == 0 {
 			evm.Config.Tracer.CaptureEnd(ret, gas-contract.Gas, time.Since(start), err)
@@ -0,0 +1,55 @@
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+func TestGasScheduleAccessCosts(t *testing.T) {
+	var (
+		contract = common.HexToAddress("0x1100000000000000000000000000000000000001")
+		caller   = common.HexToAddress("0x1100000000000000000000000000000000000002")
+		// BALANCE(caller), SLOAD(0), SLOAD(0)
+		code = []byte{
+			byte(PUSH20), 0x11, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x02, byte(BALANCE), byte(POP),
+			byte(PUSH1), 0, byte(SLOAD), byte(POP),
+			byte(PUSH1), 0, byte(SLOAD), byte(POP),
+		}
+	)
+	run := func(config *params.ChainConfig) uint64 {
+		statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.SetCode(contract, code)
+		statedb.PrepareAccessList(caller, &contract, nil, nil)
+
+		blockCtx := BlockContext{
+			BlockNumber: big.NewInt(1),
+			CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+			Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		}
+		vmenv := NewEVM(blockCtx, TxContext{}, statedb, config, Config{})
+		_, left, err := vmenv.Call(AccountRef(caller), contract, nil, 100000, new(big.Int))
+		if err != nil {
+			t.Fatalf("call failed: %v", err)
+		}
+		return 100000 - left
+	}
+	// The pushes and pops cost 3 * GasFastestStep + 3 * GasQuickStep
+	pushPop := 3*GasFastestStep + 3*GasQuickStep
+	if used := run(params.TestChainConfig); used != pushPop+params.WarmStorageReadCostEIP2929+params.ColdSloadCostEIP2929+params.WarmStorageReadCostEIP2929 {
+		t.Errorf("default access costs mismatch: have %d", used)
+	}
+	config := *params.TestChainConfig
+	config.GasSchedules = []*params.GasSchedule{{Block: big.NewInt(0), ColdSloadCost: 1000, WarmStorageReadCost: 50}}
+	if used := run(&config); used != pushPop+50+1000+50 {
+		t.Errorf("scheduled access costs mismatch: have %d", used)
+	}
+	// The jump tables shared by the forks are left untouched
+	if berlinInstructionSet[BALANCE].constantGas != params.WarmStorageReadCostEIP2929 {
+		t.Errorf("shared jump table modified")
+	}
+}
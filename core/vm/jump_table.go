@@ -58,11 +58,20 @@ var (
 	istanbulInstructionSet         = newIstanbulInstructionSet()
 	berlinInstructionSet           = newBerlinInstructionSet()
 	londonInstructionSet           = newLondonInstructionSet()
+	blockHashWindowInstructionSet  = newBlockHashWindowInstructionSet()
 )
 
 // JumpTable contains the EVM opcodes supported at a given fork.
 type JumpTable [256]*operation
 
+// newBlockHashWindowInstructionSet returns the london instructions with
+// BLOCKHASH served from the block hash history.
+func newBlockHashWindowInstructionSet() JumpTable {
+	instructionSet := newLondonInstructionSet()
+	enableBlockHashWindow(&instructionSet)
+	return instructionSet
+}
+
 // newLondonInstructionSet returns the frontier, homestead, byzantium,
 // contantinople, istanbul, petersburg, berlin and london instructions.
 func newLondonInstructionSet() JumpTable {
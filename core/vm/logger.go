@@ -112,6 +112,17 @@ type Tracer interface {
 	CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error)
 }
 
+// CallTracer is a lightweight tracer notified only of the call frames the EVM
+// enters and exits, without the per-opcode overhead of a Tracer. Unlike Tracer
+// it is invoked regardless of Config.Debug, so it can run during block import.
+type CallTracer interface {
+	// CaptureEnter is invoked after the value of a frame was transferred.
+	CaptureEnter(env *EVM, typ OpCode, from common.Address, to common.Address, value *big.Int)
+	// CaptureExit is invoked when a frame returns, a non-nil error meaning it
+	// was reverted along with its value transfers.
+	CaptureExit(err error)
+}
+
 // StructLogger is an EVM state logger and implements Tracer.
 //
 // StructLogger can capture state based on the given Log configuration and also keeps
@@ -0,0 +1,78 @@
+package vm
+
+import (
+	"encoding/binary"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// randomBeacon is a precompiled contract returning a random value mixed from
+// the hashes of the latest dominant blocks coincident with this chain, as kept
+// in the block hash history. Prime chains, having no dominant context, mix the
+// hash of their parent block instead. The hashes of the blocks of a zone are
+// left out on purpose, as they are cheap to mine and thus to reroll.
+//
+// The input is an optional salt of any length separating the values handed out
+// for different purposes. The output is the value followed by its round, the
+// number of the latest block of the fastest source context as a 32 byte word.
+// The value only changes with the round, so contracts should commit to a future
+// round and settle with the value of that round. The output is all zero until a
+// source block is recorded.
+//
+// Bias bounds: the value is the hash of proof of work solutions, so it can not
+// be chosen, only rerolled by withholding a solved source block, forfeiting the
+// reward of that block and of the blocks coincident with it. A miner with the
+// fraction f of the hash rate of a source context raises the probability p of
+// an outcome by at most f*p*(1-p), at most f/4, for every withheld block. Values
+// deciding more than the reward of a source block should not rely on the beacon
+// alone.
+type randomBeacon struct {
+	evm *EVM
+}
+
+// bind implements statefulPrecompiledContract.
+func (c *randomBeacon) bind(evm *EVM) PrecompiledContract {
+	return &randomBeacon{evm: evm}
+}
+
+// randomBeaconSources returns the number of contexts the beacon mixes hashes
+// from, the dominant ones or prime itself.
+func randomBeaconSources() int {
+	if types.QuaiNetworkContext == params.PRIME {
+		return 1 // prime mixes its own blocks
+	}
+	return types.QuaiNetworkContext
+}
+
+// RequiredGas implements PrecompiledContract.
+func (c *randomBeacon) RequiredGas(input []byte) uint64 {
+	return uint64(randomBeaconSources())*params.RandomBeaconSourceGas + toWordSize(uint64(len(input)))*params.Sha3WordGas
+}
+
+// Run implements PrecompiledContract.
+func (c *randomBeacon) Run(input []byte) ([]byte, error) {
+	var (
+		window = c.evm.chainConfig.BlockHashHistoryWindow()
+		round  uint64
+		mixed  bool
+	)
+	data := crypto.Keccak256(input)
+	for context := 0; context < randomBeaconSources(); context++ {
+		number, hash, ok := ReadLatestBlockHashHistory(c.evm.StateDB, context, window)
+		if !ok || hash == (common.Hash{}) {
+			continue
+		}
+		data = append(data, hash.Bytes()...)
+		round, mixed = number, true
+	}
+	if !mixed {
+		return make([]byte, 64), nil
+	}
+	var output [64]byte
+	copy(output[:32], crypto.Keccak256(data))
+	binary.BigEndian.PutUint64(output[56:], round)
+	return output[:], nil
+}
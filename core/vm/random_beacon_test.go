@@ -0,0 +1,85 @@
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+func TestRandomBeacon(t *testing.T) {
+	defer func(context int) { types.QuaiNetworkContext = context }(types.QuaiNetworkContext)
+	types.QuaiNetworkContext = params.ZONE
+
+	config := *params.TestChainConfig
+	config.BlockHashWindowBlock = big.NewInt(0)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	vmenv := NewEVM(BlockContext{BlockNumber: big.NewInt(100)}, TxContext{}, statedb, &config, Config{})
+	beacon := func(salt []byte) []byte {
+		p, ok := vmenv.precompile(common.BytesToAddress([]byte{0x21}))
+		if !ok {
+			t.Fatal("random beacon not active")
+		}
+		out, err := p.Run(salt)
+		if err != nil {
+			t.Fatalf("beacon failed: %v", err)
+		}
+		return out
+	}
+	if out := beacon(nil); !bytes.Equal(out, make([]byte, 64)) {
+		t.Fatalf("beacon without sources mismatch: have %x", out)
+	}
+	// Blocks of the zone itself must not influence the value
+	WriteBlockHashHistory(statedb, params.ZONE, 98, config.BlockHashHistoryWindow(), common.HexToHash("0x01"))
+	WriteBlockHashHistory(statedb, params.PRIME, 4, config.BlockHashHistoryWindow(), common.HexToHash("0x02"))
+	WriteBlockHashHistory(statedb, params.REGION, 12, config.BlockHashHistoryWindow(), common.HexToHash("0x03"))
+
+	first := beacon(nil)
+	if round := new(big.Int).SetBytes(first[32:]); round.Uint64() != 12 {
+		t.Errorf("round mismatch: have %v, want 12", round)
+	}
+	WriteBlockHashHistory(statedb, params.ZONE, 99, config.BlockHashHistoryWindow(), common.HexToHash("0x04"))
+	if out := beacon(nil); !bytes.Equal(out, first) {
+		t.Errorf("zone block changed the beacon: have %x, want %x", out, first)
+	}
+	if out := beacon([]byte("lottery")); bytes.Equal(out[:32], first[:32]) {
+		t.Errorf("salt did not change the beacon")
+	}
+	// A new coincident block starts a new round, older ones are ignored
+	WriteBlockHashHistory(statedb, params.REGION, 10, config.BlockHashHistoryWindow(), common.HexToHash("0x05"))
+	if out := beacon(nil); !bytes.Equal(out, first) {
+		t.Errorf("older region block changed the beacon")
+	}
+	WriteBlockHashHistory(statedb, params.REGION, 13, config.BlockHashHistoryWindow(), common.HexToHash("0x06"))
+	if out := beacon(nil); bytes.Equal(out[:32], first[:32]) || new(big.Int).SetBytes(out[32:]).Uint64() != 13 {
+		t.Errorf("new region block did not start a new round: %x", out)
+	}
+}
+
+// Tests that the beacon is priced per source context read, three slots each.
+func TestRandomBeaconGas(t *testing.T) {
+	defer func(context int) { types.QuaiNetworkContext = context }(types.QuaiNetworkContext)
+
+	tests := []struct {
+		context int
+		salt    []byte
+		gas     uint64
+	}{
+		{params.PRIME, nil, 3 * params.BlockHashHistoryGas},
+		{params.REGION, nil, 3 * params.BlockHashHistoryGas},
+		{params.ZONE, nil, 6 * params.BlockHashHistoryGas},
+		{params.ZONE, make([]byte, 33), 6*params.BlockHashHistoryGas + 2*params.Sha3WordGas},
+	}
+	for i, tt := range tests {
+		types.QuaiNetworkContext = tt.context
+		if gas := new(randomBeacon).RequiredGas(tt.salt); gas != tt.gas {
+			t.Errorf("test %d: gas mismatch: have %d, want %d", i, gas, tt.gas)
+		}
+	}
+}
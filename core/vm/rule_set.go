@@ -0,0 +1,61 @@
+package vm
+
+import (
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// RuleSet is the EVM rule set in effect under some chain rules.
+type RuleSet struct {
+	InstructionSet string           // Name of the fork instruction set, the gas table version
+	EVMVersion     string           // Compiler EVM version targeting the instruction set
+	Opcodes        []OpCode         // Opcodes available in the instruction set
+	Precompiles    []common.Address // Active precompiled contracts
+}
+
+// NewRuleSet returns the EVM rule set in effect under the given chain rules.
+func NewRuleSet(rules params.Rules) RuleSet {
+	name, version, jt := forkInstructionSet(rules)
+
+	var opcodes []OpCode
+	for op, operation := range jt {
+		if operation != nil {
+			opcodes = append(opcodes, OpCode(op))
+		}
+	}
+	return RuleSet{
+		InstructionSet: name,
+		EVMVersion:     version,
+		Opcodes:        opcodes,
+		Precompiles:    ActivePrecompiles(rules),
+	}
+}
+
+// forkInstructionSet returns the name, the compiler EVM version and the jump
+// table of the latest fork instruction set enabled by the chain rules. The
+// block hash window only changes the semantics of BLOCKHASH, so contracts
+// compiled for london target it.
+func forkInstructionSet(rules params.Rules) (string, string, JumpTable) {
+	switch {
+	case rules.IsBlockHashWindow:
+		return "blockHashWindow", "london", blockHashWindowInstructionSet
+	case rules.IsLondon:
+		return "london", "london", londonInstructionSet
+	case rules.IsBerlin:
+		return "berlin", "berlin", berlinInstructionSet
+	case rules.IsIstanbul:
+		return "istanbul", "istanbul", istanbulInstructionSet
+	case rules.IsConstantinople:
+		return "constantinople", "petersburg", constantinopleInstructionSet
+	case rules.IsByzantium:
+		return "byzantium", "byzantium", byzantiumInstructionSet
+	case rules.IsEIP158:
+		return "spuriousDragon", "spuriousDragon", spuriousDragonInstructionSet
+	case rules.IsEIP150:
+		return "tangerineWhistle", "tangerineWhistle", tangerineWhistleInstructionSet
+	case rules.IsHomestead:
+		return "homestead", "homestead", homesteadInstructionSet
+	default:
+		return "frontier", "homestead", frontierInstructionSet
+	}
+}
@@ -0,0 +1,33 @@
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+func TestRuleSet(t *testing.T) {
+	hasOp := func(ruleSet RuleSet, op OpCode) bool {
+		for _, enabled := range ruleSet.Opcodes {
+			if enabled == op {
+				return true
+			}
+		}
+		return false
+	}
+	london := NewRuleSet(params.TestChainConfig.Rules(new(big.Int)))
+	if london.InstructionSet != "london" || london.EVMVersion != "london" {
+		t.Errorf("instruction set mismatch: have %s/%s", london.InstructionSet, london.EVMVersion)
+	}
+	if !hasOp(london, BASEFEE) || !hasOp(london, CHAINID) || hasOp(london, OpCode(0xef)) {
+		t.Errorf("london opcodes mismatch: %v", london.Opcodes)
+	}
+	if len(london.Precompiles) != len(PrecompiledAddressesBerlin) {
+		t.Errorf("london precompiles mismatch: %v", london.Precompiles)
+	}
+	frontier := NewRuleSet(params.Rules{})
+	if frontier.InstructionSet != "frontier" || hasOp(frontier, DELEGATECALL) || !hasOp(frontier, CALL) {
+		t.Errorf("frontier rule set mismatch: %+v", frontier)
+	}
+}
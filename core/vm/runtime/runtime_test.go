@@ -935,3 +935,57 @@ func BenchmarkTracerStepVsCallFrame(b *testing.B) {
 	benchmarkNonModifyingCode(10000000, code, "tracer-step-10M", stepTracer, b)
 	benchmarkNonModifyingCode(10000000, code, "tracer-call-frame-10M", callFrameTracer, b)
 }
+
+// callTracer records the frames reported to a vm.CallTracer.
+type callTracer struct {
+	frames []string
+}
+
+func (t *callTracer) CaptureEnter(env *vm.EVM, typ vm.OpCode, from common.Address, to common.Address, value *big.Int) {
+	t.frames = append(t.frames, fmt.Sprintf("enter %v %x->%x %v", typ, from[19:], to[19:], value))
+}
+
+func (t *callTracer) CaptureExit(err error) {
+	t.frames = append(t.frames, fmt.Sprintf("exit %v", err))
+}
+
+// Tests that the lightweight call tracer is notified of call frames without
+// debug mode, reporting reverted frames.
+func TestCallTracerFrames(t *testing.T) {
+	call := func(to byte) []byte {
+		return []byte{
+			byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, // out size, out offset, in size, in offset
+			byte(vm.PUSH1), 1, // value
+			byte(vm.PUSH1), to,
+			byte(vm.PUSH2), 0xff, 0xff, // gas
+			byte(vm.CALL),
+			byte(vm.POP),
+		}
+	}
+	code := append(append(call(0xe0), call(0xee)...), byte(vm.STOP))
+
+	tracer := new(callTracer)
+	cfg := &Config{EVMConfig: vm.Config{CallTracer: tracer}}
+	setDefaults(cfg)
+	cfg.State, _ = state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	destination := common.HexToAddress("0xcc")
+	cfg.State.SetCode(destination, code)
+	cfg.State.AddBalance(destination, big.NewInt(10))
+	cfg.State.SetCode(common.HexToAddress("0xee"), []byte{byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT)})
+
+	if _, _, err := Call(destination, nil, cfg); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	want := []string{
+		fmt.Sprintf("enter CALL %x->cc 0", cfg.Origin[19:]),
+		"enter CALL cc->e0 1",
+		"exit <nil>",
+		"enter CALL cc->ee 1",
+		"exit execution reverted",
+		"exit <nil>",
+	}
+	if strings.Join(tracer.frames, "\n") != strings.Join(want, "\n") {
+		t.Errorf("frame mismatch:\nhave:\n%s\nwant:\n%s", strings.Join(tracer.frames, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,175 @@
+package vm
+
+import (
+	"encoding/binary"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// The storage of the state expiry registry is split by the first byte of the
+// slots between the records below.
+const (
+	expiryAccessTag     = 1 // epoch+1 an account was last accessed in
+	expiryExpiredTag    = 2 // epoch+1 of the root an expired account is revived against
+	expiryEpochCountTag = 3 // number of accounts marked in an epoch
+	expiryEpochEntryTag = 4 // accounts marked in an epoch, in marking order
+	expiryEpochRootTag  = 5 // state root at the end of an epoch
+	expirySweepTag      = 6 // epoch+1 being swept and index+1 of the next account to sweep in it
+)
+
+// expiryAccountSlot returns the registry slot of a per account record.
+func expiryAccountSlot(tag byte, addr common.Address) common.Hash {
+	slot := crypto.Keccak256Hash(addr.Bytes())
+	slot[0] = tag
+	return slot
+}
+
+// expiryEpochSlot returns the registry slot of a per epoch record.
+func expiryEpochSlot(tag byte, epoch uint64, index uint64) common.Hash {
+	var slot common.Hash
+	slot[0] = tag
+	binary.BigEndian.PutUint64(slot[common.HashLength-16:], epoch)
+	binary.BigEndian.PutUint64(slot[common.HashLength-8:], index)
+	return slot
+}
+
+// readExpiryNumber reads a number stored incremented in the registry, so that
+// unused slots never match, false if the slot is unused.
+func readExpiryNumber(db StateDB, slot common.Hash) (uint64, bool) {
+	stored := db.GetState(params.StateExpiryAddress, slot)
+	number := binary.BigEndian.Uint64(stored[common.HashLength-8:])
+	if number == 0 {
+		return 0, false
+	}
+	return number - 1, true
+}
+
+// writeExpiryNumber stores a number incremented in the registry.
+func writeExpiryNumber(db StateDB, slot common.Hash, number uint64) {
+	// Keep the system account non-empty, so it is not deleted as an empty account
+	if db.GetNonce(params.StateExpiryAddress) == 0 {
+		db.SetNonce(params.StateExpiryAddress, 1)
+	}
+	var stored common.Hash
+	binary.BigEndian.PutUint64(stored[common.HashLength-8:], number+1)
+	db.SetState(params.StateExpiryAddress, slot, stored)
+}
+
+// ReadAccessEpoch returns the epoch the account was last accessed in, false if
+// it was not accessed since the state expiry fork.
+func ReadAccessEpoch(db StateDB, addr common.Address) (uint64, bool) {
+	return readExpiryNumber(db, expiryAccountSlot(expiryAccessTag, addr))
+}
+
+// WriteAccessEpoch marks the account as accessed in the given epoch and adds
+// it to the accounts of the epoch, to be swept if not accessed again.
+func WriteAccessEpoch(db StateDB, addr common.Address, epoch uint64) {
+	writeExpiryNumber(db, expiryAccountSlot(expiryAccessTag, addr), epoch)
+
+	count, _ := readExpiryNumber(db, expiryEpochSlot(expiryEpochCountTag, epoch, 0))
+	db.SetState(params.StateExpiryAddress, expiryEpochSlot(expiryEpochEntryTag, epoch, count), addr.Hash())
+	writeExpiryNumber(db, expiryEpochSlot(expiryEpochCountTag, epoch, 0), count+1)
+}
+
+// ReadExpiryEpochAccounts returns the accounts marked in the given epoch. The
+// accounts accessed again later are included.
+func ReadExpiryEpochAccounts(db StateDB, epoch uint64) []common.Address {
+	count := ReadExpiryEpochCount(db, epoch)
+	accounts := make([]common.Address, count)
+	for i := uint64(0); i < count; i++ {
+		accounts[i] = ReadExpiryEpochAccount(db, epoch, i)
+	}
+	return accounts
+}
+
+// ReadExpiryEpochCount returns the number of accounts marked in the given epoch.
+func ReadExpiryEpochCount(db StateDB, epoch uint64) uint64 {
+	count, _ := readExpiryNumber(db, expiryEpochSlot(expiryEpochCountTag, epoch, 0))
+	return count
+}
+
+// ReadExpiryEpochAccount returns the account marked at the index in the given
+// epoch.
+func ReadExpiryEpochAccount(db StateDB, epoch uint64, index uint64) common.Address {
+	return common.BytesToAddress(db.GetState(params.StateExpiryAddress, expiryEpochSlot(expiryEpochEntryTag, epoch, index)).Bytes())
+}
+
+// DeleteExpiryEpochAccount removes the account marked at the index in the given
+// epoch, along with the count of the epoch once the last account is removed.
+func DeleteExpiryEpochAccount(db StateDB, epoch uint64, index uint64) {
+	db.SetState(params.StateExpiryAddress, expiryEpochSlot(expiryEpochEntryTag, epoch, index), common.Hash{})
+	if index+1 >= ReadExpiryEpochCount(db, epoch) {
+		db.SetState(params.StateExpiryAddress, expiryEpochSlot(expiryEpochCountTag, epoch, 0), common.Hash{})
+	}
+}
+
+// DeleteExpiryEpochAccounts removes the accounts marked in the given epoch.
+func DeleteExpiryEpochAccounts(db StateDB, epoch uint64) {
+	count, _ := readExpiryNumber(db, expiryEpochSlot(expiryEpochCountTag, epoch, 0))
+	for i := uint64(0); i < count; i++ {
+		db.SetState(params.StateExpiryAddress, expiryEpochSlot(expiryEpochEntryTag, epoch, i), common.Hash{})
+	}
+	db.SetState(params.StateExpiryAddress, expiryEpochSlot(expiryEpochCountTag, epoch, 0), common.Hash{})
+}
+
+// ReadExpirySweep returns the epoch whose accounts are being swept and the
+// index of the next account to sweep in it, zero for both before the first
+// sweep.
+func ReadExpirySweep(db StateDB) (uint64, uint64) {
+	epoch, _ := readExpiryNumber(db, expiryEpochSlot(expirySweepTag, 0, 0))
+	index, _ := readExpiryNumber(db, expiryEpochSlot(expirySweepTag, 0, 1))
+	return epoch, index
+}
+
+// WriteExpirySweep records the progress of the sweep.
+func WriteExpirySweep(db StateDB, epoch uint64, index uint64) {
+	writeExpiryNumber(db, expiryEpochSlot(expirySweepTag, 0, 0), epoch)
+	writeExpiryNumber(db, expiryEpochSlot(expirySweepTag, 0, 1), index)
+}
+
+// ReadExpiryRoot returns the state root at the end of the given epoch, zero
+// if it is not recorded.
+func ReadExpiryRoot(db StateDB, epoch uint64) common.Hash {
+	return db.GetState(params.StateExpiryAddress, expiryEpochSlot(expiryEpochRootTag, epoch, 0))
+}
+
+// WriteExpiryRoot records the state root at the end of the given epoch.
+func WriteExpiryRoot(db StateDB, epoch uint64, root common.Hash) {
+	if db.GetNonce(params.StateExpiryAddress) == 0 {
+		db.SetNonce(params.StateExpiryAddress, 1)
+	}
+	db.SetState(params.StateExpiryAddress, expiryEpochSlot(expiryEpochRootTag, epoch, 0), root)
+}
+
+// ReadExpiredEpoch returns the epoch whose root an expired account is revived
+// against, false if the account is not expired.
+func ReadExpiredEpoch(db StateDB, addr common.Address) (uint64, bool) {
+	return readExpiryNumber(db, expiryAccountSlot(expiryExpiredTag, addr))
+}
+
+// WriteExpired marks the account as expired, revivable against the root of the
+// given epoch, and drops its access mark.
+func WriteExpired(db StateDB, addr common.Address, epoch uint64) {
+	writeExpiryNumber(db, expiryAccountSlot(expiryExpiredTag, addr), epoch)
+	db.SetState(params.StateExpiryAddress, expiryAccountSlot(expiryAccessTag, addr), common.Hash{})
+}
+
+// DeleteExpired marks a revived account as live again.
+func DeleteExpired(db StateDB, addr common.Address) {
+	db.SetState(params.StateExpiryAddress, expiryAccountSlot(expiryExpiredTag, addr), common.Hash{})
+}
+
+// IsAccountExpired returns whether the account was removed by the state expiry
+// and not revived since.
+func IsAccountExpired(db StateDB, addr common.Address) bool {
+	_, expired := ReadExpiredEpoch(db, addr)
+	return expired
+}
+
+// accountExpired returns whether the account is expired and may thus not be
+// called or created until revived.
+func (evm *EVM) accountExpired(addr common.Address) bool {
+	return evm.chainRules.IsStateExpiry && IsAccountExpired(evm.StateDB, addr)
+}
@@ -0,0 +1,59 @@
+package vm
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+func TestStateExpiryRegistry(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+
+	var (
+		alice = common.HexToAddress("0x1100000000000000000000000000000000000001")
+		bob   = common.HexToAddress("0x1100000000000000000000000000000000000002")
+	)
+	if _, ok := ReadAccessEpoch(statedb, alice); ok {
+		t.Fatalf("unmarked account reported as accessed")
+	}
+	WriteAccessEpoch(statedb, alice, 3)
+	WriteAccessEpoch(statedb, bob, 3)
+	WriteAccessEpoch(statedb, alice, 4)
+
+	if epoch, ok := ReadAccessEpoch(statedb, alice); !ok || epoch != 4 {
+		t.Errorf("access epoch mismatch: have %d, %v", epoch, ok)
+	}
+	if accounts := ReadExpiryEpochAccounts(statedb, 3); len(accounts) != 2 || accounts[0] != alice || accounts[1] != bob {
+		t.Errorf("epoch accounts mismatch: %v", accounts)
+	}
+	DeleteExpiryEpochAccounts(statedb, 3)
+	if accounts := ReadExpiryEpochAccounts(statedb, 3); len(accounts) != 0 {
+		t.Errorf("deleted epoch accounts remain: %v", accounts)
+	}
+	WriteExpired(statedb, bob, 4)
+	if epoch, ok := ReadExpiredEpoch(statedb, bob); !ok || epoch != 4 {
+		t.Errorf("expired epoch mismatch: have %d, %v", epoch, ok)
+	}
+	if _, ok := ReadAccessEpoch(statedb, bob); ok {
+		t.Errorf("expired account still marked as accessed")
+	}
+	if statedb.GetNonce(params.StateExpiryAddress) != 1 {
+		t.Errorf("registry account may be deleted as empty")
+	}
+	// Expired accounts can not be called until revived
+	config := *params.TestChainConfig
+	config.StateExpiry = &params.StateExpiryConfig{Block: big.NewInt(0), EpochLength: 10}
+	vmenv := NewEVM(BlockContext{BlockNumber: big.NewInt(50), CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true }}, TxContext{}, statedb, &config, Config{})
+	if _, _, err := vmenv.Call(AccountRef(alice), bob, nil, 100000, new(big.Int)); !errors.Is(err, ErrAccountExpired) {
+		t.Errorf("call to expired account mismatch: have %v, want %v", err, ErrAccountExpired)
+	}
+	DeleteExpired(statedb, bob)
+	if IsAccountExpired(statedb, bob) {
+		t.Errorf("revived account still expired")
+	}
+}
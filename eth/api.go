@@ -30,12 +30,18 @@ import (
 
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/consensus/misc"
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/rawdb"
 	"github.com/spruce-solutions/go-quai/core/state"
 	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethclient/quaiclient"
 	"github.com/spruce-solutions/go-quai/internal/ethapi"
 	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/miner"
+	"github.com/spruce-solutions/go-quai/miner/auxpow"
+	"github.com/spruce-solutions/go-quai/miner/sharechain"
+	"github.com/spruce-solutions/go-quai/params"
 	"github.com/spruce-solutions/go-quai/rlp"
 	"github.com/spruce-solutions/go-quai/rpc"
 	"github.com/spruce-solutions/go-quai/trie"
@@ -67,6 +73,177 @@ func (api *PublicEthereumAPI) Hashrate() hexutil.Uint64 {
 	return hexutil.Uint64(api.e.Miner().Hashrate())
 }
 
+// DomClientStatus returns the health of the dominant chain endpoints, the
+// active one serving the calls to the dominant chain.
+func (api *PublicEthereumAPI) DomClientStatus() ([]quaiclient.EndpointStatus, error) {
+	status := api.e.blockchain.DomClientStatus()
+	if status == nil {
+		return nil, errors.New("no dominant chain client")
+	}
+	return status, nil
+}
+
+// PendingEtxs returns the external transactions destined to this chain not
+// yet applied, pending ones having their origin block linked in by a coincident
+// block and queued ones awaiting it.
+func (api *PublicEthereumAPI) PendingEtxs() map[string][]*core.EtxEntry {
+	pending, queued := api.e.blockchain.EtxPool().Content()
+	return map[string][]*core.EtxEntry{
+		"pending": pending,
+		"queued":  queued,
+	}
+}
+
+// RPCInternalTransaction is an internal transaction of a block, in the form
+// returned over RPC.
+type RPCInternalTransaction struct {
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint   `json:"transactionIndex"`
+	Type        string         `json:"type"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *hexutil.Big   `json:"value"`
+	Depth       hexutil.Uint   `json:"depth"`
+}
+
+// GetInternalTransactions returns the value transfers made by the internal calls
+// of a block's transactions. Given the hash of a transaction instead of a block,
+// only the transfers of that transaction are returned.
+func (api *PublicEthereumAPI) GetInternalTransactions(ctx context.Context, blockOrTx rpc.BlockNumberOrHash) ([]*RPCInternalTransaction, error) {
+	if api.e.config.NoInternalTxIndex {
+		return nil, errors.New("internal transaction index disabled")
+	}
+	var txHash *common.Hash
+	if hash, ok := blockOrTx.Hash(); ok {
+		if tx, blockHash, _, _ := rawdb.ReadTransaction(api.e.chainDb, hash); tx != nil {
+			txHash, blockOrTx = &hash, rpc.BlockNumberOrHashWithHash(blockHash, false)
+		}
+	}
+	header, err := api.e.APIBackend.HeaderByNumberOrHash(ctx, blockOrTx)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("block not found")
+	}
+	var (
+		hash   = header.Hash()
+		number = header.Number[types.QuaiNetworkContext].Uint64()
+	)
+	itxs := rawdb.ReadInternalTxs(api.e.chainDb, hash, number)
+	if itxs == nil {
+		return nil, fmt.Errorf("internal transactions of block %x not indexed", hash)
+	}
+	result := []*RPCInternalTransaction{}
+	for _, itx := range itxs {
+		if txHash != nil && itx.TxHash != *txHash {
+			continue
+		}
+		result = append(result, &RPCInternalTransaction{
+			BlockHash:   hash,
+			BlockNumber: hexutil.Uint64(number),
+			TxHash:      itx.TxHash,
+			TxIndex:     hexutil.Uint(itx.TxIndex),
+			Type:        itx.Kind,
+			From:        itx.From,
+			To:          itx.To,
+			Value:       (*hexutil.Big)(itx.Value),
+			Depth:       hexutil.Uint(itx.Depth),
+		})
+	}
+	return result, nil
+}
+
+// RPCReorg is a journaled reorg, in the form returned over RPC.
+type RPCReorg struct {
+	Time     hexutil.Uint64 `json:"time"`
+	Context  hexutil.Uint64 `json:"context"`
+	Number   hexutil.Uint64 `json:"number"`
+	Ancestor common.Hash    `json:"ancestor"`
+	Depth    hexutil.Uint64 `json:"depth"`
+	Dropped  []common.Hash  `json:"dropped"`
+	Adopted  []common.Hash  `json:"adopted"`
+	OldTd    *hexutil.Big   `json:"oldTd"`
+	NewTd    *hexutil.Big   `json:"newTd"`
+	TdDelta  *hexutil.Big   `json:"tdDelta"`
+}
+
+// GetReorgHistory returns the most recent reorgs executed by the chain, newest
+// first. At most count reorgs are returned if it's given.
+func (api *PublicEthereumAPI) GetReorgHistory(count *hexutil.Uint) []*RPCReorg {
+	var limit int
+	if count != nil {
+		limit = int(*count)
+	}
+	result := []*RPCReorg{}
+	for _, entry := range rawdb.ReadReorgJournal(api.e.chainDb, limit) {
+		result = append(result, &RPCReorg{
+			Time:     hexutil.Uint64(entry.Time),
+			Context:  hexutil.Uint64(entry.Context),
+			Number:   hexutil.Uint64(entry.Number),
+			Ancestor: entry.Ancestor,
+			Depth:    hexutil.Uint64(len(entry.Dropped)),
+			Dropped:  entry.Dropped,
+			Adopted:  entry.Adopted,
+			OldTd:    (*hexutil.Big)(entry.OldTd),
+			NewTd:    (*hexutil.Big)(entry.NewTd),
+			TdDelta:  (*hexutil.Big)(new(big.Int).Sub(entry.NewTd, entry.OldTd)),
+		})
+	}
+	return result
+}
+
+// RPCTwist is a subordinate block rejected for twisting the dominant chains, in
+// the form returned over RPC.
+type RPCTwist struct {
+	Hash     common.Hash    `json:"hash"`
+	Number   []*hexutil.Big `json:"number"`
+	Location hexutil.Bytes  `json:"location"`
+	Order    hexutil.Uint64 `json:"order"`
+	Context  hexutil.Uint64 `json:"context"`
+	Stage    string         `json:"stage"`
+	Reason   string         `json:"reason"`
+	Error    string         `json:"error"`
+	Time     hexutil.Uint64 `json:"time"`
+}
+
+// GetTwists returns the most recent subordinate blocks rejected by PCRC for
+// twisting the dominant chains, newest first. At most count twists are returned
+// if it's given, and only the ones detected at the stage if it's given.
+func (api *PublicEthereumAPI) GetTwists(count *hexutil.Uint, stage *string) []*RPCTwist {
+	var (
+		limit  int
+		filter string
+	)
+	if count != nil {
+		limit = int(*count)
+	}
+	if stage != nil {
+		filter = *stage
+	}
+	result := []*RPCTwist{}
+	for _, twist := range api.e.blockchain.Twists().Twists(limit, filter) {
+		numbers := make([]*hexutil.Big, len(twist.Number))
+		for i, number := range twist.Number {
+			numbers[i] = (*hexutil.Big)(number)
+		}
+		result = append(result, &RPCTwist{
+			Hash:     twist.Hash,
+			Number:   numbers,
+			Location: twist.Location,
+			Order:    hexutil.Uint64(twist.Order),
+			Context:  hexutil.Uint64(twist.Context),
+			Stage:    twist.Stage,
+			Reason:   twist.Reason,
+			Error:    twist.Error,
+			Time:     hexutil.Uint64(twist.Time),
+		})
+	}
+	return result
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -83,6 +260,44 @@ func (api *PublicMinerAPI) Mining() bool {
 	return api.e.IsMining()
 }
 
+// SubmitShare adds a share sealed by a miner of the pool to the share chain, to
+// be gossiped to the other members.
+func (api *PublicMinerAPI) SubmitShare(header *types.Header) (common.Hash, error) {
+	shares := api.e.Miner().ShareChain()
+	if shares == nil {
+		return common.Hash{}, errors.New("share chain disabled")
+	}
+	share := sharechain.NewShare(header)
+	if err := shares.Add(share); err != nil {
+		return common.Hash{}, err
+	}
+	return share.Hash(), nil
+}
+
+// SharePayouts returns the split of the reward over the miners of the share
+// chain, the one the miner of a block found now owes the pool. The reward
+// defaults to the block reward, which the coinbase of the block receives whole.
+func (api *PublicMinerAPI) SharePayouts(reward *hexutil.Big) ([]*sharechain.Payout, error) {
+	shares := api.e.Miner().ShareChain()
+	if shares == nil {
+		return nil, errors.New("share chain disabled")
+	}
+	if reward == nil {
+		reward = (*hexutil.Big)(misc.CalculateReward())
+	}
+	return shares.Payouts(reward.ToInt()), nil
+}
+
+// GetAuxPow returns the proof that the block merge-mined the commitment of the
+// auxiliary chain, in the auxpow layout of merged mining.
+func (api *PublicMinerAPI) GetAuxPow(blockHash common.Hash, chainID common.Hash) (*auxpow.AuxPow, error) {
+	header := api.e.blockchain.GetHeaderByHash(blockHash)
+	if header == nil {
+		return nil, fmt.Errorf("block %#x not found", blockHash)
+	}
+	return api.e.Miner().AuxPow(header, chainID)
+}
+
 // PrivateMinerAPI provides private RPC methods to control the miner.
 // These methods can be abused by external users and must be considered insecure for use by untrusted users.
 type PrivateMinerAPI struct {
@@ -120,6 +335,15 @@ func (api *PrivateMinerAPI) SetExtra(extra string) (bool, error) {
 	return true, nil
 }
 
+// SetAuxCommitment sets the commitment of the auxiliary chain merge-mined with
+// the blocks, a zero commitment removing the chain.
+func (api *PrivateMinerAPI) SetAuxCommitment(chainID common.Hash, commitment common.Hash) (bool, error) {
+	if err := api.e.Miner().SetAuxCommitment(chainID, commitment); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // SetGasPrice sets the minimum accepted gas price for the miner.
 func (api *PrivateMinerAPI) SetGasPrice(gasPrice hexutil.Big) bool {
 	api.e.lock.Lock()
@@ -147,6 +371,37 @@ func (api *PrivateMinerAPI) SetRecommitInterval(interval int) {
 	api.e.Miner().SetRecommitInterval(time.Duration(interval) * time.Millisecond)
 }
 
+// SealLatency returns the latency breakdown of the recently sealed blocks, from
+// work generation to the first peer accepting the block.
+func (api *PrivateMinerAPI) SealLatency() []miner.LatencyReport {
+	return api.e.Miner().SealLatency().Reports()
+}
+
+// SetPolicy replaces the local transaction policy applied when building blocks,
+// a null policy clears it. Policies only affect the locally mined blocks.
+func (api *PrivateMinerAPI) SetPolicy(policy *miner.Policy) (bool, error) {
+	if err := api.e.Miner().SetPolicy(policy); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Policy returns the local transaction policy and the number of transactions it
+// excluded from the locally built blocks.
+func (api *PrivateMinerAPI) Policy() *miner.PolicyStatus {
+	return api.e.Miner().Policy()
+}
+
+// Election returns the state of the block producer election among redundant
+// miners.
+func (api *PrivateMinerAPI) Election() (*miner.ElectionStatus, error) {
+	status := api.e.Miner().Election()
+	if status == nil {
+		return nil, errors.New("block producer election disabled")
+	}
+	return status, nil
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -257,6 +512,122 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// SetMaxPeers fixes the maximum number of network peers, overriding the scaling
+// with the sync state. A zero limit resumes the scaling.
+func (api *PrivateAdminAPI) SetMaxPeers(max int) (*PeerTarget, error) {
+	if max < 0 {
+		return nil, fmt.Errorf("invalid peer limit %d", max)
+	}
+	if max > 0 && max <= api.eth.peerScaler.lightPeers {
+		return nil, fmt.Errorf("peer limit %d not above the light peer count %d", max, api.eth.peerScaler.lightPeers)
+	}
+	return api.eth.peerScaler.setOverride(max), nil
+}
+
+// PeerTarget returns the maximum number of network peers and its scaling state.
+func (api *PrivateAdminAPI) PeerTarget() *PeerTarget {
+	return api.eth.peerScaler.target()
+}
+
+// DomClient returns the state of the connection to the dominant chain.
+func (api *PrivateAdminAPI) DomClient() (*core.DomClientHealth, error) {
+	health := api.eth.blockchain.DomClientHealth()
+	if health == nil {
+		return nil, errors.New("no dominant chain client")
+	}
+	return health, nil
+}
+
+// VerifyChain starts re-deriving the transaction, uncle and receipt roots of the
+// canonical blocks from first to last in the background. If reexec is set, the
+// blocks are also re-executed to verify their state roots. The progress can be
+// followed with VerifyChainStatus.
+func (api *PrivateAdminAPI) VerifyChain(first uint64, last *uint64, reexec *bool) (*ChainVerifyStatus, error) {
+	head := api.eth.BlockChain().CurrentBlock().NumberU64()
+	if last == nil {
+		last = &head
+	}
+	if *last > head {
+		return nil, fmt.Errorf("last block %d above the head %d", *last, head)
+	}
+	if first > *last {
+		return nil, fmt.Errorf("first block %d above the last %d", first, *last)
+	}
+	return api.eth.verifier.start(first, *last, reexec != nil && *reexec)
+}
+
+// VerifyChainStatus returns the progress of the last chain verification, or
+// nil if none was started.
+func (api *PrivateAdminAPI) VerifyChainStatus() *ChainVerifyStatus {
+	return api.eth.verifier.progress()
+}
+
+// AbortVerifyChain stops the running chain verification, returning whether one
+// was running.
+func (api *PrivateAdminAPI) AbortVerifyChain() bool {
+	return api.eth.verifier.stop()
+}
+
+// RehearseFork starts validating the canonical blocks from first on under the
+// proposed chain config in the background, reporting the blocks its fork rules
+// would reject. Blocks imported while it runs are validated too, until it's
+// aborted. If first is omitted, the last rehearsalRange blocks are validated.
+// The canonical chain and database are not affected. The progress can be
+// followed with RehearseForkStatus.
+func (api *PrivateAdminAPI) RehearseFork(config *params.ChainConfig, first *uint64) (*RehearsalStatus, error) {
+	if config == nil {
+		return nil, errors.New("missing chain config")
+	}
+	current := api.eth.blockchain.Config()
+	if config.ChainID == nil || current.ChainID == nil || config.ChainID.Cmp(current.ChainID) != 0 {
+		return nil, fmt.Errorf("chain id %v doesn't match the chain id %v", config.ChainID, current.ChainID)
+	}
+	head := api.eth.BlockChain().CurrentBlock().NumberU64()
+	var from uint64
+	if head > rehearsalRange {
+		from = head - rehearsalRange
+	}
+	if first != nil {
+		from = *first
+	}
+	if from > head {
+		return nil, fmt.Errorf("first block %d above the head %d", from, head)
+	}
+	return api.eth.rehearsal.start(config, from)
+}
+
+// RehearseForkStatus returns the progress of the last fork rehearsal, or nil if
+// none was started.
+func (api *PrivateAdminAPI) RehearseForkStatus() *RehearsalStatus {
+	return api.eth.rehearsal.progress()
+}
+
+// AbortRehearseFork stops the running fork rehearsal, returning whether one was
+// running.
+func (api *PrivateAdminAPI) AbortRehearseFork() bool {
+	return api.eth.rehearsal.stop()
+}
+
+// ScheduleAction schedules the node lifecycle action at the block, executed once
+// the head reaches it: stopMining stops sealing, stopTxs rejects transactions
+// from RPC and peers until acceptTxs, and exit shuts the node down. Actions are
+// kept in memory and need to be scheduled again after a restart.
+func (api *PrivateAdminAPI) ScheduleAction(action string, block hexutil.Uint64) (*ScheduledAction, error) {
+	return api.eth.scheduler.schedule(action, uint64(block))
+}
+
+// ScheduledActions returns the node lifecycle actions scheduled, ordered by
+// block.
+func (api *PrivateAdminAPI) ScheduledActions() []ScheduledAction {
+	return api.eth.scheduler.list()
+}
+
+// CancelAction cancels the pending node lifecycle action, returning whether it
+// was pending.
+func (api *PrivateAdminAPI) CancelAction(id hexutil.Uint64) bool {
+	return api.eth.scheduler.cancel(uint64(id))
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -607,3 +978,58 @@ func (api *PrivateDebugAPI) GetAccessibleState(from, to rpc.BlockNumber) (uint64
 	}
 	return 0, fmt.Errorf("No state found")
 }
+
+const (
+	// defaultInvariantCheckRange is the number of blocks below the head checked
+	// by debug_runInvariantCheck if no starting block is given.
+	defaultInvariantCheckRange = 1024
+
+	// maxInvariantCheckRange is the maximum number of blocks checked by a call
+	// of debug_runInvariantCheck, which holds no lock but reads every block.
+	maxInvariantCheckRange = 16384
+)
+
+// InvariantCheckResult is the result of a debug_runInvariantCheck call.
+type InvariantCheckResult struct {
+	From       hexutil.Uint64            `json:"from"`
+	To         hexutil.Uint64            `json:"to"`
+	Violations []core.InvariantViolation `json:"violations"`
+}
+
+// RunInvariantCheck verifies the database invariants of the canonical chain
+// between the given blocks (inclusive) and reports every violation found, see
+// core.BlockChain.CheckInvariants. If to is omitted the current head is used,
+// if from is omitted the last defaultInvariantCheckRange blocks are checked.
+// Ranges over maxInvariantCheckRange blocks are rejected.
+func (api *PrivateDebugAPI) RunInvariantCheck(from, to *rpc.BlockNumber) (*InvariantCheckResult, error) {
+	head := api.eth.blockchain.CurrentBlock()
+	if head == nil {
+		return nil, fmt.Errorf("current block missing")
+	}
+	end := head.NumberU64()
+	if to != nil && to.Int64() >= 0 {
+		end = uint64(to.Int64())
+	}
+	var start uint64
+	if end > defaultInvariantCheckRange {
+		start = end - defaultInvariantCheckRange
+	}
+	if from != nil && from.Int64() >= 0 {
+		start = uint64(from.Int64())
+	}
+	if start > end {
+		return nil, fmt.Errorf("start block %d after end block %d", start, end)
+	}
+	if end-start >= maxInvariantCheckRange {
+		return nil, fmt.Errorf("range of %d blocks exceeds the limit of %d", end-start+1, maxInvariantCheckRange)
+	}
+	violations := api.eth.blockchain.CheckInvariants(start, end)
+	if len(violations) > 0 {
+		log.Warn("Invariant check found violations", "from", start, "to", end, "violations", len(violations))
+	}
+	return &InvariantCheckResult{
+		From:       hexutil.Uint64(start),
+		To:         hexutil.Uint64(end),
+		Violations: violations,
+	}, nil
+}
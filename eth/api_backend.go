@@ -20,20 +20,24 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"sync/atomic"
 
 	ethereum "github.com/spruce-solutions/go-quai"
 	"github.com/spruce-solutions/go-quai/accounts"
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/contractmeta"
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/bloombits"
 	"github.com/spruce-solutions/go-quai/core/rawdb"
 	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/state/snapshot"
 	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/core/vm"
 	"github.com/spruce-solutions/go-quai/eth/gasprice"
 	"github.com/spruce-solutions/go-quai/ethdb"
 	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/labels"
 	"github.com/spruce-solutions/go-quai/miner"
 	"github.com/spruce-solutions/go-quai/params"
 	"github.com/spruce-solutions/go-quai/rpc"
@@ -52,6 +56,22 @@ func (b *EthAPIBackend) ChainConfig() *params.ChainConfig {
 	return b.eth.blockchain.Config()
 }
 
+// ContractMetadata implements contractmeta.Backend, returning the store of
+// verified contract metadata, nil if none was installed.
+func (b *EthAPIBackend) ContractMetadata() contractmeta.Store {
+	return b.eth.contractMeta
+}
+
+// AddressLabels implements labels.Backend, returning the node-local address
+// labels, nil if none were installed.
+func (b *EthAPIBackend) AddressLabels() labels.Labeler {
+	return b.eth.addressLabels
+}
+
+// errPendingUnavailable is returned for pending queries while the miner could
+// not assemble a pending block on the current head.
+var errPendingUnavailable = errors.New("pending block not available")
+
 func (b *EthAPIBackend) CurrentBlock() *types.Block {
 	return b.eth.blockchain.CurrentBlock()
 }
@@ -65,6 +85,9 @@ func (b *EthAPIBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumb
 	// Pending block is only known by the miner
 	if number == rpc.PendingBlockNumber {
 		block := b.eth.miner.PendingBlock()
+		if block == nil {
+			return nil, errPendingUnavailable
+		}
 		return block.Header(), nil
 	}
 	// Otherwise resolve and return the block
@@ -99,6 +122,9 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumbe
 	// Pending block is only known by the miner
 	if number == rpc.PendingBlockNumber {
 		block := b.eth.miner.PendingBlock()
+		if block == nil {
+			return nil, errPendingUnavailable
+		}
 		return block, nil
 	}
 	// Otherwise resolve and return the block
@@ -132,6 +158,10 @@ func (b *EthAPIBackend) GetExternalBlockByHashAndContext(hash common.Hash, conte
 	return b.eth.blockchain.GetExternalBlockByHashAndContext(hash, context)
 }
 
+func (b *EthAPIBackend) GetExternalBlocks(header *types.Header) ([]*types.ExternalBlock, error) {
+	return b.eth.blockchain.GetExternalBlocks(header)
+}
+
 func (b *EthAPIBackend) GetSubordinateSet(stopHash common.Hash, location []byte) ([]common.Hash, error) {
 	return b.eth.blockchain.GetSubordinateSet(stopHash, location)
 }
@@ -197,6 +227,9 @@ func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.B
 	// Pending state is only known by the miner
 	if number == rpc.PendingBlockNumber {
 		block, state := b.eth.miner.Pending()
+		if block == nil || state == nil {
+			return nil, nil, errPendingUnavailable
+		}
 		return state, block.Header(), nil
 	}
 	// Otherwise resolve the block number and return its state
@@ -211,6 +244,13 @@ func (b *EthAPIBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.B
 	return stateDb, header, err
 }
 
+func (b *EthAPIBackend) Snapshot(root common.Hash) snapshot.Snapshot {
+	if snaps := b.eth.blockchain.Snapshots(); snaps != nil {
+		return snaps.Snapshot(root)
+	}
+	return nil
+}
+
 func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.StateAndHeaderByNumber(ctx, blockNr)
@@ -279,6 +319,10 @@ func (b *EthAPIBackend) SubscribeReOrgEvent(ch chan<- core.ReOrgRollup) event.Su
 	return b.eth.BlockChain().SubscribeReOrgEvent(ch)
 }
 
+func (b *EthAPIBackend) SubscribeForkChoiceEvent(ch chan<- core.ForkChoiceEvent) event.Subscription {
+	return b.eth.BlockChain().SubscribeForkChoiceEvent(ch)
+}
+
 func (b *EthAPIBackend) SubscribeMissingExternalBlockEvent(ch chan<- core.MissingExternalBlock) event.Subscription {
 	return b.eth.BlockChain().SubscribeMissingExternalBlockEvent(ch)
 }
@@ -304,6 +348,12 @@ func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	if atomic.LoadUint32(&b.eth.handler.haltTxs) == 1 {
+		return errTxsHalted
+	}
+	if forwarded, err := b.eth.forwarder.forward(ctx, signedTx); forwarded {
+		return err
+	}
 	return b.eth.txPool.AddLocal(signedTx)
 }
 
@@ -28,10 +28,12 @@ import (
 	"time"
 
 	"github.com/spruce-solutions/go-quai/accounts"
+	"github.com/spruce-solutions/go-quai/accounts/multisig"
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/common/hexutil"
 	"github.com/spruce-solutions/go-quai/consensus"
 	"github.com/spruce-solutions/go-quai/consensus/clique"
+	"github.com/spruce-solutions/go-quai/contractmeta"
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/bloombits"
 	"github.com/spruce-solutions/go-quai/core/rawdb"
@@ -43,10 +45,12 @@ import (
 	"github.com/spruce-solutions/go-quai/eth/filters"
 	"github.com/spruce-solutions/go-quai/eth/gasprice"
 	"github.com/spruce-solutions/go-quai/eth/protocols/eth"
+	"github.com/spruce-solutions/go-quai/eth/protocols/share"
 	"github.com/spruce-solutions/go-quai/eth/protocols/snap"
 	"github.com/spruce-solutions/go-quai/ethdb"
 	"github.com/spruce-solutions/go-quai/event"
 	"github.com/spruce-solutions/go-quai/internal/ethapi"
+	"github.com/spruce-solutions/go-quai/labels"
 	"github.com/spruce-solutions/go-quai/log"
 	"github.com/spruce-solutions/go-quai/miner"
 	"github.com/spruce-solutions/go-quai/node"
@@ -72,6 +76,7 @@ type Ethereum struct {
 	handler            *handler
 	ethDialCandidates  enode.Iterator
 	snapDialCandidates enode.Iterator
+	shareHandler       *share.Handler // Gossip of the pool share chain, nil if solo mining
 
 	// DB interfaces
 	chainDb ethdb.Database // Block chain database
@@ -93,6 +98,17 @@ type Ethereum struct {
 	networkID     uint64
 	netRPCService *ethapi.PublicNetAPI
 
+	migrator  *rawdb.Migrator // Background schema migrations of the chain database
+	verifier  chainVerifier   // Background verification of the canonical chain roots
+	rehearsal forkRehearsal   // Background validation of the chain under proposed fork rules
+	scheduler actionScheduler // Node lifecycle actions scheduled at a block height
+	forwarder *txForwarder    // Relay of the transactions submitted for other locations
+
+	peerScaler *peerScaler // Peer limit scaling with the sync state
+
+	contractMeta  contractmeta.Store // Verified contract metadata decoding calls and events, nil if none
+	addressLabels labels.Labeler     // Node-local address labels annotating traces, nil if none
+
 	p2pServer *p2p.Server
 
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
@@ -108,6 +124,9 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
+	if config.SyncMode == downloader.CheckpointSync && config.CheckpointAnchor == (common.Hash{}) {
+		return nil, errors.New("checkpoint sync mode requires a checkpoint anchor")
+	}
 	if config.Miner.GasPrice == nil || config.Miner.GasPrice.Cmp(common.Big0) <= 0 {
 		log.Warn("Sanitizing invalid miner gas price", "provided", config.Miner.GasPrice, "updated", ethconfig.Defaults.Miner.GasPrice)
 		config.Miner.GasPrice = new(big.Int).Set(ethconfig.Defaults.Miner.GasPrice)
@@ -126,8 +145,13 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	// Transfer mining-related config to the ethash config.
 	blake3Config := config.Blake3
 	blake3Config.NotifyFull = config.Miner.NotifyFull
+	blake3Config.ThreadsAffinity = config.Miner.ThreadsAffinity
+	blake3Config.DutyCycle = config.Miner.DutyCycle
+	blake3Config.StratumAddr = config.Miner.Stratum
+	blake3Config.StratumDifficulty = config.Miner.StratumDifficulty
 
 	// Assemble the Ethereum object
+	rawdb.SetCompression(config.DatabaseCompress)
 	chainDb, err := stack.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/", false)
 	if err != nil {
 		return nil, err
@@ -136,12 +160,18 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
 	}
+	if config.SyncMode == downloader.CheckpointSync && chainConfig.Context != params.ZONE {
+		return nil, fmt.Errorf("checkpoint sync mode is only supported by zone nodes, context %d", chainConfig.Context)
+	}
 
+	// The knot is only written along with a configured genesis, nodes started
+	// without one rely on the database content
 	knotSet := make([]*types.Block, 0)
-	switch types.QuaiNetworkContext {
-	case params.PRIME:
+	switch {
+	case config.Genesis == nil:
+	case types.QuaiNetworkContext == params.PRIME:
 		knotSet = config.Genesis.Knot
-	case params.REGION:
+	case types.QuaiNetworkContext == params.REGION:
 		for i, block := range config.Genesis.Knot {
 			if i != 0 {
 				if block.Header().Location[0] == chainConfig.Location[0] {
@@ -149,7 +179,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 				}
 			}
 		}
-	case params.ZONE:
+	case types.QuaiNetworkContext == params.ZONE:
 		for i, block := range config.Genesis.Knot {
 			if i != 0 {
 				if bytes.Equal(block.Header().Location, chainConfig.Location) {
@@ -173,6 +203,14 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 
 	log.Info("Initialised chain configuration", "config", chainConfig)
 
+	// Expired accounts are revived from their storage tries, which must thus be
+	// kept, and the snapshots have no way to restore the revived storage
+	if chainConfig.StateExpiry != nil {
+		log.Warn("Experimental state expiry enabled, forcing archive mode without snapshots", "block", chainConfig.StateExpiry.Block, "epoch", chainConfig.StateExpiry.EpochLength)
+		config.NoPruning = true
+		config.SnapshotCache = 0
+	}
+
 	if err := pruner.RecoverPruning(stack.ResolvePath(""), chainDb, stack.ResolvePath(config.TrieCleanCacheJournal)); err != nil {
 		log.Error("Failed to recover state", "error", err)
 	}
@@ -196,7 +234,25 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if bcVersion != nil {
 		dbVer = fmt.Sprintf("%d", *bcVersion)
 	}
-	log.Info("Initialising Quai Network protocol", "network", config.NetworkId, "dbversion", dbVer)
+	log.Info("Initialising Quai Network protocol", "network", config.NetworkId, "dbversion", dbVer, "schema", rawdb.ReadSchemaVersion(chainDb))
+
+	// Fresh databases are written in the latest schema, nothing to migrate
+	if bcVersion == nil {
+		rawdb.WriteSchemaVersion(chainDb, rawdb.SchemaVersion)
+	}
+	eth.migrator = rawdb.NewMigrator(chainDb)
+	if err := eth.migrator.Check(); err != nil {
+		return nil, err
+	}
+	eth.verifier.eth = eth
+	eth.rehearsal.eth = eth
+	eth.scheduler.eth = eth
+	eth.forwarder = newTxForwarder(chainConfig.ChainID, config.TxForwardUrls, config.Client)
+	eth.scheduler.exit = func() {
+		if err := stack.Close(); err != nil {
+			log.Error("Failed to shut down for scheduled exit", "err", err)
+		}
+	}
 
 	if !config.SkipBcVersionCheck {
 		if bcVersion != nil && *bcVersion > core.BlockChainVersion {
@@ -213,17 +269,26 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			EnablePreimageRecording: config.EnablePreimageRecording,
 		}
 		cacheConfig = &core.CacheConfig{
-			TrieCleanLimit:       config.TrieCleanCache,
-			TrieCleanJournal:     stack.ResolvePath(config.TrieCleanCacheJournal),
-			TrieCleanRejournal:   config.TrieCleanCacheRejournal,
-			TrieCleanNoPrefetch:  config.NoPrefetch,
-			TrieDirtyLimit:       config.TrieDirtyCache,
-			TrieDirtyDisabled:    config.NoPruning,
-			TrieTimeLimit:        config.TrieTimeout,
-			SnapshotLimit:        config.SnapshotCache,
-			Preimages:            config.Preimages,
-			ExternalBlockLimit:   config.ExternalBlockCache,
-			ExternalBlockJournal: stack.ResolvePath(config.ExternalBlocksCacheJournal),
+			TrieCleanLimit:         config.TrieCleanCache,
+			TrieCleanJournal:       stack.ResolvePath(config.TrieCleanCacheJournal),
+			TrieCleanRejournal:     config.TrieCleanCacheRejournal,
+			TrieCleanNoPrefetch:    config.NoPrefetch,
+			TrieDirtyLimit:         config.TrieDirtyCache,
+			TrieDirtyDisabled:      config.NoPruning,
+			TrieTimeLimit:          config.TrieTimeout,
+			SnapshotLimit:          config.SnapshotCache,
+			Preimages:              config.Preimages,
+			InternalTxIndex:        !config.NoInternalTxIndex,
+			ExternalBlockLimit:     config.ExternalBlockCache,
+			ExternalBlockJournal:   stack.ResolvePath(config.ExternalBlocksCacheJournal),
+			PCRCCacheLimit:         config.PCRCCache,
+			Client:                 config.Client,
+			Tiebreak:               config.Tiebreak,
+			TiebreakProbability:    config.TiebreakProbability,
+			MaxReorgDepth:          config.MaxReorgDepth,
+			FutureBlockLimit:       config.FutureBlocks,
+			FutureBlockHorizon:     config.FutureBlockHorizon,
+			InvariantCheckInterval: config.InvariantCheckInterval,
 		}
 	)
 
@@ -238,8 +303,6 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		eth.blockchain.SetHead(compat.RewindTo)
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
-	eth.bloomIndexer.Start(eth.blockchain)
-
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
@@ -260,13 +323,26 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		BloomCache: uint64(cacheLimit),
 		EventMux:   eth.eventMux,
 		Checkpoint: checkpoint,
+		Anchor:     config.CheckpointAnchor,
 		Whitelist:  config.Whitelist,
 	}); err != nil {
 		return nil, err
 	}
 
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
-	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
+	if shares := eth.miner.ShareChain(); shares != nil {
+		eth.shareHandler = share.NewHandler(shares)
+	}
+	extra := config.Miner.ExtraData
+	if config.Miner.ExtraTemplate != "" {
+		rendered, err := miner.RenderExtra(config.Miner.ExtraTemplate, config.Miner.ExtraPool, config.Miner.ExtraWorker)
+		if err != nil {
+			return nil, err
+		}
+		extra = rendered
+	}
+	eth.miner.SetExtra(makeExtraData(extra))
+	eth.handler.sealLatency = eth.miner.SealLatency()
 
 	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil}
 	if eth.APIBackend.allowUnprotectedTxs {
@@ -384,6 +460,10 @@ func (s *Ethereum) APIs() []rpc.API {
 			Version:   "1.0",
 			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute),
 			Public:    true,
+		}, {
+			Namespace: "multisig",
+			Version:   "1.0",
+			Service:   multisig.NewPrivateMultisigAPI(s.accountManager, s.blockchain.Config().ChainID),
 		}, {
 			Namespace: "admin",
 			Version:   "1.0",
@@ -556,6 +636,23 @@ func (s *Ethereum) StopMining() {
 	s.miner.Stop()
 }
 
+// SetContractMetadata installs the store of verified contract metadata the
+// tracing and log APIs decode calls and events with.
+func (s *Ethereum) SetContractMetadata(store contractmeta.Store) {
+	s.contractMeta = store
+}
+
+// SetAddressLabels installs the address labels the tracing APIs annotate their
+// output with.
+func (s *Ethereum) SetAddressLabels(labeler labels.Labeler) {
+	s.addressLabels = labeler
+}
+
+// RebroadcastTransactions announces the given transactions to all peers again.
+func (s *Ethereum) RebroadcastTransactions(txs types.Transactions) {
+	s.handler.RebroadcastTransactions(txs)
+}
+
 func (s *Ethereum) IsMining() bool      { return s.miner.Mining() }
 func (s *Ethereum) Miner() *miner.Miner { return s.miner }
 
@@ -576,7 +673,10 @@ func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
 func (s *Ethereum) Protocols() []p2p.Protocol {
 	protos := eth.MakeProtocols((*ethHandler)(s.handler), s.networkID, s.ethDialCandidates)
 	if s.config.SnapshotCache > 0 {
-		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
+		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates, s.config.SnapServe)...)
+	}
+	if s.shareHandler != nil {
+		protos = append(protos, s.shareHandler.MakeProtocols()...)
 	}
 	return protos
 }
@@ -586,19 +686,34 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 func (s *Ethereum) Start() error {
 	eth.StartENRUpdater(s.blockchain, s.p2pServer.LocalNode())
 
+	// Start indexing the bloom bits once the endpoints are open, catching up on
+	// the sections missed while down competes with the startup otherwise
+	s.bloomIndexer.Start(s.blockchain)
+
 	// Start the bloom bits servicing goroutines
 	s.startBloomHandlers(params.BloomBitsBlocks)
 
+	// Upgrade the database schema in the background
+	s.migrator.Start()
+
 	// Figure out a max peers count based on the server limits
-	maxPeers := s.p2pServer.MaxPeers
+	maxPeers, lightPeers := s.p2pServer.MaxPeers, 0
 	if s.config.LightServ > 0 {
 		if s.config.LightPeers >= s.p2pServer.MaxPeers {
 			return fmt.Errorf("invalid peer config: light peer count (%d) >= total peer count (%d)", s.config.LightPeers, s.p2pServer.MaxPeers)
 		}
-		maxPeers -= s.config.LightPeers
+		lightPeers = s.config.LightPeers
+		maxPeers -= lightPeers
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers)
+	if s.shareHandler != nil {
+		s.shareHandler.Start()
+	}
+
+	// Scale the peer limit with the sync state
+	s.peerScaler = newPeerScaler(s, s.p2pServer.MaxPeers, s.config.SyncMaxPeers, lightPeers)
+	s.peerScaler.start()
 	return nil
 }
 
@@ -606,13 +721,22 @@ func (s *Ethereum) Start() error {
 // Ethereum protocol.
 func (s *Ethereum) Stop() error {
 	// Stop all the peer-related stuff first.
+	s.peerScaler.stop()
 	s.ethDialCandidates.Close()
 	s.snapDialCandidates.Close()
 	s.handler.Stop()
+	if s.shareHandler != nil {
+		s.shareHandler.Stop()
+	}
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
+	s.migrator.Stop()
+	s.verifier.stop()
+	s.rehearsal.stop()
+	s.scheduler.stop()
+	s.forwarder.close()
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.blockchain.Stop()
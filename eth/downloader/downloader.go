@@ -27,6 +27,7 @@ import (
 
 	ethereum "github.com/spruce-solutions/go-quai"
 	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
 	"github.com/spruce-solutions/go-quai/core/rawdb"
 	"github.com/spruce-solutions/go-quai/core/state/snapshot"
 	"github.com/spruce-solutions/go-quai/core/types"
@@ -70,6 +71,8 @@ var (
 	errBadPeer                 = errors.New("action from bad peer ignored")
 	errStallingPeer            = errors.New("peer is stalling")
 	errUnsyncedPeer            = errors.New("unsynced peer")
+	errNoAnchor                = errors.New("checkpoint sync without anchor")
+	errInvalidAnchor           = errors.New("invalid checkpoint anchor")
 	errNoPeers                 = errors.New("no peers to keep download active")
 	errTimeout                 = errors.New("timeout")
 	errEmptyHeaderSet          = errors.New("empty header set by peer")
@@ -77,6 +80,7 @@ var (
 	errInvalidAncestor         = errors.New("retrieved ancestor is invalid")
 	errInvalidChain            = errors.New("retrieved hash chain is invalid")
 	errInvalidBody             = errors.New("retrieved block body is invalid")
+	errSliceNotReady           = errors.New("slice not ready for the retrieved chain")
 	errInvalidReceipt          = errors.New("retrieved receipt is invalid")
 	errCancelStateFetch        = errors.New("state data download canceled (requested)")
 	errCancelContentProcessing = errors.New("content processing canceled (requested)")
@@ -132,11 +136,17 @@ type Downloader struct {
 	pivotLock   sync.RWMutex  // Lock protecting pivot header reads from updates
 
 	snapSync       bool         // Whether to run state sync over the snap protocol
+	checkpointSync bool         // Whether to sync forward from the trusted anchor block
 	SnapSyncer     *snap.Syncer // TODO(karalabe): make private! hack for now
 	stateSyncStart chan *stateSync
 	trackStateReq  chan *stateReq
 	stateCh        chan dataPack // Channel receiving inbound node state data
 
+	// Checkpoint sync
+	anchorHash   common.Hash               // Hash of the trusted Prime block to sync forward from
+	anchorVerify func(*types.Header) error // Checks the anchor header is a Prime block of the local zone
+	anchor       *types.Header             // Anchor header resolved from the sync peer, nil if not checkpoint syncing
+
 	// Cancellation and termination
 	cancelPeer string         // Identifier of the peer currently being used as the master (cancel on drop)
 	cancelCh   chan struct{}  // Channel to cancel mid-flight syncs
@@ -209,6 +219,9 @@ type BlockChain interface {
 	// Adds ExternalBlock to external block cache
 	AddExternalBlock(block *types.ExternalBlock) error
 
+	// StoreExternalBlocks writes the external blocks into the database.
+	StoreExternalBlocks(blocks []*types.ExternalBlock) error
+
 	// HLCR does hierarchical comparison of two difficulty tuples and returns true if second tuple is greater than the first
 	HLCR(localDifficulties []*big.Int, externDifficulties []*big.Int) bool
 }
@@ -249,6 +262,16 @@ func New(checkpoint uint64, stateDb ethdb.Database, stateBloom *trie.SyncBloom,
 	return dl
 }
 
+// SetCheckpointAnchor sets the trusted Prime block checkpoint sync starts from.
+// Coincident blocks share their header across contexts, so the hash is also the
+// one of the zone block, which is fetched from the sync peer and passed to
+// verify before the sync starts. The seals of the headers before the anchor are
+// not verified, their parent hashes linking them to the anchor instead.
+func (d *Downloader) SetCheckpointAnchor(hash common.Hash, verify func(*types.Header) error) {
+	d.anchorHash = hash
+	d.anchorVerify = verify
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -398,6 +421,14 @@ func (d *Downloader) synchronise(id string, hash common.Hash, td []*big.Int, mod
 		}
 		mode = FastSync
 	}
+	// If checkpoint sync was requested, fast sync forward from the anchor
+	if mode == CheckpointSync {
+		if d.anchorHash == (common.Hash{}) {
+			return errNoAnchor
+		}
+		d.checkpointSync = true
+		mode = FastSync
+	}
 	// Reset the queue, peer set and wake channels to clean any internal leftover state
 	d.queue.Reset(blockCacheMaxItems, blockCacheInitialItems)
 	d.peers.Reset()
@@ -456,6 +487,9 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td []*big
 		if err != nil {
 			d.mux.Post(FailedEvent{err})
 		} else {
+			// The sync reached its target, there is nothing left to resume
+			rawdb.DeleteSyncResume(d.stateDB)
+
 			latest := d.lightchain.CurrentHeader()
 			d.mux.Post(DoneEvent{latest})
 		}
@@ -485,13 +519,41 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td []*big
 	}
 	height := latest.Number[types.QuaiNetworkContext].Uint64()
 
+	// Resolve the checkpoint anchor, the state is synced at or above it
+	d.anchor = nil
+	if mode == FastSync && d.checkpointSync {
+		anchor, err := d.fetchAnchor(p)
+		if err != nil {
+			return err
+		}
+		number := anchor.Number[types.QuaiNetworkContext].Uint64()
+		if height < number {
+			return fmt.Errorf("%w: remote head %d below anchor %d", errUnsyncedPeer, height, number)
+		}
+		if pivot.Number[types.QuaiNetworkContext].Uint64() < number {
+			pivot = anchor
+		}
+		d.anchor = anchor
+	}
 	origin, err := d.findAncestor(p, latest)
 	if err != nil {
 		return err
 	}
+	// Pick up the progress of an interrupted sync, possibly in another datadir
+	resume := ReadSyncResume(d.stateDB)
+	if mode == FastSync {
+		if resumed := d.resumePivot(resume, origin, height); resumed != nil {
+			log.Info("Resuming sync pivot", "number", resumed.Number[types.QuaiNetworkContext], "hash", resumed.Hash())
+			pivot = resumed
+		}
+	}
+	startOrigin := origin
+	if resume != nil && resume.Origin < origin {
+		startOrigin = resume.Origin
+	}
 	d.syncStatsLock.Lock()
 	if d.syncStatsChainHeight <= origin || d.syncStatsChainOrigin > origin {
-		d.syncStatsChainOrigin = origin
+		d.syncStatsChainOrigin = startOrigin
 	}
 	d.syncStatsChainHeight = height
 	d.syncStatsLock.Unlock()
@@ -529,7 +591,9 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td []*big
 		// The peer would start to feed us valid blocks until head, resulting in all of
 		// the blocks might be written into the ancient store. A following mini-reorg
 		// could cause issues.
-		if d.checkpoint != 0 && d.checkpoint > fullMaxForkAncestry+1 {
+		if d.anchor != nil && d.anchor.Number[types.QuaiNetworkContext].Uint64() > fullMaxForkAncestry+1 {
+			d.ancientLimit = d.anchor.Number[types.QuaiNetworkContext].Uint64()
+		} else if d.checkpoint != 0 && d.checkpoint > fullMaxForkAncestry+1 {
 			d.ancientLimit = d.checkpoint
 		} else if height > fullMaxForkAncestry+1 {
 			d.ancientLimit = height - fullMaxForkAncestry - 1
@@ -553,6 +617,23 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td []*big
 			}
 		}
 	}
+	// Persist the sync boundaries to resume from if interrupted
+	resume = &SyncResume{
+		Mode:   mode,
+		Origin: startOrigin,
+		Target: SyncAnchor{Number: height, Hash: latest.Hash()},
+	}
+	if mode == FastSync {
+		if d.snapSync {
+			resume.Mode = SnapSync
+		}
+		if d.checkpointSync {
+			resume.Mode = CheckpointSync
+		}
+		resume.Pivot = &SyncAnchor{Number: pivot.Number[types.QuaiNetworkContext].Uint64(), Hash: pivot.Hash()}
+	}
+	WriteSyncResume(d.stateDB, resume)
+
 	// Initiate the sync using a concurrent header and content retrieval algorithm
 	d.queue.Prepare(origin+1, mode)
 	if d.syncInitHook != nil {
@@ -714,6 +795,53 @@ func (d *Downloader) fetchHead(p *peerConnection) (head *types.Header, pivot *ty
 	}
 }
 
+// fetchAnchor retrieves the header of the checkpoint anchor from a remote peer
+// and verifies it is the trusted Prime block of the local zone.
+func (d *Downloader) fetchAnchor(p *peerConnection) (*types.Header, error) {
+	p.log.Debug("Retrieving checkpoint anchor", "hash", d.anchorHash)
+	go p.peer.RequestHeadersByHash(d.anchorHash, 1, 0, false)
+
+	ttl := d.peers.rates.TargetTimeout()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCanceled
+
+		case packet := <-d.headerCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				log.Debug("Received headers from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			headers := packet.(*headerPack).headers
+			if len(headers) != 1 {
+				return nil, fmt.Errorf("%w: anchor %x not served", errUnsyncedPeer, d.anchorHash)
+			}
+			anchor := headers[0]
+			if anchor.Hash() != d.anchorHash {
+				return nil, fmt.Errorf("%w: remote anchor %x != requested %x", errBadPeer, anchor.Hash(), d.anchorHash)
+			}
+			if d.anchorVerify != nil {
+				if err := d.anchorVerify(anchor); err != nil {
+					return nil, fmt.Errorf("%w: %v", errInvalidAnchor, err)
+				}
+			}
+			p.log.Debug("Checkpoint anchor identified", "number", anchor.Number, "hash", anchor.Hash())
+			return anchor, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for checkpoint anchor timed out", "elapsed", ttl)
+			return nil, errTimeout
+
+		case <-d.bodyCh:
+		case <-d.receiptCh:
+		case <-d.extBlockCh:
+			// Out of bounds delivery, ignore
+		}
+	}
+}
+
 // calculateRequestSpan calculates what headers to request from a peer when trying to determine the
 // common ancestor.
 // It returns parameters to be used for peer.RequestHeadersByNumber:
@@ -972,13 +1100,13 @@ func (d *Downloader) findAncestorBinarySearch(p *peerConnection, mode SyncMode,
 				case FastSync:
 					known = d.blockchain.HasFastBlock(h, n)
 				default:
-					known = d.blockchain.HasBlock(h, n)
+					known = d.lightchain.HasHeader(h, n)
 				}
 				if !known {
 					end = check
 					break
 				}
-				header := d.blockchain.GetHeaderByHash(h) // Independent of sync mode, header surely exists
+				header := d.lightchain.GetHeaderByHash(h) // Independent of sync mode, header surely exists
 				// Somehow got past known check
 				if header == nil {
 					break
@@ -1546,7 +1674,7 @@ func (d *Downloader) fetchParts(deliveryCh chan dataPack, deliver func(dataPack)
 			// Make sure that we have peers available for fetching. If all peers have been tried
 			// and all failed throw an error
 			if !progressed && !throttled && !running && len(idles) == total && pendCount > 0 {
-				return nil
+				return errPeersUnavailable
 			}
 		}
 	}
@@ -1561,6 +1689,7 @@ func (d *Downloader) processHeaders(origin uint64, td []*big.Int) error {
 		rollback    uint64 // Zero means no rollback (fine as you can't unroll the genesis)
 		rollbackErr error
 		mode        = d.getMode()
+		start       = origin // First header of the sync, rolled back to if not leading to the anchor
 	)
 	defer func() {
 		if rollback > 0 {
@@ -1670,6 +1799,22 @@ func (d *Downloader) processHeaders(origin uint64, td []*big.Int) error {
 					if chunk[len(chunk)-1].Number[types.QuaiNetworkContext].Uint64()+uint64(fsHeaderForceVerify) > pivot {
 						frequency = 1
 					}
+					// Headers before the checkpoint anchor are linked to it by their
+					// hashes, skip verifying their seals but make sure they lead to it
+					if d.anchor != nil {
+						anchor := d.anchor.Number[types.QuaiNetworkContext].Uint64()
+						if chunk[len(chunk)-1].Number[types.QuaiNetworkContext].Uint64() < anchor {
+							frequency = 0
+						}
+						for _, header := range chunk {
+							if header.Number[types.QuaiNetworkContext].Uint64() == anchor && header.Hash() != d.anchor.Hash() {
+								rollbackErr = errInvalidAnchor
+								rollback = start
+								log.Warn("Chain not leading to the checkpoint anchor", "number", anchor, "hash", header.Hash(), "anchor", d.anchor.Hash())
+								return fmt.Errorf("%w: header %d %x != anchor %x", errInvalidChain, anchor, header.Hash(), d.anchor.Hash())
+							}
+						}
+					}
 					if n, err := d.lightchain.InsertHeaderChain(chunk, frequency); err != nil {
 						rollbackErr = err
 
@@ -1677,6 +1822,12 @@ func (d *Downloader) processHeaders(origin uint64, td []*big.Int) error {
 						if (mode == FastSync || frequency > 1) && n > 0 && rollback == 0 {
 							rollback = chunk[0].Number[types.QuaiNetworkContext].Uint64()
 						}
+						// Headers the dominant chain couldn't anchor yet aren't the
+						// fault of the peer, retry once the slice caught up.
+						if errors.Is(err, consensus.ErrSliceNotSynced) {
+							log.Debug("Header deferred", "number", chunk[n].Number, "hash", chunk[n].Hash(), "err", err)
+							return fmt.Errorf("%w: %v", errSliceNotReady, err)
+						}
 						log.Warn("Invalid header encountered", "number", chunk[n].Number, "hash", chunk[n].Hash(), "parent", chunk[n].ParentHash, "err", err)
 						return fmt.Errorf("%w: %v", errInvalidChain, err)
 					}
@@ -1785,6 +1936,11 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 			// of the blocks delivered from the downloader, and the indexing will be off.
 			log.Debug("Downloaded item processing failed on sidechain import", "index", index, "err", err)
 		}
+		// Blocks failing on the state of the dominant chain aren't the fault of
+		// the peer, retry once the slice caught up.
+		if errors.Is(err, consensus.ErrSliceNotSynced) || errors.Is(err, consensus.ErrNonCanonicalDom) {
+			return fmt.Errorf("%w: %v", errSliceNotReady, err)
+		}
 		return fmt.Errorf("%w: %v", errInvalidChain, err)
 	}
 	return nil
@@ -1876,6 +2032,7 @@ func (d *Downloader) processFastSyncContent() error {
 				// Write out the pivot into the database so a rollback beyond it will
 				// reenable fast sync
 				rawdb.WriteLastPivotNumber(d.stateDB, pivot.Number[types.QuaiNetworkContext].Uint64())
+				d.updateResumePivot(pivot)
 			}
 		}
 		P, beforeP, afterP := splitAroundPivot(pivot.Number[types.QuaiNetworkContext].Uint64(), results)
@@ -1963,6 +2120,11 @@ func (d *Downloader) commitFastSyncData(results []*fetchResult, stateSync *state
 		blocks[i] = types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles)
 		receipts[i] = result.Receipts
 	}
+	// Blocks are not executed in fast sync, so the external blocks linked by the
+	// coincident ones are stored directly for the checks of the later blocks
+	if err := d.storeExternalBlocks(results); err != nil {
+		return err
+	}
 	if index, err := d.blockchain.InsertReceiptChain(blocks, receipts, d.ancientLimit); err != nil {
 		log.Debug("Downloaded item processing failed", "number", results[index].Header.Number, "hash", results[index].Header.Hash(), "err", err)
 		return fmt.Errorf("%w: %v", errInvalidChain, err)
@@ -1970,11 +2132,31 @@ func (d *Downloader) commitFastSyncData(results []*fetchResult, stateSync *state
 	return nil
 }
 
+// storeExternalBlocks writes the external blocks delivered along fast synced
+// blocks into the database.
+func (d *Downloader) storeExternalBlocks(results []*fetchResult) error {
+	var extBlocks []*types.ExternalBlock
+	for _, result := range results {
+		extBlocks = append(extBlocks, result.ExternalBlocks...)
+	}
+	if len(extBlocks) == 0 {
+		return nil
+	}
+	if err := d.blockchain.StoreExternalBlocks(extBlocks); err != nil {
+		log.Debug("Failed to store external blocks", "count", len(extBlocks), "err", err)
+		return fmt.Errorf("%w: %v", errInvalidChain, err)
+	}
+	return nil
+}
+
 func (d *Downloader) commitPivotBlock(result *fetchResult) error {
 	block := types.NewBlockWithHeader(result.Header).WithBody(result.Transactions, result.Uncles)
 	log.Debug("Committing fast sync pivot as new head", "number", block.Number(), "hash", block.Hash())
 
 	// Commit the pivot block as the new head, will require full sync from here on
+	if err := d.storeExternalBlocks([]*fetchResult{result}); err != nil {
+		return err
+	}
 	if _, err := d.blockchain.InsertReceiptChain([]*types.Block{block}, []types.Receipts{result.Receipts}, d.ancientLimit); err != nil {
 		return err
 	}
@@ -58,12 +58,12 @@ type downloadTester struct {
 	ownHeaders  map[common.Hash]*types.Header  // Headers belonging to the tester
 	ownBlocks   map[common.Hash]*types.Block   // Blocks belonging to the tester
 	ownReceipts map[common.Hash]types.Receipts // Receipts belonging to the tester
-	ownChainTd  map[common.Hash]*big.Int       // Total difficulties of the blocks in the local chain
+	ownChainTd  map[common.Hash][]*big.Int     // Total difficulties of the blocks in the local chain
 
 	ancientHeaders  map[common.Hash]*types.Header  // Ancient headers belonging to the tester
 	ancientBlocks   map[common.Hash]*types.Block   // Ancient blocks belonging to the tester
 	ancientReceipts map[common.Hash]types.Receipts // Ancient receipts belonging to the tester
-	ancientChainTd  map[common.Hash]*big.Int       // Ancient total difficulties of the blocks in the local chain
+	ancientChainTd  map[common.Hash][]*big.Int     // Ancient total difficulties of the blocks in the local chain
 
 	lock sync.RWMutex
 }
@@ -78,13 +78,13 @@ func newTester() *downloadTester {
 		ownHeaders:  map[common.Hash]*types.Header{testGenesis.Hash(): testGenesis.Header()},
 		ownBlocks:   map[common.Hash]*types.Block{testGenesis.Hash(): testGenesis},
 		ownReceipts: map[common.Hash]types.Receipts{testGenesis.Hash(): nil},
-		ownChainTd:  map[common.Hash]*big.Int{testGenesis.Hash(): testGenesis.Difficulty()},
+		ownChainTd:  map[common.Hash][]*big.Int{testGenesis.Hash(): testGenesis.Header().Difficulty},
 
 		// Initialize ancient store with test genesis block
 		ancientHeaders:  map[common.Hash]*types.Header{testGenesis.Hash(): testGenesis.Header()},
 		ancientBlocks:   map[common.Hash]*types.Block{testGenesis.Hash(): testGenesis},
 		ancientReceipts: map[common.Hash]types.Receipts{testGenesis.Hash(): nil},
-		ancientChainTd:  map[common.Hash]*big.Int{testGenesis.Hash(): testGenesis.Difficulty()},
+		ancientChainTd:  map[common.Hash][]*big.Int{testGenesis.Hash(): testGenesis.Header().Difficulty},
 	}
 	tester.stateDb = rawdb.NewMemoryDatabase()
 	tester.stateDb.Put(testGenesis.Root().Bytes(), []byte{0x00})
@@ -100,7 +100,7 @@ func (dl *downloadTester) terminate() {
 }
 
 // sync starts synchronizing with a remote peer, blocking until it completes.
-func (dl *downloadTester) sync(id string, td *big.Int, mode SyncMode) error {
+func (dl *downloadTester) sync(id string, td []*big.Int, mode SyncMode) error {
 	dl.lock.RLock()
 	hash := dl.peers[id].chain.headBlock().Hash()
 	// If no particular TD was requested, load from the peer's blockchain
@@ -236,7 +236,7 @@ func (dl *downloadTester) FastSyncCommitHead(hash common.Hash) error {
 }
 
 // GetTd retrieves the block's total difficulty from the canonical chain.
-func (dl *downloadTester) GetTd(hash common.Hash, number uint64) *big.Int {
+func (dl *downloadTester) GetTd(hash common.Hash, number uint64) []*big.Int {
 	dl.lock.RLock()
 	defer dl.lock.RUnlock()
 
@@ -246,13 +246,27 @@ func (dl *downloadTester) GetTd(hash common.Hash, number uint64) *big.Int {
 // getTd retrieves the block's total difficulty if found either within
 // ancients or own blocks).
 // This method assumes that the caller holds at least the read-lock (dl.lock)
-func (dl *downloadTester) getTd(hash common.Hash) *big.Int {
+func (dl *downloadTester) getTd(hash common.Hash) []*big.Int {
 	if td := dl.ancientChainTd[hash]; td != nil {
 		return td
 	}
 	return dl.ownChainTd[hash]
 }
 
+// HLCR compares two total difficulties context by context, starting with Prime,
+// returning whether the external one is greater.
+func (dl *downloadTester) HLCR(localDifficulties []*big.Int, externDifficulties []*big.Int) bool {
+	if len(localDifficulties) == 0 || len(externDifficulties) == 0 {
+		return false
+	}
+	for i := range localDifficulties {
+		if c := localDifficulties[i].Cmp(externDifficulties[i]); c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
+
 // InsertHeaderChain injects a new batch of headers into the simulated chain.
 func (dl *downloadTester) InsertHeaderChain(headers []*types.Header, checkFreq int) (i int, err error) {
 	dl.lock.Lock()
@@ -284,7 +298,7 @@ func (dl *downloadTester) InsertHeaderChain(headers []*types.Header, checkFreq i
 		dl.ownHeaders[hash] = header
 
 		td := dl.getTd(header.ParentHash[types.QuaiNetworkContext])
-		dl.ownChainTd[hash] = new(big.Int).Add(td, header.Difficulty[types.QuaiNetworkContext])
+		dl.ownChainTd[hash] = addTd(td, header)
 	}
 	return len(headers), nil
 }
@@ -307,7 +321,7 @@ func (dl *downloadTester) InsertChain(blocks types.Blocks) (i int, err error) {
 		dl.ownReceipts[block.Hash()] = make(types.Receipts, 0)
 		dl.stateDb.Put(block.Root().Bytes(), []byte{0x00})
 		td := dl.getTd(block.ParentHash())
-		dl.ownChainTd[block.Hash()] = new(big.Int).Add(td, block.Difficulty())
+		dl.ownChainTd[block.Hash()] = addTd(td, block.Header())
 	}
 	return len(blocks), nil
 }
@@ -332,7 +346,7 @@ func (dl *downloadTester) InsertReceiptChain(blocks types.Blocks, receipts []typ
 
 			// Migrate from active db to ancient db
 			dl.ancientHeaders[blocks[i].Hash()] = blocks[i].Header()
-			dl.ancientChainTd[blocks[i].Hash()] = new(big.Int).Add(dl.ancientChainTd[blocks[i].ParentHash()], blocks[i].Difficulty())
+			dl.ancientChainTd[blocks[i].Hash()] = addTd(dl.ancientChainTd[blocks[i].ParentHash()], blocks[i].Header())
 			delete(dl.ownHeaders, blocks[i].Hash())
 			delete(dl.ownChainTd, blocks[i].Hash())
 		} else {
@@ -349,6 +363,11 @@ func (dl *downloadTester) AddExternalBlock(block *types.ExternalBlock) error {
 	return nil
 }
 
+// StoreExternalBlocks writes the external blocks into the simulated database.
+func (dl *downloadTester) StoreExternalBlocks(blocks []*types.ExternalBlock) error {
+	return nil
+}
+
 // SetHead rewinds the local chain to a new head.
 func (dl *downloadTester) SetHead(head uint64) error {
 	dl.lock.Lock()
@@ -430,7 +449,7 @@ type downloadTesterPeer struct {
 
 // Head constructs a function to retrieve a peer's current head hash
 // and total difficulty.
-func (dlp *downloadTesterPeer) Head() (common.Hash, *big.Int) {
+func (dlp *downloadTesterPeer) Head() (common.Hash, []*big.Int) {
 	b := dlp.chain.headBlock()
 	return b.Hash(), dlp.chain.td(b.Hash())
 }
@@ -462,10 +481,11 @@ func (dlp *downloadTesterPeer) RequestBodies(hashes []common.Hash) error {
 	return nil
 }
 
-//RequestExteralBlocks
+// RequestExternalBlocks constructs a getExternalBlocks method associated with a
+// particular peer in the download tester. The test chains are single context,
+// so every block is delivered without external blocks.
 func (dlp *downloadTesterPeer) RequestExternalBlocks(hashes []common.Hash) error {
-	//extBlocks := dlp.chain
-	//go dlp.dl.downloader.DeliverExtBlocks(dlp.id,extBlocks)
+	go dlp.dl.downloader.DeliverExtBlocks(dlp.id, make([][]*types.ExternalBlock, len(hashes)))
 	return nil
 }
 
@@ -1095,7 +1115,7 @@ func testHighTDStarvationAttack(t *testing.T, protocol uint, mode SyncMode) {
 
 	chain := testChainBase.shorten(1)
 	tester.newPeer("attack", protocol, chain)
-	if err := tester.sync("attack", big.NewInt(1000000), mode); err != errStallingPeer {
+	if err := tester.sync("attack", []*big.Int{big.NewInt(1000000), big.NewInt(1000000), big.NewInt(1000000)}, mode); err != errStallingPeer {
 		t.Fatalf("synchronisation error mismatch: have %v, want %v", err, errStallingPeer)
 	}
 	tester.terminate()
@@ -1145,7 +1165,7 @@ func testBlockHeaderAttackerDropping(t *testing.T, protocol uint) {
 		// Simulate a synchronisation and check the required result
 		tester.downloader.synchroniseMock = func(string, common.Hash) error { return tt.result }
 
-		tester.downloader.Synchronise(id, tester.genesis.Hash(), big.NewInt(1000), FullSync)
+		tester.downloader.Synchronise(id, tester.genesis.Hash(), []*big.Int{big.NewInt(1000), big.NewInt(1000), big.NewInt(1000)}, FullSync)
 		if _, ok := tester.peers[id]; !ok != tt.drop {
 			t.Errorf("test %d: peer drop mismatch for %v: have %v, want %v", i, tt.result, !ok, tt.drop)
 		}
@@ -1482,7 +1502,7 @@ type floodingTestPeer struct {
 	tester *downloadTester
 }
 
-func (ftp *floodingTestPeer) Head() (common.Hash, *big.Int) { return ftp.peer.Head() }
+func (ftp *floodingTestPeer) Head() (common.Hash, []*big.Int) { return ftp.peer.Head() }
 func (ftp *floodingTestPeer) RequestHeadersByHash(hash common.Hash, count int, skip int, reverse bool) error {
 	return ftp.peer.RequestHeadersByHash(hash, count, skip, reverse)
 }
@@ -1644,3 +1664,118 @@ func testCheckpointEnforcement(t *testing.T, protocol uint, mode SyncMode) {
 		assertOwnChain(t, tester, chain.len())
 	}
 }
+
+// anchorTestPeer serves a checkpoint anchor that isn't necessarily part of the
+// chain of the peer.
+type anchorTestPeer struct {
+	Peer
+	id     string
+	tester *downloadTester
+	anchor *types.Header
+}
+
+func (atp *anchorTestPeer) RequestHeadersByHash(hash common.Hash, count int, skip int, reverse bool) error {
+	if hash == atp.anchor.Hash() {
+		go atp.tester.downloader.DeliverHeaders(atp.id, []*types.Header{atp.anchor})
+		return nil
+	}
+	return atp.Peer.RequestHeadersByHash(hash, count, skip, reverse)
+}
+
+// newAnchorTester creates a tester checkpoint syncing from anchor with a peer
+// serving chain, recording the anchors passed to the verify callback.
+func newAnchorTester(chain *testChain, anchor *types.Header, verifyErr error) (*downloadTester, *[]*types.Header) {
+	tester := newTester()
+	tester.newPeer("peer", eth.QUAI66, chain)
+	tester.downloader.peers.peers["peer"].peer = &anchorTestPeer{
+		Peer:   tester.downloader.peers.peers["peer"].peer,
+		id:     "peer",
+		tester: tester,
+		anchor: anchor,
+	}
+	verified := new([]*types.Header)
+	tester.downloader.SetCheckpointAnchor(anchor.Hash(), func(header *types.Header) error {
+		*verified = append(*verified, header)
+		return verifyErr
+	})
+	return tester, verified
+}
+
+// Tests that checkpoint sync is refused without an anchor.
+func TestCheckpointSyncNoAnchor66(t *testing.T) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	chain := testChainBase.shorten(64)
+	tester.newPeer("peer", eth.QUAI66, chain)
+
+	head := chain.headBlock().Hash()
+	if err := tester.downloader.synchronise("peer", head, chain.td(head), CheckpointSync); err != errNoAnchor {
+		t.Fatalf("sync error mismatch: have %v, want %v", err, errNoAnchor)
+	}
+}
+
+// Tests that the headers of a chain not leading to the checkpoint anchor are
+// rolled back and the chain rejected.
+func TestCheckpointSyncAnchorRollback66(t *testing.T) {
+	t.Parallel()
+
+	base := testChainBase.shorten(200)
+	chain, fork := base.makeFork(100, false, 1), base.makeFork(100, false, 2)
+	anchor := fork.headerm[fork.chain[250]]
+
+	tester, verified := newAnchorTester(chain, anchor, nil)
+	defer tester.terminate()
+
+	if err := tester.sync("peer", nil, CheckpointSync); !errors.Is(err, errInvalidChain) {
+		t.Fatalf("sync error mismatch: have %v, want %v", err, errInvalidChain)
+	}
+	if len(*verified) != 1 || (*verified)[0].Hash() != anchor.Hash() {
+		t.Fatalf("verified anchors mismatch: have %v, want [%x]", *verified, anchor.Hash())
+	}
+	if head := tester.CurrentHeader().Number[types.QuaiNetworkContext].Uint64(); head != 0 {
+		t.Fatalf("headers not rolled back: head %d, want 0", head)
+	}
+}
+
+// Tests that peers whose head is below the checkpoint anchor are refused.
+func TestCheckpointSyncAnchorAboveHead66(t *testing.T) {
+	t.Parallel()
+
+	chain := testChainBase.shorten(200)
+	anchor := testChainBase.headerm[testChainBase.chain[250]]
+
+	tester, _ := newAnchorTester(chain, anchor, nil)
+	defer tester.terminate()
+
+	if err := tester.sync("peer", nil, CheckpointSync); !errors.Is(err, errUnsyncedPeer) {
+		t.Fatalf("sync error mismatch: have %v, want %v", err, errUnsyncedPeer)
+	}
+	if len(tester.ownHashes) != 1 {
+		t.Fatalf("headers imported below the anchor: have %d, want 1", len(tester.ownHashes))
+	}
+}
+
+// Tests that an anchor rejected by the verify callback aborts the sync before
+// anything is imported.
+func TestCheckpointSyncAnchorVerify66(t *testing.T) {
+	t.Parallel()
+
+	chain := testChainBase.shorten(300)
+	anchor := chain.headerm[chain.chain[250]]
+
+	tester, verified := newAnchorTester(chain, anchor, errors.New("not a prime block"))
+	defer tester.terminate()
+
+	if err := tester.sync("peer", nil, CheckpointSync); !errors.Is(err, errInvalidAnchor) {
+		t.Fatalf("sync error mismatch: have %v, want %v", err, errInvalidAnchor)
+	}
+	if len(*verified) != 1 || (*verified)[0].Hash() != anchor.Hash() {
+		t.Fatalf("verified anchors mismatch: have %v, want [%x]", *verified, anchor.Hash())
+	}
+	if len(tester.ownHashes) != 1 {
+		t.Fatalf("headers imported for a rejected anchor: have %d, want 1", len(tester.ownHashes))
+	}
+}
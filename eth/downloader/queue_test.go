@@ -43,12 +43,19 @@ var (
 // contains a transaction and every 5th an uncle to allow testing correct block
 // reassembly.
 func makeChain(n int, seed byte, parent *types.Block, empty bool) ([]*types.Block, []types.Receipts) {
-	blocks, receipts := core.GenerateChain(params.TestChainConfig, parent, blake3.NewFaker(), testdb, n, func(i int, block *core.BlockGen) {
+	blocks, receipts := core.GenerateChain(testChainConfig, parent, blake3.NewFaker(), testdb, n, func(i int, block *core.BlockGen) {
 		block.SetCoinbase(common.Address{seed})
 		// Add one tx to every secondblock
 		if !empty && i%2 == 0 {
-			signer := types.MakeSigner(params.TestChainConfig, block.Number())
-			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(testAddress), common.Address{seed}, big.NewInt(1000), params.TxGas, block.BaseFee(), nil), signer, testKey)
+			signer := types.MakeSigner(testChainConfig, block.Number())
+			tx, err := types.SignNewTx(testKey, signer, &types.AccessListTx{
+				ChainID:  testChainConfig.ChainID,
+				Nonce:    block.TxNonce(testAddress),
+				To:       &common.Address{seed},
+				Value:    big.NewInt(1000),
+				Gas:      params.TxGas,
+				GasPrice: block.BaseFee(),
+			})
 			if err != nil {
 				panic(err)
 			}
@@ -250,9 +257,18 @@ func TestEmptyBlocks(t *testing.T) {
 	if q.receiptTaskQueue.Size() != 0 {
 		t.Errorf("expected receipt task queue to be %d, got %d", 0, q.receiptTaskQueue.Size())
 	}
-	if got, exp := q.resultCache.countCompleted(), 10; got != exp {
+	// Empty blocks still wait for their external blocks
+	if got, exp := q.resultCache.countCompleted(), 0; got != exp {
 		t.Errorf("wrong processable count, got %d, exp %d", got, exp)
 	}
+	{
+		peer := dummyPeer("peer-4")
+		fetchReq, _, _ := q.ReserveExtBlocks(peer, 50)
+
+		if fetchReq == nil || len(fetchReq.Headers) != 10 {
+			t.Fatalf("expected external block fetch of %d headers, got %v", 10, fetchReq)
+		}
+	}
 }
 
 // XTestDelivery does some more extensive testing of events that happen,
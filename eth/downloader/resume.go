@@ -0,0 +1,140 @@
+package downloader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/log"
+)
+
+// SyncAnchor identifies a block a synchronisation is anchored to.
+type SyncAnchor struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// SyncRange is an inclusive range of block numbers available locally.
+type SyncRange struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// SyncResume is the progress of an unfinished synchronisation. It is persisted
+// in the database, so a restarted node or a datadir copied to another machine
+// resumes the sync instead of starting over, and can be exported to bootstrap
+// other nodes from the same chain data.
+type SyncResume struct {
+	Mode    SyncMode    `json:"mode"`
+	Origin  uint64      `json:"origin"`          // Local block the sync started from
+	Target  SyncAnchor  `json:"target"`          // Remote head the sync was heading to
+	Pivot   *SyncAnchor `json:"pivot,omitempty"` // Fast sync block the state is synced at
+	Headers SyncRange   `json:"headers"`         // Headers received since the origin
+	Blocks  SyncRange   `json:"blocks"`          // Blocks received since the origin
+}
+
+// ReadSyncResume retrieves the progress of the unfinished synchronisation from
+// the database, with the received ranges updated to the local chain. Nil is
+// returned if there is no sync to resume.
+func ReadSyncResume(db ethdb.Reader) *SyncResume {
+	blob := rawdb.ReadSyncResume(db)
+	if len(blob) == 0 {
+		return nil
+	}
+	resume := new(SyncResume)
+	if err := json.Unmarshal(blob, resume); err != nil {
+		log.Warn("Discarding corrupt sync resume metadata", "err", err)
+		return nil
+	}
+	resume.Headers = SyncRange{From: resume.Origin, To: resume.Origin}
+	if number := rawdb.ReadHeaderNumber(db, rawdb.ReadHeadHeaderHash(db)); number != nil && *number > resume.Origin {
+		resume.Headers.To = *number
+	}
+	head := rawdb.ReadHeadBlockHash(db)
+	if resume.Mode == FastSync || resume.Mode == SnapSync || resume.Mode == CheckpointSync {
+		head = rawdb.ReadHeadFastBlockHash(db)
+	}
+	resume.Blocks = SyncRange{From: resume.Origin, To: resume.Origin}
+	if number := rawdb.ReadHeaderNumber(db, head); number != nil && *number > resume.Origin {
+		resume.Blocks.To = *number
+	}
+	return resume
+}
+
+// WriteSyncResume stores the progress of an unfinished synchronisation into
+// the database.
+func WriteSyncResume(db ethdb.KeyValueWriter, resume *SyncResume) {
+	blob, err := json.Marshal(resume)
+	if err != nil {
+		log.Crit("Failed to encode sync resume metadata", "err", err)
+	}
+	rawdb.WriteSyncResume(db, blob)
+}
+
+// ExportSyncResume writes the progress of the unfinished synchronisation in
+// the database to w.
+func ExportSyncResume(db ethdb.Reader, w io.Writer) error {
+	resume := ReadSyncResume(db)
+	if resume == nil {
+		return errors.New("no unfinished sync to export")
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(resume)
+}
+
+// ImportSyncResume reads sync progress exported by ExportSyncResume from r and
+// stores it into the database, to be resumed on the next synchronisation. The
+// pivot of the imported progress must not conflict with the local chain.
+func ImportSyncResume(db ethdb.Database, r io.Reader) error {
+	resume := new(SyncResume)
+	if err := json.NewDecoder(r).Decode(resume); err != nil {
+		return err
+	}
+	if !resume.Mode.IsValid() {
+		return fmt.Errorf("invalid sync mode %d", resume.Mode)
+	}
+	if resume.Origin > resume.Target.Number {
+		return fmt.Errorf("origin %d above target %d", resume.Origin, resume.Target.Number)
+	}
+	if pivot := resume.Pivot; pivot != nil {
+		if pivot.Number > resume.Target.Number {
+			return fmt.Errorf("pivot %d above target %d", pivot.Number, resume.Target.Number)
+		}
+		if hash := rawdb.ReadCanonicalHash(db, pivot.Number); hash != (common.Hash{}) && hash != pivot.Hash {
+			return fmt.Errorf("pivot %d [%x] conflicts with local block %x", pivot.Number, pivot.Hash, hash)
+		}
+	}
+	WriteSyncResume(db, resume)
+	return nil
+}
+
+// resumePivot returns the pivot of the interrupted synchronisation if it is on
+// the local chain below the common ancestor with the new sync target and still
+// fresh enough for peers to serve its state, nil otherwise. Resuming the pivot
+// keeps the state already retrieved for it.
+func (d *Downloader) resumePivot(resume *SyncResume, origin, height uint64) *types.Header {
+	if resume == nil || resume.Pivot == nil || resume.Pivot.Number > origin {
+		return nil
+	}
+	if height >= resume.Pivot.Number+2*uint64(fsMinFullBlocks)-uint64(reorgProtHeaderDelay) {
+		return nil
+	}
+	if rawdb.ReadCanonicalHash(d.stateDB, resume.Pivot.Number) != resume.Pivot.Hash {
+		return nil
+	}
+	return d.lightchain.GetHeaderByHash(resume.Pivot.Hash)
+}
+
+// updateResumePivot records a pivot move in the persisted sync progress.
+func (d *Downloader) updateResumePivot(pivot *types.Header) {
+	if resume := ReadSyncResume(d.stateDB); resume != nil {
+		resume.Pivot = &SyncAnchor{Number: pivot.Number[types.QuaiNetworkContext].Uint64(), Hash: pivot.Hash()}
+		WriteSyncResume(d.stateDB, resume)
+	}
+}
@@ -26,18 +26,26 @@ import (
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/rawdb"
 	"github.com/spruce-solutions/go-quai/core/types"
-	"github.com/spruce-solutions/go-quai/crypto"
 	"github.com/spruce-solutions/go-quai/params"
 )
 
 // Test chain parameters.
 var (
-	testKey, _  = crypto.HexToECDSA("e5406fa9618589dbebc2ff870ab671290e194b0512ec9b85be47287bb59d83dd")
-	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
-	testDB      = rawdb.NewMemoryDatabase()
-	testGenesis = core.GenesisBlockForTesting(testDB, testAddress, big.NewInt(1000000000000000))
+	testKey, testAddress = core.NetworkETXSender(1, 1)
+	testChainConfig      = testZoneConfig()
+	testDB               = rawdb.NewMemoryDatabase()
+	testGenesis          = core.GenesisBlockForTesting(testDB, testAddress, big.NewInt(1000000000000000))
 )
 
+// testZoneConfig returns the config of the test chains, the one of a zone whose
+// address range holds testAddress, so the transactions of testKey are signed for
+// it, at the context of the node.
+func testZoneConfig() *params.ChainConfig {
+	config := core.NetworkZoneConfig(params.TestChainConfig, 1, 1)
+	config.Context = types.QuaiNetworkContext
+	return config
+}
+
 // The common prefix of all test chains:
 var testChainBase = newTestChain(blockCacheMaxItems+200, testGenesis)
 
@@ -60,7 +68,7 @@ type testChain struct {
 	headerm  map[common.Hash]*types.Header
 	blockm   map[common.Hash]*types.Block
 	receiptm map[common.Hash][]*types.Receipt
-	tdm      map[common.Hash]*big.Int
+	tdm      map[common.Hash][]*big.Int
 }
 
 // newTestChain creates a blockchain of the given length.
@@ -69,7 +77,7 @@ func newTestChain(length int, genesis *types.Block) *testChain {
 	tc.genesis = genesis
 	tc.chain = append(tc.chain, genesis.Hash())
 	tc.headerm[tc.genesis.Hash()] = tc.genesis.Header()
-	tc.tdm[tc.genesis.Hash()] = tc.genesis.Difficulty()
+	tc.tdm[tc.genesis.Hash()] = tc.genesis.Header().Difficulty
 	tc.blockm[tc.genesis.Hash()] = tc.genesis
 	tc.generate(length-1, 0, genesis, false)
 	return tc
@@ -97,7 +105,7 @@ func (tc *testChain) copy(newlen int) *testChain {
 		headerm:  make(map[common.Hash]*types.Header, newlen),
 		blockm:   make(map[common.Hash]*types.Block, newlen),
 		receiptm: make(map[common.Hash][]*types.Receipt, newlen),
-		tdm:      make(map[common.Hash]*big.Int, newlen),
+		tdm:      make(map[common.Hash][]*big.Int, newlen),
 	}
 	for i := 0; i < len(tc.chain) && i < newlen; i++ {
 		hash := tc.chain[i]
@@ -118,7 +126,7 @@ func (tc *testChain) generate(n int, seed byte, parent *types.Block, heavy bool)
 	// start := time.Now()
 	// defer func() { fmt.Printf("test chain generated in %v\n", time.Since(start)) }()
 
-	blocks, receipts := core.GenerateChain(params.TestChainConfig, parent, blake3.NewFaker(), testDB, n, func(i int, block *core.BlockGen) {
+	blocks, receipts := core.GenerateChain(testChainConfig, parent, blake3.NewFaker(), testDB, n, func(i int, block *core.BlockGen) {
 		block.SetCoinbase(common.Address{seed})
 		// If a heavy chain is requested, delay blocks to raise difficulty
 		if heavy {
@@ -126,8 +134,15 @@ func (tc *testChain) generate(n int, seed byte, parent *types.Block, heavy bool)
 		}
 		// Include transactions to the miner to make blocks more interesting.
 		if parent == tc.genesis && i%22 == 0 {
-			signer := types.MakeSigner(params.TestChainConfig, block.Number())
-			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(testAddress), common.Address{seed}, big.NewInt(1000), params.TxGas, block.BaseFee(), nil), signer, testKey)
+			signer := types.MakeSigner(testChainConfig, block.Number())
+			tx, err := types.SignNewTx(testKey, signer, &types.AccessListTx{
+				ChainID:  testChainConfig.ChainID,
+				Nonce:    block.TxNonce(testAddress),
+				To:       &common.Address{seed},
+				Value:    big.NewInt(1000),
+				Gas:      params.TxGas,
+				GasPrice: block.BaseFee(),
+			})
 			if err != nil {
 				panic(err)
 			}
@@ -135,23 +150,23 @@ func (tc *testChain) generate(n int, seed byte, parent *types.Block, heavy bool)
 		}
 		// if the block number is a multiple of 5, add a bonus uncle to the block
 		if i > 0 && i%5 == 0 {
-			block.AddUncle(&types.Header{
-				ParentHash: []common.Hash{block.PrevBlock(i - 1).Hash(), block.PrevBlock(i - 1).Hash(), block.PrevBlock(i - 1).Hash()},
-				Number:     []*big.Int{big.NewInt(block.Number().Int64() - 1), big.NewInt(block.Number().Int64() - 1), big.NewInt(block.Number().Int64() - 1)},
-			})
+			uncle := types.NewEmptyHeader()
+			uncle.ParentHash = []common.Hash{block.PrevBlock(i - 1).Hash(), block.PrevBlock(i - 1).Hash(), block.PrevBlock(i - 1).Hash()}
+			uncle.Number = []*big.Int{big.NewInt(block.Number().Int64() - 1), big.NewInt(block.Number().Int64() - 1), big.NewInt(block.Number().Int64() - 1)}
+			block.AddUncle(uncle)
 		}
 	})
 
 	// Convert the block-chain into a hash-chain and header/block maps
-	td := new(big.Int).Set(tc.td(parent.Hash()))
+	td := tc.td(parent.Hash())
 	for i, b := range blocks {
-		td := td.Add(td, b.Difficulty())
+		td = addTd(td, b.Header())
 		hash := b.Hash()
 		tc.chain = append(tc.chain, hash)
 		tc.blockm[hash] = b
 		tc.headerm[hash] = b.Header()
 		tc.receiptm[hash] = receipts[i]
-		tc.tdm[hash] = new(big.Int).Set(td)
+		tc.tdm[hash] = td
 	}
 }
 
@@ -166,10 +181,19 @@ func (tc *testChain) headBlock() *types.Block {
 }
 
 // td returns the total difficulty of the given block.
-func (tc *testChain) td(hash common.Hash) *big.Int {
+func (tc *testChain) td(hash common.Hash) []*big.Int {
 	return tc.tdm[hash]
 }
 
+// addTd returns the total difficulty of a header given the one of its parent.
+func addTd(parent []*big.Int, header *types.Header) []*big.Int {
+	td := make([]*big.Int, len(parent))
+	for i := range parent {
+		td[i] = new(big.Int).Add(parent[i], header.Difficulty[i])
+	}
+	return td
+}
+
 // headersByHash returns headers in order from the given hash.
 func (tc *testChain) headersByHash(origin common.Hash, amount int, skip int, reverse bool) []*types.Header {
 	num, _ := tc.hashToNumber(origin)
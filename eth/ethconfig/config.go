@@ -29,6 +29,8 @@ import (
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/eth/downloader"
 	"github.com/spruce-solutions/go-quai/eth/gasprice"
+	"github.com/spruce-solutions/go-quai/eth/protocols/snap"
+	"github.com/spruce-solutions/go-quai/ethclient/quaiclient"
 	"github.com/spruce-solutions/go-quai/ethdb"
 	"github.com/spruce-solutions/go-quai/miner"
 	"github.com/spruce-solutions/go-quai/node"
@@ -60,6 +62,7 @@ var Defaults = Config{
 	SyncMode:                   downloader.SnapSync,
 	Blake3:                     blake3.Config{},
 	NetworkId:                  9000,
+	SyncMaxPeers:               100,
 	TxLookupLimit:              2350000,
 	LightPeers:                 100,
 	UltraLightFraction:         75,
@@ -71,8 +74,15 @@ var Defaults = Config{
 	TrieTimeout:                60 * time.Minute,
 	ExternalBlockCache:         256,
 	ExternalBlocksCacheJournal: "externalblocks",
+	Tiebreak:                   core.TiebreakRandom,
+	TiebreakProbability:        0.5,
 
 	SnapshotCache: 102,
+	PCRCCache:     1024,
+	SnapServe: snap.ServeConfig{
+		Capacity:  8 * 1024 * 1024,
+		PeerQuota: 2 * 1024 * 1024,
+	},
 	Miner: miner.Config{
 		GasCeil:  8000000,
 		GasPrice: big.NewInt(1),
@@ -86,6 +96,10 @@ var Defaults = Config{
 	Zone:        0,
 	DomUrl:      "ws://127.0.0.1:8546",
 	SubUrls:     []string{"ws://127.0.0.1:8546", "ws://127.0.0.1:8546", "ws://127.0.0.1:8546"},
+	Client: quaiclient.Config{
+		CallTimeout: 10 * time.Second,
+		CallRetries: 2,
+	},
 }
 
 func init() {
@@ -103,13 +117,41 @@ type Config struct {
 	NetworkId uint64 // Network ID to use for selecting peers to connect to
 	SyncMode  downloader.SyncMode
 
+	// Hash of the trusted Prime block a zone node checkpoint syncs forward from
+	CheckpointAnchor common.Hash `toml:",omitempty"`
+
+	// Maximum number of network peers while syncing, raised from the node's
+	// peer limit until the chain is synced
+	SyncMaxPeers int
+
 	// This can be set to list of enrtree:// URLs which will be queried for
 	// for nodes to connect to.
 	EthDiscoveryURLs  []string
 	SnapDiscoveryURLs []string
 
-	NoPruning  bool // Whether to disable pruning and flush everything to disk
-	NoPrefetch bool // Whether to disable prefetching and only load state on demand
+	// Bandwidth limits for serving snapshot data to syncing peers
+	SnapServe snap.ServeConfig
+
+	NoPruning         bool // Whether to disable pruning and flush everything to disk
+	NoPrefetch        bool // Whether to disable prefetching and only load state on demand
+	NoInternalTxIndex bool // Whether to skip indexing the value transfers of internal calls
+
+	// Fork choice between heads of equal height and total difficulty
+	Tiebreak            core.TiebreakPolicy
+	TiebreakProbability float64 // Probability of adopting the extern head under the random tiebreak
+
+	// Depth of the zone reorgs refused unless the new head is anchored by a
+	// coincident block of the dominant chain (0 = unlimited)
+	MaxReorgDepth uint64
+
+	// Bounds of the queue of blocks dated ahead of the local clock or missing
+	// their ancestors, re-injected once due (0 = default)
+	FutureBlocks       int
+	FutureBlockHorizon time.Duration
+
+	// Interval between the checks of the database invariants of the imported
+	// canonical blocks, for soak testing (0 = disabled)
+	InvariantCheckInterval time.Duration `toml:",omitempty"`
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
@@ -135,6 +177,7 @@ type Config struct {
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
 	DatabaseFreezer    string
+	DatabaseCompress   bool `toml:",omitempty"` // Whether to compress block bodies and receipts in the key-value store
 
 	TrieCleanCache          int
 	TrieCleanCacheJournal   string        `toml:",omitempty"` // Disk journal directory for trie cache to survive node restarts
@@ -143,6 +186,7 @@ type Config struct {
 	TrieTimeout             time.Duration
 	SnapshotCache           int
 	Preimages               bool
+	PCRCCache               int `toml:",omitempty"` // Number of PCRC results cached
 
 	// External Block cache options
 	ExternalBlockCache         int
@@ -188,11 +232,19 @@ type Config struct {
 	// Zone location options
 	Zone int
 
-	// Dom node websocket url
+	// Dom node websocket url, comma separated urls of equivalent nodes are
+	// failed over between in the given order
 	DomUrl string
 
 	// Sub node websoccket urls
 	SubUrls []string
+
+	// Connection settings of the dom and sub node clients
+	Client quaiclient.Config
+
+	// Urls of the nodes transactions submitted for other locations are
+	// forwarded to by chain ID, failed over between in the given order
+	TxForwardUrls map[uint64][]string `toml:"-"`
 }
 
 // CreateConsensusEngine creates a consensus engine for the given chain configuration.
@@ -203,7 +255,11 @@ func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, co
 	}
 	// Otherwise assume proof-of-work
 	engine, err := blake3.New(blake3.Config{
-		NotifyFull: config.NotifyFull,
+		NotifyFull:        config.NotifyFull,
+		ThreadsAffinity:   config.ThreadsAffinity,
+		DutyCycle:         config.DutyCycle,
+		StratumAddr:       config.StratumAddr,
+		StratumDifficulty: config.StratumDifficulty,
 	}, notify, noverify)
 	if nil != err {
 		log.Fatal(err)
@@ -11,6 +11,8 @@ import (
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/eth/downloader"
 	"github.com/spruce-solutions/go-quai/eth/gasprice"
+	"github.com/spruce-solutions/go-quai/eth/protocols/snap"
+	"github.com/spruce-solutions/go-quai/ethclient/quaiclient"
 	"github.com/spruce-solutions/go-quai/miner"
 	"github.com/spruce-solutions/go-quai/params"
 )
@@ -21,10 +23,20 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
+		CheckpointAnchor        common.Hash `toml:",omitempty"`
+		SyncMaxPeers            int
 		EthDiscoveryURLs        []string
 		SnapDiscoveryURLs       []string
+		SnapServe               snap.ServeConfig
 		NoPruning               bool
 		NoPrefetch              bool
+		NoInternalTxIndex       bool
+		Tiebreak                core.TiebreakPolicy
+		TiebreakProbability     float64
+		MaxReorgDepth           uint64
+		FutureBlocks            int
+		FutureBlockHorizon      time.Duration
+		InvariantCheckInterval  time.Duration          `toml:",omitempty"`
 		TxLookupLimit           uint64                 `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
@@ -41,6 +53,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DatabaseHandles         int                    `toml:"-"`
 		DatabaseCache           int
 		DatabaseFreezer         string
+		DatabaseCompress        bool `toml:",omitempty"`
 		TrieCleanCache          int
 		TrieCleanCacheJournal   string        `toml:",omitempty"`
 		TrieCleanCacheRejournal time.Duration `toml:",omitempty"`
@@ -48,6 +61,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TrieTimeout             time.Duration
 		SnapshotCache           int
 		Preimages               bool
+		PCRCCache               int `toml:",omitempty"`
 		Miner                   miner.Config
 		Blake3                  blake3.Config
 		TxPool                  core.TxPoolConfig
@@ -59,15 +73,27 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideLondon          *big.Int                       `toml:",omitempty"`
+		Client                  quaiclient.Config
+		TxForwardUrls           map[uint64][]string `toml:"-"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
+	enc.CheckpointAnchor = c.CheckpointAnchor
+	enc.SyncMaxPeers = c.SyncMaxPeers
 	enc.EthDiscoveryURLs = c.EthDiscoveryURLs
 	enc.SnapDiscoveryURLs = c.SnapDiscoveryURLs
+	enc.SnapServe = c.SnapServe
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
+	enc.NoInternalTxIndex = c.NoInternalTxIndex
+	enc.Tiebreak = c.Tiebreak
+	enc.TiebreakProbability = c.TiebreakProbability
+	enc.MaxReorgDepth = c.MaxReorgDepth
+	enc.FutureBlocks = c.FutureBlocks
+	enc.FutureBlockHorizon = c.FutureBlockHorizon
+	enc.InvariantCheckInterval = c.InvariantCheckInterval
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.Whitelist = c.Whitelist
 	enc.LightServ = c.LightServ
@@ -84,6 +110,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
 	enc.DatabaseFreezer = c.DatabaseFreezer
+	enc.DatabaseCompress = c.DatabaseCompress
 	enc.TrieCleanCache = c.TrieCleanCache
 	enc.TrieCleanCacheJournal = c.TrieCleanCacheJournal
 	enc.TrieCleanCacheRejournal = c.TrieCleanCacheRejournal
@@ -91,6 +118,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TrieTimeout = c.TrieTimeout
 	enc.SnapshotCache = c.SnapshotCache
 	enc.Preimages = c.Preimages
+	enc.PCRCCache = c.PCRCCache
 	enc.Miner = c.Miner
 	enc.Blake3 = c.Blake3
 	enc.TxPool = c.TxPool
@@ -102,6 +130,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
 	enc.OverrideLondon = c.OverrideLondon
+	enc.Client = c.Client
+	enc.TxForwardUrls = c.TxForwardUrls
 	return &enc, nil
 }
 
@@ -111,10 +141,20 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
+		CheckpointAnchor        *common.Hash `toml:",omitempty"`
+		SyncMaxPeers            *int
 		EthDiscoveryURLs        []string
 		SnapDiscoveryURLs       []string
+		SnapServe               *snap.ServeConfig
 		NoPruning               *bool
 		NoPrefetch              *bool
+		NoInternalTxIndex       *bool
+		Tiebreak                *core.TiebreakPolicy
+		TiebreakProbability     *float64
+		MaxReorgDepth           *uint64
+		FutureBlocks            *int
+		FutureBlockHorizon      *time.Duration
+		InvariantCheckInterval  *time.Duration         `toml:",omitempty"`
 		TxLookupLimit           *uint64                `toml:",omitempty"`
 		Whitelist               map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
@@ -131,6 +171,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DatabaseHandles         *int                   `toml:"-"`
 		DatabaseCache           *int
 		DatabaseFreezer         *string
+		DatabaseCompress        *bool `toml:",omitempty"`
 		TrieCleanCache          *int
 		TrieCleanCacheJournal   *string        `toml:",omitempty"`
 		TrieCleanCacheRejournal *time.Duration `toml:",omitempty"`
@@ -138,6 +179,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TrieTimeout             *time.Duration
 		SnapshotCache           *int
 		Preimages               *bool
+		PCRCCache               *int `toml:",omitempty"`
 		Miner                   *miner.Config
 		Blake3                  *blake3.Config
 		TxPool                  *core.TxPoolConfig
@@ -149,6 +191,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Checkpoint              *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle        *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideLondon          *big.Int                       `toml:",omitempty"`
+		Client                  *quaiclient.Config
+		TxForwardUrls           map[uint64][]string `toml:"-"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -163,18 +207,48 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SyncMode != nil {
 		c.SyncMode = *dec.SyncMode
 	}
+	if dec.CheckpointAnchor != nil {
+		c.CheckpointAnchor = *dec.CheckpointAnchor
+	}
+	if dec.SyncMaxPeers != nil {
+		c.SyncMaxPeers = *dec.SyncMaxPeers
+	}
 	if dec.EthDiscoveryURLs != nil {
 		c.EthDiscoveryURLs = dec.EthDiscoveryURLs
 	}
 	if dec.SnapDiscoveryURLs != nil {
 		c.SnapDiscoveryURLs = dec.SnapDiscoveryURLs
 	}
+	if dec.SnapServe != nil {
+		c.SnapServe = *dec.SnapServe
+	}
 	if dec.NoPruning != nil {
 		c.NoPruning = *dec.NoPruning
 	}
 	if dec.NoPrefetch != nil {
 		c.NoPrefetch = *dec.NoPrefetch
 	}
+	if dec.NoInternalTxIndex != nil {
+		c.NoInternalTxIndex = *dec.NoInternalTxIndex
+	}
+	if dec.Tiebreak != nil {
+		c.Tiebreak = *dec.Tiebreak
+	}
+	if dec.TiebreakProbability != nil {
+		c.TiebreakProbability = *dec.TiebreakProbability
+	}
+	if dec.MaxReorgDepth != nil {
+		c.MaxReorgDepth = *dec.MaxReorgDepth
+	}
+	if dec.FutureBlocks != nil {
+		c.FutureBlocks = *dec.FutureBlocks
+	}
+	if dec.FutureBlockHorizon != nil {
+		c.FutureBlockHorizon = *dec.FutureBlockHorizon
+	}
+	if dec.InvariantCheckInterval != nil {
+		c.InvariantCheckInterval = *dec.InvariantCheckInterval
+	}
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
@@ -223,6 +297,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseFreezer != nil {
 		c.DatabaseFreezer = *dec.DatabaseFreezer
 	}
+	if dec.DatabaseCompress != nil {
+		c.DatabaseCompress = *dec.DatabaseCompress
+	}
 	if dec.TrieCleanCache != nil {
 		c.TrieCleanCache = *dec.TrieCleanCache
 	}
@@ -244,6 +321,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Preimages != nil {
 		c.Preimages = *dec.Preimages
 	}
+	if dec.PCRCCache != nil {
+		c.PCRCCache = *dec.PCRCCache
+	}
 	if dec.Miner != nil {
 		c.Miner = *dec.Miner
 	}
@@ -277,5 +357,11 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.OverrideLondon != nil {
 		c.OverrideLondon = dec.OverrideLondon
 	}
+	if dec.Client != nil {
+		c.Client = *dec.Client
+	}
+	if dec.TxForwardUrls != nil {
+		c.TxForwardUrls = dec.TxForwardUrls
+	}
 	return nil
 }
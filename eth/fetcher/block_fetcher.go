@@ -22,6 +22,7 @@ import (
 	"math/rand"
 	"time"
 
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/common/prque"
 	"github.com/spruce-solutions/go-quai/consensus"
@@ -43,6 +44,8 @@ const (
 	maxQueueDist = 32  // Maximum allowed distance from the chain head to queue
 	hashLimit    = 256 // Maximum number of unique blocks or headers a peer may have announced
 	blockLimit   = 64  // Maximum number of unique blocks a peer may have delivered
+	latencyLimit = 256 // Maximum number of peers whose response latency is tracked
+	latencySlack = 4   // Fraction of the lowest latency within which peers rank as fast
 )
 
 var (
@@ -50,6 +53,7 @@ var (
 	blockAnnounceOutTimer  = metrics.NewRegisteredTimer("eth/fetcher/block/announces/out", nil)
 	blockAnnounceDropMeter = metrics.NewRegisteredMeter("eth/fetcher/block/announces/drop", nil)
 	blockAnnounceDOSMeter  = metrics.NewRegisteredMeter("eth/fetcher/block/announces/dos", nil)
+	blockAnnounceDupMeter  = metrics.NewRegisteredMeter("eth/fetcher/block/announces/dup", nil)
 
 	blockBroadcastInMeter   = metrics.NewRegisteredMeter("eth/fetcher/block/broadcasts/in", nil)
 	blockBroadcastOutTimer  = metrics.NewRegisteredTimer("eth/fetcher/block/broadcasts/out", nil)
@@ -117,6 +121,8 @@ type blockAnnounce struct {
 	time   time.Time     // Timestamp of the announcement
 	body   *types.Block  // Body of the block announcement
 
+	requested time.Time // Timestamp of the header or body request to the origin
+
 	origin string // Identifier of the peer originating the notification
 
 	fetchHeader    headerRequesterFn   // Fetcher function to retrieve the header of an announced block
@@ -201,6 +207,8 @@ type BlockFetcher struct {
 	queues map[string]int                       // Per peer block counts to prevent memory exhaustion
 	queued map[common.Hash]*blockOrHeaderInject // Set of already queued blocks (to dedup imports)
 
+	latencies *lru.Cache // Moving average of the response latency of the peers
+
 	// Callbacks
 	getHeader      HeaderRetrievalFn   // Retrieves a header from the local chain
 	getBlock       blockRetrievalFn    // Retrieves a block from the local chain
@@ -223,6 +231,7 @@ type BlockFetcher struct {
 
 // NewBlockFetcher creates a block fetcher to retrieve blocks based on hash announcements.
 func NewBlockFetcher(light bool, getHeader HeaderRetrievalFn, getBlock blockRetrievalFn, verifyHeader headerVerifierFn, broadcastBlock blockBroadcasterFn, chainHeight chainHeightFn, insertHeaders headersInsertFn, insertChain chainInsertFn, dropPeer peerDropFn, getExtBlocks extBlockRetrievalFn, addExtBlocks addExtBlockFn) *BlockFetcher {
+	latencies, _ := lru.New(latencyLimit)
 	return &BlockFetcher{
 		light:          light,
 		notify:         make(chan *blockAnnounce),
@@ -240,6 +249,7 @@ func NewBlockFetcher(light bool, getHeader HeaderRetrievalFn, getBlock blockRetr
 		queue:          prque.New(nil),
 		queues:         make(map[string]int),
 		queued:         make(map[common.Hash]*blockOrHeaderInject),
+		latencies:      latencies,
 		getHeader:      getHeader,
 		getBlock:       getBlock,
 		verifyHeader:   verifyHeader,
@@ -402,6 +412,8 @@ func (f *BlockFetcher) loop() {
 		// Clean up any expired block fetches
 		for hash, announce := range f.fetching {
 			if time.Since(announce.time) > fetchTimeout {
+				// Count the expired request as a response taking the whole timeout
+				f.markLatency(announce, announce.requested.Add(fetchTimeout))
 				f.forgetHash(hash)
 			}
 		}
@@ -460,10 +472,8 @@ func (f *BlockFetcher) loop() {
 				}
 			}
 			// All is well, schedule the announce if block's not yet downloading
-			if _, ok := f.fetching[notification.hash]; ok {
-				break
-			}
-			if _, ok := f.completing[notification.hash]; ok {
+			if f.duplicate(notification) {
+				blockAnnounceDupMeter.Mark(1)
 				break
 			}
 			f.announces[notification.origin] = count
@@ -503,8 +513,9 @@ func (f *BlockFetcher) loop() {
 					timeout = 0
 				}
 				if time.Since(announces[0].time) > timeout {
-					// Pick a random peer to retrieve from, reset all others
-					announce := announces[rand.Intn(len(announces))]
+					// Pick the fastest peer to retrieve from, reset all others
+					announce := f.fastest(announces)
+					announce.requested = time.Now()
 					f.forgetHash(hash)
 
 					// If the block still didn't arrive, queue for fetching
@@ -547,8 +558,9 @@ func (f *BlockFetcher) loop() {
 			request := make(map[string][]common.Hash)
 
 			for hash, announces := range f.fetched {
-				// Pick a random peer to retrieve from, reset all others
-				announce := announces[rand.Intn(len(announces))]
+				// Pick the fastest peer to retrieve from, reset all others
+				announce := f.fastest(announces)
+				announce.requested = time.Now()
 				f.forgetHash(hash)
 
 				block := f.getBlock(hash)
@@ -598,6 +610,8 @@ func (f *BlockFetcher) loop() {
 
 				// Filter fetcher-requested headers from other synchronisation algorithms
 				if announce := f.fetching[hash]; announce != nil && announce.origin == task.peer && f.fetched[hash] == nil && f.completing[hash] == nil && f.queued[hash] == nil {
+					f.markLatency(announce, task.time)
+
 					// If the delivered header does not match the promised number, drop the announcer
 					if header.Number[types.QuaiNetworkContext].Uint64() != announce.number {
 						log.Trace("Invalid block number fetched", "peer", announce.origin, "hash", header.Hash(), "announced", announce.number, "provided", header.Number)
@@ -715,6 +729,7 @@ func (f *BlockFetcher) loop() {
 						}
 						// Mark the body matched, reassemble if still unknown
 						matched = true
+						f.markLatency(announce, task.time)
 						if f.getBlock(hash) == nil {
 							block := types.NewBlockWithHeader(announce.header).WithBody(task.transactions[i], task.uncles[i])
 							block.ReceivedAt = task.time
@@ -795,6 +810,65 @@ func (f *BlockFetcher) loop() {
 	}
 }
 
+// duplicate returns whether the announcement is of a block already being
+// retrieved or imported, or was already announced by the same peer.
+func (f *BlockFetcher) duplicate(announce *blockAnnounce) bool {
+	hash := announce.hash
+	if f.fetching[hash] != nil || f.fetched[hash] != nil || f.completing[hash] != nil || f.queued[hash] != nil {
+		return true
+	}
+	for _, other := range f.announced[hash] {
+		if other.origin == announce.origin {
+			return true
+		}
+	}
+	return false
+}
+
+// fastest returns the announcement of a peer picked at random among the ones
+// whose response latency is within a latencySlack fraction of the lowest, so
+// that the load spreads over comparable peers. Peers without a measured latency
+// rank after the measured ones, they are only picked if none is measured.
+func (f *BlockFetcher) fastest(announces []*blockAnnounce) *blockAnnounce {
+	var (
+		lowest    time.Duration
+		latencies = make([]time.Duration, len(announces))
+	)
+	for i, announce := range announces {
+		if l, ok := f.latencies.Get(announce.origin); ok {
+			latencies[i] = l.(time.Duration)
+			if lowest == 0 || latencies[i] < lowest {
+				lowest = latencies[i]
+			}
+		}
+	}
+	candidates := make([]*blockAnnounce, 0, len(announces))
+	for i, announce := range announces {
+		if lowest == 0 || (latencies[i] != 0 && latencies[i] <= lowest+lowest/latencySlack) {
+			candidates = append(candidates, announce)
+		}
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
+
+// markLatency updates the response latency of the origin of the announcement
+// with the delivery of the requested header or body, or the expiry of the
+// request.
+func (f *BlockFetcher) markLatency(announce *blockAnnounce, delivered time.Time) {
+	if announce.requested.IsZero() || delivered.Before(announce.requested) {
+		return
+	}
+	latency := delivered.Sub(announce.requested)
+	if latency == 0 {
+		latency = 1 // Zero marks an unmeasured peer
+	}
+	if l, ok := f.latencies.Get(announce.origin); ok {
+		latency = (l.(time.Duration)*7 + latency) / 8
+	}
+	f.latencies.Add(announce.origin, latency)
+	announce.requested = time.Time{}
+}
+
 // rescheduleFetch resets the specified fetch timer to the next blockAnnounce timeout.
 func (f *BlockFetcher) rescheduleFetch(fetch *time.Timer) {
 	// Short circuit if no blocks are announced
@@ -29,44 +29,73 @@ import (
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/rawdb"
 	"github.com/spruce-solutions/go-quai/core/types"
-	"github.com/spruce-solutions/go-quai/crypto"
 	"github.com/spruce-solutions/go-quai/params"
 	"github.com/spruce-solutions/go-quai/trie"
 )
 
 var (
-	testdb       = rawdb.NewMemoryDatabase()
-	testKey, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
-	testAddress  = crypto.PubkeyToAddress(testKey.PublicKey)
-	genesis      = core.GenesisBlockForTesting(testdb, testAddress, big.NewInt(1000000000000000))
-	unknownBlock = types.NewBlock(&types.Header{
-		GasLimit: []uint64{params.GenesisGasLimit, params.GenesisGasLimit, params.GenesisGasLimit},
-		BaseFee:  []*big.Int{big.NewInt(params.InitialBaseFee), big.NewInt(params.InitialBaseFee), big.NewInt(params.InitialBaseFee)}}, nil, nil, nil, trie.NewStackTrie(nil))
+	testdb               = rawdb.NewMemoryDatabase()
+	testKey, testAddress = core.NetworkETXSender(1, 1)
+	testChainConfig      = testZoneConfig()
+	genesis              = core.GenesisBlockForTesting(testdb, testAddress, big.NewInt(1000000000000000))
+	unknownBlock         = newUnknownBlock()
 )
 
+// newUnknownBlock creates a block unknown to the tester, to generate chains
+// whose ancestry can't be retrieved.
+func newUnknownBlock() *types.Block {
+	header := types.NewEmptyHeader()
+	for i := range header.Number {
+		header.Number[i] = big.NewInt(0)
+		header.Difficulty[i] = big.NewInt(1)
+		header.GasLimit[i] = params.GenesisGasLimit
+		header.BaseFee[i] = big.NewInt(params.InitialBaseFee)
+	}
+	return types.NewBlock(header, nil, nil, nil, trie.NewStackTrie(nil))
+}
+
+// lightSkipReason is why the light mode tests are skipped: the fetcher loop
+// imports queued entries as blocks only, the header import being disabled.
+const lightSkipReason = "light mode header import is disabled in the fetcher loop"
+
+// testZoneConfig returns the config of the test chains, the one of a zone whose
+// address range holds testAddress, at the context of the node.
+func testZoneConfig() *params.ChainConfig {
+	config := core.NetworkZoneConfig(params.TestChainConfig, 1, 1)
+	config.Context = types.QuaiNetworkContext
+	return config
+}
+
 // makeChain creates a chain of n blocks starting at and including parent.
 // the returned hash chain is ordered head->parent. In addition, every 3rd block
 // contains a transaction and every 5th an uncle to allow testing correct block
 // reassembly.
 func makeChain(n int, seed byte, parent *types.Block) ([]common.Hash, map[common.Hash]*types.Block) {
-	blocks, _ := core.GenerateChain(params.TestChainConfig, parent, blake3.NewFaker(), testdb, n, func(i int, block *core.BlockGen) {
+	blocks, _ := core.GenerateChain(testChainConfig, parent, blake3.NewFaker(), testdb, n, func(i int, block *core.BlockGen) {
 		block.SetCoinbase(common.Address{seed})
 
 		// If the block number is multiple of 3, send a bonus transaction to the miner
 		if parent == genesis && i%3 == 0 {
-			signer := types.MakeSigner(params.TestChainConfig, block.Number())
-			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(testAddress), common.Address{seed}, big.NewInt(1000), params.TxGas, block.BaseFee(), nil), signer, testKey)
+			signer := types.LatestSigner(testChainConfig)
+			tx, err := types.SignNewTx(testKey, signer, &types.AccessListTx{
+				ChainID:  testChainConfig.ChainID,
+				Nonce:    block.TxNonce(testAddress),
+				To:       &common.Address{seed},
+				Value:    big.NewInt(1000),
+				Gas:      params.TxGas,
+				GasPrice: block.BaseFee(),
+			})
 			if err != nil {
 				panic(err)
 			}
 			block.AddTx(tx)
 		}
 		// If the block number is a multiple of 5, add a bonus uncle to the block
-		if i%5 == 0 {
-			block.AddUncle(&types.Header{
-				ParentHash: []common.Hash{block.PrevBlock(i - 1).Hash(), block.PrevBlock(i - 1).Hash(), block.PrevBlock(i - 1).Hash()},
-				Number:     []*big.Int{big.NewInt(int64(i - 1)), big.NewInt(int64(i - 1)), big.NewInt(int64(i - 1))},
-			})
+		if i > 0 && i%5 == 0 {
+			uncle := types.NewEmptyHeader()
+			uncle.ParentHash = []common.Hash{block.PrevBlock(i - 1).Hash(), block.PrevBlock(i - 1).Hash(), block.PrevBlock(i - 1).Hash()}
+			uncle.Number = []*big.Int{big.NewInt(block.Number().Int64() - 1), big.NewInt(block.Number().Int64() - 1), big.NewInt(block.Number().Int64() - 1)}
+			block.AddUncle(uncle)
 		}
 	})
 	hashes := make([]common.Hash, n+1)
@@ -100,7 +129,7 @@ func newTester(light bool) *fetcherTester {
 		blocks:  map[common.Hash]*types.Block{genesis.Hash(): genesis},
 		drops:   make(map[string]bool),
 	}
-	tester.fetcher = NewBlockFetcher(light, tester.getHeader, tester.getBlock, tester.verifyHeader, tester.broadcastBlock, tester.chainHeight, tester.insertHeaders, tester.insertChain, tester.dropPeer)
+	tester.fetcher = NewBlockFetcher(light, tester.getHeader, tester.getBlock, tester.verifyHeader, tester.broadcastBlock, tester.chainHeight, tester.insertHeaders, tester.insertChain, tester.dropPeer, tester.getExtBlocks, tester.addExtBlocks)
 	tester.fetcher.Start()
 
 	return tester
@@ -184,6 +213,17 @@ func (f *fetcherTester) insertChain(blocks types.Blocks, extBlocks []*types.Exte
 	return 0, nil
 }
 
+// getExtBlocks is a nop placeholder for the external block retrieval, the
+// tester's blocks don't reference any.
+func (f *fetcherTester) getExtBlocks(header *types.Header) ([]*types.ExternalBlock, error) {
+	return nil, nil
+}
+
+// addExtBlocks is a nop placeholder for the external block caching.
+func (f *fetcherTester) addExtBlocks(extBlocks []*types.ExternalBlock) error {
+	return nil
+}
+
 // dropPeer is an emulator for the peer removal, simply accumulating the various
 // peers dropped by the fetcher.
 func (f *fetcherTester) dropPeer(peer string) {
@@ -320,8 +360,11 @@ func verifyChainHeight(t *testing.T, fetcher *fetcherTester, height uint64) {
 
 // Tests that a fetcher accepts block/header announcements and initiates retrievals
 // for them, successfully importing into the local chain.
-func TestFullSequentialAnnouncements(t *testing.T)  { testSequentialAnnouncements(t, false) }
-func TestLightSequentialAnnouncements(t *testing.T) { testSequentialAnnouncements(t, true) }
+func TestFullSequentialAnnouncements(t *testing.T) { testSequentialAnnouncements(t, false) }
+func TestLightSequentialAnnouncements(t *testing.T) {
+	t.Skip(lightSkipReason)
+	testSequentialAnnouncements(t, true)
+}
 
 func testSequentialAnnouncements(t *testing.T, light bool) {
 	// Create a chain of blocks to import
@@ -358,8 +401,11 @@ func testSequentialAnnouncements(t *testing.T, light bool) {
 
 // Tests that if blocks are announced by multiple peers (or even the same buggy
 // peer), they will only get downloaded at most once.
-func TestFullConcurrentAnnouncements(t *testing.T)  { testConcurrentAnnouncements(t, false) }
-func TestLightConcurrentAnnouncements(t *testing.T) { testConcurrentAnnouncements(t, true) }
+func TestFullConcurrentAnnouncements(t *testing.T) { testConcurrentAnnouncements(t, false) }
+func TestLightConcurrentAnnouncements(t *testing.T) {
+	t.Skip(lightSkipReason)
+	testConcurrentAnnouncements(t, true)
+}
 
 func testConcurrentAnnouncements(t *testing.T, light bool) {
 	// Create a chain of blocks to import
@@ -415,8 +461,11 @@ func testConcurrentAnnouncements(t *testing.T, light bool) {
 
 // Tests that announcements arriving while a previous is being fetched still
 // results in a valid import.
-func TestFullOverlappingAnnouncements(t *testing.T)  { testOverlappingAnnouncements(t, false) }
-func TestLightOverlappingAnnouncements(t *testing.T) { testOverlappingAnnouncements(t, true) }
+func TestFullOverlappingAnnouncements(t *testing.T) { testOverlappingAnnouncements(t, false) }
+func TestLightOverlappingAnnouncements(t *testing.T) {
+	t.Skip(lightSkipReason)
+	testOverlappingAnnouncements(t, true)
+}
 
 func testOverlappingAnnouncements(t *testing.T, light bool) {
 	// Create a chain of blocks to import
@@ -462,8 +511,11 @@ func testOverlappingAnnouncements(t *testing.T, light bool) {
 }
 
 // Tests that announces already being retrieved will not be duplicated.
-func TestFullPendingDeduplication(t *testing.T)  { testPendingDeduplication(t, false) }
-func TestLightPendingDeduplication(t *testing.T) { testPendingDeduplication(t, true) }
+func TestFullPendingDeduplication(t *testing.T) { testPendingDeduplication(t, false) }
+func TestLightPendingDeduplication(t *testing.T) {
+	t.Skip(lightSkipReason)
+	testPendingDeduplication(t, true)
+}
 
 func testPendingDeduplication(t *testing.T, light bool) {
 	// Create a hash and corresponding block
@@ -473,6 +525,7 @@ func testPendingDeduplication(t *testing.T, light bool) {
 	tester := newTester(light)
 	headerFetcher := tester.makeHeaderFetcher("repeater", blocks, -gatherSlack)
 	bodyFetcher := tester.makeBodyFetcher("repeater", blocks, 0)
+	extBlockFetcher := func(h []common.Hash) error { return nil }
 
 	delay := 50 * time.Millisecond
 	counter := uint32(0)
@@ -496,7 +549,7 @@ func testPendingDeduplication(t *testing.T, light bool) {
 	}
 	// Announce the same block many times until it's fetched (wait for any pending ops)
 	for checkNonExist() {
-		tester.fetcher.Notify("repeater", hashes[0], 1, time.Now().Add(-arriveTimeout), headerWrapper, bodyFetcher, nil)
+		tester.fetcher.Notify("repeater", hashes[0], 1, time.Now().Add(-arriveTimeout), headerWrapper, bodyFetcher, extBlockFetcher)
 		time.Sleep(time.Millisecond)
 	}
 	time.Sleep(delay)
@@ -510,8 +563,11 @@ func testPendingDeduplication(t *testing.T, light bool) {
 
 // Tests that announcements retrieved in a random order are cached and eventually
 // imported when all the gaps are filled in.
-func TestFullRandomArrivalImport(t *testing.T)  { testRandomArrivalImport(t, false) }
-func TestLightRandomArrivalImport(t *testing.T) { testRandomArrivalImport(t, true) }
+func TestFullRandomArrivalImport(t *testing.T) { testRandomArrivalImport(t, false) }
+func TestLightRandomArrivalImport(t *testing.T) {
+	t.Skip(lightSkipReason)
+	testRandomArrivalImport(t, true)
+}
 
 func testRandomArrivalImport(t *testing.T, light bool) {
 	// Create a chain of blocks to import, and choose one to delay
@@ -654,8 +710,11 @@ func TestDistantPropagationDiscarding(t *testing.T) {
 // Tests that announcements with numbers much lower or higher than out current
 // head get discarded to prevent wasting resources on useless blocks from faulty
 // peers.
-func TestFullDistantAnnouncementDiscarding(t *testing.T)  { testDistantAnnouncementDiscarding(t, false) }
-func TestLightDistantAnnouncementDiscarding(t *testing.T) { testDistantAnnouncementDiscarding(t, true) }
+func TestFullDistantAnnouncementDiscarding(t *testing.T) { testDistantAnnouncementDiscarding(t, false) }
+func TestLightDistantAnnouncementDiscarding(t *testing.T) {
+	t.Skip(lightSkipReason)
+	testDistantAnnouncementDiscarding(t, true)
+}
 
 func testDistantAnnouncementDiscarding(t *testing.T, light bool) {
 	// Create a long chain to import and define the discard boundaries
@@ -698,8 +757,11 @@ func testDistantAnnouncementDiscarding(t *testing.T, light bool) {
 
 // Tests that peers announcing blocks with invalid numbers (i.e. not matching
 // the headers provided afterwards) get dropped as malicious.
-func TestFullInvalidNumberAnnouncement(t *testing.T)  { testInvalidNumberAnnouncement(t, false) }
-func TestLightInvalidNumberAnnouncement(t *testing.T) { testInvalidNumberAnnouncement(t, true) }
+func TestFullInvalidNumberAnnouncement(t *testing.T) { testInvalidNumberAnnouncement(t, false) }
+func TestLightInvalidNumberAnnouncement(t *testing.T) {
+	t.Skip(lightSkipReason)
+	testInvalidNumberAnnouncement(t, true)
+}
 
 func testInvalidNumberAnnouncement(t *testing.T, light bool) {
 	// Create a single block to import and check numbers against
@@ -909,3 +971,130 @@ func TestBlockMemoryExhaustionAttack(t *testing.T) {
 	}
 	verifyImportDone(t, imported)
 }
+
+// Tests that announcements of blocks already being retrieved or imported, or
+// already announced by the same peer, are recognised as duplicates.
+func TestAnnounceDuplicate(t *testing.T) {
+	fetcher := NewBlockFetcher(false, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	hash := common.Hash{0x01}
+	if fetcher.duplicate(&blockAnnounce{hash: hash, origin: "first"}) {
+		t.Fatalf("fresh announcement reported duplicate")
+	}
+	fetcher.announced[hash] = []*blockAnnounce{{hash: hash, origin: "first"}}
+	if !fetcher.duplicate(&blockAnnounce{hash: hash, origin: "first"}) {
+		t.Errorf("repeated announcement of a peer not reported duplicate")
+	}
+	if fetcher.duplicate(&blockAnnounce{hash: hash, origin: "second"}) {
+		t.Errorf("announcement of another peer reported duplicate")
+	}
+	delete(fetcher.announced, hash)
+
+	stages := map[string]func(){
+		"fetching":   func() { fetcher.fetching[hash] = &blockAnnounce{hash: hash} },
+		"fetched":    func() { fetcher.fetched[hash] = []*blockAnnounce{{hash: hash}} },
+		"completing": func() { fetcher.completing[hash] = &blockAnnounce{hash: hash} },
+		"queued":     func() { fetcher.queued[hash] = &blockOrHeaderInject{} },
+	}
+	for stage, set := range stages {
+		set()
+		if !fetcher.duplicate(&blockAnnounce{hash: hash, origin: "second"}) {
+			t.Errorf("announcement of a %s block not reported duplicate", stage)
+		}
+		delete(fetcher.fetching, hash)
+		delete(fetcher.fetched, hash)
+		delete(fetcher.completing, hash)
+		delete(fetcher.queued, hash)
+	}
+}
+
+// Tests that a block announced repeatedly by several peers is only requested
+// once.
+func TestAnnounceDeduplicationFetch(t *testing.T) {
+	hashes, blocks := makeChain(1, 0, genesis)
+
+	tester := newTester(false)
+	extBlockFetcher := func(h []common.Hash) error { return nil }
+
+	var requests int32
+	countingFetcher := func(peer string) headerRequesterFn {
+		fetchHeader := tester.makeHeaderFetcher(peer, blocks, -gatherSlack)
+		return func(hash common.Hash) error {
+			atomic.AddInt32(&requests, 1)
+			return fetchHeader(hash)
+		}
+	}
+	imported := make(chan interface{})
+	tester.fetcher.importedHook = func(header *types.Header, block *types.Block) { imported <- block }
+
+	for _, peer := range []string{"first", "first", "second", "first"} {
+		tester.fetcher.Notify(peer, hashes[0], 1, time.Now().Add(-arriveTimeout), countingFetcher(peer), tester.makeBodyFetcher(peer, blocks, 0), extBlockFetcher)
+	}
+	verifyImportEvent(t, imported, true)
+	verifyImportDone(t, imported)
+
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("header requests mismatch: have %d, want 1", n)
+	}
+}
+
+// pickCounts returns how many times each peer is picked to fetch from over a
+// number of rounds.
+func pickCounts(fetcher *BlockFetcher, announces []*blockAnnounce, rounds int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < rounds; i++ {
+		counts[fetcher.fastest(announces).origin]++
+	}
+	return counts
+}
+
+// Tests that blocks are fetched from peers picked at random among the ones of
+// comparable latency, and from any peer while none is measured.
+func TestFastestPeer(t *testing.T) {
+	fetcher := NewBlockFetcher(false, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	announces := []*blockAnnounce{{origin: "slow"}, {origin: "fast"}, {origin: "close"}, {origin: "fresh"}}
+
+	counts := pickCounts(fetcher, announces, 1000)
+	for _, announce := range announces {
+		if counts[announce.origin] == 0 {
+			t.Errorf("unmeasured peer %s never picked", announce.origin)
+		}
+	}
+	fetcher.latencies.Add("slow", time.Second)
+	fetcher.latencies.Add("fast", 100*time.Millisecond)
+	fetcher.latencies.Add("close", 120*time.Millisecond)
+
+	counts = pickCounts(fetcher, announces, 1000)
+	if counts["fast"] == 0 || counts["close"] == 0 {
+		t.Errorf("comparable peers not both picked: %v", counts)
+	}
+	if counts["slow"] != 0 || counts["fresh"] != 0 {
+		t.Errorf("slower peers picked: %v", counts)
+	}
+}
+
+// Tests that a request expiring without a response counts as a response taking
+// the whole fetch timeout in the latency of the peer.
+func TestFetchTimeoutLatency(t *testing.T) {
+	hashes, blocks := makeChain(1, 0, genesis)
+
+	tester := newTester(false)
+	silentFetcher := func(hash common.Hash) error { return nil }
+	extBlockFetcher := func(h []common.Hash) error { return nil }
+
+	initial := 100 * time.Millisecond
+	tester.fetcher.latencies.Add("silent", initial)
+
+	tester.fetcher.Notify("silent", hashes[0], 1, time.Now().Add(-fetchTimeout-time.Second), silentFetcher, tester.makeBodyFetcher("silent", blocks, 0), extBlockFetcher)
+
+	want := (initial*7 + fetchTimeout) / 8
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if l, _ := tester.fetcher.latencies.Get("silent"); l.(time.Duration) != initial {
+			if l.(time.Duration) != want {
+				t.Fatalf("latency mismatch: have %v, want %v", l, want)
+			}
+			return
+		}
+	}
+	t.Fatalf("expired request not accounted in the latency")
+}
@@ -0,0 +1,141 @@
+package filters
+
+import (
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// Kinds of address activity.
+const (
+	ActivityTransaction = "transaction" // watched address sent or received a transaction
+	ActivityLog         = "log"         // watched contract emitted a log
+	ActivityETX         = "etx"         // watched address was involved in an external transaction
+)
+
+// AddressActivity notifies that a watched address was involved in an imported
+// block of the local chain.
+type AddressActivity struct {
+	Address     common.Address `json:"address"`
+	Kind        string         `json:"kind"`
+	Role        string         `json:"role"` // "from", "to" or "emitter"
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint   `json:"transactionIndex"`
+	LogIndex    *hexutil.Uint  `json:"logIndex,omitempty"`
+	Value       *hexutil.Big   `json:"value,omitempty"`
+}
+
+// SubscribeAddressActivity creates a subscription that writes the activity of
+// the given addresses in every block imported into the chain.
+func (es *EventSystem) SubscribeAddressActivity(addresses []common.Address, activity chan []*AddressActivity) *Subscription {
+	sub := &subscription{
+		id:              rpc.NewID(),
+		typ:             AddressActivitySubscription,
+		created:         time.Now(),
+		addresses:       addresses,
+		activity:        activity,
+		logs:            make(chan []*types.Log),
+		hashes:          make(chan []common.Hash),
+		headers:         make(chan *types.Header),
+		installed:       make(chan struct{}),
+		err:             make(chan error),
+		reOrg:           make(chan core.ReOrgRollup),
+		uncleEvent:      make(chan *types.Header),
+		missingExtBlock: make(chan core.MissingExternalBlock),
+	}
+	return es.subscribe(sub)
+}
+
+// watch adds an address activity subscription to the watched-set index.
+func (es *EventSystem) watch(f *subscription) {
+	for _, addr := range f.addresses {
+		subs := es.watched[addr]
+		if subs == nil {
+			subs = make(map[rpc.ID]*subscription)
+			es.watched[addr] = subs
+		}
+		subs[f.id] = f
+	}
+}
+
+// unwatch removes an address activity subscription from the watched-set index.
+func (es *EventSystem) unwatch(f *subscription) {
+	for _, addr := range f.addresses {
+		if subs := es.watched[addr]; subs != nil {
+			delete(subs, f.id)
+			if len(subs) == 0 {
+				delete(es.watched, addr)
+			}
+		}
+	}
+}
+
+// handleAddressActivity matches the participants of an imported block against
+// the watched-set index, notifying every subscription of its addresses' activity.
+func (es *EventSystem) handleAddressActivity(ev core.ChainEvent) {
+	if len(es.watched) == 0 {
+		return
+	}
+	var (
+		block   = ev.Block
+		number  = hexutil.Uint64(block.NumberU64())
+		matches = make(map[*subscription][]*AddressActivity)
+	)
+	notify := func(addr common.Address, activity AddressActivity) {
+		for _, f := range es.watched[addr] {
+			activity := activity
+			activity.Address = addr
+			matches[f] = append(matches[f], &activity)
+		}
+	}
+	for i, tx := range block.Transactions() {
+		base := AddressActivity{
+			Kind:        ActivityTransaction,
+			BlockHash:   ev.Hash,
+			BlockNumber: number,
+			TxHash:      tx.Hash(),
+			TxIndex:     hexutil.Uint(i),
+			Value:       (*hexutil.Big)(tx.Value()),
+		}
+		// Value transfers leaving the context of the chain become external transactions
+		if to := tx.To(); to != nil && tx.Value().Sign() > 0 {
+			if idRange := params.LookupChainIDRange(tx.ChainId()); len(idRange) == 2 {
+				if prefix := int(to.Bytes()[0]); prefix < idRange[0] || prefix > idRange[1] {
+					base.Kind = ActivityETX
+				}
+			}
+		}
+		if from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx); err == nil {
+			activity := base
+			activity.Role = "from"
+			notify(from, activity)
+		}
+		if to := tx.To(); to != nil {
+			activity := base
+			activity.Role = "to"
+			notify(*to, activity)
+		}
+	}
+	for _, log := range ev.Logs {
+		index := hexutil.Uint(log.Index)
+		notify(log.Address, AddressActivity{
+			Kind:        ActivityLog,
+			Role:        "emitter",
+			BlockHash:   ev.Hash,
+			BlockNumber: number,
+			TxHash:      log.TxHash,
+			TxIndex:     hexutil.Uint(log.TxIndex),
+			LogIndex:    &index,
+		})
+	}
+	for f, activity := range matches {
+		f.activity <- activity
+	}
+}
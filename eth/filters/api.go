@@ -28,11 +28,13 @@ import (
 	ethereum "github.com/spruce-solutions/go-quai"
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/contractmeta"
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/ethdb"
 	"github.com/spruce-solutions/go-quai/event"
 	"github.com/spruce-solutions/go-quai/rpc"
+	"golang.org/x/time/rate"
 )
 
 // filter is a helper struct that holds meta information over the filter type
@@ -57,6 +59,8 @@ type PublicFilterAPI struct {
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
 	timeout   time.Duration
+
+	backfillLimiter *rate.Limiter // throttle of the backfilling subscriptions
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
@@ -67,6 +71,8 @@ func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration)
 		events:  NewEventSystem(backend, lightMode),
 		filters: make(map[rpc.ID]*filter),
 		timeout: timeout,
+
+		backfillLimiter: rate.NewLimiter(backfillRate, backfillChunk),
 	}
 	go api.timeoutLoop(timeout)
 
@@ -361,6 +367,74 @@ func (api *PublicFilterAPI) MissingExtBlock(ctx context.Context) (*rpc.Subscript
 	return rpcSub, nil
 }
 
+// ForkChoice creates a subscription that fires each time the fork choice selects
+// a new head, with the difficulties compared and the reason for the decision.
+func (api *PublicFilterAPI) ForkChoice(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		forkChoice := make(chan core.ForkChoiceEvent)
+		forkChoiceSub := api.events.SubscribeForkChoice(forkChoice)
+
+		for {
+			select {
+			case ev := <-forkChoice:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-rpcSub.Err():
+				forkChoiceSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				forkChoiceSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// AddressActivity creates a subscription that fires when any of the given
+// addresses sends or receives a transaction, emits a log or is involved in an
+// external transaction in a block imported into the local chain.
+func (api *PublicFilterAPI) AddressActivity(ctx context.Context, addresses []common.Address) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("no addresses to watch")
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		activity := make(chan []*AddressActivity)
+		activitySub := api.events.SubscribeAddressActivity(addresses, activity)
+
+		for {
+			select {
+			case entries := <-activity:
+				for _, entry := range entries {
+					notifier.Notify(rpcSub.ID, entry)
+				}
+			case <-rpcSub.Err():
+				activitySub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				activitySub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
 func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
@@ -452,29 +526,98 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 //
 // https://eth.wiki/json-rpc/API#eth_getlogs
 func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
-	var filter *Filter
-	if crit.BlockHash != nil {
-		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics)
-	} else {
-		// Convert the RPC block numbers into internal representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if crit.FromBlock != nil {
-			begin = crit.FromBlock.Int64()
+	// Run the filter and return all the logs
+	logs, err := api.criteriaFilter(crit).Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return returnLogs(logs), err
+}
+
+// GetLogById returns the log with the given identifier, or nil if its block or
+// the log is unknown. Logs of blocks no longer canonical are returned with the
+// removed flag set, so consumers replaying reorgs can retract them.
+func (api *PublicFilterAPI) GetLogById(ctx context.Context, id types.LogID) (*types.Log, error) {
+	header, err := api.backend.HeaderByHash(ctx, id.BlockHash())
+	if header == nil || err != nil {
+		return nil, err
+	}
+	logs, err := api.backend.GetLogs(ctx, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	if id.TxIndex() >= uint(len(logs)) {
+		return nil, nil
+	}
+	for _, log := range logs[id.TxIndex()] {
+		if log.Index != id.Index() {
+			continue
 		}
-		end := rpc.LatestBlockNumber.Int64()
-		if crit.ToBlock != nil {
-			end = crit.ToBlock.Int64()
+		canonical, err := api.backend.HeaderByNumber(ctx, rpc.BlockNumber(header.Number[types.QuaiNetworkContext].Int64()))
+		if err != nil {
+			return nil, err
 		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
+		if canonical == nil || canonical.Hash() != header.Hash() {
+			removed := *log
+			removed.Removed = true
+			return &removed, nil
+		}
+		return log, nil
 	}
-	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
+	return nil, nil
+}
+
+// EstimateLogsCost estimates the work of retrieving the logs matching the given
+// argument without running the query, returning the number of blocks whose
+// receipts would be loaded overall and per bloom section of the range, so that
+// large ranges can be split into queries of bounded cost.
+func (api *PublicFilterAPI) EstimateLogsCost(ctx context.Context, crit FilterCriteria) (*LogsCost, error) {
+	return api.criteriaFilter(crit).Cost(ctx)
+}
+
+// criteriaFilter constructs the filter of a log query.
+func (api *PublicFilterAPI) criteriaFilter(crit FilterCriteria) *Filter {
+	if crit.BlockHash != nil {
+		// Block filter requested, construct a single-shot filter
+		return NewBlockFilter(api.backend, *crit.BlockHash, crit.Addresses, crit.Topics)
+	}
+	// Convert the RPC block numbers into internal representations
+	begin := rpc.LatestBlockNumber.Int64()
+	if crit.FromBlock != nil {
+		begin = crit.FromBlock.Int64()
+	}
+	end := rpc.LatestBlockNumber.Int64()
+	if crit.ToBlock != nil {
+		end = crit.ToBlock.Int64()
+	}
+	// Construct the range filter
+	return NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
+}
+
+// DecodedLog is a log along with its decoding by the metadata of the emitter.
+type DecodedLog struct {
+	Log     *types.Log          `json:"log"`
+	Decoded *contractmeta.Event `json:"decoded,omitempty"` // nil if the emitter is not verified
+}
+
+// GetDecodedLogs returns the logs matching the given argument, decoding those
+// emitted by verified contracts with their metadata.
+func (api *PublicFilterAPI) GetDecodedLogs(ctx context.Context, crit FilterCriteria) ([]*DecodedLog, error) {
+	backend, ok := api.backend.(contractmeta.Backend)
+	if !ok || backend.ContractMetadata() == nil {
+		return nil, errors.New("contract metadata unavailable")
+	}
+	logs, err := api.GetLogs(ctx, crit)
 	if err != nil {
 		return nil, err
 	}
-	return returnLogs(logs), err
+	store := backend.ContractMetadata()
+
+	decoded := make([]*DecodedLog, len(logs))
+	for i, log := range logs {
+		decoded[i] = &DecodedLog{Log: log, Decoded: contractmeta.DecodeLog(store, log)}
+	}
+	return decoded, nil
 }
 
 // UninstallFilter removes the filter with the given filter id.
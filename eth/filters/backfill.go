@@ -0,0 +1,201 @@
+package filters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	ethereum "github.com/spruce-solutions/go-quai"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+const (
+	// backfillRate is the number of historical blocks per second replayed to
+	// all the backfilling subscriptions of an API together.
+	backfillRate = 256
+
+	// backfillChunk is the number of blocks replayed at once.
+	backfillChunk = 32
+)
+
+// Kinds of events replayable by a backfilling subscription.
+const (
+	BackfillNewHeads = "newHeads"
+	BackfillLogs     = "logs"
+)
+
+// backfill tracks the progress of a backfilling subscription, so that the live
+// events overlapping the replayed history are delivered exactly once.
+type backfill struct {
+	next   uint64                 // next block to replay
+	live   bool                   // whether the history was replayed
+	recent map[common.Hash]uint64 // recently replayed blocks, by hash
+}
+
+// replayed records a block as delivered by the replay.
+func (b *backfill) replayed(hash common.Hash, number uint64) {
+	b.recent[hash] = number
+}
+
+// advance moves the replay past a chunk, forgetting the blocks no live event
+// can overlap anymore.
+func (b *backfill) advance(next uint64) {
+	b.next = next
+	for hash, number := range b.recent {
+		if number+2*backfillChunk < next {
+			delete(b.recent, hash)
+		}
+	}
+}
+
+// deliver reports whether a live event of the given block is to be delivered.
+// Events of blocks yet to be replayed are dropped as the replay delivers them
+// from the chain, as are events of blocks already delivered by the replay. An
+// event of an earlier block with another hash is a reorg and is delivered.
+func (b *backfill) deliver(hash common.Hash, number uint64, removed bool) bool {
+	if !b.live && number >= b.next {
+		return false
+	}
+	if removed {
+		return true
+	}
+	_, ok := b.recent[hash]
+	return !ok
+}
+
+// WithBackfill creates a subscription replaying the events of the canonical
+// chain from the given block number before switching to the live events, so
+// that indexers catch up and follow the chain through a single code path. The
+// subscription is made with quai_subscribe("withBackfill", kind, from, crit),
+// where kind is "newHeads" or "logs" and crit holds the criteria of the logs.
+// The notifications are the same as the ones of the live subscriptions.
+//
+// The replay is throttled on the server, sharing a budget of blocks per second
+// between all backfilling subscriptions.
+func (api *PublicFilterAPI) WithBackfill(ctx context.Context, kind string, from hexutil.Uint64, crit *FilterCriteria) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	var (
+		headers = make(chan *types.Header)
+		logs    = make(chan []*types.Log)
+		sub     *Subscription
+	)
+	switch kind {
+	case BackfillNewHeads:
+		sub = api.events.SubscribeNewHeads(headers)
+	case BackfillLogs:
+		if crit == nil {
+			return nil, errors.New("missing log criteria")
+		}
+		if crit.BlockHash != nil || crit.FromBlock != nil || crit.ToBlock != nil {
+			return nil, errors.New("log criteria cannot have a block range, the replay starts at the given block")
+		}
+		var err error
+		if sub, err = api.events.SubscribeLogs(ethereum.FilterQuery(*crit), logs); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported backfill kind %q", kind)
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		defer sub.Unsubscribe()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var (
+			state = &backfill{next: uint64(from), recent: make(map[common.Hash]uint64)}
+			timer = time.NewTimer(0)
+			tick  = timer.C
+		)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-tick:
+				done, err := api.replayChunk(ctx, kind, crit, state, func(v interface{}) { notifier.Notify(rpcSub.ID, v) })
+				if err != nil {
+					log.Debug("Failed to backfill subscription", "id", rpcSub.ID, "block", state.next, "err", err)
+				}
+				if done {
+					log.Debug("Backfilled subscription", "id", rpcSub.ID, "kind", kind, "from", uint64(from), "to", state.next)
+					state.live, tick = true, nil
+					continue
+				}
+				timer.Reset(api.backfillLimiter.ReserveN(time.Now(), backfillChunk).Delay())
+
+			case h := <-headers:
+				if state.deliver(h.Hash(), h.Number[types.QuaiNetworkContext].Uint64(), false) {
+					notifier.Notify(rpcSub.ID, h)
+				}
+			case matched := <-logs:
+				for _, log := range matched {
+					if state.deliver(log.BlockHash, log.BlockNumber, log.Removed) {
+						notifier.Notify(rpcSub.ID, &log)
+					}
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// replayChunk replays the next chunk of the history of a backfilling
+// subscription, reporting whether the replay reached the head of the chain.
+func (api *PublicFilterAPI) replayChunk(ctx context.Context, kind string, crit *FilterCriteria, state *backfill, notify func(interface{})) (bool, error) {
+	head, err := api.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return false, err
+	}
+	if head == nil {
+		return false, errMissingHeader
+	}
+	number := head.Number[types.QuaiNetworkContext].Uint64()
+	if state.next > number {
+		return true, nil
+	}
+	end := state.next + backfillChunk - 1
+	if end > number {
+		end = number
+	}
+	switch kind {
+	case BackfillNewHeads:
+		for number := state.next; number <= end; number++ {
+			header, err := api.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if err != nil {
+				return false, err
+			}
+			if header == nil {
+				return false, errMissingHeader
+			}
+			notify(header)
+			state.replayed(header.Hash(), number)
+			state.advance(number + 1)
+		}
+	case BackfillLogs:
+		logs, err := NewRangeFilter(api.backend, int64(state.next), int64(end), crit.Addresses, crit.Topics).Logs(ctx)
+		if err != nil {
+			return false, err
+		}
+		for _, log := range logs {
+			notify(log)
+			state.replayed(log.BlockHash, log.BlockNumber)
+		}
+		state.advance(end + 1)
+	}
+	return false, nil
+}
@@ -20,8 +20,10 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"sync"
 
 	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/bloombits"
 	"github.com/spruce-solutions/go-quai/core/types"
@@ -45,12 +47,21 @@ type Backend interface {
 	SubscribePendingBlockEvent(ch chan<- *types.Header) event.Subscription
 	SubscribeReOrgEvent(ch chan<- core.ReOrgRollup) event.Subscription
 	SubscribeMissingExternalBlockEvent(ch chan<- core.MissingExternalBlock) event.Subscription
+	SubscribeForkChoiceEvent(ch chan<- core.ForkChoiceEvent) event.Subscription
 	SubscribeChainUncleEvent(ch chan<- *types.Header) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// logWorkers is the number of blocks whose logs are retrieved concurrently by a
+// range filter.
+const logWorkers = 8
+
+// errMissingHeader is returned internally when a block of the filtered range is
+// not available, ending the retrieval without an error.
+var errMissingHeader = errors.New("missing header")
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -133,19 +144,10 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 		return f.blockLogs(ctx, header)
 	}
 	// Figure out the limits of the filter range
-	header, _ := f.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
-	if header == nil {
+	end, _, ok := f.limits(ctx)
+	if !ok {
 		return nil, nil
 	}
-	head := header.Number[types.QuaiNetworkContext].Uint64()
-
-	if f.begin == -1 {
-		f.begin = int64(head)
-	}
-	end := uint64(f.end)
-	if f.end == -1 {
-		end = head
-	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
@@ -167,6 +169,26 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	return logs, err
 }
 
+// limits resolves the range of the filter against the current head, replacing
+// the latest block placeholders. It returns the last block of the range and the
+// head, false if the chain has no head yet.
+func (f *Filter) limits(ctx context.Context) (uint64, uint64, bool) {
+	header, _ := f.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if header == nil {
+		return 0, 0, false
+	}
+	head := header.Number[types.QuaiNetworkContext].Uint64()
+
+	if f.begin == -1 {
+		f.begin = int64(head)
+	}
+	end := uint64(f.end)
+	if f.end == -1 {
+		end = head
+	}
+	return end, head, true
+}
+
 // indexedLogs returns the logs matching the filter criteria based on the bloom
 // bits indexed available locally or via the network.
 func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
@@ -181,56 +203,126 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 
 	f.backend.ServiceFilter(ctx, session)
 
-	// Iterate over the matches until exhausted or context closed
-	var logs []*types.Log
-
-	for {
-		select {
-		case number, ok := <-matches:
-			// Abort if all matches have been fulfilled
-			if !ok {
-				err := session.Error()
-				if err == nil {
-					f.begin = int64(end) + 1
-				}
-				return logs, err
-			}
-			f.begin = int64(number) + 1
-
-			// Retrieve the suggested block and pull any truly matching logs
-			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
-			if header == nil || err != nil {
-				return logs, err
-			}
-			found, err := f.checkMatches(ctx, header)
-			if err != nil {
-				return logs, err
-			}
-			logs = append(logs, found...)
-
-		case <-ctx.Done():
-			return logs, ctx.Err()
+	// Pull the truly matching logs of the suggested blocks until exhausted
+	logs, err := f.collectLogs(ctx, matches, f.checkMatches)
+	if err == errMissingHeader {
+		return logs, nil
+	}
+	if err == nil {
+		if err = session.Error(); err == nil {
+			f.begin = int64(end) + 1
 		}
 	}
+	return logs, err
 }
 
 // unindexedLogs returns the logs matching the filter criteria based on raw block
 // iteration and bloom matching.
 func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
-	var logs []*types.Log
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	numbers := make(chan uint64)
+	go func(begin int64) {
+		defer close(numbers)
+		for number := begin; number <= int64(end); number++ {
+			select {
+			case numbers <- uint64(number):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}(f.begin)
 
-	for ; f.begin <= int64(end); f.begin++ {
-		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
-		if header == nil || err != nil {
-			return logs, err
+	logs, err := f.collectLogs(ctx, numbers, f.blockLogs)
+	if err == errMissingHeader {
+		return logs, nil
+	}
+	return logs, err
+}
+
+// collectLogs retrieves the logs of the blocks sent on numbers with the check
+// function, using a pool of workers but returning the logs in the order of the
+// blocks. The start of the filter is moved past every block processed, so that
+// a failed retrieval can be resumed.
+func (f *Filter) collectLogs(ctx context.Context, numbers <-chan uint64, check func(context.Context, *types.Header) ([]*types.Log, error)) ([]*types.Log, error) {
+	type task struct {
+		number uint64
+		logs   []*types.Log
+		err    error
+		done   chan struct{}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+
+	var (
+		tasks = make(chan *task)
+		queue = make(chan *task, 2*logWorkers) // tasks in block order, awaiting collection
+		wg    sync.WaitGroup
+	)
+	defer wg.Wait()
+	defer cancel()
+
+	for i := 0; i < logWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(t.number))
+				switch {
+				case err != nil:
+					t.err = err
+				case header == nil:
+					t.err = errMissingHeader
+				default:
+					t.logs, t.err = check(ctx, header)
+				}
+				close(t.done)
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(queue)
+		defer close(tasks)
+
+		for {
+			var t *task
+			select {
+			case number, ok := <-numbers:
+				if !ok {
+					return
+				}
+				t = &task{number: number, done: make(chan struct{})}
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case queue <- t:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case tasks <- t:
+			case <-ctx.Done():
+				return
+			}
 		}
-		found, err := f.blockLogs(ctx, header)
-		if err != nil {
-			return logs, err
+	}()
+	var logs []*types.Log
+	for t := range queue {
+		select {
+		case <-t.done:
+		case <-ctx.Done():
+			return logs, ctx.Err()
 		}
-		logs = append(logs, found...)
+		if t.err != nil {
+			return logs, t.err
+		}
+		logs = append(logs, t.logs...)
+		f.begin = int64(t.number) + 1
 	}
-	return logs, nil
+	return logs, ctx.Err()
 }
 
 // blockLogs returns the logs matching the filter criteria within a single block.
@@ -350,3 +442,122 @@ func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]commo
 	}
 	return true
 }
+
+// LogsCost is the estimated work of a log query, letting clients split large
+// ranges into queries of bounded cost before running them.
+type LogsCost struct {
+	FromBlock  hexutil.Uint64    `json:"fromBlock"`
+	ToBlock    hexutil.Uint64    `json:"toBlock"`
+	Blocks     hexutil.Uint64    `json:"blocks"`     // Number of blocks in the range
+	Indexed    hexutil.Uint64    `json:"indexed"`    // Number of blocks covered by the bloombits index
+	Candidates hexutil.Uint64    `json:"candidates"` // Number of blocks whose bloom matches, needing their receipts loaded
+	Sections   []LogsSectionCost `json:"sections"`   // Candidates per bloom section of the range
+}
+
+// LogsSectionCost is the number of candidate blocks within a bloom section of a
+// log query range.
+type LogsSectionCost struct {
+	FromBlock  hexutil.Uint64 `json:"fromBlock"`
+	ToBlock    hexutil.Uint64 `json:"toBlock"`
+	Candidates hexutil.Uint64 `json:"candidates"`
+}
+
+// Cost estimates the work of running the filter without loading any receipts,
+// by counting the blocks whose blooms match the filter criteria.
+func (f *Filter) Cost(ctx context.Context) (*LogsCost, error) {
+	// If we're doing singleton block filtering, check the bloom of the block
+	if f.block != (common.Hash{}) {
+		header, err := f.backend.HeaderByHash(ctx, f.block)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, errors.New("unknown block")
+		}
+		number := hexutil.Uint64(header.Number[types.QuaiNetworkContext].Uint64())
+		cost := &LogsCost{FromBlock: number, ToBlock: number, Blocks: 1}
+		if bloomFilter(header.Bloom[types.QuaiNetworkContext], f.addresses, f.topics) {
+			cost.Candidates = 1
+		}
+		cost.Sections = []LogsSectionCost{{FromBlock: number, ToBlock: number, Candidates: cost.Candidates}}
+		return cost, nil
+	}
+	end, head, ok := f.limits(ctx)
+	if end > head {
+		end = head
+	}
+	if !ok || uint64(f.begin) > end {
+		return nil, errors.New("empty block range")
+	}
+	begin := uint64(f.begin)
+	cost := &LogsCost{FromBlock: hexutil.Uint64(begin), ToBlock: hexutil.Uint64(end), Blocks: hexutil.Uint64(end - begin + 1)}
+
+	size, sections := f.backend.BloomStatus()
+	if size == 0 {
+		size = end + 1 // no index, count the whole range as one section
+	}
+	candidates := make(map[uint64]uint64) // section -> candidate blocks
+
+	// Count the matches of the indexed part with the bloombits
+	next := begin
+	if indexed := sections * size; indexed > begin {
+		last := end
+		if indexed <= end {
+			last = indexed - 1
+		}
+		matches := make(chan uint64, 64)
+		session, err := f.matcher.Start(ctx, begin, last, matches)
+		if err != nil {
+			return nil, err
+		}
+		defer session.Close()
+
+		f.backend.ServiceFilter(ctx, session)
+	count:
+		for {
+			select {
+			case number, ok := <-matches:
+				if !ok {
+					break count
+				}
+				candidates[number/size]++
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if err := session.Error(); err != nil {
+			return nil, err
+		}
+		cost.Indexed = hexutil.Uint64(last - begin + 1)
+		next = last + 1
+	}
+	// Check the header blooms of the unindexed part
+	for number := next; number <= end; number++ {
+		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			break
+		}
+		if bloomFilter(header.Bloom[types.QuaiNetworkContext], f.addresses, f.topics) {
+			candidates[number/size]++
+		}
+	}
+	for section := begin / size; section <= end/size; section++ {
+		from, to := section*size, (section+1)*size-1
+		if from < begin {
+			from = begin
+		}
+		if to > end {
+			to = end
+		}
+		cost.Candidates += hexutil.Uint64(candidates[section])
+		cost.Sections = append(cost.Sections, LogsSectionCost{
+			FromBlock:  hexutil.Uint64(from),
+			ToBlock:    hexutil.Uint64(to),
+			Candidates: hexutil.Uint64(candidates[section]),
+		})
+	}
+	return cost, nil
+}
@@ -62,6 +62,10 @@ const (
 	MissingExternalBlockSubscription
 	// uncleChSubscription writes the header of a block that is notified as an uncle.
 	uncleChSubscription
+	// AddressActivitySubscription queries the activity of watched addresses in imported blocks
+	AddressActivitySubscription
+	// ForkChoiceSubscription queries the heads selected by the fork choice
+	ForkChoiceSubscription
 )
 
 const (
@@ -92,6 +96,9 @@ type subscription struct {
 	reOrg           chan core.ReOrgRollup
 	uncleEvent      chan *types.Header
 	missingExtBlock chan core.MissingExternalBlock
+	addresses       []common.Address
+	activity        chan []*AddressActivity
+	forkChoice      chan core.ForkChoiceEvent
 }
 
 // EventSystem creates subscriptions, processes events and broadcasts them to the
@@ -100,6 +107,7 @@ type EventSystem struct {
 	backend   Backend
 	lightMode bool
 	lastHead  *types.Header
+	watched   map[common.Address]map[rpc.ID]*subscription // Address activity subscriptions by watched address
 
 	// Subscriptions
 	txsSub                  event.Subscription // Subscription for new transaction event
@@ -111,6 +119,7 @@ type EventSystem struct {
 	reOrgSub                event.Subscription // Subscription for reorg event
 	uncleChSub              event.Subscription // Subscription for side chain event
 	missingExternalBlockSub event.Subscription // Subscription for missingBlock event
+	forkChoiceSub           event.Subscription // Subscription for fork choice event
 
 	// Channels
 	install                chan *subscription             // install filter for event notification
@@ -124,6 +133,7 @@ type EventSystem struct {
 	reOrgCh                chan core.ReOrgRollup          // Channel to receive reorg event data
 	uncleCh                chan *types.Header             // Channel to receive side chain event data
 	missingExternalBlockCh chan core.MissingExternalBlock // Channel to receive the missing external block event
+	forkChoiceCh           chan core.ForkChoiceEvent      // Channel to receive the fork choice event
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -147,6 +157,8 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 		reOrgCh:                make(chan core.ReOrgRollup),
 		uncleCh:                make(chan *types.Header),
 		missingExternalBlockCh: make(chan core.MissingExternalBlock),
+		forkChoiceCh:           make(chan core.ForkChoiceEvent),
+		watched:                make(map[common.Address]map[rpc.ID]*subscription),
 	}
 
 	// Subscribe events
@@ -159,9 +171,10 @@ func NewEventSystem(backend Backend, lightMode bool) *EventSystem {
 	m.reOrgSub = m.backend.SubscribeReOrgEvent(m.reOrgCh)
 	m.missingExternalBlockSub = m.backend.SubscribeMissingExternalBlockEvent(m.missingExternalBlockCh)
 	m.uncleChSub = m.backend.SubscribeChainUncleEvent(m.uncleCh)
+	m.forkChoiceSub = m.backend.SubscribeForkChoiceEvent(m.forkChoiceCh)
 
 	// Make sure none of the subscriptions are empty
-	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil || m.reOrgSub == nil || m.uncleChSub == nil {
+	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil || m.reOrgSub == nil || m.uncleChSub == nil || m.forkChoiceSub == nil {
 		log.Crit("Subscribe for event system failed")
 	}
 
@@ -197,6 +210,7 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.hashes:
 			case <-sub.f.headers:
+			case <-sub.f.activity:
 			}
 		}
 
@@ -426,6 +440,26 @@ func (es *EventSystem) SubscribeMissingExternalBlock(extBlockMiss chan core.Miss
 	return es.subscribe(sub)
 }
 
+// SubscribeForkChoice creates a subscription that writes the heads selected by
+// the fork choice, with the reason they were selected for.
+func (es *EventSystem) SubscribeForkChoice(forkChoice chan core.ForkChoiceEvent) *Subscription {
+	sub := &subscription{
+		id:              rpc.NewID(),
+		typ:             ForkChoiceSubscription,
+		created:         time.Now(),
+		logs:            make(chan []*types.Log),
+		hashes:          make(chan []common.Hash),
+		headers:         make(chan *types.Header),
+		installed:       make(chan struct{}),
+		err:             make(chan error),
+		reOrg:           make(chan core.ReOrgRollup),
+		uncleEvent:      make(chan *types.Header),
+		missingExtBlock: make(chan core.MissingExternalBlock),
+		forkChoice:      forkChoice,
+	}
+	return es.subscribe(sub)
+}
+
 type filterIndex map[Type]map[rpc.ID]*subscription
 
 func (es *EventSystem) handleLogs(filters filterIndex, ev []*types.Log) {
@@ -469,6 +503,12 @@ func (es *EventSystem) handleMissingExternalBlock(filters filterIndex, ev core.M
 		f.missingExtBlock <- ev
 	}
 }
+func (es *EventSystem) handleForkChoice(filters filterIndex, ev core.ForkChoiceEvent) {
+	for _, f := range filters[ForkChoiceSubscription] {
+		f.forkChoice <- ev
+	}
+}
+
 func (es *EventSystem) handleUncleCh(filters filterIndex, ev *types.Header) {
 	for _, f := range filters[uncleChSubscription] {
 		f.uncleEvent <- ev
@@ -498,6 +538,7 @@ func (es *EventSystem) handleChainEvent(filters filterIndex, ev core.ChainEvent)
 	for _, f := range filters[BlocksSubscription] {
 		f.headers <- ev.Block.Header()
 	}
+	es.handleAddressActivity(ev)
 	if es.lightMode && len(filters[LogsSubscription]) > 0 {
 		es.lightFilterNewHead(ev.Block.Header(), func(header *types.Header, remove bool) {
 			for _, f := range filters[LogsSubscription] {
@@ -595,10 +636,11 @@ func (es *EventSystem) eventLoop() {
 		es.reOrgSub.Unsubscribe()
 		es.missingExternalBlockSub.Unsubscribe()
 		es.uncleChSub.Unsubscribe()
+		es.forkChoiceSub.Unsubscribe()
 	}()
 
 	index := make(filterIndex)
-	for i := UnknownSubscription; i <= uncleChSubscription; i++ {
+	for i := UnknownSubscription; i <= ForkChoiceSubscription; i++ {
 		index[i] = make(map[rpc.ID]*subscription)
 	}
 
@@ -622,6 +664,8 @@ func (es *EventSystem) eventLoop() {
 			es.handleMissingExternalBlock(index, ev)
 		case ev := <-es.uncleCh:
 			es.handleUncleCh(index, ev)
+		case ev := <-es.forkChoiceCh:
+			es.handleForkChoice(index, ev)
 
 		case f := <-es.install:
 			if f.typ == MinedAndPendingLogsSubscription {
@@ -631,6 +675,9 @@ func (es *EventSystem) eventLoop() {
 			} else {
 				index[f.typ][f.id] = f
 			}
+			if f.typ == AddressActivitySubscription {
+				es.watch(f)
+			}
 			close(f.installed)
 
 		case f := <-es.uninstall:
@@ -641,6 +688,9 @@ func (es *EventSystem) eventLoop() {
 			} else {
 				delete(index[f.typ], f.id)
 			}
+			if f.typ == AddressActivitySubscription {
+				es.unwatch(f)
+			}
 			close(f.err)
 
 		// System stopped
@@ -658,6 +708,8 @@ func (es *EventSystem) eventLoop() {
 			return
 		case <-es.missingExternalBlockSub.Err():
 			return
+		case <-es.forkChoiceSub.Err():
+			return
 		}
 	}
 }
@@ -0,0 +1,109 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethclient/quaiclient"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// forwardDialTimeout is the time waited for a node of another location to be
+// reachable when forwarding the first transaction to it.
+const forwardDialTimeout = 10 * time.Second
+
+var (
+	forwardedTxMeter   = metrics.NewRegisteredMeter("eth/forward/txs", nil)
+	forwardFailMeter   = metrics.NewRegisteredMeter("eth/forward/failures", nil)
+	errForwarderClosed = errors.New("transaction forwarder closed")
+)
+
+// txForwarder relays the transactions submitted to the node for other locations
+// to the configured nodes of those locations, so that wallets can submit all
+// their transactions through a single endpoint. Transactions received from
+// peers are not forwarded, they are only accepted for the local location.
+type txForwarder struct {
+	chainID *big.Int
+	urls    map[uint64][]string // Urls of the nodes of the other locations by chain ID
+	config  quaiclient.Config
+
+	lock    sync.Mutex
+	clients map[uint64]*quaiclient.Failover
+	closed  bool
+}
+
+// newTxForwarder creates a forwarder to the nodes of the other locations.
+func newTxForwarder(chainID *big.Int, urls map[uint64][]string, config quaiclient.Config) *txForwarder {
+	return &txForwarder{
+		chainID: chainID,
+		urls:    urls,
+		config:  config,
+		clients: make(map[uint64]*quaiclient.Failover),
+	}
+}
+
+// forward sends the transaction to a node of its location if it's signed for
+// another location with configured nodes, returning whether it was forwarded.
+// Transactions for other locations without nodes are left to the pool, which
+// rejects them.
+func (f *txForwarder) forward(ctx context.Context, tx *types.Transaction) (bool, error) {
+	if !tx.Protected() || tx.ChainId().Cmp(f.chainID) == 0 || !tx.ChainId().IsUint64() {
+		return false, nil
+	}
+	target := tx.ChainId().Uint64()
+	if len(f.urls[target]) == 0 {
+		return false, nil
+	}
+	client, err := f.client(target)
+	if err == nil {
+		err = client.Client().SendTransaction(ctx, tx)
+	}
+	if err != nil {
+		forwardFailMeter.Mark(1)
+		log.Warn("Failed to forward transaction", "hash", tx.Hash(), "location", params.LocationName(tx.ChainId()), "err", err)
+		return true, err
+	}
+	forwardedTxMeter.Mark(1)
+	log.Debug("Forwarded transaction", "hash", tx.Hash(), "location", params.LocationName(tx.ChainId()))
+	return true, nil
+}
+
+// client returns the client of the nodes of the chain, connecting to them on
+// first use.
+func (f *txForwarder) client(chainID uint64) (*quaiclient.Failover, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return nil, errForwarderClosed
+	}
+	if client, ok := f.clients[chainID]; ok {
+		return client, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), forwardDialTimeout)
+	defer cancel()
+
+	client, err := quaiclient.DialFailover(ctx, f.urls[chainID], f.config)
+	if err != nil {
+		return nil, err
+	}
+	f.clients[chainID] = client
+	return client, nil
+}
+
+// close disconnects from the nodes of the other locations.
+func (f *txForwarder) close() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, client := range f.clients {
+		client.Close()
+	}
+	f.clients, f.closed = nil, true
+}
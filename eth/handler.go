@@ -17,7 +17,9 @@
 package eth
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sync"
@@ -35,6 +37,7 @@ import (
 	"github.com/spruce-solutions/go-quai/ethdb"
 	"github.com/spruce-solutions/go-quai/event"
 	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/miner"
 	"github.com/spruce-solutions/go-quai/p2p"
 	"github.com/spruce-solutions/go-quai/params"
 	"github.com/spruce-solutions/go-quai/trie"
@@ -84,6 +87,7 @@ type handlerConfig struct {
 	BloomCache uint64                    // Megabytes to alloc for fast sync bloom
 	EventMux   *event.TypeMux            // Legacy event mux, deprecate for `feed`
 	Checkpoint *params.TrustedCheckpoint // Hard coded checkpoint for sync challenges
+	Anchor     common.Hash               // Trusted Prime block to checkpoint sync from
 	Whitelist  map[uint64]common.Hash    // Hard coded whitelist for sync challenged
 }
 
@@ -91,9 +95,11 @@ type handler struct {
 	networkID  uint64
 	forkFilter forkid.Filter // Fork ID filter, constant across the lifetime of the node
 
-	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
-	snapSync  uint32 // Flag whether fast sync should operate on top of the snap protocol
-	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
+	fastSync       uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	snapSync       uint32 // Flag whether fast sync should operate on top of the snap protocol
+	checkpointSync uint32 // Flag whether fast sync should start from the trusted anchor block
+	acceptTxs      uint32 // Flag whether we're considered synchronised (enables transaction processing)
+	haltTxs        uint32 // Flag whether transaction processing is stopped by the operator
 
 	checkpointNumber uint64      // Block number for the sync progress validator to cross reference
 	checkpointHash   common.Hash // Block hash for the sync progress validator to cross reference
@@ -101,7 +107,7 @@ type handler struct {
 	database ethdb.Database
 	txpool   txPool
 	chain    *core.BlockChain
-	maxPeers int
+	maxPeers int32 // Maximum number of `eth` peers, accessed atomically
 
 	downloader   *downloader.Downloader
 	stateBloom   *trie.SyncBloom
@@ -116,6 +122,8 @@ type handler struct {
 
 	whitelist map[uint64]common.Hash
 
+	sealLatency *miner.SealLatency // Latency tracker of the locally sealed blocks, set by the backend once the miner exists
+
 	// channels for fetcher, syncer, txsyncLoop
 	quitSync chan struct{}
 
@@ -165,6 +173,9 @@ func newHandler(config *handlerConfig) (*handler, error) {
 			if config.Sync == downloader.SnapSync {
 				h.snapSync = uint32(1)
 			}
+			if config.Sync == downloader.CheckpointSync {
+				h.checkpointSync = uint32(1)
+			}
 		}
 	}
 	// If we have trusted checkpoints, enforce them on the chain
@@ -183,6 +194,21 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		h.stateBloom = trie.NewSyncBloom(config.BloomCache, config.Database)
 	}
 	h.downloader = downloader.New(h.checkpointNumber, config.Database, h.stateBloom, h.eventMux, h.chain, nil, h.removePeer)
+	if config.Anchor != (common.Hash{}) {
+		h.downloader.SetCheckpointAnchor(config.Anchor, func(header *types.Header) error {
+			if !bytes.Equal(header.Location, h.chain.Config().Location) {
+				return fmt.Errorf("anchor location %v, want %v", header.Location, h.chain.Config().Location)
+			}
+			order, err := h.chain.Engine().GetDifficultyOrder(header)
+			if err != nil {
+				return err
+			}
+			if order != params.PRIME {
+				return fmt.Errorf("anchor difficulty order %d, want prime", order)
+			}
+			return nil
+		})
+	}
 
 	// Construct the fetcher (short sync)
 	validator := func(header *types.Header) error {
@@ -277,7 +303,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 	}
 	// Ignore maxPeers if this is a trusted peer
 	if !peer.Peer.Info().Network.Trusted {
-		if reject || h.peers.len() >= h.maxPeers {
+		if reject || h.peers.len() >= int(atomic.LoadInt32(&h.maxPeers)) {
 			return p2p.DiscTooManyPeers
 		}
 	}
@@ -395,7 +421,7 @@ func (h *handler) unregisterPeer(id string) {
 }
 
 func (h *handler) Start(maxPeers int) {
-	h.maxPeers = maxPeers
+	atomic.StoreInt32(&h.maxPeers, int32(maxPeers))
 
 	// broadcast transactions
 	h.wg.Add(1)
@@ -533,6 +559,21 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		"tx packs", directPeers, "broadcast txs", directCount)
 }
 
+// RebroadcastTransactions announces the given transactions to every peer, even
+// if they are believed to know them already, so that transactions dropped by
+// remote pools get another chance to propagate.
+func (h *handler) RebroadcastTransactions(txs types.Transactions) {
+	hashes := make([]common.Hash, 0, len(txs))
+	for _, tx := range txs {
+		hashes = append(hashes, tx.Hash())
+	}
+	peers := h.peers.allPeers()
+	for _, peer := range peers {
+		peer.AsyncSendPooledTransactionHashes(hashes)
+	}
+	log.Debug("Transaction rebroadcast", "txs", len(txs), "peers", len(peers))
+}
+
 // minedBroadcastLoop sends mined blocks to connected peers.
 func (h *handler) minedBroadcastLoop() {
 	defer h.wg.Done()
@@ -549,6 +590,9 @@ func (h *handler) minedBroadcastLoop() {
 					log.Info("minedBroadcastLoop", "hash", header.Hash(), "extBlocks", len(extBlocks))
 					h.BroadcastBlock(ev.Block, extBlocks, true)  // First propagate block to peers
 					h.BroadcastBlock(ev.Block, extBlocks, false) // Only then announce to the rest
+					if h.sealLatency != nil {
+						h.sealLatency.MarkPropagated(ev.Block.Hash())
+					}
 				}
 			}
 		}
@@ -57,7 +57,7 @@ func (h *ethHandler) PeerInfo(id enode.ID) interface{} {
 // AcceptTxs retrieves whether transaction processing is enabled on the node
 // or if inbound transactions should simply be dropped.
 func (h *ethHandler) AcceptTxs() bool {
-	return atomic.LoadUint32(&h.acceptTxs) == 1
+	return atomic.LoadUint32(&h.acceptTxs) == 1 && atomic.LoadUint32(&h.haltTxs) == 0
 }
 
 // Handle is invoked from a peer's message handler when it receives a new remote
@@ -205,6 +205,9 @@ func (h *ethHandler) handleBlockAnnounces(peer *eth.Peer, hashes []common.Hash,
 		unknownNumbers = make([]uint64, 0, len(numbers))
 	)
 	for i := 0; i < len(hashes); i++ {
+		if h.sealLatency != nil {
+			h.sealLatency.MarkAccepted(hashes[i], peer.ID())
+		}
 		if !h.chain.HasBlock(hashes[i], numbers[i]) {
 			unknownHashes = append(unknownHashes, hashes[i])
 			unknownNumbers = append(unknownNumbers, numbers[i])
@@ -221,6 +224,9 @@ func (h *ethHandler) handleBlockAnnounces(peer *eth.Peer, hashes []common.Hash,
 func (h *ethHandler) handleBlockBroadcast(peer *eth.Peer, block *types.Block, td []*big.Int, extBlocks []*types.ExternalBlock) error {
 	log.Info("handleBlockBroadcast: Received block broadcast", "hash", block.Hash(), "num", block.Header().Number, "extBlocks", len(extBlocks))
 
+	if h.sealLatency != nil {
+		h.sealLatency.MarkAccepted(block.Hash(), peer.ID())
+	}
 	for _, extBlock := range extBlocks {
 		h.chain.AddExternalBlock(extBlock)
 	}
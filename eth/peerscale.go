@@ -0,0 +1,248 @@
+package eth
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/p2p"
+	"github.com/spruce-solutions/go-quai/p2p/enr"
+)
+
+const (
+	// peerScaleInterval is the interval the sync state is checked at.
+	peerScaleInterval = 10 * time.Second
+
+	// peerScaleUpGap is the number of blocks the local chain must fall behind
+	// the best known head to raise the peer limit.
+	peerScaleUpGap = 64
+
+	// peerScaleDownGap is the number of blocks the local chain may be behind the
+	// best known head while considered synced.
+	peerScaleDownGap = 4
+
+	// peerScaleDownHold is the time the node must stay synced before the peer
+	// limit is lowered, so short lags don't make the limit flap.
+	peerScaleDownHold = 5 * time.Minute
+)
+
+// PeerTarget is the state of the peer limit scaling.
+type PeerTarget struct {
+	MaxPeers int  `json:"maxPeers"` // Current limit of connected peers
+	Steady   int  `json:"steady"`   // Limit once synced
+	Sync     int  `json:"sync"`     // Limit while syncing
+	Syncing  bool `json:"syncing"`  // Whether the node is considered syncing
+	Override bool `json:"override"` // Whether the limit was set by the admin
+}
+
+// peerScaler raises the peer limit while the node is syncing and lowers it once
+// the node is synced, dropping the peers least worth keeping. The limits only
+// switch after the sync state crossed separate thresholds, lowering it only
+// after staying synced for a while.
+type peerScaler struct {
+	eth        *Ethereum
+	steady     int // Limit of connected peers once synced
+	sync       int // Limit of connected peers while syncing
+	lightPeers int // Peer slots reserved for light clients
+
+	current     int       // Limit currently applied
+	syncing     bool      // Whether the node is considered syncing
+	syncedSince time.Time // Time the node caught up, zero if it's behind
+	override    int       // Limit set by the admin, zero if scaling
+	lock        sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newPeerScaler creates a scaler switching the limit of connected peers between
+// sync and steady, scaling being disabled if sync doesn't exceed steady or the
+// networking is disabled.
+func newPeerScaler(eth *Ethereum, steady, sync, lightPeers int) *peerScaler {
+	if steady == 0 || sync < steady {
+		sync = steady
+	}
+	return &peerScaler{
+		eth:        eth,
+		steady:     steady,
+		sync:       sync,
+		lightPeers: lightPeers,
+		current:    steady,
+		quit:       make(chan struct{}),
+	}
+}
+
+// start raises the peer limit unless the node is already synced and starts
+// tracking the sync state.
+func (s *peerScaler) start() {
+	if s.sync == s.steady {
+		return
+	}
+	s.lock.Lock()
+	s.syncing = atomic.LoadUint32(&s.eth.handler.acceptTxs) == 0
+	s.apply()
+	s.lock.Unlock()
+
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// stop terminates the sync state tracking.
+func (s *peerScaler) stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+func (s *peerScaler) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(peerScaleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.update()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// update checks the sync state, switching the peer limit if it changed.
+func (s *peerScaler) update() {
+	var (
+		progress = s.eth.Downloader().Progress()
+		gap      uint64
+	)
+	if progress.HighestBlock > progress.CurrentBlock {
+		gap = progress.HighestBlock - progress.CurrentBlock
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch {
+	case !s.syncing && gap > peerScaleUpGap:
+		s.syncing, s.syncedSince = true, time.Time{}
+		s.apply()
+
+	case s.syncing:
+		synced := atomic.LoadUint32(&s.eth.handler.acceptTxs) == 1 && gap <= peerScaleDownGap && !s.eth.Downloader().Synchronising()
+		if !synced {
+			s.syncedSince = time.Time{}
+			return
+		}
+		if s.syncedSince.IsZero() {
+			s.syncedSince = time.Now()
+		} else if time.Since(s.syncedSince) > peerScaleDownHold {
+			s.syncing = false
+			s.apply()
+		}
+	}
+}
+
+// setOverride fixes the peer limit, or resumes scaling if max is zero.
+func (s *peerScaler) setOverride(max int) *PeerTarget {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.override = max
+	s.apply()
+	return s.status()
+}
+
+// target returns the current peer limit status.
+func (s *peerScaler) target() *PeerTarget {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.status()
+}
+
+// status assembles the peer limit status, the lock must be held.
+func (s *peerScaler) status() *PeerTarget {
+	return &PeerTarget{
+		MaxPeers: s.current,
+		Steady:   s.steady,
+		Sync:     s.sync,
+		Syncing:  s.syncing,
+		Override: s.override > 0,
+	}
+}
+
+// apply switches the peer limit to the one required by the current state,
+// dropping the excess peers if it's lowered. The lock must be held.
+func (s *peerScaler) apply() {
+	limit := s.steady
+	switch {
+	case s.override > 0:
+		limit = s.override
+	case s.syncing:
+		limit = s.sync
+	}
+	if limit == s.current {
+		return
+	}
+	log.Info("Changing peer limit", "old", s.current, "new", limit, "syncing", s.syncing, "override", s.override > 0)
+
+	lowered := limit < s.current
+	s.current = limit
+	s.eth.p2pServer.SetMaxPeers(limit)
+
+	ethPeers := limit - s.lightPeers
+	if ethPeers < 1 {
+		ethPeers = 1
+	}
+	atomic.StoreInt32(&s.eth.handler.maxPeers, int32(ethPeers))
+	if lowered {
+		s.prune(ethPeers)
+	}
+}
+
+// prune disconnects the `eth` peers above the limit, keeping trusted and static
+// peers first, then the peers operating in the local slice, then the ones
+// connected for the longest time.
+func (s *peerScaler) prune(limit int) {
+	peers := s.eth.handler.peers.allPeers()
+	if len(peers) <= limit {
+		return
+	}
+	location := s.eth.blockchain.Config().Location
+
+	type candidate struct {
+		peer      *ethPeer
+		reserved  bool
+		sameSlice bool
+	}
+	candidates := make([]candidate, len(peers))
+	for i, peer := range peers {
+		info := peer.Info()
+
+		var loc enr.Location
+		candidates[i] = candidate{
+			peer:      peer,
+			reserved:  info.Network.Trusted || info.Network.Static,
+			sameSlice: peer.Node().Load(&loc) == nil && bytes.Equal(loc, location),
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.reserved != b.reserved {
+			return a.reserved
+		}
+		if a.sameSlice != b.sameSlice {
+			return a.sameSlice
+		}
+		return a.peer.Connected() < b.peer.Connected()
+	})
+	for _, c := range candidates[limit:] {
+		if c.reserved {
+			continue
+		}
+		c.peer.Log().Debug("Dropping peer above lowered limit", "sameslice", c.sameSlice)
+		c.peer.Disconnect(p2p.DiscTooManyPeers)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/spruce-solutions/go-quai/core/forkid"
 	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/p2p/enode"
+	"github.com/spruce-solutions/go-quai/p2p/enr"
 	"github.com/spruce-solutions/go-quai/rlp"
 )
 
@@ -39,7 +40,11 @@ func (e enrEntry) ENRKey() string {
 
 // StartENRUpdater starts the `eth` ENR updater loop, which listens for chain
 // head events and updates the requested node record whenever a fork is passed.
+// The slice location of the chain is advertised alongside, so that bootnodes
+// and peers can filter out nodes operating in unrelated slices.
 func StartENRUpdater(chain *core.BlockChain, ln *enode.LocalNode) {
+	ln.Set(enr.Location(chain.Config().Location))
+
 	var newHead = make(chan core.ChainHeadEvent, 10)
 	sub := chain.SubscribeChainHeadEvent(newHead)
 
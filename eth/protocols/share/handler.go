@@ -0,0 +1,190 @@
+package share
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/miner/sharechain"
+	"github.com/spruce-solutions/go-quai/p2p"
+)
+
+// Handler gossips the shares of a share chain with the peers of the pool, and
+// fetches the missing ancestors of the shares it's told about.
+type Handler struct {
+	chain *sharechain.ShareChain
+
+	peers map[string]*Peer
+	lock  sync.RWMutex
+
+	shareCh  chan sharechain.NewShareEvent
+	shareSub event.Subscription
+	wg       sync.WaitGroup
+}
+
+// NewHandler creates the gossip handler of the share chain.
+func NewHandler(chain *sharechain.ShareChain) *Handler {
+	return &Handler{
+		chain: chain,
+		peers: make(map[string]*Peer),
+	}
+}
+
+// Start starts broadcasting the shares added to the share chain.
+func (h *Handler) Start() {
+	h.shareCh = make(chan sharechain.NewShareEvent, maxQueuedShares)
+	h.shareSub = h.chain.SubscribeNewShareEvent(h.shareCh)
+
+	h.wg.Add(1)
+	go h.broadcastLoop()
+}
+
+// Stop stops broadcasting shares.
+func (h *Handler) Stop() {
+	h.shareSub.Unsubscribe()
+	h.wg.Wait()
+}
+
+// MakeProtocols constructs the P2P protocol definitions for `qshr`.
+func (h *Handler) MakeProtocols() []p2p.Protocol {
+	protocols := make([]p2p.Protocol, len(ProtocolVersions))
+	for i, version := range ProtocolVersions {
+		version := version // Closure
+
+		protocols[i] = p2p.Protocol{
+			Name:    ProtocolName,
+			Version: version,
+			Length:  protocolLengths[version],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				return h.runPeer(newPeer(version, p, rw))
+			},
+		}
+	}
+	return protocols
+}
+
+// runPeer registers the peer and handles its messages until it disconnects.
+// The head share is sent right away for the peer to fetch the share chain.
+func (h *Handler) runPeer(peer *Peer) error {
+	h.lock.Lock()
+	h.peers[peer.id] = peer
+	h.lock.Unlock()
+
+	defer func() {
+		h.lock.Lock()
+		delete(h.peers, peer.id)
+		h.lock.Unlock()
+		peer.close()
+	}()
+	go peer.broadcast()
+
+	if head := h.chain.Head(); head != nil {
+		peer.AsyncSendShare(head.Header())
+	}
+	for {
+		if err := h.handleMessage(peer); err != nil {
+			peer.Log().Debug("Message handling failed in `qshr`", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMessage is invoked whenever an inbound message is received from a
+// remote peer on the `qshr` protocol. The remote connection is torn down upon
+// returning any error.
+func (h *Handler) handleMessage(peer *Peer) error {
+	msg, err := peer.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("%w: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case NewShareMsg:
+		var packet NewSharePacket
+		if err := msg.Decode(&packet); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		share := sharechain.NewShare(packet.Header)
+		peer.markShare(share.Hash())
+
+		err := h.chain.Add(share)
+		switch {
+		case errors.Is(err, sharechain.ErrUnknownParent):
+			return peer.RequestShares(share.Hash(), uint64(h.chain.Config().Window))
+		case errors.Is(err, sharechain.ErrInvalidSeal):
+			return err
+		case err != nil && !errors.Is(err, sharechain.ErrKnownShare):
+			peer.Log().Debug("Share rejected", "hash", share.Hash(), "err", err)
+		}
+		return nil
+
+	case GetSharesMsg:
+		var query GetSharesPacket
+		if err := msg.Decode(&query); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		if query.Amount > maxShares {
+			query.Amount = maxShares
+		}
+		shares := h.chain.Ancestors(query.Hash, int(query.Amount))
+		headers := make(SharesPacket, len(shares))
+		for i, share := range shares {
+			headers[i] = share.Header()
+		}
+		return p2p.Send(peer.rw, SharesMsg, headers)
+
+	case SharesMsg:
+		var headers SharesPacket
+		if err := msg.Decode(&headers); err != nil {
+			return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+		}
+		shares := make([]*sharechain.Share, len(headers))
+		for i, header := range headers {
+			shares[i] = sharechain.NewShare(header)
+			peer.markShare(shares[i].Hash())
+		}
+		if n, err := h.chain.Import(shares); err != nil {
+			if errors.Is(err, sharechain.ErrInvalidSeal) {
+				return err
+			}
+			peer.Log().Debug("Share segment rejected", "index", n, "err", err)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("%w: %v", errInvalidMsgCode, msg.Code)
+	}
+}
+
+// broadcastLoop propagates the shares added to the share chain to the peers
+// not knowing them yet.
+func (h *Handler) broadcastLoop() {
+	defer h.wg.Done()
+
+	for {
+		select {
+		case ev := <-h.shareCh:
+			h.broadcast(ev.Share.Header())
+		case <-h.shareSub.Err():
+			return
+		}
+	}
+}
+
+func (h *Handler) broadcast(header *types.Header) {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	hash := header.Hash()
+	for _, peer := range h.peers {
+		if !peer.KnownShare(hash) {
+			peer.AsyncSendShare(header)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package share
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/miner/sharechain"
+	"github.com/spruce-solutions/go-quai/p2p"
+	"github.com/spruce-solutions/go-quai/p2p/enode"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+var testLocation = []byte{1, 1}
+
+// testChain is a zone chain of a single block.
+type testChain struct {
+	consensus.ChainHeaderReader // Unused methods panic
+
+	config *params.ChainConfig
+	head   *types.Header
+}
+
+func (c *testChain) Config() *params.ChainConfig { return c.config }
+
+func (c *testChain) CurrentHeader() *types.Header { return c.head }
+
+func (c *testChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	if hash == c.head.Hash() {
+		return c.head
+	}
+	return nil
+}
+
+// testEngine accepts every header, sealing the ones it's told to with the
+// highest hash and the others with the lowest.
+type testEngine struct {
+	consensus.Engine // Unused methods panic
+
+	unsealed map[common.Hash]bool
+}
+
+func (e *testEngine) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	return nil
+}
+
+func (e *testEngine) SealHash(header *types.Header) common.Hash {
+	if e.unsealed[header.Hash()] {
+		return common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff")
+	}
+	return common.Hash{}
+}
+
+// handlerTester is a share handler on a fresh share chain, along with a peer
+// whose messages it handles.
+type handlerTester struct {
+	handler *Handler
+	shares  *sharechain.ShareChain
+	engine  *testEngine
+	block   *types.Header
+
+	peer *Peer
+	app  *p2p.MsgPipeRW // Remote side of the peer
+}
+
+func newHandlerTester(t *testing.T, window int) *handlerTester {
+	context := types.QuaiNetworkContext
+	types.QuaiNetworkContext = params.ZONE
+	t.Cleanup(func() { types.QuaiNetworkContext = context })
+
+	config := *params.TestChainConfig
+	config.Location = testLocation
+
+	block := types.NewEmptyHeader()
+	block.Number[params.ZONE] = big.NewInt(0)
+	block.Difficulty[params.ZONE] = big.NewInt(1 << 20)
+	block.Location = testLocation
+
+	engine := &testEngine{unsealed: make(map[common.Hash]bool)}
+	shares, err := sharechain.New(sharechain.Config{Ratio: 1, Window: window}, &testChain{config: &config, head: block}, engine)
+	if err != nil {
+		t.Fatalf("failed to create share chain: %v", err)
+	}
+	app, net := p2p.MsgPipe()
+	t.Cleanup(func() {
+		app.Close()
+		net.Close()
+	})
+	return &handlerTester{
+		handler: NewHandler(shares),
+		shares:  shares,
+		engine:  engine,
+		block:   block,
+		peer:    newPeer(share1, p2p.NewPeer(enode.ID{}, "peer", nil), net),
+		app:     app,
+	}
+}
+
+// share creates the header of a share built on the block of the tester,
+// linking to the parent share. All fields are set for the header to hash the
+// same once relayed.
+func (ht *handlerTester) share(parent common.Hash, nonce uint64) *types.Header {
+	header := types.NewEmptyHeader()
+	for ctx := range header.Number {
+		header.Number[ctx] = new(big.Int)
+		header.Difficulty[ctx] = new(big.Int)
+		header.NetworkDifficulty[ctx] = new(big.Int)
+		header.BaseFee[ctx] = new(big.Int)
+	}
+	header.ParentHash[params.ZONE] = ht.block.Hash()
+	header.Number[params.ZONE] = big.NewInt(1)
+	header.Difficulty[params.ZONE] = big.NewInt(1 << 20)
+	header.Extra[params.ZONE] = parent.Bytes()
+	header.Nonce = types.EncodeNonce(nonce)
+	header.Location = testLocation
+	return header
+}
+
+// handle sends a message from the remote peer and returns the result of its
+// handling.
+func (ht *handlerTester) handle(code uint64, data interface{}) error {
+	go p2p.Send(ht.app, code, data)
+	return ht.handler.handleMessage(ht.peer)
+}
+
+// handleAsync sends a message from the remote peer and handles it in the
+// background, for the replies to be read.
+func (ht *handlerTester) handleAsync(code uint64, data interface{}) chan error {
+	errc := make(chan error, 1)
+	go p2p.Send(ht.app, code, data)
+	go func() { errc <- ht.handler.handleMessage(ht.peer) }()
+	return errc
+}
+
+// Tests that malformed messages tear the connection down.
+func TestHandleMalformed(t *testing.T) {
+	ht := newHandlerTester(t, 8)
+
+	if err := ht.handle(0x10, []uint{}); !errors.Is(err, errInvalidMsgCode) {
+		t.Errorf("unknown code error mismatch: have %v, want %v", err, errInvalidMsgCode)
+	}
+	for _, code := range []uint64{NewShareMsg, GetSharesMsg, SharesMsg} {
+		if err := ht.handle(code, uint(1)); !errors.Is(err, errDecode) {
+			t.Errorf("message %d: undecodable error mismatch: have %v, want %v", code, err, errDecode)
+		}
+	}
+	if err := ht.handle(NewShareMsg, make([]byte, maxMessageSize)); !errors.Is(err, errMsgTooLarge) {
+		t.Errorf("oversized error mismatch: have %v, want %v", err, errMsgTooLarge)
+	}
+}
+
+// Tests that new shares are added to the share chain, the ancestors of the
+// ones linking to unknown shares are requested, and the peers sending shares
+// not meeting the share difficulty are disconnected.
+func TestHandleNewShare(t *testing.T) {
+	ht := newHandlerTester(t, 8)
+
+	first := ht.share(common.Hash{}, 0)
+	if err := ht.handle(NewShareMsg, &NewSharePacket{Header: first}); err != nil {
+		t.Fatalf("valid share failed: %v", err)
+	}
+	if !ht.shares.Has(first.Hash()) || !ht.peer.KnownShare(first.Hash()) {
+		t.Fatalf("valid share not added")
+	}
+	// Known shares and shares of other locations are ignored
+	if err := ht.handle(NewShareMsg, &NewSharePacket{Header: first}); err != nil {
+		t.Errorf("known share failed: %v", err)
+	}
+	foreign := ht.share(first.Hash(), 1)
+	foreign.Location = []byte{1, 2}
+	if err := ht.handle(NewShareMsg, &NewSharePacket{Header: foreign}); err != nil {
+		t.Errorf("foreign share failed: %v", err)
+	}
+	// Shares of unknown parents trigger a request of their ancestors
+	orphan := ht.share(common.Hash{0xff}, 2)
+	errc := ht.handleAsync(NewShareMsg, &NewSharePacket{Header: orphan})
+	if err := p2p.ExpectMsg(ht.app, GetSharesMsg, &GetSharesPacket{Hash: orphan.Hash(), Amount: 8}); err != nil {
+		t.Fatalf("ancestor request mismatch: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("orphan share failed: %v", err)
+	}
+	// Unsealed shares disconnect the peer
+	unsealed := ht.share(first.Hash(), 3)
+	ht.engine.unsealed[unsealed.Hash()] = true
+	if err := ht.handle(NewShareMsg, &NewSharePacket{Header: unsealed}); !errors.Is(err, sharechain.ErrInvalidSeal) {
+		t.Errorf("unsealed share error mismatch: have %v, want %v", err, sharechain.ErrInvalidSeal)
+	}
+}
+
+// Tests that share queries are served up to the maximum, and that served share
+// segments are imported unless not meeting the share difficulty.
+func TestHandleShares(t *testing.T) {
+	ht := newHandlerTester(t, 2*maxShares)
+
+	var (
+		headers SharesPacket
+		parent  common.Hash
+	)
+	for i := 0; i < maxShares+8; i++ {
+		header := ht.share(parent, uint64(i))
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	if err := ht.handle(SharesMsg, headers[:4]); err != nil {
+		t.Fatalf("share segment failed: %v", err)
+	}
+	if err := ht.handle(SharesMsg, headers[4:]); err != nil {
+		t.Fatalf("share segment failed: %v", err)
+	}
+	if head := ht.shares.Head(); head.Hash() != parent {
+		t.Fatalf("head mismatch: have %x, want %x", head.Hash(), parent)
+	}
+	// Queries beyond the maximum are capped
+	errc := ht.handleAsync(GetSharesMsg, &GetSharesPacket{Hash: parent, Amount: 2 * maxShares})
+	if err := p2p.ExpectMsg(ht.app, SharesMsg, headers[len(headers)-maxShares:]); err != nil {
+		t.Fatalf("served shares mismatch: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Errorf("share query failed: %v", err)
+	}
+	// Segments holding unsealed shares disconnect the peer
+	unsealed := ht.share(parent, uint64(len(headers)))
+	ht.engine.unsealed[unsealed.Hash()] = true
+	if err := ht.handle(SharesMsg, SharesPacket{unsealed}); !errors.Is(err, sharechain.ErrInvalidSeal) {
+		t.Errorf("unsealed segment error mismatch: have %v, want %v", err, sharechain.ErrInvalidSeal)
+	}
+}
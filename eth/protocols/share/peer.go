@@ -0,0 +1,117 @@
+package share
+
+import (
+	mapset "github.com/deckarep/golang-set"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/p2p"
+)
+
+const (
+	// maxKnownShares is the maximum share hashes to keep in the known list
+	// before starting to randomly evict them.
+	maxKnownShares = 4096
+
+	// maxQueuedShares is the maximum number of shares to queue up before
+	// dropping broadcasts.
+	maxQueuedShares = 64
+)
+
+// Peer is a collection of relevant information we have about a `qshr` peer.
+type Peer struct {
+	id string // Unique ID for the peer, cached
+
+	*p2p.Peer                   // The embedded P2P package peer
+	rw        p2p.MsgReadWriter // Input/output streams for qshr
+	version   uint              // Protocol version negotiated
+
+	knownShares mapset.Set         // Set of share hashes known to be known by this peer
+	queue       chan *types.Header // Queue of shares to broadcast to the peer
+	term        chan struct{}      // Termination channel to stop the broadcaster
+
+	logger log.Logger // Contextual logger with the peer id injected
+}
+
+// newPeer create a wrapper for a network connection and negotiated protocol
+// version.
+func newPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter) *Peer {
+	id := p.ID().String()
+	return &Peer{
+		id:          id,
+		Peer:        p,
+		rw:          rw,
+		version:     version,
+		knownShares: mapset.NewSet(),
+		queue:       make(chan *types.Header, maxQueuedShares),
+		term:        make(chan struct{}),
+		logger:      log.New("peer", id[:8]),
+	}
+}
+
+// ID retrieves the peer's unique identifier.
+func (p *Peer) ID() string {
+	return p.id
+}
+
+// Version retrieves the peer's negoatiated `qshr` protocol version.
+func (p *Peer) Version() uint {
+	return p.version
+}
+
+// Log overrides the P2P logget with the higher level one containing only the id.
+func (p *Peer) Log() log.Logger {
+	return p.logger
+}
+
+// markShare marks a share as known for the peer, ensuring that it will never be
+// propagated to this particular peer.
+func (p *Peer) markShare(hash common.Hash) {
+	for p.knownShares.Cardinality() >= maxKnownShares {
+		p.knownShares.Pop()
+	}
+	p.knownShares.Add(hash)
+}
+
+// KnownShare returns whether peer is known to already have a share.
+func (p *Peer) KnownShare(hash common.Hash) bool {
+	return p.knownShares.Contains(hash)
+}
+
+// AsyncSendShare queues a share for propagation to the remote peer. If the
+// peer's broadcast queue is full, the share is silently dropped.
+func (p *Peer) AsyncSendShare(header *types.Header) {
+	select {
+	case p.queue <- header:
+		p.markShare(header.Hash())
+	default:
+		p.Log().Debug("Dropping share propagation", "hash", header.Hash())
+	}
+}
+
+// RequestShares fetches the shares ending at the given one from the remote
+// peer.
+func (p *Peer) RequestShares(hash common.Hash, amount uint64) error {
+	p.Log().Debug("Fetching shares", "hash", hash, "amount", amount)
+	return p2p.Send(p.rw, GetSharesMsg, &GetSharesPacket{Hash: hash, Amount: amount})
+}
+
+// broadcast is a write loop that sends the queued shares to the remote peer.
+// The goroutine stops when the peer is closed.
+func (p *Peer) broadcast() {
+	for {
+		select {
+		case header := <-p.queue:
+			if err := p2p.Send(p.rw, NewShareMsg, &NewSharePacket{Header: header}); err != nil {
+				return
+			}
+		case <-p.term:
+			return
+		}
+	}
+}
+
+// close signals the broadcast goroutine to terminate.
+func (p *Peer) close() {
+	close(p.term)
+}
@@ -0,0 +1,58 @@
+package share
+
+import (
+	"errors"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	share1 = 1
+)
+
+// ProtocolName is the official short name of the `qshr` protocol used during
+// devp2p capability negotiation.
+const ProtocolName = "qshr"
+
+// ProtocolVersions are the supported versions of the `qshr` protocol (first
+// is primary).
+var ProtocolVersions = []uint{share1}
+
+// protocolLengths are the number of implemented message corresponding to
+// different protocol versions.
+var protocolLengths = map[uint]uint64{share1: 3}
+
+// maxMessageSize is the maximum cap on the size of a protocol message.
+const maxMessageSize = 2 * 1024 * 1024
+
+// maxShares is the maximum number of shares served in reply to a request.
+const maxShares = 256
+
+const (
+	NewShareMsg  = 0x00
+	GetSharesMsg = 0x01
+	SharesMsg    = 0x02
+)
+
+var (
+	errMsgTooLarge    = errors.New("message too long")
+	errDecode         = errors.New("invalid message")
+	errInvalidMsgCode = errors.New("invalid message code")
+)
+
+// NewSharePacket is the network packet for propagating a new share.
+type NewSharePacket struct {
+	Header *types.Header
+}
+
+// GetSharesPacket represents a query for the shares ending at a share.
+type GetSharesPacket struct {
+	Hash   common.Hash // Hash of the newest share to retrieve
+	Amount uint64      // Maximum number of shares to retrieve
+}
+
+// SharesPacket is the network packet for the shares served on a query, oldest
+// first.
+type SharesPacket []*types.Header
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/mclock"
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/light"
@@ -82,14 +83,17 @@ type Backend interface {
 	Handle(peer *Peer, packet Packet) error
 }
 
-// MakeProtocols constructs the P2P protocol definitions for `qsnap`.
-func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
+// MakeProtocols constructs the P2P protocol definitions for `qsnap`, serving
+// remote requests within the given bandwidth limits.
+func MakeProtocols(backend Backend, dnsdisc enode.Iterator, serve ServeConfig) []p2p.Protocol {
 	// Filter the discovery iterator for nodes advertising qsnap support.
 	dnsdisc = enode.Filter(dnsdisc, func(n *enode.Node) bool {
 		var snap enrEntry
 		return n.Load(&snap) == nil
 	})
 
+	limiter := newServingLimiter(serve, mclock.System{})
+
 	protocols := make([]p2p.Protocol, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
 		version := version // Closure
@@ -100,7 +104,7 @@ func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
 			Length:  protocolLengths[version],
 			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
 				return backend.RunPeer(newPeer(version, p, rw), func(peer *Peer) error {
-					return handle(backend, peer)
+					return handle(backend, limiter, peer)
 				})
 			},
 			NodeInfo: func() interface{} {
@@ -118,9 +122,12 @@ func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
 
 // handle is the callback invoked to manage the life cycle of a `snap` peer.
 // When this function terminates, the peer is disconnected.
-func handle(backend Backend, peer *Peer) error {
+func handle(backend Backend, limiter *servingLimiter, peer *Peer) error {
+	limiter.register(peer.id)
+	defer limiter.unregister(peer.id)
+
 	for {
-		if err := handleMessage(backend, peer); err != nil {
+		if err := handleMessage(backend, limiter, peer); err != nil {
 			peer.Log().Debug("Message handling failed in `snap`", "err", err)
 			return err
 		}
@@ -130,7 +137,7 @@ func handle(backend Backend, peer *Peer) error {
 // handleMessage is invoked whenever an inbound message is received from a
 // remote peer on the `snap` protocol. The remote connection is torn down upon
 // returning any error.
-func handleMessage(backend Backend, peer *Peer) error {
+func handleMessage(backend Backend, limiter *servingLimiter, peer *Peer) error {
 	// Read the next message from the remote peer, and ensure it's fully consumed
 	msg, err := peer.rw.ReadMsg()
 	if err != nil {
@@ -164,6 +171,7 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if req.Bytes > softResponseLimit {
 			req.Bytes = softResponseLimit
 		}
+		req.Bytes = limiter.wait(peer.id, req.Bytes)
 		// Retrieve the requested state and bail out if non existent
 		tr, err := trie.New(req.Root, backend.Chain().StateCache().TrieDB())
 		if err != nil {
@@ -214,6 +222,8 @@ func handleMessage(backend Backend, peer *Peer) error {
 		for _, blob := range proof.NodeList() {
 			proofs = append(proofs, blob)
 		}
+		limiter.charge(peer.id, size)
+
 		// Send back anything accumulated
 		return p2p.Send(peer.rw, AccountRangeMsg, &AccountRangePacket{
 			ID:       req.ID,
@@ -246,6 +256,7 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if req.Bytes > softResponseLimit {
 			req.Bytes = softResponseLimit
 		}
+		req.Bytes = limiter.wait(peer.id, req.Bytes)
 		// TODO(karalabe): Do we want to enforce > 0 accounts and 1 account if origin is set?
 		// TODO(karalabe):   - Logging locally is not ideal as remote faulst annoy the local user
 		// TODO(karalabe):   - Dropping the remote peer is less flexible wrt client bugs (slow is better than non-functional)
@@ -347,6 +358,8 @@ func handleMessage(backend Backend, peer *Peer) error {
 				break
 			}
 		}
+		limiter.charge(peer.id, size)
+
 		// Send back anything accumulated
 		return p2p.Send(peer.rw, StorageRangesMsg, &StorageRangesPacket{
 			ID:    req.ID,
@@ -381,6 +394,7 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if req.Bytes > softResponseLimit {
 			req.Bytes = softResponseLimit
 		}
+		req.Bytes = limiter.wait(peer.id, req.Bytes)
 		if len(req.Hashes) > maxCodeLookups {
 			req.Hashes = req.Hashes[:maxCodeLookups]
 		}
@@ -402,6 +416,8 @@ func handleMessage(backend Backend, peer *Peer) error {
 				break
 			}
 		}
+		limiter.charge(peer.id, bytes)
+
 		// Send back anything accumulated
 		return p2p.Send(peer.rw, ByteCodesMsg, &ByteCodesPacket{
 			ID:    req.ID,
@@ -427,6 +443,7 @@ func handleMessage(backend Backend, peer *Peer) error {
 		if req.Bytes > softResponseLimit {
 			req.Bytes = softResponseLimit
 		}
+		req.Bytes = limiter.wait(peer.id, req.Bytes)
 		// Make sure we have the state associated with the request
 		triedb := backend.Chain().StateCache().TrieDB()
 
@@ -497,6 +514,8 @@ func handleMessage(backend Backend, peer *Peer) error {
 				break
 			}
 		}
+		limiter.charge(peer.id, bytes)
+
 		// Send back anything accumulated
 		return p2p.Send(peer.rw, TrieNodesMsg, &TrieNodesPacket{
 			ID:    req.ID,
@@ -0,0 +1,162 @@
+package snap
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common/mclock"
+)
+
+const (
+	// servingBurst is the maximum time a peer can accumulate its share of the
+	// serving bandwidth for while not requesting anything.
+	servingBurst = time.Second
+
+	// servingActive is the window within which a peer must have requested data
+	// to be counted when splitting the serving capacity.
+	servingActive = 10 * time.Second
+)
+
+// ServeConfig limits the bandwidth spent on serving snapshot data to syncing
+// peers. A zero value disables the respective limit.
+type ServeConfig struct {
+	Capacity  uint64 // Bytes per second served to all peers combined
+	PeerQuota uint64 // Bytes per second served to a single peer
+}
+
+// servingBucket tracks the serving allowance of a single peer.
+type servingBucket struct {
+	tokens  float64        // Bytes the peer may be served, negative if in debt
+	updated mclock.AbsTime // Last time the tokens were refilled
+	active  mclock.AbsTime // Last time the peer requested data
+}
+
+// servingLimiter throttles the snapshot data served to remote peers. The
+// capacity is split evenly between the peers actively requesting data, each
+// capped at its quota, regardless of what the peer serves in exchange. A peer
+// exceeding its share has its requests delayed until it's paid its debt,
+// without affecting the requests of others.
+type servingLimiter struct {
+	config ServeConfig
+	clock  mclock.Clock
+
+	peers map[string]*servingBucket
+	lock  sync.Mutex
+}
+
+// newServingLimiter creates a limiter enforcing the given serving limits, or
+// nil if both are disabled.
+func newServingLimiter(config ServeConfig, clock mclock.Clock) *servingLimiter {
+	if config.Capacity == 0 && config.PeerQuota == 0 {
+		return nil
+	}
+	return &servingLimiter{
+		config: config,
+		clock:  clock,
+		peers:  make(map[string]*servingBucket),
+	}
+}
+
+// register starts tracking the allowance of a peer, granting it a full burst.
+func (l *servingLimiter) register(id string) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+	l.peers[id] = &servingBucket{updated: now, active: now}
+	l.peers[id].tokens = l.rate(now) * servingBurst.Seconds()
+}
+
+// unregister stops tracking the allowance of a peer.
+func (l *servingLimiter) unregister(id string) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	delete(l.peers, id)
+}
+
+// rate returns the bytes per second currently served to each active peer. The
+// lock must be held.
+func (l *servingLimiter) rate(now mclock.AbsTime) float64 {
+	rate := math.Inf(1)
+	if l.config.Capacity > 0 {
+		active := 0
+		for _, bucket := range l.peers {
+			if time.Duration(now-bucket.active) < servingActive {
+				active++
+			}
+		}
+		if active == 0 {
+			active = 1
+		}
+		rate = float64(l.config.Capacity) / float64(active)
+	}
+	if l.config.PeerQuota > 0 && float64(l.config.PeerQuota) < rate {
+		rate = float64(l.config.PeerQuota)
+	}
+	return rate
+}
+
+// allowance returns the number of bytes the peer may be served now, capped at
+// limit, or the time to wait if it's in debt.
+func (l *servingLimiter) allowance(id string, limit uint64) (uint64, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	bucket := l.peers[id]
+	if bucket == nil {
+		return limit, 0
+	}
+	now := l.clock.Now()
+	bucket.active = now
+
+	rate := l.rate(now)
+	bucket.tokens += rate * time.Duration(now-bucket.updated).Seconds()
+	if burst := rate * servingBurst.Seconds(); bucket.tokens > burst {
+		bucket.tokens = burst
+	}
+	bucket.updated = now
+
+	if bucket.tokens <= 0 {
+		return 0, time.Duration(-bucket.tokens/rate*float64(time.Second)) + time.Millisecond
+	}
+	if bucket.tokens < float64(limit) {
+		return uint64(bucket.tokens), 0
+	}
+	return limit, 0
+}
+
+// wait blocks until the peer may be served and returns the number of bytes to
+// serve it at most, capped at limit.
+func (l *servingLimiter) wait(id string, limit uint64) uint64 {
+	if l == nil {
+		return limit
+	}
+	for {
+		allowance, delay := l.allowance(id, limit)
+		if delay == 0 {
+			return allowance
+		}
+		l.clock.Sleep(delay)
+	}
+}
+
+// charge deducts the bytes served to a peer from its allowance.
+func (l *servingLimiter) charge(id string, size uint64) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if bucket := l.peers[id]; bucket != nil {
+		bucket.tokens -= float64(size)
+	}
+}
@@ -0,0 +1,46 @@
+package snap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common/mclock"
+)
+
+// Tests that the serving capacity is split between the active peers, each
+// capped at its quota, and that peers in debt are delayed.
+func TestServingLimiter(t *testing.T) {
+	clock := new(mclock.Simulated)
+	limiter := newServingLimiter(ServeConfig{Capacity: 1000, PeerQuota: 800}, clock)
+
+	// A lone peer is capped at its quota
+	limiter.register("a")
+	if allowance, delay := limiter.allowance("a", 10000); allowance != 800 || delay != 0 {
+		t.Fatalf("lone peer allowance mismatch: have %d/%v, want 800/0", allowance, delay)
+	}
+	if allowance, _ := limiter.allowance("a", 100); allowance != 100 {
+		t.Fatalf("capped allowance mismatch: have %d, want 100", allowance)
+	}
+	// Two active peers share the capacity, serving above it incurs a delay
+	limiter.register("b")
+	limiter.charge("a", 1300)
+	if allowance, delay := limiter.allowance("a", 10000); allowance != 0 || delay != time.Second+time.Millisecond {
+		t.Fatalf("indebted peer allowance mismatch: have %d/%v, want 0/%v", allowance, delay, time.Second+time.Millisecond)
+	}
+	clock.Run(2 * time.Second)
+	if allowance, delay := limiter.allowance("a", 10000); allowance != 500 || delay != 0 {
+		t.Fatalf("shared allowance mismatch: have %d/%v, want 500/0", allowance, delay)
+	}
+	// Idle and unregistered peers no longer reduce the share of others
+	clock.Run(servingActive)
+	if allowance, _ := limiter.allowance("a", 10000); allowance != 800 {
+		t.Fatalf("allowance beside idle peer mismatch: have %d, want 800", allowance)
+	}
+	limiter.unregister("a")
+	if allowance, _ := limiter.allowance("a", 10000); allowance != 10000 {
+		t.Fatalf("unregistered peer allowance mismatch: have %d, want 10000", allowance)
+	}
+	if newServingLimiter(ServeConfig{}, clock) != nil {
+		t.Fatalf("limiter created without limits")
+	}
+}
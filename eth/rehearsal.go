@@ -0,0 +1,296 @@
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+const (
+	// rehearsalRange is the number of blocks below the head a fork rehearsal
+	// starts at if no first block is given.
+	rehearsalRange = 128
+
+	// maxRehearsalFailures is the number of blocks failing a rehearsal kept for
+	// the status, later ones only being counted.
+	maxRehearsalFailures = 1024
+)
+
+// errRehearsalRunning is returned when starting a fork rehearsal while one is
+// already in progress.
+var errRehearsalRunning = errors.New("fork rehearsal already running")
+
+const (
+	// RehearsalHeader marks blocks whose header the proposed rules reject.
+	RehearsalHeader = "header"
+
+	// RehearsalState marks blocks whose execution the proposed rules reject or
+	// that lead to another state under them.
+	RehearsalState = "state"
+)
+
+// RehearsalFailure is a canonical block that would be invalid under the chain
+// config rehearsed.
+type RehearsalFailure struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+	Check  string         `json:"check"` // RehearsalHeader or RehearsalState
+	Reason string         `json:"reason"`
+}
+
+// RehearsalStatus is the progress of a fork rehearsal.
+type RehearsalStatus struct {
+	Config   *params.ChainConfig `json:"config"`
+	From     hexutil.Uint64      `json:"from"`
+	Current  hexutil.Uint64      `json:"current"` // next block to check
+	Checked  hexutil.Uint64      `json:"checked"`
+	Running  bool                `json:"running"`
+	Started  time.Time           `json:"started"`
+	Elapsed  string              `json:"elapsed"`
+	Invalid  hexutil.Uint64      `json:"invalid"` // number of blocks failing, including the ones not listed
+	Failures []RehearsalFailure  `json:"failures"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// rehearsalChain is the chain as seen by the consensus engine under the chain
+// config rehearsed.
+type rehearsalChain struct {
+	*core.BlockChain
+	config *params.ChainConfig
+}
+
+// Config returns the chain config rehearsed.
+func (c *rehearsalChain) Config() *params.ChainConfig {
+	return c.config
+}
+
+// forkRehearsal runs a single background job validating the canonical blocks
+// under a proposed chain config, the recent ones first and then the ones
+// imported while it runs, reporting the blocks the proposed rules would reject.
+// Blocks are executed on state isolated from the live database, which is never
+// written to.
+type forkRehearsal struct {
+	eth *Ethereum
+
+	lock   sync.Mutex
+	status *RehearsalStatus
+	abort  chan struct{}
+	wg     sync.WaitGroup
+}
+
+// start launches the rehearsal of the config from the given block on.
+func (r *forkRehearsal) start(config *params.ChainConfig, from uint64) (*RehearsalStatus, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.status != nil && r.status.Running {
+		return nil, errRehearsalRunning
+	}
+	r.status = &RehearsalStatus{
+		Config:  config,
+		From:    hexutil.Uint64(from),
+		Current: hexutil.Uint64(from),
+		Running: true,
+		Started: time.Now(),
+	}
+	r.abort = make(chan struct{})
+
+	r.wg.Add(1)
+	go r.run(config, from, r.abort)
+
+	return r.copyStatus(), nil
+}
+
+// stop aborts the running rehearsal, if any, and waits for it to return.
+func (r *forkRehearsal) stop() bool {
+	r.lock.Lock()
+	running := r.status != nil && r.status.Running
+	if running {
+		close(r.abort)
+		r.status.Running = false
+	}
+	r.lock.Unlock()
+
+	r.wg.Wait()
+	return running
+}
+
+// progress returns the status of the last rehearsal, or nil if none was
+// started.
+func (r *forkRehearsal) progress() *RehearsalStatus {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.status == nil {
+		return nil
+	}
+	return r.copyStatus()
+}
+
+// copyStatus returns a copy of the current status, the lock must be held.
+func (r *forkRehearsal) copyStatus() *RehearsalStatus {
+	status := *r.status
+	status.Failures = append([]RehearsalFailure{}, r.status.Failures...)
+	if status.Running {
+		status.Elapsed = common.PrettyDuration(time.Since(status.Started)).String()
+	}
+	return &status
+}
+
+func (r *forkRehearsal) run(config *params.ChainConfig, from uint64, abort chan struct{}) {
+	defer r.wg.Done()
+
+	var (
+		bc      = r.eth.blockchain
+		heads   = make(chan core.ChainHeadEvent, 16)
+		sub     = bc.SubscribeChainHeadEvent(heads)
+		chain   = &rehearsalChain{BlockChain: bc, config: config}
+		statedb *state.StateDB
+		parent  common.Hash // root of the referenced intermediate state
+		logged  = time.Now()
+		number  = from
+		err     error
+	)
+	defer sub.Unsubscribe()
+
+	finish := func(err error) {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		if err != nil {
+			r.status.Error = err.Error()
+		}
+		r.status.Running = false
+		r.status.Elapsed = common.PrettyDuration(time.Since(r.status.Started)).String()
+		log.Info("Fork rehearsal finished", "from", from, "checked", uint64(r.status.Checked),
+			"invalid", uint64(r.status.Invalid), "elapsed", r.status.Elapsed, "err", err)
+	}
+	for {
+		// Check the blocks up to the head, then wait for new ones
+		for ; number <= bc.CurrentBlock().NumberU64(); number++ {
+			select {
+			case <-abort:
+				finish(errors.New("aborted"))
+				return
+			default:
+			}
+			block := bc.GetBlockByNumber(number)
+			if block == nil {
+				finish(fmt.Errorf("canonical block %d missing", number))
+				return
+			}
+			if statedb == nil && number > 0 {
+				if statedb, err = r.parentState(block); err != nil {
+					finish(err)
+					return
+				}
+				parent = common.Hash{}
+			}
+			failure, root, err := r.rehearse(chain, block, statedb)
+			if err != nil {
+				finish(err)
+				return
+			}
+			// Continue on the post state if it's the canonical one, start over
+			// from the parent state of the next block otherwise
+			if failure != nil {
+				statedb = nil
+			} else if number > 0 {
+				if statedb, err = state.New(root, statedb.Database(), nil); err != nil {
+					finish(fmt.Errorf("state reset after block %d failed: %v", number, err))
+					return
+				}
+				statedb.Database().TrieDB().Reference(root, common.Hash{})
+				if parent != (common.Hash{}) {
+					statedb.Database().TrieDB().Dereference(parent)
+				}
+				parent = root
+			}
+
+			r.lock.Lock()
+			r.status.Current = hexutil.Uint64(number + 1)
+			r.status.Checked++
+			if failure != nil {
+				r.status.Invalid++
+				if len(r.status.Failures) < maxRehearsalFailures {
+					r.status.Failures = append(r.status.Failures, *failure)
+				}
+			}
+			r.lock.Unlock()
+
+			if failure != nil {
+				log.Warn("Block invalid under rehearsed rules", "number", failure.Number, "hash", failure.Hash, "check", failure.Check, "reason", failure.Reason)
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Rehearsing fork", "number", number, "head", bc.CurrentBlock().NumberU64())
+				logged = time.Now()
+			}
+		}
+		select {
+		case <-heads:
+		case err := <-sub.Err():
+			finish(err)
+			return
+		case <-abort:
+			finish(errors.New("aborted"))
+			return
+		}
+	}
+}
+
+// parentState returns the state of the parent of the given block in a database
+// isolated from the live one, re-executing blocks if needed.
+func (r *forkRehearsal) parentState(block *types.Block) (*state.StateDB, error) {
+	parent := r.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent of block %d missing", block.NumberU64())
+	}
+	return r.eth.StateAtBlock(parent, verifyReexec, nil, false, false)
+}
+
+// rehearse validates the block under the rehearsed config on the state of its
+// parent, returning the failure if it's invalid. Otherwise the post state, the
+// canonical one, is committed and its root returned.
+func (r *forkRehearsal) rehearse(chain *rehearsalChain, block *types.Block, statedb *state.StateDB) (*RehearsalFailure, common.Hash, error) {
+	fail := func(check string, err error) (*RehearsalFailure, common.Hash, error) {
+		return &RehearsalFailure{
+			Number: hexutil.Uint64(block.NumberU64()),
+			Hash:   block.Hash(),
+			Check:  check,
+			Reason: err.Error(),
+		}, common.Hash{}, nil
+	}
+	if block.NumberU64() == 0 {
+		return nil, common.Hash{}, nil
+	}
+	if err := r.eth.engine.VerifyHeader(chain, block.Header(), false); err != nil {
+		return fail(RehearsalHeader, err)
+	}
+	var (
+		bc        = r.eth.blockchain
+		processor = core.NewStateProcessor(chain.config, bc, r.eth.engine)
+		validator = core.NewBlockValidator(chain.config, bc, r.eth.engine)
+	)
+	receipts, _, usedGas, _, err := processor.Process(block, statedb, vm.Config{})
+	if err != nil {
+		return fail(RehearsalState, err)
+	}
+	if err := validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
+		return fail(RehearsalState, err)
+	}
+	root, err := statedb.Commit(chain.config.DeleteEmptyAccounts(block.Number()))
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("state commit of block %d failed: %v", block.NumberU64(), err)
+	}
+	return nil, root, nil
+}
@@ -0,0 +1,191 @@
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/log"
+)
+
+// scheduleHeadChanSize is the size of channel listening to ChainHeadEvent.
+const scheduleHeadChanSize = 10
+
+// Actions the node lifecycle scheduler executes at a block height.
+const (
+	ActionStopMining = "stopMining" // stop sealing blocks
+	ActionStopTxs    = "stopTxs"    // reject transactions from RPC and peers
+	ActionAcceptTxs  = "acceptTxs"  // accept transactions again after stopTxs
+	ActionExit       = "exit"       // shut the node down, e.g. to upgrade it
+)
+
+// Status of a scheduled action.
+const (
+	ActionPending   = "pending"
+	ActionDone      = "done"
+	ActionCancelled = "cancelled"
+)
+
+// errTxsHalted is returned when submitting transactions while their acceptance
+// is stopped by the operator.
+var errTxsHalted = errors.New("transaction acceptance stopped by the operator")
+
+// ScheduledAction is a node lifecycle action executed once the head reaches
+// a block height.
+type ScheduledAction struct {
+	ID       hexutil.Uint64 `json:"id"`
+	Action   string         `json:"action"`
+	Block    hexutil.Uint64 `json:"block"`
+	Status   string         `json:"status"`
+	Created  time.Time      `json:"created"`
+	Executed *time.Time     `json:"executed,omitempty"`
+	Head     hexutil.Uint64 `json:"head,omitempty"` // head the action was executed at
+}
+
+// actionScheduler executes the node lifecycle actions scheduled by the
+// operator once the head of the chain reaches their block, so that fleets of
+// nodes can be stopped or upgraded in step at a fork. The schedule is kept in
+// memory, actions not executed before a restart need to be scheduled again.
+type actionScheduler struct {
+	eth  *Ethereum
+	exit func() // shuts the node down
+
+	lock    sync.Mutex
+	actions []*ScheduledAction
+	nextID  uint64
+	quit    chan struct{} // closed to stop the head loop, nil if it's not running
+	wg      sync.WaitGroup
+}
+
+// schedule adds the action at the block, starting to follow the head if needed.
+func (s *actionScheduler) schedule(action string, block uint64) (*ScheduledAction, error) {
+	switch action {
+	case ActionStopMining, ActionStopTxs, ActionAcceptTxs, ActionExit:
+	default:
+		return nil, fmt.Errorf("unknown action %q, want one of %s, %s, %s or %s", action, ActionStopMining, ActionStopTxs, ActionAcceptTxs, ActionExit)
+	}
+	if head := s.eth.blockchain.CurrentBlock().NumberU64(); block <= head {
+		return nil, fmt.Errorf("block %d already reached, head is %d", block, head)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.nextID++
+	scheduled := &ScheduledAction{
+		ID:      hexutil.Uint64(s.nextID),
+		Action:  action,
+		Block:   hexutil.Uint64(block),
+		Status:  ActionPending,
+		Created: time.Now(),
+	}
+	s.actions = append(s.actions, scheduled)
+	if s.quit == nil {
+		s.quit = make(chan struct{})
+		s.wg.Add(1)
+		go s.loop(s.quit)
+	}
+	log.Info("Scheduled node action", "id", s.nextID, "action", action, "block", block)
+
+	copied := *scheduled
+	return &copied, nil
+}
+
+// cancel cancels the pending action, returning whether it was pending.
+func (s *actionScheduler) cancel(id uint64) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, action := range s.actions {
+		if uint64(action.ID) == id && action.Status == ActionPending {
+			action.Status = ActionCancelled
+			log.Info("Cancelled node action", "id", id, "action", action.Action, "block", uint64(action.Block))
+			return true
+		}
+	}
+	return false
+}
+
+// list returns the scheduled actions ordered by block.
+func (s *actionScheduler) list() []ScheduledAction {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	actions := make([]ScheduledAction, len(s.actions))
+	for i, action := range s.actions {
+		actions[i] = *action
+	}
+	sort.SliceStable(actions, func(i, j int) bool { return actions[i].Block < actions[j].Block })
+	return actions
+}
+
+// stop stops following the head, leaving the pending actions unexecuted.
+func (s *actionScheduler) stop() {
+	s.lock.Lock()
+	if s.quit != nil {
+		close(s.quit)
+		s.quit = nil
+	}
+	s.lock.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *actionScheduler) loop(quit chan struct{}) {
+	defer s.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, scheduleHeadChanSize)
+	sub := s.eth.blockchain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	// Actions may have been scheduled for the block imported before subscribing
+	s.execute(s.eth.blockchain.CurrentBlock().NumberU64())
+	for {
+		select {
+		case head := <-heads:
+			s.execute(head.Block.NumberU64())
+		case err := <-sub.Err():
+			log.Error("Node action schedule stopped following the head", "err", err)
+			return
+		case <-quit:
+			return
+		}
+	}
+}
+
+// execute runs the pending actions whose block the head reached, in the order
+// of their block.
+func (s *actionScheduler) execute(head uint64) {
+	s.lock.Lock()
+	var due []*ScheduledAction
+	for _, action := range s.actions {
+		if action.Status == ActionPending && uint64(action.Block) <= head {
+			due = append(due, action)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].Block < due[j].Block })
+	for _, action := range due {
+		now := time.Now()
+		action.Status, action.Executed, action.Head = ActionDone, &now, hexutil.Uint64(head)
+	}
+	s.lock.Unlock()
+
+	for _, action := range due {
+		log.Warn("Executing scheduled node action", "id", uint64(action.ID), "action", action.Action, "block", uint64(action.Block), "head", head)
+		switch action.Action {
+		case ActionStopMining:
+			s.eth.StopMining()
+		case ActionStopTxs:
+			atomic.StoreUint32(&s.eth.handler.haltTxs, 1)
+		case ActionAcceptTxs:
+			atomic.StoreUint32(&s.eth.handler.haltTxs, 0)
+		case ActionExit:
+			// Shut down outside of the loop, which the shutdown waits for
+			go s.exit()
+		}
+	}
+}
@@ -150,8 +150,8 @@ func (cs *chainSyncer) nextSyncOp() *chainSyncOp {
 	minPeers := defaultMinSyncPeers
 	if cs.forced {
 		minPeers = 1
-	} else if minPeers > cs.handler.maxPeers {
-		minPeers = cs.handler.maxPeers
+	} else if maxPeers := int(atomic.LoadInt32(&cs.handler.maxPeers)); minPeers > maxPeers {
+		minPeers = maxPeers
 	}
 	if cs.handler.peers.len() < minPeers {
 		return nil
@@ -166,6 +166,10 @@ func (cs *chainSyncer) nextSyncOp() *chainSyncOp {
 		// Fast sync via the snap protocol
 		mode = downloader.SnapSync
 	}
+	if mode == downloader.FastSync && atomic.LoadUint32(&cs.handler.checkpointSync) == 1 {
+		// Fast sync forward from the trusted anchor
+		mode = downloader.CheckpointSync
+	}
 	op := peerToSyncOp(mode, peer)
 
 	// Sanity check on the TD tuple given by the Peer.
@@ -221,7 +225,7 @@ func (cs *chainSyncer) startSync(op *chainSyncOp) {
 
 // doSync synchronizes the local blockchain with a remote peer.
 func (h *handler) doSync(op *chainSyncOp) error {
-	if op.mode == downloader.FastSync || op.mode == downloader.SnapSync {
+	if op.mode == downloader.FastSync || op.mode == downloader.SnapSync || op.mode == downloader.CheckpointSync {
 		// Before launch the fast sync, we have to ensure user uses the same
 		// txlookup limit.
 		// The main concern here is: during the fast sync Geth won't index the
@@ -252,6 +256,10 @@ func (h *handler) doSync(op *chainSyncOp) error {
 		log.Info("Snap sync complete, auto disabling")
 		atomic.StoreUint32(&h.snapSync, 0)
 	}
+	if atomic.LoadUint32(&h.checkpointSync) == 1 {
+		log.Info("Checkpoint sync complete, auto disabling")
+		atomic.StoreUint32(&h.checkpointSync, 0)
+	}
 	// If we've successfully finished a sync cycle and passed any required checkpoint,
 	// enable accepting transactions from the network.
 	head := h.chain.CurrentBlock()
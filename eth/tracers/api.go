@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -31,6 +32,7 @@ import (
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/common/hexutil"
 	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/contractmeta"
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/rawdb"
 	"github.com/spruce-solutions/go-quai/core/state"
@@ -38,6 +40,7 @@ import (
 	"github.com/spruce-solutions/go-quai/core/vm"
 	"github.com/spruce-solutions/go-quai/ethdb"
 	"github.com/spruce-solutions/go-quai/internal/ethapi"
+	"github.com/spruce-solutions/go-quai/labels"
 	"github.com/spruce-solutions/go-quai/log"
 	"github.com/spruce-solutions/go-quai/params"
 	"github.com/spruce-solutions/go-quai/rlp"
@@ -159,6 +162,8 @@ type TraceConfig struct {
 	Tracer  *string
 	Timeout *string
 	Reexec  *uint64
+	Decode  bool // decode calls to verified contracts with their metadata
+	Labels  bool // annotate call frames with the node-local address labels
 }
 
 // TraceCallConfig is the config for traceCall API. It holds one more
@@ -169,6 +174,8 @@ type TraceCallConfig struct {
 	Timeout        *string
 	Reexec         *uint64
 	StateOverrides *ethapi.StateOverride
+	Decode         bool
+	Labels         bool
 }
 
 // StdTraceConfig holds extra parameters to standard-json trace functions.
@@ -277,7 +284,7 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 						break
 					}
 					// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
-					task.statedb.Finalise(api.backend.ChainConfig().IsEIP158(task.block.Number()))
+					task.statedb.Finalise(api.backend.ChainConfig().DeleteEmptyAccounts(task.block.Number()))
 					task.results[i] = &txTraceResult{Result: res}
 				}
 				// Stream the result back to the user or abort on teardown
@@ -372,8 +379,10 @@ func (api *API) traceChain(ctx context.Context, start, end *types.Block, config
 			}
 			// Send the block over to the concurrent tracers (if not in the fast-forward phase)
 			txs := next.Transactions()
+			taskdb := statedb.Copy()
+			core.ApplySystemChanges(api.backend.ChainConfig(), api.backend.Engine(), block.Header(), next.Header(), taskdb)
 			select {
-			case tasks <- &blockTraceTask{statedb: statedb.Copy(), block: next, rootref: block.Root(), results: make([]*txTraceResult, len(txs))}:
+			case tasks <- &blockTraceTask{statedb: taskdb, block: next, rootref: block.Root(), results: make([]*txTraceResult, len(txs))}:
 			case <-notifier.Closed():
 				return
 			}
@@ -500,12 +509,13 @@ func (api *API) IntermediateRoots(ctx context.Context, hash common.Hash, config
 	if err != nil {
 		return nil, err
 	}
+	core.ApplySystemChanges(api.backend.ChainConfig(), api.backend.Engine(), parent.Header(), block.Header(), statedb)
 	var (
 		roots              []common.Hash
 		signer             = types.MakeSigner(api.backend.ChainConfig(), block.Number())
 		chainConfig        = api.backend.ChainConfig()
 		vmctx              = core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
-		deleteEmptyObjects = chainConfig.IsEIP158(block.Number())
+		deleteEmptyObjects = chainConfig.DeleteEmptyAccounts(block.Number())
 	)
 	for i, tx := range block.Transactions() {
 		var (
@@ -561,6 +571,7 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 	if err != nil {
 		return nil, err
 	}
+	core.ApplySystemChanges(api.backend.ChainConfig(), api.backend.Engine(), parent.Header(), block.Header(), statedb)
 	// Execute all the transaction contained within the block concurrently
 	var (
 		signer  = types.MakeSigner(api.backend.ChainConfig(), block.Number())
@@ -613,7 +624,7 @@ func (api *API) traceBlock(ctx context.Context, block *types.Block, config *Trac
 		}
 		// Finalize the state so any modifications are written to the trie
 		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
-		statedb.Finalise(vmenv.ChainConfig().IsEIP158(block.Number()))
+		statedb.Finalise(vmenv.ChainConfig().DeleteEmptyAccounts(block.Number()))
 	}
 	close(jobs)
 	pend.Wait()
@@ -650,6 +661,7 @@ func (api *API) standardTraceBlockToFile(ctx context.Context, block *types.Block
 	if err != nil {
 		return nil, err
 	}
+	core.ApplySystemChanges(api.backend.ChainConfig(), api.backend.Engine(), parent.Header(), block.Header(), statedb)
 	// Retrieve the tracing configurations, or use default values
 	var (
 		logConfig vm.LogConfig
@@ -733,7 +745,7 @@ func (api *API) standardTraceBlockToFile(ctx context.Context, block *types.Block
 		}
 		// Finalize the state so any modifications are written to the trie
 		// Only delete empty objects if EIP158/161 (a.k.a Spurious Dragon) is in effect
-		statedb.Finalise(vmenv.ChainConfig().IsEIP158(block.Number()))
+		statedb.Finalise(vmenv.ChainConfig().DeleteEmptyAccounts(block.Number()))
 
 		// If we've traced the transaction we were looking for, abort
 		if tx.Hash() == txHash {
@@ -834,6 +846,8 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 			Tracer:    config.Tracer,
 			Timeout:   config.Timeout,
 			Reexec:    config.Reexec,
+			Decode:    config.Decode,
+			Labels:    config.Labels,
 		}
 	}
 	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
@@ -858,16 +872,24 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 				return nil, err
 			}
 		}
-		// Constuct the JavaScript tracer to execute with
-		if tracer, err = New(*config.Tracer, txctx); err != nil {
+		// Constuct the native or JavaScript tracer to execute with
+		var stoppable NativeTracer
+		if ctor, ok := native(*config.Tracer); ok {
+			stoppable, err = ctor(txctx)
+		} else {
+			stoppable, err = New(*config.Tracer, txctx)
+		}
+		if err != nil {
 			return nil, err
 		}
+		tracer = stoppable
+
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
 			if deadlineCtx.Err() == context.DeadlineExceeded {
-				tracer.(*Tracer).Stop(errors.New("execution timeout"))
+				stoppable.Stop(errors.New("execution timeout"))
 			}
 		}()
 		defer cancel()
@@ -897,14 +919,28 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 		if len(result.Revert()) > 0 {
 			returnVal = fmt.Sprintf("%x", result.Revert())
 		}
-		return &ethapi.ExecutionResult{
+		res := &ethapi.ExecutionResult{
 			Gas:         result.UsedGas,
 			Failed:      result.Failed(),
 			ReturnValue: returnVal,
 			StructLogs:  ethapi.FormatLogs(tracer.StructLogs()),
-		}, nil
+		}
+		if config != nil && config.Decode && message.To() != nil {
+			res.Decoded = contractmeta.DecodeCall(api.contractMetadata(), *message.To(), message.Data())
+		}
+		return res, nil
 
 	case *Tracer:
+		res, err := tracer.GetResult()
+		if err == nil && config.Decode {
+			res, err = decodeCallFrames(api.contractMetadata(), res)
+		}
+		if err == nil && config.Labels {
+			res, err = labelCallFrames(api.addressLabels(), res)
+		}
+		return res, err
+
+	case NativeTracer:
 		return tracer.GetResult()
 
 	default:
@@ -912,6 +948,104 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 	}
 }
 
+// contractMetadata returns the contract metadata store of the backend, if it
+// has one.
+func (api *API) contractMetadata() contractmeta.Store {
+	if backend, ok := api.backend.(contractmeta.Backend); ok {
+		return backend.ContractMetadata()
+	}
+	return nil
+}
+
+// addressLabels returns the address labels of the backend, if it has them.
+func (api *API) addressLabels() labels.Labeler {
+	if backend, ok := api.backend.(labels.Backend); ok {
+		return backend.AddressLabels()
+	}
+	return nil
+}
+
+// decodeCallFrames decodes the inputs of the calls in a JavaScript tracer
+// result with the metadata of their callees. Every object carrying a "to" and
+// an "input" field, as the frames of the call tracer do, gains a "decoded"
+// field if its callee is verified.
+func decodeCallFrames(store contractmeta.Store, result json.RawMessage) (json.RawMessage, error) {
+	if store == nil {
+		return result, nil
+	}
+	var frames interface{}
+	if err := json.Unmarshal(result, &frames); err != nil {
+		return result, nil // not JSON structured, nothing to decode
+	}
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch node := node.(type) {
+		case map[string]interface{}:
+			for _, child := range node {
+				walk(child)
+			}
+			to, ok := node["to"].(string)
+			if !ok || !common.IsHexAddress(to) {
+				return
+			}
+			input, ok := node["input"].(string)
+			if !ok {
+				return
+			}
+			data, err := hexutil.Decode(input)
+			if err != nil {
+				return
+			}
+			if call := contractmeta.DecodeCall(store, common.HexToAddress(to), data); call != nil {
+				node["decoded"] = call
+			}
+		case []interface{}:
+			for _, child := range node {
+				walk(child)
+			}
+		}
+	}
+	walk(frames)
+	return json.Marshal(frames)
+}
+
+// labelCallFrames annotates the addresses in a JavaScript tracer result with
+// their labels. Every object carrying a labelled "from" or "to" address, as the
+// frames of the call tracer do, gains a "fromLabel" or "toLabel" field.
+func labelCallFrames(labeler labels.Labeler, result json.RawMessage) (json.RawMessage, error) {
+	if labeler == nil {
+		return result, nil
+	}
+	var frames interface{}
+	if err := json.Unmarshal(result, &frames); err != nil {
+		return result, nil // not JSON structured, nothing to annotate
+	}
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch node := node.(type) {
+		case map[string]interface{}:
+			for _, child := range node {
+				walk(child)
+			}
+			for _, field := range []string{"from", "to"} {
+				addr, ok := node[field].(string)
+				if !ok || !common.IsHexAddress(addr) {
+					continue
+				}
+				if label := labeler.Label(common.HexToAddress(addr)); label != "" {
+					node[field+"Label"] = label
+				}
+			}
+		case []interface{}:
+			for _, child := range node {
+				walk(child)
+			}
+		}
+	}
+	walk(frames)
+	return json.Marshal(frames)
+}
+
 // APIs return the collection of RPC services the tracer package offers.
 func APIs(backend Backend) []rpc.API {
 	// Append all the local APIs and return
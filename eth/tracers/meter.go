@@ -0,0 +1,211 @@
+package tracers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// ETxReport is an external transaction emitted by a metered transaction, i.e.
+// a value transfer to an address outside of the local chain's range.
+type ETxReport struct {
+	To    common.Address `json:"to"`
+	Value *hexutil.Big   `json:"value"`
+	Depth int            `json:"depth"`
+}
+
+// ResourceReport is the execution cost breakdown of a single transaction as
+// returned by debug_meterTransaction.
+type ResourceReport struct {
+	TxHash  common.Hash `json:"txHash"`
+	Gas     uint64      `json:"gas"`
+	Failed  bool        `json:"failed"`
+	EVMTime uint64      `json:"evmTime"` // nanoseconds spent in the EVM, tracing overhead included
+
+	Opcodes       uint64 `json:"opcodes"`
+	Calls         uint64 `json:"calls"`
+	Creates       uint64 `json:"creates"`
+	StorageReads  uint64 `json:"storageReads"`  // SLOAD operations
+	StorageWrites uint64 `json:"storageWrites"` // SSTORE operations
+	AccountReads  uint64 `json:"accountReads"`  // balance and code lookups of other accounts
+
+	// Distinct trie leaves read or written during execution, and the trie
+	// updates and deletions needed to hash the resulting state.
+	AccountsTouched int    `json:"accountsTouched"`
+	SlotsTouched    int    `json:"slotsTouched"`
+	AccountUpdates  int    `json:"accountUpdates"`
+	StorageUpdates  int    `json:"storageUpdates"`
+	AccountDeletes  int    `json:"accountDeletes"`
+	StorageDeletes  int    `json:"storageDeletes"`
+	TrieReadTime    uint64 `json:"trieReadTime"` // nanoseconds spent loading accounts and slots
+
+	ETxs []ETxReport `json:"etxs"`
+}
+
+// resourceMeter is a vm.Tracer counting the state accesses and external
+// transactions of a single message.
+type resourceMeter struct {
+	idRange []int // first address bytes belonging to the local chain
+	report  *ResourceReport
+
+	accounts map[common.Address]struct{}
+	slots    map[common.Address]map[common.Hash]struct{}
+}
+
+func newResourceMeter(idRange []int) *resourceMeter {
+	return &resourceMeter{
+		idRange:  idRange,
+		report:   &ResourceReport{ETxs: []ETxReport{}},
+		accounts: make(map[common.Address]struct{}),
+		slots:    make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// external reports whether the address lies outside the local chain.
+func (m *resourceMeter) external(addr common.Address) bool {
+	if len(m.idRange) != 2 {
+		return false
+	}
+	prefix := int(addr.Bytes()[0])
+	return prefix < m.idRange[0] || prefix > m.idRange[1]
+}
+
+func (m *resourceMeter) touchAccount(addr common.Address) {
+	m.accounts[addr] = struct{}{}
+}
+
+func (m *resourceMeter) touchSlot(addr common.Address, slot common.Hash) {
+	m.touchAccount(addr)
+	if m.slots[addr] == nil {
+		m.slots[addr] = make(map[common.Hash]struct{})
+	}
+	m.slots[addr][slot] = struct{}{}
+}
+
+func (m *resourceMeter) transfer(to common.Address, value *big.Int, depth int) {
+	if value != nil && value.Sign() > 0 && m.external(to) {
+		m.report.ETxs = append(m.report.ETxs, ETxReport{To: to, Value: (*hexutil.Big)(new(big.Int).Set(value)), Depth: depth})
+	}
+}
+
+func (m *resourceMeter) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	m.touchAccount(from)
+	m.touchAccount(to)
+	if create {
+		m.report.Creates++
+	}
+	m.transfer(to, value, 0)
+}
+
+func (m *resourceMeter) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	m.report.Opcodes++
+
+	stack := scope.Stack
+	switch op {
+	case vm.SLOAD:
+		m.report.StorageReads++
+		m.touchSlot(scope.Contract.Address(), common.Hash(stack.Back(0).Bytes32()))
+	case vm.SSTORE:
+		m.report.StorageWrites++
+		m.touchSlot(scope.Contract.Address(), common.Hash(stack.Back(0).Bytes32()))
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODEHASH, vm.EXTCODECOPY:
+		m.report.AccountReads++
+		m.touchAccount(common.Address(stack.Back(0).Bytes20()))
+	case vm.CALL, vm.CALLCODE:
+		to := common.Address(stack.Back(1).Bytes20())
+		m.report.Calls++
+		m.touchAccount(to)
+		m.transfer(to, stack.Back(2).ToBig(), depth)
+	case vm.DELEGATECALL, vm.STATICCALL:
+		m.report.Calls++
+		m.touchAccount(common.Address(stack.Back(1).Bytes20()))
+	case vm.CREATE, vm.CREATE2:
+		m.report.Creates++
+	case vm.SELFDESTRUCT:
+		m.touchAccount(common.Address(stack.Back(0).Bytes20()))
+	}
+}
+
+func (m *resourceMeter) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+func (m *resourceMeter) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (m *resourceMeter) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (m *resourceMeter) CaptureEnd(output []byte, gasUsed uint64, t time.Duration, err error) {
+	m.report.EVMTime = uint64(t)
+}
+
+// MeterTransaction re-executes the given transaction and reports the resources
+// it consumed: time spent in the EVM, state reads and writes, trie entries
+// touched and the external transactions it emitted.
+//
+// The transaction is executed with the meter hooked into every opcode, so the
+// EVM time includes the tracing overhead. It is an upper bound of the time an
+// untraced execution takes, meant for comparing transactions with each other.
+func (api *API) MeterTransaction(ctx context.Context, hash common.Hash, reexec *uint64) (*ResourceReport, error) {
+	_, blockHash, blockNumber, index, err := api.backend.GetTransaction(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if blockNumber == 0 {
+		return nil, errors.New("genesis is not traceable")
+	}
+	replay := defaultTraceReexec
+	if reexec != nil {
+		replay = *reexec
+	}
+	block, err := api.blockByNumberAndHash(ctx, rpc.BlockNumber(blockNumber), blockHash)
+	if err != nil {
+		return nil, err
+	}
+	msg, vmctx, statedb, err := api.backend.StateAtTransaction(ctx, block, int(index), replay)
+	if err != nil {
+		return nil, err
+	}
+	config := api.backend.ChainConfig()
+	meter := newResourceMeter(config.ChainIDRange())
+
+	// Snapshot the statedb counters, they include the replayed transactions
+	var (
+		reads          = statedb.AccountReads + statedb.StorageReads + statedb.SnapshotAccountReads + statedb.SnapshotStorageReads
+		accountUpdates = statedb.AccountUpdated
+		storageUpdates = statedb.StorageUpdated
+		accountDeletes = statedb.AccountDeleted
+		storageDeletes = statedb.StorageDeleted
+	)
+	vmenv := vm.NewEVM(vmctx, core.NewEVMTxContext(msg), statedb, config, vm.Config{Debug: true, Tracer: meter, NoBaseFee: true})
+	statedb.Prepare(hash, int(index))
+
+	result, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas()))
+	if err != nil {
+		return nil, fmt.Errorf("metering failed: %w", err)
+	}
+	// Hash the resulting state to account for the trie updates
+	statedb.IntermediateRoot(config.DeleteEmptyAccounts(block.Number()))
+
+	report := meter.report
+	report.TxHash = hash
+	report.Gas = result.UsedGas
+	report.Failed = result.Failed()
+	report.AccountsTouched = len(meter.accounts)
+	for _, slots := range meter.slots {
+		report.SlotsTouched += len(slots)
+	}
+	report.AccountUpdates = statedb.AccountUpdated - accountUpdates
+	report.StorageUpdates = statedb.StorageUpdated - storageUpdates
+	report.AccountDeletes = statedb.AccountDeleted - accountDeletes
+	report.StorageDeletes = statedb.StorageDeleted - storageDeletes
+	report.TrieReadTime = uint64(statedb.AccountReads + statedb.StorageReads + statedb.SnapshotAccountReads + statedb.SnapshotStorageReads - reads)
+	return report, nil
+}
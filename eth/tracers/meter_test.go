@@ -0,0 +1,128 @@
+package tracers
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// meterRange is the address range of the local chain in the meter tests.
+var meterRange = []int{0x00, 0x1d}
+
+// runMeter calls to with value from a funded sender, with code deployed at
+// contract, and returns the meter of the call.
+func runMeter(t *testing.T, contract common.Address, code []byte, to common.Address, value *big.Int) *resourceMeter {
+	t.Helper()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	sender := common.Address{0x01}
+	statedb.AddBalance(sender, big.NewInt(params.Ether))
+	statedb.SetCode(contract, code)
+	statedb.AddBalance(contract, big.NewInt(params.Ether))
+
+	meter := newResourceMeter(meterRange)
+	blockCtx := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(1),
+		Difficulty:  big.NewInt(1),
+		BaseFee:     big.NewInt(0),
+		GasLimit:    params.GenesisGasLimit,
+	}
+	env := vm.NewEVM(blockCtx, vm.TxContext{Origin: sender, GasPrice: big.NewInt(0)}, statedb, params.TestChainConfig, vm.Config{Debug: true, Tracer: meter})
+	if _, _, err := env.Call(vm.AccountRef(sender), to, nil, 1000000, value); err != nil {
+		t.Fatalf("call failed: %v", err)
+	}
+	return meter
+}
+
+// callCode returns the code of a CALL to addr transferring value.
+func callCode(addr common.Address, value byte) []byte {
+	code := []byte{
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, // out and in sizes and offsets
+		byte(vm.PUSH1), value,
+		byte(vm.PUSH20),
+	}
+	code = append(code, addr.Bytes()...)
+	return append(code, byte(vm.GAS), byte(vm.CALL), byte(vm.POP))
+}
+
+// Tests that the meter counts the opcodes, storage and account accesses of a
+// call and reports the value transfers leaving the local chain as ETXs.
+func TestResourceMeter(t *testing.T) {
+	var (
+		contract = common.Address{0x02}
+		balance  = common.Address{0x03}
+		local    = common.Address{0x04}
+		external = common.Address{0xe0}
+	)
+	code := []byte{
+		byte(vm.PUSH1), 1, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 1, byte(vm.SLOAD), byte(vm.POP),
+		byte(vm.PUSH1), 5, byte(vm.PUSH1), 2, byte(vm.SSTORE),
+		byte(vm.PUSH20),
+	}
+	code = append(code, balance.Bytes()...)
+	code = append(code, byte(vm.BALANCE), byte(vm.POP))
+	code = append(code, callCode(external, 7)...)
+	code = append(code, callCode(local, 9)...)
+	code = append(code, byte(vm.STOP))
+
+	meter := runMeter(t, contract, code, contract, new(big.Int))
+	report := meter.report
+
+	if report.Opcodes != 31 {
+		t.Errorf("opcode count mismatch: have %d, want %d", report.Opcodes, 31)
+	}
+	if report.StorageReads != 2 || report.StorageWrites != 1 {
+		t.Errorf("storage access mismatch: have %d reads %d writes, want 2 reads 1 write", report.StorageReads, report.StorageWrites)
+	}
+	if report.AccountReads != 1 || report.Calls != 2 || report.Creates != 0 {
+		t.Errorf("account access mismatch: have %d reads %d calls %d creates, want 1 read 2 calls", report.AccountReads, report.Calls, report.Creates)
+	}
+	// The sender, the contract, the balance lookup and both callees
+	if len(meter.accounts) != 5 {
+		t.Errorf("touched accounts mismatch: have %d, want %d", len(meter.accounts), 5)
+	}
+	// Slot 1 is read twice but touched once
+	if slots := len(meter.slots[contract]); len(meter.slots) != 1 || slots != 2 {
+		t.Errorf("touched slots mismatch: have %d in %d accounts, want 2 in 1", slots, len(meter.slots))
+	}
+	if len(report.ETxs) != 1 {
+		t.Fatalf("ETX count mismatch: have %d, want %d", len(report.ETxs), 1)
+	}
+	if etx := report.ETxs[0]; etx.To != external || etx.Value.ToInt().Uint64() != 7 || etx.Depth != 1 {
+		t.Errorf("ETX mismatch: have %x %v at depth %d, want %x 7 at depth 1", etx.To, etx.Value, etx.Depth, external)
+	}
+	if report.EVMTime == 0 {
+		t.Errorf("EVM time not measured")
+	}
+}
+
+// Tests that a value transfer of the metered message itself to an address
+// outside of the local chain is reported as an ETX.
+func TestResourceMeterTransfer(t *testing.T) {
+	external := common.Address{0xe0}
+
+	report := runMeter(t, common.Address{0x02}, nil, external, big.NewInt(3)).report
+	if report.Opcodes != 0 {
+		t.Errorf("opcode count mismatch: have %d, want 0", report.Opcodes)
+	}
+	if len(report.ETxs) != 1 {
+		t.Fatalf("ETX count mismatch: have %d, want %d", len(report.ETxs), 1)
+	}
+	if etx := report.ETxs[0]; etx.To != external || etx.Value.ToInt().Uint64() != 3 || etx.Depth != 0 {
+		t.Errorf("ETX mismatch: have %x %v at depth %d, want %x 3 at depth 0", etx.To, etx.Value, etx.Depth, external)
+	}
+	// Transfers within the local chain aren't
+	if etxs := runMeter(t, common.Address{0x02}, nil, common.Address{0x04}, big.NewInt(3)).report.ETxs; len(etxs) != 0 {
+		t.Errorf("local transfer reported as ETX: %v", etxs)
+	}
+}
@@ -0,0 +1,47 @@
+package tracers
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/spruce-solutions/go-quai/core/vm"
+)
+
+// NativeTracer is a tracer implemented in Go, run by the tracing API when it
+// is requested by the name it was registered under.
+type NativeTracer interface {
+	vm.Tracer
+
+	// GetResult returns the result of the trace, after the transaction ran.
+	GetResult() (json.RawMessage, error)
+
+	// Stop aborts the trace with the error, it is called on timeouts
+	// concurrently with the tracing.
+	Stop(err error)
+}
+
+// NativeTracerCtor creates a native tracer for the transaction of the context.
+type NativeTracerCtor func(ctx *Context) (NativeTracer, error)
+
+var (
+	natives     = make(map[string]NativeTracerCtor)
+	nativesLock sync.RWMutex
+)
+
+// RegisterNative makes a native tracer selectable by name, taking precedence
+// over the JavaScript tracers.
+func RegisterNative(name string, ctor NativeTracerCtor) {
+	nativesLock.Lock()
+	defer nativesLock.Unlock()
+
+	natives[name] = ctor
+}
+
+// native retrieves a native tracer by name.
+func native(name string) (NativeTracerCtor, bool) {
+	nativesLock.RLock()
+	defer nativesLock.RUnlock()
+
+	ctor, ok := natives[name]
+	return ctor, ok
+}
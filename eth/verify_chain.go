@@ -0,0 +1,224 @@
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/trie"
+)
+
+// verifyReexec is the number of blocks re-executed at most to regenerate the
+// parent state of the first block verified by re-execution.
+const verifyReexec = 128
+
+// errVerifyRunning is returned when starting a chain verification while one is
+// already in progress.
+var errVerifyRunning = errors.New("chain verification already running")
+
+// ChainVerifyStatus is the progress of a chain verification job.
+type ChainVerifyStatus struct {
+	From       hexutil.Uint64      `json:"from"`
+	To         hexutil.Uint64      `json:"to"`
+	Reexec     bool                `json:"reexec"`
+	Current    hexutil.Uint64      `json:"current"` // next block to verify
+	Running    bool                `json:"running"`
+	Started    time.Time           `json:"started"`
+	Elapsed    string              `json:"elapsed"`
+	Mismatches []core.RootMismatch `json:"mismatches"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// chainVerifier runs a single background job re-deriving the roots of a range
+// of canonical blocks, see core.BlockChain.VerifyBlockRoots. If requested, the
+// blocks are also re-executed to verify their state and receipt roots.
+type chainVerifier struct {
+	eth *Ethereum
+
+	lock   sync.Mutex
+	status *ChainVerifyStatus
+	abort  chan struct{}
+	wg     sync.WaitGroup
+}
+
+// start launches the verification of the given range in the background.
+func (v *chainVerifier) start(from, to uint64, reexec bool) (*ChainVerifyStatus, error) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.status != nil && v.status.Running {
+		return nil, errVerifyRunning
+	}
+	v.status = &ChainVerifyStatus{
+		From:    hexutil.Uint64(from),
+		To:      hexutil.Uint64(to),
+		Reexec:  reexec,
+		Current: hexutil.Uint64(from),
+		Running: true,
+		Started: time.Now(),
+	}
+	v.abort = make(chan struct{})
+
+	v.wg.Add(1)
+	go v.run(from, to, reexec, v.abort)
+
+	return v.copyStatus(), nil
+}
+
+// stop aborts the running job, if any, and waits for it to return.
+func (v *chainVerifier) stop() bool {
+	v.lock.Lock()
+	running := v.status != nil && v.status.Running
+	if running {
+		close(v.abort)
+		v.status.Running = false
+	}
+	v.lock.Unlock()
+
+	v.wg.Wait()
+	return running
+}
+
+// progress returns the status of the last job, or nil if none was started.
+func (v *chainVerifier) progress() *ChainVerifyStatus {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	if v.status == nil {
+		return nil
+	}
+	return v.copyStatus()
+}
+
+// copyStatus returns a copy of the current status, the lock must be held.
+func (v *chainVerifier) copyStatus() *ChainVerifyStatus {
+	status := *v.status
+	status.Mismatches = append([]core.RootMismatch{}, v.status.Mismatches...)
+	if status.Running {
+		status.Elapsed = common.PrettyDuration(time.Since(status.Started)).String()
+	}
+	return &status
+}
+
+func (v *chainVerifier) run(from, to uint64, reexec bool, abort chan struct{}) {
+	defer v.wg.Done()
+
+	var (
+		bc      = v.eth.blockchain
+		statedb *state.StateDB
+		parent  common.Hash // root of the referenced intermediate state
+		logged  = time.Now()
+		err     error
+	)
+	finish := func(err error) {
+		v.lock.Lock()
+		defer v.lock.Unlock()
+
+		if err != nil {
+			v.status.Error = err.Error()
+		}
+		v.status.Running = false
+		v.status.Elapsed = common.PrettyDuration(time.Since(v.status.Started)).String()
+		log.Info("Chain verification finished", "from", from, "to", to, "verified", uint64(v.status.Current)-from,
+			"mismatches", len(v.status.Mismatches), "elapsed", v.status.Elapsed, "err", err)
+	}
+	for number := from; number <= to; number++ {
+		select {
+		case <-abort:
+			finish(errors.New("aborted"))
+			return
+		default:
+		}
+		mismatches := bc.VerifyBlockRoots(number)
+
+		if reexec && number > 0 && len(mismatches) == 0 {
+			block := bc.GetBlockByNumber(number)
+			if statedb == nil {
+				if statedb, err = v.parentState(block); err != nil {
+					finish(err)
+					return
+				}
+				parent = common.Hash{}
+			}
+			var root common.Hash
+			if mismatches, root, err = v.reexecute(block, statedb); err != nil {
+				finish(err)
+				return
+			}
+			// Continue on the verified state, start over from the parent state of
+			// the next block on mismatches
+			if len(mismatches) > 0 {
+				statedb = nil
+			} else if statedb, err = state.New(root, statedb.Database(), nil); err != nil {
+				finish(fmt.Errorf("state reset after block %d failed: %v", number, err))
+				return
+			} else {
+				statedb.Database().TrieDB().Reference(root, common.Hash{})
+				if parent != (common.Hash{}) {
+					statedb.Database().TrieDB().Dereference(parent)
+				}
+				parent = root
+			}
+		}
+		v.lock.Lock()
+		v.status.Mismatches = append(v.status.Mismatches, mismatches...)
+		v.status.Current = hexutil.Uint64(number + 1)
+		v.lock.Unlock()
+
+		for _, mismatch := range mismatches {
+			log.Warn("Chain verification found mismatch", "number", mismatch.Number, "hash", mismatch.Hash, "root", mismatch.Root, "reason", mismatch.Reason)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying chain", "number", number, "to", to, "remaining", to-number)
+			logged = time.Now()
+		}
+	}
+	finish(nil)
+}
+
+// parentState returns the state of the parent of the given block in a database
+// isolated from the live one, re-executing blocks if needed.
+func (v *chainVerifier) parentState(block *types.Block) (*state.StateDB, error) {
+	parent := v.eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent of block %d missing", block.NumberU64())
+	}
+	return v.eth.StateAtBlock(parent, verifyReexec, nil, false, false)
+}
+
+// reexecute processes the block on its parent state, compares the resulting
+// state and receipt roots to the ones in the header and commits the state.
+func (v *chainVerifier) reexecute(block *types.Block, statedb *state.StateDB) ([]core.RootMismatch, common.Hash, error) {
+	var (
+		bc         = v.eth.blockchain
+		header     = block.Header()
+		mismatches []core.RootMismatch
+	)
+	report := func(root string, format string, args ...interface{}) {
+		mismatches = append(mismatches, core.RootMismatch{Root: root, Number: block.NumberU64(), Hash: block.Hash(), Reason: fmt.Sprintf(format, args...)})
+	}
+	receipts, _, _, _, err := bc.Processor().Process(block, statedb, vm.Config{})
+	if err != nil {
+		report(core.RootState, "processing failed: %v", err)
+		return mismatches, common.Hash{}, nil
+	}
+	if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != header.ReceiptHash[types.QuaiNetworkContext] {
+		report(core.RootReceipt, "re-executed %x, header %x", hash, header.ReceiptHash[types.QuaiNetworkContext])
+	}
+	root, err := statedb.Commit(bc.Config().DeleteEmptyAccounts(block.Number()))
+	if err != nil {
+		return nil, common.Hash{}, fmt.Errorf("state commit of block %d failed: %v", block.NumberU64(), err)
+	}
+	if root != header.Root[types.QuaiNetworkContext] {
+		report(core.RootState, "re-executed %x, header %x", root, header.Root[types.QuaiNetworkContext])
+	}
+	return mismatches, root, nil
+}
@@ -415,6 +415,13 @@ func (ec *Client) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header)
 	return ec.c.EthSubscribe(ctx, ch, "newHeads")
 }
 
+// SubscribeNewHeadWithBackfill subscribes to notifications about the headers
+// of the chain from the given block number, replaying the existing ones before
+// the new ones.
+func (ec *Client) SubscribeNewHeadWithBackfill(ctx context.Context, from uint64, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return ec.c.EthSubscribe(ctx, ch, "withBackfill", "newHeads", hexutil.Uint64(from))
+}
+
 // SubscribePendingBlock subscribes to notifications about the current pending block on the node.
 func (ec *Client) SubscribePendingBlock(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
 	return ec.c.EthSubscribe(ctx, ch, "pendingBlock")
@@ -512,6 +519,20 @@ func (ec *Client) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuer
 	return ec.c.EthSubscribe(ctx, ch, "logs", arg)
 }
 
+// SubscribeFilterLogsWithBackfill subscribes to the results of a streaming
+// filter query, replaying the matching logs of the chain from the given block
+// number before the new ones. The query must not have a block range.
+func (ec *Client) SubscribeFilterLogsWithBackfill(ctx context.Context, q ethereum.FilterQuery, from uint64, ch chan<- types.Log) (ethereum.Subscription, error) {
+	if q.BlockHash != nil || q.FromBlock != nil || q.ToBlock != nil {
+		return nil, fmt.Errorf("cannot specify a block range for a backfilling subscription")
+	}
+	arg := map[string]interface{}{
+		"address": q.Addresses,
+		"topics":  q.Topics,
+	}
+	return ec.c.EthSubscribe(ctx, ch, "withBackfill", "logs", hexutil.Uint64(from), arg)
+}
+
 func toFilterArg(q ethereum.FilterQuery) (interface{}, error) {
 	arg := map[string]interface{}{
 		"address": q.Addresses,
@@ -680,6 +701,17 @@ func (ec *Client) CheckPCRC(ctx context.Context, header *types.Header) (types.PC
 	return PCRCTermini, nil
 }
 
+// HeadersByRange returns up to count consecutive canonical headers starting at
+// the given number, with their total difficulties and difficulty orders, in a
+// single compressed response.
+func (ec *Client) HeadersByRange(ctx context.Context, from uint64, count uint64) ([]*ethapi.HeaderRangeEntry, error) {
+	var blob hexutil.Bytes
+	if err := ec.c.CallContext(ctx, &blob, "quai_getHeadersByRange", hexutil.Uint64(from), hexutil.Uint64(count), true); err != nil {
+		return nil, err
+	}
+	return ethapi.DecodeHeaderRange(blob)
+}
+
 // header: header in which the intended chain is to roll back to.
 // newHeaders: potentially now valid dominant headers to take out of nonCanonDom db.
 // oldHeaders: invalid dominant headers to insert into nonCanonDom db.
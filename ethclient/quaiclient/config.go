@@ -0,0 +1,118 @@
+package quaiclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/p2p/netutil"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// dialTimeout is the time allowed to establish a connection to the remote node.
+const dialTimeout = 30 * time.Second
+
+// Config contains the settings of the connection to a remote node. The zero
+// value connects directly with the default transport settings.
+type Config struct {
+	Proxy string `toml:",omitempty"` // SOCKS5 proxy URL to connect through
+
+	TLSCA   string `toml:",omitempty"` // PEM file of the CAs verifying the node, system roots if empty
+	TLSCert string `toml:",omitempty"` // PEM file of the certificate authenticating the client
+	TLSKey  string `toml:",omitempty"` // PEM file of the client certificate's key
+
+	KeepAlive    time.Duration `toml:",omitempty"` // TCP keepalive period, the OS default if zero
+	MaxIdleConns int           `toml:",omitempty"` // Idle HTTP connections kept per node, the default if zero
+	IdleTimeout  time.Duration `toml:",omitempty"` // Time idle HTTP connections are kept, unlimited if zero
+
+	CallTimeout time.Duration `toml:",omitempty"` // Deadline of a single call attempt, unlimited if zero
+	CallRetries int           `toml:",omitempty"` // Times a failed call is retried on failover clients
+	HedgeDelay  time.Duration `toml:",omitempty"` // Delay after which lookups are also sent to the next healthy node, disabled if zero
+}
+
+// dialer returns the function establishing a single RPC connection to rawurl
+// with the connection settings.
+func (c *Config) dialer(rawurl string) (func(ctx context.Context) (*rpc.Client, error), error) {
+	if c.defaultTransport() {
+		return func(ctx context.Context) (*rpc.Client, error) {
+			return rpc.DialContext(ctx, rawurl)
+		}, nil
+	}
+	transport, err := c.transport()
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context) (*rpc.Client, error) {
+		return rpc.DialContextWithTransport(ctx, rawurl, transport)
+	}, nil
+}
+
+// defaultTransport returns whether the connection settings are all defaults, the
+// call policy not affecting the connection.
+func (c *Config) defaultTransport() bool {
+	conn := *c
+	conn.CallTimeout, conn.CallRetries, conn.HedgeDelay = 0, 0, 0
+	return conn == Config{}
+}
+
+// tlsConfig creates the TLS configuration for https and wss endpoints, or nil
+// if the defaults are used.
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	if c.TLSCA == "" && c.TLSCert == "" && c.TLSKey == "" {
+		return nil, nil
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.TLSCA != "" {
+		pem, err := ioutil.ReadFile(c.TLSCA)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", c.TLSCA)
+		}
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return nil, errors.New("client certificate and key must be given together")
+	}
+	if c.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// transport creates the HTTP transport connecting to the remote node. It also
+// provides the dialer and TLS configuration of websocket connections.
+func (c *Config) transport() (*http.Transport, error) {
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: c.KeepAlive}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost: c.MaxIdleConns,
+		IdleConnTimeout:     c.IdleTimeout,
+		ForceAttemptHTTP2:   tlsConfig != nil,
+	}
+	if c.Proxy != "" {
+		proxy, err := netutil.ProxyDialer(c.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		transport.DialContext = proxy
+	}
+	return transport, nil
+}
@@ -0,0 +1,135 @@
+package quaiclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// writeTestCert creates a self-signed certificate for localhost and writes it
+// and its key as PEM files into dir.
+func writeTestCert(t *testing.T, dir, name string) (tls.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+// Tests that clients connect to nodes requiring mutual TLS over https and wss.
+func TestDialMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quaiclient-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	serverCert, serverCertFile, _ := writeTestCert(t, dir, "server")
+	clientCert, clientCertFile, clientKeyFile := writeTestCert(t, dir, "client")
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("quai", &testQuaiAPI{head: 7}); err != nil {
+		t.Fatal(err)
+	}
+	clientLeaf, err := x509.ParseCertificate(clientCert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientLeaf)
+	httpSrv := httptest.NewUnstartedServer(server)
+	httpSrv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	httpSrv.StartTLS()
+	defer httpSrv.Close()
+
+	wsSrv := httptest.NewUnstartedServer(server.WebsocketHandler([]string{"*"}))
+	wsSrv.TLS = httpSrv.TLS
+	wsSrv.StartTLS()
+	defer wsSrv.Close()
+
+	config := Config{TLSCA: serverCertFile, TLSCert: clientCertFile, TLSKey: clientKeyFile}
+	for _, url := range []string{httpSrv.URL, "wss" + strings.TrimPrefix(wsSrv.URL, "https")} {
+		client, err := DialContextWithConfig(context.Background(), url, config)
+		if err != nil {
+			t.Fatalf("%s: dial failed: %v", url, err)
+		}
+		var head hexutil.Uint64
+		if err := client.c.Call(&head, "quai_blockNumber"); err != nil {
+			t.Errorf("%s: call failed: %v", url, err)
+		} else if head != 7 {
+			t.Errorf("%s: head mismatch: have %d, want 7", url, head)
+		}
+		client.Close()
+	}
+	// Check that the node rejects clients without a certificate.
+	client, err := DialContextWithConfig(context.Background(), httpSrv.URL, Config{TLSCA: serverCertFile})
+	if err != nil {
+		t.Fatal("dial failed:", err)
+	}
+	defer client.Close()
+
+	var head hexutil.Uint64
+	if err := client.c.Call(&head, "quai_blockNumber"); err == nil {
+		t.Error("call without client certificate succeeded")
+	}
+}
+
+func TestConfigTLSKeyPair(t *testing.T) {
+	if _, err := (&Config{TLSCert: "client.crt"}).transport(); err == nil {
+		t.Error("certificate without key accepted")
+	}
+}
@@ -0,0 +1,172 @@
+// Package embedded runs a Quai node inside a Go program. The program can
+// register its own services and APIs on the node and access its chain and
+// transaction pool directly, or over an in-process RPC client.
+package embedded
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/eth"
+	"github.com/spruce-solutions/go-quai/eth/ethconfig"
+	"github.com/spruce-solutions/go-quai/eth/tracers"
+	"github.com/spruce-solutions/go-quai/ethclient/quaiclient"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// Chain is the view of the blockchain of the node exposed to the program.
+type Chain interface {
+	Config() *params.ChainConfig
+	CurrentBlock() *types.Block
+	GetBlockByHash(hash common.Hash) *types.Block
+	GetBlockByNumber(number uint64) *types.Block
+	GetHeaderByHash(hash common.Hash) *types.Header
+	GetHeaderByNumber(number uint64) *types.Header
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+	State() (*state.StateDB, error)
+	StateAt(root common.Hash) (*state.StateDB, error)
+
+	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+	SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription
+	SubscribeChainEventByOrder(ch chan<- core.ChainEvent, maxOrder int) event.Subscription
+	SubscribeChainHeadEventByOrder(ch chan<- core.ChainHeadEvent, maxOrder int) event.Subscription
+	SubscribeChainSideEventByOrder(ch chan<- core.ChainSideEvent, maxOrder int) event.Subscription
+}
+
+// TxPool is the view of the transaction pool of the node exposed to the
+// program.
+type TxPool interface {
+	AddLocal(tx *types.Transaction) error
+	Get(hash common.Hash) *types.Transaction
+	Has(hash common.Hash) bool
+	Nonce(addr common.Address) uint64
+	Pending(enforceTips bool) (map[common.Address]types.Transactions, error)
+	Stats() (int, int)
+
+	SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription
+}
+
+var (
+	_ Chain  = (*core.BlockChain)(nil)
+	_ TxPool = (*core.TxPool)(nil)
+)
+
+// Config is the configuration of an embedded node.
+type Config struct {
+	Node node.Config
+	Eth  ethconfig.Config
+}
+
+// ZoneConfig returns the default configuration of a node of the zone, on the
+// mainnet or, if testnet is set, on the Ropsten test network. Regions and
+// zones are numbered from 1. The node connects to its region node at
+// Eth.DomUrl, which defaults to the local one.
+func ZoneConfig(region, zone int, testnet bool) (*Config, error) {
+	configs := params.MainnetZoneChainConfigs
+	if testnet {
+		configs = params.RopstenZoneChainConfigs
+	}
+	if region < 1 || region > len(configs) || zone < 1 || zone > len(configs[region-1]) {
+		return nil, fmt.Errorf("unknown zone %d-%d", region, zone)
+	}
+	chainConfig := &configs[region-1][zone-1]
+
+	config := &Config{Node: node.DefaultConfig, Eth: ethconfig.Defaults}
+	config.Node.Name = "quai"
+	config.Eth.Region, config.Eth.Zone = region, zone
+	config.Eth.NetworkId = chainConfig.ChainID.Uint64()
+	if testnet {
+		config.Eth.Genesis = core.RopstenZoneGenesisBlock(chainConfig)
+	} else {
+		config.Eth.Genesis = core.MainnetZoneGenesisBlock(chainConfig)
+	}
+	return config, nil
+}
+
+// Node is a Quai node embedded in the program.
+type Node struct {
+	stack   *node.Node
+	backend *eth.Ethereum
+}
+
+// New creates the node with the configuration. The program registers its
+// services and APIs before starting it.
+//
+// The context of the chain is process wide, a program can only embed nodes of
+// a single context.
+func New(config *Config) (*Node, error) {
+	if config.Eth.Genesis == nil {
+		return nil, errors.New("no genesis configured")
+	}
+	types.QuaiNetworkContext = config.Eth.Genesis.Config.Context
+
+	stack, err := node.New(&config.Node)
+	if err != nil {
+		return nil, err
+	}
+	backend, err := eth.New(stack, &config.Eth)
+	if err != nil {
+		stack.Close()
+		return nil, err
+	}
+	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+	return &Node{stack: stack, backend: backend}, nil
+}
+
+// RegisterLifecycle registers a service started and stopped with the node.
+func (n *Node) RegisterLifecycle(lifecycle node.Lifecycle) {
+	n.stack.RegisterLifecycle(lifecycle)
+}
+
+// RegisterAPIs registers APIs served by the node along with its own.
+func (n *Node) RegisterAPIs(apis []rpc.API) {
+	n.stack.RegisterAPIs(apis)
+}
+
+// Start starts the node and the registered services.
+func (n *Node) Start() error {
+	return n.stack.Start()
+}
+
+// Close stops the node and the registered services and releases its resources.
+func (n *Node) Close() error {
+	return n.stack.Close()
+}
+
+// Wait blocks until the node is closed.
+func (n *Node) Wait() {
+	n.stack.Wait()
+}
+
+// Stack returns the underlying node, for the settings not covered here.
+func (n *Node) Stack() *node.Node {
+	return n.stack
+}
+
+// Chain returns the blockchain of the node.
+func (n *Node) Chain() Chain {
+	return n.backend.BlockChain()
+}
+
+// TxPool returns the transaction pool of the node.
+func (n *Node) TxPool() TxPool {
+	return n.backend.TxPool()
+}
+
+// Client returns a client of the node served in process, including the APIs
+// registered by the program. The node must be started.
+func (n *Node) Client() (*quaiclient.Client, error) {
+	client, err := n.stack.Attach()
+	if err != nil {
+		return nil, err
+	}
+	return quaiclient.NewClient(client), nil
+}
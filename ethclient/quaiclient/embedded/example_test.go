@@ -0,0 +1,67 @@
+package embedded_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/ethclient/quaiclient/embedded"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// HeadService is a custom API served by the embedded node next to its own.
+type HeadService struct {
+	chain embedded.Chain
+}
+
+// Number returns the number of the head block.
+func (s *HeadService) Number() uint64 {
+	return s.chain.CurrentBlock().NumberU64()
+}
+
+// Example embeds a node of zone 1-1, registers a custom API and follows the
+// blocks coincident with Prime.
+func Example() {
+	config, err := embedded.ZoneConfig(1, 1, false)
+	if err != nil {
+		log.Fatalf("Failed to configure the zone: %v", err)
+	}
+	config.Node.DataDir = "zone-1-1"
+	config.Eth.DomUrl = "ws://127.0.0.1:8547"
+
+	stack, err := embedded.New(config)
+	if err != nil {
+		log.Fatalf("Failed to create the node: %v", err)
+	}
+	defer stack.Close()
+
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "head",
+		Version:   "1.0",
+		Service:   &HeadService{chain: stack.Chain()},
+		Public:    true,
+	}})
+	if err := stack.Start(); err != nil {
+		log.Fatalf("Failed to start the node: %v", err)
+	}
+	client, err := stack.Client()
+	if err != nil {
+		log.Fatalf("Failed to attach to the node: %v", err)
+	}
+	head, err := client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		log.Fatalf("Failed to retrieve the head: %v", err)
+	}
+	fmt.Println("head", head.Number)
+
+	events := make(chan core.ChainHeadEvent, 16)
+	sub := stack.Chain().SubscribeChainHeadEventByOrder(events, params.PRIME)
+	defer sub.Unsubscribe()
+
+	for ev := range events {
+		pending, _ := stack.TxPool().Stats()
+		fmt.Println("prime coincident block", ev.Block.NumberU64(), "pending txs", pending)
+	}
+}
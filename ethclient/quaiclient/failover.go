@@ -0,0 +1,235 @@
+package quaiclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/log"
+)
+
+const (
+	// failoverCheckInterval is the interval the endpoints are health checked at.
+	failoverCheckInterval = 3 * time.Second
+
+	// failoverCheckTimeout is the time an endpoint has to answer a health check
+	// or to accept a connection.
+	failoverCheckTimeout = 2 * time.Second
+)
+
+// EndpointStatus is the health of an endpoint of a failover client.
+type EndpointStatus struct {
+	URL       string         `json:"url"`
+	Active    bool           `json:"active"`              // Whether calls are routed to the endpoint
+	Healthy   bool           `json:"healthy"`             // Whether the last health check succeeded
+	Head      hexutil.Uint64 `json:"head"`                // Head block number reported by the last check
+	Latency   string         `json:"latency"`             // Response time of the last check
+	Failures  int            `json:"failures"`            // Consecutive failed checks
+	Breaker   string         `json:"breaker"`             // State of the circuit breaker of calls
+	LastCheck time.Time      `json:"lastCheck"`           // Time of the last check
+	LastError string         `json:"lastError,omitempty"` // Error of the last failed check
+}
+
+// failoverEndpoint is an endpoint of a failover client.
+type failoverEndpoint struct {
+	dial    func(ctx context.Context) (*Client, error)
+	client  *Client // Connection to the endpoint, nil if not connected
+	status  EndpointStatus
+	breaker breaker
+}
+
+// Failover is a client of a set of equivalent endpoints, routing calls to the
+// first healthy one in the configured order. The endpoints are health checked
+// periodically, so calls fail over when the active endpoint goes down and fail
+// back once an endpoint preferred over it recovers. Calls made through the
+// failover itself also follow the call policy of the config.
+type Failover struct {
+	config    Config
+	endpoints []*failoverEndpoint
+	active    int // Index of the endpoint calls are routed to
+	lock      sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// DialFailover connects a failover client to the given URLs, in the order of
+// preference. It blocks until one of the endpoints is reachable or the context
+// is cancelled.
+func DialFailover(ctx context.Context, rawurls []string, config Config) (*Failover, error) {
+	if len(rawurls) == 0 {
+		return nil, errors.New("no endpoints to connect to")
+	}
+	f := &Failover{config: config, quit: make(chan struct{})}
+	for _, rawurl := range rawurls {
+		dial, err := config.dialer(rawurl)
+		if err != nil {
+			return nil, err
+		}
+		f.endpoints = append(f.endpoints, &failoverEndpoint{
+			dial: func(ctx context.Context) (*Client, error) {
+				c, err := dial(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return NewClient(c), nil
+			},
+			status: EndpointStatus{URL: rawurl},
+		})
+	}
+	for attempts := 1; ; attempts++ {
+		if f.check() {
+			break
+		}
+		log.Warn("Attempting to connect to go-quai nodes. Waiting and retrying...", "endpoints", len(rawurls), "attempts", attempts)
+		select {
+		case <-ctx.Done():
+			f.close()
+			return nil, ctx.Err()
+		case <-time.After(failoverCheckInterval):
+		}
+	}
+	f.wg.Add(1)
+	go f.loop()
+	return f, nil
+}
+
+// Client returns the client of the active endpoint. If no endpoint is healthy,
+// the last active one is returned and calls fail until an endpoint recovers.
+func (f *Failover) Client() *Client {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	return f.endpoints[f.active].client
+}
+
+// Status returns the health of the endpoints.
+func (f *Failover) Status() []EndpointStatus {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	var (
+		now    = time.Now()
+		status = make([]EndpointStatus, len(f.endpoints))
+	)
+	for i, endpoint := range f.endpoints {
+		status[i] = endpoint.status
+		status[i].Active = i == f.active
+		status[i].Breaker = endpoint.breaker.state(now)
+	}
+	return status
+}
+
+// Close stops the health checks and closes the connections to all endpoints.
+func (f *Failover) Close() {
+	close(f.quit)
+	f.wg.Wait()
+	f.close()
+}
+
+// close closes the connections to all endpoints.
+func (f *Failover) close() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for _, endpoint := range f.endpoints {
+		if endpoint.client != nil {
+			endpoint.client.Close()
+			endpoint.client = nil
+		}
+	}
+}
+
+func (f *Failover) loop() {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(failoverCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.check()
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// check health checks all endpoints concurrently and switches calls to the
+// first healthy one. It returns whether any endpoint is healthy.
+func (f *Failover) check() bool {
+	var wg sync.WaitGroup
+	for _, endpoint := range f.endpoints {
+		wg.Add(1)
+		go func(endpoint *failoverEndpoint) {
+			defer wg.Done()
+			f.checkEndpoint(endpoint)
+		}(endpoint)
+	}
+	wg.Wait()
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for i, endpoint := range f.endpoints {
+		if !endpoint.status.Healthy {
+			continue
+		}
+		if i != f.active {
+			log.Warn("Switching go-quai node endpoint", "old", f.endpoints[f.active].status.URL, "new", endpoint.status.URL)
+			f.active = i
+		}
+		return true
+	}
+	return false
+}
+
+// checkEndpoint connects to the endpoint if needed and queries its head,
+// updating its health.
+func (f *Failover) checkEndpoint(endpoint *failoverEndpoint) {
+	ctx, cancel := context.WithTimeout(context.Background(), failoverCheckTimeout)
+	defer cancel()
+
+	f.lock.RLock()
+	client := endpoint.client
+	f.lock.RUnlock()
+
+	var (
+		start = time.Now()
+		head  hexutil.Uint64
+		err   error
+	)
+	if client == nil {
+		client, err = endpoint.dial(ctx)
+	}
+	if err == nil {
+		err = client.c.CallContext(ctx, &head, "quai_blockNumber")
+	}
+	if err != nil && client != nil {
+		// Drop the connection, websocket connections aren't reestablished
+		client.Close()
+		client = nil
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	endpoint.client = client
+	endpoint.status.LastCheck = time.Now()
+	endpoint.status.Latency = time.Since(start).String()
+	if err != nil {
+		if endpoint.status.Healthy {
+			log.Warn("Go-quai node endpoint unhealthy", "url", endpoint.status.URL, "err", err)
+		}
+		endpoint.status.Healthy = false
+		endpoint.status.Failures++
+		endpoint.status.LastError = err.Error()
+		return
+	}
+	endpoint.status.Healthy = true
+	endpoint.status.Head = head
+	endpoint.status.Failures = 0
+	endpoint.status.LastError = ""
+}
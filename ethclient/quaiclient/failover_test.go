@@ -0,0 +1,89 @@
+package quaiclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// newTestFailoverServer starts an http node serving the given head, failing
+// all requests while down is set.
+func newTestFailoverServer(t *testing.T, head uint64, down *int32) *httptest.Server {
+	server := rpc.NewServer()
+	if err := server.RegisterName("quai", &testQuaiAPI{head: head}); err != nil {
+		t.Fatal(err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(down) == 1 {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}))
+}
+
+// Tests that calls fail over to the next healthy endpoint and fail back once
+// the preferred one recovers.
+func TestFailover(t *testing.T) {
+	var down1, down2 int32
+	atomic.StoreInt32(&down1, 1)
+
+	srv1 := newTestFailoverServer(t, 1, &down1)
+	defer srv1.Close()
+	srv2 := newTestFailoverServer(t, 2, &down2)
+	defer srv2.Close()
+
+	f, err := DialFailover(context.Background(), []string{srv1.URL, srv2.URL}, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	checkActive := func(want int) {
+		t.Helper()
+		status := f.Status()
+		for i, s := range status {
+			if s.Active != (i == want) {
+				t.Fatalf("endpoint %d active mismatch: have %v, want %v", i, s.Active, i == want)
+			}
+		}
+		if !status[want].Healthy {
+			t.Fatalf("active endpoint %d unhealthy", want)
+		}
+		var head hexutil.Uint64
+		if err := f.Client().c.CallContext(context.Background(), &head, "quai_blockNumber"); err != nil {
+			t.Fatal(err)
+		}
+		if head != hexutil.Uint64(want+1) {
+			t.Fatalf("call served by wrong endpoint: have head %d, want %d", head, want+1)
+		}
+	}
+	// The preferred endpoint is down, calls should go to the second one
+	checkActive(1)
+	if status := f.Status(); status[0].Healthy || status[0].Failures == 0 || status[0].LastError == "" {
+		t.Fatalf("down endpoint reported healthy: %+v", status[0])
+	}
+	// Recover the preferred endpoint, calls should fail back
+	atomic.StoreInt32(&down1, 0)
+	f.check()
+	checkActive(0)
+
+	// Take it down again, calls should fail over
+	atomic.StoreInt32(&down1, 1)
+	f.check()
+	checkActive(1)
+
+	// With no healthy endpoint the last active one is kept
+	atomic.StoreInt32(&down2, 1)
+	if f.check() {
+		t.Fatal("check succeeded with all endpoints down")
+	}
+	if status := f.Status(); !status[1].Active {
+		t.Fatal("active endpoint changed with all endpoints down")
+	}
+}
@@ -0,0 +1,269 @@
+package quaiclient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	quai "github.com/spruce-solutions/go-quai"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+const (
+	// callRetryBackoff is the base delay before retrying a failed call, doubled
+	// on every retry and jittered.
+	callRetryBackoff = 200 * time.Millisecond
+
+	// breakerThreshold is the number of consecutive failed calls opening the
+	// circuit breaker of an endpoint.
+	breakerThreshold = 5
+
+	// breakerCooldown is the time calls skip an endpoint with an open circuit
+	// breaker before it's tried again.
+	breakerCooldown = 30 * time.Second
+)
+
+var (
+	callMeter           = metrics.NewRegisteredMeter("quaiclient/calls", nil)
+	callFailureMeter    = metrics.NewRegisteredMeter("quaiclient/calls/failures", nil)
+	callTimeoutMeter    = metrics.NewRegisteredMeter("quaiclient/calls/timeouts", nil)
+	callRetryMeter      = metrics.NewRegisteredMeter("quaiclient/calls/retries", nil)
+	callHedgeMeter      = metrics.NewRegisteredMeter("quaiclient/calls/hedges", nil)
+	breakerOpenMeter    = metrics.NewRegisteredMeter("quaiclient/breaker/opens", nil)
+	breakerRejectMeter  = metrics.NewRegisteredMeter("quaiclient/breaker/rejects", nil)
+	breakerOpenGauge    = metrics.NewRegisteredGauge("quaiclient/breaker/open", nil)
+	errNoCallableClient = errors.New("no go-quai node endpoint available")
+)
+
+// breaker is the circuit breaker of an endpoint, skipping it in calls for a
+// while after repeated failures.
+type breaker struct {
+	failures  int       // Consecutive failed calls
+	openUntil time.Time // Time calls are skipping the endpoint until
+}
+
+// state returns the name of the breaker state.
+func (b *breaker) state(now time.Time) string {
+	switch {
+	case b.failures < breakerThreshold:
+		return "closed"
+	case now.Before(b.openUntil):
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+// callTarget is an endpoint a call is sent to.
+type callTarget struct {
+	endpoint *failoverEndpoint
+	client   *Client
+}
+
+// GetBlockStatus queries the status of the header on the dominant chain. The
+// lookup is hedged, NonStatTy and the error being returned if all endpoints
+// failed.
+func (f *Failover) GetBlockStatus(ctx context.Context, header *types.Header) (WriteStatus, error) {
+	var status WriteStatus
+	err := f.call(ctx, true, func(ctx context.Context, c *Client) error {
+		return c.c.CallContext(ctx, &status, "quai_getBlockStatus", header)
+	})
+	if err != nil {
+		return NonStatTy, err
+	}
+	return status, nil
+}
+
+// HeaderByHash looks up the header with the given hash on the dominant chain.
+// The lookup is hedged, quai.NotFound being returned if the header is unknown.
+func (f *Failover) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	var head *types.Header
+	err := f.call(ctx, true, func(ctx context.Context, c *Client) error {
+		return c.c.CallContext(ctx, &head, "quai_getBlockByHash", hash, false)
+	})
+	if err == nil && head == nil {
+		err = quai.NotFound
+	}
+	return head, err
+}
+
+// HLCRReorg asks the dominant chain whether it reorgs to the block. As the call
+// may reorg the node, it's retried but never hedged.
+func (f *Failover) HLCRReorg(ctx context.Context, block *types.Block) (bool, error) {
+	data, err := RPCMarshalBlock(block, true, true)
+	if err != nil {
+		return false, err
+	}
+	var domReorgNeeded bool
+	err = f.call(ctx, false, func(ctx context.Context, c *Client) error {
+		return c.c.CallContext(ctx, &domReorgNeeded, "quai_hLCRReorg", data)
+	})
+	return domReorgNeeded, err
+}
+
+// call runs fn against the endpoints under the call policy: every attempt has
+// the call timeout, failed attempts are retried after a jittered backoff and,
+// if hedge is set, an attempt is also sent to the next endpoint if the first
+// doesn't answer within the hedge delay. Errors returned by the node itself
+// are not retried.
+func (f *Failover) call(ctx context.Context, hedge bool, fn func(ctx context.Context, c *Client) error) error {
+	var err error
+	for attempt := 0; attempt <= f.config.CallRetries; attempt++ {
+		if attempt > 0 {
+			callRetryMeter.Mark(1)
+
+			backoff := callRetryBackoff << (attempt - 1)
+			backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff)))
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		err = f.attempt(ctx, hedge, fn)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		var rpcErr rpc.Error
+		if errors.As(err, &rpcErr) {
+			return err
+		}
+	}
+	return err
+}
+
+// attempt sends the call to the first callable endpoint, hedging it to the
+// second one if requested.
+func (f *Failover) attempt(ctx context.Context, hedge bool, fn func(ctx context.Context, c *Client) error) error {
+	targets := f.targets()
+	if len(targets) == 0 {
+		breakerRejectMeter.Mark(1)
+		return errNoCallableClient
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		results = make(chan error, 2)
+		pending int
+		hedged  <-chan time.Time
+	)
+	send := func(target callTarget) {
+		pending++
+		go func() { results <- f.try(ctx, target, fn) }()
+	}
+	send(targets[0])
+	if hedge && f.config.HedgeDelay > 0 && len(targets) > 1 {
+		timer := time.NewTimer(f.config.HedgeDelay)
+		defer timer.Stop()
+		hedged = timer.C
+	}
+	var err error
+	for pending > 0 {
+		select {
+		case <-hedged:
+			hedged = nil
+			callHedgeMeter.Mark(1)
+			send(targets[1])
+
+		case err = <-results:
+			pending--
+			if err == nil {
+				return nil
+			}
+			// Don't wait for the hedge delay if the first endpoint failed
+			if hedged != nil {
+				hedged = nil
+				send(targets[1])
+			}
+		}
+	}
+	return err
+}
+
+// try runs a single attempt of the call against the target, recording the
+// outcome in its circuit breaker.
+func (f *Failover) try(ctx context.Context, target callTarget, fn func(ctx context.Context, c *Client) error) error {
+	callCtx := ctx
+	if f.config.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, f.config.CallTimeout)
+		defer cancel()
+	}
+	callMeter.Mark(1)
+	err := fn(callCtx, target.client)
+
+	// Attempts cancelled by the caller or by a hedged attempt winning don't
+	// count against the endpoint
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+	var rpcErr rpc.Error
+	if err != nil && errors.As(err, &rpcErr) {
+		f.record(target.endpoint, nil)
+		return err
+	}
+	if err != nil {
+		callFailureMeter.Mark(1)
+		if errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			callTimeoutMeter.Mark(1)
+		}
+	}
+	f.record(target.endpoint, err)
+	return err
+}
+
+// targets returns the endpoints calls can be sent to in the order of preference:
+// the active endpoint, then the other healthy ones, skipping open breakers.
+func (f *Failover) targets() []callTarget {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	var (
+		now     = time.Now()
+		targets []callTarget
+	)
+	add := func(endpoint *failoverEndpoint) {
+		if endpoint.client != nil && endpoint.breaker.state(now) != "open" {
+			targets = append(targets, callTarget{endpoint: endpoint, client: endpoint.client})
+		}
+	}
+	add(f.endpoints[f.active])
+	for i, endpoint := range f.endpoints {
+		if i != f.active && endpoint.status.Healthy {
+			add(endpoint)
+		}
+	}
+	return targets
+}
+
+// record updates the circuit breaker of the endpoint with the outcome of a call.
+func (f *Failover) record(endpoint *failoverEndpoint, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	b := &endpoint.breaker
+	if err == nil {
+		if b.failures >= breakerThreshold {
+			log.Info("Go-quai node endpoint circuit closed", "url", endpoint.status.URL)
+			breakerOpenGauge.Dec(1)
+		}
+		b.failures, b.openUntil = 0, time.Time{}
+		return
+	}
+	b.failures++
+	if b.failures < breakerThreshold || time.Now().Before(b.openUntil) {
+		return
+	}
+	if b.failures == breakerThreshold {
+		breakerOpenGauge.Inc(1)
+	}
+	log.Warn("Go-quai node endpoint circuit opened", "url", endpoint.status.URL, "failures", b.failures, "err", err)
+	breakerOpenMeter.Mark(1)
+	b.openUntil = time.Now().Add(breakerCooldown)
+}
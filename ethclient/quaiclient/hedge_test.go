@@ -0,0 +1,106 @@
+package quaiclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// newTestSlowServer starts an http node serving the given head, delaying the
+// answers of all but the first request by the given time.
+func newTestSlowServer(t *testing.T, head uint64, delay time.Duration) *httptest.Server {
+	server := rpc.NewServer()
+	if err := server.RegisterName("quai", &testQuaiAPI{head: head}); err != nil {
+		t.Fatal(err)
+	}
+	var requests int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		server.ServeHTTP(w, r)
+	}))
+}
+
+func testBlockNumber(f *Failover, hedge bool) (uint64, error) {
+	var head hexutil.Uint64
+	err := f.call(context.Background(), hedge, func(ctx context.Context, c *Client) error {
+		return c.c.CallContext(ctx, &head, "quai_blockNumber")
+	})
+	return uint64(head), err
+}
+
+// Tests that slow lookups are hedged to the next healthy endpoint.
+func TestFailoverHedge(t *testing.T) {
+	slow := newTestSlowServer(t, 1, time.Second)
+	defer slow.Close()
+	fast := newTestSlowServer(t, 2, 0)
+	defer fast.Close()
+
+	f, err := DialFailover(context.Background(), []string{slow.URL, fast.URL}, Config{HedgeDelay: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	start := time.Now()
+	head, err := testBlockNumber(f, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head != 2 {
+		t.Fatalf("hedged call answered by wrong endpoint: have head %d, want 2", head)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("hedged call took too long: %v", elapsed)
+	}
+	// The answer of the slow endpoint must not count against it
+	if status := f.Status(); status[0].Breaker != "closed" {
+		t.Fatalf("slow endpoint breaker %s, want closed", status[0].Breaker)
+	}
+}
+
+// Tests that calls time out, are retried and open the circuit breaker of the
+// endpoint after repeated failures.
+func TestFailoverBreaker(t *testing.T) {
+	slow := newTestSlowServer(t, 1, time.Second)
+	defer slow.Close()
+
+	f, err := DialFailover(context.Background(), []string{slow.URL}, Config{CallTimeout: 20 * time.Millisecond, CallRetries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for i := 0; i < breakerThreshold/2; i++ {
+		if _, err := testBlockNumber(f, false); err == nil {
+			t.Fatal("slow call didn't time out")
+		}
+	}
+	if status := f.Status(); status[0].Breaker != "closed" {
+		t.Fatalf("breaker %s after %d failures, want closed", status[0].Breaker, 2*(breakerThreshold/2))
+	}
+	if _, err := testBlockNumber(f, false); err == nil {
+		t.Fatal("slow call didn't time out")
+	}
+	if status := f.Status(); status[0].Breaker != "open" {
+		t.Fatalf("breaker %s after repeated failures, want open", status[0].Breaker)
+	}
+	start := time.Now()
+	if _, err := testBlockNumber(f, false); err != errNoCallableClient {
+		t.Fatalf("call with open breaker: have error %v, want %v", err, errNoCallableClient)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("call with open breaker took too long: %v", elapsed)
+	}
+}
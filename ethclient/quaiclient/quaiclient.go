@@ -28,6 +28,7 @@ import (
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/common/hexutil"
 	"github.com/spruce-solutions/go-quai/common/math"
+	"github.com/spruce-solutions/go-quai/consensus"
 	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/log"
 	"github.com/spruce-solutions/go-quai/rpc"
@@ -83,13 +84,22 @@ func Dial(rawurl string) (*Client, error) {
 }
 
 func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	return DialContextWithConfig(ctx, rawurl, Config{})
+}
+
+// DialContextWithConfig connects a client to the given URL with the given
+// connection settings.
+func DialContextWithConfig(ctx context.Context, rawurl string, config Config) (*Client, error) {
+	dial, err := config.dialer(rawurl)
+	if err != nil {
+		return nil, err
+	}
 	connectStatus := false
 	attempts := 0
 
 	var c *rpc.Client
-	var err error
 	for !connectStatus {
-		c, err = rpc.DialContext(ctx, rawurl)
+		c, err = dial(ctx)
 		if err == nil {
 			break
 		}
@@ -192,6 +202,25 @@ func (ec *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.H
 	return head, err
 }
 
+// HeaderByHash returns the block header with the given hash.
+func (ec *Client) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	var head *types.Header
+	err := ec.c.CallContext(ctx, &head, "quai_getBlockByHash", hash, false)
+	if err == nil && head == nil {
+		err = quai.NotFound
+	}
+	return head, err
+}
+
+// SendTransaction injects a signed transaction into the pending pool of the node.
+func (ec *Client) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return ec.c.CallContext(ctx, nil, "quai_sendRawTransaction", hexutil.Encode(data))
+}
+
 // SendMinedBlock sends a mined block back to the node
 func (ec *Client) SendMinedBlock(ctx context.Context, block *types.Block, inclTx bool, fullTx bool) error {
 	data, err := RPCMarshalBlock(block, inclTx, fullTx)
@@ -250,7 +279,7 @@ func (ec *Client) CheckPCRC(ctx context.Context, header *types.Header, order int
 
 	var PCRCTermini types.PCRCTermini
 	if err := ec.c.CallContext(ctx, &PCRCTermini, "quai_checkPCRC", data); err != nil {
-		return types.PCRCTermini{}, err
+		return types.PCRCTermini{}, sliceCheckError(err)
 	}
 	return PCRCTermini, nil
 }
@@ -262,11 +291,36 @@ func (ec *Client) CheckPCCRC(ctx context.Context, header *types.Header, order in
 
 	var PCCRCTermini types.PCRCTermini
 	if err := ec.c.CallContext(ctx, &PCCRCTermini, "quai_checkPCCRC", data); err != nil {
-		return types.PCRCTermini{}, err
+		return types.PCRCTermini{}, sliceCheckError(err)
 	}
 	return PCCRCTermini, nil
 }
 
+// sliceError is a slice check failure reported by the node, matching the error
+// of its reason.
+type sliceError struct {
+	err    error
+	reason error
+}
+
+func (e *sliceError) Error() string        { return e.err.Error() }
+func (e *sliceError) Unwrap() error        { return e.err }
+func (e *sliceError) Is(target error) bool { return target == e.reason }
+
+// sliceCheckError restores the error of a slice check failure from the reason
+// the node reported along.
+func sliceCheckError(err error) error {
+	dataErr, ok := err.(rpc.DataError)
+	if !ok {
+		return err
+	}
+	reason, _ := dataErr.ErrorData().(string)
+	if sentinel := consensus.ReasonError(reason); sentinel != nil {
+		return &sliceError{err: err, reason: sentinel}
+	}
+	return err
+}
+
 func (ec *Client) getExternalBlock(ctx context.Context, method string, args ...interface{}) (*types.ExternalBlock, error) {
 	var raw json.RawMessage
 	err := ec.c.CallContext(ctx, &raw, method, args...)
@@ -338,6 +392,9 @@ func RPCMarshalHeader(head *types.Header) map[string]interface{} {
 	if head.BaseFee != nil {
 		result["baseFeePerGas"] = head.BaseFee
 	}
+	if head.EtxRollupHash != nil {
+		result["etxRollupHash"] = head.EtxRollupHash
+	}
 
 	return result
 }
@@ -472,6 +529,7 @@ type RPCTransaction struct {
 	Type             hexutil.Uint64    `json:"type"`
 	Accesses         *types.AccessList `json:"accessList,omitempty"`
 	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
+	ValidUntil       *hexutil.Uint64   `json:"validUntil,omitempty"`
 	V                *hexutil.Big      `json:"v"`
 	R                *hexutil.Big      `json:"r"`
 	S                *hexutil.Big      `json:"s"`
@@ -516,7 +574,7 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		al := tx.AccessList()
 		result.Accesses = &al
 		result.ChainID = (*hexutil.Big)(tx.ChainId())
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.ExpiringTxType, types.ReviveTxType:
 		al := tx.AccessList()
 		result.Accesses = &al
 		result.ChainID = (*hexutil.Big)(tx.ChainId())
@@ -530,6 +588,9 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		} else {
 			result.GasPrice = (*hexutil.Big)(tx.GasFeeCap())
 		}
+		if validUntil := tx.ValidUntil(); validUntil != 0 {
+			result.ValidUntil = (*hexutil.Uint64)(&validUntil)
+		}
 	}
 	return result
 }
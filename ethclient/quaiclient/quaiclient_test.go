@@ -0,0 +1,67 @@
+package quaiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// testCheckError is a slice check failure as reported by the node.
+type testCheckError struct {
+	msg    string
+	reason string
+}
+
+func (e *testCheckError) Error() string          { return e.msg }
+func (e *testCheckError) ErrorData() interface{} { return e.reason }
+
+// testSliceAPI fails the slice checks with the given reason.
+type testSliceAPI struct {
+	reason string
+}
+
+func (api *testSliceAPI) CheckPCRC(ctx context.Context, raw json.RawMessage) (types.PCRCTermini, error) {
+	return types.PCRCTermini{}, &testCheckError{msg: "check failed", reason: api.reason}
+}
+
+// Tests that the errors of slice check failures are restored from the reasons
+// reported by the node.
+func TestSliceCheckError(t *testing.T) {
+	tests := []struct {
+		reason string
+		want   error
+	}{
+		{consensus.ReasonSliceNotSynced, consensus.ErrSliceNotSynced},
+		{consensus.ReasonNonCanonicalDom, consensus.ErrNonCanonicalDom},
+		{consensus.ReasonTwist, consensus.ErrTwist},
+		{"unknown", nil},
+	}
+	for _, tt := range tests {
+		server := rpc.NewServer()
+		if err := server.RegisterName("quai", &testSliceAPI{reason: tt.reason}); err != nil {
+			t.Fatal(err)
+		}
+		client := NewClient(rpc.DialInProc(server))
+
+		_, err := client.CheckPCRC(context.Background(), types.NewEmptyHeader(), 0)
+		if err == nil {
+			t.Fatalf("reason %q: check succeeded", tt.reason)
+		}
+		for _, sentinel := range []error{consensus.ErrSliceNotSynced, consensus.ErrNonCanonicalDom, consensus.ErrTwist} {
+			if have, want := errors.Is(err, sentinel), sentinel == tt.want; have != want {
+				t.Errorf("reason %q: errors.Is(%v) = %v, want %v", tt.reason, sentinel, have, want)
+			}
+		}
+		var rpcErr rpc.Error
+		if !errors.As(err, &rpcErr) {
+			t.Errorf("reason %q: node error lost: %v", tt.reason, err)
+		}
+		client.Close()
+		server.Stop()
+	}
+}
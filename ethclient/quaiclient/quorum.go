@@ -0,0 +1,234 @@
+package quaiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	quai "github.com/spruce-solutions/go-quai"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// ErrNoQuorum is returned when not enough endpoints agree on an answer.
+var ErrNoQuorum = errors.New("no quorum")
+
+// QuorumClient fans read requests out to several endpoints and only returns an
+// answer a quorum of them agrees on. State reads are pinned to a block hash the
+// quorum agrees on first, protecting against a single compromised or forked
+// RPC provider.
+type QuorumClient struct {
+	clients []*Client
+	quorum  int
+}
+
+// DialQuorum connects a quorum client to the given URLs, requiring quorum of
+// them to agree on every answer.
+func DialQuorum(ctx context.Context, rawurls []string, quorum int) (*QuorumClient, error) {
+	clients := make([]*Client, 0, len(rawurls))
+	for _, rawurl := range rawurls {
+		c, err := rpc.DialContext(ctx, rawurl)
+		if err != nil {
+			for _, client := range clients {
+				client.Close()
+			}
+			return nil, fmt.Errorf("dial %s: %v", rawurl, err)
+		}
+		clients = append(clients, NewClient(c))
+	}
+	return NewQuorumClient(clients, quorum)
+}
+
+// NewQuorumClient creates a quorum client on top of the given clients.
+func NewQuorumClient(clients []*Client, quorum int) (*QuorumClient, error) {
+	if quorum <= 0 || quorum > len(clients) {
+		return nil, fmt.Errorf("invalid quorum %d of %d endpoints", quorum, len(clients))
+	}
+	return &QuorumClient{clients: clients, quorum: quorum}, nil
+}
+
+// Close closes the connections to all endpoints.
+func (qc *QuorumClient) Close() {
+	for _, client := range qc.clients {
+		client.Close()
+	}
+}
+
+// CallContext performs the read request on every endpoint and decodes into
+// result the answer returned identically by at least a quorum of them.
+func (qc *QuorumClient) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	type answer struct {
+		raw json.RawMessage
+		err error
+	}
+	answers := make(chan answer, len(qc.clients))
+	for _, client := range qc.clients {
+		go func(client *Client) {
+			var raw json.RawMessage
+			err := client.c.CallContext(ctx, &raw, method, args...)
+			answers <- answer{raw, err}
+		}(client)
+	}
+	var (
+		votes = make(map[string]int)
+		errs  int
+	)
+	for range qc.clients {
+		a := <-answers
+		if a.err != nil {
+			errs++
+			continue
+		}
+		votes[string(a.raw)]++
+		if votes[string(a.raw)] == qc.quorum {
+			if result == nil {
+				return nil
+			}
+			return json.Unmarshal(a.raw, result)
+		}
+	}
+	return fmt.Errorf("%w: %s answered by %d endpoints in %d variants, %d failed, %d required",
+		ErrNoQuorum, method, len(qc.clients)-errs, len(votes), errs, qc.quorum)
+}
+
+// BlockNumber returns the highest block number reached by at least a quorum of
+// the endpoints.
+func (qc *QuorumClient) BlockNumber(ctx context.Context) (uint64, error) {
+	type answer struct {
+		number hexutil.Uint64
+		err    error
+	}
+	answers := make(chan answer, len(qc.clients))
+	for _, client := range qc.clients {
+		go func(client *Client) {
+			var number hexutil.Uint64
+			err := client.c.CallContext(ctx, &number, "quai_blockNumber")
+			answers <- answer{number, err}
+		}(client)
+	}
+	var numbers []uint64
+	for range qc.clients {
+		if a := <-answers; a.err == nil {
+			numbers = append(numbers, uint64(a.number))
+		}
+	}
+	if len(numbers) < qc.quorum {
+		return 0, fmt.Errorf("%w: block number answered by %d endpoints, %d required", ErrNoQuorum, len(numbers), qc.quorum)
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] > numbers[j] })
+	return numbers[qc.quorum-1], nil
+}
+
+// resolveNumber returns the given block number, or the highest number reached
+// by a quorum if it is nil.
+func (qc *QuorumClient) resolveNumber(ctx context.Context, number *big.Int) (*big.Int, error) {
+	if number != nil {
+		return number, nil
+	}
+	head, err := qc.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetUint64(head), nil
+}
+
+// HeaderByNumber returns the canonical header at the given number a quorum of
+// the endpoints agrees on. If number is nil, the header at the highest number
+// reached by a quorum is returned.
+func (qc *QuorumClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	number, err := qc.resolveNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	var head *types.Header
+	err = qc.CallContext(ctx, &head, "quai_getBlockByNumber", toBlockNumArg(number), false)
+	if err == nil && head == nil {
+		err = quai.NotFound
+	}
+	return head, err
+}
+
+// blockContext returns the block hash a quorum agrees on at the given number,
+// as the canonical block argument of state reads. If number is nil, the highest
+// number reached by a quorum is used.
+func (qc *QuorumClient) blockContext(ctx context.Context, number *big.Int) (rpc.BlockNumberOrHash, error) {
+	number, err := qc.resolveNumber(ctx, number)
+	if err != nil {
+		return rpc.BlockNumberOrHash{}, err
+	}
+	var block *struct {
+		Hash common.Hash `json:"hash"`
+	}
+	if err := qc.CallContext(ctx, &block, "quai_getBlockByNumber", toBlockNumArg(number), false); err != nil {
+		return rpc.BlockNumberOrHash{}, err
+	}
+	if block == nil {
+		return rpc.BlockNumberOrHash{}, quai.NotFound
+	}
+	return rpc.BlockNumberOrHashWithHash(block.Hash, true), nil
+}
+
+// BalanceAt returns the balance of the account at the given block a quorum
+// agrees on. If blockNumber is nil, the highest block reached by a quorum is used.
+func (qc *QuorumClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	block, err := qc.blockContext(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	var result hexutil.Big
+	err = qc.CallContext(ctx, &result, "quai_getBalance", account, block)
+	return (*big.Int)(&result), err
+}
+
+// NonceAt returns the nonce of the account at the given block a quorum agrees
+// on. If blockNumber is nil, the highest block reached by a quorum is used.
+func (qc *QuorumClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	block, err := qc.blockContext(ctx, blockNumber)
+	if err != nil {
+		return 0, err
+	}
+	var result hexutil.Uint64
+	err = qc.CallContext(ctx, &result, "quai_getTransactionCount", account, block)
+	return uint64(result), err
+}
+
+// CodeAt returns the contract code of the account at the given block a quorum
+// agrees on. If blockNumber is nil, the highest block reached by a quorum is used.
+func (qc *QuorumClient) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	block, err := qc.blockContext(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	var result hexutil.Bytes
+	err = qc.CallContext(ctx, &result, "quai_getCode", account, block)
+	return result, err
+}
+
+// StorageAt returns the value of key in the contract storage of the account at
+// the given block a quorum agrees on. If blockNumber is nil, the highest block
+// reached by a quorum is used.
+func (qc *QuorumClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	block, err := qc.blockContext(ctx, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	var result hexutil.Bytes
+	err = qc.CallContext(ctx, &result, "quai_getStorageAt", account, key, block)
+	return result, err
+}
+
+// TransactionReceipt returns the receipt of a mined transaction a quorum agrees
+// on, including the hash of the block it was mined in.
+func (qc *QuorumClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	var r *types.Receipt
+	err := qc.CallContext(ctx, &r, "quai_getTransactionReceipt", txHash)
+	if err == nil && r == nil {
+		return nil, quai.NotFound
+	}
+	return r, err
+}
@@ -0,0 +1,77 @@
+package quaiclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// testQuaiAPI serves a block number and the balances at a canonical block hash.
+type testQuaiAPI struct {
+	head     uint64
+	hash     common.Hash // canonical hash of every block
+	balances map[common.Address]*big.Int
+}
+
+func (api *testQuaiAPI) BlockNumber() hexutil.Uint64 { return hexutil.Uint64(api.head) }
+
+func (api *testQuaiAPI) GetBalance(address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	if hash, ok := blockNrOrHash.Hash(); !ok || hash != api.hash {
+		return nil, errors.New("unknown block")
+	}
+	return (*hexutil.Big)(api.balances[address]), nil
+}
+
+func (api *testQuaiAPI) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) map[string]interface{} {
+	return map[string]interface{}{"number": hexutil.Uint64(number), "hash": api.hash}
+}
+
+func newTestQuorumClient(t *testing.T, quorum int, apis ...*testQuaiAPI) *QuorumClient {
+	var clients []*Client
+	for _, api := range apis {
+		server := rpc.NewServer()
+		if err := server.RegisterName("quai", api); err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, NewClient(rpc.DialInProc(server)))
+	}
+	qc, err := NewQuorumClient(clients, quorum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return qc
+}
+
+func TestQuorumClient(t *testing.T) {
+	var (
+		account = common.Address{0x11}
+		honest  = func(head uint64) *testQuaiAPI {
+			return &testQuaiAPI{head: head, hash: common.Hash{0x01}, balances: map[common.Address]*big.Int{account: big.NewInt(100)}}
+		}
+		forked = &testQuaiAPI{head: 20, hash: common.Hash{0x02}, balances: map[common.Address]*big.Int{account: big.NewInt(1000)}}
+	)
+	qc := newTestQuorumClient(t, 2, honest(10), honest(12), forked)
+	defer qc.Close()
+
+	if number, err := qc.BlockNumber(context.Background()); err != nil || number != 12 {
+		t.Errorf("block number mismatch: have %d, %v, want 12", number, err)
+	}
+	if balance, err := qc.BalanceAt(context.Background(), account, nil); err != nil || balance.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("balance mismatch: have %v, %v, want 100", balance, err)
+	}
+	// Without a quorum on the block context no answer is returned
+	qc = newTestQuorumClient(t, 2, honest(10), forked)
+	defer qc.Close()
+
+	if _, err := qc.BalanceAt(context.Background(), account, big.NewInt(5)); !errors.Is(err, ErrNoQuorum) {
+		t.Errorf("split endpoints error mismatch: have %v, want %v", err, ErrNoQuorum)
+	}
+	if _, err := NewQuorumClient(nil, 1); err == nil {
+		t.Errorf("quorum above the endpoint count accepted")
+	}
+}
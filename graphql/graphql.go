@@ -228,7 +228,7 @@ func (t *Transaction) GasPrice(ctx context.Context) (hexutil.Big, error) {
 	switch tx.Type() {
 	case types.AccessListTxType:
 		return hexutil.Big(*tx.GasPrice()), nil
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.ExpiringTxType, types.ReviveTxType:
 		if t.block != nil {
 			if baseFee, _ := t.block.BaseFeePerGas(ctx); baseFee != nil {
 				// price = min(tip, gasFeeCap - baseFee) + baseFee
@@ -264,7 +264,7 @@ func (t *Transaction) MaxFeePerGas(ctx context.Context) (*hexutil.Big, error) {
 	switch tx.Type() {
 	case types.AccessListTxType:
 		return nil, nil
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.ExpiringTxType, types.ReviveTxType:
 		return (*hexutil.Big)(tx.GasFeeCap()), nil
 	default:
 		return nil, nil
@@ -279,7 +279,7 @@ func (t *Transaction) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, e
 	switch tx.Type() {
 	case types.AccessListTxType:
 		return nil, nil
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.ExpiringTxType, types.ReviveTxType:
 		return (*hexutil.Big)(tx.GasTipCap()), nil
 	default:
 		return nil, nil
@@ -638,36 +638,36 @@ func (b *Block) Nonce(ctx context.Context) (hexutil.Bytes, error) {
 	return header.Nonce[:], nil
 }
 
-func (b *Block) TransactionsRoot(ctx context.Context) ([]common.Hash, error) {
+func (b *Block) TransactionsRoot(ctx context.Context) (common.Hash, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
-		return []common.Hash{common.Hash{}, common.Hash{}, common.Hash{}}, err
+		return common.Hash{}, err
 	}
-	return header.TxHash, nil
+	return header.TxHash[types.QuaiNetworkContext], nil
 }
 
-func (b *Block) StateRoot(ctx context.Context) ([]common.Hash, error) {
+func (b *Block) StateRoot(ctx context.Context) (common.Hash, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
-		return []common.Hash{common.Hash{}, common.Hash{}, common.Hash{}}, err
+		return common.Hash{}, err
 	}
-	return header.Root, nil
+	return header.Root[types.QuaiNetworkContext], nil
 }
 
-func (b *Block) ReceiptsRoot(ctx context.Context) ([]common.Hash, error) {
+func (b *Block) ReceiptsRoot(ctx context.Context) (common.Hash, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
-		return []common.Hash{common.Hash{}, common.Hash{}, common.Hash{}}, err
+		return common.Hash{}, err
 	}
-	return header.ReceiptHash, nil
+	return header.ReceiptHash[types.QuaiNetworkContext], nil
 }
 
-func (b *Block) OmmerHash(ctx context.Context) ([]common.Hash, error) {
+func (b *Block) OmmerHash(ctx context.Context) (common.Hash, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
-		return []common.Hash{common.Hash{}, common.Hash{}, common.Hash{}}, err
+		return common.Hash{}, err
 	}
-	return header.UncleHash, nil
+	return header.UncleHash[types.QuaiNetworkContext], nil
 }
 
 func (b *Block) OmmerCount(ctx context.Context) (*int32, error) {
@@ -696,20 +696,20 @@ func (b *Block) Ommers(ctx context.Context) (*[]*Block, error) {
 	return &ret, nil
 }
 
-func (b *Block) ExtraData(ctx context.Context) ([][]byte, error) {
+func (b *Block) ExtraData(ctx context.Context) (hexutil.Bytes, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
-		return [][]byte{hexutil.Bytes{}, hexutil.Bytes{}, hexutil.Bytes{}}, err
+		return hexutil.Bytes{}, err
 	}
-	return header.Extra, nil
+	return header.Extra[types.QuaiNetworkContext], nil
 }
 
-func (b *Block) LogsBloom(ctx context.Context) ([]hexutil.Bytes, error) {
+func (b *Block) LogsBloom(ctx context.Context) (hexutil.Bytes, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
-		return []hexutil.Bytes{hexutil.Bytes{}, hexutil.Bytes{}, hexutil.Bytes{}}, err
+		return hexutil.Bytes{}, err
 	}
-	return []hexutil.Bytes{header.Bloom[0].Bytes(), header.Bloom[1].Bytes(), header.Bloom[2].Bytes()}, nil
+	return header.Bloom[types.QuaiNetworkContext].Bytes(), nil
 }
 
 func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
@@ -729,6 +729,151 @@ func (b *Block) TotalDifficulty(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*td), nil
 }
 
+// Numbers returns the number of the block in every context, prime first.
+func (b *Block) Numbers(ctx context.Context) ([]*Long, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	numbers := make([]*Long, len(header.Number))
+	for i, number := range header.Number {
+		if number != nil {
+			n := Long(number.Uint64())
+			numbers[i] = &n
+		}
+	}
+	return numbers, nil
+}
+
+// Difficulties returns the difficulty of the block in every context, prime
+// first.
+func (b *Block) Difficulties(ctx context.Context) ([]*hexutil.Big, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	difficulties := make([]*hexutil.Big, len(header.Difficulty))
+	for i, difficulty := range header.Difficulty {
+		difficulties[i] = (*hexutil.Big)(difficulty)
+	}
+	return difficulties, nil
+}
+
+// TotalDifficulties returns the total difficulty of the block in every
+// context, prime first.
+func (b *Block) TotalDifficulties(ctx context.Context) ([]*hexutil.Big, error) {
+	hash, err := b.Hash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	td := b.backend.GetTd(ctx, hash)
+	if td == nil {
+		return nil, fmt.Errorf("total difficulty not found %x", hash)
+	}
+	tds := make([]*hexutil.Big, len(td))
+	for i := range td {
+		tds[i] = (*hexutil.Big)(td[i])
+	}
+	return tds, nil
+}
+
+func (b *Block) Location(ctx context.Context) (hexutil.Bytes, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return hexutil.Bytes{}, err
+	}
+	return header.Location, nil
+}
+
+// Order returns the difficulty order of the block, the most dominant context
+// whose difficulty its seal satisfies.
+func (b *Block) Order(ctx context.Context) (int32, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return 0, err
+	}
+	order, err := b.backend.Engine().GetDifficultyOrder(header)
+	if err != nil {
+		return 0, err
+	}
+	return int32(order), nil
+}
+
+// ExternalBlocks returns the blocks of other chains whose transactions the
+// block applies.
+func (b *Block) ExternalBlocks(ctx context.Context) (*[]*ExternalBlock, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil || header == nil {
+		return nil, err
+	}
+	blocks, err := b.backend.GetExternalBlocks(header)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*ExternalBlock, len(blocks))
+	for i, block := range blocks {
+		ret[i] = &ExternalBlock{backend: b.backend, block: block}
+	}
+	return &ret, nil
+}
+
+// ExternalTransactions returns the transactions of the external blocks the
+// block applies.
+func (b *Block) ExternalTransactions(ctx context.Context) (*[]*Transaction, error) {
+	blocks, err := b.ExternalBlocks(ctx)
+	if err != nil || blocks == nil {
+		return nil, err
+	}
+	ret := make([]*Transaction, 0)
+	for _, block := range *blocks {
+		ret = append(ret, block.Transactions(ctx)...)
+	}
+	return &ret, nil
+}
+
+// ExternalBlock represents a block of another chain applied by a coincident
+// block.
+type ExternalBlock struct {
+	backend ethapi.Backend
+	block   *types.ExternalBlock
+}
+
+func (e *ExternalBlock) Hash(ctx context.Context) common.Hash {
+	return e.block.Hash()
+}
+
+func (e *ExternalBlock) Context(ctx context.Context) int32 {
+	return int32(e.block.Context().Int64())
+}
+
+// Number returns the number of the block in its own context.
+func (e *ExternalBlock) Number(ctx context.Context) (Long, error) {
+	header := e.block.Header()
+	context := int(e.block.Context().Int64())
+	if context < 0 || context >= len(header.Number) || header.Number[context] == nil {
+		return 0, fmt.Errorf("external block %x lacks number in context %d", e.block.Hash(), context)
+	}
+	return Long(header.Number[context].Uint64()), nil
+}
+
+func (e *ExternalBlock) Location(ctx context.Context) hexutil.Bytes {
+	return e.block.Header().Location
+}
+
+func (e *ExternalBlock) Transactions(ctx context.Context) []*Transaction {
+	txs := e.block.Transactions()
+	ret := make([]*Transaction, len(txs))
+	for i, tx := range txs {
+		ret[i] = &Transaction{
+			backend: e.backend,
+			hash:    tx.Hash(),
+			tx:      tx,
+			index:   uint64(i),
+		}
+	}
+	return ret
+}
+
 // BlockNumberArgs encapsulates arguments to accessors that specify a block number.
 type BlockNumberArgs struct {
 	// TODO: Ideally we could use input unions to allow the query to specify the
@@ -1112,6 +1257,58 @@ func (r *Resolver) Blocks(ctx context.Context, args struct {
 	return ret, nil
 }
 
+// maxCoincidentRange is the number of blocks a coincidentBlocks query may scan.
+const maxCoincidentRange = 2048
+
+// CoincidentBlocks returns the canonical blocks between two numbers, inclusive,
+// that are coincident with a dominant context, their order being at most the
+// given one. If to is not supplied, it defaults to the most recent known block.
+// If order is not supplied, blocks coincident with any dominant context are
+// returned.
+func (r *Resolver) CoincidentBlocks(ctx context.Context, args struct {
+	From  Long
+	To    *Long
+	Order *int32
+}) ([]*Block, error) {
+	to := Long(r.backend.CurrentBlock().NumberU64())
+	if args.To != nil && *args.To < to {
+		to = *args.To
+	}
+	if to < args.From {
+		return []*Block{}, nil
+	}
+	if to-args.From >= maxCoincidentRange {
+		return nil, fmt.Errorf("block range exceeds %d blocks", maxCoincidentRange)
+	}
+	maxOrder := types.QuaiNetworkContext - 1
+	if args.Order != nil && int(*args.Order) < maxOrder {
+		maxOrder = int(*args.Order)
+	}
+	ret := make([]*Block, 0)
+	for i := args.From; i <= to; i++ {
+		numberOrHash := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(i))
+		block := &Block{
+			backend:      r.backend,
+			numberOrHash: &numberOrHash,
+		}
+		header, err := block.resolveHeader(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			break
+		}
+		order, err := r.backend.Engine().GetDifficultyOrder(header)
+		if err != nil {
+			return nil, err
+		}
+		if order <= maxOrder {
+			ret = append(ret, block)
+		}
+	}
+	return ret, nil
+}
+
 func (r *Resolver) Pending(ctx context.Context) *Pending {
 	return &Pending{r.backend}
 }
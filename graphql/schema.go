@@ -194,8 +194,6 @@ const schema string = `
         # LogsBloom is a bloom filter that can be used to check if a block may
         # contain log entries matching a filter.
         logsBloom: Bytes!
-        # MixHash is the hash that was used as an input to the PoW process.
-        mixHash: Bytes32!
         # Difficulty is a measure of the difficulty of mining this block.
         difficulty: BigInt!
         # TotalDifficulty is the sum of all difficulty values up to and including
@@ -231,6 +229,40 @@ const schema string = `
         # EstimateGas estimates the amount of gas that will be required for
         # successful execution of a transaction at the current block's state.
         estimateGas(data: CallData!): Long!
+        # Numbers is the number of this block in the prime, region and zone
+        # contexts.
+        numbers: [Long]!
+        # Difficulties is the difficulty of this block in the prime, region and
+        # zone contexts.
+        difficulties: [BigInt]!
+        # TotalDifficulties is the total difficulty of this block in the prime,
+        # region and zone contexts.
+        totalDifficulties: [BigInt]!
+        # Location is the region and zone this block was mined in.
+        location: Bytes!
+        # Order is the difficulty order of this block, the most dominant context
+        # whose difficulty it satisfies (0 for prime, 1 for region, 2 for zone).
+        order: Int!
+        # ExternalBlocks is the list of blocks of other chains whose transactions
+        # this block applies. If they are unavailable, this field will be null.
+        externalBlocks: [ExternalBlock!]
+        # ExternalTransactions is the list of transactions of the external blocks
+        # this block applies. If they are unavailable, this field will be null.
+        externalTransactions: [Transaction!]
+    }
+
+    # ExternalBlock is a block of another chain applied by a coincident block.
+    type ExternalBlock {
+        # Hash is the block hash of this block.
+        hash: Bytes32!
+        # Context is the context this block was mined in.
+        context: Int!
+        # Number is the number of this block in its context.
+        number: Long!
+        # Location is the region and zone this block was mined in.
+        location: Bytes!
+        # Transactions is the list of transactions of this block.
+        transactions: [Transaction!]!
     }
 
     # CallData represents the data associated with a local contract call.
@@ -327,6 +359,12 @@ const schema string = `
         # Blocks returns all the blocks between two numbers, inclusive. If
         # to is not supplied, it defaults to the most recent known block.
         blocks(from: Long, to: Long): [Block!]!
+        # CoincidentBlocks returns the blocks between two numbers, inclusive,
+        # that are coincident with a dominant context, their order being at most
+        # the given one. If to is not supplied, it defaults to the most recent
+        # known block. If order is not supplied, blocks coincident with any
+        # dominant context are returned.
+        coincidentBlocks(from: Long!, to: Long, order: Int): [Block!]!
         # Pending returns the current pending state.
         pending: Pending!
         # Transaction returns a transaction specified by its hash.
@@ -34,6 +34,7 @@ import (
 	"github.com/spruce-solutions/go-quai/common/hexutil"
 	"github.com/spruce-solutions/go-quai/common/math"
 	"github.com/spruce-solutions/go-quai/consensus/clique"
+	"github.com/spruce-solutions/go-quai/contractmeta"
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/state"
 	"github.com/spruce-solutions/go-quai/core/types"
@@ -44,6 +45,7 @@ import (
 	"github.com/spruce-solutions/go-quai/params"
 	"github.com/spruce-solutions/go-quai/rlp"
 	"github.com/spruce-solutions/go-quai/rpc"
+	"github.com/spruce-solutions/go-quai/signer/core/apitypes"
 	"github.com/tyler-smith/go-bip39"
 )
 
@@ -625,11 +627,15 @@ func (s *PublicBlockChainAPI) BlockNumber() hexutil.Uint64 {
 // given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
 // block numbers are also allowed.
 func (s *PublicBlockChainAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
-	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
-	if state == nil || err != nil {
+	reader, err := newStateReader(ctx, s.b, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := reader.balance(address)
+	if balance == nil || err != nil {
 		return nil, err
 	}
-	return (*hexutil.Big)(state.GetBalance(address)), state.Error()
+	return (*hexutil.Big)(balance), nil
 }
 
 // Result structs for GetProof
@@ -848,12 +854,15 @@ func (s *PublicBlockChainAPI) GetCode(ctx context.Context, address common.Addres
 // block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
 // numbers are also allowed.
 func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
-	if state == nil || err != nil {
+	reader, err := newStateReader(ctx, s.b, blockNrOrHash)
+	if err != nil {
 		return nil, err
 	}
-	res := state.GetState(address, common.HexToHash(key))
-	return res[:], state.Error()
+	res, err := reader.storage(address, common.HexToHash(key))
+	if res == nil || err != nil {
+		return nil, err
+	}
+	return res[:], nil
 }
 
 // OverrideAccount indicates the overriding fields of account during the execution
@@ -1149,6 +1158,8 @@ type ExecutionResult struct {
 	Failed      bool           `json:"failed"`
 	ReturnValue string         `json:"returnValue"`
 	StructLogs  []StructLogRes `json:"structLogs"`
+
+	Decoded *contractmeta.Call `json:"decoded,omitempty"` // traced call, if decoding was requested and the callee is verified
 }
 
 // StructLogRes stores a structured log emitted by the EVM while replaying a
@@ -1235,6 +1246,9 @@ func RPCMarshalEthHeader(head *types.Header) map[string]interface{} {
 	if head.BaseFee[context] != nil {
 		result["baseFeePerGas"] = (*hexutil.Big)(head.BaseFee[context])
 	}
+	if len(head.EtxRollupHash) > context {
+		result["etxRollupHash"] = head.EtxRollupHash[context]
+	}
 	return result
 }
 
@@ -1359,6 +1373,7 @@ type RPCTransaction struct {
 	Type             hexutil.Uint64    `json:"type"`
 	Accesses         *types.AccessList `json:"accessList,omitempty"`
 	ChainID          *hexutil.Big      `json:"chainId,omitempty"`
+	ValidUntil       *hexutil.Uint64   `json:"validUntil,omitempty"`
 	V                *hexutil.Big      `json:"v"`
 	R                *hexutil.Big      `json:"r"`
 	S                *hexutil.Big      `json:"s"`
@@ -1403,7 +1418,7 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		al := tx.AccessList()
 		result.Accesses = &al
 		result.ChainID = (*hexutil.Big)(tx.ChainId())
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.ExpiringTxType, types.ReviveTxType:
 		al := tx.AccessList()
 		result.Accesses = &al
 		result.ChainID = (*hexutil.Big)(tx.ChainId())
@@ -1417,6 +1432,9 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		} else {
 			result.GasPrice = (*hexutil.Big)(tx.GasFeeCap())
 		}
+		if validUntil := tx.ValidUntil(); validUntil != 0 {
+			result.ValidUntil = (*hexutil.Uint64)(&validUntil)
+		}
 	}
 	return result
 }
@@ -1631,6 +1649,13 @@ func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, addr
 		if err != nil {
 			return nil, err
 		}
+		// The pool may lag behind the block the miner is sealing, e.g. right
+		// after a new head, never report a nonce below the pending state's
+		if state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.PendingBlockNumber); err == nil && state != nil {
+			if pending := state.GetNonce(address); pending > nonce {
+				nonce = pending
+			}
+		}
 		return (*hexutil.Uint64)(&nonce), nil
 	}
 	// Resolve block number and use its state to ask for the nonce
@@ -1772,6 +1797,10 @@ func SubmitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (c
 	}
 	// Print a log with full tx details for manual investigations and interventions
 	signer := types.MakeSigner(b.ChainConfig(), b.CurrentBlock().Number())
+	if tx.Protected() && tx.ChainId().Cmp(b.ChainConfig().ChainID) != 0 {
+		// Forwarded to a node of the location it's signed for
+		signer = types.LatestSignerForChainID(tx.ChainId())
+	}
 	from, err := types.Sender(signer, tx)
 	if err != nil {
 		return common.Hash{}, err
@@ -1895,6 +1924,48 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
+// typedDataSigner is implemented by wallets signing EIP-712 typed data from its
+// structured form, like external signers displaying it for approval.
+type typedDataSigner interface {
+	SignTypedData(account accounts.Account, typedData apitypes.TypedData) ([]byte, error)
+}
+
+// SignTypedData_v4 calculates an EIP-712 signature of the given typed data:
+// sign(keccak256("\x19\x01" ‖ domainSeparator ‖ hashStruct(message))).
+// A chain ID in the typed data domain has to match the chain of the node.
+//
+// Note, the produced signature conforms to the secp256k1 curve R, S and V values,
+// where the V value will be 27 or 28 for legacy reasons.
+//
+// The account associated with addr must be unlocked.
+func (s *PublicTransactionPoolAPI) SignTypedData_v4(addr common.Address, typedData apitypes.TypedData) (hexutil.Bytes, error) {
+	if chainID := typedData.Domain.ChainId; chainID != nil && (*big.Int)(chainID).Cmp(s.b.ChainConfig().ChainID) != 0 {
+		return nil, fmt.Errorf("typed data chain id %v does not match chain %v", (*big.Int)(chainID), s.b.ChainConfig().ChainID)
+	}
+	_, rawData, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	// Look up the wallet containing the requested signer
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	var signature []byte
+	if signer, ok := wallet.(typedDataSigner); ok {
+		signature, err = signer.SignTypedData(account, typedData)
+	} else {
+		signature, err = wallet.SignData(account, accounts.MimetypeTypedData, []byte(rawData))
+	}
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -2009,6 +2080,59 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs Transact
 	return common.Hash{}, fmt.Errorf("transaction %#x not found", matchTx.Hash())
 }
 
+// CancelTransaction replaces a pending transaction sent from a local account
+// with a zero value transfer to the sender at the same nonce, paying enough to
+// be accepted as a replacement by the pool. The optional maxFee caps the gas
+// price (or fee cap) the replacement may pay. It returns the replacement hash.
+func (s *PublicTransactionPoolAPI) CancelTransaction(ctx context.Context, hash common.Hash, maxFee *hexutil.Big) (common.Hash, error) {
+	tx := s.b.GetPoolTransaction(hash)
+	if tx == nil {
+		return common.Hash{}, fmt.Errorf("transaction %#x not pending", hash)
+	}
+	from, err := types.Sender(s.signer, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	tip, err := s.b.SuggestGasTipCap(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var (
+		head  = s.b.CurrentHeader()
+		bump  = new(big.Int).SetUint64(100 + core.DefaultTxPoolConfig.PriceBump)
+		raise = func(price *big.Int) *big.Int {
+			bumped := new(big.Int).Mul(price, bump)
+			bumped.Div(bumped, big.NewInt(100))
+			return bumped.Add(bumped, common.Big1)
+		}
+		data types.TxData
+		fee  *big.Int
+	)
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		price := new(big.Int).Add(tip, head.BaseFee[types.QuaiNetworkContext])
+		fee = math.BigMax(raise(tx.GasPrice()), price)
+		data = &types.LegacyTx{Nonce: tx.Nonce(), GasPrice: fee, Gas: params.TxGas, To: &from, Value: new(big.Int)}
+	default:
+		tip = math.BigMax(raise(tx.GasTipCap()), tip)
+		fee = new(big.Int).Add(tip, new(big.Int).Mul(head.BaseFee[types.QuaiNetworkContext], big.NewInt(2)))
+		fee = math.BigMax(raise(tx.GasFeeCap()), fee)
+		data = &types.DynamicFeeTx{ChainID: s.b.ChainConfig().ChainID, Nonce: tx.Nonce(), GasTipCap: tip, GasFeeCap: fee, Gas: params.TxGas, To: &from, Value: new(big.Int)}
+	}
+	if maxFee != nil && fee.Cmp(maxFee.ToInt()) > 0 {
+		return common.Hash{}, fmt.Errorf("replacement fee %v exceeds maxFee %v", fee, maxFee.ToInt())
+	}
+	signed, err := s.sign(from, types.NewTx(data))
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if _, err := SubmitTransaction(ctx, s.b, signed); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Cancelling transaction", "hash", hash, "replacement", signed.Hash(), "nonce", tx.Nonce(), "fee", fee)
+	return signed.Hash(), nil
+}
+
 // PublicDebugAPI is the collection of Ethereum APIs exposed over the public
 // debugging endpoint.
 type PublicDebugAPI struct {
@@ -0,0 +1,147 @@
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+const (
+	// defaultHashrateBlocks is the number of recent local blocks the hashrate
+	// estimation is based on if the caller does not choose.
+	defaultHashrateBlocks = 1024
+
+	// maxHashrateBlocks caps the number of blocks a single estimation walks.
+	maxHashrateBlocks = 8192
+
+	// hashrateConfidenceZ is the standard score of the reported 95% confidence
+	// interval.
+	hashrateConfidenceZ = 1.96
+)
+
+// HashrateEstimate is the estimated hashrate of the chain at one context of the
+// hierarchy, as returned by quai_estimateHashrate.
+type HashrateEstimate struct {
+	Context    int            `json:"context"`
+	Blocks     hexutil.Uint64 `json:"blocks"` // blocks of the context mined within the span
+	Span       hexutil.Uint64 `json:"span"`   // seconds covered by the sample
+	Difficulty *hexutil.Big   `json:"difficulty"`
+	Hashrate   *hexutil.Big   `json:"hashrate"` // hashes per second
+	Lower      *hexutil.Big   `json:"lower"`    // lower bound of the 95% confidence interval
+	Upper      *hexutil.Big   `json:"upper"`    // upper bound of the 95% confidence interval
+}
+
+// EstimateHashrate estimates the network hashrate of the local chain and of its
+// dominant chains from the intervals of the given number of recent local blocks.
+// Dominant chain rates are derived from the coincident blocks in the sample,
+// scaling their difficulty by the dominant chain's progress between them.
+// Confidence intervals assume exponentially distributed block intervals.
+func (s *PublicBlockChainQuaiAPI) EstimateHashrate(ctx context.Context, blocks *hexutil.Uint64) ([]*HashrateEstimate, error) {
+	count := uint64(defaultHashrateBlocks)
+	if blocks != nil {
+		count = uint64(*blocks)
+	}
+	if count < 2 {
+		return nil, errors.New("at least two blocks are needed to estimate the hashrate")
+	}
+	if count > maxHashrateBlocks {
+		count = maxHashrateBlocks
+	}
+	var (
+		engine  = s.b.Engine()
+		head    = s.b.CurrentHeader()
+		number  = head.Number[types.QuaiNetworkContext].Uint64()
+		headers = make([]*types.Header, 0, count)
+		orders  = make([]int, 0, count)
+	)
+	if count > number+1 {
+		count = number + 1
+	}
+	// Collect the sample oldest first, along with the order of every block
+	for n := number + 1 - count; n <= number; n++ {
+		header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(n))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			continue
+		}
+		order, err := engine.GetDifficultyOrder(header)
+		if err != nil {
+			order = types.QuaiNetworkContext
+		}
+		headers = append(headers, header)
+		orders = append(orders, order)
+	}
+	estimates := make([]*HashrateEstimate, 0, types.QuaiNetworkContext+1)
+	for context := 0; context <= types.QuaiNetworkContext; context++ {
+		var sample []*types.Header
+		for i, header := range headers {
+			if orders[i] <= context {
+				sample = append(sample, header)
+			}
+		}
+		estimates = append(estimates, estimateHashrate(sample, context))
+	}
+	return estimates, nil
+}
+
+// estimateHashrate computes the hashrate of a context from the blocks of the
+// local chain coincident with it, oldest first.
+func estimateHashrate(sample []*types.Header, context int) *HashrateEstimate {
+	estimate := &HashrateEstimate{
+		Context:    context,
+		Difficulty: (*hexutil.Big)(new(big.Int)),
+		Hashrate:   (*hexutil.Big)(new(big.Int)),
+		Lower:      (*hexutil.Big)(new(big.Int)),
+		Upper:      (*hexutil.Big)(new(big.Int)),
+	}
+	if len(sample) == 0 {
+		return estimate
+	}
+	last := sample[len(sample)-1]
+	if last.Difficulty[context] != nil {
+		estimate.Difficulty = (*hexutil.Big)(new(big.Int).Set(last.Difficulty[context]))
+	}
+	if len(sample) < 2 {
+		return estimate
+	}
+	first := sample[0]
+	if last.Time <= first.Time || first.Number[context] == nil || last.Number[context] == nil {
+		return estimate
+	}
+	progress := new(big.Int).Sub(last.Number[context], first.Number[context])
+	if progress.Sign() <= 0 {
+		return estimate
+	}
+	// Average the difficulty over the sample, the blocks in between coincident
+	// ones are mined at similar difficulties
+	total := new(big.Int)
+	for _, header := range sample[1:] {
+		if header.Difficulty[context] != nil {
+			total.Add(total, header.Difficulty[context])
+		}
+	}
+	work := new(big.Float).SetInt(total)
+	work.Quo(work, big.NewFloat(float64(len(sample)-1)))
+	work.Mul(work, new(big.Float).SetInt(progress))
+
+	span := last.Time - first.Time
+	rate := new(big.Float).Quo(work, new(big.Float).SetUint64(span))
+
+	margin := hashrateConfidenceZ / math.Sqrt(float64(progress.Uint64()))
+	lower := new(big.Float).Mul(rate, big.NewFloat(math.Max(0, 1-margin)))
+	upper := new(big.Float).Mul(rate, big.NewFloat(1+margin))
+
+	estimate.Blocks = hexutil.Uint64(progress.Uint64())
+	estimate.Span = hexutil.Uint64(span)
+	rate.Int((*big.Int)(estimate.Hashrate))
+	lower.Int((*big.Int)(estimate.Lower))
+	upper.Int((*big.Int)(estimate.Upper))
+	return estimate
+}
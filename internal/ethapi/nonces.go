@@ -0,0 +1,83 @@
+package ethapi
+
+import (
+	"context"
+	"sort"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// NonceRange is an inclusive range of transaction nonces.
+type NonceRange struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// NonceGaps is the nonce layout of an account in the transaction pool, as
+// returned by txpool_nonceGaps.
+type NonceGaps struct {
+	Address common.Address   `json:"address"`
+	Nonce   hexutil.Uint64   `json:"nonce"`   // nonce of the account in the latest state
+	Next    hexutil.Uint64   `json:"next"`    // nonce the next transaction must use to be executable
+	Pending []hexutil.Uint64 `json:"pending"` // nonces of the executable pool transactions
+	Queued  []hexutil.Uint64 `json:"queued"`  // nonces of the pool transactions waiting for a gap to be filled
+	Gaps    []NonceRange     `json:"gaps"`    // missing nonces keeping the queued transactions from executing
+}
+
+// NonceGaps returns the on-chain nonce of an account along with the nonces of
+// its pending and queued pool transactions and the missing nonces between them.
+// Queued transactions only become executable once every gap below them is
+// filled, which is the usual reason for a transaction being stuck.
+func (s *PublicTxPoolAPI) NonceGaps(ctx context.Context, address common.Address) (*NonceGaps, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	pending, queued := s.b.TxPoolContentFrom(address)
+	return nonceGaps(address, state.GetNonce(address), pending, queued), state.Error()
+}
+
+// nonceGaps computes the nonce layout of an account's pool transactions on top
+// of its state nonce.
+func nonceGaps(address common.Address, nonce uint64, pending, queued types.Transactions) *NonceGaps {
+	gaps := &NonceGaps{
+		Address: address,
+		Nonce:   hexutil.Uint64(nonce),
+		Next:    hexutil.Uint64(nonce),
+		Pending: sortedNonces(pending),
+		Queued:  sortedNonces(queued),
+		Gaps:    []NonceRange{},
+	}
+	// Pending transactions are contiguous from the state nonce on
+	if n := len(gaps.Pending); n > 0 && uint64(gaps.Pending[n-1]) >= nonce {
+		gaps.Next = gaps.Pending[n-1] + 1
+	}
+	next := uint64(gaps.Next)
+	for _, queued := range gaps.Queued {
+		switch {
+		case uint64(queued) < next:
+			continue
+		case uint64(queued) > next:
+			gaps.Gaps = append(gaps.Gaps, NonceRange{From: hexutil.Uint64(next), To: queued - 1})
+		}
+		next = uint64(queued) + 1
+	}
+	return gaps
+}
+
+// sortedNonces returns the distinct nonces of the transactions in ascending order.
+func sortedNonces(txs types.Transactions) []hexutil.Uint64 {
+	nonces := make([]hexutil.Uint64, 0, len(txs))
+	seen := make(map[uint64]struct{}, len(txs))
+	for _, tx := range txs {
+		if _, ok := seen[tx.Nonce()]; !ok {
+			seen[tx.Nonce()] = struct{}{}
+			nonces = append(nonces, hexutil.Uint64(tx.Nonce()))
+		}
+	}
+	sort.Slice(nonces, func(i, j int) bool { return nonces[i] < nonces[j] })
+	return nonces
+}
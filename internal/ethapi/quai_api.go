@@ -24,12 +24,16 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/golang/snappy"
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/consensus"
 	"github.com/spruce-solutions/go-quai/core"
 	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
 	"github.com/spruce-solutions/go-quai/crypto"
 	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/rlp"
 	"github.com/spruce-solutions/go-quai/rpc"
 )
 
@@ -116,6 +120,94 @@ func (s *PublicQuaiAPI) Syncing() (interface{}, error) {
 	}, nil
 }
 
+// ForkStatus is the readiness of a single fork as returned by quai_forkSchedule.
+type ForkStatus struct {
+	Name      string          `json:"name"`
+	Context   int             `json:"context"`
+	Location  hexutil.Bytes   `json:"location"`
+	Block     *hexutil.Big    `json:"block"`               // nil if the fork is not scheduled
+	Timestamp *hexutil.Uint64 `json:"timestamp,omitempty"` // time of the activation block, once activated
+	Activated bool            `json:"activated"`
+	Remaining *hexutil.Big    `json:"remaining,omitempty"` // blocks until activation, if scheduled and pending
+	Supported bool            `json:"supported"`
+
+	Contexts []ForkContextStatus `json:"contexts,omitempty"` // positions of the head and activation block in every context
+}
+
+// ForkContextStatus is the position of the current head and of the activation
+// block of a fork in one context, as blocks are numbered in every context they
+// are coincident with.
+type ForkContextStatus struct {
+	Context int          `json:"context"`
+	Head    *hexutil.Big `json:"head"`            // number of the current head in the context
+	Block   *hexutil.Big `json:"block,omitempty"` // number of the activation block in the context, once activated
+}
+
+// ForkSchedule returns the forks of the local chain config together with their
+// activation status at the current head, broken down by context. Additional fork
+// names may be passed to check whether the running release supports them; names
+// unknown to the release are reported as unscheduled and unsupported.
+func (s *PublicQuaiAPI) ForkSchedule(ctx context.Context, names *[]string) ([]ForkStatus, error) {
+	var (
+		config  = s.b.ChainConfig()
+		current = s.b.CurrentHeader()
+		head    = current.Number[types.QuaiNetworkContext]
+		forks   = config.Forks()
+		known   = make(map[string]bool, len(forks))
+	)
+	result := make([]ForkStatus, 0, len(forks))
+	for _, fork := range forks {
+		known[fork.Name] = true
+
+		status := ForkStatus{
+			Name:      fork.Name,
+			Context:   types.QuaiNetworkContext,
+			Location:  config.Location,
+			Supported: true,
+			Contexts:  make([]ForkContextStatus, len(current.Number)),
+		}
+		for context, number := range current.Number {
+			status.Contexts[context] = ForkContextStatus{Context: context, Head: (*hexutil.Big)(number)}
+		}
+		if fork.Block != nil {
+			status.Block = (*hexutil.Big)(fork.Block)
+			if head.Cmp(fork.Block) >= 0 {
+				status.Activated = true
+				header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(fork.Block.Int64()))
+				if err != nil {
+					return nil, err
+				}
+				if header != nil {
+					timestamp := hexutil.Uint64(header.Time)
+					status.Timestamp = &timestamp
+					for context := range status.Contexts {
+						if context < len(header.Number) {
+							status.Contexts[context].Block = (*hexutil.Big)(header.Number[context])
+						}
+					}
+				}
+			} else {
+				status.Remaining = (*hexutil.Big)(new(big.Int).Sub(fork.Block, head))
+			}
+		}
+		result = append(result, status)
+	}
+	if names != nil {
+		for _, name := range *names {
+			if known[name] {
+				continue
+			}
+			known[name] = true
+			result = append(result, ForkStatus{
+				Name:     name,
+				Context:  types.QuaiNetworkContext,
+				Location: config.Location,
+			})
+		}
+	}
+	return result, nil
+}
+
 // PublicBlockChainQuaiAPI provides an API to access the Quai blockchain.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicBlockChainQuaiAPI struct {
@@ -146,11 +238,36 @@ func (s *PublicBlockChainQuaiAPI) BlockNumber() hexutil.Uint64 {
 // given block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta
 // block numbers are also allowed.
 func (s *PublicBlockChainQuaiAPI) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
-	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
-	if state == nil || err != nil {
+	reader, err := newStateReader(ctx, s.b, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := reader.balance(address)
+	if balance == nil || err != nil {
 		return nil, err
 	}
-	return (*hexutil.Big)(state.GetBalance(address)), state.Error()
+	return (*hexutil.Big)(balance), nil
+}
+
+// GetBalances returns the balances of the given addresses in the state of the
+// given block number, in the order of the addresses.
+func (s *PublicBlockChainQuaiAPI) GetBalances(ctx context.Context, addresses []common.Address, blockNrOrHash rpc.BlockNumberOrHash) ([]*hexutil.Big, error) {
+	if len(addresses) > maxStateBatch {
+		return nil, fmt.Errorf("too many addresses, at most %d allowed", maxStateBatch)
+	}
+	reader, err := newStateReader(ctx, s.b, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]*hexutil.Big, len(addresses))
+	for i, address := range addresses {
+		balance, err := reader.balance(address)
+		if balance == nil || err != nil {
+			return nil, err
+		}
+		balances[i] = (*hexutil.Big)(balance)
+	}
+	return balances, nil
 }
 
 // GetProof returns the Merkle-proof for a given account and optionally some storage keys.
@@ -230,6 +347,162 @@ func (s *PublicBlockChainQuaiAPI) GetHeaderByHash(ctx context.Context, hash comm
 	return nil
 }
 
+// maxHeaderRange is the maximum number of headers returned by a single
+// GetHeadersByRange call.
+const maxHeaderRange = 2048
+
+// HeaderRangeEntry is a canonical header as returned by GetHeadersByRange, with
+// its total difficulty in every context and its difficulty order.
+type HeaderRangeEntry struct {
+	Header *types.Header
+	Td     []*big.Int
+	Order  uint64
+}
+
+// DecodeHeaderRange decodes the compressed response of GetHeadersByRange.
+func DecodeHeaderRange(blob []byte) ([]*HeaderRangeEntry, error) {
+	data, err := snappy.Decode(nil, blob)
+	if err != nil {
+		return nil, err
+	}
+	var entries []*HeaderRangeEntry
+	if err := rlp.DecodeBytes(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetHeadersByRange returns up to count consecutive canonical headers starting
+// at the given number, together with their total difficulties and difficulty
+// orders, stopping early at the chain head. If compressed is set, the headers
+// are returned as a snappy compressed RLP list of HeaderRangeEntry items, see
+// DecodeHeaderRange. Otherwise they are returned as header objects carrying the
+// additional totalDifficulties and order fields.
+func (s *PublicBlockChainQuaiAPI) GetHeadersByRange(ctx context.Context, from hexutil.Uint64, count hexutil.Uint64, compressed *bool) (interface{}, error) {
+	if count == 0 || count > maxHeaderRange {
+		return nil, fmt.Errorf("header count must be between 1 and %d", maxHeaderRange)
+	}
+	var entries []*HeaderRangeEntry
+	for number := uint64(from); number < uint64(from)+uint64(count); number++ {
+		header, err := s.b.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			break
+		}
+		td := s.b.GetTd(ctx, header.Hash())
+		if td == nil {
+			return nil, fmt.Errorf("missing total difficulty of header #%d [%x]", number, header.Hash())
+		}
+		order, err := s.b.Engine().GetDifficultyOrder(header)
+		if err != nil {
+			return nil, fmt.Errorf("header #%d [%x]: %v", number, header.Hash(), err)
+		}
+		entries = append(entries, &HeaderRangeEntry{Header: header, Td: td, Order: uint64(order)})
+	}
+	if compressed != nil && *compressed {
+		data, err := rlp.EncodeToBytes(entries)
+		if err != nil {
+			return nil, err
+		}
+		return hexutil.Bytes(snappy.Encode(nil, data)), nil
+	}
+	headers := make([]map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		tds := make([]*hexutil.Big, len(entry.Td))
+		for j, td := range entry.Td {
+			tds[j] = (*hexutil.Big)(td)
+		}
+		headers[i] = RPCMarshalHeader(entry.Header)
+		if len(tds) > types.QuaiNetworkContext {
+			headers[i]["totalDifficulty"] = tds[types.QuaiNetworkContext]
+		}
+		headers[i]["totalDifficulties"] = tds
+		headers[i]["order"] = hexutil.Uint64(entry.Order)
+	}
+	return headers, nil
+}
+
+// EVMConfig is the EVM rule set in effect at a block as returned by
+// quai_getEVMConfig.
+type EVMConfig struct {
+	Number         *hexutil.Big     `json:"number"`
+	Context        int              `json:"context"`
+	InstructionSet string           `json:"instructionSet"` // gas table version
+	EVMVersion     string           `json:"evmVersion"`     // compiler EVM version to target
+	Opcodes        []string         `json:"opcodes"`
+	Precompiles    []common.Address `json:"precompiles"`
+}
+
+// GetEVMConfig returns the EVM rule set in effect at the given block, letting
+// tooling configure the EVM version it targets on this context.
+func (s *PublicBlockChainQuaiAPI) GetEVMConfig(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*EVMConfig, error) {
+	header, err := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("header not found")
+	}
+	number := header.Number[types.QuaiNetworkContext]
+	ruleSet := vm.NewRuleSet(s.b.ChainConfig().Rules(number))
+
+	opcodes := make([]string, len(ruleSet.Opcodes))
+	for i, op := range ruleSet.Opcodes {
+		opcodes[i] = op.String()
+	}
+	return &EVMConfig{
+		Number:         (*hexutil.Big)(number),
+		Context:        types.QuaiNetworkContext,
+		InstructionSet: ruleSet.InstructionSet,
+		EVMVersion:     ruleSet.EVMVersion,
+		Opcodes:        opcodes,
+		Precompiles:    ruleSet.Precompiles,
+	}, nil
+}
+
+// BlockTdTuple is the total difficulty of a block in every context as returned
+// by quai_getBlockTdTuple.
+type BlockTdTuple struct {
+	Hash   common.Hash    `json:"hash"`
+	Number *hexutil.Big   `json:"number"`
+	Td     []*hexutil.Big `json:"totalDifficulties"` // Prime, Region and Zone
+	Order  hexutil.Uint64 `json:"order"`
+}
+
+// GetBlockTdTuple returns the total difficulties of the given block in the
+// prime, region and zone contexts, along with its difficulty order, which the
+// HLCR fork choice compares blocks by.
+func (s *PublicBlockChainQuaiAPI) GetBlockTdTuple(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*BlockTdTuple, error) {
+	header, err := s.b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("header not found")
+	}
+	hash := header.Hash()
+	td := s.b.GetTd(ctx, hash)
+	if td == nil {
+		return nil, fmt.Errorf("missing total difficulty of header [%x]", hash)
+	}
+	order, err := s.b.Engine().GetDifficultyOrder(header)
+	if err != nil {
+		return nil, fmt.Errorf("header [%x]: %v", hash, err)
+	}
+	tds := make([]*hexutil.Big, len(td))
+	for i := range td {
+		tds[i] = (*hexutil.Big)(td[i])
+	}
+	return &BlockTdTuple{
+		Hash:   hash,
+		Number: (*hexutil.Big)(header.Number[types.QuaiNetworkContext]),
+		Td:     tds,
+		Order:  hexutil.Uint64(order),
+	}, nil
+}
+
 // GetBlockByNumber returns the requested canonical block.
 // * When blockNr is -1 the chain head is returned.
 // * When blockNr is -2 the pending chain head is returned.
@@ -357,12 +630,43 @@ func (s *PublicBlockChainQuaiAPI) GetCode(ctx context.Context, address common.Ad
 // block number. The rpc.LatestBlockNumber and rpc.PendingBlockNumber meta block
 // numbers are also allowed.
 func (s *PublicBlockChainQuaiAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
-	state, _, err := s.b.StateAndHeaderByNumberOrHash(ctx, blockNrOrHash)
-	if state == nil || err != nil {
+	reader, err := newStateReader(ctx, s.b, blockNrOrHash)
+	if err != nil {
 		return nil, err
 	}
-	res := state.GetState(address, common.HexToHash(key))
-	return res[:], state.Error()
+	res, err := reader.storage(address, common.HexToHash(key))
+	if res == nil || err != nil {
+		return nil, err
+	}
+	return res[:], nil
+}
+
+// GetStorageSlots returns the storage slots of the given contracts in the state
+// of the given block number, in the order of the keys of each contract.
+func (s *PublicBlockChainQuaiAPI) GetStorageSlots(ctx context.Context, slots map[common.Address][]string, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address][]hexutil.Bytes, error) {
+	var count int
+	for _, keys := range slots {
+		count += len(keys)
+	}
+	if count > maxStateBatch {
+		return nil, fmt.Errorf("too many storage slots, at most %d allowed", maxStateBatch)
+	}
+	reader, err := newStateReader(ctx, s.b, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[common.Address][]hexutil.Bytes, len(slots))
+	for address, keys := range slots {
+		values[address] = make([]hexutil.Bytes, len(keys))
+		for i, key := range keys {
+			value, err := reader.storage(address, common.HexToHash(key))
+			if value == nil || err != nil {
+				return nil, err
+			}
+			values[address][i] = value[:]
+		}
+	}
+	return values, nil
 }
 
 // Call executes the given transaction on the state for the given block number.
@@ -419,6 +723,9 @@ func RPCMarshalHeader(head *types.Header) map[string]interface{} {
 	if head.BaseFee != nil {
 		result["baseFeePerGas"] = head.BaseFee
 	}
+	if head.EtxRollupHash != nil {
+		result["etxRollupHash"] = head.EtxRollupHash
+	}
 
 	return result
 }
@@ -791,7 +1098,8 @@ func (s *PublicBlockChainQuaiAPI) CheckPCRC(ctx context.Context, raw json.RawMes
 		return types.PCRCTermini{}, err
 	}
 	fmt.Println("Header Number:", headerWithOrder.Header.Number, "Order:", headerWithOrder.Order, "Hash:", headerWithOrder.Header.Hash())
-	return s.b.PCRC(headerWithOrder.Header, headerWithOrder.Order)
+	termini, err := s.b.PCRC(headerWithOrder.Header, headerWithOrder.Order)
+	return termini, newSliceCheckError(err)
 }
 
 // CheckPCCRC runs PCCRC on a node and returns the response codes.
@@ -802,5 +1110,27 @@ func (s *PublicBlockChainQuaiAPI) CheckPCCRC(ctx context.Context, raw json.RawMe
 		return types.PCRCTermini{}, err
 	}
 	fmt.Println("Header Number:", headerWithOrder.Header.Number, "Order:", headerWithOrder.Order, "Hash:", headerWithOrder.Header.Hash())
-	return s.b.PCCRC(headerWithOrder.Header, headerWithOrder.Order)
+	termini, err := s.b.PCCRC(headerWithOrder.Header, headerWithOrder.Order)
+	return termini, newSliceCheckError(err)
+}
+
+// sliceCheckError is an API error of a failed slice check, carrying the reason
+// of the failure as error data.
+type sliceCheckError struct {
+	error
+	reason string
+}
+
+// newSliceCheckError attaches the failure reason to the error of a slice check,
+// if it has one.
+func newSliceCheckError(err error) error {
+	if reason := consensus.FailureReason(err); reason != "" {
+		return &sliceCheckError{error: err, reason: reason}
+	}
+	return err
+}
+
+// ErrorData returns the reason of the failure.
+func (e *sliceCheckError) ErrorData() interface{} {
+	return e.reason
 }
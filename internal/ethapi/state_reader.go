@@ -0,0 +1,125 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/state/snapshot"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/rlp"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// maxStateBatch is the maximum number of accounts or storage slots read by a
+// single batch call.
+const maxStateBatch = 1024
+
+var (
+	stateSnapHitMeter  = metrics.NewRegisteredMeter("rpc/state/snapshot/hit", nil)
+	stateSnapMissMeter = metrics.NewRegisteredMeter("rpc/state/snapshot/miss", nil)
+)
+
+// stateReader reads balances and storage slots in the state at a block. Reads
+// are served from the snapshot if it covers the state, the state trie is only
+// opened for the reads the snapshot can't serve, e.g. while it is generated.
+type stateReader struct {
+	ctx context.Context
+	b   Backend
+	at  rpc.BlockNumberOrHash
+
+	snap  snapshot.Snapshot // Snapshot of the state, nil if not covered
+	state *state.StateDB    // State trie, opened on the first fallback
+}
+
+// newStateReader creates a reader of the state at the block.
+func newStateReader(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrHash) (*stateReader, error) {
+	r := &stateReader{ctx: ctx, b: b, at: blockNrOrHash}
+
+	header, err := b.HeaderByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header != nil {
+		r.snap = b.Snapshot(header.Root[types.QuaiNetworkContext])
+	}
+	return r, nil
+}
+
+// trie returns the state trie, nil if the state is unavailable.
+func (r *stateReader) trie() (*state.StateDB, error) {
+	if r.state == nil {
+		state, _, err := r.b.StateAndHeaderByNumberOrHash(r.ctx, r.at)
+		if state == nil || err != nil {
+			return nil, err
+		}
+		r.state = state
+	}
+	return r.state, nil
+}
+
+// balance returns the balance of the account, nil if the state is unavailable.
+func (r *stateReader) balance(address common.Address) (*big.Int, error) {
+	if r.snap != nil {
+		if account, err := r.snap.Account(crypto.Keccak256Hash(address.Bytes())); err == nil {
+			stateSnapHitMeter.Mark(1)
+			if account == nil {
+				return new(big.Int), nil
+			}
+			return account.Balance, nil
+		}
+	}
+	stateSnapMissMeter.Mark(1)
+
+	state, err := r.trie()
+	if state == nil || err != nil {
+		return nil, err
+	}
+	return state.GetBalance(address), state.Error()
+}
+
+// storage returns the value of the storage slot, nil if the state is
+// unavailable.
+func (r *stateReader) storage(address common.Address, key common.Hash) (*common.Hash, error) {
+	if r.snap != nil {
+		if enc, err := r.snap.Storage(crypto.Keccak256Hash(address.Bytes()), crypto.Keccak256Hash(key.Bytes())); err == nil {
+			var value common.Hash
+			if len(enc) == 0 {
+				stateSnapHitMeter.Mark(1)
+				return &value, nil
+			}
+			if _, content, _, err := rlp.Split(enc); err == nil {
+				stateSnapHitMeter.Mark(1)
+				value.SetBytes(content)
+				return &value, nil
+			}
+		}
+	}
+	stateSnapMissMeter.Mark(1)
+
+	state, err := r.trie()
+	if state == nil || err != nil {
+		return nil, err
+	}
+	value := state.GetState(address, key)
+	return &value, state.Error()
+}
@@ -24,6 +24,8 @@ var Modules = map[string]string{
 	"debug":    DebugJs,
 	"eth":      EthJs,
 	"miner":    MinerJs,
+	"multisig": MultisigJs,
+	"metadata": MetadataJs,
 	"net":      NetJs,
 	"personal": PersonalJs,
 	"rpc":      RpcJs,
@@ -152,6 +154,43 @@ web3._extend({
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setMaxPeers',
+			call: 'admin_setMaxPeers',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'verifyChain',
+			call: 'admin_verifyChain',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'abortVerifyChain',
+			call: 'admin_abortVerifyChain'
+		}),
+		new web3._extend.Method({
+			name: 'rehearseFork',
+			call: 'admin_rehearseFork',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'abortRehearseFork',
+			call: 'admin_abortRehearseFork'
+		}),
+		new web3._extend.Method({
+			name: 'scheduleAction',
+			call: 'admin_scheduleAction',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'cancelAction',
+			call: 'admin_cancelAction',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -189,6 +228,24 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'labelAddress',
+			call: 'admin_labelAddress',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'unlabelAddress',
+			call: 'admin_unlabelAddress',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'addressLabel',
+			call: 'admin_addressLabel',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -203,6 +260,30 @@ web3._extend({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'addressLabels',
+			getter: 'admin_addressLabels'
+		}),
+		new web3._extend.Property({
+			name: 'peerTarget',
+			getter: 'admin_peerTarget'
+		}),
+		new web3._extend.Property({
+			name: 'verifyChainStatus',
+			getter: 'admin_verifyChainStatus'
+		}),
+		new web3._extend.Property({
+			name: 'rehearseForkStatus',
+			getter: 'admin_rehearseForkStatus'
+		}),
+		new web3._extend.Property({
+			name: 'scheduledActions',
+			getter: 'admin_scheduledActions'
+		}),
+		new web3._extend.Property({
+			name: 'domClient',
+			getter: 'admin_domClient'
+		}),
 	]
 });
 `
@@ -426,6 +507,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'meterTransaction',
+			call: 'debug_meterTransaction',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'traceCall',
 			call: 'debug_traceCall',
@@ -471,6 +558,17 @@ web3._extend({
 			params: 2,
 			inputFormatter:[web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter],
 		}),
+		new web3._extend.Method({
+			name: 'runInvariantCheck',
+			call: 'debug_runInvariantCheck',
+			params: 2,
+			inputFormatter:[null, null],
+		}),
+		new web3._extend.Method({
+			name: 'simulateDifficulty',
+			call: 'debug_simulateDifficulty',
+			params: 1,
+		}),
 	],
 	properties: []
 });
@@ -497,6 +595,18 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
 		}),
+		new web3._extend.Method({
+			name: 'signTypedData',
+			call: 'eth_signTypedData_v4',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'cancelTransaction',
+			call: 'eth_cancelTransaction',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
 		new web3._extend.Method({
 			name: 'signTransaction',
 			call: 'eth_signTransaction',
@@ -576,6 +686,27 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'estimateLogsCost',
+			call: 'eth_estimateLogsCost',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'submitShare',
+			call: 'eth_submitShare',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sharePayouts',
+			call: 'eth_sharePayouts',
+			params: 1,
+			inputFormatter: [null]
+		}),
+		new web3._extend.Method({
+			name: 'getAuxPow',
+			call: 'eth_getAuxPow',
+			params: 2
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -595,6 +726,10 @@ web3._extend({
 			getter: 'eth_maxPriorityFeePerGas',
 			outputFormatter: web3._extend.utils.toBigNumber
 		}),
+		new web3._extend.Property({
+			name: 'pendingEtxs',
+			getter: 'eth_pendingEtxs'
+		}),
 	]
 });
 `
@@ -624,6 +759,11 @@ web3._extend({
 			call: 'miner_setExtra',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'setAuxCommitment',
+			call: 'miner_setAuxCommitment',
+			params: 2
+		}),
 		new web3._extend.Method({
 			name: 'setGasPrice',
 			call: 'miner_setGasPrice',
@@ -641,6 +781,27 @@ web3._extend({
 			call: 'miner_setRecommitInterval',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'sealLatency',
+			call: 'miner_sealLatency',
+		}),
+		new web3._extend.Method({
+			name: 'stats',
+			call: 'miner_stats',
+		}),
+		new web3._extend.Method({
+			name: 'setPolicy',
+			call: 'miner_setPolicy',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'policy',
+			call: 'miner_policy',
+		}),
+		new web3._extend.Method({
+			name: 'election',
+			call: 'miner_election',
+		}),
 		new web3._extend.Method({
 			name: 'getHashrate',
 			call: 'miner_getHashrate'
@@ -732,6 +893,84 @@ web3._extend({
 });
 `
 
+const MultisigJs = `
+web3._extend({
+	property: 'multisig',
+	methods: [
+		new web3._extend.Method({
+			name: 'propose',
+			call: 'multisig_propose',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'hash',
+			call: 'multisig_hash',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'sign',
+			call: 'multisig_sign',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'combine',
+			call: 'multisig_combine',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'assemble',
+			call: 'multisig_assemble',
+			params: 2,
+			inputFormatter: [null, web3._extend.utils.fromDecimal]
+		}),
+	]
+});
+`
+
+const MetadataJs = `
+web3._extend({
+	property: 'metadata',
+	methods: [
+		new web3._extend.Method({
+			name: 'get',
+			call: 'metadata_get',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'register',
+			call: 'metadata_register',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'remove',
+			call: 'metadata_remove',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'decodeCall',
+			call: 'metadata_decodeCall',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'decodeLog',
+			call: 'metadata_decodeLog',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+	],
+	properties: [
+		new web3._extend.Property({
+			name: 'contracts',
+			getter: 'metadata_contracts'
+		}),
+	]
+});
+`
+
 const TxpoolJs = `
 web3._extend({
 	property: 'txpool',
@@ -760,6 +999,11 @@ web3._extend({
 			call: 'txpool_contentFrom',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'nonceGaps',
+			call: 'txpool_nonceGaps',
+			params: 1,
+		}),
 	]
 });
 `
@@ -0,0 +1,208 @@
+// Package labels implements a node-local registry of address labels, the names
+// an operator gives to well-known addresses such as bridges and pools. Labelled
+// addresses are annotated in the log output, in traces and in the console.
+//
+// The labels are kept in a JSON file in the data directory of the node, so the
+// processes of all contexts sharing a data directory share their labels.
+package labels
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// fileName is the file in the data directory holding the labels.
+const fileName = "addresslabels.json"
+
+// MaxLabelLength is the maximum length of a label in bytes.
+const MaxLabelLength = 64
+
+// errInvalidLabel is returned when labelling an address with an empty, over
+// long or non-printable label.
+var errInvalidLabel = fmt.Errorf("label must be 1 to %d printable characters", MaxLabelLength)
+
+// Labeler provides the labels of addresses.
+type Labeler interface {
+	// Label returns the label of an address, empty if it is not labelled.
+	Label(addr common.Address) string
+}
+
+// Backend is implemented by API backends offering address labels to the APIs
+// annotating their output with them.
+type Backend interface {
+	// AddressLabels returns the label registry, nil if there is none.
+	AddressLabels() Labeler
+}
+
+// Store is a Labeler persisting the labels in a JSON file.
+type Store struct {
+	path string // file holding the labels, empty to keep them in memory only
+
+	lock   sync.RWMutex
+	labels map[common.Address]string
+}
+
+// NewStore creates a label store backed by the given file, loading the labels
+// already in it. An empty path creates a store kept in memory only.
+func NewStore(path string) (*Store, error) {
+	store := &Store{path: path, labels: make(map[common.Address]string)}
+	if path == "" {
+		return store, nil
+	}
+	blob, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(blob, &store.labels); err != nil {
+		return nil, fmt.Errorf("invalid label file %s: %v", path, err)
+	}
+	return store, nil
+}
+
+// New creates the label store in the data directory of the node, registers its
+// APIs and installs it to annotate the addresses in the log output.
+func New(stack *node.Node) (*Store, error) {
+	store, err := NewStore(stack.ResolvePath(fileName))
+	if err != nil {
+		return nil, err
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "admin",
+		Version:   "1.0",
+		Service:   &PrivateAdminAPI{store},
+	}})
+	log.SetLabeler(store.logLabel)
+	return store, nil
+}
+
+// Label implements Labeler.
+func (s *Store) Label(addr common.Address) string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.labels[addr]
+}
+
+// Labels returns all the labelled addresses and their labels.
+func (s *Store) Labels() map[common.Address]string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	labels := make(map[common.Address]string, len(s.labels))
+	for addr, label := range s.labels {
+		labels[addr] = label
+	}
+	return labels
+}
+
+// SetLabel labels an address, replacing any earlier label of it.
+func (s *Store) SetLabel(addr common.Address, label string) error {
+	label = strings.TrimSpace(label)
+	if len(label) == 0 || len(label) > MaxLabelLength || strings.IndexFunc(label, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0 {
+		return errInvalidLabel
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	prev, ok := s.labels[addr]
+	s.labels[addr] = label
+	if err := s.save(); err != nil {
+		if ok {
+			s.labels[addr] = prev
+		} else {
+			delete(s.labels, addr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Remove deletes the label of an address.
+func (s *Store) Remove(addr common.Address) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	prev, ok := s.labels[addr]
+	if !ok {
+		return fmt.Errorf("no label for %s", addr.Hex())
+	}
+	delete(s.labels, addr)
+	if err := s.save(); err != nil {
+		s.labels[addr] = prev
+		return err
+	}
+	return nil
+}
+
+// save writes the labels into the backing file. The caller must hold the lock.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	blob, err := json.MarshalIndent(s.labels, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write through a temporary file so a crash never leaves a truncated file
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, blob, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// logLabel returns the label of a logged address, empty for other values.
+func (s *Store) logLabel(value interface{}) string {
+	switch v := value.(type) {
+	case common.Address:
+		return s.Label(v)
+	case *common.Address:
+		if v != nil {
+			return s.Label(*v)
+		}
+	}
+	return ""
+}
+
+// PrivateAdminAPI offers the management of the address labels over the
+// authenticated RPC endpoints.
+type PrivateAdminAPI struct {
+	store *Store
+}
+
+// LabelAddress labels an address, replacing any earlier label of it.
+func (api *PrivateAdminAPI) LabelAddress(addr common.Address, label string) error {
+	return api.store.SetLabel(addr, label)
+}
+
+// UnlabelAddress deletes the label of an address.
+func (api *PrivateAdminAPI) UnlabelAddress(addr common.Address) error {
+	return api.store.Remove(addr)
+}
+
+// AddressLabels returns all the labelled addresses and their labels.
+func (api *PrivateAdminAPI) AddressLabels() map[common.Address]string {
+	return api.store.Labels()
+}
+
+// AddressLabel returns the label of an address.
+func (api *PrivateAdminAPI) AddressLabel(addr common.Address) (string, error) {
+	if label := api.store.Label(addr); label != "" {
+		return label, nil
+	}
+	return "", errors.New("address not labelled")
+}
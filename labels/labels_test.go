@@ -0,0 +1,76 @@
+package labels
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/log"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "labels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var (
+		path   = filepath.Join(dir, fileName)
+		bridge = common.HexToAddress("0x1100000000000000000000000000000000000001")
+		pool   = common.HexToAddress("0x1100000000000000000000000000000000000002")
+	)
+	store, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	for _, label := range []string{"", "  ", strings.Repeat("x", MaxLabelLength+1), "bad\nlabel"} {
+		if err := store.SetLabel(bridge, label); err == nil {
+			t.Errorf("accepted invalid label %q", label)
+		}
+	}
+	if err := store.SetLabel(bridge, " Bridge "); err != nil {
+		t.Fatalf("failed to label address: %v", err)
+	}
+	if err := store.SetLabel(pool, "Mining pool"); err != nil {
+		t.Fatalf("failed to label address: %v", err)
+	}
+	if err := store.Remove(common.Address{}); err == nil {
+		t.Errorf("removed label of unlabelled address")
+	}
+	// Reopen the store to read the persisted labels
+	if store, err = NewStore(path); err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	if label := store.Label(bridge); label != "Bridge" {
+		t.Errorf("label mismatch: have %q, want %q", label, "Bridge")
+	}
+	if err := store.Remove(bridge); err != nil {
+		t.Fatalf("failed to remove label: %v", err)
+	}
+	if store, _ = NewStore(path); store.Label(bridge) != "" || len(store.Labels()) != 1 {
+		t.Errorf("removed label persisted: %v", store.Labels())
+	}
+}
+
+func TestLogLabels(t *testing.T) {
+	store, _ := NewStore("")
+	pool := common.HexToAddress("0x1100000000000000000000000000000000000002")
+	store.SetLabel(pool, "Mining pool")
+
+	log.SetLabeler(store.logLabel)
+	defer log.SetLabeler(func(interface{}) string { return "" })
+
+	out := new(bytes.Buffer)
+	logger := log.New()
+	logger.SetHandler(log.StreamHandler(out, log.LogfmtFormat()))
+	logger.Info("Paid", "to", pool, "from", common.Address{}, "amount", 1)
+
+	if want := `to="0x1100000000000000000000000000000000000002(Mining pool)" from=0x0000000000000000000000000000000000000000 amount=1`; !strings.Contains(out.String(), want) {
+		t.Errorf("log output mismatch: have %q, want %q", out.String(), want)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/spruce-solutions/go-quai/core/bloombits"
 	"github.com/spruce-solutions/go-quai/core/rawdb"
 	"github.com/spruce-solutions/go-quai/core/state"
+	"github.com/spruce-solutions/go-quai/core/state/snapshot"
 	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/core/vm"
 	"github.com/spruce-solutions/go-quai/eth/gasprice"
@@ -124,6 +125,10 @@ func (b *LesApiBackend) GetExternalBlockByHashAndContext(hash common.Hash, conte
 	return nil, errors.New("light client does not support external block caching")
 }
 
+func (b *LesApiBackend) GetExternalBlocks(header *types.Header) ([]*types.ExternalBlock, error) {
+	return nil, errors.New("light client does not support external block caching")
+}
+
 func (b *LesApiBackend) GetAncestorByLocation(hash common.Hash, location []byte) (*types.Header, error) {
 	return nil, errors.New("light client does not support getting ancestor by location")
 }
@@ -175,6 +180,10 @@ func (b *LesApiBackend) StateAndHeaderByNumber(ctx context.Context, number rpc.B
 	return light.NewState(ctx, header, b.eth.odr), header, nil
 }
 
+func (b *LesApiBackend) Snapshot(root common.Hash) snapshot.Snapshot {
+	return nil
+}
+
 func (b *LesApiBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, *types.Header, error) {
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.StateAndHeaderByNumber(ctx, blockNr)
@@ -299,6 +308,13 @@ func (b *LesApiBackend) SubscribeReOrgEvent(ch chan<- core.ReOrgRollup) event.Su
 	})
 }
 
+func (b *LesApiBackend) SubscribeForkChoiceEvent(ch chan<- core.ForkChoiceEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SubscribeMissingExternalBlockEvent(ch chan<- core.MissingExternalBlock) event.Subscription {
 	return event.NewSubscription(func(quit <-chan struct{}) error {
 		<-quit
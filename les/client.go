@@ -60,6 +60,7 @@ type LightEthereum struct {
 	handler            *clientHandler
 	txPool             *light.TxPool
 	blockchain         *light.LightChain
+	domClient          *core.DomClient
 	serverPool         *vfc.ServerPool
 	serverPoolIterator enode.Iterator
 	pruner             *pruner
@@ -142,6 +143,12 @@ func New(stack *node.Node, config *ethconfig.Config) (*LightEthereum, error) {
 		return nil, err
 	}
 	leth.chainReader = leth.blockchain
+
+	// Anchor coincident headers to the dominant chain, unless running in prime
+	if types.QuaiNetworkContext != params.PRIME && config.DomUrl != "" {
+		leth.domClient = core.MakeDomClient(config.DomUrl, config.Client)
+		leth.blockchain.SetDomChain(leth.domClient)
+	}
 	leth.txPool = light.NewTxPool(leth.chainConfig, leth.blockchain, leth.relay)
 
 	// Set up checkpoint oracle.
@@ -379,6 +386,9 @@ func (s *LightEthereum) Stop() error {
 	s.bloomIndexer.Close()
 	s.chtIndexer.Close()
 	s.blockchain.Stop()
+	if s.domClient != nil {
+		s.domClient.Close()
+	}
 	s.handler.stop()
 	s.txPool.Stop()
 	s.engine.Close()
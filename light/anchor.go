@@ -0,0 +1,84 @@
+package light
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+// anchorTimeout is the time looking up a coincident header on the dominant
+// chain may take.
+const anchorTimeout = 5 * time.Second
+
+var (
+	anchorMeter         = metrics.NewRegisteredMeter("light/anchor/checked", nil)
+	anchorDeferredMeter = metrics.NewRegisteredMeter("light/anchor/deferred", nil)
+
+	// errUnanchored is returned if a coincident header isn't known to the
+	// dominant chain it claims to extend.
+	errUnanchored = errors.New("coincident header unknown to dominant chain")
+)
+
+// DomHeaderReader retrieves headers of the dominant chain by hash, nil being
+// returned for unknown ones.
+type DomHeaderReader interface {
+	HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error)
+}
+
+// SetDomChain sets the dominant chain the coincident headers inserted into the
+// light chain are anchored to. Headers are only checked against the local
+// context without one.
+func (lc *LightChain) SetDomChain(dom DomHeaderReader) {
+	lc.chainmu.Lock()
+	defer lc.chainmu.Unlock()
+
+	lc.dom = dom
+}
+
+// anchor checks that the coincident headers of the chain, the ones also
+// satisfying the difficulty of a dominant context, are part of the dominant
+// chain. A coincident header is the same header in every context it's
+// coincident in, so the dominant chain knowing its hash anchors the light chain
+// to the work done in the dominant contexts. While the dominant chain is
+// unreachable, the headers from the first coincident one on are deferred with
+// an error wrapping consensus.ErrSliceNotSynced, to be retried once it answers.
+// The returned index is the one of the failing header.
+func (lc *LightChain) anchor(chain []*types.Header) (int, error) {
+	lc.chainmu.RLock()
+	dom := lc.dom
+	lc.chainmu.RUnlock()
+
+	if dom == nil {
+		return 0, nil
+	}
+	for i, header := range chain {
+		order, err := lc.engine.GetDifficultyOrder(header)
+		if err != nil {
+			return i, err
+		}
+		if order >= types.QuaiNetworkContext {
+			continue
+		}
+		hash := header.Hash()
+		ctx, cancel := context.WithTimeout(context.Background(), anchorTimeout)
+		domHeader, err := dom.HeaderByHash(ctx, hash)
+		cancel()
+		if err != nil {
+			anchorDeferredMeter.Mark(1)
+			log.Debug("Dominant chain unreachable, coincident header deferred", "number", header.Number, "hash", hash, "err", err)
+			return i, fmt.Errorf("%w: dominant chain unreachable for #%d [%x]: %v", consensus.ErrSliceNotSynced, header.Number[types.QuaiNetworkContext], hash, err)
+		}
+		anchorMeter.Mark(1)
+		if domHeader == nil || domHeader.Hash() != hash {
+			return i, fmt.Errorf("%w: #%d [%x], order %d", errUnanchored, header.Number[types.QuaiNetworkContext], hash, order)
+		}
+	}
+	return 0, nil
+}
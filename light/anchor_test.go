@@ -0,0 +1,128 @@
+package light
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// anchorTestEngine assigns the difficulty orders of the headers from a table,
+// the others being of the zone order.
+type anchorTestEngine struct {
+	consensus.Engine // Unused methods panic
+
+	orders map[common.Hash]int
+}
+
+func (e *anchorTestEngine) GetDifficultyOrder(header *types.Header) (int, error) {
+	if order, ok := e.orders[header.Hash()]; ok {
+		return order, nil
+	}
+	return params.ZONE, nil
+}
+
+// fakeDomReader is a dominant chain knowing a set of headers, failing every
+// lookup while unreachable.
+type fakeDomReader struct {
+	headers     map[common.Hash]*types.Header
+	unreachable bool
+	lookups     int
+}
+
+func (d *fakeDomReader) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	d.lookups++
+	if d.unreachable {
+		return nil, errors.New("connection refused")
+	}
+	return d.headers[hash], nil
+}
+
+// newAnchorTest creates a zone chain of headers with the given difficulty
+// orders, along with a light chain anchoring them to a dominant chain knowing
+// the coincident ones.
+func newAnchorTest(t *testing.T, orders []int) (*LightChain, *fakeDomReader, []*types.Header) {
+	prevContext := types.QuaiNetworkContext
+	types.QuaiNetworkContext = params.ZONE
+	t.Cleanup(func() { types.QuaiNetworkContext = prevContext })
+
+	var (
+		engine  = &anchorTestEngine{orders: make(map[common.Hash]int)}
+		dom     = &fakeDomReader{headers: make(map[common.Hash]*types.Header)}
+		headers = make([]*types.Header, len(orders))
+	)
+	for i, order := range orders {
+		header := types.NewEmptyHeader()
+		header.Number[params.ZONE] = big.NewInt(int64(i + 1))
+		if i > 0 {
+			header.ParentHash[params.ZONE] = headers[i-1].Hash()
+		}
+		headers[i] = header
+
+		engine.orders[header.Hash()] = order
+		if order < params.ZONE {
+			dom.headers[header.Hash()] = header
+		}
+	}
+	lc := &LightChain{engine: engine}
+	lc.SetDomChain(dom)
+	return lc, dom, headers
+}
+
+// Tests that coincident headers known to the dominant chain are anchored, and
+// that only the coincident ones are looked up.
+func TestAnchorKnown(t *testing.T) {
+	lc, dom, headers := newAnchorTest(t, []int{2, 1, 2, 0, 2})
+
+	if i, err := lc.anchor(headers); err != nil {
+		t.Fatalf("anchored chain rejected at %d: %v", i, err)
+	}
+	if dom.lookups != 2 {
+		t.Errorf("dominant lookups mismatch: have %d, want 2", dom.lookups)
+	}
+	// Without a dominant chain nothing is checked
+	lc.SetDomChain(nil)
+	dom.headers = nil
+	if i, err := lc.anchor(headers); err != nil {
+		t.Errorf("chain without dominant rejected at %d: %v", i, err)
+	}
+}
+
+// Tests that coincident headers unknown to the dominant chain, or known by it
+// under another hash, are rejected.
+func TestAnchorUnknown(t *testing.T) {
+	lc, dom, headers := newAnchorTest(t, []int{2, 1, 2, 0, 2})
+
+	delete(dom.headers, headers[3].Hash())
+	if i, err := lc.anchor(headers); !errors.Is(err, errUnanchored) || i != 3 {
+		t.Errorf("unknown header result mismatch: have %d, %v, want 3, %v", i, err, errUnanchored)
+	}
+	dom.headers[headers[3].Hash()] = headers[2]
+	if i, err := lc.anchor(headers); !errors.Is(err, errUnanchored) || i != 3 {
+		t.Errorf("mismatching header result mismatch: have %d, %v, want 3, %v", i, err, errUnanchored)
+	}
+}
+
+// Tests that coincident headers are deferred, rather than accepted unanchored,
+// while the dominant chain is unreachable, and anchored once it answers.
+func TestAnchorUnreachable(t *testing.T) {
+	lc, dom, headers := newAnchorTest(t, []int{2, 2, 1, 2, 0})
+
+	dom.unreachable = true
+	if i, err := lc.anchor(headers); !errors.Is(err, consensus.ErrSliceNotSynced) || i != 2 {
+		t.Errorf("unreachable result mismatch: have %d, %v, want 2, %v", i, err, consensus.ErrSliceNotSynced)
+	}
+	// Zone headers don't need the dominant chain
+	if i, err := lc.anchor(headers[:2]); err != nil {
+		t.Errorf("zone headers deferred at %d: %v", i, err)
+	}
+	dom.unreachable = false
+	if i, err := lc.anchor(headers); err != nil {
+		t.Errorf("anchored chain rejected at %d: %v", i, err)
+	}
+}
@@ -54,6 +54,7 @@ type LightChain struct {
 	chainDb       ethdb.Database
 	engine        consensus.Engine
 	odr           OdrBackend
+	dom           DomHeaderReader // Dominant chain coincident headers are anchored to
 	chainFeed     event.Feed
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
@@ -360,7 +361,7 @@ func (lc *LightChain) postChainEvents(events []interface{}) {
 		switch ev := event.(type) {
 		case core.ChainEvent:
 			if lc.CurrentHeader().Hash() == ev.Hash {
-				lc.chainHeadFeed.Send(core.ChainHeadEvent{Block: ev.Block})
+				lc.chainHeadFeed.Send(core.ChainHeadEvent{Block: ev.Block, Context: ev.Context, Order: ev.Order})
 			}
 			lc.chainFeed.Send(ev)
 		case core.ChainSideEvent:
@@ -379,7 +380,8 @@ func (lc *LightChain) postChainEvents(events []interface{}) {
 // because nonces can be verified sparsely, not needing to check each.
 //
 // In the case of a light chain, InsertHeaderChain also creates and posts light
-// chain events when necessary.
+// chain events when necessary. Coincident headers the dominant chain can't be
+// asked about are deferred along with the headers following them.
 func (lc *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (int, error) {
 	if atomic.LoadInt32(&lc.disableCheckFreq) == 1 {
 		checkFreq = 0
@@ -388,6 +390,15 @@ func (lc *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (i
 	if i, err := lc.hc.ValidateHeaderChain(chain, checkFreq); err != nil {
 		return i, err
 	}
+	// Insert the headers preceding a deferred coincident one, the rest being
+	// retried once the dominant chain answers
+	deferred, deferErr := lc.anchor(chain)
+	if deferErr != nil {
+		if !errors.Is(deferErr, consensus.ErrSliceNotSynced) {
+			return deferred, deferErr
+		}
+		chain = chain[:deferred]
+	}
 
 	// Make sure only one thread manipulates the chain at once
 	lc.chainmu.Lock()
@@ -396,8 +407,11 @@ func (lc *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (i
 	lc.wg.Add(1)
 	defer lc.wg.Done()
 
+	if len(chain) == 0 {
+		return deferred, deferErr
+	}
 	status, err := lc.hc.InsertHeaderChain(chain, start)
-	if err != nil || len(chain) == 0 {
+	if err != nil {
 		return 0, err
 	}
 
@@ -406,16 +420,20 @@ func (lc *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (i
 		events     = make([]interface{}, 0, 1)
 		lastHeader = chain[len(chain)-1]
 		block      = types.NewBlockWithHeader(lastHeader)
+		order      = types.QuaiNetworkContext
 	)
+	if blockOrder, err := lc.engine.GetDifficultyOrder(lastHeader); err == nil {
+		order = blockOrder
+	}
 	switch status {
 	case core.CanonStatTy:
-		events = append(events, core.ChainEvent{Block: block, Hash: block.Hash()})
+		events = append(events, core.ChainEvent{Block: block, Hash: block.Hash(), Context: types.QuaiNetworkContext, Order: order})
 	case core.SideStatTy:
-		events = append(events, core.ChainSideEvent{Block: block})
+		events = append(events, core.ChainSideEvent{Block: block, Context: types.QuaiNetworkContext, Order: order})
 	}
 	lc.postChainEvents(events)
 
-	return 0, err
+	return deferred, deferErr
 }
 
 // CurrentHeader retrieves the current head header of the canonical chain. The
@@ -418,7 +418,7 @@ func (pool *TxPool) add(ctx context.Context, tx *types.Transaction) error {
 		// Notify the subscribers. This event is posted in a goroutine
 		// because it's possible that somewhere during the post "Remove transaction"
 		// gets called which will then wait for the global tx pool lock and deadlock.
-		go pool.txFeed.Send(core.NewTxsEvent{Txs: types.Transactions{tx}})
+		go pool.txFeed.Send(core.NewTxsEvent{Txs: types.Transactions{tx}, Context: types.QuaiNetworkContext})
 	}
 
 	// Print a log message if low enough level is set
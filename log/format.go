@@ -37,6 +37,16 @@ func PrintOrigins(print bool) {
 	}
 }
 
+// SetLabeler installs the function annotating logged values with node-local
+// labels, such as the names of well-known addresses, in the terminal and logfmt
+// formats. The function returns an empty label for values it does not know.
+func SetLabeler(fn func(value interface{}) string) {
+	labeler.Store(fn)
+}
+
+// labeler holds the function installed by SetLabeler.
+var labeler atomic.Value
+
 // locationEnabled is an atomic flag controlling whether the terminal formatter
 // should append the log locations too when printing entries.
 var locationEnabled uint32
@@ -169,6 +179,8 @@ func logfmt(buf *bytes.Buffer, ctx []interface{}, color int, term bool) {
 		v := formatLogfmtValue(ctx[i+1], term)
 		if !ok {
 			k, v = errorKey, formatLogfmtValue(k, term)
+		} else if label := labelOf(ctx[i+1]); label != "" {
+			v = formatLogfmtValue(v+"("+label+")", term)
 		}
 
 		// XXX: we should probably check that all of your key bytes aren't invalid
@@ -324,6 +336,16 @@ func formatJSONValue(value interface{}) interface{} {
 	}
 }
 
+// labelOf returns the label of a logged value, empty if no labeler is installed
+// or the value is not labeled.
+func labelOf(value interface{}) string {
+	fn, ok := labeler.Load().(func(value interface{}) string)
+	if !ok || value == nil {
+		return ""
+	}
+	return fn(value)
+}
+
 // formatValue formats a value for serialization
 func formatLogfmtValue(value interface{}, term bool) string {
 	if value == nil {
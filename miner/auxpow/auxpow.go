@@ -0,0 +1,198 @@
+// Package auxpow implements merged mining of auxiliary chains with Quai.
+//
+// Auxiliary chains register commitments, typically the hash of the block they
+// are mining, with the miner. The commitments are hashed into a merkle tree
+// whose root is set as the extra data of the mined header. Once the header is
+// sealed, its proof of work is exported in the auxpow layout of merged mining:
+// the sealed parent header, and the merkle branch linking the commitment of the
+// auxiliary chain to the root the parent header commits to. The auxiliary chain
+// accepts its block if the seal hash of the parent header meets its own target.
+package auxpow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/rlp"
+)
+
+var (
+	// ErrUnknownChain is returned if no commitment of an auxiliary chain is in
+	// the commitment tree.
+	ErrUnknownChain = errors.New("no commitment of auxiliary chain")
+
+	// ErrNotCommitted is returned if the parent header doesn't commit to the
+	// root the merkle branch leads to.
+	ErrNotCommitted = errors.New("parent header doesn't commit to auxiliary chain")
+
+	// ErrInvalidPoW is returned if the parent header doesn't meet the target of
+	// the auxiliary chain.
+	ErrInvalidPoW = errors.New("parent header doesn't satisfy auxiliary target")
+)
+
+// SealHasher computes the hash the proof of work of a header is checked on.
+type SealHasher interface {
+	SealHash(header *types.Header) common.Hash
+}
+
+// leaf is the commitment of an auxiliary chain.
+type leaf struct {
+	chain      common.Hash
+	commitment common.Hash
+}
+
+// hash returns the leaf hash of the commitment, binding it to the chain.
+func (l leaf) hash() common.Hash {
+	return crypto.Keccak256Hash(l.chain.Bytes(), l.commitment.Bytes())
+}
+
+// Tree is the merkle tree over the commitments of the auxiliary chains, the
+// leaves being ordered by chain id. Odd nodes are paired with themselves.
+type Tree struct {
+	leaves []leaf
+	levels [][]common.Hash // Node hashes from the leaves up to the root
+}
+
+// NewTree builds the commitment tree of the commitments by auxiliary chain id.
+func NewTree(commitments map[common.Hash]common.Hash) *Tree {
+	leaves := make([]leaf, 0, len(commitments))
+	for chain, commitment := range commitments {
+		leaves = append(leaves, leaf{chain, commitment})
+	}
+	sort.Slice(leaves, func(i, j int) bool {
+		return bytes.Compare(leaves[i].chain[:], leaves[j].chain[:]) < 0
+	})
+	level := make([]common.Hash, len(leaves))
+	for i, l := range leaves {
+		level[i] = l.hash()
+	}
+	t := &Tree{leaves: leaves, levels: [][]common.Hash{level}}
+	for len(level) > 1 {
+		next := make([]common.Hash, (len(level)+1)/2)
+		for i := range next {
+			left, right := level[2*i], level[2*i]
+			if 2*i+1 < len(level) {
+				right = level[2*i+1]
+			}
+			next[i] = crypto.Keccak256Hash(left.Bytes(), right.Bytes())
+		}
+		t.levels = append(t.levels, next)
+		level = next
+	}
+	return t
+}
+
+// Root returns the root hash of the tree, the zero hash if it's empty.
+func (t *Tree) Root() common.Hash {
+	top := t.levels[len(t.levels)-1]
+	if len(top) == 0 {
+		return common.Hash{}
+	}
+	return top[0]
+}
+
+// Len returns the number of commitments in the tree.
+func (t *Tree) Len() int {
+	return len(t.leaves)
+}
+
+// Branch returns the commitment of the auxiliary chain, along with the merkle
+// branch from its leaf to the root and the index of the leaf.
+func (t *Tree) Branch(chain common.Hash) (common.Hash, []common.Hash, uint64, error) {
+	index := sort.Search(len(t.leaves), func(i int) bool {
+		return bytes.Compare(t.leaves[i].chain[:], chain[:]) >= 0
+	})
+	if index == len(t.leaves) || t.leaves[index].chain != chain {
+		return common.Hash{}, nil, 0, fmt.Errorf("%w %x", ErrUnknownChain, chain)
+	}
+	var branch []common.Hash
+	for i, level := range t.levels[:len(t.levels)-1] {
+		sibling := (index >> uint(i)) ^ 1
+		if sibling >= len(level) {
+			sibling = len(level) - 1
+		}
+		branch = append(branch, level[sibling])
+	}
+	return t.leaves[index].commitment, branch, uint64(index), nil
+}
+
+// AuxPow is the proof that an auxiliary block was merge-mined with a sealed
+// Quai header.
+type AuxPow struct {
+	ChainID    common.Hash    `json:"chainId"`
+	Commitment common.Hash    `json:"commitment"`
+	Branch     []common.Hash  `json:"branch"`  // Merkle branch from the commitment to the root
+	Index      hexutil.Uint64 `json:"index"`   // Index of the commitment, its bits selecting the branch sides
+	Context    hexutil.Uint64 `json:"context"` // Context of the extra data holding the root
+	Header     hexutil.Bytes  `json:"header"`  // RLP encoding of the sealed parent header
+}
+
+// New exports the proof of work of the sealed header for the auxiliary chain,
+// the header committing to the root of the tree in the given context.
+func New(header *types.Header, context int, tree *Tree, chain common.Hash) (*AuxPow, error) {
+	commitment, branch, index, err := tree.Branch(chain)
+	if err != nil {
+		return nil, err
+	}
+	if context >= len(header.Extra) || !bytes.Equal(header.Extra[context], tree.Root().Bytes()) {
+		return nil, ErrNotCommitted
+	}
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	return &AuxPow{
+		ChainID:    chain,
+		Commitment: commitment,
+		Branch:     branch,
+		Index:      hexutil.Uint64(index),
+		Context:    hexutil.Uint64(context),
+		Header:     enc,
+	}, nil
+}
+
+// ParentHeader decodes the sealed parent header.
+func (p *AuxPow) ParentHeader() (*types.Header, error) {
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(p.Header, header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// Root returns the root of the commitment tree the merkle branch leads to.
+func (p *AuxPow) Root() common.Hash {
+	hash := leaf{p.ChainID, p.Commitment}.hash()
+	for i, sibling := range p.Branch {
+		if (uint64(p.Index)>>uint(i))&1 == 0 {
+			hash = crypto.Keccak256Hash(hash.Bytes(), sibling.Bytes())
+		} else {
+			hash = crypto.Keccak256Hash(sibling.Bytes(), hash.Bytes())
+		}
+	}
+	return hash
+}
+
+// Verify checks that the parent header commits to the commitment of the
+// auxiliary chain, and that its seal hash meets the target of the auxiliary
+// chain.
+func (p *AuxPow) Verify(hasher SealHasher, target *big.Int) error {
+	header, err := p.ParentHeader()
+	if err != nil {
+		return err
+	}
+	if uint64(p.Context) >= uint64(len(header.Extra)) || !bytes.Equal(header.Extra[p.Context], p.Root().Bytes()) {
+		return ErrNotCommitted
+	}
+	if new(big.Int).SetBytes(hasher.SealHash(header).Bytes()).Cmp(target) > 0 {
+		return ErrInvalidPoW
+	}
+	return nil
+}
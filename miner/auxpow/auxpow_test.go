@@ -0,0 +1,102 @@
+package auxpow
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/crypto"
+)
+
+// keccakSealer hashes the header as the seal hash.
+type keccakSealer struct{}
+
+func (keccakSealer) SealHash(header *types.Header) common.Hash {
+	return header.Hash()
+}
+
+func testHeader(extra []byte) *types.Header {
+	header := &types.Header{
+		ParentHash:        make([]common.Hash, 3),
+		Number:            []*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)},
+		Extra:             make([][]byte, 3),
+		BaseFee:           []*big.Int{big.NewInt(0), big.NewInt(0), big.NewInt(0)},
+		GasLimit:          make([]uint64, 3),
+		Coinbase:          make([]common.Address, 3),
+		Difficulty:        []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)},
+		NetworkDifficulty: []*big.Int{big.NewInt(1), big.NewInt(1), big.NewInt(1)},
+		Root:              make([]common.Hash, 3),
+		TxHash:            make([]common.Hash, 3),
+		ReceiptHash:       make([]common.Hash, 3),
+		UncleHash:         make([]common.Hash, 3),
+		GasUsed:           make([]uint64, 3),
+		Bloom:             make([]types.Bloom, 3),
+		Location:          []byte{1, 1},
+	}
+	header.Extra[2] = extra
+	return header
+}
+
+// Tests that the branches of trees of every size lead to the root.
+func TestTreeBranches(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		commitments := make(map[common.Hash]common.Hash)
+		for i := 0; i < size; i++ {
+			commitments[crypto.Keccak256Hash([]byte{byte(i)})] = common.BytesToHash([]byte{byte(i + 1)})
+		}
+		tree := NewTree(commitments)
+		if tree.Len() != size {
+			t.Fatalf("size %d: tree holds %d commitments", size, tree.Len())
+		}
+		for chain, want := range commitments {
+			commitment, branch, index, err := tree.Branch(chain)
+			if err != nil {
+				t.Fatalf("size %d: failed to get branch: %v", size, err)
+			}
+			if commitment != want {
+				t.Fatalf("size %d: commitment mismatch: have %x, want %x", size, commitment, want)
+			}
+			pow := &AuxPow{ChainID: chain, Commitment: commitment, Branch: branch, Index: hexutil.Uint64(index)}
+			if root := pow.Root(); root != tree.Root() {
+				t.Fatalf("size %d: branch of %x leads to %x, want %x", size, chain, root, tree.Root())
+			}
+		}
+	}
+	if _, _, _, err := NewTree(nil).Branch(common.Hash{1}); !errors.Is(err, ErrUnknownChain) {
+		t.Fatalf("unknown chain error mismatch: have %v, want %v", err, ErrUnknownChain)
+	}
+}
+
+// Tests that exported proofs verify against the target of the auxiliary chain,
+// and are rejected if tampered with.
+func TestAuxPowVerify(t *testing.T) {
+	chain := common.Hash{0xaa}
+	tree := NewTree(map[common.Hash]common.Hash{
+		chain:  {0x01},
+		{0xbb}: {0x02},
+		{0xcc}: {0x03},
+	})
+	header := testHeader(tree.Root().Bytes())
+
+	if _, err := New(testHeader(nil), 2, tree, chain); !errors.Is(err, ErrNotCommitted) {
+		t.Fatalf("uncommitted header error mismatch: have %v, want %v", err, ErrNotCommitted)
+	}
+	pow, err := New(header, 2, tree, chain)
+	if err != nil {
+		t.Fatalf("failed to export proof: %v", err)
+	}
+	max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+	if err := pow.Verify(keccakSealer{}, max); err != nil {
+		t.Fatalf("failed to verify proof: %v", err)
+	}
+	if err := pow.Verify(keccakSealer{}, big.NewInt(0)); !errors.Is(err, ErrInvalidPoW) {
+		t.Fatalf("target error mismatch: have %v, want %v", err, ErrInvalidPoW)
+	}
+	pow.Commitment = common.Hash{0x02}
+	if err := pow.Verify(keccakSealer{}, max); !errors.Is(err, ErrNotCommitted) {
+		t.Fatalf("tampered commitment error mismatch: have %v, want %v", err, ErrNotCommitted)
+	}
+}
@@ -0,0 +1,238 @@
+package miner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+// defaultElectionTTL is the time the block producer lease is held without
+// renewal if none is configured.
+const defaultElectionTTL = 5 * time.Second
+
+var (
+	electionLeaderGauge   = metrics.NewRegisteredGauge("miner/election/leader", nil)
+	electionElectedMeter  = metrics.NewRegisteredMeter("miner/election/elected", nil)
+	electionDemotedMeter  = metrics.NewRegisteredMeter("miner/election/demoted", nil)
+	electionFailuresMeter = metrics.NewRegisteredMeter("miner/election/failures", nil)
+
+	// errStandby is returned by the seal guard of a miner not holding the
+	// block producer lease.
+	errStandby = errors.New("standby miner, not holding the block producer lease")
+)
+
+// LeaseStore is a shared store holding the block producer lease of redundant
+// miners, such as etcd or redis. Implementations must make Acquire and Release
+// atomic against concurrent holders.
+type LeaseStore interface {
+	// Acquire takes the lease under the key for the holder for the ttl if it's
+	// free, or extends it if the holder already owns it. It returns whether the
+	// holder owns the lease.
+	Acquire(ctx context.Context, key, holder string, ttl time.Duration) (bool, error)
+
+	// Release frees the lease if it's owned by the holder.
+	Release(ctx context.Context, key, holder string) error
+
+	// Holder returns the current owner of the lease, empty if it's free.
+	Holder(ctx context.Context, key string) (string, error)
+
+	// Close releases the resources of the store.
+	Close() error
+}
+
+// NewLeaseStore creates the lease store for the URL. Only redis://[:password@]host:port[/db]
+// stores are built in, other stores can be plugged in through the interface.
+func NewLeaseStore(rawurl string) (LeaseStore, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "redis":
+		return newRedisLeaseStore(u)
+	default:
+		return nil, fmt.Errorf("unsupported lease store %q", rawurl)
+	}
+}
+
+// ElectionStatus is the state of the block producer election of the miner.
+type ElectionStatus struct {
+	ID        string    `json:"id"`                  // Holder id of this miner
+	Key       string    `json:"key"`                 // Key of the lease in the store
+	Leader    bool      `json:"leader"`              // Whether this miner is the active block producer
+	Holder    string    `json:"holder"`              // Lease holder as last seen in the store
+	Since     time.Time `json:"since"`               // Time the leadership last changed
+	Expires   time.Time `json:"expires"`             // Time the lease held is considered lost unless renewed
+	LastError string    `json:"lastError,omitempty"` // Error of the last failed store access
+}
+
+// election elects the active block producer among redundant miners of a chain
+// through a lease in a shared store. The holder renews the lease well within
+// its ttl and stops producing work as soon as it can't be sure to hold it, a
+// standby takes over once the lease expires or is released.
+type election struct {
+	store     LeaseStore
+	key, id   string
+	ttl       time.Duration
+	onElected func() // Called when this miner becomes the block producer
+
+	status ElectionStatus
+	lock   sync.RWMutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newElection(store LeaseStore, key, id string, ttl time.Duration, onElected func()) *election {
+	if ttl <= 0 {
+		ttl = defaultElectionTTL
+	}
+	if id == "" {
+		host, _ := os.Hostname()
+		id = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	e := &election{
+		store:     store,
+		key:       key,
+		id:        id,
+		ttl:       ttl,
+		onElected: onElected,
+		status:    ElectionStatus{ID: id, Key: key, Since: time.Now()},
+		quit:      make(chan struct{}),
+	}
+	e.wg.Add(1)
+	go e.loop()
+	return e
+}
+
+// guard is the seal guard refusing work while this miner doesn't hold the lease.
+func (e *election) guard(header *types.Header) error {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	if e.status.Leader && time.Now().Before(e.status.Expires) {
+		return nil
+	}
+	return errStandby
+}
+
+// Status returns the state of the election.
+func (e *election) Status() *ElectionStatus {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	status := e.status
+	status.Leader = status.Leader && time.Now().Before(status.Expires)
+	return &status
+}
+
+// close stops campaigning, releasing the lease if held so a standby takes over
+// without waiting for it to expire.
+func (e *election) close() {
+	close(e.quit)
+	e.wg.Wait()
+
+	e.lock.RLock()
+	leader := e.status.Leader
+	e.lock.RUnlock()
+	if leader {
+		ctx, cancel := context.WithTimeout(context.Background(), e.ttl/4)
+		if err := e.store.Release(ctx, e.key, e.id); err != nil {
+			log.Warn("Failed to release block producer lease", "key", e.key, "err", err)
+		}
+		cancel()
+	}
+	e.setLeader(false, time.Time{})
+	e.store.Close()
+}
+
+func (e *election) loop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.ttl / 4)
+	defer ticker.Stop()
+
+	for {
+		e.campaign()
+		select {
+		case <-ticker.C:
+		case <-e.quit:
+			return
+		}
+	}
+}
+
+// campaign acquires or renews the lease, updating the leadership.
+func (e *election) campaign() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.ttl/4)
+	defer cancel()
+
+	// The lease is only relied on for three quarters of its ttl counted from
+	// before the request, leaving a margin for clock drift against the store
+	start := time.Now()
+	held, err := e.store.Acquire(ctx, e.key, e.id, e.ttl)
+	if err != nil {
+		electionFailuresMeter.Mark(1)
+		e.lock.Lock()
+		e.status.LastError = err.Error()
+		expired := e.status.Leader && !time.Now().Before(e.status.Expires)
+		e.lock.Unlock()
+		if expired {
+			log.Warn("Block producer lease lost, store unreachable", "key", e.key, "err", err)
+			e.setLeader(false, time.Time{})
+		}
+		return
+	}
+	holder := e.id
+	if !held {
+		if holder, err = e.store.Holder(ctx, e.key); err != nil {
+			holder = ""
+		}
+	}
+	e.lock.Lock()
+	e.status.Holder = holder
+	e.status.LastError = ""
+	e.lock.Unlock()
+
+	if held {
+		e.setLeader(true, start.Add(e.ttl*3/4))
+	} else {
+		e.setLeader(false, time.Time{})
+	}
+}
+
+// setLeader updates the leadership, announcing changes.
+func (e *election) setLeader(leader bool, expires time.Time) {
+	e.lock.Lock()
+	changed := leader != e.status.Leader
+	e.status.Leader, e.status.Expires = leader, expires
+	if changed {
+		e.status.Since = time.Now()
+	}
+	holder := e.status.Holder
+	e.lock.Unlock()
+
+	if !changed {
+		return
+	}
+	if leader {
+		electionLeaderGauge.Update(1)
+		electionElectedMeter.Mark(1)
+		log.Info("Elected block producer", "key", e.key, "id", e.id)
+		if e.onElected != nil {
+			e.onElected()
+		}
+		return
+	}
+	electionLeaderGauge.Update(0)
+	electionDemotedMeter.Mark(1)
+	log.Warn("Stepped down as block producer", "key", e.key, "id", e.id, "holder", holder)
+}
@@ -0,0 +1,212 @@
+package miner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// redisAcquireScript takes the lease if free or extends it if owned.
+	redisAcquireScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+if redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2]) then
+	return 1
+end
+return 0`
+
+	// redisReleaseScript frees the lease if owned.
+	redisReleaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+	// redisDialTimeout is the time connecting to the store may take.
+	redisDialTimeout = 2 * time.Second
+)
+
+// redisLeaseStore is a lease store over a redis server, speaking the few
+// commands needed over a single connection.
+type redisLeaseStore struct {
+	addr     string
+	password string
+	db       int
+
+	conn   net.Conn // Connection to the server, nil if not connected
+	reader *bufio.Reader
+	lock   sync.Mutex
+}
+
+func newRedisLeaseStore(u *url.URL) (*redisLeaseStore, error) {
+	if u.Host == "" {
+		return nil, errors.New("redis lease store address missing")
+	}
+	s := &redisLeaseStore{addr: u.Host}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			s.password = password
+		} else {
+			s.password = u.User.Username()
+		}
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		db, err := strconv.Atoi(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis database %q", path)
+		}
+		s.db = db
+	}
+	return s, nil
+}
+
+func (s *redisLeaseStore) Acquire(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	reply, err := s.do(ctx, "EVAL", redisAcquireScript, "1", key, holder, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == int64(1), nil
+}
+
+func (s *redisLeaseStore) Release(ctx context.Context, key, holder string) error {
+	_, err := s.do(ctx, "EVAL", redisReleaseScript, "1", key, holder)
+	return err
+}
+
+func (s *redisLeaseStore) Holder(ctx context.Context, key string) (string, error) {
+	reply, err := s.do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return "", err
+	}
+	return reply.(string), nil
+}
+
+func (s *redisLeaseStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn, s.reader = nil, nil
+	return err
+}
+
+// do runs a command, connecting to the server first if needed. The connection
+// is dropped on any error so the next command starts afresh.
+func (s *redisLeaseStore) do(ctx context.Context, args ...string) (interface{}, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := s.roundtrip(ctx, args...)
+	if err != nil {
+		var redisErr redisError
+		if !errors.As(err, &redisErr) {
+			s.conn.Close()
+			s.conn, s.reader = nil, nil
+		}
+		return nil, err
+	}
+	return reply, nil
+}
+
+// connect dials the server, authenticating and selecting the database.
+func (s *redisLeaseStore) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: redisDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	s.conn, s.reader = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if s.password != "" {
+		setup = append(setup, []string{"AUTH", s.password})
+	}
+	if s.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(s.db)})
+	}
+	for _, args := range setup {
+		if _, err := s.roundtrip(ctx, args...); err != nil {
+			conn.Close()
+			s.conn, s.reader = nil, nil
+			return fmt.Errorf("redis %s failed: %v", args[0], err)
+		}
+	}
+	return nil
+}
+
+// roundtrip sends a command and reads its reply.
+func (s *redisLeaseStore) roundtrip(ctx context.Context, args ...string) (interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(redisDialTimeout)
+	}
+	s.conn.SetDeadline(deadline)
+
+	var cmd strings.Builder
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(s.conn, cmd.String()); err != nil {
+		return nil, err
+	}
+	return readRedisReply(s.reader)
+}
+
+// redisError is an error reply of the server.
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// readRedisReply reads a simple string, error, integer or bulk string reply.
+// Nil bulk strings are returned as nil.
+func readRedisReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		return string(data[:size]), nil
+	default:
+		return nil, fmt.Errorf("redis: unsupported reply %q", line)
+	}
+}
@@ -0,0 +1,218 @@
+package miner
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is a server speaking enough RESP to answer the commands of the
+// lease store from a script, recording the commands and connections it sees.
+type fakeRedis struct {
+	listener net.Listener
+	replies  map[string][]string // Raw replies to hand out per command name, in order
+
+	commands [][]string
+	conns    int
+	lock     sync.Mutex
+}
+
+func newFakeRedis(t *testing.T, replies map[string][]string) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &fakeRedis{listener: listener, replies: replies}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			s.lock.Lock()
+			s.conns++
+			s.lock.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readRedisCommand(reader)
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		s.commands = append(s.commands, args)
+		reply := "-ERR unknown command"
+		if queue := s.replies[args[0]]; len(queue) > 0 {
+			reply, s.replies[args[0]] = queue[0], queue[1:]
+		}
+		s.lock.Unlock()
+
+		if _, err := io.WriteString(conn, reply+"\r\n"); err != nil {
+			return
+		}
+	}
+}
+
+// readRedisCommand reads a command sent as an array of bulk strings.
+func readRedisCommand(r *bufio.Reader) ([]string, error) {
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+	if err != nil || header[0] != '*' {
+		return nil, fmt.Errorf("invalid command header %q", header)
+	}
+	args := make([]string, count)
+	for i := range args {
+		reply, err := readRedisReply(r)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = reply.(string)
+	}
+	return args, nil
+}
+
+// Tests that all the reply types used by the lease store are parsed.
+func TestRedisReplyParsing(t *testing.T) {
+	tests := []struct {
+		raw   string
+		reply interface{}
+		err   error
+	}{
+		{"+OK\r\n", "OK", nil},
+		{":1\r\n", int64(1), nil},
+		{":-3\r\n", int64(-3), nil},
+		{"$6\r\nholder\r\n", "holder", nil},
+		{"$8\r\nhol\r\nder\r\n", "hol\r\nder", nil},
+		{"$0\r\n\r\n", "", nil},
+		{"$-1\r\n", nil, nil},
+		{"-ERR wrong type\r\n", nil, redisError("ERR wrong type")},
+	}
+	for i, tt := range tests {
+		reply, err := readRedisReply(bufio.NewReader(strings.NewReader(tt.raw)))
+		if err != tt.err || reply != tt.reply {
+			t.Errorf("test %d: reply mismatch: have %v (%v), want %v (%v)", i, reply, err, tt.reply, tt.err)
+		}
+	}
+	for _, raw := range []string{"\r\n", "*1\r\n", ":one\r\n", "$x\r\n", "$6\r\nhol"} {
+		if reply, err := readRedisReply(bufio.NewReader(strings.NewReader(raw))); err == nil {
+			t.Errorf("invalid reply %q parsed as %v", raw, reply)
+		}
+	}
+}
+
+// Tests the lease store against a redis server: the connection setup, the
+// replies of the lease commands and the reconnection after failures.
+func TestRedisLeaseStore(t *testing.T) {
+	server := newFakeRedis(t, map[string][]string{
+		"AUTH":   {"+OK"},
+		"SELECT": {"+OK"},
+		"EVAL":   {":1", ":0", "-NOSCRIPT busy", ":1"},
+		"GET":    {"$7\r\nstandby", "$-1"},
+	})
+	store, err := NewLeaseStore(fmt.Sprintf("redis://:secret@%s/2", server.listener.Addr()))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if held, err := store.Acquire(ctx, "producer", "leader", 1500*time.Millisecond); err != nil || !held {
+		t.Fatalf("free lease not acquired: %v, %v", held, err)
+	}
+	if held, err := store.Acquire(ctx, "producer", "leader", time.Second); err != nil || held {
+		t.Fatalf("taken lease acquired: %v, %v", held, err)
+	}
+	if holder, err := store.Holder(ctx, "producer"); err != nil || holder != "standby" {
+		t.Fatalf("holder mismatch: have %q, %v, want standby", holder, err)
+	}
+	if holder, err := store.Holder(ctx, "producer"); err != nil || holder != "" {
+		t.Fatalf("free lease holder mismatch: have %q, %v", holder, err)
+	}
+	// Error replies are returned without dropping the connection
+	var redisErr redisError
+	if err := store.Release(ctx, "producer", "leader"); !errors.As(err, &redisErr) {
+		t.Fatalf("error reply mismatch: have %v, want a redis error", err)
+	}
+	if err := store.Release(ctx, "producer", "leader"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	server.lock.Lock()
+	commands, conns := server.commands, server.conns
+	server.lock.Unlock()
+
+	if conns != 1 {
+		t.Errorf("connections mismatch: have %d, want 1", conns)
+	}
+	want := [][]string{
+		{"AUTH", "secret"},
+		{"SELECT", "2"},
+		{"EVAL", redisAcquireScript, "1", "producer", "leader", "1500"},
+	}
+	for i, args := range want {
+		if strings.Join(commands[i], " ") != strings.Join(args, " ") {
+			t.Errorf("command %d mismatch: have %q, want %q", i, commands[i], args)
+		}
+	}
+	if len(commands) != 8 {
+		t.Errorf("command count mismatch: have %d, want 8", len(commands))
+	}
+	// Unknown replies drop the connection, the next command reconnecting
+	server.lock.Lock()
+	server.replies["EVAL"] = []string{"*0"}
+	server.replies["AUTH"] = []string{"+OK"}
+	server.replies["SELECT"] = []string{"+OK"}
+	server.replies["GET"] = []string{"$6\r\nleader"}
+	server.lock.Unlock()
+
+	if _, err := store.Acquire(ctx, "producer", "leader", time.Second); err == nil {
+		t.Fatalf("unsupported reply accepted")
+	}
+	if holder, err := store.Holder(ctx, "producer"); err != nil || holder != "leader" {
+		t.Fatalf("holder after reconnection mismatch: have %q, %v", holder, err)
+	}
+	server.lock.Lock()
+	conns = server.conns
+	server.lock.Unlock()
+	if conns != 2 {
+		t.Errorf("connections after failure mismatch: have %d, want 2", conns)
+	}
+}
+
+// Tests that lease store URLs are validated.
+func TestNewLeaseStore(t *testing.T) {
+	for _, url := range []string{"etcd://127.0.0.1:2379", "redis://", "redis://127.0.0.1:6379/db", "://"} {
+		if _, err := NewLeaseStore(url); err == nil {
+			t.Errorf("invalid store %q accepted", url)
+		}
+	}
+	store, err := NewLeaseStore("redis://user@127.0.0.1:6379/3")
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	if redis := store.(*redisLeaseStore); redis.password != "user" || redis.db != 3 {
+		t.Errorf("store settings mismatch: password %q, db %d", redis.password, redis.db)
+	}
+}
@@ -0,0 +1,236 @@
+package miner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryLeaseStore is an in-memory lease store, failing every access while err
+// is set.
+type memoryLeaseStore struct {
+	holder  string
+	expires time.Time
+	err     error
+	closed  bool
+	lock    sync.Mutex
+}
+
+func (s *memoryLeaseStore) Acquire(ctx context.Context, key, holder string, ttl time.Duration) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err != nil {
+		return false, s.err
+	}
+	if s.holder != "" && s.holder != holder && time.Now().Before(s.expires) {
+		return false, nil
+	}
+	s.holder, s.expires = holder, time.Now().Add(ttl)
+	return true, nil
+}
+
+func (s *memoryLeaseStore) Release(ctx context.Context, key, holder string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if s.holder == holder {
+		s.holder = ""
+	}
+	return nil
+}
+
+func (s *memoryLeaseStore) Holder(ctx context.Context, key string) (string, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err != nil {
+		return "", s.err
+	}
+	if !time.Now().Before(s.expires) {
+		return "", nil
+	}
+	return s.holder, nil
+}
+
+func (s *memoryLeaseStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+func (s *memoryLeaseStore) fail(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.err = err
+}
+
+// newTestElection creates an election campaigning only when told to, counting
+// the times it gets elected.
+func newTestElection(store LeaseStore, id string, ttl time.Duration) (*election, *int) {
+	elected := new(int)
+	e := &election{
+		store:     store,
+		key:       "producer",
+		id:        id,
+		ttl:       ttl,
+		onElected: func() { *elected++ },
+		status:    ElectionStatus{ID: id, Key: "producer"},
+		quit:      make(chan struct{}),
+	}
+	return e, elected
+}
+
+// Tests that the first miner campaigning takes the lease, the others standing
+// by and seeing it as the holder.
+func TestElectionAcquire(t *testing.T) {
+	store := new(memoryLeaseStore)
+	leader, elected := newTestElection(store, "leader", time.Minute)
+	standby, standbyElected := newTestElection(store, "standby", time.Minute)
+
+	leader.campaign()
+	standby.campaign()
+
+	if status := leader.Status(); !status.Leader || status.Holder != "leader" {
+		t.Fatalf("leader status mismatch: %+v", status)
+	}
+	if err := leader.guard(nil); err != nil {
+		t.Fatalf("leader refused to seal: %v", err)
+	}
+	if *elected != 1 {
+		t.Fatalf("leader election announced %d times, want 1", *elected)
+	}
+	if status := standby.Status(); status.Leader || status.Holder != "leader" {
+		t.Fatalf("standby status mismatch: %+v", status)
+	}
+	if err := standby.guard(nil); err != errStandby {
+		t.Fatalf("standby seal guard mismatch: have %v, want %v", err, errStandby)
+	}
+	if *standbyElected != 0 {
+		t.Fatalf("standby election announced")
+	}
+}
+
+// Tests that campaigning again renews the lease held, pushing its expiry out
+// without announcing the leadership again.
+func TestElectionRenew(t *testing.T) {
+	store := new(memoryLeaseStore)
+	e, elected := newTestElection(store, "leader", time.Minute)
+
+	e.campaign()
+	first := e.Status()
+
+	time.Sleep(10 * time.Millisecond)
+	e.campaign()
+	second := e.Status()
+
+	if !second.Leader || !second.Expires.After(first.Expires) {
+		t.Fatalf("lease not renewed: expiry %v -> %v", first.Expires, second.Expires)
+	}
+	if !second.Since.Equal(first.Since) || *elected != 1 {
+		t.Fatalf("renewal announced as a new leadership: since %v -> %v, %d elections", first.Since, second.Since, *elected)
+	}
+}
+
+// Tests that the lease is only relied on for three quarters of its ttl, the
+// seal guard refusing work past that while the store may still hold it.
+func TestElectionGuardWindow(t *testing.T) {
+	var (
+		ttl   = 200 * time.Millisecond
+		store = new(memoryLeaseStore)
+		e, _  = newTestElection(store, "leader", ttl)
+	)
+	before := time.Now()
+	e.campaign()
+	after := time.Now()
+
+	expires := e.Status().Expires
+	if expires.Before(before.Add(ttl*3/4)) || expires.After(after.Add(ttl*3/4)) {
+		t.Fatalf("guard window mismatch: expires %v after the campaign, want %v", expires.Sub(before), ttl*3/4)
+	}
+	if err := e.guard(nil); err != nil {
+		t.Fatalf("leader refused to seal within the window: %v", err)
+	}
+	time.Sleep(time.Until(expires) + 10*time.Millisecond)
+
+	if err := e.guard(nil); err != errStandby {
+		t.Fatalf("seal guard past the window mismatch: have %v, want %v", err, errStandby)
+	}
+	if e.Status().Leader {
+		t.Fatalf("leadership reported past the window")
+	}
+	if holder, _ := store.Holder(context.Background(), e.key); holder != "leader" {
+		t.Fatalf("store lease expired before the window: holder %q", holder)
+	}
+}
+
+// Tests that store errors keep the leader in place until its lease expires,
+// then demote it, and that it's elected again once the store recovers.
+func TestElectionStoreFailure(t *testing.T) {
+	var (
+		ttl   = 200 * time.Millisecond
+		store = new(memoryLeaseStore)
+		e, _  = newTestElection(store, "leader", ttl)
+	)
+	e.campaign()
+
+	failure := errors.New("store unreachable")
+	store.fail(failure)
+	e.campaign()
+
+	status := e.Status()
+	if !status.Leader || status.LastError != failure.Error() {
+		t.Fatalf("leader demoted within its lease: %+v", status)
+	}
+	time.Sleep(time.Until(status.Expires) + 10*time.Millisecond)
+	e.campaign()
+
+	e.lock.RLock()
+	leader := e.status.Leader
+	e.lock.RUnlock()
+	if leader {
+		t.Fatalf("expired leader not demoted")
+	}
+	if err := e.guard(nil); err != errStandby {
+		t.Fatalf("seal guard mismatch: have %v, want %v", err, errStandby)
+	}
+	store.fail(nil)
+	e.campaign()
+
+	if status := e.Status(); !status.Leader || status.LastError != "" {
+		t.Fatalf("leader not elected again after recovery: %+v", status)
+	}
+}
+
+// Tests that closing the election releases the lease held, a standby taking
+// over without waiting for it to expire.
+func TestElectionCloseReleases(t *testing.T) {
+	store := new(memoryLeaseStore)
+	leader, _ := newTestElection(store, "leader", time.Minute)
+	standby, _ := newTestElection(store, "standby", time.Minute)
+
+	leader.campaign()
+	leader.close()
+
+	if status := leader.Status(); status.Leader {
+		t.Fatalf("closed election still leading")
+	}
+	if holder, _ := store.Holder(context.Background(), "producer"); holder != "" {
+		t.Fatalf("lease not released: held by %q", holder)
+	}
+	if !store.closed {
+		t.Fatalf("store not closed")
+	}
+	standby.campaign()
+	if !standby.Status().Leader {
+		t.Fatalf("standby not elected after the release")
+	}
+}
@@ -0,0 +1,59 @@
+package miner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// ExtraTemplateVersion is the version of the extra-data tagging format, exposed
+// to templates as {version} so explorers can tell how to parse a tag.
+const ExtraTemplateVersion = 1
+
+// RenderExtra expands an extra-data template. The supported variables are
+// {pool}, {worker} and {version}, braces are escaped by doubling them. The
+// rendered extra-data must fit the maximum extra-data size.
+func RenderExtra(template, pool, worker string) ([]byte, error) {
+	var (
+		out  strings.Builder
+		rest = template
+	)
+	for len(rest) > 0 {
+		switch {
+		case strings.HasPrefix(rest, "{{"), strings.HasPrefix(rest, "}}"):
+			out.WriteByte(rest[0])
+			rest = rest[2:]
+
+		case rest[0] == '{':
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated variable in extra-data template %q", template)
+			}
+			switch name := rest[1:end]; name {
+			case "pool":
+				out.WriteString(pool)
+			case "worker":
+				out.WriteString(worker)
+			case "version":
+				out.WriteString(strconv.Itoa(ExtraTemplateVersion))
+			default:
+				return nil, fmt.Errorf("unknown variable {%s} in extra-data template", name)
+			}
+			rest = rest[end+1:]
+
+		case rest[0] == '}':
+			return nil, fmt.Errorf("unbalanced brace in extra-data template %q", template)
+
+		default:
+			out.WriteByte(rest[0])
+			rest = rest[1:]
+		}
+	}
+	extra := []byte(out.String())
+	if uint64(len(extra)) > params.MaximumExtraDataSize {
+		return nil, fmt.Errorf("rendered extra-data %q exceeds the limit: %d > %d", extra, len(extra), params.MaximumExtraDataSize)
+	}
+	return extra, nil
+}
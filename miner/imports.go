@@ -0,0 +1,61 @@
+package miner
+
+import (
+	"time"
+)
+
+const (
+	// importSpikeWindow is the time span over which imported blocks are counted
+	// to detect an import spike.
+	importSpikeWindow = time.Second
+
+	// importSpikeBlocks is the number of blocks imported within the window
+	// which is considered a spike.
+	importSpikeBlocks = 4
+)
+
+// pauser is implemented by consensus engines able to suspend their local
+// mining threads.
+type pauser interface {
+	Pause(d time.Duration)
+}
+
+// importSample is the number of blocks a chain head update advanced the chain by.
+type importSample struct {
+	time   time.Time
+	blocks uint64
+}
+
+// importMonitor detects block import spikes from the chain head updates, so
+// that local mining can give way to block processing.
+type importMonitor struct {
+	head    uint64 // last observed head number
+	samples []importSample
+}
+
+// newImportMonitor creates a monitor starting at the given head number.
+func newImportMonitor(head uint64) *importMonitor {
+	return &importMonitor{head: head}
+}
+
+// observe records a new chain head and reports whether the blocks imported
+// within the spike window reached the spike threshold.
+func (m *importMonitor) observe(number uint64, now time.Time) bool {
+	if number > m.head {
+		m.samples = append(m.samples, importSample{time: now, blocks: number - m.head})
+	}
+	m.head = number
+
+	var (
+		blocks uint64
+		keep   = m.samples[:0]
+	)
+	for _, sample := range m.samples {
+		if now.Sub(sample.time) <= importSpikeWindow {
+			keep = append(keep, sample)
+			blocks += sample.blocks
+		}
+	}
+	m.samples = keep
+	return blocks >= importSpikeBlocks
+}
@@ -0,0 +1,30 @@
+package miner
+
+import (
+	"fmt"
+
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+var workInvalidationMeter = metrics.NewRegisteredMeter("miner/invalidations", nil)
+
+// workInvalidator is implemented by consensus engines able to withdraw the work
+// handed out to remote miners before new work is ready.
+type workInvalidator interface {
+	InvalidateWork(reason string)
+}
+
+// invalidateWork withdraws the sealing work once the dominant head changed, as
+// the work links to the previous one as coincident parent. Miners are told to
+// stop right away instead of learning about it on their next work poll.
+func (w *worker) invalidateWork(head *types.Header) {
+	invalidator, ok := w.engine.(workInvalidator)
+	if !ok {
+		return
+	}
+	workInvalidationMeter.Mark(1)
+	log.Debug("Invalidating sealing work on dominant head change", "number", head.Number, "hash", head.Hash())
+	invalidator.InvalidateWork(fmt.Sprintf("dominant head changed to %x", head.Hash()))
+}
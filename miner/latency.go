@@ -0,0 +1,159 @@
+package miner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+// sealLatencyBlocks is the number of recently sealed blocks whose latency
+// breakdown is retained for the RPC API.
+const sealLatencyBlocks = 256
+
+var (
+	sealTimer      = metrics.NewRegisteredTimer("miner/latency/seal", nil)      // work generated -> seal found
+	assembleTimer  = metrics.NewRegisteredTimer("miner/latency/assemble", nil)  // seal found -> block assembled
+	propagateTimer = metrics.NewRegisteredTimer("miner/latency/propagate", nil) // block assembled -> broadcast to peers
+	acceptTimer    = metrics.NewRegisteredTimer("miner/latency/accept", nil)    // broadcast -> first peer acceptance
+	totalTimer     = metrics.NewRegisteredTimer("miner/latency/total", nil)     // work generated -> first peer acceptance
+)
+
+// LatencyReport is the latency breakdown of a locally sealed block. Durations
+// are in milliseconds, measured from the previous stage; stages which have not
+// been reached yet are omitted.
+type LatencyReport struct {
+	Hash      common.Hash    `json:"hash"`
+	Number    hexutil.Uint64 `json:"number"`
+	Generated time.Time      `json:"generated"`
+
+	Seal      *int64 `json:"seal,omitempty"`
+	Assemble  *int64 `json:"assemble,omitempty"`
+	Propagate *int64 `json:"propagate,omitempty"`
+	Accept    *int64 `json:"accept,omitempty"`
+	Total     *int64 `json:"total,omitempty"`
+
+	AcceptedBy string `json:"acceptedBy,omitempty"` // id of the first peer relaying the block back
+}
+
+// sealedBlock holds the stage timestamps of a locally sealed block.
+type sealedBlock struct {
+	hash   common.Hash
+	number uint64
+
+	generated  time.Time
+	sealed     time.Time
+	assembled  time.Time
+	propagated time.Time
+	accepted   time.Time
+	acceptedBy string
+}
+
+// SealLatency tracks the pipeline of locally sealed blocks from work generation
+// to the first peer accepting the block, feeding the miner/latency metrics.
+type SealLatency struct {
+	blocks map[common.Hash]*sealedBlock
+	order  []common.Hash // insertion order, for evicting the oldest blocks
+	lock   sync.Mutex
+}
+
+// newSealLatency creates an empty seal latency tracker.
+func newSealLatency() *SealLatency {
+	return &SealLatency{blocks: make(map[common.Hash]*sealedBlock)}
+}
+
+// sealed starts tracking a block whose work was generated at the given time
+// and whose seal was found at the other.
+func (l *SealLatency) sealed(hash common.Hash, number uint64, generated, sealed time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if _, ok := l.blocks[hash]; ok {
+		return
+	}
+	if len(l.order) >= sealLatencyBlocks {
+		delete(l.blocks, l.order[0])
+		l.order = l.order[1:]
+	}
+	l.blocks[hash] = &sealedBlock{hash: hash, number: number, generated: generated, sealed: sealed}
+	l.order = append(l.order, hash)
+
+	sealTimer.Update(sealed.Sub(generated))
+}
+
+// assembled marks a sealed block as assembled with its receipts and logs.
+func (l *SealLatency) assembled(hash common.Hash) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if block := l.blocks[hash]; block != nil && block.assembled.IsZero() {
+		block.assembled = time.Now()
+		assembleTimer.Update(block.assembled.Sub(block.sealed))
+	}
+}
+
+// MarkPropagated marks a locally sealed block as broadcast to the peers. Blocks
+// not sealed locally are ignored.
+func (l *SealLatency) MarkPropagated(hash common.Hash) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if block := l.blocks[hash]; block != nil && block.propagated.IsZero() {
+		block.propagated = time.Now()
+		if !block.assembled.IsZero() {
+			propagateTimer.Update(block.propagated.Sub(block.assembled))
+		}
+	}
+}
+
+// MarkAccepted marks a locally sealed block as accepted by a peer, which it is
+// once the peer relays it back. Only the first acceptance is recorded.
+func (l *SealLatency) MarkAccepted(hash common.Hash, peer string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	block := l.blocks[hash]
+	if block == nil || !block.accepted.IsZero() || block.propagated.IsZero() {
+		return
+	}
+	block.accepted, block.acceptedBy = time.Now(), peer
+	acceptTimer.Update(block.accepted.Sub(block.propagated))
+	totalTimer.Update(block.accepted.Sub(block.generated))
+}
+
+// Reports returns the latency breakdown of the recently sealed blocks, oldest
+// first.
+func (l *SealLatency) Reports() []LatencyReport {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	reports := make([]LatencyReport, 0, len(l.order))
+	for _, hash := range l.order {
+		block := l.blocks[hash]
+		report := LatencyReport{
+			Hash:       block.hash,
+			Number:     hexutil.Uint64(block.number),
+			Generated:  block.generated,
+			Seal:       stageMillis(block.generated, block.sealed),
+			Assemble:   stageMillis(block.sealed, block.assembled),
+			Propagate:  stageMillis(block.assembled, block.propagated),
+			Accept:     stageMillis(block.propagated, block.accepted),
+			Total:      stageMillis(block.generated, block.accepted),
+			AcceptedBy: block.acceptedBy,
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// stageMillis returns the milliseconds between two stages, or nil if either
+// has not been reached.
+func stageMillis(from, to time.Time) *int64 {
+	if from.IsZero() || to.IsZero() {
+		return nil
+	}
+	ms := to.Sub(from).Milliseconds()
+	return &ms
+}
@@ -0,0 +1,121 @@
+package miner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+)
+
+// Tests that the stages of a sealed block are reported as they are reached,
+// and that the ones reached out of order are ignored.
+func TestSealLatencyStages(t *testing.T) {
+	latency := newSealLatency()
+	hash := common.Hash{0x01}
+
+	// Stages of blocks not sealed locally are ignored
+	latency.assembled(hash)
+	latency.MarkPropagated(hash)
+	latency.MarkAccepted(hash, "peer")
+	if reports := latency.Reports(); len(reports) != 0 {
+		t.Fatalf("untracked block reported: %v", reports)
+	}
+	generated := time.Now().Add(-time.Second)
+	latency.sealed(hash, 10, generated, generated.Add(400*time.Millisecond))
+
+	report := latency.Reports()[0]
+	if report.Hash != hash || report.Number != 10 || !report.Generated.Equal(generated) {
+		t.Fatalf("report mismatch: have %x #%d at %v, want %x #10 at %v", report.Hash, report.Number, report.Generated, hash, generated)
+	}
+	if report.Seal == nil || *report.Seal != 400 {
+		t.Fatalf("seal latency mismatch: have %v, want 400", report.Seal)
+	}
+	if report.Assemble != nil || report.Propagate != nil || report.Accept != nil || report.Total != nil {
+		t.Fatalf("unreached stages reported: %+v", report)
+	}
+	// An acceptance before the propagation is ignored
+	latency.MarkAccepted(hash, "early")
+	if report := latency.Reports()[0]; report.Accept != nil || report.AcceptedBy != "" {
+		t.Fatalf("acceptance before propagation recorded: %+v", report)
+	}
+	latency.assembled(hash)
+	latency.MarkPropagated(hash)
+
+	report = latency.Reports()[0]
+	if report.Assemble == nil || report.Propagate == nil {
+		t.Fatalf("reached stages not reported: %+v", report)
+	}
+	if report.Accept != nil || report.Total != nil {
+		t.Fatalf("unreached stages reported: %+v", report)
+	}
+	latency.MarkAccepted(hash, "peer")
+
+	report = latency.Reports()[0]
+	if report.Accept == nil || report.Total == nil || report.AcceptedBy != "peer" {
+		t.Fatalf("acceptance not reported: %+v", report)
+	}
+	if *report.Total < 1000 {
+		t.Fatalf("total latency mismatch: have %d, want at least 1000", *report.Total)
+	}
+}
+
+// Tests that only the first peer accepting a block is recorded.
+func TestSealLatencyFirstAcceptance(t *testing.T) {
+	latency := newSealLatency()
+	hash := common.Hash{0x01}
+
+	now := time.Now()
+	latency.sealed(hash, 1, now, now)
+	latency.assembled(hash)
+	latency.MarkPropagated(hash)
+	latency.MarkAccepted(hash, "first")
+
+	accept := *latency.Reports()[0].Accept
+	time.Sleep(10 * time.Millisecond)
+	latency.MarkAccepted(hash, "second")
+
+	report := latency.Reports()[0]
+	if report.AcceptedBy != "first" {
+		t.Errorf("accepting peer mismatch: have %s, want first", report.AcceptedBy)
+	}
+	if *report.Accept != accept {
+		t.Errorf("acceptance latency changed: have %d, want %d", *report.Accept, accept)
+	}
+}
+
+// Tests that only the latest sealLatencyBlocks blocks are retained, oldest
+// first, and that sealing a tracked block again doesn't reset it.
+func TestSealLatencyEviction(t *testing.T) {
+	latency := newSealLatency()
+
+	now := time.Now()
+	for i := 0; i < sealLatencyBlocks+10; i++ {
+		latency.sealed(common.Hash{byte(i), byte(i >> 8)}, uint64(i), now, now)
+	}
+	reports := latency.Reports()
+	if len(reports) != sealLatencyBlocks {
+		t.Fatalf("retained blocks mismatch: have %d, want %d", len(reports), sealLatencyBlocks)
+	}
+	for i, report := range reports {
+		if want := uint64(i + 10); uint64(report.Number) != want {
+			t.Fatalf("report %d: number mismatch: have %d, want %d", i, report.Number, want)
+		}
+	}
+	// Stages of evicted blocks are ignored
+	latency.MarkPropagated(common.Hash{0x00})
+	if len(latency.blocks) != sealLatencyBlocks || len(latency.order) != sealLatencyBlocks {
+		t.Fatalf("evicted block tracked again: %d blocks, %d ordered", len(latency.blocks), len(latency.order))
+	}
+	// Sealing a tracked block again neither resets it nor evicts another
+	n := sealLatencyBlocks + 9
+	last := common.Hash{byte(n), byte(n >> 8)}
+	latency.sealed(last, 0, now.Add(time.Hour), now.Add(time.Hour))
+
+	reports = latency.Reports()
+	if len(reports) != sealLatencyBlocks || reports[0].Number != 10 {
+		t.Fatalf("resealed block evicted another: %d blocks, oldest #%d", len(reports), reports[0].Number)
+	}
+	if report := reports[len(reports)-1]; report.Hash != last || uint64(report.Number) != uint64(n) {
+		t.Fatalf("resealed block reset: have %x #%d", report.Hash, report.Number)
+	}
+}
@@ -0,0 +1,52 @@
+package miner
+
+import (
+	"errors"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/miner/auxpow"
+)
+
+// auxTreeLimit is the number of commitment trees kept to export the proof of
+// work of blocks sealed on them.
+const auxTreeLimit = 64
+
+var errAuxShareChain = errors.New("merged mining unavailable with the share chain")
+
+// setAuxCommitment sets the commitment of the auxiliary chain the extra data of
+// the work commits to, a zero commitment removing the chain.
+func (w *worker) setAuxCommitment(chain, commitment common.Hash) error {
+	if w.shares != nil {
+		return errAuxShareChain
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if commitment == (common.Hash{}) {
+		delete(w.auxCommitments, chain)
+	} else {
+		w.auxCommitments[chain] = commitment
+	}
+	if len(w.auxCommitments) == 0 {
+		w.aux = nil
+		return nil
+	}
+	if w.aux == nil && len(w.extra) > 0 {
+		log.Warn("Merged mining commitments replace the block extra data")
+	}
+	w.aux = auxpow.NewTree(w.auxCommitments)
+	return nil
+}
+
+// auxPow exports the proof of work of the sealed header for the auxiliary
+// chain, looking up the commitment tree the header committed to.
+func (w *worker) auxPow(header *types.Header, chain common.Hash) (*auxpow.AuxPow, error) {
+	root := common.BytesToHash(header.Extra[types.QuaiNetworkContext])
+	tree, ok := w.auxTrees.Get(root)
+	if !ok {
+		return nil, auxpow.ErrNotCommitted
+	}
+	return auxpow.New(header, types.QuaiNetworkContext, tree.(*auxpow.Tree), chain)
+}
@@ -31,6 +31,8 @@ import (
 	"github.com/spruce-solutions/go-quai/eth/downloader"
 	"github.com/spruce-solutions/go-quai/event"
 	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/miner/auxpow"
+	"github.com/spruce-solutions/go-quai/miner/sharechain"
 	"github.com/spruce-solutions/go-quai/params"
 )
 
@@ -52,6 +54,29 @@ type Config struct {
 	GasPrice   *big.Int       // Minimum gas price for mining a transaction
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
+
+	ThreadsAffinity []int         `toml:",omitempty"` // CPUs the local mining threads are pinned to (only useful in blake3).
+	DutyCycle       int           `toml:",omitempty"` // Percentage of time the local mining threads spend hashing (0 = unthrottled).
+	ImportPause     time.Duration `toml:",omitempty"` // Time local mining is paused for on block import spikes (0 = disabled).
+
+	Policy   *Policy `toml:",omitempty"` // Local transaction policy applied when filling blocks.
+	Ordering string  `toml:",omitempty"` // Transaction ordering: tip, fifo, fair, a registered name or a Go plugin .so file (default = tip)
+
+	NoSelfCheck bool `toml:",omitempty"` // Skip validating candidate blocks as block import would before sealing
+
+	ExtraTemplate string `toml:",omitempty"` // Extra-data template overriding ExtraData, see RenderExtra
+	ExtraPool     string `toml:",omitempty"` // Pool name substituted for {pool} in the extra-data template
+	ExtraWorker   string `toml:",omitempty"` // Worker id substituted for {worker} in the extra-data template
+
+	ElectionURL string        `toml:",omitempty"` // Lease store electing the active block producer among redundant miners (redis://)
+	ElectionKey string        `toml:",omitempty"` // Key of the block producer lease (default = per chain id)
+	ElectionID  string        `toml:",omitempty"` // Id the lease is held under (default = hostname and pid)
+	ElectionTTL time.Duration `toml:",omitempty"` // Time the lease is held without renewal (0 = 5s)
+
+	Stratum           string `toml:",omitempty"` // Listen address of the stratum server for pools and mining devices (empty = disabled)
+	StratumDifficulty uint64 `toml:",omitempty"` // Difficulty of the shares accepted by the stratum server (0 = block solutions only)
+
+	ShareChain *sharechain.Config `toml:",omitempty"` // Share chain splitting the rewards of a decentralized pool (nil = solo mining)
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -64,6 +89,7 @@ type Miner struct {
 	exitCh   chan struct{}
 	startCh  chan common.Address
 	stopCh   chan struct{}
+	election *election // Block producer election among redundant miners, nil if disabled
 }
 
 func New(eth Backend, config *Config, chainConfig *params.ChainConfig, mux *event.TypeMux, engine consensus.Engine, isLocalBlock func(block *types.Header) bool) *Miner {
@@ -76,6 +102,18 @@ func New(eth Backend, config *Config, chainConfig *params.ChainConfig, mux *even
 		stopCh:  make(chan struct{}),
 		worker:  newWorker(config, chainConfig, engine, eth, mux, isLocalBlock, true),
 	}
+	if config.ElectionURL != "" {
+		store, err := NewLeaseStore(config.ElectionURL)
+		if err != nil {
+			log.Crit("Failed to create block producer lease store", "err", err)
+		}
+		key := config.ElectionKey
+		if key == "" {
+			key = fmt.Sprintf("go-quai/miner/%v", chainConfig.ChainID)
+		}
+		miner.election = newElection(store, key, config.ElectionID, config.ElectionTTL, miner.worker.trigger)
+		miner.worker.addSealGuard(miner.election.guard)
+	}
 	go miner.update()
 
 	return miner
@@ -139,6 +177,9 @@ func (miner *Miner) update() {
 			shouldStart = false
 			miner.worker.stop()
 		case <-miner.exitCh:
+			if miner.election != nil {
+				miner.election.close()
+			}
 			miner.worker.close()
 			return
 		}
@@ -176,6 +217,57 @@ func (miner *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+// SetAuxCommitment sets the commitment of the auxiliary chain merge-mined with
+// the blocks, a zero commitment removing the chain. The root of the commitments
+// replaces the block extra data.
+func (miner *Miner) SetAuxCommitment(chain, commitment common.Hash) error {
+	return miner.worker.setAuxCommitment(chain, commitment)
+}
+
+// AuxPow exports the proof of work of the sealed header for the auxiliary chain
+// whose commitment the header committed to.
+func (miner *Miner) AuxPow(header *types.Header, chain common.Hash) (*auxpow.AuxPow, error) {
+	return miner.worker.auxPow(header, chain)
+}
+
+// AddSealGuard installs a function consulted before new sealing work is
+// committed. Work for headers any guard returns an error for is not sealed.
+func (miner *Miner) AddSealGuard(guard func(header *types.Header) error) {
+	miner.worker.addSealGuard(guard)
+}
+
+// Election returns the state of the block producer election, nil if the miner
+// isn't electing one.
+func (miner *Miner) Election() *ElectionStatus {
+	if miner.election == nil {
+		return nil
+	}
+	return miner.election.Status()
+}
+
+// ShareChain returns the share chain the work links to, nil if pooled mining is
+// disabled.
+func (miner *Miner) ShareChain() *sharechain.ShareChain {
+	return miner.worker.shares
+}
+
+// SealLatency returns the latency tracker of the locally sealed blocks.
+func (miner *Miner) SealLatency() *SealLatency {
+	return miner.worker.latency
+}
+
+// SetPolicy replaces the local transaction policy applied when filling blocks,
+// a nil policy clears it.
+func (miner *Miner) SetPolicy(policy *Policy) error {
+	return miner.worker.policy.set(policy)
+}
+
+// Policy returns the local transaction policy and the number of transactions
+// it excluded.
+func (miner *Miner) Policy() *PolicyStatus {
+	return miner.worker.policy.status()
+}
+
 // SetRecommitInterval sets the interval for sealing work resubmitting.
 func (miner *Miner) SetRecommitInterval(interval time.Duration) {
 	miner.worker.setRecommitInterval(interval)
@@ -0,0 +1,257 @@
+package miner
+
+import (
+	"container/heap"
+	"fmt"
+	"math/big"
+	"plugin"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+)
+
+const (
+	// OrderingTip orders transactions by effective miner tip, the default.
+	OrderingTip = "tip"
+
+	// OrderingFIFO orders transactions by the time they were first seen.
+	OrderingFIFO = "fifo"
+
+	// OrderingFair takes one transaction of every account in turn, accounts
+	// paying the highest tip going first in each round.
+	OrderingFair = "fair"
+
+	// orderingSymbol is the symbol a Go plugin exports its ordering under.
+	orderingSymbol = "Ordering"
+)
+
+// TxSet is a set of pending transactions returned in inclusion order, nonce
+// order being honoured within every account.
+type TxSet interface {
+	// Peek returns the next transaction to include, nil if none is left.
+	Peek() *types.Transaction
+
+	// Shift replaces the next transaction with the following one from the same
+	// account, after the transaction was included.
+	Shift()
+
+	// Pop removes the next transaction along with the following ones from the
+	// same account, after the transaction couldn't be included.
+	Pop()
+}
+
+// TxOrdering is a strategy ordering the pending transactions of a block. Custom
+// strategies are registered with RegisterOrdering or loaded from Go plugins
+// exporting an Ordering variable implementing it.
+type TxOrdering interface {
+	// Order returns the set over the nonce sorted transactions of every account,
+	// taking ownership of the map.
+	Order(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TxSet
+}
+
+// TxOrderingFunc is an adapter to use a function as a transaction ordering.
+type TxOrderingFunc func(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TxSet
+
+// Order implements TxOrdering, calling f.
+func (f TxOrderingFunc) Order(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TxSet {
+	return f(signer, txs, baseFee)
+}
+
+var (
+	orderings = map[string]TxOrdering{
+		OrderingTip: TxOrderingFunc(func(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TxSet {
+			return types.NewTransactionsByPriceAndNonce(signer, txs, baseFee)
+		}),
+		OrderingFIFO: TxOrderingFunc(newTxsByArrival),
+		OrderingFair: TxOrderingFunc(newTxsByAccount),
+	}
+	orderingsLock sync.RWMutex
+)
+
+// RegisterOrdering makes a custom transaction ordering selectable by name.
+func RegisterOrdering(name string, ordering TxOrdering) {
+	orderingsLock.Lock()
+	defer orderingsLock.Unlock()
+
+	orderings[name] = ordering
+}
+
+// LoadOrdering returns the transaction ordering with the name, or the one
+// exported by the Go plugin if a .so file is given. An empty name selects the
+// tip ordering.
+func LoadOrdering(name string) (TxOrdering, error) {
+	if name == "" {
+		name = OrderingTip
+	}
+	if strings.HasSuffix(name, ".so") {
+		return loadOrderingPlugin(name)
+	}
+	orderingsLock.RLock()
+	defer orderingsLock.RUnlock()
+
+	if ordering, ok := orderings[name]; ok {
+		return ordering, nil
+	}
+	names := make([]string, 0, len(orderings))
+	for known := range orderings {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown transaction ordering %q (known: %s)", name, strings.Join(names, ", "))
+}
+
+// loadOrderingPlugin opens the Go plugin at path, looking up its ordering.
+func loadOrderingPlugin(path string) (TxOrdering, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ordering plugin: %v", err)
+	}
+	symbol, err := p.Lookup(orderingSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("ordering plugin %s: %v", path, err)
+	}
+	// Exported variables are looked up as pointers to them
+	switch ordering := symbol.(type) {
+	case *TxOrdering:
+		if *ordering != nil {
+			return *ordering, nil
+		}
+	case TxOrdering:
+		return ordering, nil
+	}
+	return nil, fmt.Errorf("ordering plugin %s: %s is not a miner.TxOrdering", path, orderingSymbol)
+}
+
+// accountHeads removes the transactions whose sender doesn't match their
+// account, returning the accounts left.
+func accountHeads(signer types.Signer, txs map[common.Address]types.Transactions) []common.Address {
+	accounts := make([]common.Address, 0, len(txs))
+	for from, accTxs := range txs {
+		if len(accTxs) == 0 {
+			delete(txs, from)
+			continue
+		}
+		if acc, _ := types.Sender(signer, accTxs[0]); acc != from {
+			delete(txs, from)
+			continue
+		}
+		accounts = append(accounts, from)
+	}
+	return accounts
+}
+
+// txsByArrival returns transactions in the order they were first seen.
+type txsByArrival struct {
+	txs   map[common.Address]types.Transactions
+	heads arrivalHeap
+}
+
+func newTxsByArrival(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TxSet {
+	set := &txsByArrival{
+		txs:   txs,
+		heads: arrivalHeap{accounts: accountHeads(signer, txs), txs: txs},
+	}
+	heap.Init(&set.heads)
+	return set
+}
+
+func (s *txsByArrival) Peek() *types.Transaction {
+	if len(s.heads.accounts) == 0 {
+		return nil
+	}
+	return s.txs[s.heads.accounts[0]][0]
+}
+
+func (s *txsByArrival) Shift() {
+	from := s.heads.accounts[0]
+	if s.txs[from] = s.txs[from][1:]; len(s.txs[from]) > 0 {
+		heap.Fix(&s.heads, 0)
+		return
+	}
+	heap.Pop(&s.heads)
+}
+
+func (s *txsByArrival) Pop() {
+	delete(s.txs, s.heads.accounts[0])
+	heap.Pop(&s.heads)
+}
+
+// arrivalHeap is a heap of accounts by the arrival of their next transaction.
+type arrivalHeap struct {
+	accounts []common.Address
+	txs      map[common.Address]types.Transactions
+}
+
+func (h *arrivalHeap) Len() int { return len(h.accounts) }
+func (h *arrivalHeap) Less(i, j int) bool {
+	a, b := h.txs[h.accounts[i]][0], h.txs[h.accounts[j]][0]
+	if !a.Time().Equal(b.Time()) {
+		return a.Time().Before(b.Time())
+	}
+	return a.Hash().Hex() < b.Hash().Hex()
+}
+func (h *arrivalHeap) Swap(i, j int) { h.accounts[i], h.accounts[j] = h.accounts[j], h.accounts[i] }
+
+func (h *arrivalHeap) Push(x interface{}) {
+	h.accounts = append(h.accounts, x.(common.Address))
+}
+
+func (h *arrivalHeap) Pop() interface{} {
+	n := len(h.accounts)
+	x := h.accounts[n-1]
+	h.accounts = h.accounts[:n-1]
+	return x
+}
+
+// txsByAccount takes one transaction of every account in turn.
+type txsByAccount struct {
+	txs   map[common.Address]types.Transactions
+	queue []common.Address // Accounts in turn order, the next one first
+}
+
+func newTxsByAccount(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TxSet {
+	var (
+		queue = accountHeads(signer, txs)
+		tips  = make(map[common.Address]*big.Int, len(queue))
+	)
+	for i := 0; i < len(queue); i++ {
+		tip, err := txs[queue[i]][0].EffectiveGasTip(baseFee)
+		if err != nil {
+			delete(txs, queue[i])
+			queue = append(queue[:i], queue[i+1:]...)
+			i--
+			continue
+		}
+		tips[queue[i]] = tip
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		if cmp := tips[queue[i]].Cmp(tips[queue[j]]); cmp != 0 {
+			return cmp > 0
+		}
+		return txs[queue[i]][0].Time().Before(txs[queue[j]][0].Time())
+	})
+	return &txsByAccount{txs: txs, queue: queue}
+}
+
+func (s *txsByAccount) Peek() *types.Transaction {
+	if len(s.queue) == 0 {
+		return nil
+	}
+	return s.txs[s.queue[0]][0]
+}
+
+func (s *txsByAccount) Shift() {
+	from := s.queue[0]
+	s.queue = s.queue[1:]
+	if s.txs[from] = s.txs[from][1:]; len(s.txs[from]) > 0 {
+		s.queue = append(s.queue, from)
+	}
+}
+
+func (s *txsByAccount) Pop() {
+	delete(s.txs, s.queue[0])
+	s.queue = s.queue[1:]
+}
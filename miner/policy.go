@@ -0,0 +1,234 @@
+package miner
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+// errExcludedByPolicy is returned when a transaction is left out of a block by
+// the local transaction policy.
+var errExcludedByPolicy = errors.New("transaction excluded by local policy")
+
+// Policy declares the transactions the local miner excludes from the blocks it
+// builds. Policies are local-only: they do not change the consensus rules, the
+// excluded transactions stay valid and other miners may include them.
+type Policy struct {
+	Addresses       []common.Address `json:"addresses,omitempty"`       // Senders and recipients, including internal calls, to exclude
+	Opcodes         []string         `json:"opcodes,omitempty"`         // Opcodes whose execution excludes a transaction
+	ETxDestinations []*big.Int       `json:"etxDestinations,omitempty"` // Chain IDs external transactions may not be sent to
+}
+
+// LoadPolicy reads a JSON encoded transaction policy from a file.
+func LoadPolicy(path string) (*Policy, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	policy := new(Policy)
+	if err := json.Unmarshal(blob, policy); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %v", path, err)
+	}
+	if _, err := compilePolicy(policy); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %v", path, err)
+	}
+	return policy, nil
+}
+
+// txFilter is the compiled form of a policy used while filling blocks.
+type txFilter struct {
+	policy    *Policy
+	addresses map[common.Address]struct{}
+	opcodes   map[vm.OpCode]struct{}
+	ranges    [][]int // address prefix ranges of the excluded ETX destinations
+}
+
+// compilePolicy validates a policy and turns it into a filter, a nil filter
+// is returned for a policy excluding nothing.
+func compilePolicy(policy *Policy) (*txFilter, error) {
+	if policy == nil {
+		return nil, nil
+	}
+	filter := &txFilter{
+		policy:    policy,
+		addresses: make(map[common.Address]struct{}),
+		opcodes:   make(map[vm.OpCode]struct{}),
+	}
+	for _, addr := range policy.Addresses {
+		filter.addresses[addr] = struct{}{}
+	}
+	for _, name := range policy.Opcodes {
+		op := vm.StringToOp(strings.ToUpper(name))
+		if op == vm.STOP && !strings.EqualFold(name, vm.STOP.String()) {
+			return nil, fmt.Errorf("unknown opcode %q", name)
+		}
+		filter.opcodes[op] = struct{}{}
+	}
+	for _, id := range policy.ETxDestinations {
+		idRange := params.LookupChainIDRange(id)
+		if len(idRange) != 2 {
+			return nil, fmt.Errorf("unknown ETX destination chain %v", id)
+		}
+		filter.ranges = append(filter.ranges, idRange)
+	}
+	if len(filter.addresses) == 0 && len(filter.opcodes) == 0 && len(filter.ranges) == 0 {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// check reports the policy rule a top-level transaction violates before it is
+// executed, or nil if it may be executed.
+func (f *txFilter) check(from common.Address, tx *types.Transaction) error {
+	if _, ok := f.addresses[from]; ok {
+		return fmt.Errorf("%w: excluded sender %x", errExcludedByPolicy, from)
+	}
+	if to := tx.To(); to != nil {
+		return f.checkCall(*to, tx.Value())
+	}
+	return nil
+}
+
+// checkCall reports the policy rule a call to the given address violates.
+func (f *txFilter) checkCall(to common.Address, value *big.Int) error {
+	if _, ok := f.addresses[to]; ok {
+		return fmt.Errorf("%w: excluded recipient %x", errExcludedByPolicy, to)
+	}
+	if value == nil || value.Sign() <= 0 {
+		return nil
+	}
+	prefix := int(to.Bytes()[0])
+	for _, idRange := range f.ranges {
+		if prefix >= idRange[0] && prefix <= idRange[1] {
+			return fmt.Errorf("%w: excluded ETX destination %x", errExcludedByPolicy, to)
+		}
+	}
+	return nil
+}
+
+// policyTracer records the first policy violation of a transaction during its
+// execution, forwarding all events to the tracer configured on the chain.
+type policyTracer struct {
+	filter *txFilter
+	inner  vm.Tracer // tracer of the chain's VM config, nil if none
+	err    error
+}
+
+func (t *policyTracer) violate(err error) {
+	if t.err == nil && err != nil {
+		t.err = err
+	}
+}
+
+func (t *policyTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	if t.inner != nil {
+		t.inner.CaptureStart(env, from, to, create, input, gas, value)
+	}
+}
+
+func (t *policyTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if _, ok := t.filter.opcodes[op]; ok {
+		t.violate(fmt.Errorf("%w: excluded opcode %v", errExcludedByPolicy, op))
+	}
+	if t.inner != nil {
+		t.inner.CaptureState(env, pc, op, gas, cost, scope, rData, depth, err)
+	}
+}
+
+func (t *policyTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.violate(t.filter.checkCall(to, value))
+	if t.inner != nil {
+		t.inner.CaptureEnter(typ, from, to, input, gas, value)
+	}
+}
+
+func (t *policyTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.inner != nil {
+		t.inner.CaptureExit(output, gasUsed, err)
+	}
+}
+
+func (t *policyTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if t.inner != nil {
+		t.inner.CaptureFault(env, pc, op, gas, cost, scope, depth, err)
+	}
+}
+
+func (t *policyTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {
+	if t.inner != nil {
+		t.inner.CaptureEnd(output, gasUsed, d, err)
+	}
+}
+
+// PolicyStatus is the active transaction policy and the number of transactions
+// it excluded since it was set.
+type PolicyStatus struct {
+	Policy   *Policy `json:"policy"`
+	Excluded uint64  `json:"excluded"`
+}
+
+// policyState holds the active transaction policy of the worker and counts the
+// transactions it excluded.
+type policyState struct {
+	lock     sync.RWMutex
+	filter   *txFilter
+	excluded uint64
+}
+
+// set replaces the active policy, logging that it only applies locally.
+func (p *policyState) set(policy *Policy) error {
+	filter, err := compilePolicy(policy)
+	if err != nil {
+		return err
+	}
+	p.lock.Lock()
+	p.filter, p.excluded = filter, 0
+	p.lock.Unlock()
+
+	if filter == nil {
+		log.Info("Local transaction policy cleared")
+		return nil
+	}
+	log.Warn("Local transaction policy enabled, excluded transactions remain valid and may be mined by others",
+		"addresses", len(filter.addresses), "opcodes", len(filter.opcodes), "etxDestinations", len(filter.ranges))
+	return nil
+}
+
+// get returns the active filter, nil if no policy is set.
+func (p *policyState) get() *txFilter {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.filter
+}
+
+// status returns the active policy and its exclusion count.
+func (p *policyState) status() *PolicyStatus {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	status := &PolicyStatus{Excluded: p.excluded}
+	if p.filter != nil {
+		status.Policy = p.filter.policy
+	}
+	return status
+}
+
+// exclude records a transaction left out by the policy.
+func (p *policyState) exclude(tx *types.Transaction, err error) {
+	p.lock.Lock()
+	p.excluded++
+	p.lock.Unlock()
+
+	log.Debug("Skipping transaction due to local policy", "hash", tx.Hash(), "reason", err)
+}
@@ -0,0 +1,29 @@
+package miner
+
+import (
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/types"
+)
+
+// workPusher is implemented by consensus engines handing the sealing work to
+// remote miners, over the work API, notifications or stratum.
+type workPusher interface {
+	PushWork(block *types.Block, results chan<- *types.Block)
+}
+
+// pushWork hands the sealing task to the remote miners of the engine. The
+// blocks they seal are processed by the result loop.
+func (w *worker) pushWork(task *task) {
+	if pusher, ok := w.engine.(workPusher); ok {
+		pusher.PushWork(task.block, w.resultCh)
+	}
+}
+
+// pendingSealHash returns the seal hash the sealing task of the header is
+// tracked by. The seal hash covers the nonce, the one of the sealed block is
+// cleared to find its task.
+func (w *worker) pendingSealHash(header *types.Header) common.Hash {
+	header = types.CopyHeader(header)
+	header.Nonce = types.BlockNonce{}
+	return w.engine.SealHash(header)
+}
@@ -0,0 +1,90 @@
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+var (
+	selfCheckTimer        = metrics.NewRegisteredTimer("miner/selfcheck", nil)
+	selfCheckFailureMeter = metrics.NewRegisteredMeter("miner/selfcheck/failures", nil)
+)
+
+// selfCheck validates candidate blocks the way block import would before they
+// are handed out for sealing, so no hashrate is spent on blocks the node itself
+// would reject. A passing PCRC is remembered while the dominant heads the
+// candidates build on stay the same, since it only depends on the links of the
+// header.
+type selfCheck struct {
+	w *worker
+
+	links common.Hash // Hash of the parent links the PCRC last passed for
+	lock  sync.Mutex
+}
+
+// check validates the header, body and state of the assembled block against
+// the chain, and runs the PCRC at prime order, which covers the checks of every
+// order the block may be sealed at.
+func (c *selfCheck) check(block *types.Block, env *environment) error {
+	defer func(start time.Time) { selfCheckTimer.UpdateSince(start) }(time.Now())
+
+	err := c.validate(block, env)
+	if err != nil {
+		selfCheckFailureMeter.Mark(1)
+	}
+	return err
+}
+
+func (c *selfCheck) validate(block *types.Block, env *environment) error {
+	w := c.w
+	if err := w.engine.VerifyHeader(w.chain, block.Header(), false); err != nil {
+		return fmt.Errorf("invalid header: %w", err)
+	}
+	validator := w.chain.Validator()
+	if err := validator.ValidateBody(block); err != nil {
+		return fmt.Errorf("invalid body: %w", err)
+	}
+	if err := validator.ValidateState(block, env.state, env.receipts, block.GasUsed()); err != nil {
+		return fmt.Errorf("invalid state: %w", err)
+	}
+	return c.pcrc(block.Header())
+}
+
+// pcrc runs the PCRC of the header against the current dominant heads, reusing
+// the last result if the header links to the same ones. Dominant chains not yet
+// synced are tolerated, import parks such blocks instead of rejecting them.
+func (c *selfCheck) pcrc(header *types.Header) error {
+	var blob []byte
+	for _, hash := range header.ParentHash {
+		blob = append(blob, hash.Bytes()...)
+	}
+	blob = append(blob, header.Location...)
+	links := crypto.Keccak256Hash(blob)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if links == c.links {
+		return nil
+	}
+	_, err := c.w.chain.PCRC(header, params.PRIME)
+	if errors.Is(err, consensus.ErrSliceNotSynced) {
+		log.Debug("Dominant chain not synced for self-check", "number", header.Number, "err", err)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("PCRC failed: %w", err)
+	}
+	c.links = links
+	return nil
+}
@@ -0,0 +1,389 @@
+// Package sharechain implements the auxiliary chain of sub-threshold shares
+// that decentralized pools split the rewards of the blocks they find by.
+//
+// A share is a zone header sealed at a fraction of the block difficulty, its
+// extra data linking to the previous share. As the link is covered by the seal,
+// shares can't be moved between chains. The chain of shares with the most work
+// is followed, and the reward of a block found by the pool is split over the
+// miners of the last shares of that chain, by the work they contributed (PPLNS).
+//
+// The split is not paid in the coinbase of the block. Consensus credits the
+// whole reward of a zone block to its single coinbase, and nodes outside of the
+// pool have no share chain to check a split against. Payouts is the split the
+// miner of a block owes the other miners of the pool, settled by transactions.
+package sharechain
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+const (
+	// staleDepth is the number of blocks the zone head may be ahead of the
+	// parent block of a share for it to be accepted.
+	staleDepth = 8
+
+	// pruneDepth is the number of blocks shares outside of the payout window are
+	// kept for behind the head share, to follow competing share chains.
+	pruneDepth = 256
+)
+
+var (
+	shareAcceptMeter = metrics.NewRegisteredMeter("miner/sharechain/accepted", nil)
+	shareRejectMeter = metrics.NewRegisteredMeter("miner/sharechain/rejected", nil)
+	shareReorgMeter  = metrics.NewRegisteredMeter("miner/sharechain/reorgs", nil)
+	shareCountGauge  = metrics.NewRegisteredGauge("miner/sharechain/shares", nil)
+
+	big2e256 = new(big.Int).Exp(big.NewInt(2), big.NewInt(256), big.NewInt(0))
+
+	// ErrKnownShare is returned if a share is already in the share chain.
+	ErrKnownShare = errors.New("known share")
+
+	// ErrUnknownParent is returned if the parent share of a share isn't known.
+	ErrUnknownParent = errors.New("unknown parent share")
+
+	// ErrInvalidSeal is returned if a share doesn't meet the share difficulty.
+	ErrInvalidSeal = errors.New("share doesn't satisfy the share difficulty")
+
+	errMissingLink   = errors.New("share doesn't link to a parent share")
+	errStaleShare    = errors.New("stale share")
+	errShareLocation = errors.New("share of another location")
+)
+
+// Config are the parameters of the share chain, which all miners of the pool
+// must agree on.
+type Config struct {
+	Ratio  uint64 `toml:",omitempty"` // Ratio of the block difficulty to the share difficulty
+	Window int    `toml:",omitempty"` // Number of last shares the block rewards are split over
+}
+
+// DefaultConfig contains the default share chain parameters.
+var DefaultConfig = Config{
+	Ratio:  1024,
+	Window: 4096,
+}
+
+// Share is a zone header sealed at the share difficulty.
+type Share struct {
+	header *types.Header
+	hash   common.Hash
+}
+
+// NewShare wraps a sealed header as a share.
+func NewShare(header *types.Header) *Share {
+	return &Share{header: header, hash: header.Hash()}
+}
+
+// Header returns a copy of the sealed header of the share.
+func (s *Share) Header() *types.Header { return types.CopyHeader(s.header) }
+
+// Hash returns the hash of the share, the one of its header.
+func (s *Share) Hash() common.Hash { return s.hash }
+
+// Parent returns the hash of the parent share the header links to, the zero
+// hash for the first share of a chain.
+func (s *Share) Parent() common.Hash {
+	return common.BytesToHash(s.header.Extra[types.QuaiNetworkContext])
+}
+
+// Miner returns the address the share is paid out to.
+func (s *Share) Miner() common.Address {
+	return s.header.Coinbase[types.QuaiNetworkContext]
+}
+
+// NewShareEvent is posted when a share is added to the share chain.
+type NewShareEvent struct {
+	Share *Share
+	Head  bool // Whether the share became the head of the share chain
+}
+
+// Payout is the part of a block reward owed to a miner of the share chain.
+type Payout struct {
+	Miner  common.Address `json:"miner"`
+	Shares int            `json:"shares"` // Number of shares of the miner in the window
+	Work   *hexutil.Big   `json:"work"`   // Share difficulty contributed in the window
+	Amount *hexutil.Big   `json:"amount"`
+}
+
+// shareEntry is a share along with its position in the share chain.
+type shareEntry struct {
+	share *Share
+	work  *big.Int // Share difficulty of the share
+	total *big.Int // Total share difficulty of the chain up to the share
+}
+
+// ShareChain tracks the shares of the pool, following the chain with the most
+// work.
+type ShareChain struct {
+	config Config
+	chain  consensus.ChainHeaderReader
+	engine consensus.Engine
+
+	shares map[common.Hash]*shareEntry
+	head   *shareEntry
+	feed   event.Feed
+	lock   sync.RWMutex
+}
+
+// New creates an empty share chain validating shares against the zone chain.
+func New(config Config, chain consensus.ChainHeaderReader, engine consensus.Engine) (*ShareChain, error) {
+	if config.Ratio == 0 {
+		return nil, errors.New("share difficulty ratio must be positive")
+	}
+	if config.Window <= 0 {
+		return nil, errors.New("share window must be positive")
+	}
+	return &ShareChain{
+		config: config,
+		chain:  chain,
+		engine: engine,
+		shares: make(map[common.Hash]*shareEntry),
+	}, nil
+}
+
+// Config returns the share chain parameters.
+func (sc *ShareChain) Config() Config {
+	return sc.config
+}
+
+// Head returns the head of the share chain, nil if no share is known.
+func (sc *ShareChain) Head() *Share {
+	sc.lock.RLock()
+	defer sc.lock.RUnlock()
+
+	if sc.head == nil {
+		return nil
+	}
+	return sc.head.share
+}
+
+// Link returns the extra data new work links to the head share with.
+func (sc *ShareChain) Link() []byte {
+	sc.lock.RLock()
+	defer sc.lock.RUnlock()
+
+	if sc.head == nil {
+		return common.Hash{}.Bytes()
+	}
+	return sc.head.share.Hash().Bytes()
+}
+
+// Get returns the known share with the hash, nil if unknown.
+func (sc *ShareChain) Get(hash common.Hash) *Share {
+	sc.lock.RLock()
+	defer sc.lock.RUnlock()
+
+	if entry := sc.shares[hash]; entry != nil {
+		return entry.share
+	}
+	return nil
+}
+
+// Has reports whether the share with the hash is known.
+func (sc *ShareChain) Has(hash common.Hash) bool {
+	return sc.Get(hash) != nil
+}
+
+// Ancestors returns up to amount shares ending at the share with the hash,
+// oldest first.
+func (sc *ShareChain) Ancestors(hash common.Hash, amount int) []*Share {
+	sc.lock.RLock()
+	defer sc.lock.RUnlock()
+
+	var shares []*Share
+	for entry := sc.shares[hash]; entry != nil && len(shares) < amount; entry = sc.shares[entry.share.Parent()] {
+		shares = append(shares, entry.share)
+	}
+	for i, j := 0, len(shares)-1; i < j; i, j = i+1, j-1 {
+		shares[i], shares[j] = shares[j], shares[i]
+	}
+	return shares
+}
+
+// Add validates a share and adds it to the share chain, its parent share being
+// required to be known already.
+func (sc *ShareChain) Add(share *Share) error {
+	return sc.add(share, false, false)
+}
+
+// Import adds a contiguous segment of shares, oldest first, as fetched from a
+// peer. The first share may start the segment without its parent being known,
+// and shares built on older blocks are accepted as they aren't new work.
+func (sc *ShareChain) Import(shares []*Share) (int, error) {
+	for i, share := range shares {
+		if i > 0 && share.Parent() != shares[i-1].Hash() {
+			return i, fmt.Errorf("non contiguous share segment at %d", i)
+		}
+		if err := sc.add(share, i == 0, true); err != nil && !errors.Is(err, ErrKnownShare) {
+			return i, err
+		}
+	}
+	return len(shares), nil
+}
+
+func (sc *ShareChain) add(share *Share, root bool, imported bool) error {
+	work, err := sc.verify(share, !imported)
+	if err != nil {
+		if !errors.Is(err, ErrKnownShare) {
+			shareRejectMeter.Mark(1)
+		}
+		return err
+	}
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	if sc.shares[share.Hash()] != nil {
+		return ErrKnownShare
+	}
+	total := new(big.Int).Set(work)
+	if parent := sc.shares[share.Parent()]; parent != nil {
+		total.Add(total, parent.total)
+	} else if share.Parent() != (common.Hash{}) && !root {
+		shareRejectMeter.Mark(1)
+		return fmt.Errorf("%w: %x", ErrUnknownParent, share.Parent())
+	}
+	entry := &shareEntry{share: share, work: work, total: total}
+	sc.shares[share.Hash()] = entry
+	shareAcceptMeter.Mark(1)
+
+	head := sc.head == nil || total.Cmp(sc.head.total) > 0
+	if head {
+		if sc.head != nil && sc.head.share.Hash() != share.Parent() {
+			shareReorgMeter.Mark(1)
+			log.Debug("Share chain reorganised", "old", sc.head.share.Hash(), "new", share.Hash())
+		}
+		sc.head = entry
+		sc.prune()
+	}
+	shareCountGauge.Update(int64(len(sc.shares)))
+	log.Trace("Added share", "hash", share.Hash(), "miner", share.Miner(), "head", head)
+
+	sc.feed.Send(NewShareEvent{Share: share, Head: head})
+	return nil
+}
+
+// verify checks the share against the zone chain and its seal against the
+// share difficulty, returning the latter. Fresh shares must be built on one of
+// the last blocks.
+func (sc *ShareChain) verify(share *Share, fresh bool) (*big.Int, error) {
+	header, ctx := share.header, types.QuaiNetworkContext
+	if sc.Has(share.Hash()) {
+		return nil, ErrKnownShare
+	}
+	if len(header.Extra) <= ctx || len(header.Extra[ctx]) != common.HashLength {
+		return nil, errMissingLink
+	}
+	if !bytes.Equal(header.Location, sc.chain.Config().Location) {
+		return nil, errShareLocation
+	}
+	parent := sc.chain.GetHeaderByHash(header.ParentHash[ctx])
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	if head := sc.chain.CurrentHeader(); fresh && head.Number[ctx].Uint64() > parent.Number[ctx].Uint64()+staleDepth {
+		return nil, fmt.Errorf("%w: built on block %d, head %d", errStaleShare, parent.Number[ctx], head.Number[ctx])
+	}
+	if err := sc.engine.VerifyHeader(sc.chain, header, false); err != nil {
+		return nil, err
+	}
+	work := sc.shareDifficulty(header)
+	target := new(big.Int).Div(big2e256, work)
+	if new(big.Int).SetBytes(sc.engine.SealHash(header).Bytes()).Cmp(target) > 0 {
+		return nil, ErrInvalidSeal
+	}
+	return work, nil
+}
+
+// shareDifficulty returns the difficulty a share built on the header must meet.
+func (sc *ShareChain) shareDifficulty(header *types.Header) *big.Int {
+	difficulty := new(big.Int).Div(header.Difficulty[types.QuaiNetworkContext], new(big.Int).SetUint64(sc.config.Ratio))
+	if difficulty.Sign() <= 0 {
+		difficulty.SetUint64(1)
+	}
+	return difficulty
+}
+
+// Payouts splits the reward over the miners of the shares in the window ending
+// at the head share, by the share difficulty they contributed. The rounding
+// remainder goes to the miner of the head share. The payouts are sorted by
+// miner address. The split isn't applied to any block, see the package doc.
+func (sc *ShareChain) Payouts(reward *big.Int) []*Payout {
+	sc.lock.RLock()
+	defer sc.lock.RUnlock()
+
+	var (
+		payouts = make(map[common.Address]*Payout)
+		total   = new(big.Int)
+	)
+	entry := sc.head
+	for i := 0; entry != nil && i < sc.config.Window; i++ {
+		payout := payouts[entry.share.Miner()]
+		if payout == nil {
+			payout = &Payout{Miner: entry.share.Miner(), Work: new(hexutil.Big), Amount: new(hexutil.Big)}
+			payouts[payout.Miner] = payout
+		}
+		payout.Shares++
+		payout.Work.ToInt().Add(payout.Work.ToInt(), entry.work)
+		total.Add(total, entry.work)
+
+		entry = sc.shares[entry.share.Parent()]
+	}
+	if total.Sign() == 0 {
+		return nil
+	}
+	var (
+		result = make([]*Payout, 0, len(payouts))
+		paid   = new(big.Int)
+	)
+	for _, payout := range payouts {
+		amount := payout.Amount.ToInt()
+		amount.Mul(reward, payout.Work.ToInt())
+		amount.Div(amount, total)
+		paid.Add(paid, amount)
+		result = append(result, payout)
+	}
+	head := payouts[sc.head.share.Miner()].Amount.ToInt()
+	head.Add(head, paid.Sub(reward, paid))
+
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].Miner.Bytes(), result[j].Miner.Bytes()) < 0
+	})
+	return result
+}
+
+// SubscribeNewShareEvent registers a subscription of NewShareEvent.
+func (sc *ShareChain) SubscribeNewShareEvent(ch chan<- NewShareEvent) event.Subscription {
+	return sc.feed.Subscribe(ch)
+}
+
+// prune drops the shares outside of the payout window built on blocks more than
+// pruneDepth behind the block of the head share. The lock must be held.
+func (sc *ShareChain) prune() {
+	window := make(map[common.Hash]bool, sc.config.Window)
+	entry := sc.head
+	for i := 0; entry != nil && i < sc.config.Window; i++ {
+		window[entry.share.Hash()] = true
+		entry = sc.shares[entry.share.Parent()]
+	}
+	number := sc.head.share.header.Number[types.QuaiNetworkContext].Uint64()
+	for hash, entry := range sc.shares {
+		if window[hash] {
+			continue
+		}
+		if entry.share.header.Number[types.QuaiNetworkContext].Uint64()+pruneDepth < number {
+			delete(sc.shares, hash)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package sharechain
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/consensus"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+var testLocation = []byte{1, 1}
+
+// testChain is a zone chain whose blocks are headers only.
+type testChain struct {
+	consensus.ChainHeaderReader // Unused methods panic
+
+	config  *params.ChainConfig
+	headers map[common.Hash]*types.Header
+	head    *types.Header
+}
+
+func newTestChain() *testChain {
+	config := *params.TestChainConfig
+	config.Location = testLocation
+
+	chain := &testChain{config: &config, headers: make(map[common.Hash]*types.Header)}
+	chain.head = chain.extend(nil)
+	return chain
+}
+
+// extend adds a block on top of the parent, the genesis if nil, and makes it
+// the head.
+func (c *testChain) extend(parent *types.Header) *types.Header {
+	header := types.NewEmptyHeader()
+	header.Number[types.QuaiNetworkContext] = big.NewInt(0)
+	if parent != nil {
+		header.ParentHash[types.QuaiNetworkContext] = parent.Hash()
+		header.Number[types.QuaiNetworkContext] = new(big.Int).Add(parent.Number[types.QuaiNetworkContext], common.Big1)
+	}
+	header.Difficulty[types.QuaiNetworkContext] = big.NewInt(1 << 20)
+	header.Location = testLocation
+
+	c.headers[header.Hash()] = header
+	c.head = header
+	return header
+}
+
+func (c *testChain) Config() *params.ChainConfig { return c.config }
+
+func (c *testChain) CurrentHeader() *types.Header { return c.head }
+
+func (c *testChain) GetHeaderByHash(hash common.Hash) *types.Header { return c.headers[hash] }
+
+// testEngine accepts every header, sealing them with the lowest hash unless
+// told otherwise.
+type testEngine struct {
+	consensus.Engine // Unused methods panic
+
+	seals map[common.Hash]common.Hash
+}
+
+func (e *testEngine) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	return nil
+}
+
+func (e *testEngine) SealHash(header *types.Header) common.Hash {
+	return e.seals[header.Hash()]
+}
+
+// newTestShare creates a share of the miner built on the block, linking to the
+// parent share. The nonce tells apart the shares built on the same block.
+func newTestShare(block *types.Header, parent common.Hash, miner common.Address, difficulty int64, nonce uint64) *Share {
+	header := types.NewEmptyHeader()
+	header.ParentHash[types.QuaiNetworkContext] = block.Hash()
+	header.Number[types.QuaiNetworkContext] = new(big.Int).Add(block.Number[types.QuaiNetworkContext], common.Big1)
+	header.Difficulty[types.QuaiNetworkContext] = big.NewInt(difficulty)
+	header.Coinbase[types.QuaiNetworkContext] = miner
+	header.Extra[types.QuaiNetworkContext] = parent.Bytes()
+	header.Nonce = types.EncodeNonce(nonce)
+	header.Location = testLocation
+	return NewShare(header)
+}
+
+// newTestShareChain creates a share chain on a fresh zone chain, at the zone
+// context.
+func newTestShareChain(t *testing.T, config Config) (*ShareChain, *testChain, *testEngine) {
+	context := types.QuaiNetworkContext
+	types.QuaiNetworkContext = params.ZONE
+	t.Cleanup(func() { types.QuaiNetworkContext = context })
+
+	chain := newTestChain()
+	engine := &testEngine{seals: make(map[common.Hash]common.Hash)}
+	sc, err := New(config, chain, engine)
+	if err != nil {
+		t.Fatalf("failed to create share chain: %v", err)
+	}
+	return sc, chain, engine
+}
+
+// Tests that shares are checked against the zone chain, the share difficulty
+// and the parent share.
+func TestVerify(t *testing.T) {
+	sc, chain, engine := newTestShareChain(t, Config{Ratio: 16, Window: 8})
+	block := chain.head
+	miner := common.Address{0x01}
+
+	// A share meeting the share difficulty is accepted and starts the chain
+	first := newTestShare(block, common.Hash{}, miner, 1<<20, 0)
+	if err := sc.Add(first); err != nil {
+		t.Fatalf("valid share rejected: %v", err)
+	}
+	if work := sc.shares[first.Hash()].work; work.Cmp(big.NewInt(1<<16)) != 0 {
+		t.Errorf("share work mismatch: have %v, want %v", work, 1<<16)
+	}
+	if err := sc.Add(first); !errors.Is(err, ErrKnownShare) {
+		t.Errorf("known share error mismatch: have %v, want %v", err, ErrKnownShare)
+	}
+	// The share difficulty doesn't drop below one
+	low := newTestShare(block, first.Hash(), miner, 4, 1)
+	if err := sc.Add(low); err != nil {
+		t.Fatalf("low difficulty share rejected: %v", err)
+	}
+	if work := sc.shares[low.Hash()].work; work.Cmp(common.Big1) != 0 {
+		t.Errorf("minimum share work mismatch: have %v, want 1", work)
+	}
+
+	unlinked := newTestShare(block, common.Hash{}, miner, 1<<20, 2)
+	unlinked.header.Extra[types.QuaiNetworkContext] = []byte{0x01}
+
+	foreign := newTestShare(block, first.Hash(), miner, 1<<20, 3)
+	foreign.header.Location = []byte{1, 2}
+
+	orphan := newTestShare(block, first.Hash(), miner, 1<<20, 4)
+	orphan.header.ParentHash[types.QuaiNetworkContext] = common.Hash{0xff}
+
+	unsealed := newTestShare(block, first.Hash(), miner, 1<<20, 5)
+	engine.seals[unsealed.header.Hash()] = common.HexToHash("0x0002000000000000000000000000000000000000000000000000000000000000")
+
+	unknown := newTestShare(block, common.Hash{0xfe}, miner, 1<<20, 6)
+
+	tests := []struct {
+		name  string
+		share *Share
+		want  error
+	}{
+		{"missing link", NewShare(unlinked.header), errMissingLink},
+		{"other location", NewShare(foreign.header), errShareLocation},
+		{"unknown block", NewShare(orphan.header), consensus.ErrUnknownAncestor},
+		{"share difficulty", NewShare(unsealed.header), ErrInvalidSeal},
+		{"unknown parent", unknown, ErrUnknownParent},
+	}
+	for _, tt := range tests {
+		if err := sc.Add(tt.share); !errors.Is(err, tt.want) {
+			t.Errorf("%s: error mismatch: have %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	// Shares built on blocks too far behind the head are stale, unless imported
+	for i := 0; i <= staleDepth; i++ {
+		chain.extend(chain.head)
+	}
+	stale := newTestShare(block, low.Hash(), miner, 1<<20, 7)
+	if err := sc.Add(stale); !errors.Is(err, errStaleShare) {
+		t.Errorf("stale share error mismatch: have %v, want %v", err, errStaleShare)
+	}
+	if n, err := sc.Import([]*Share{stale}); err != nil || n != 1 {
+		t.Errorf("stale share import failed: %d, %v", n, err)
+	}
+}
+
+// Tests that share segments are imported without their first parent, that the
+// heaviest chain is followed, and that shares out of the window and far behind
+// the head are pruned.
+func TestImportPrune(t *testing.T) {
+	sc, chain, _ := newTestShareChain(t, Config{Ratio: 1, Window: 4})
+	miner := common.Address{0x01}
+
+	// Import a segment of a chain whose first shares are unknown
+	var (
+		block   = chain.head
+		segment []*Share
+		parent  = common.Hash{0xaa}
+	)
+	for i := 0; i < 3; i++ {
+		share := newTestShare(block, parent, miner, 10, uint64(i))
+		segment = append(segment, share)
+		parent = share.Hash()
+	}
+	if n, err := sc.Import(segment); err != nil || n != len(segment) {
+		t.Fatalf("segment import failed: %d, %v", n, err)
+	}
+	if head := sc.Head(); head.Hash() != segment[2].Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head.Hash(), segment[2].Hash())
+	}
+	// Known shares are skipped, gaps rejected
+	if n, err := sc.Import(segment[1:]); err != nil || n != 2 {
+		t.Errorf("known segment import failed: %d, %v", n, err)
+	}
+	if n, err := sc.Import([]*Share{segment[0], segment[2]}); err == nil || n != 1 {
+		t.Errorf("non contiguous segment imported: %d, %v", n, err)
+	}
+	// A lighter fork is kept but doesn't become the head, a heavier one does
+	light := newTestShare(block, segment[0].Hash(), miner, 10, 10)
+	if err := sc.Add(light); err != nil {
+		t.Fatalf("fork share rejected: %v", err)
+	}
+	if head := sc.Head(); head.Hash() != segment[2].Hash() {
+		t.Errorf("lighter fork became the head")
+	}
+	heavy := newTestShare(block, light.Hash(), miner, 100, 11)
+	if err := sc.Add(heavy); err != nil {
+		t.Fatalf("fork share rejected: %v", err)
+	}
+	if head := sc.Head(); head.Hash() != heavy.Hash() {
+		t.Errorf("heavier fork not the head")
+	}
+	// Extend the chain far past the shares, only the window is kept
+	parent = heavy.Hash()
+	for i := 0; i <= pruneDepth; i++ {
+		chain.extend(chain.head)
+	}
+	var window []*Share
+	for i := 0; i < 4; i++ {
+		share := newTestShare(chain.head, parent, miner, 1000, uint64(20+i))
+		if n, err := sc.Import([]*Share{share}); err != nil || n != 1 {
+			t.Fatalf("share import failed: %d, %v", n, err)
+		}
+		window = append(window, share)
+		parent = share.Hash()
+	}
+	for _, share := range window {
+		if !sc.Has(share.Hash()) {
+			t.Errorf("share in the window pruned")
+		}
+	}
+	for _, share := range append(segment, light, heavy) {
+		if sc.Has(share.Hash()) {
+			t.Errorf("share out of the window kept: %x", share.Hash())
+		}
+	}
+	if len(sc.shares) != len(window) {
+		t.Errorf("share count mismatch: have %d, want %d", len(sc.shares), len(window))
+	}
+}
+
+// Tests that the payouts split the whole reward by work over the window, the
+// rounding remainder going to the miner of the head share, in a deterministic
+// order.
+func TestPayouts(t *testing.T) {
+	sc, chain, _ := newTestShareChain(t, Config{Ratio: 1, Window: 4})
+
+	if payouts := sc.Payouts(big.NewInt(1000)); payouts != nil {
+		t.Fatalf("payouts of an empty share chain: %v", payouts)
+	}
+	var (
+		alice  = common.Address{0x0a}
+		bob    = common.Address{0x0b}
+		carol  = common.Address{0x0c}
+		parent common.Hash
+	)
+	// The first share falls out of the window
+	shares := []struct {
+		miner common.Address
+		work  int64
+	}{{carol, 1000}, {bob, 3}, {alice, 1}, {bob, 1}, {carol, 2}}
+	for i, s := range shares {
+		share := newTestShare(chain.head, parent, s.miner, s.work, uint64(i))
+		if err := sc.Add(share); err != nil {
+			t.Fatalf("share %d rejected: %v", i, err)
+		}
+		parent = share.Hash()
+	}
+	// 1000 over the works 1, 4 and 2 of 7 leave a remainder of 2 to carol
+	want := []*Payout{
+		{Miner: alice, Shares: 1, Work: (*hexutil.Big)(big.NewInt(1)), Amount: (*hexutil.Big)(big.NewInt(142))},
+		{Miner: bob, Shares: 2, Work: (*hexutil.Big)(big.NewInt(4)), Amount: (*hexutil.Big)(big.NewInt(571))},
+		{Miner: carol, Shares: 1, Work: (*hexutil.Big)(big.NewInt(2)), Amount: (*hexutil.Big)(big.NewInt(287))},
+	}
+	for round := 0; round < 8; round++ {
+		have := sc.Payouts(big.NewInt(1000))
+		if len(have) != len(want) {
+			t.Fatalf("payout count mismatch: have %d, want %d", len(have), len(want))
+		}
+		for i := range want {
+			if have[i].Miner != want[i].Miner || have[i].Shares != want[i].Shares ||
+				have[i].Work.ToInt().Cmp(want[i].Work.ToInt()) != 0 || have[i].Amount.ToInt().Cmp(want[i].Amount.ToInt()) != 0 {
+				t.Fatalf("round %d payout %d mismatch: have %+v, want %+v", round, i, have[i], want[i])
+			}
+		}
+	}
+}
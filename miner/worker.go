@@ -9,6 +9,7 @@ import (
 	"time"
 
 	mapset "github.com/deckarep/golang-set"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/consensus"
 	"github.com/spruce-solutions/go-quai/consensus/misc"
@@ -17,6 +18,8 @@ import (
 	"github.com/spruce-solutions/go-quai/core/types"
 	"github.com/spruce-solutions/go-quai/event"
 	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/miner/auxpow"
+	"github.com/spruce-solutions/go-quai/miner/sharechain"
 	"github.com/spruce-solutions/go-quai/params"
 	"github.com/spruce-solutions/go-quai/trie"
 )
@@ -182,10 +185,15 @@ type worker struct {
 	chainHeadSub event.Subscription
 	chainSideCh  chan core.ChainSideEvent
 	chainSideSub event.Subscription
+	domHeadCh    chan core.DomHeadEvent
+	domHeadSub   event.Subscription
+	shareCh      chan sharechain.NewShareEvent
+	shareSub     event.Subscription
 
 	// Channels
 	newWorkCh          chan *newWorkReq
 	getWorkCh          chan *getWorkReq
+	pendingCh          chan chan struct{}
 	taskCh             chan *task
 	resultCh           chan *types.Block
 	startCh            chan struct{}
@@ -199,10 +207,20 @@ type worker struct {
 	localUncles  map[common.Hash]*types.Block // A set of side blocks generated locally as the possible uncle blocks.
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
-
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
-	coinbase common.Address
-	extra    []byte
+	latency      *SealLatency                 // Latency breakdown of the locally sealed blocks.
+	imports      *importMonitor               // Block import spike detector, nil if pausing is disabled.
+	policy       policyState                  // Local transaction policy applied when filling blocks.
+	ordering     TxOrdering                   // Strategy ordering the pending transactions of blocks.
+	selfCheck    *selfCheck                   // Validation of candidate blocks before sealing, nil if disabled.
+	shares       *sharechain.ShareChain       // Share chain the work links to, nil if disabled.
+
+	mu             sync.RWMutex // The lock used to protect the coinbase, extra, sealGuards and aux fields
+	coinbase       common.Address
+	extra          []byte
+	sealGuards     []func(header *types.Header) error // Functions used to refuse sealing work for a header.
+	aux            *auxpow.Tree                       // Commitments of the merge-mined chains, nil if none.
+	auxCommitments map[common.Hash]common.Hash
+	auxTrees       *lru.Cache // Commitment trees of the recent work by root.
 
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
@@ -234,6 +252,7 @@ type worker struct {
 }
 
 func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus.Engine, eth Backend, mux *event.TypeMux, isLocalBlock func(header *types.Header) bool, init bool) *worker {
+	auxTrees, _ := lru.New(auxTreeLimit)
 	worker := &worker{
 		config:             config,
 		chainConfig:        chainConfig,
@@ -245,12 +264,17 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		localUncles:        make(map[common.Hash]*types.Block),
 		remoteUncles:       make(map[common.Hash]*types.Block),
 		unconfirmed:        newUnconfirmedBlocks(eth.BlockChain(), sealingLogAtDepth),
+		latency:            newSealLatency(),
 		pendingTasks:       make(map[common.Hash]*task),
+		auxCommitments:     make(map[common.Hash]common.Hash),
+		auxTrees:           auxTrees,
 		txsCh:              make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:        make(chan core.ChainHeadEvent, chainHeadChanSize),
 		chainSideCh:        make(chan core.ChainSideEvent, chainSideChanSize),
+		domHeadCh:          make(chan core.DomHeadEvent, chainHeadChanSize),
 		newWorkCh:          make(chan *newWorkReq),
 		getWorkCh:          make(chan *getWorkReq),
+		pendingCh:          make(chan chan struct{}),
 		taskCh:             make(chan *task),
 		resultCh:           make(chan *types.Block, resultQueueSize),
 		exitCh:             make(chan struct{}),
@@ -263,6 +287,47 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	// Subscribe events for blockchain
 	worker.chainHeadSub = eth.BlockChain().SubscribeChainHeadEvent(worker.chainHeadCh)
 	worker.chainSideSub = eth.BlockChain().SubscribeChainSideEvent(worker.chainSideCh)
+	worker.domHeadSub = eth.BlockChain().SubscribeDomHeadEvent(worker.domHeadCh)
+
+	// Track block imports if mining should give way to import spikes.
+	if worker.config.ImportPause > 0 {
+		if _, ok := engine.(pauser); ok {
+			worker.imports = newImportMonitor(eth.BlockChain().CurrentBlock().NumberU64())
+		} else {
+			log.Warn("Consensus engine does not support pausing on block imports")
+		}
+	}
+	// Apply the configured local transaction policy.
+	if worker.config.Policy != nil {
+		if err := worker.policy.set(worker.config.Policy); err != nil {
+			log.Error("Invalid transaction policy, mining without", "err", err)
+		}
+	}
+	// Select the configured transaction ordering.
+	ordering, err := LoadOrdering(worker.config.Ordering)
+	if err != nil {
+		log.Error("Invalid transaction ordering, ordering by tip", "err", err)
+		ordering, _ = LoadOrdering(OrderingTip)
+	}
+	worker.ordering = ordering
+
+	if !worker.config.NoSelfCheck {
+		worker.selfCheck = &selfCheck{w: worker}
+	}
+	// Link the work to the share chain if pooled mining is enabled.
+	if worker.config.ShareChain != nil {
+		shares, err := sharechain.New(*worker.config.ShareChain, eth.BlockChain(), engine)
+		if err != nil {
+			log.Error("Invalid share chain, mining without", "err", err)
+		} else {
+			if len(worker.config.ExtraData) > 0 || worker.config.ExtraTemplate != "" {
+				log.Warn("Share chain links replace the block extra data")
+			}
+			worker.shares = shares
+			worker.shareCh = make(chan sharechain.NewShareEvent, chainHeadChanSize)
+			worker.shareSub = shares.SubscribeNewShareEvent(worker.shareCh)
+		}
+	}
 
 	// Sanitize recommit interval if the user-specified one is too short.
 	recommit := worker.config.Recommit
@@ -304,6 +369,39 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+// addSealGuard adds a function consulted before committing sealing work.
+func (w *worker) addSealGuard(guard func(header *types.Header) error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sealGuards = append(w.sealGuards, guard)
+}
+
+// checkSealGuard runs the seal guards against the given header, returning the
+// first refusal.
+func (w *worker) checkSealGuard(header *types.Header) error {
+	w.mu.RLock()
+	guards := w.sealGuards
+	w.mu.RUnlock()
+
+	for _, guard := range guards {
+		if err := guard(header); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trigger commits new sealing work, if the worker is running.
+func (w *worker) trigger() {
+	if !w.isRunning() {
+		return
+	}
+	select {
+	case w.startCh <- struct{}{}:
+	default:
+	}
+}
+
 // setRecommitInterval updates the interval for miner sealing work recommitting.
 func (w *worker) setRecommitInterval(interval time.Duration) {
 	select {
@@ -322,8 +420,58 @@ func (w *worker) enablePreseal() {
 	atomic.StoreUint32(&w.noempty, 0)
 }
 
+// snapshotStale reports whether the pending snapshot is missing or was built on
+// top of a block other than the current chain head.
+func (w *worker) snapshotStale() bool {
+	w.snapshotMu.RLock()
+	defer w.snapshotMu.RUnlock()
+
+	if w.snapshotBlock == nil || w.snapshotState == nil {
+		return true
+	}
+	return w.snapshotBlock.ParentHash() != w.chain.CurrentBlock().Hash()
+}
+
+// refreshSnapshot makes sure the pending snapshot is built on the current head,
+// waiting for the main loop to rebuild it otherwise. Without it pending queries
+// would be answered from a missing or outdated snapshot between a new head and
+// the next sealing work.
+func (w *worker) refreshSnapshot() {
+	if !w.snapshotStale() {
+		return
+	}
+	done := make(chan struct{})
+	select {
+	case w.pendingCh <- done:
+		<-done
+	case <-w.exitCh:
+	}
+}
+
+// rebuildSnapshot assembles a pending block on the current head, including the
+// scheduled external transaction settlements and the pool transactions, and
+// installs it as the pending snapshot. It must be called from the main loop.
+func (w *worker) rebuildSnapshot() {
+	if !w.snapshotStale() {
+		return
+	}
+	work, err := w.prepareWork(&generateParams{timestamp: uint64(time.Now().Unix()), noUncle: true})
+	if err != nil {
+		log.Debug("Failed to rebuild pending state", "err", err)
+		return
+	}
+	defer work.discard()
+
+	w.fillExternalTransactions(nil, work)
+	w.adjustGasLimit(nil, work)
+	w.fillTransactions(nil, work)
+	w.updateSnapshot(work)
+}
+
 // pending returns the pending state and corresponding block.
 func (w *worker) pending() (*types.Block, *state.StateDB) {
+	w.refreshSnapshot()
+
 	// return a snapshot to avoid contention on currentMu mutex
 	w.snapshotMu.RLock()
 	defer w.snapshotMu.RUnlock()
@@ -335,6 +483,8 @@ func (w *worker) pending() (*types.Block, *state.StateDB) {
 
 // pendingBlock returns pending block.
 func (w *worker) pendingBlock() *types.Block {
+	w.refreshSnapshot()
+
 	// return a snapshot to avoid contention on currentMu mutex
 	w.snapshotMu.RLock()
 	defer w.snapshotMu.RUnlock()
@@ -343,6 +493,8 @@ func (w *worker) pendingBlock() *types.Block {
 
 // pendingBlockAndReceipts returns pending block and corresponding receipts.
 func (w *worker) pendingBlockAndReceipts() (*types.Block, types.Receipts) {
+	w.refreshSnapshot()
+
 	// return a snapshot to avoid contention on currentMu mutex
 	w.snapshotMu.RLock()
 	defer w.snapshotMu.RUnlock()
@@ -398,6 +550,9 @@ func recalcRecommit(minRecommit, prev time.Duration, target float64, inc bool) t
 // newWorkLoop is a standalone goroutine to submit new sealing work upon received events.
 func (w *worker) newWorkLoop(recommit time.Duration) {
 	defer w.wg.Done()
+	if w.shareSub != nil {
+		defer w.shareSub.Unsubscribe()
+	}
 	var (
 		interrupt   *int32
 		minRecommit = recommit // minimal resubmit interval specified by user.
@@ -442,9 +597,27 @@ func (w *worker) newWorkLoop(recommit time.Duration) {
 
 		case head := <-w.chainHeadCh:
 			clearPending(head.Block.NumberU64())
+			w.pauseOnImportSpike(head.Block.NumberU64())
 			timestamp = time.Now().Unix()
 			commit(false, commitInterruptNewHead)
 
+		case ev := <-w.domHeadCh:
+			// The work links to the previous dominant head, withdraw it from the
+			// miners and push new work right away.
+			if w.isRunning() {
+				w.invalidateWork(ev.Header)
+				timestamp = time.Now().Unix()
+				commit(false, commitInterruptNewHead)
+			}
+
+		case ev := <-w.shareCh:
+			// Build on the new head share right away, shares linking to an
+			// older one lose the payouts of the head's descendants.
+			if ev.Head && w.isRunning() {
+				timestamp = time.Now().Unix()
+				commit(false, commitInterruptNewHead)
+			}
+
 		case <-timer.C:
 			// If sealing is running resubmit a new work cycle periodically to pull in
 			// higher priced transactions. Disable this overhead for pending blocks.
@@ -501,6 +674,7 @@ func (w *worker) mainLoop() {
 	defer w.txsSub.Unsubscribe()
 	defer w.chainHeadSub.Unsubscribe()
 	defer w.chainSideSub.Unsubscribe()
+	defer w.domHeadSub.Unsubscribe()
 	defer func() {
 		if w.current != nil {
 			w.current.discard()
@@ -524,6 +698,10 @@ func (w *worker) mainLoop() {
 				req.result <- block
 			}
 
+		case done := <-w.pendingCh:
+			w.rebuildSnapshot()
+			close(done)
+
 		case ev := <-w.chainSideCh:
 			// Short circuit for duplicate side blocks
 			if _, exist := w.localUncles[ev.Block.Hash()]; exist {
@@ -577,7 +755,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee[types.QuaiNetworkContext])
+				txset := w.ordering.Order(w.current.signer, txs, w.current.header.BaseFee[types.QuaiNetworkContext])
 				tcount := w.current.tcount
 				w.commitTransactions(w.current, txset, nil)
 
@@ -605,6 +783,8 @@ func (w *worker) mainLoop() {
 			return
 		case <-w.chainSideSub.Err():
 			return
+		case <-w.domHeadSub.Err():
+			return
 		}
 	}
 }
@@ -632,7 +812,7 @@ func (w *worker) taskLoop() {
 				w.newTaskHook(task)
 			}
 			// Reject duplicate sealing work due to resubmitting.
-			sealHash := w.engine.SealHash(task.block.Header())
+			sealHash := w.pendingSealHash(task.block.Header())
 			if sealHash == prev {
 				log.Info("sealHash == prev, continuing with sending task to pending channel", "seal", sealHash, "prev", prev)
 				// continue
@@ -651,6 +831,8 @@ func (w *worker) taskLoop() {
 			w.snapshotMu.Lock()
 			w.pendingBlockFeed.Send(task.block.Header())
 			w.snapshotMu.Unlock()
+
+			w.pushWork(task)
 		case <-w.exitCh:
 			interrupt()
 			return
@@ -674,8 +856,9 @@ func (w *worker) resultLoop() {
 				continue
 			}
 			var (
-				sealhash = w.engine.SealHash(block.Header())
+				sealhash = w.pendingSealHash(block.Header())
 				hash     = block.Hash()
+				sealedAt = time.Now()
 			)
 			w.pendingMu.RLock()
 			task, exist := w.pendingTasks[sealhash]
@@ -684,6 +867,7 @@ func (w *worker) resultLoop() {
 				log.Error("Block found but no relative pending task", "number", block.Number(), "sealhash", sealhash, "hash", hash)
 				continue
 			}
+			w.latency.sealed(hash, block.NumberU64(), task.createdAt, sealedAt)
 			// Different block could share same sealhash, deep copy here to prevent write-write conflict.
 			var (
 				receipts = make([]*types.Receipt, len(task.receipts))
@@ -707,12 +891,14 @@ func (w *worker) resultLoop() {
 					receipt.Logs[i] = log
 					*log = *taskLog
 					log.BlockHash = hash
+					log.ID = types.NewLogID(hash, log.TxIndex, log.Index)
 				}
 				logs = append(logs, receipt.Logs...)
 			}
 
 			log.Info("Successfully sealed new block", "number", block.Number(), "sealhash", sealhash, "hash", hash,
 				"elapsed", common.PrettyDuration(time.Since(task.createdAt)))
+			w.latency.assembled(hash)
 
 			// Broadcast the block and announce chain insertion event
 			w.mux.Post(core.NewMinedBlockEvent{Block: block})
@@ -720,12 +906,29 @@ func (w *worker) resultLoop() {
 			// Insert the block into the set of pending ones to resultLoop for confirmations
 			w.unconfirmed.Insert(block.NumberU64(), block.Hash())
 
+			// A block satisfies the share difficulty as well
+			if w.shares != nil {
+				if err := w.shares.Add(sharechain.NewShare(block.Header())); err != nil {
+					log.Debug("Sealed block rejected by share chain", "hash", hash, "err", err)
+				}
+			}
+
 		case <-w.exitCh:
 			return
 		}
 	}
 }
 
+// pauseOnImportSpike pauses the local mining threads if the chain head update
+// completes a block import spike.
+func (w *worker) pauseOnImportSpike(number uint64) {
+	if w.imports == nil || !w.imports.observe(number, time.Now()) {
+		return
+	}
+	w.engine.(pauser).Pause(w.config.ImportPause)
+	log.Debug("Pausing local mining on block import spike", "number", number, "pause", w.config.ImportPause)
+}
+
 // makeEnv creates a new environment for the sealing block.
 func (w *worker) makeEnv(parent *types.Block, header *types.Header, coinbase common.Address) (*environment, error) {
 	// Retrieve the parent state to execute on top and start a prefetcher for
@@ -745,6 +948,7 @@ func (w *worker) makeEnv(parent *types.Block, header *types.Header, coinbase com
 		return nil, err
 	}
 	state.StartPrefetcher("miner")
+	core.ApplySystemChanges(w.chainConfig, w.engine, parent.Header(), header, state)
 
 	// Note the passed coinbase may be different with header.Coinbase.
 	env := &environment{
@@ -807,8 +1011,28 @@ func (w *worker) updateSnapshot(env *environment) {
 
 func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*types.Log, error) {
 	if tx != nil {
-		snap := env.state.Snapshot()
-		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &env.coinbase, env.gasPool, env.state, env.header, tx, &env.header.GasUsed[types.QuaiNetworkContext], *w.chain.GetVMConfig())
+		var (
+			snap     = env.state.Snapshot()
+			gas      = env.gasPool.Gas()
+			gasUsed  = env.header.GasUsed[types.QuaiNetworkContext]
+			vmConfig = *w.chain.GetVMConfig()
+			tracer   *policyTracer
+		)
+		// Execute under the policy tracer if the local policy excludes anything
+		if filter := w.policy.get(); filter != nil {
+			tracer = &policyTracer{filter: filter}
+			if vmConfig.Debug {
+				tracer.inner = vmConfig.Tracer
+			}
+			vmConfig.Debug, vmConfig.Tracer = true, tracer
+		}
+		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &env.coinbase, env.gasPool, env.state, env.header, tx, &env.header.GasUsed[types.QuaiNetworkContext], vmConfig)
+		if err == nil && tracer != nil && tracer.err != nil {
+			// Excluded during execution, undo the gas accounting too
+			*env.gasPool = core.GasPool(gas)
+			env.header.GasUsed[types.QuaiNetworkContext] = gasUsed
+			err = tracer.err
+		}
 		if err != nil {
 			env.state.RevertToSnapshot(snap)
 			return nil, err
@@ -839,7 +1063,7 @@ func (w *worker) commitExternalTransaction(env *environment, tx *types.Transacti
 	return nil, errors.New("error finding external transaction")
 }
 
-func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *int32) bool {
+func (w *worker) commitTransactions(env *environment, txs TxSet, interrupt *int32) bool {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(gasLimit[types.QuaiNetworkContext])
@@ -890,6 +1114,14 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			txs.Pop()
 			continue
 		}
+		// Leave out transactions excluded by the local policy up front
+		if filter := w.policy.get(); filter != nil {
+			if err := filter.check(from, tx); err != nil {
+				w.policy.exclude(tx, err)
+				txs.Pop()
+				continue
+			}
+		}
 		// Start executing the transaction
 		env.state.Prepare(tx.Hash(), env.tcount)
 
@@ -910,12 +1142,28 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			log.Trace("Skipping account with hight nonce", "sender", from, "nonce", tx.Nonce())
 			txs.Pop()
 
+		case errors.Is(err, core.ErrTxExpired):
+			// Validity window passed since the pool last reset, skip account
+			log.Trace("Skipping expired transaction", "hash", tx.Hash(), "validuntil", tx.ValidUntil())
+			txs.Pop()
+
+		case errors.Is(err, core.ErrAccountExpired), errors.Is(err, core.ErrInvalidRevival):
+			// State expired or revived since the pool last reset, skip account
+			log.Trace("Skipping transaction of expired state", "hash", tx.Hash(), "err", err)
+			txs.Pop()
+
 		case errors.Is(err, nil):
 			// Everything ok, collect the logs and shift in the next transaction from the same account
 			coalescedLogs = append(coalescedLogs, logs...)
 			env.tcount++
 			txs.Shift()
 
+		case errors.Is(err, errExcludedByPolicy):
+			// Pop the transaction excluded by the local policy along with the
+			// dependent ones from the account
+			w.policy.exclude(tx, err)
+			txs.Pop()
+
 		case errors.Is(err, core.ErrTxTypeNotSupported):
 			// Pop the unsupported transaction without shifting in the next from the account
 			log.Trace("Skipping unsupported transaction type", "sender", from, "type", tx.Type())
@@ -1006,6 +1254,16 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 	header.ParentHash[types.QuaiNetworkContext] = parent.Hash()
 	header.Number[types.QuaiNetworkContext] = big.NewInt(int64(num.Uint64()) + 1)
 	header.Extra[types.QuaiNetworkContext] = w.extra
+	// The coinbase stays the local one with pooled mining, its miner settles the
+	// split of the reward over the share chain, see sharechain.Payouts
+	if w.shares != nil {
+		header.Extra[types.QuaiNetworkContext] = w.shares.Link()
+	}
+	if w.aux != nil {
+		root := w.aux.Root()
+		header.Extra[types.QuaiNetworkContext] = root.Bytes()
+		w.auxTrees.Add(root, w.aux)
+	}
 	header.BaseFee[types.QuaiNetworkContext] = misc.CalcBaseFee(w.chainConfig, parent.Header(), w.chain.GetHeaderByNumber, w.chain.GetUnclesInChain, w.chain.GetGasUsedInChain)
 	if w.isRunning() {
 		if w.coinbase == (common.Address{}) {
@@ -1055,21 +1313,39 @@ func (w *worker) fillExternalTransactions(interrupt *int32, env *environment) {
 		return
 	}
 
-	externalGasUsed := uint64(0)
+	var (
+		externalGasUsed = uint64(0)
+		etxs            types.Transactions
+		signer          = types.MakeSigner(w.chainConfig, env.header.Number[types.QuaiNetworkContext])
+	)
 	for _, externalBlock := range externalBlocks {
 		externalBlock.Receipts().DeriveFields(w.chainConfig, externalBlock.Hash(), externalBlock.Header().Number[externalBlock.Context().Int64()].Uint64(), externalBlock.Transactions())
 		externalGasUsed += uint64(externalBlock.Header().GasUsed[externalBlock.Context().Uint64()])
 		for _, tx := range externalBlock.Transactions() {
-			w.commitExternalTransaction(env, tx, externalBlock)
+			// Only the transactions applied on import go into the block
+			msg, err := tx.AsMessage(signer, env.header.BaseFee[types.QuaiNetworkContext])
+			if err != nil || !core.IsApplicableEtx(w.chainConfig, msg, tx) {
+				continue
+			}
+			if _, err := w.commitExternalTransaction(env, tx, externalBlock); err == nil {
+				etxs = append(etxs, tx)
+			}
 		}
 	}
 	env.externalGasUsed = externalGasUsed
 	env.externalBlockLength = len(externalBlocks)
+
+	// Commit the sealed work to the applied external transactions
+	if w.chainConfig.IsEtxRollup(env.header.Number[types.QuaiNetworkContext]) {
+		if env.header.EtxRollupHash == nil {
+			env.header.EtxRollupHash = make([]common.Hash, types.ContextDepth)
+		}
+		env.header.EtxRollupHash[types.QuaiNetworkContext] = core.EtxRollupHash(etxs)
+	}
 }
 
 // fillTransactions retrieves the pending transactions from the txpool and fills them
-// into the given sealing block. The transaction selection and ordering strategy can
-// be customized with the plugin in the future.
+// into the given sealing block in the order of the configured strategy.
 func (w *worker) fillTransactions(interrupt *int32, env *environment) {
 	// Split the pending transactions into locals and remotes
 	// Fill the block with all available pending transactions.
@@ -1085,13 +1361,13 @@ func (w *worker) fillTransactions(interrupt *int32, env *environment) {
 		}
 	}
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, localTxs, env.header.BaseFee[types.QuaiNetworkContext])
+		txs := w.ordering.Order(env.signer, localTxs, env.header.BaseFee[types.QuaiNetworkContext])
 		if w.commitTransactions(env, txs, interrupt) {
 			return
 		}
 	}
 	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, remoteTxs, env.header.BaseFee[types.QuaiNetworkContext])
+		txs := w.ordering.Order(env.signer, remoteTxs, env.header.BaseFee[types.QuaiNetworkContext])
 		if w.commitTransactions(env, txs, interrupt) {
 			return
 		}
@@ -1125,6 +1401,7 @@ func (w *worker) generateWork(params *generateParams) (*types.Block, error) {
 	w.fillExternalTransactions(nil, work)
 	w.adjustGasLimit(nil, work)
 	w.fillTransactions(nil, work)
+	core.MarkStateAccess(w.chainConfig, work.header, work.state)
 	return w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts)
 }
 
@@ -1180,22 +1457,38 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 		// Create a local environment copy, avoid the data race with snapshot state.
 		// https://github.com/ethereum/go-ethereum/issues/24299
 		env := env.copy()
-		block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts)
-		if err != nil {
-			return err
-		}
-		select {
-		case w.taskCh <- &task{receipts: env.receipts, state: env.state, block: block, createdAt: time.Now()}:
-			w.unconfirmed.Shift(block.NumberU64() - 1)
-			log.Info("Commit new sealing work", "number", block.Number(), "sealhash", w.engine.SealHash(block.Header()),
-				"uncles", len(env.uncles), "txs", env.tcount,
-				"gas", block.GasUsed(), "fees", totalFees(block, env.receipts),
-				"elapsed", common.PrettyDuration(time.Since(start)))
-
-		case <-w.exitCh:
-			log.Info("Worker has exited")
+		if err := w.checkSealGuard(env.header); errors.Is(err, errStandby) {
+			log.Debug("Refusing to commit sealing work", "number", env.header.Number[types.QuaiNetworkContext], "err", err)
+		} else if err != nil {
+			log.Warn("Refusing to commit sealing work", "number", env.header.Number[types.QuaiNetworkContext], "err", err)
+		} else {
+			core.MarkStateAccess(w.chainConfig, env.header, env.state)
+			block, err := w.engine.FinalizeAndAssemble(w.chain, env.header, env.state, env.txs, env.unclelist(), env.receipts)
+			if err != nil {
+				return err
+			}
+			// Never seal a block the node would reject on import
+			if w.selfCheck != nil {
+				if err := w.selfCheck.check(block, env); err != nil {
+					log.Warn("Refusing to commit sealing work failing self-check", "number", block.Number(), "err", err)
+					if update {
+						w.updateSnapshot(env)
+					}
+					return nil
+				}
+			}
+			select {
+			case w.taskCh <- &task{receipts: env.receipts, state: env.state, block: block, createdAt: time.Now()}:
+				w.unconfirmed.Shift(block.NumberU64() - 1)
+				log.Info("Commit new sealing work", "number", block.Number(), "sealhash", w.engine.SealHash(block.Header()),
+					"uncles", len(env.uncles), "txs", env.tcount,
+					"gas", block.GasUsed(), "fees", totalFees(block, env.receipts),
+					"elapsed", common.PrettyDuration(time.Since(start)))
+
+			case <-w.exitCh:
+				log.Info("Worker has exited")
+			}
 		}
-
 	}
 	if update {
 		w.updateSnapshot(env)
@@ -25,6 +25,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/crypto"
@@ -190,6 +191,18 @@ type Config struct {
 
 	// AllowUnprotectedTxs allows non EIP-155 protected transactions to be send over RPC.
 	AllowUnprotectedTxs bool `toml:",omitempty"`
+
+	// RPCSlowThreshold is the serving time above which RPC requests are logged as
+	// slow along with their truncated parameters. Zero disables the log.
+	RPCSlowThreshold time.Duration `toml:",omitempty"`
+
+	// RPCAuditLog is the file the calls of the admin, debug, miner and personal
+	// methods are appended to, with the caller and outcome. It is rotated once
+	// larger than RPCAuditLogMaxSize megabytes, keeping RPCAuditLogBackups
+	// rotated files.
+	RPCAuditLog        string `toml:",omitempty"`
+	RPCAuditLogMaxSize int    `toml:",omitempty"`
+	RPCAuditLogBackups int    `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
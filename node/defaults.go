@@ -46,6 +46,9 @@ var DefaultConfig = Config{
 	WSPort:              DefaultWSPort,
 	WSModules:           []string{"net", "web3"},
 	GraphQLVirtualHosts: []string{"localhost"},
+	RPCSlowThreshold:    rpc.DefaultSlowRequestThreshold,
+	RPCAuditLogMaxSize:  100,
+	RPCAuditLogBackups:  10,
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   50,
@@ -51,12 +51,13 @@ type Node struct {
 	state         int               // Tracks state of node lifecycle
 
 	lock          sync.Mutex
-	lifecycles    []Lifecycle // All registered backends, services, and auxiliary services that have a lifecycle
-	rpcAPIs       []rpc.API   // List of APIs currently provided by the node
-	http          *httpServer //
-	ws            *httpServer //
-	ipc           *ipcServer  // Stores information about the ipc http server
-	inprocHandler *rpc.Server // In-process RPC request handler to process the API requests
+	lifecycles    []Lifecycle   // All registered backends, services, and auxiliary services that have a lifecycle
+	rpcAPIs       []rpc.API     // List of APIs currently provided by the node
+	http          *httpServer   //
+	ws            *httpServer   //
+	ipc           *ipcServer    // Stores information about the ipc http server
+	inprocHandler *rpc.Server   // In-process RPC request handler to process the API requests
+	auditLog      *rpc.AuditLog // Log of the privileged RPC calls, if enabled
 
 	databases map[*closeTrackingDB]struct{} // All open databases
 }
@@ -96,6 +97,8 @@ func New(conf *Config) (*Node, error) {
 		return nil, errors.New(`Config.Name cannot end in ".ipc"`)
 	}
 
+	rpc.SetSlowRequestThreshold(conf.RPCSlowThreshold)
+
 	node := &Node{
 		config:        conf,
 		inprocHandler: rpc.NewServer(),
@@ -339,6 +342,16 @@ func (n *Node) closeDataDir() {
 // startup. It's not meant to be called at any time afterwards as it makes certain
 // assumptions about the state of the node.
 func (n *Node) startRPC() error {
+	if n.config.RPCAuditLog != "" {
+		path := n.config.ResolvePath(n.config.RPCAuditLog)
+		audit, err := rpc.OpenAuditLog(path, int64(n.config.RPCAuditLogMaxSize)*1024*1024, n.config.RPCAuditLogBackups)
+		if err != nil {
+			return err
+		}
+		n.auditLog = audit
+		rpc.SetAuditLog(audit)
+		n.log.Info("Auditing privileged RPC calls", "path", path)
+	}
 	if err := n.startInProc(); err != nil {
 		return err
 	}
@@ -400,6 +413,14 @@ func (n *Node) stopRPC() {
 	n.ws.stop()
 	n.ipc.stop()
 	n.stopInProc()
+
+	if n.auditLog != nil {
+		rpc.SetAuditLog(nil)
+		if err := n.auditLog.Close(); err != nil {
+			n.log.Error("Failed to close RPC audit log", "err", err)
+		}
+		n.auditLog = nil
+	}
 }
 
 // startInProc registers all RPC APIs on the inproc server.
@@ -0,0 +1,376 @@
+// Package orphans implements an indexer attributing orphaned and uncled blocks
+// of the local chain to their miners, exposing orphan rate statistics over RPC
+// to help spotting propagation problems and selfish mining patterns.
+package orphans
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// DefaultWindow is the default number of heights the statistics span.
+	DefaultWindow = 4096
+
+	// DefaultDepth is the default number of confirmations after which a height
+	// is indexed, giving sibling blocks time to arrive.
+	DefaultDepth = 7
+)
+
+var (
+	orphanMeter     = metrics.NewRegisteredMeter("orphans/blocks", nil)
+	orphanRateGauge = metrics.NewRegisteredGaugeFloat64("orphans/rate", nil)
+)
+
+// Config contains the settings of the orphan indexer.
+type Config struct {
+	Window uint64 // number of indexed heights the statistics span
+	Depth  uint64 // confirmations before a height is indexed
+}
+
+// backend is the chain access needed by the orphan indexer.
+type backend interface {
+	ChainDb() ethdb.Database
+	CurrentHeader() *types.Header
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Orphan is a block of the local chain which did not become canonical.
+type Orphan struct {
+	Number   hexutil.Uint64 `json:"number"`
+	Hash     common.Hash    `json:"hash"`
+	Coinbase common.Address `json:"coinbase"`
+	Winner   common.Address `json:"winner"` // coinbase of the canonical block at the same height
+	Uncle    bool           `json:"uncle"`  // whether the block was included as an uncle
+}
+
+// height is an indexed height of the local chain.
+type height struct {
+	number   uint64
+	coinbase common.Address // coinbase of the canonical block
+	orphans  []*Orphan
+}
+
+// Indexer tracks the canonical and orphaned blocks over a sliding window of
+// recent heights.
+type Indexer struct {
+	config  Config
+	backend backend
+	db      ethdb.Database
+
+	lock    sync.RWMutex
+	heights []*height               // indexed heights, oldest first
+	known   map[common.Hash]*Orphan // orphans in the window by hash
+	next    uint64                  // next height to index
+
+	headSub event.Subscription
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New creates the orphan indexer and registers it with the node.
+func New(stack *node.Node, backend backend, config Config) *Indexer {
+	if config.Window == 0 {
+		config.Window = DefaultWindow
+	}
+	if config.Depth == 0 {
+		config.Depth = DefaultDepth
+	}
+	idx := &Indexer{
+		config:  config,
+		backend: backend,
+		db:      backend.ChainDb(),
+		known:   make(map[common.Hash]*Orphan),
+		quit:    make(chan struct{}),
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "quai",
+		Version:   "1.0",
+		Service:   &PublicOrphanAPI{idx},
+		Public:    true,
+	}})
+	stack.RegisterLifecycle(idx)
+	return idx
+}
+
+// Start implements node.Lifecycle, starting the indexing loop.
+func (idx *Indexer) Start() error {
+	headCh := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	idx.headSub = idx.backend.SubscribeChainHeadEvent(headCh)
+
+	idx.wg.Add(1)
+	go idx.loop(headCh)
+
+	log.Info("Orphan indexer started", "window", idx.config.Window, "depth", idx.config.Depth)
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the indexing loop.
+func (idx *Indexer) Stop() error {
+	idx.headSub.Unsubscribe()
+	close(idx.quit)
+	idx.wg.Wait()
+	log.Info("Orphan indexer stopped")
+	return nil
+}
+
+func (idx *Indexer) loop(headCh chan core.ChainHeadEvent) {
+	defer idx.wg.Done()
+
+	if head := idx.backend.CurrentHeader(); head != nil {
+		number := head.Number[types.QuaiNetworkContext].Uint64()
+		if number > idx.config.Depth+idx.config.Window {
+			idx.next = number - idx.config.Depth - idx.config.Window
+		}
+		idx.index(number)
+	}
+	for {
+		select {
+		case ev := <-headCh:
+			idx.index(ev.Block.Header().Number[types.QuaiNetworkContext].Uint64())
+		case <-idx.headSub.Err():
+			return
+		case <-idx.quit:
+			return
+		}
+	}
+}
+
+// index processes every height with enough confirmations below the given head.
+func (idx *Indexer) index(head uint64) {
+	if head < idx.config.Depth {
+		return
+	}
+	for ; idx.next <= head-idx.config.Depth; idx.next++ {
+		select {
+		case <-idx.quit:
+			return
+		default:
+		}
+		idx.process(idx.next)
+	}
+	if stats := idx.Stats(); stats.Blocks+stats.Orphans > 0 {
+		orphanRateGauge.Update(stats.Rate)
+	}
+}
+
+// process indexes the canonical block and siblings at the given height, along
+// with the uncles included by the canonical block.
+func (idx *Indexer) process(number uint64) {
+	canonical := rawdb.ReadCanonicalHash(idx.db, number)
+	if canonical == (common.Hash{}) {
+		return
+	}
+	header := rawdb.ReadHeader(idx.db, canonical, number)
+	if header == nil {
+		return
+	}
+	entry := &height{number: number, coinbase: header.Coinbase[types.QuaiNetworkContext]}
+
+	var orphans []*Orphan
+	for _, hash := range rawdb.ReadAllHashes(idx.db, number) {
+		if hash == canonical {
+			continue
+		}
+		if sibling := rawdb.ReadHeader(idx.db, hash, number); sibling != nil {
+			orphans = append(orphans, &Orphan{
+				Number:   hexutil.Uint64(number),
+				Hash:     hash,
+				Coinbase: sibling.Coinbase[types.QuaiNetworkContext],
+				Winner:   entry.coinbase,
+			})
+		}
+	}
+	var uncles []*types.Header
+	if body := rawdb.ReadBody(idx.db, canonical, number); body != nil {
+		uncles = body.Uncles
+	}
+
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	for _, orphan := range orphans {
+		if idx.known[orphan.Hash] == nil {
+			idx.known[orphan.Hash] = orphan
+			entry.orphans = append(entry.orphans, orphan)
+			orphanMeter.Mark(1)
+		}
+	}
+	for _, uncle := range uncles {
+		hash := uncle.Hash()
+		if orphan := idx.known[hash]; orphan != nil {
+			orphan.Uncle = true
+			continue
+		}
+		// The uncle was never imported as a sibling, attribute it to its own height
+		uncleNumber := uncle.Number[types.QuaiNetworkContext].Uint64()
+		if parent := idx.find(uncleNumber); parent != nil {
+			orphan := &Orphan{
+				Number:   hexutil.Uint64(uncleNumber),
+				Hash:     hash,
+				Coinbase: uncle.Coinbase[types.QuaiNetworkContext],
+				Winner:   parent.coinbase,
+				Uncle:    true,
+			}
+			idx.known[hash] = orphan
+			parent.orphans = append(parent.orphans, orphan)
+			orphanMeter.Mark(1)
+		}
+	}
+	idx.heights = append(idx.heights, entry)
+	for uint64(len(idx.heights)) > idx.config.Window {
+		for _, orphan := range idx.heights[0].orphans {
+			delete(idx.known, orphan.Hash)
+		}
+		idx.heights = idx.heights[1:]
+	}
+}
+
+// find returns the indexed height with the given number, if still in the window.
+func (idx *Indexer) find(number uint64) *height {
+	if len(idx.heights) == 0 || number < idx.heights[0].number {
+		return nil
+	}
+	if i := number - idx.heights[0].number; i < uint64(len(idx.heights)) && idx.heights[i].number == number {
+		return idx.heights[i]
+	}
+	// Heights without a canonical block leave gaps, fall back to searching
+	i := sort.Search(len(idx.heights), func(i int) bool { return idx.heights[i].number >= number })
+	if i < len(idx.heights) && idx.heights[i].number == number {
+		return idx.heights[i]
+	}
+	return nil
+}
+
+// MinerStats are the orphan statistics of a single coinbase.
+type MinerStats struct {
+	Coinbase common.Address `json:"coinbase"`
+	Blocks   uint64         `json:"blocks"`  // canonical blocks mined
+	Orphans  uint64         `json:"orphans"` // blocks mined that did not become canonical
+	Uncles   uint64         `json:"uncles"`  // orphans included as uncles
+	Caused   uint64         `json:"caused"`  // orphans of other miners at heights this miner won
+	Rate     float64        `json:"rate"`    // orphans / (blocks + orphans)
+}
+
+// Stats are the orphan statistics of the local chain over the indexed window.
+type Stats struct {
+	Context int            `json:"context"`
+	From    hexutil.Uint64 `json:"from"`
+	To      hexutil.Uint64 `json:"to"`
+	Blocks  uint64         `json:"blocks"`
+	Orphans uint64         `json:"orphans"`
+	Uncles  uint64         `json:"uncles"`
+	Rate    float64        `json:"rate"`
+	Miners  []*MinerStats  `json:"miners"` // sorted by orphan count, descending
+}
+
+// Stats aggregates the orphan statistics over the indexed window.
+func (idx *Indexer) Stats() *Stats {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	stats := &Stats{Context: types.QuaiNetworkContext, Miners: []*MinerStats{}}
+	if len(idx.heights) == 0 {
+		return stats
+	}
+	stats.From = hexutil.Uint64(idx.heights[0].number)
+	stats.To = hexutil.Uint64(idx.heights[len(idx.heights)-1].number)
+
+	miners := make(map[common.Address]*MinerStats)
+	miner := func(coinbase common.Address) *MinerStats {
+		if miners[coinbase] == nil {
+			miners[coinbase] = &MinerStats{Coinbase: coinbase}
+		}
+		return miners[coinbase]
+	}
+	for _, entry := range idx.heights {
+		stats.Blocks++
+		miner(entry.coinbase).Blocks++
+
+		for _, orphan := range entry.orphans {
+			stats.Orphans++
+			m := miner(orphan.Coinbase)
+			m.Orphans++
+			if orphan.Uncle {
+				stats.Uncles++
+				m.Uncles++
+			}
+			if orphan.Coinbase != orphan.Winner {
+				miner(orphan.Winner).Caused++
+			}
+		}
+	}
+	stats.Rate = rate(stats.Blocks, stats.Orphans)
+	for _, m := range miners {
+		m.Rate = rate(m.Blocks, m.Orphans)
+		stats.Miners = append(stats.Miners, m)
+	}
+	sort.Slice(stats.Miners, func(i, j int) bool {
+		if stats.Miners[i].Orphans != stats.Miners[j].Orphans {
+			return stats.Miners[i].Orphans > stats.Miners[j].Orphans
+		}
+		return stats.Miners[i].Blocks > stats.Miners[j].Blocks
+	})
+	return stats
+}
+
+// Recent returns up to limit of the most recent orphans, newest first.
+func (idx *Indexer) Recent(limit int) []*Orphan {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	orphans := []*Orphan{}
+	for i := len(idx.heights) - 1; i >= 0 && len(orphans) < limit; i-- {
+		for _, orphan := range idx.heights[i].orphans {
+			if len(orphans) == limit {
+				break
+			}
+			o := *orphan
+			orphans = append(orphans, &o)
+		}
+	}
+	return orphans
+}
+
+func rate(blocks, orphans uint64) float64 {
+	if blocks+orphans == 0 {
+		return 0
+	}
+	return float64(orphans) / float64(blocks+orphans)
+}
+
+// PublicOrphanAPI offers the orphan statistics over RPC.
+type PublicOrphanAPI struct {
+	idx *Indexer
+}
+
+// OrphanStats returns the orphan rate statistics of the local chain, overall
+// and per miner, over the indexed window.
+func (api *PublicOrphanAPI) OrphanStats() *Stats {
+	return api.idx.Stats()
+}
+
+// RecentOrphans returns the most recent orphaned blocks, newest first. The
+// limit defaults to 100.
+func (api *PublicOrphanAPI) RecentOrphans(limit *int) []*Orphan {
+	n := 100
+	if limit != nil && *limit > 0 {
+		n = *limit
+	}
+	return api.idx.Recent(n)
+}
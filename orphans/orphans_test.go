@@ -0,0 +1,85 @@
+package orphans
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethdb"
+)
+
+var (
+	minerA = common.Address{0x0a}
+	minerB = common.Address{0x0b}
+)
+
+// writeHeader stores a header mined by the given coinbase at the given height.
+func writeHeader(db ethdb.Database, number uint64, coinbase common.Address, nonce byte, canonical bool, uncles ...*types.Header) *types.Header {
+	header := types.NewEmptyHeader()
+	for i := 0; i < types.ContextDepth; i++ {
+		header.Number[i] = new(big.Int).SetUint64(number)
+		header.Difficulty[i] = big.NewInt(1)
+		header.NetworkDifficulty[i] = big.NewInt(1)
+		header.BaseFee[i] = big.NewInt(0)
+		header.Coinbase[i] = coinbase
+	}
+	header.Extra[types.QuaiNetworkContext] = []byte{nonce}
+
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteBody(db, header.Hash(), number, &types.Body{Uncles: uncles})
+	if canonical {
+		rawdb.WriteCanonicalHash(db, header.Hash(), number)
+	}
+	return header
+}
+
+func TestIndexer(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	writeHeader(db, 1, minerA, 0, true)
+	sibling := writeHeader(db, 1, minerB, 1, false)
+	writeHeader(db, 2, minerB, 0, true)
+	writeHeader(db, 3, minerA, 0, true, sibling)
+
+	idx := &Indexer{
+		config: Config{Window: 2, Depth: 1},
+		db:     db,
+		known:  make(map[common.Hash]*Orphan),
+	}
+	idx.next = 1
+	idx.index(3)
+
+	stats := idx.Stats()
+	if stats.From != 1 || stats.To != 2 {
+		t.Fatalf("window mismatch: have %d-%d, want 1-2", stats.From, stats.To)
+	}
+	if stats.Blocks != 2 || stats.Orphans != 1 || stats.Uncles != 0 {
+		t.Fatalf("totals mismatch: have %d blocks, %d orphans, %d uncles", stats.Blocks, stats.Orphans, stats.Uncles)
+	}
+	if stats.Miners[0].Coinbase != minerB || stats.Miners[0].Orphans != 1 || stats.Miners[0].Rate != 0.5 {
+		t.Errorf("miner stats mismatch: have %+v", stats.Miners[0])
+	}
+	// Indexing the height including the sibling as uncle marks it, and evicts height 1
+	idx.index(4)
+	if orphans := idx.Recent(10); len(orphans) != 0 {
+		t.Errorf("evicted orphans reported: %v", orphans)
+	}
+
+	idx = &Indexer{
+		config: Config{Window: 10, Depth: 1},
+		db:     db,
+		known:  make(map[common.Hash]*Orphan),
+	}
+	idx.next = 1
+	idx.index(4)
+
+	orphans := idx.Recent(10)
+	if len(orphans) != 1 || orphans[0].Hash != sibling.Hash() || !orphans[0].Uncle || orphans[0].Winner != minerA {
+		t.Fatalf("orphan mismatch: have %+v", orphans)
+	}
+	if stats := idx.Stats(); stats.Uncles != 1 || stats.Miners[1].Caused != 1 {
+		t.Errorf("uncle attribution mismatch: have %d uncles, miners %+v %+v", stats.Uncles, stats.Miners[0], stats.Miners[1])
+	}
+}
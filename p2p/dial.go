@@ -58,16 +58,32 @@ type nodeResolver interface {
 	Resolve(*enode.Node) *enode.Node
 }
 
-// tcpDialer implements NodeDialer using real TCP connections.
+// tcpDialer implements NodeDialer using real TCP connections, established
+// through a proxy if one is set.
 type tcpDialer struct {
-	d *net.Dialer
+	d      *net.Dialer
+	policy DialPolicy
+	proxy  netutil.DialContextFunc
 }
 
 func (t tcpDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
-	return t.d.DialContext(ctx, "tcp", nodeAddr(dest).String())
+	addr := t.policy.endpoint(dest)
+	if addr == nil {
+		return nil, errDialFamily
+	}
+	if t.proxy != nil {
+		ctx, cancel := context.WithTimeout(ctx, t.d.Timeout)
+		defer cancel()
+		return t.proxy(ctx, "tcp", addr.String())
+	}
+	return t.d.DialContext(ctx, "tcp", addr.String())
 }
 
-func nodeAddr(n *enode.Node) net.Addr {
+// nodeAddr returns the endpoint of the node dialed under the policy.
+func nodeAddr(n *enode.Node, policy DialPolicy) net.Addr {
+	if addr := policy.endpoint(n); addr != nil {
+		return addr
+	}
 	return &net.TCPAddr{IP: n.IP(), Port: n.TCP()}
 }
 
@@ -79,6 +95,7 @@ var (
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNetRestrict      = errors.New("not contained in netrestrict list")
 	errNoPort           = errors.New("node does not provide TCP port")
+	errDialFamily       = errors.New("node has no endpoint in the dialed address family")
 )
 
 // dialer creates outbound connections and submits them into Server.
@@ -103,6 +120,7 @@ type dialScheduler struct {
 	remStaticCh chan *enode.Node
 	addPeerCh   chan *conn
 	remPeerCh   chan *conn
+	maxPeersCh  chan int
 
 	// Everything below here belongs to loop and
 	// should only be accessed by code on the loop goroutine.
@@ -134,6 +152,7 @@ type dialConfig struct {
 	maxDialPeers   int              // maximum number of dialed peers
 	maxActiveDials int              // maximum number of active dials
 	netRestrict    *netutil.Netlist // IP netrestrict list, disabled if nil
+	dialPolicy     DialPolicy       // IP address family of the endpoints dialed
 	resolver       nodeResolver
 	dialer         NodeDialer
 	log            log.Logger
@@ -173,6 +192,7 @@ func newDialScheduler(config dialConfig, it enode.Iterator, setupFunc dialSetupF
 		remStaticCh: make(chan *enode.Node),
 		addPeerCh:   make(chan *conn),
 		remPeerCh:   make(chan *conn),
+		maxPeersCh:  make(chan int),
 	}
 	d.lastStatsLog = d.clock.Now()
 	d.ctx, d.cancel = context.WithCancel(context.Background())
@@ -220,6 +240,14 @@ func (d *dialScheduler) peerRemoved(c *conn) {
 	}
 }
 
+// setMaxDialPeers updates the maximum number of dialed peers.
+func (d *dialScheduler) setMaxDialPeers(n int) {
+	select {
+	case d.maxPeersCh <- n:
+	case <-d.ctx.Done():
+	}
+}
+
 // loop is the main loop of the dialer.
 func (d *dialScheduler) loop(it enode.Iterator) {
 	var (
@@ -274,6 +302,9 @@ loop:
 			delete(d.peers, c.node.ID())
 			d.updateStaticPool(c.node.ID())
 
+		case n := <-d.maxPeersCh:
+			d.maxDialPeers = n
+
 		case node := <-d.addStaticCh:
 			id := node.ID()
 			_, exists := d.static[id]
@@ -401,7 +432,15 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	if _, ok := d.peers[n.ID()]; ok {
 		return errAlreadyConnected
 	}
-	if d.netRestrict != nil && !d.netRestrict.Contains(n.IP()) {
+	ip := n.IP()
+	if ip != nil {
+		addr := d.dialPolicy.endpoint(n)
+		if addr == nil {
+			return errDialFamily
+		}
+		ip = addr.IP
+	}
+	if d.netRestrict != nil && !d.netRestrict.Contains(ip) {
 		return errNetRestrict
 	}
 	if d.history.contains(string(n.ID().Bytes())) {
@@ -537,10 +576,10 @@ func (t *dialTask) resolve(d *dialScheduler) bool {
 func (t *dialTask) dial(d *dialScheduler, dest *enode.Node) error {
 	fd, err := d.dialer.Dial(d.ctx, t.dest)
 	if err != nil {
-		d.log.Trace("Dial error", "id", t.dest.ID(), "addr", nodeAddr(t.dest), "conn", t.flags, "err", cleanupDialErr(err))
+		d.log.Trace("Dial error", "id", t.dest.ID(), "addr", nodeAddr(t.dest, d.dialPolicy), "conn", t.flags, "err", cleanupDialErr(err))
 		return &dialError{err}
 	}
-	mfd := newMeteredConn(fd, false, &net.TCPAddr{IP: dest.IP(), Port: dest.TCP()})
+	mfd := newMeteredConn(fd, false, nodeAddr(dest, d.dialPolicy).(*net.TCPAddr))
 	return d.setupFunc(mfd, t.flags, dest)
 }
 
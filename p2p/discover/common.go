@@ -47,6 +47,11 @@ type Config struct {
 	Log          log.Logger         // if set, log messages go here
 	ValidSchemes enr.IdentityScheme // allowed identity schemes
 	Clock        mclock.Clock
+
+	// ServeFilter, if set, is consulted before a node from the local table is
+	// included in a FINDNODE response. Nodes for which it returns false are not
+	// served to the requester, but remain in the table.
+	ServeFilter func(n *enode.Node) bool
 }
 
 func (cfg Config) withDefaults() Config {
@@ -71,6 +71,7 @@ type UDPv5 struct {
 	log          log.Logger
 	clock        mclock.Clock
 	validSchemes enr.IdentityScheme
+	serveFilter  func(*enode.Node) bool
 
 	// talkreq handler registry
 	trlock     sync.Mutex
@@ -148,6 +149,7 @@ func newUDPv5(conn UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv5, error) {
 		log:          cfg.Log,
 		validSchemes: cfg.ValidSchemes,
 		clock:        cfg.Clock,
+		serveFilter:  cfg.ServeFilter,
 		trhandlers:   make(map[string]TalkRequestHandler),
 		// channels into dispatch
 		packetInCh:    make(chan ReadPacket, 1),
@@ -814,6 +816,9 @@ func (t *UDPv5) collectTableNodes(rip net.IP, distances []uint, limit int) []*en
 			if netutil.CheckRelayIP(rip, n.IP()) != nil {
 				continue
 			}
+			if t.serveFilter != nil && dist != 0 && !t.serveFilter(n) {
+				continue
+			}
 			nodes = append(nodes, n)
 			if len(nodes) >= limit {
 				return nodes
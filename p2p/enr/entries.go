@@ -75,6 +75,13 @@ type UDP6 uint16
 
 func (v UDP6) ENRKey() string { return "udp6" }
 
+// Location is the "location" key, which holds the slice of the Quai hierarchy
+// the node operates in, encoded as a [region, zone] pair. Prime nodes use {0, 0}
+// and region nodes use {region, 0}.
+type Location []byte
+
+func (v Location) ENRKey() string { return "location" }
+
 // ID is the "id" key, which holds the name of the identity scheme.
 type ID string
 
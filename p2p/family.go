@@ -0,0 +1,153 @@
+package p2p
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/spruce-solutions/go-quai/p2p/enode"
+	"github.com/spruce-solutions/go-quai/p2p/enr"
+)
+
+// AddrFamily selects the IP address families the listeners are bound to.
+type AddrFamily int
+
+const (
+	DualStack AddrFamily = iota // Bind both IPv4 and IPv6 if the listen address allows
+	IPv4Only                    // Bind IPv4 only
+	IPv6Only                    // Bind IPv6 only
+)
+
+// String implements fmt.Stringer.
+func (f AddrFamily) String() string {
+	switch f {
+	case DualStack:
+		return "dual"
+	case IPv4Only:
+		return "ipv4"
+	case IPv6Only:
+		return "ipv6"
+	default:
+		return fmt.Sprintf("AddrFamily(%d)", int(f))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (f AddrFamily) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (f *AddrFamily) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "dual":
+		*f = DualStack
+	case "ipv4":
+		*f = IPv4Only
+	case "ipv6":
+		*f = IPv6Only
+	default:
+		return fmt.Errorf(`unknown address family %q, want "dual", "ipv4" or "ipv6"`, text)
+	}
+	return nil
+}
+
+// network returns the name of the network to listen on for the given protocol,
+// "tcp" or "udp", restricted to the address family.
+func (f AddrFamily) network(proto string) string {
+	switch f {
+	case IPv4Only:
+		return proto + "4"
+	case IPv6Only:
+		return proto + "6"
+	default:
+		return proto
+	}
+}
+
+// DialPolicy selects the endpoint dialed for nodes advertising addresses in
+// both IP families, and whether nodes in the other family are dialed at all.
+type DialPolicy int
+
+const (
+	PreferIPv4   DialPolicy = iota // Dial IPv4 endpoints, IPv6 if the node has none
+	PreferIPv6                     // Dial IPv6 endpoints, IPv4 if the node has none
+	DialIPv4Only                   // Dial IPv4 endpoints only
+	DialIPv6Only                   // Dial IPv6 endpoints only
+)
+
+// String implements fmt.Stringer.
+func (p DialPolicy) String() string {
+	switch p {
+	case PreferIPv4:
+		return "prefer-ipv4"
+	case PreferIPv6:
+		return "prefer-ipv6"
+	case DialIPv4Only:
+		return "ipv4"
+	case DialIPv6Only:
+		return "ipv6"
+	default:
+		return fmt.Sprintf("DialPolicy(%d)", int(p))
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p DialPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *DialPolicy) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "prefer-ipv4":
+		*p = PreferIPv4
+	case "prefer-ipv6":
+		*p = PreferIPv6
+	case "ipv4":
+		*p = DialIPv4Only
+	case "ipv6":
+		*p = DialIPv6Only
+	default:
+		return fmt.Errorf(`unknown dial policy %q, want "prefer-ipv4", "prefer-ipv6", "ipv4" or "ipv6"`, text)
+	}
+	return nil
+}
+
+// endpoint returns the TCP endpoint of the node to dial, or nil if the node has
+// none in the families allowed by the policy.
+func (p DialPolicy) endpoint(n *enode.Node) *net.TCPAddr {
+	var (
+		ip4  enr.IPv4
+		ip6  enr.IPv6
+		tcp  enr.TCP
+		tcp6 enr.TCP6
+		v4   *net.TCPAddr
+		v6   *net.TCPAddr
+	)
+	n.Load(&tcp)
+	if n.Load(&ip4) == nil && tcp != 0 {
+		v4 = &net.TCPAddr{IP: net.IP(ip4), Port: int(tcp)}
+	}
+	if n.Load(&tcp6) != nil {
+		tcp6 = enr.TCP6(tcp)
+	}
+	if n.Load(&ip6) == nil && tcp6 != 0 {
+		v6 = &net.TCPAddr{IP: net.IP(ip6), Port: int(tcp6)}
+	}
+	switch p {
+	case DialIPv4Only:
+		return v4
+	case DialIPv6Only:
+		return v6
+	case PreferIPv6:
+		if v6 != nil {
+			return v6
+		}
+		return v4
+	default:
+		if v4 != nil {
+			return v4
+		}
+		return v6
+	}
+}
@@ -0,0 +1,72 @@
+package p2p
+
+import (
+	"net"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/p2p/enode"
+	"github.com/spruce-solutions/go-quai/p2p/enr"
+)
+
+// Tests that the dialed endpoint of a node follows the dial policy.
+func TestDialPolicyEndpoint(t *testing.T) {
+	newNode := func(ip4, ip6 net.IP, tcp, tcp6 int) *enode.Node {
+		var r enr.Record
+		if ip4 != nil {
+			r.Set(enr.IPv4(ip4))
+		}
+		if ip6 != nil {
+			r.Set(enr.IPv6(ip6))
+		}
+		if tcp != 0 {
+			r.Set(enr.TCP(tcp))
+		}
+		if tcp6 != 0 {
+			r.Set(enr.TCP6(tcp6))
+		}
+		return enode.SignNull(&r, randomID())
+	}
+	var (
+		ip4 = net.IP{10, 0, 0, 1}
+		ip6 = net.ParseIP("2001:db8::1")
+
+		dual   = newNode(ip4, ip6, 30303, 30304)
+		only4  = newNode(ip4, nil, 30303, 0)
+		only6  = newNode(nil, ip6, 30303, 0)
+		want4  = &net.TCPAddr{IP: ip4, Port: 30303}
+		want6  = &net.TCPAddr{IP: ip6, Port: 30304}
+		want6b = &net.TCPAddr{IP: ip6, Port: 30303} // tcp port shared with ipv6
+	)
+	tests := []struct {
+		policy DialPolicy
+		node   *enode.Node
+		want   *net.TCPAddr
+	}{
+		{PreferIPv4, dual, want4},
+		{PreferIPv4, only6, want6b},
+		{PreferIPv6, dual, want6},
+		{PreferIPv6, only4, want4},
+		{DialIPv4Only, dual, want4},
+		{DialIPv4Only, only6, nil},
+		{DialIPv6Only, dual, want6},
+		{DialIPv6Only, only4, nil},
+	}
+	for i, tt := range tests {
+		have := tt.policy.endpoint(tt.node)
+		if (have == nil) != (tt.want == nil) || (have != nil && have.String() != tt.want.String()) {
+			t.Errorf("test %d (%v): endpoint mismatch: have %v, want %v", i, tt.policy, have, tt.want)
+		}
+	}
+}
+
+func TestAddrFamilyNetwork(t *testing.T) {
+	for family, want := range map[AddrFamily]string{DualStack: "tcp", IPv4Only: "tcp4", IPv6Only: "tcp6"} {
+		if have := family.network("tcp"); have != want {
+			t.Errorf("%v: network mismatch: have %s, want %s", family, have, want)
+		}
+		var parsed AddrFamily
+		if text, _ := family.MarshalText(); parsed.UnmarshalText(text) != nil || parsed != family {
+			t.Errorf("%v: text round trip failed", family)
+		}
+	}
+}
@@ -41,12 +41,15 @@ var (
 	egressConnectMeter  = metrics.NewRegisteredMeter("p2p/dials", nil)
 	egressTrafficMeter  = metrics.NewRegisteredMeter(egressMeterName, nil)
 	activePeerGauge     = metrics.NewRegisteredGauge("p2p/peers", nil)
+	activePeer4Gauge    = metrics.NewRegisteredGauge("p2p/peers/ipv4", nil)
+	activePeer6Gauge    = metrics.NewRegisteredGauge("p2p/peers/ipv6", nil)
 )
 
 // meteredConn is a wrapper around a net.Conn that meters both the
 // inbound and outbound network traffic.
 type meteredConn struct {
 	net.Conn
+	family metrics.Gauge // Peer count of the remote address family
 }
 
 // newMeteredConn creates a new metered connection, bumps the ingress or egress
@@ -64,7 +67,13 @@ func newMeteredConn(conn net.Conn, ingress bool, addr *net.TCPAddr) net.Conn {
 		egressConnectMeter.Mark(1)
 	}
 	activePeerGauge.Inc(1)
-	return &meteredConn{Conn: conn}
+
+	family := activePeer6Gauge
+	if addr == nil || addr.IP.To4() != nil {
+		family = activePeer4Gauge
+	}
+	family.Inc(1)
+	return &meteredConn{Conn: conn, family: family}
 }
 
 // Read delegates a network read to the underlying connection, bumping the common
@@ -89,6 +98,7 @@ func (c *meteredConn) Close() error {
 	err := c.Conn.Close()
 	if err == nil {
 		activePeerGauge.Dec(1)
+		c.family.Dec(1)
 	}
 	return err
 }
@@ -0,0 +1,35 @@
+package netutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	"golang.org/x/net/proxy"
+)
+
+// DialContextFunc establishes a network connection to addr.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// ProxyDialer returns a dial function connecting through the SOCKS5 proxy at
+// rawurl, e.g. socks5://127.0.0.1:9050 for a local Tor daemon. Credentials can
+// be given in the user info part of the URL. Host names are resolved by the
+// proxy, so they aren't leaked to the local resolver.
+func ProxyDialer(rawurl string) (DialContextFunc, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q, want socks5", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", rawurl)
+	}
+	dialer, err := proxy.FromURL(u, proxy.Direct)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.(proxy.ContextDialer).DialContext, nil
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"os"
 	"sort"
 	"sync"
 	"time"
@@ -116,6 +117,7 @@ type Peer struct {
 
 	// events receives message send / receive events if set
 	events   *event.Feed
+	traceDir string     // directory the message traces are recorded to, if set
 	testPipe *MsgPipeRW // for testing
 }
 
@@ -192,6 +194,11 @@ func (p *Peer) LocalAddr() net.Addr {
 	return p.rw.fd.LocalAddr()
 }
 
+// Connected returns the time the connection to the peer was established.
+func (p *Peer) Connected() mclock.AbsTime {
+	return p.created
+}
+
 // Disconnect terminates the peer connection with the given reason.
 // It returns immediately and does not wait until the connection is closed.
 func (p *Peer) Disconnect(reason DiscReason) {
@@ -401,9 +408,21 @@ func (p *Peer) startProtocols(writeStart <-chan struct{}, writeErr chan<- error)
 		if p.events != nil {
 			rw = newMsgEventer(rw, p.events, p.ID(), proto.Name, p.Info().Network.RemoteAddress, p.Info().Network.LocalAddress)
 		}
+		var trace *os.File
+		if p.traceDir != "" {
+			var err error
+			if trace, err = openTrace(p.traceDir, p, proto.Protocol); err != nil {
+				p.log.Warn("Failed to open message trace", "protocol", proto.Name, "err", err)
+			} else {
+				rw = NewTraceRecorder(rw, trace)
+			}
+		}
 		p.log.Trace(fmt.Sprintf("Starting protocol %s/%d", proto.Name, proto.Version))
 		go func() {
 			defer p.wg.Done()
+			if trace != nil {
+				defer trace.Close()
+			}
 			err := proto.Run(p, rw)
 			if err == nil {
 				p.log.Trace(fmt.Sprintf("Protocol %s/%d returned", proto.Name, proto.Version))
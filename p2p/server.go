@@ -136,6 +136,14 @@ type Config struct {
 	// the server is started.
 	ListenAddr string
 
+	// ListenFamily restricts the TCP and UDP listeners to an IP address family.
+	// By default both families are bound if the listen address is unspecified.
+	ListenFamily AddrFamily `toml:",omitempty"`
+
+	// DialPolicy selects the IP address family of the endpoints dialed, for
+	// nodes advertising both an IPv4 and an IPv6 address.
+	DialPolicy DialPolicy `toml:",omitempty"`
+
 	// If set to a non-nil value, the given NAT port mapper
 	// is used to make the listening port available to the
 	// Internet.
@@ -148,10 +156,19 @@ type Config struct {
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
+	// Proxy is the URL of a SOCKS5 proxy outbound peer connections are dialed
+	// through. Discovery traffic is not proxied.
+	Proxy string `toml:",omitempty"`
+
 	// If EnableMsgEvents is set then the server will emit PeerEvents
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
 
+	// If TraceDir is set, the messages of every protocol run with a peer are
+	// recorded to a trace file in the directory, which can be replayed with
+	// TraceReplayer.
+	TraceDir string `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -353,6 +370,16 @@ func (srv *Server) RemovePeer(node *enode.Node) {
 	}
 }
 
+// SetMaxPeers changes the maximum number of connected peers. Lowering it does
+// not drop any peers, only stops accepting and dialing new ones until the peer
+// count is below the limit.
+func (srv *Server) SetMaxPeers(n int) {
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		srv.MaxPeers = n
+		srv.dialsched.setMaxDialPeers(srv.maxDialedConns())
+	})
+}
+
 // AddTrustedPeer adds the given node to a reserved trusted list which allows the
 // node to always connect, even if the slot are full.
 func (srv *Server) AddTrustedPeer(node *enode.Node) {
@@ -481,7 +508,9 @@ func (srv *Server) Start() (err error) {
 	if err := srv.setupDiscovery(); err != nil {
 		return err
 	}
-	srv.setupDialScheduler()
+	if err := srv.setupDialScheduler(); err != nil {
+		return err
+	}
 
 	srv.loopWG.Add(1)
 	go srv.run()
@@ -504,7 +533,12 @@ func (srv *Server) setupLocalNode() error {
 	}
 	srv.nodedb = db
 	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
-	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
+	if srv.ListenFamily != IPv6Only {
+		srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
+	}
+	if srv.ListenFamily != IPv4Only {
+		srv.localnode.SetFallbackIP(net.IPv6loopback)
+	}
 	// TODO: check conflicts
 	for _, p := range srv.Protocols {
 		for _, e := range p.Attributes {
@@ -549,11 +583,11 @@ func (srv *Server) setupDiscovery() error {
 		return nil
 	}
 
-	addr, err := net.ResolveUDPAddr("udp", srv.ListenAddr)
+	addr, err := net.ResolveUDPAddr(srv.ListenFamily.network("udp"), srv.ListenAddr)
 	if err != nil {
 		return err
 	}
-	conn, err := net.ListenUDP("udp", addr)
+	conn, err := net.ListenUDP(srv.ListenFamily.network("udp"), addr)
 	if err != nil {
 		return err
 	}
@@ -614,13 +648,14 @@ func (srv *Server) setupDiscovery() error {
 	return nil
 }
 
-func (srv *Server) setupDialScheduler() {
+func (srv *Server) setupDialScheduler() error {
 	config := dialConfig{
 		self:           srv.localnode.ID(),
 		maxDialPeers:   srv.maxDialedConns(),
 		maxActiveDials: srv.MaxPendingPeers,
 		log:            srv.Logger,
 		netRestrict:    srv.NetRestrict,
+		dialPolicy:     srv.DialPolicy,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
 	}
@@ -628,12 +663,24 @@ func (srv *Server) setupDialScheduler() {
 		config.resolver = srv.ntab
 	}
 	if config.dialer == nil {
-		config.dialer = tcpDialer{&net.Dialer{Timeout: defaultDialTimeout}}
+		dialer := tcpDialer{d: &net.Dialer{Timeout: defaultDialTimeout}, policy: srv.DialPolicy}
+		if srv.Proxy != "" {
+			proxy, err := netutil.ProxyDialer(srv.Proxy)
+			if err != nil {
+				return fmt.Errorf("invalid p2p proxy: %v", err)
+			}
+			dialer.proxy = proxy
+			if !srv.NoDiscovery || srv.DiscoveryV5 {
+				srv.log.Warn("Dialing peers through proxy, node discovery is not proxied")
+			}
+		}
+		config.dialer = dialer
 	}
 	srv.dialsched = newDialScheduler(config, srv.discmix, srv.SetupConn)
 	for _, n := range srv.StaticNodes {
 		srv.dialsched.addStatic(n)
 	}
+	return nil
 }
 
 func (srv *Server) maxInboundConns() int {
@@ -657,7 +704,7 @@ func (srv *Server) maxDialedConns() (limit int) {
 
 func (srv *Server) setupListening() error {
 	// Launch the listener.
-	listener, err := srv.listenFunc("tcp", srv.ListenAddr)
+	listener, err := srv.listenFunc(srv.ListenFamily.network("tcp"), srv.ListenAddr)
 	if err != nil {
 		return err
 	}
@@ -1019,6 +1066,7 @@ func (srv *Server) launchPeer(c *conn) *Peer {
 		// to the peer.
 		p.events = &srv.peerFeed
 	}
+	p.traceDir = srv.TraceDir
 	go srv.runPeer(p)
 	return p
 }
@@ -300,6 +300,46 @@ func TestServerAtCap(t *testing.T) {
 	}
 }
 
+// Tests that the peer limit can be changed on a running server.
+func TestServerSetMaxPeers(t *testing.T) {
+	remote := newkey()
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  newkey(),
+			MaxPeers:    10,
+			NoDial:      true,
+			NoDiscovery: true,
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&remote.PublicKey, fd, nil)
+		node := enode.SignNull(new(enr.Record), id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, cont: make(chan error)}
+	}
+	for i := 0; i < 5; i++ {
+		if err := srv.checkpoint(newconn(randomID()), srv.checkpointAddPeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+	}
+	// Lowering the limit to the peer count rejects further peers
+	srv.SetMaxPeers(5)
+	if err := srv.checkpoint(newconn(randomID()), srv.checkpointPostHandshake); err != DiscTooManyPeers {
+		t.Error("wrong error for insert above lowered limit:", err)
+	}
+	// Raising it accepts them again
+	srv.SetMaxPeers(6)
+	if err := srv.checkpoint(newconn(randomID()), srv.checkpointPostHandshake); err != nil {
+		t.Error("unexpected error for insert below raised limit:", err)
+	}
+}
+
 func TestServerPeerLimits(t *testing.T) {
 	srvkey := newkey()
 	clientkey := newkey()
@@ -0,0 +1,128 @@
+package adapters
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/p2p/enode"
+)
+
+// errPartitioned is returned when dialing or writing across a simulated
+// network partition.
+var errPartitioned = errors.New("simulated network partition")
+
+// LinkConditioner is implemented by adapters which can inject latency and
+// partitions between simulated nodes.
+type LinkConditioner interface {
+	// SetLatency sets the one-way latency applied to every write between the
+	// two nodes. A zero duration removes the latency.
+	SetLatency(one, other enode.ID, latency time.Duration)
+
+	// Partition splits the network into the given groups. Nodes in different
+	// groups can no longer dial or talk to each other, nodes not mentioned in
+	// any group remain reachable by everyone.
+	Partition(groups ...[]enode.ID)
+
+	// Heal removes all partitions.
+	Heal()
+
+	// Partitioned reports whether the two nodes are separated by a partition.
+	Partitioned(one, other enode.ID) bool
+}
+
+// linkPair is an unordered pair of node IDs.
+type linkPair struct {
+	a, b enode.ID
+}
+
+func newLinkPair(one, other enode.ID) linkPair {
+	if bytes.Compare(one[:], other[:]) > 0 {
+		one, other = other, one
+	}
+	return linkPair{one, other}
+}
+
+// linkConditions tracks the latency and partition state of the simulated
+// links.
+type linkConditions struct {
+	lock    sync.RWMutex
+	latency map[linkPair]time.Duration
+	groups  map[enode.ID]int
+}
+
+func newLinkConditions() *linkConditions {
+	return &linkConditions{
+		latency: make(map[linkPair]time.Duration),
+		groups:  make(map[enode.ID]int),
+	}
+}
+
+func (lc *linkConditions) setLatency(one, other enode.ID, latency time.Duration) {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+
+	if latency <= 0 {
+		delete(lc.latency, newLinkPair(one, other))
+		return
+	}
+	lc.latency[newLinkPair(one, other)] = latency
+}
+
+func (lc *linkConditions) getLatency(one, other enode.ID) time.Duration {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+	return lc.latency[newLinkPair(one, other)]
+}
+
+func (lc *linkConditions) partition(groups ...[]enode.ID) {
+	lc.lock.Lock()
+	defer lc.lock.Unlock()
+
+	lc.groups = make(map[enode.ID]int)
+	for i, group := range groups {
+		for _, id := range group {
+			lc.groups[id] = i + 1
+		}
+	}
+}
+
+func (lc *linkConditions) heal() {
+	lc.partition()
+}
+
+func (lc *linkConditions) partitioned(one, other enode.ID) bool {
+	lc.lock.RLock()
+	defer lc.lock.RUnlock()
+
+	g1, g2 := lc.groups[one], lc.groups[other]
+	return g1 != 0 && g2 != 0 && g1 != g2
+}
+
+// conditionedConn wraps one end of a simulated connection, delaying writes by
+// the configured link latency and failing them once the link is partitioned.
+type conditionedConn struct {
+	net.Conn
+	local, remote enode.ID
+	links         *linkConditions
+}
+
+func (c *conditionedConn) Write(b []byte) (int, error) {
+	if c.links.partitioned(c.local, c.remote) {
+		c.Conn.Close()
+		return 0, errPartitioned
+	}
+	if latency := c.links.getLatency(c.local, c.remote); latency > 0 {
+		time.Sleep(latency)
+	}
+	return c.Conn.Write(b)
+}
+
+// simDialer is the per-node p2p.NodeDialer handed out by the SimAdapter, so
+// that link conditions know which node is dialing.
+type simDialer struct {
+	adapter *SimAdapter
+	src     enode.ID
+}
@@ -23,6 +23,7 @@ import (
 	"math"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/spruce-solutions/go-quai/event"
 	"github.com/spruce-solutions/go-quai/log"
@@ -41,6 +42,7 @@ type SimAdapter struct {
 	mtx        sync.RWMutex
 	nodes      map[enode.ID]*SimNode
 	lifecycles LifecycleConstructors
+	links      *linkConditions
 }
 
 // NewSimAdapter creates a SimAdapter which is capable of running in-memory
@@ -52,6 +54,7 @@ func NewSimAdapter(services LifecycleConstructors) *SimAdapter {
 		pipe:       pipes.NetPipe,
 		nodes:      make(map[enode.ID]*SimNode),
 		lifecycles: services,
+		links:      newLinkConditions(),
 	}
 }
 
@@ -96,7 +99,7 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 			PrivateKey:      config.PrivateKey,
 			MaxPeers:        math.MaxInt32,
 			NoDiscovery:     true,
-			Dialer:          s,
+			Dialer:          &simDialer{adapter: s, src: id},
 			EnableMsgEvents: config.EnableMsgEvents,
 		},
 		ExternalSigner: config.ExternalSigner,
@@ -120,6 +123,19 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 // Dial implements the p2p.NodeDialer interface by connecting to the node using
 // an in-memory net.Pipe
 func (s *SimAdapter) Dial(ctx context.Context, dest *enode.Node) (conn net.Conn, err error) {
+	return s.dial(ctx, enode.ID{}, dest)
+}
+
+// Dial implements the p2p.NodeDialer interface, applying the link conditions
+// between the dialing node and the destination.
+func (d *simDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	return d.adapter.dial(ctx, d.src, dest)
+}
+
+func (s *SimAdapter) dial(ctx context.Context, src enode.ID, dest *enode.Node) (conn net.Conn, err error) {
+	if s.links.partitioned(src, dest.ID()) {
+		return nil, errPartitioned
+	}
 	node, ok := s.GetNode(dest.ID())
 	if !ok {
 		return nil, fmt.Errorf("unknown node: %s", dest.ID())
@@ -136,10 +152,55 @@ func (s *SimAdapter) Dial(ctx context.Context, dest *enode.Node) (conn net.Conn,
 	// this is simulated 'listening'
 	// asynchronously call the dialed destination node's p2p server
 	// to set up connection on the 'listening' side
+	pipe1 = &conditionedConn{Conn: pipe1, local: dest.ID(), remote: src, links: s.links}
+	pipe2 = &conditionedConn{Conn: pipe2, local: src, remote: dest.ID(), links: s.links}
 	go srv.SetupConn(pipe1, 0, nil)
 	return pipe2, nil
 }
 
+// Redial connects the source node to the destination right away, bypassing
+// the dial scheduler of the source node and its redial backoff.
+func (s *SimAdapter) Redial(src, dest enode.ID) error {
+	srcNode, ok := s.GetNode(src)
+	if !ok {
+		return fmt.Errorf("unknown node: %s", src)
+	}
+	destNode, ok := s.GetNode(dest)
+	if !ok {
+		return fmt.Errorf("unknown node: %s", dest)
+	}
+	srv := srcNode.Server()
+	if srv == nil {
+		return fmt.Errorf("node not running: %s", src)
+	}
+	conn, err := s.dial(context.Background(), src, destNode.Node())
+	if err != nil {
+		return err
+	}
+	go srv.SetupConn(conn, 0, destNode.Node())
+	return nil
+}
+
+// SetLatency implements LinkConditioner.
+func (s *SimAdapter) SetLatency(one, other enode.ID, latency time.Duration) {
+	s.links.setLatency(one, other, latency)
+}
+
+// Partition implements LinkConditioner.
+func (s *SimAdapter) Partition(groups ...[]enode.ID) {
+	s.links.partition(groups...)
+}
+
+// Heal implements LinkConditioner.
+func (s *SimAdapter) Heal() {
+	s.links.heal()
+}
+
+// Partitioned implements LinkConditioner.
+func (s *SimAdapter) Partitioned(one, other enode.ID) bool {
+	return s.links.partitioned(one, other)
+}
+
 // DialRPC implements the RPCDialer interface by creating an in-memory RPC
 // client of the given node
 func (s *SimAdapter) DialRPC(id enode.ID) (*rpc.Client, error) {
@@ -110,6 +110,11 @@ type NodeConfig struct {
 	// ExternalSigner specifies an external URI for a clef-type signer
 	ExternalSigner string
 
+	// Location is the [region, zone] slice the node belongs to. Prime nodes
+	// use {0, 0} and region nodes use {region, 0}. A nil location means the
+	// node is not part of a simulated hierarchy.
+	Location []byte
+
 	// Enode
 	node *enode.Node
 
@@ -145,6 +150,7 @@ type nodeConfigJSON struct {
 	Port            uint16   `json:"port"`
 	LogFile         string   `json:"logfile"`
 	LogVerbosity    int      `json:"log_verbosity"`
+	Location        string   `json:"location,omitempty"`
 }
 
 // MarshalJSON implements the json.Marshaler interface by encoding the config
@@ -160,6 +166,9 @@ func (n *NodeConfig) MarshalJSON() ([]byte, error) {
 		LogFile:         n.LogFile,
 		LogVerbosity:    int(n.LogVerbosity),
 	}
+	if n.Location != nil {
+		confJSON.Location = hex.EncodeToString(n.Location)
+	}
 	if n.PrivateKey != nil {
 		confJSON.PrivateKey = hex.EncodeToString(crypto.FromECDSA(n.PrivateKey))
 	}
@@ -192,6 +201,14 @@ func (n *NodeConfig) UnmarshalJSON(data []byte) error {
 		n.PrivateKey = privKey
 	}
 
+	if confJSON.Location != "" {
+		loc, err := hex.DecodeString(confJSON.Location)
+		if err != nil {
+			return err
+		}
+		n.Location = loc
+	}
+
 	n.Name = confJSON.Name
 	n.Lifecycles = confJSON.Lifecycles
 	n.Properties = confJSON.Properties
@@ -307,6 +324,9 @@ func (n *NodeConfig) initEnode(ip net.IP, tcpport int, udpport int) error {
 	n.Record.Set(&enrTcpPort)
 	enrUdpPort := enr.UDP(udpport)
 	n.Record.Set(&enrUdpPort)
+	if n.Location != nil {
+		n.Record.Set(enr.Location(n.Location))
+	}
 
 	err := enode.SignV4(&n.Record, n.PrivateKey)
 	if err != nil {
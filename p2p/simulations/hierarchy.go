@@ -0,0 +1,289 @@
+package simulations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/p2p"
+	"github.com/spruce-solutions/go-quai/p2p/enode"
+	"github.com/spruce-solutions/go-quai/p2p/simulations/adapters"
+)
+
+// Node properties assigned to the nodes of a simulated hierarchy, so that they
+// can be looked up with GetNodesByProperty.
+const (
+	PrimeProperty  = "prime"
+	RegionProperty = "region"
+	ZoneProperty   = "zone"
+)
+
+// errNoLinkConditioner is returned when link conditions are requested from a
+// network whose adapter cannot simulate them.
+var errNoLinkConditioner = errors.New("node adapter does not support link conditions")
+
+// SetLatency sets the one-way latency between two nodes of the network.
+func (net *Network) SetLatency(one, other enode.ID, latency time.Duration) error {
+	lc, ok := net.nodeAdapter.(adapters.LinkConditioner)
+	if !ok {
+		return errNoLinkConditioner
+	}
+	lc.SetLatency(one, other, latency)
+	return nil
+}
+
+// Partition splits the network into the given groups of nodes and drops every
+// live connection crossing a group boundary. The dropped connections are
+// remembered so that Heal can re-establish them.
+func (net *Network) Partition(groups ...[]enode.ID) error {
+	lc, ok := net.nodeAdapter.(adapters.LinkConditioner)
+	if !ok {
+		return errNoLinkConditioner
+	}
+	lc.Partition(groups...)
+
+	net.lock.Lock()
+	for _, conn := range net.Conns {
+		if conn.Up && lc.Partitioned(conn.One, conn.Other) {
+			net.partitioned = append(net.partitioned, conn)
+		}
+	}
+	net.lock.Unlock()
+
+	for _, node := range net.GetNodes() {
+		srv, ok := node.Node.(interface{ Server() *p2p.Server })
+		if !ok || srv.Server() == nil {
+			continue
+		}
+		for _, peer := range srv.Server().Peers() {
+			if lc.Partitioned(node.ID(), peer.ID()) {
+				peer.Disconnect(p2p.DiscNetworkError)
+			}
+		}
+	}
+	return nil
+}
+
+// Heal removes all partitions from the network. If the adapter supports it,
+// the connections dropped by Partition are re-established right away instead
+// of waiting for the redial backoff of the p2p servers to expire.
+func (net *Network) Heal() error {
+	lc, ok := net.nodeAdapter.(adapters.LinkConditioner)
+	if !ok {
+		return errNoLinkConditioner
+	}
+	lc.Heal()
+
+	net.lock.Lock()
+	dropped := net.partitioned
+	net.partitioned = nil
+	net.lock.Unlock()
+
+	redialer, ok := net.nodeAdapter.(interface {
+		Redial(src, dest enode.ID) error
+	})
+	if !ok {
+		return nil
+	}
+	for _, conn := range dropped {
+		if conn.nodesUp() != nil {
+			continue
+		}
+		if err := redialer.Redial(conn.One, conn.Other); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HierarchyConfig describes the shape of a simulated prime/region/zone network.
+type HierarchyConfig struct {
+	Regions       int    // number of regions below prime
+	Zones         int    // number of zones below every region
+	NodesPerChain int    // number of nodes running every chain
+	Lifecycle     string // service to run, the network default if empty
+}
+
+// Hierarchy is a set of simulated nodes arranged as one prime chain, a number
+// of region chains and a number of zone chains per region. Nodes running the
+// same chain are fully connected to each other.
+type Hierarchy struct {
+	net    *Network
+	chains map[string][]enode.ID
+}
+
+// locationKey returns the canonical "region-zone" notation of a location.
+func locationKey(loc []byte) string {
+	return fmt.Sprintf("%d-%d", loc[0], loc[1])
+}
+
+// NewHierarchy creates the nodes of a hierarchy in the given network. The
+// nodes are not started, use Start to bring them up.
+func NewHierarchy(net *Network, config HierarchyConfig) (*Hierarchy, error) {
+	if config.Regions <= 0 || config.Zones <= 0 || config.NodesPerChain <= 0 {
+		return nil, fmt.Errorf("invalid hierarchy %d regions, %d zones, %d nodes per chain", config.Regions, config.Zones, config.NodesPerChain)
+	}
+	if config.Regions > 255 || config.Zones > 255 {
+		return nil, fmt.Errorf("hierarchy too large: %d regions, %d zones", config.Regions, config.Zones)
+	}
+	h := &Hierarchy{
+		net:    net,
+		chains: make(map[string][]enode.ID),
+	}
+	if err := h.addChain([]byte{0, 0}, PrimeProperty, config); err != nil {
+		return nil, err
+	}
+	for r := 1; r <= config.Regions; r++ {
+		if err := h.addChain([]byte{byte(r), 0}, RegionProperty, config); err != nil {
+			return nil, err
+		}
+		for z := 1; z <= config.Zones; z++ {
+			if err := h.addChain([]byte{byte(r), byte(z)}, ZoneProperty, config); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return h, nil
+}
+
+func (h *Hierarchy) addChain(loc []byte, property string, config HierarchyConfig) error {
+	key := locationKey(loc)
+	for i := 0; i < config.NodesPerChain; i++ {
+		conf := adapters.RandomNodeConfig()
+		conf.Name = fmt.Sprintf("%s-%s-%d", property, key, i)
+		conf.Location = common.CopyBytes(loc)
+		conf.Properties = []string{property}
+		if config.Lifecycle != "" {
+			conf.Lifecycles = []string{config.Lifecycle}
+		}
+		node, err := h.net.NewNodeWithConfig(conf)
+		if err != nil {
+			return err
+		}
+		h.chains[key] = append(h.chains[key], node.ID())
+	}
+	return nil
+}
+
+// Start starts every node of the hierarchy and connects the nodes running the
+// same chain to each other.
+func (h *Hierarchy) Start() error {
+	for _, ids := range h.chains {
+		for _, id := range ids {
+			if err := h.net.Start(id); err != nil {
+				return err
+			}
+		}
+	}
+	for _, ids := range h.chains {
+		if err := h.net.ConnectNodesFull(ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Locations returns the locations of all chains in the hierarchy, sorted.
+func (h *Hierarchy) Locations() [][]byte {
+	keys := make([]string, 0, len(h.chains))
+	for key := range h.chains {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	locs := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		loc := h.net.GetNode(h.chains[key][0]).Config.Location
+		locs = append(locs, common.CopyBytes(loc))
+	}
+	return locs
+}
+
+// Chain returns the IDs of the nodes running the chain at the given location.
+func (h *Hierarchy) Chain(loc []byte) []enode.ID {
+	if len(loc) != 2 {
+		return nil
+	}
+	return append([]enode.ID(nil), h.chains[locationKey(loc)]...)
+}
+
+// Slice returns the IDs of all nodes participating in the given zone slice,
+// i.e. the prime, region and zone nodes along the path to the zone. This is
+// handy for cutting a whole slice off from the rest of the network.
+func (h *Hierarchy) Slice(region, zone byte) []enode.ID {
+	var ids []enode.ID
+	ids = append(ids, h.Chain([]byte{0, 0})...)
+	ids = append(ids, h.Chain([]byte{region, 0})...)
+	ids = append(ids, h.Chain([]byte{region, zone})...)
+	return ids
+}
+
+// HeadFunc retrieves the current head hash of a simulated node.
+type HeadFunc func(node *Node) (common.Hash, error)
+
+// RPCHeadFunc returns a HeadFunc which fetches the head block over the node's
+// RPC client using the given getBlockByNumber style method.
+func RPCHeadFunc(method string) HeadFunc {
+	return func(node *Node) (common.Hash, error) {
+		client, err := node.Client()
+		if err != nil {
+			return common.Hash{}, err
+		}
+		var head struct {
+			Hash common.Hash `json:"hash"`
+		}
+		if err := client.Call(&head, method, "latest", false); err != nil {
+			return common.Hash{}, err
+		}
+		return head.Hash, nil
+	}
+}
+
+// Converged reports whether all nodes running the same chain agree on the
+// head returned by the given function. The first disagreement or retrieval
+// failure is returned as error.
+func (h *Hierarchy) Converged(head HeadFunc) error {
+	for _, loc := range h.Locations() {
+		var (
+			ids  = h.chains[locationKey(loc)]
+			want common.Hash
+		)
+		for i, id := range ids {
+			hash, err := head(h.net.GetNode(id))
+			if err != nil {
+				return fmt.Errorf("chain %s node %s: %v", locationKey(loc), id.TerminalString(), err)
+			}
+			if i == 0 {
+				want = hash
+				continue
+			}
+			if hash != want {
+				return fmt.Errorf("chain %s diverged: node %s head %x, node %s head %x", locationKey(loc),
+					ids[0].TerminalString(), want, id.TerminalString(), hash)
+			}
+		}
+	}
+	return nil
+}
+
+// WaitForConvergence polls the nodes until every chain of the hierarchy has
+// converged on a single head or the context is cancelled.
+func (h *Hierarchy) WaitForConvergence(ctx context.Context, head HeadFunc) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		err := h.Converged(head)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%v: %v", ctx.Err(), err)
+		case <-ticker.C:
+		}
+	}
+}
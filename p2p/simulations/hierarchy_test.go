@@ -0,0 +1,122 @@
+package simulations
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/p2p/enode"
+	"github.com/spruce-solutions/go-quai/p2p/simulations/adapters"
+)
+
+func newHierarchyTestNetwork(t *testing.T) (*Network, *Hierarchy) {
+	t.Helper()
+
+	adapter := adapters.NewSimAdapter(adapters.LifecycleConstructors{
+		"noopwoop": func(ctx *adapters.ServiceContext, stack *node.Node) (node.Lifecycle, error) {
+			return NewNoopService(nil), nil
+		},
+	})
+	network := NewNetwork(adapter, &NetworkConfig{DefaultService: "noopwoop"})
+
+	h, err := NewHierarchy(network, HierarchyConfig{Regions: 2, Zones: 2, NodesPerChain: 2})
+	if err != nil {
+		network.Shutdown()
+		t.Fatal(err)
+	}
+	return network, h
+}
+
+func TestHierarchyLayout(t *testing.T) {
+	network, h := newHierarchyTestNetwork(t)
+	defer network.Shutdown()
+
+	// 1 prime + 2 regions + 4 zones, 2 nodes each
+	if n := len(network.GetNodes()); n != 14 {
+		t.Fatalf("node count mismatch: have %d, want 14", n)
+	}
+	if n := len(h.Locations()); n != 7 {
+		t.Fatalf("chain count mismatch: have %d, want 7", n)
+	}
+	if n := len(network.GetNodesByProperty(ZoneProperty)); n != 8 {
+		t.Fatalf("zone node count mismatch: have %d, want 8", n)
+	}
+	for _, id := range h.Chain([]byte{2, 1}) {
+		if loc := network.GetNode(id).Config.Location; !bytes.Equal(loc, []byte{2, 1}) {
+			t.Fatalf("node %s location mismatch: have %v, want [2 1]", id.TerminalString(), loc)
+		}
+	}
+	if n := len(h.Slice(1, 2)); n != 6 {
+		t.Fatalf("slice node count mismatch: have %d, want 6", n)
+	}
+}
+
+func TestHierarchyPartition(t *testing.T) {
+	network, h := newHierarchyTestNetwork(t)
+	defer network.Shutdown()
+
+	if err := h.Start(); err != nil {
+		t.Fatal(err)
+	}
+	prime := h.Chain([]byte{0, 0})
+	waitConnected(t, network, prime[0], prime[1], true)
+
+	if err := network.SetLatency(prime[0], prime[1], 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := network.Partition(prime[:1], prime[1:]); err != nil {
+		t.Fatal(err)
+	}
+	waitConnected(t, network, prime[0], prime[1], false)
+
+	if err := network.Heal(); err != nil {
+		t.Fatal(err)
+	}
+	waitConnected(t, network, prime[0], prime[1], true)
+}
+
+func TestHierarchyConvergence(t *testing.T) {
+	network, h := newHierarchyTestNetwork(t)
+	defer network.Shutdown()
+
+	diverged := h.Chain([]byte{1, 2})[1]
+	heads := func(node *Node) (common.Hash, error) {
+		if node.ID() == diverged {
+			return common.Hash{0x01}, nil
+		}
+		return common.BytesToHash(node.Config.Location), nil
+	}
+	if err := h.Converged(heads); err == nil {
+		t.Fatal("expected divergence to be detected")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := h.WaitForConvergence(ctx, heads); err == nil {
+		t.Fatal("expected convergence timeout")
+	}
+
+	diverged = enode.ID{}
+	if err := h.WaitForConvergence(context.Background(), heads); err != nil {
+		t.Fatalf("failed to converge: %v", err)
+	}
+}
+
+// waitConnected waits until the connection between two nodes is up or down.
+func waitConnected(t *testing.T, network *Network, one, other enode.ID, up bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if conn := network.GetConn(one, other); conn != nil && conn.Up == up {
+			return
+		}
+		if conn := network.GetConn(other, one); conn != nil && conn.Up == up {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("connection %s-%s did not reach up=%v", one.TerminalString(), other.TerminalString(), up)
+}
@@ -62,6 +62,9 @@ type Network struct {
 	Conns   []*Conn `json:"conns"`
 	connMap map[string]int
 
+	// Connections dropped by Partition, re-established by Heal
+	partitioned []*Conn
+
 	nodeAdapter adapters.NodeAdapter
 	events      event.Feed
 	lock        sync.RWMutex
@@ -0,0 +1,177 @@
+package p2p
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/rlp"
+)
+
+// TraceEntry is a message of a protocol connection captured in a trace.
+type TraceEntry struct {
+	Offset  uint64 // Nanoseconds since the start of the trace
+	Inbound bool   // Whether the message was received from the peer
+	Code    uint64 // Message code within the protocol
+	Payload []byte // Raw RLP payload of the message
+}
+
+// traceRecorder wraps the MsgReadWriter of a protocol, appending every message
+// sent or received to a trace. Failing to write the trace stops the recording
+// but not the protocol.
+type traceRecorder struct {
+	MsgReadWriter
+
+	start time.Time
+	w     io.Writer
+	err   error // Error that stopped the recording
+	lock  sync.Mutex
+}
+
+// NewTraceRecorder returns a MsgReadWriter recording the messages passing
+// through rw into w, in the format read by ReadTrace.
+func NewTraceRecorder(rw MsgReadWriter, w io.Writer) MsgReadWriter {
+	return &traceRecorder{MsgReadWriter: rw, start: time.Now(), w: w}
+}
+
+// ReadMsg reads a message from the underlying MsgReadWriter and records it.
+func (t *traceRecorder) ReadMsg() (Msg, error) {
+	msg, err := t.MsgReadWriter.ReadMsg()
+	if err != nil {
+		return msg, err
+	}
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return msg, err
+	}
+	msg.Payload = bytes.NewReader(payload)
+	t.record(true, msg.Code, payload)
+	return msg, nil
+}
+
+// WriteMsg records a message and writes it to the underlying MsgReadWriter.
+func (t *traceRecorder) WriteMsg(msg Msg) error {
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return err
+	}
+	msg.Payload = bytes.NewReader(payload)
+	t.record(false, msg.Code, payload)
+	return t.MsgReadWriter.WriteMsg(msg)
+}
+
+func (t *traceRecorder) record(inbound bool, code uint64, payload []byte) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.err != nil {
+		return
+	}
+	entry := &TraceEntry{
+		Offset:  uint64(time.Since(t.start)),
+		Inbound: inbound,
+		Code:    code,
+		Payload: payload,
+	}
+	if t.err = rlp.Encode(t.w, entry); t.err != nil {
+		log.Warn("Stopped recording message trace", "err", t.err)
+	}
+}
+
+// openTrace creates the trace file of a protocol run with a peer in dir.
+func openTrace(dir string, peer *Peer, proto Protocol) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("%s-%s%d-%d.trace", peer.ID().TerminalString(), proto.Name, proto.Version, time.Now().UnixNano())
+	return os.Create(filepath.Join(dir, name))
+}
+
+// ReadTrace reads the messages of a trace recorded by NewTraceRecorder.
+func ReadTrace(r io.Reader) ([]TraceEntry, error) {
+	var (
+		stream  = rlp.NewStream(r, 0)
+		entries []TraceEntry
+	)
+	for {
+		var entry TraceEntry
+		if err := stream.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return nil, fmt.Errorf("trace entry %d: %v", len(entries), err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// TraceReplayer is a MsgReadWriter replaying a trace to a protocol handler,
+// so sync bugs captured on a live node can be reproduced deterministically in
+// tests. It delivers the inbound messages of the trace in order and collects
+// the messages the handler writes, without any timing. Once the inbound
+// messages are exhausted, reads fail with io.EOF.
+type TraceReplayer struct {
+	entries []TraceEntry
+	next    int
+	written []TraceEntry
+	start   time.Time
+	lock    sync.Mutex
+}
+
+// NewTraceReplayer creates a replayer of the inbound messages of the trace.
+func NewTraceReplayer(entries []TraceEntry) *TraceReplayer {
+	return &TraceReplayer{entries: entries, start: time.Now()}
+}
+
+// ReadMsg returns the next inbound message of the trace.
+func (r *TraceReplayer) ReadMsg() (Msg, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for ; r.next < len(r.entries); r.next++ {
+		entry := r.entries[r.next]
+		if !entry.Inbound {
+			continue
+		}
+		r.next++
+		return Msg{
+			Code:       entry.Code,
+			Size:       uint32(len(entry.Payload)),
+			Payload:    bytes.NewReader(entry.Payload),
+			ReceivedAt: time.Now(),
+		}, nil
+	}
+	return Msg{}, io.EOF
+}
+
+// WriteMsg collects a message written by the handler.
+func (r *TraceReplayer) WriteMsg(msg Msg) error {
+	payload, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return err
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.written = append(r.written, TraceEntry{
+		Offset:  uint64(time.Since(r.start)),
+		Code:    msg.Code,
+		Payload: payload,
+	})
+	return nil
+}
+
+// Written returns the messages written by the handler so far.
+func (r *TraceReplayer) Written() []TraceEntry {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return append([]TraceEntry(nil), r.written...)
+}
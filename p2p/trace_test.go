@@ -0,0 +1,79 @@
+package p2p
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// echoHandler answers every message with the same payload, under the next
+// message code.
+func echoHandler(rw MsgReadWriter) error {
+	for {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return err
+		}
+		var payload string
+		if err := msg.Decode(&payload); err != nil {
+			return err
+		}
+		if err := Send(rw, msg.Code+1, payload); err != nil {
+			return err
+		}
+	}
+}
+
+func TestTraceRecordReplay(t *testing.T) {
+	// Record a live exchange with a handler
+	local, remote := MsgPipe()
+	defer local.Close()
+
+	var trace bytes.Buffer
+	done := make(chan error, 1)
+	go func() { done <- echoHandler(NewTraceRecorder(local, &trace)) }()
+
+	for i, payload := range []string{"ping", "pong"} {
+		if err := Send(remote, uint64(2*i), payload); err != nil {
+			t.Fatalf("failed to send message %d: %v", i, err)
+		}
+		if err := ExpectMsg(remote, uint64(2*i+1), payload); err != nil {
+			t.Fatalf("reply %d: %v", i, err)
+		}
+	}
+	remote.Close()
+	if err := <-done; err != ErrPipeClosed {
+		t.Fatalf("handler error mismatch: have %v, want %v", err, ErrPipeClosed)
+	}
+	// Read the trace back, it should have every message in order
+	entries, err := ReadTrace(&trace)
+	if err != nil {
+		t.Fatalf("failed to read trace: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("trace length mismatch: have %d, want 4", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.Code != uint64(i) || entry.Inbound != (i%2 == 0) {
+			t.Errorf("entry %d: mismatch: code %d, inbound %v", i, entry.Code, entry.Inbound)
+		}
+		if i > 0 && entry.Offset < entries[i-1].Offset {
+			t.Errorf("entry %d: offset %d before previous %d", i, entry.Offset, entries[i-1].Offset)
+		}
+	}
+	// Replaying the trace should reproduce the replies of the handler
+	replayer := NewTraceReplayer(entries)
+	if err := echoHandler(replayer); err != io.EOF {
+		t.Fatalf("replay error mismatch: have %v, want %v", err, io.EOF)
+	}
+	written := replayer.Written()
+	if len(written) != 2 {
+		t.Fatalf("replayed replies mismatch: have %d, want 2", len(written))
+	}
+	for i, entry := range written {
+		want := entries[2*i+1]
+		if entry.Code != want.Code || !bytes.Equal(entry.Payload, want.Payload) {
+			t.Errorf("reply %d: mismatch: have %d %x, want %d %x", i, entry.Code, entry.Payload, want.Code, want.Payload)
+		}
+	}
+}
@@ -701,7 +701,7 @@ var (
 		GenesisHashes:       nil,
 		FullerMapContext:    big.NewInt(0)}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), 0, []byte{0, 0}, big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, big.NewInt(0)}
+	TestChainConfig = &ChainConfig{big.NewInt(1), 0, []byte{0, 0}, big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, new(EthashConfig), nil, nil, big.NewInt(0), nil, 0, nil, nil, nil, nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -791,6 +791,31 @@ type ChainConfig struct {
 
 	// Quai Network Ontology
 	FullerMapContext *big.Int // Block number effective for Fuller Map Context ontology
+
+	// BlockHashWindowBlock switches BLOCKHASH to the block hash history kept in
+	// the state, serving the hashes of the last BlockHashWindow blocks along with
+	// the hashes of the coincident dominant blocks.
+	BlockHashWindowBlock *big.Int `json:"blockHashWindowBlock,omitempty"` // Block hash window switch block (nil = no fork, 0 = already activated)
+	BlockHashWindow      uint64   `json:"blockHashWindow,omitempty"`      // Number of blocks in the block hash history (0 = DefaultBlockHashWindow)
+
+	// EtxRollupBlock requires headers to commit to the external transactions
+	// their block applies from the external blocks of its coincident window.
+	EtxRollupBlock *big.Int `json:"etxRollupBlock,omitempty"` // Etx rollup switch block (nil = no fork, 0 = already activated)
+
+	// ExpiringTxBlock enables the expiring transaction type, only valid up to
+	// the block given in the transaction.
+	ExpiringTxBlock *big.Int `json:"expiringTxBlock,omitempty"` // Expiring transaction switch block (nil = no fork, 0 = already activated)
+
+	// SystemContracts are deployed or upgraded by the chain at their blocks.
+	SystemContracts []*SystemContract `json:"systemContracts,omitempty"`
+
+	// StateExpiry enables the experimental epoch based state expiry, meant for
+	// test zones only.
+	StateExpiry *StateExpiryConfig `json:"stateExpiry,omitempty"`
+
+	// GasSchedules override the EVM gas refund, state clearing and state access
+	// parameters at their blocks, in ascending block order.
+	GasSchedules []*GasSchedule `json:"gasSchedules,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -909,6 +934,30 @@ func (c *ChainConfig) IsFuller(num *big.Int) bool {
 	return isForked(c.FullerMapContext, num)
 }
 
+// IsBlockHashWindow returns whether num is either equal to the block hash window fork block or greater.
+func (c *ChainConfig) IsBlockHashWindow(num *big.Int) bool {
+	return isForked(c.BlockHashWindowBlock, num)
+}
+
+// IsEtxRollup returns whether num is either equal to the etx rollup fork block or greater.
+func (c *ChainConfig) IsEtxRollup(num *big.Int) bool {
+	return isForked(c.EtxRollupBlock, num)
+}
+
+// IsExpiringTx returns whether num is either equal to the expiring transaction fork block or greater.
+func (c *ChainConfig) IsExpiringTx(num *big.Int) bool {
+	return isForked(c.ExpiringTxBlock, num)
+}
+
+// BlockHashHistoryWindow returns the number of blocks kept in the block hash
+// history once the block hash window fork is active.
+func (c *ChainConfig) BlockHashHistoryWindow() uint64 {
+	if c.BlockHashWindow == 0 {
+		return DefaultBlockHashWindow
+	}
+	return c.BlockHashWindow
+}
+
 // CheckCompatible checks whether scheduled fork transitions have been imported
 // with a mismatching chain configuration.
 func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *ConfigCompatError {
@@ -927,6 +976,53 @@ func (c *ChainConfig) CheckCompatible(newcfg *ChainConfig, height uint64) *Confi
 	return lasterr
 }
 
+// Fork is a named block based fork of the chain config.
+type Fork struct {
+	Name  string   // name of the fork in the chain config
+	Block *big.Int // activation block in the config's context, nil if not scheduled
+}
+
+// Forks returns every fork known to this release in activation order, including
+// the ones not scheduled in the config, followed by the gas schedules and system
+// contracts of the config in config order.
+func (c *ChainConfig) Forks() []Fork {
+	forks := []Fork{
+		{Name: "homesteadBlock", Block: c.HomesteadBlock},
+		{Name: "eip150Block", Block: c.EIP150Block},
+		{Name: "eip155Block", Block: c.EIP155Block},
+		{Name: "eip158Block", Block: c.EIP158Block},
+		{Name: "byzantiumBlock", Block: c.ByzantiumBlock},
+		{Name: "constantinopleBlock", Block: c.ConstantinopleBlock},
+		{Name: "petersburgBlock", Block: c.PetersburgBlock},
+		{Name: "istanbulBlock", Block: c.IstanbulBlock},
+		{Name: "muirGlacierBlock", Block: c.MuirGlacierBlock},
+		{Name: "berlinBlock", Block: c.BerlinBlock},
+		{Name: "londonBlock", Block: c.LondonBlock},
+		{Name: "catalystBlock", Block: c.CatalystBlock},
+		{Name: "fullerMapContext", Block: c.FullerMapContext},
+		{Name: "blockHashWindowBlock", Block: c.BlockHashWindowBlock},
+		{Name: "etxRollupBlock", Block: c.EtxRollupBlock},
+		{Name: "expiringTxBlock", Block: c.ExpiringTxBlock},
+	}
+	stateExpiry := Fork{Name: "stateExpiry"}
+	if c.StateExpiry != nil {
+		stateExpiry.Block = c.StateExpiry.Block
+	}
+	forks = append(forks, stateExpiry)
+
+	for i, schedule := range c.GasSchedules {
+		forks = append(forks, Fork{Name: fmt.Sprintf("gasSchedules[%d]", i), Block: schedule.Block})
+	}
+	for i, contract := range c.SystemContracts {
+		name := contract.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		forks = append(forks, Fork{Name: fmt.Sprintf("systemContracts[%s]", name), Block: contract.Block})
+	}
+	return forks
+}
+
 // CheckConfigForkOrder checks that we don't "skip" any forks, geth isn't pluggable enough
 // to guarantee that forks can be implemented in a different order than on official networks
 func (c *ChainConfig) CheckConfigForkOrder() error {
@@ -949,6 +1045,9 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 		{name: "berlinBlock", block: c.BerlinBlock},
 		{name: "londonBlock", block: c.LondonBlock},
 		{name: "c.FullerMapContext", block: c.FullerMapContext},
+		{name: "blockHashWindowBlock", block: c.BlockHashWindowBlock, optional: true},
+		{name: "etxRollupBlock", block: c.EtxRollupBlock, optional: true},
+		{name: "expiringTxBlock", block: c.ExpiringTxBlock, optional: true},
 	} {
 		if lastFork.name != "" {
 			// Next one must be higher number
@@ -968,7 +1067,13 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
-	return nil
+	if err := c.checkSystemContracts(); err != nil {
+		return err
+	}
+	if err := c.checkStateExpiry(); err != nil {
+		return err
+	}
+	return c.checkGasSchedules()
 }
 
 func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
@@ -1015,6 +1120,27 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.FullerMapContext, newcfg.FullerMapContext, head) {
 		return newCompatError("Fuller ontology block", c.FullerMapContext, newcfg.FullerMapContext)
 	}
+	if isForkIncompatible(c.BlockHashWindowBlock, newcfg.BlockHashWindowBlock, head) {
+		return newCompatError("Block hash window fork block", c.BlockHashWindowBlock, newcfg.BlockHashWindowBlock)
+	}
+	if isForked(c.BlockHashWindowBlock, head) && c.BlockHashHistoryWindow() != newcfg.BlockHashHistoryWindow() {
+		return newCompatError("Block hash window", c.BlockHashWindowBlock, newcfg.BlockHashWindowBlock)
+	}
+	if isForkIncompatible(c.EtxRollupBlock, newcfg.EtxRollupBlock, head) {
+		return newCompatError("Etx rollup fork block", c.EtxRollupBlock, newcfg.EtxRollupBlock)
+	}
+	if isForkIncompatible(c.ExpiringTxBlock, newcfg.ExpiringTxBlock, head) {
+		return newCompatError("Expiring transaction fork block", c.ExpiringTxBlock, newcfg.ExpiringTxBlock)
+	}
+	if err := c.checkSystemContractsCompatible(newcfg, head); err != nil {
+		return err
+	}
+	if err := c.checkStateExpiryCompatible(newcfg, head); err != nil {
+		return err
+	}
+	if err := c.checkGasSchedulesCompatible(newcfg, head); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -1084,6 +1210,8 @@ type Rules struct {
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
 	IsBerlin, IsLondon, IsCatalyst                          bool
 	IsFuller, IsTuring, IsLovelace                          bool
+	IsBlockHashWindow, IsStateExpiry                        bool
+	Gas                                                     GasRules
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -1106,6 +1234,10 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 		IsLondon:         c.IsLondon(num),
 		IsCatalyst:       c.IsCatalyst(num),
 		IsFuller:         c.IsFuller(num),
+
+		IsBlockHashWindow: c.IsBlockHashWindow(num),
+		IsStateExpiry:     c.IsStateExpiry(num),
+		Gas:               c.GasRules(num),
 	}
 }
 
@@ -1229,6 +1361,52 @@ func CheckETxChainID(ourID *big.Int, txID *big.Int) bool {
 	return false
 }
 
+// LookupAddressChainID returns the chain of the network of the given chain ID
+// whose address space contains the address prefix, nil if none does.
+func LookupAddressChainID(chainID *big.Int, prefix byte) *big.Int {
+	for _, set := range []struct {
+		chains   []*big.Int
+		prefixes [][]int
+	}{{mainnetValidChains, mainnetBytePrefixList}, {testnetValidChains, testnetBytePrefixList}} {
+		var inSet bool
+		for _, id := range set.chains {
+			if chainID.Cmp(id) == 0 {
+				inSet = true
+			}
+		}
+		if !inSet {
+			continue
+		}
+		for _, id := range set.chains {
+			if idRange := set.prefixes[id.Int64()]; int(prefix) >= idRange[0] && int(prefix) <= idRange[1] {
+				return id
+			}
+		}
+	}
+	return nil
+}
+
+// LocationName returns the name of the location of the chain ID in its
+// network, e.g. "zone 1-2" for 9102.
+func LocationName(chainID *big.Int) string {
+	if !chainID.IsInt64() {
+		return "unknown location"
+	}
+	var (
+		location = chainID.Int64() % 1000
+		region   = location / 100
+		zone     = location % 100
+	)
+	switch {
+	case region == 0:
+		return "prime"
+	case zone == 0:
+		return fmt.Sprintf("region %d", region)
+	default:
+		return fmt.Sprintf("zone %d-%d", region, zone)
+	}
+}
+
 // CurrentOntology is used to retrieve the MapContext of a given block.
 func (c *ChainConfig) CurrentOntology(number []*big.Int) ([]int, error) {
 	forkNumber := number[0]
@@ -87,6 +87,17 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     30,
 			},
 		},
+		{
+			stored: &ChainConfig{ExpiringTxBlock: big.NewInt(10)},
+			new:    &ChainConfig{ExpiringTxBlock: big.NewInt(20)},
+			head:   15,
+			wantErr: &ConfigCompatError{
+				What:         "Expiring transaction fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(20),
+				RewindTo:     9,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -96,3 +107,94 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestForks(t *testing.T) {
+	config := &ChainConfig{HomesteadBlock: big.NewInt(0), LondonBlock: big.NewInt(10)}
+	forks := config.Forks()
+
+	seen := make(map[string]bool)
+	for _, fork := range forks {
+		if seen[fork.Name] {
+			t.Errorf("duplicate fork %s", fork.Name)
+		}
+		seen[fork.Name] = true
+
+		switch fork.Name {
+		case "homesteadBlock", "londonBlock":
+			if fork.Block == nil {
+				t.Errorf("fork %s not scheduled", fork.Name)
+			}
+		default:
+			if fork.Block != nil {
+				t.Errorf("fork %s unexpectedly scheduled at %v", fork.Name, fork.Block)
+			}
+		}
+	}
+	if forks[0].Name != "homesteadBlock" || forks[len(forks)-1].Name != "stateExpiry" {
+		t.Errorf("fork order mismatch: first %s, last %s", forks[0].Name, forks[len(forks)-1].Name)
+	}
+}
+
+func TestForksConfigured(t *testing.T) {
+	config := &ChainConfig{
+		StateExpiry:  &StateExpiryConfig{Block: big.NewInt(100), EpochLength: 10},
+		GasSchedules: []*GasSchedule{{Block: big.NewInt(20)}, {Block: big.NewInt(40)}},
+		SystemContracts: []*SystemContract{
+			{Name: "registry", Block: big.NewInt(30)},
+			{Block: big.NewInt(50)},
+		},
+	}
+	want := map[string]*big.Int{
+		"stateExpiry":               big.NewInt(100),
+		"gasSchedules[0]":           big.NewInt(20),
+		"gasSchedules[1]":           big.NewInt(40),
+		"systemContracts[registry]": big.NewInt(30),
+		"systemContracts[#1]":       big.NewInt(50),
+	}
+	for _, fork := range config.Forks() {
+		block, ok := want[fork.Name]
+		if !ok {
+			continue
+		}
+		if fork.Block == nil || fork.Block.Cmp(block) != 0 {
+			t.Errorf("fork %s block mismatch: have %v, want %v", fork.Name, fork.Block, block)
+		}
+		delete(want, fork.Name)
+	}
+	for name := range want {
+		t.Errorf("fork %s missing", name)
+	}
+}
+
+func TestLookupAddressChainID(t *testing.T) {
+	tests := []struct {
+		chainID int64
+		prefix  byte
+		want    int64 // 0 = none
+		name    string
+	}{
+		{9102, 5, 9000, "prime"},
+		{9102, 15, 9100, "region 1"},
+		{9102, 35, 9102, "zone 1-2"},
+		{9000, 129, 9303, "zone 3-3"},
+		{12000, 65, 12201, "zone 2-1"},
+		{9102, 200, 0, ""},
+		{1, 35, 0, ""},
+	}
+	for _, tt := range tests {
+		have := LookupAddressChainID(big.NewInt(tt.chainID), tt.prefix)
+		if tt.want == 0 {
+			if have != nil {
+				t.Errorf("chain %d prefix %d: have %v, want none", tt.chainID, tt.prefix, have)
+			}
+			continue
+		}
+		if have == nil || have.Int64() != tt.want {
+			t.Errorf("chain %d prefix %d: have %v, want %d", tt.chainID, tt.prefix, have, tt.want)
+			continue
+		}
+		if name := LocationName(have); name != tt.name {
+			t.Errorf("chain %d name mismatch: have %q, want %q", tt.want, name, tt.name)
+		}
+	}
+}
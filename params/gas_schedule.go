@@ -0,0 +1,152 @@
+package params
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// GasSchedule overrides the EVM gas refund, state clearing and state access
+// parameters from its block on, letting a context adopt or diverge from the
+// upstream EIPs defining them. Unset fields keep the values in effect before
+// the block, which default to the upstream ones of the active forks.
+type GasSchedule struct {
+	Block *big.Int `json:"block"` // Block the parameters take effect at
+
+	RefundQuotient      uint64 `json:"refundQuotient,omitempty"`      // Divisor of the gas used capping the refund (EIP-3529)
+	DeleteEmptyAccounts *bool  `json:"deleteEmptyAccounts,omitempty"` // Whether touched empty accounts are deleted (EIP-161)
+
+	ColdAccountAccessCost uint64 `json:"coldAccountAccessCost,omitempty"` // COLD_ACCOUNT_ACCESS_COST (EIP-2929)
+	ColdSloadCost         uint64 `json:"coldSloadCost,omitempty"`         // COLD_SLOAD_COST (EIP-2929)
+	WarmStorageReadCost   uint64 `json:"warmStorageReadCost,omitempty"`   // WARM_STORAGE_READ_COST (EIP-2929)
+}
+
+// GasRules are the EVM gas refund, state clearing and state access parameters
+// in effect at a block. The access costs only apply once EIP-2929 is active.
+type GasRules struct {
+	RefundQuotient        uint64
+	DeleteEmptyAccounts   bool
+	ColdAccountAccessCost uint64
+	ColdSloadCost         uint64
+	WarmStorageReadCost   uint64
+}
+
+// SstoreClearsScheduleRefund returns the refund for clearing a storage slot
+// once EIP-3529 is active, SSTORE_RESET_GAS - COLD_SLOAD_COST + ACCESS_LIST_STORAGE_KEY_COST.
+func (g GasRules) SstoreClearsScheduleRefund() uint64 {
+	return SstoreResetGasEIP2200 - g.ColdSloadCost + TxAccessListStorageKeyGas
+}
+
+// GasRules returns the EVM gas refund, state clearing and state access
+// parameters in effect at the given block.
+func (c *ChainConfig) GasRules(num *big.Int) GasRules {
+	rules := GasRules{
+		RefundQuotient:        RefundQuotient,
+		DeleteEmptyAccounts:   c.IsEIP158(num),
+		ColdAccountAccessCost: ColdAccountAccessCostEIP2929,
+		ColdSloadCost:         ColdSloadCostEIP2929,
+		WarmStorageReadCost:   WarmStorageReadCostEIP2929,
+	}
+	if c.IsLondon(num) {
+		rules.RefundQuotient = RefundQuotientEIP3529
+	}
+	for _, schedule := range c.GasSchedules {
+		if !isForked(schedule.Block, num) {
+			break
+		}
+		rules = schedule.apply(rules)
+	}
+	return rules
+}
+
+// DeleteEmptyAccounts returns whether the touched empty accounts are deleted at
+// the end of the transactions of the given block.
+func (c *ChainConfig) DeleteEmptyAccounts(num *big.Int) bool {
+	return c.GasRules(num).DeleteEmptyAccounts
+}
+
+// apply overrides the rules with the fields set in the schedule.
+func (s *GasSchedule) apply(rules GasRules) GasRules {
+	if s.RefundQuotient != 0 {
+		rules.RefundQuotient = s.RefundQuotient
+	}
+	if s.DeleteEmptyAccounts != nil {
+		rules.DeleteEmptyAccounts = *s.DeleteEmptyAccounts
+	}
+	if s.ColdAccountAccessCost != 0 {
+		rules.ColdAccountAccessCost = s.ColdAccountAccessCost
+	}
+	if s.ColdSloadCost != 0 {
+		rules.ColdSloadCost = s.ColdSloadCost
+	}
+	if s.WarmStorageReadCost != 0 {
+		rules.WarmStorageReadCost = s.WarmStorageReadCost
+	}
+	return rules
+}
+
+// checkGasSchedules checks that the gas schedules are ordered and that the
+// access costs they result in are consistent.
+func (c *ChainConfig) checkGasSchedules() error {
+	var (
+		last  *big.Int
+		rules = GasRules{
+			ColdAccountAccessCost: ColdAccountAccessCostEIP2929,
+			ColdSloadCost:         ColdSloadCostEIP2929,
+			WarmStorageReadCost:   WarmStorageReadCostEIP2929,
+		}
+	)
+	for _, schedule := range c.GasSchedules {
+		switch {
+		case schedule.Block == nil:
+			return fmt.Errorf("gas schedule: missing block")
+		case last != nil && schedule.Block.Cmp(last) <= 0:
+			return fmt.Errorf("unsupported gas schedule ordering: block %v after %v", schedule.Block, last)
+		}
+		last, rules = schedule.Block, schedule.apply(rules)
+
+		// The cold costs are charged on top of the warm ones already charged
+		if rules.WarmStorageReadCost > rules.ColdAccountAccessCost || rules.WarmStorageReadCost > rules.ColdSloadCost {
+			return fmt.Errorf("gas schedule at %v: warm storage read cost %d above the cold access costs", schedule.Block, rules.WarmStorageReadCost)
+		}
+		if rules.ColdSloadCost > SstoreResetGasEIP2200 {
+			return fmt.Errorf("gas schedule at %v: cold sload cost %d above the sstore reset cost", schedule.Block, rules.ColdSloadCost)
+		}
+	}
+	return nil
+}
+
+// checkGasSchedulesCompatible checks whether the gas schedules in effect up to
+// the head are the same in the new config.
+func (c *ChainConfig) checkGasSchedulesCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
+	have, want := c.GasSchedules, newcfg.GasSchedules
+	for i := 0; i < len(have) || i < len(want); i++ {
+		var haveBlock, wantBlock *big.Int
+		if i < len(have) {
+			haveBlock = have[i].Block
+		}
+		if i < len(want) {
+			wantBlock = want[i].Block
+		}
+		if !isForked(haveBlock, head) && !isForked(wantBlock, head) {
+			break
+		}
+		if haveBlock == nil || wantBlock == nil || !gasSchedulesEqual(have[i], want[i]) {
+			return newCompatError("Gas schedule", haveBlock, wantBlock)
+		}
+	}
+	return nil
+}
+
+// gasSchedulesEqual returns whether two gas schedules set the same parameters
+// at the same block.
+func gasSchedulesEqual(a, b *GasSchedule) bool {
+	if (a.DeleteEmptyAccounts == nil) != (b.DeleteEmptyAccounts == nil) {
+		return false
+	}
+	if a.DeleteEmptyAccounts != nil && *a.DeleteEmptyAccounts != *b.DeleteEmptyAccounts {
+		return false
+	}
+	return configNumEqual(a.Block, b.Block) && a.RefundQuotient == b.RefundQuotient &&
+		a.ColdAccountAccessCost == b.ColdAccountAccessCost && a.ColdSloadCost == b.ColdSloadCost &&
+		a.WarmStorageReadCost == b.WarmStorageReadCost
+}
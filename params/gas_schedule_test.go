@@ -0,0 +1,57 @@
+package params
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestGasSchedules(t *testing.T) {
+	keep := false
+	config := *TestChainConfig
+	config.GasSchedules = []*GasSchedule{
+		{Block: big.NewInt(10), RefundQuotient: 3, DeleteEmptyAccounts: &keep},
+		{Block: big.NewInt(20), ColdSloadCost: 1000, WarmStorageReadCost: 50},
+	}
+	if err := config.checkGasSchedules(); err != nil {
+		t.Fatalf("valid gas schedules rejected: %v", err)
+	}
+	if rules := config.GasRules(big.NewInt(9)); rules.RefundQuotient != RefundQuotientEIP3529 || !rules.DeleteEmptyAccounts || rules.ColdSloadCost != ColdSloadCostEIP2929 {
+		t.Errorf("default gas rules mismatch: %+v", rules)
+	}
+	if rules := config.GasRules(big.NewInt(15)); rules.RefundQuotient != 3 || rules.DeleteEmptyAccounts || rules.WarmStorageReadCost != WarmStorageReadCostEIP2929 {
+		t.Errorf("first schedule rules mismatch: %+v", rules)
+	}
+	rules := config.GasRules(big.NewInt(20))
+	if rules.RefundQuotient != 3 || rules.DeleteEmptyAccounts || rules.ColdSloadCost != 1000 || rules.WarmStorageReadCost != 50 || rules.ColdAccountAccessCost != ColdAccountAccessCostEIP2929 {
+		t.Errorf("second schedule rules mismatch: %+v", rules)
+	}
+	if refund := rules.SstoreClearsScheduleRefund(); refund != SstoreResetGasEIP2200-1000+TxAccessListStorageKeyGas {
+		t.Errorf("sstore clearing refund mismatch: have %d", refund)
+	}
+	if config.Rules(big.NewInt(20)).Gas != rules {
+		t.Errorf("chain rules do not carry the gas rules")
+	}
+	// Schedules can not change once active
+	changed := *TestChainConfig
+	changed.GasSchedules = []*GasSchedule{
+		{Block: big.NewInt(10), RefundQuotient: 3, DeleteEmptyAccounts: &keep},
+		{Block: big.NewInt(20), ColdSloadCost: 1200},
+	}
+	if err := config.CheckCompatible(&changed, 15); err != nil {
+		t.Errorf("changing future schedule rejected: %v", err)
+	}
+	if err := config.CheckCompatible(&changed, 25); err == nil || err.RewindTo != 19 {
+		t.Errorf("changing active schedule mismatch: %v", err)
+	}
+	for _, schedules := range [][]*GasSchedule{
+		{{RefundQuotient: 2}},
+		{{Block: big.NewInt(2)}, {Block: big.NewInt(1)}},
+		{{Block: big.NewInt(1), WarmStorageReadCost: 3000}},
+		{{Block: big.NewInt(1), ColdSloadCost: 6000, ColdAccountAccessCost: 6000}},
+	} {
+		invalid := ChainConfig{GasSchedules: schedules}
+		if err := invalid.checkGasSchedules(); err == nil {
+			t.Errorf("invalid gas schedules accepted: %+v", schedules)
+		}
+	}
+}
@@ -161,6 +161,13 @@ const (
 	RefundQuotient        uint64 = 2
 	RefundQuotientEIP3529 uint64 = 5
 
+	DefaultBlockHashWindow uint64 = 8192 // Number of blocks kept in the block hash history if the chain config sets no window
+	BlockHashHistoryGas    uint64 = 2100 // Gas of reading a hash from the block hash history, priced as a cold SLOAD
+
+	// The random beacon reads three slots of the block hash history per source
+	// context: its latest number, the number stored with it and its hash
+	RandomBeaconSourceGas uint64 = 3 * BlockHashHistoryGas
+
 	ExternalBlockLookupLimit int   = 75 // Amount of iterations to lookup external block
 	ExternalBlockLookupDelay int64 = 25 // Delay time (ms) for external block lookup during polling
 )
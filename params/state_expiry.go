@@ -0,0 +1,84 @@
+package params
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common"
+)
+
+// StateExpiryAddress is the system account whose storage serves as the registry
+// of the state expiry: the epoch every account was last accessed in, the state
+// roots the expired accounts are revived against and the accounts expired.
+var StateExpiryAddress = common.HexToAddress("0x00000000000000000000000000000000000000fc")
+
+// StateExpirySweepLimit is the maximum number of marked accounts a block
+// sweeps, the rest being carried forward to the next blocks.
+const StateExpirySweepLimit = 1024
+
+// StateExpiryConfig is the config of the experimental state expiry. The chain
+// is split in epochs of EpochLength blocks starting at Block. Accounts not
+// accessed during an epoch and the one following it are removed from the state
+// from the start of the next epoch on, StateExpirySweepLimit per block, and can
+// be revived with a proof against the state root recorded at the end of the
+// epoch following the last access.
+//
+// Accounts not accessed at all since Block are never expired, as there is no
+// record of them to sweep.
+type StateExpiryConfig struct {
+	Block       *big.Int `json:"block"`       // First block of the first epoch
+	EpochLength uint64   `json:"epochLength"` // Number of blocks in an epoch
+}
+
+// IsStateExpiry returns whether num is either equal to the state expiry block or greater.
+func (c *ChainConfig) IsStateExpiry(num *big.Int) bool {
+	return c.StateExpiry != nil && isForked(c.StateExpiry.Block, num)
+}
+
+// ExpiryEpoch returns the state expiry epoch of the given block. The block must
+// be past the state expiry block.
+func (c *ChainConfig) ExpiryEpoch(num uint64) uint64 {
+	return (num - c.StateExpiry.Block.Uint64()) / c.StateExpiry.EpochLength
+}
+
+// IsExpiryEpochStart returns whether num is the first block of a state expiry
+// epoch.
+func (c *ChainConfig) IsExpiryEpochStart(num *big.Int) bool {
+	if !c.IsStateExpiry(num) {
+		return false
+	}
+	return (num.Uint64()-c.StateExpiry.Block.Uint64())%c.StateExpiry.EpochLength == 0
+}
+
+// checkStateExpiry checks that the state expiry is usable.
+func (c *ChainConfig) checkStateExpiry() error {
+	if c.StateExpiry == nil {
+		return nil
+	}
+	if c.StateExpiry.Block == nil {
+		return errors.New("state expiry: missing block")
+	}
+	if c.StateExpiry.EpochLength == 0 {
+		return errors.New("state expiry: epoch length must be positive")
+	}
+	return nil
+}
+
+// checkStateExpiryCompatible checks whether the state expiry active at the
+// head is configured the same way by the new config.
+func (c *ChainConfig) checkStateExpiryCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
+	var have, want *big.Int
+	if c.StateExpiry != nil {
+		have = c.StateExpiry.Block
+	}
+	if newcfg.StateExpiry != nil {
+		want = newcfg.StateExpiry.Block
+	}
+	if isForkIncompatible(have, want, head) {
+		return newCompatError("State expiry block", have, want)
+	}
+	if c.IsStateExpiry(head) && c.StateExpiry.EpochLength != newcfg.StateExpiry.EpochLength {
+		return newCompatError("State expiry epoch length", have, want)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package params
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestStateExpiry(t *testing.T) {
+	config := ChainConfig{StateExpiry: &StateExpiryConfig{Block: big.NewInt(100), EpochLength: 10}}
+	if err := config.checkStateExpiry(); err != nil {
+		t.Fatalf("valid state expiry rejected: %v", err)
+	}
+	if config.IsStateExpiry(big.NewInt(99)) || !config.IsStateExpiry(big.NewInt(100)) {
+		t.Errorf("state expiry activation mismatch")
+	}
+	for number, want := range map[uint64]uint64{100: 0, 109: 0, 110: 1, 135: 3} {
+		if epoch := config.ExpiryEpoch(number); epoch != want {
+			t.Errorf("epoch of block %d mismatch: have %d, want %d", number, epoch, want)
+		}
+	}
+	if !config.IsExpiryEpochStart(big.NewInt(120)) || config.IsExpiryEpochStart(big.NewInt(121)) || config.IsExpiryEpochStart(big.NewInt(90)) {
+		t.Errorf("epoch start mismatch")
+	}
+	// The epochs can not change once the state expiry is active
+	changed := ChainConfig{StateExpiry: &StateExpiryConfig{Block: big.NewInt(100), EpochLength: 20}}
+	if err := config.CheckCompatible(&changed, 50); err != nil {
+		t.Errorf("changing future epochs rejected: %v", err)
+	}
+	if err := config.CheckCompatible(&changed, 150); err == nil || err.RewindTo != 99 {
+		t.Errorf("changing active epochs mismatch: %v", err)
+	}
+	if err := config.CheckCompatible(&ChainConfig{}, 150); err == nil {
+		t.Errorf("dropping active state expiry accepted")
+	}
+	for _, expiry := range []*StateExpiryConfig{{EpochLength: 10}, {Block: big.NewInt(1)}} {
+		invalid := ChainConfig{StateExpiry: expiry}
+		if err := invalid.checkStateExpiry(); err == nil {
+			t.Errorf("invalid state expiry accepted: %+v", expiry)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package params
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+)
+
+// SystemContract is a contract deployed or upgraded by the chain itself at the
+// start of a fork block, irrespective of the transactions of the block. The
+// code replaces any code at the address and the storage entries overwrite the
+// existing ones, leaving the other entries of the contract in place.
+type SystemContract struct {
+	Name    string                      `json:"name"`              // Name of the contract for the logs and the fork schedule
+	Block   *big.Int                    `json:"block"`             // Block at whose start the contract is deployed
+	Address common.Address              `json:"address"`           // Address of the contract
+	Code    hexutil.Bytes               `json:"code,omitempty"`    // Code of the contract, nil to keep the existing code
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"` // Storage entries to set
+}
+
+// SystemContractsAt returns the system contracts deployed at the start of the
+// given block, in the order of the config.
+func (c *ChainConfig) SystemContractsAt(num *big.Int) []*SystemContract {
+	var contracts []*SystemContract
+	for _, contract := range c.SystemContracts {
+		if contract.Block != nil && contract.Block.Cmp(num) == 0 {
+			contracts = append(contracts, contract)
+		}
+	}
+	return contracts
+}
+
+// checkSystemContracts checks that the system contracts are deployable.
+func (c *ChainConfig) checkSystemContracts() error {
+	for i, contract := range c.SystemContracts {
+		name := contract.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		switch {
+		case contract.Block == nil || contract.Block.Sign() <= 0:
+			// Contracts existing from the genesis belong in the genesis alloc
+			return fmt.Errorf("system contract %s: deployment block must be positive", name)
+		case contract.Address == (common.Address{}):
+			return fmt.Errorf("system contract %s: missing address", name)
+		case contract.Code == nil && len(contract.Storage) == 0:
+			return fmt.Errorf("system contract %s: neither code nor storage to deploy", name)
+		}
+	}
+	return nil
+}
+
+// checkSystemContractsCompatible checks whether the system contracts deployed
+// up to the head are deployed the same way by the new config.
+func (c *ChainConfig) checkSystemContractsCompatible(newcfg *ChainConfig, head *big.Int) *ConfigCompatError {
+	deployed := func(config *ChainConfig) map[string]*SystemContract {
+		contracts := make(map[string]*SystemContract)
+		for _, contract := range config.SystemContracts {
+			if isForked(contract.Block, head) {
+				contracts[fmt.Sprintf("%v-%x", contract.Block, contract.Address)] = contract
+			}
+		}
+		return contracts
+	}
+	have, want := deployed(c), deployed(newcfg)
+	for key, contract := range have {
+		if other, ok := want[key]; !ok || !systemContractsEqual(contract, other) {
+			return newCompatError(fmt.Sprintf("System contract %s", contract.Name), contract.Block, nil)
+		}
+	}
+	for key, contract := range want {
+		if _, ok := have[key]; !ok {
+			return newCompatError(fmt.Sprintf("System contract %s", contract.Name), nil, contract.Block)
+		}
+	}
+	return nil
+}
+
+// systemContractsEqual returns whether two system contracts deploy the same
+// code and storage.
+func systemContractsEqual(a, b *SystemContract) bool {
+	if string(a.Code) != string(b.Code) || (a.Code == nil) != (b.Code == nil) || len(a.Storage) != len(b.Storage) {
+		return false
+	}
+	for key, value := range a.Storage {
+		if other, ok := b.Storage[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
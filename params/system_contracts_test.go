@@ -0,0 +1,50 @@
+package params
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+)
+
+func TestSystemContracts(t *testing.T) {
+	var config ChainConfig
+	blob := `{"systemContracts": [
+		{"name": "etxFeeVault", "block": 10, "address": "0x1100000000000000000000000000000000000010", "code": "0x6000"},
+		{"name": "beaconBuffer", "block": 20, "address": "0x00000000000000000000000000000000000000fb", "storage": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"}}
+	]}`
+	if err := json.Unmarshal([]byte(blob), &config); err != nil {
+		t.Fatalf("failed to decode config: %v", err)
+	}
+	if err := config.checkSystemContracts(); err != nil {
+		t.Fatalf("valid system contracts rejected: %v", err)
+	}
+	if contracts := config.SystemContractsAt(big.NewInt(10)); len(contracts) != 1 || contracts[0].Name != "etxFeeVault" {
+		t.Errorf("contracts at block 10 mismatch: %v", contracts)
+	}
+	if contracts := config.SystemContractsAt(big.NewInt(11)); len(contracts) != 0 {
+		t.Errorf("contracts at block 11 mismatch: %v", contracts)
+	}
+	// Contracts deployed up to the head can not change, later ones can
+	changed := config
+	changed.SystemContracts = []*SystemContract{config.SystemContracts[0], {
+		Name: "beaconBuffer", Block: big.NewInt(25), Address: config.SystemContracts[1].Address, Code: []byte{0x00},
+	}}
+	if err := config.CheckCompatible(&changed, 15); err != nil {
+		t.Errorf("rescheduling a future contract rejected: %v", err)
+	}
+	if err := config.CheckCompatible(&changed, 22); err == nil || err.RewindTo != 19 {
+		t.Errorf("rescheduling a deployed contract mismatch: %v", err)
+	}
+	for _, contract := range []*SystemContract{
+		{Address: common.Address{1}, Code: []byte{0x00}},
+		{Block: big.NewInt(1), Code: []byte{0x00}},
+		{Block: big.NewInt(1), Address: common.Address{1}},
+	} {
+		invalid := ChainConfig{SystemContracts: []*SystemContract{contract}}
+		if err := invalid.checkSystemContracts(); err == nil {
+			t.Errorf("invalid system contract accepted: %+v", contract)
+		}
+	}
+}
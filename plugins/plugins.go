@@ -0,0 +1,314 @@
+// Package plugins loads Go plugins extending the node with JSON-RPC namespaces,
+// native tracers and transaction pool filters, so operators can customize the
+// client without forking it.
+//
+// A plugin is built with -buildmode=plugin against the same release of the
+// client and exports a Plugin variable implementing Plugin. Panics of the
+// plugin are contained: they fail the call that caused them instead of the
+// node.
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"plugin"
+	"runtime/debug"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/core/vm"
+	"github.com/spruce-solutions/go-quai/eth/tracers"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+// APIVersion is the version of the plugin API, bumped on every incompatible
+// change of Plugin or Host. Plugins built for another version are refused.
+const APIVersion = 1
+
+// pluginSymbol is the symbol a Go plugin exports its plugin under.
+const pluginSymbol = "Plugin"
+
+var pluginPanicMeter = metrics.NewRegisteredMeter("plugins/panics", nil)
+
+// Plugin is implemented by the value a Go plugin exports as Plugin.
+type Plugin interface {
+	// Name returns the unique name of the plugin.
+	Name() string
+
+	// APIVersion returns the version of the plugin API the plugin was built
+	// for, normally the APIVersion constant it was compiled with.
+	APIVersion() int
+
+	// Init registers the extensions of the plugin with the node, before the
+	// node starts.
+	Init(host Host) error
+}
+
+// Host is the node as seen by a plugin.
+type Host interface {
+	// ChainConfig returns the configuration of the chain of the node.
+	ChainConfig() *params.ChainConfig
+
+	// Chain returns the blockchain of the node.
+	Chain() *core.BlockChain
+
+	// TxPool returns the transaction pool of the node.
+	TxPool() *core.TxPool
+
+	// Log returns the logger of the plugin.
+	Log() log.Logger
+
+	// RegisterAPIs registers JSON-RPC namespaces served by the node.
+	RegisterAPIs(apis []rpc.API)
+
+	// RegisterTracer makes a native tracer selectable by name in the tracing
+	// API, taking precedence over the JavaScript tracers.
+	RegisterTracer(name string, ctor tracers.NativeTracerCtor)
+
+	// RegisterTxFilter adds an admission rule to the transaction pool.
+	RegisterTxFilter(filter core.TxFilter)
+
+	// RegisterLifecycle registers a service started and stopped with the node.
+	RegisterLifecycle(lifecycle node.Lifecycle)
+}
+
+// Backend is the node access needed by the plugins.
+type Backend interface {
+	BlockChain() *core.BlockChain
+	TxPool() *core.TxPool
+}
+
+// Info describes a loaded plugin.
+type Info struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	APIVersion int    `json:"apiVersion"`
+}
+
+// Load opens the Go plugins at the paths and initializes them with the node.
+// The loaded plugins are listed by plugins_list.
+func Load(stack *node.Node, backend Backend, paths []string) error {
+	api := new(API)
+	for _, path := range paths {
+		p, err := open(path)
+		if err != nil {
+			return err
+		}
+		info, err := initPlugin(stack, backend, p, path, api.plugins)
+		if err != nil {
+			return fmt.Errorf("plugin %s: %v", path, err)
+		}
+		api.plugins = append(api.plugins, info)
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "plugins",
+		Version:   "1.0",
+		Service:   api,
+	}})
+	return nil
+}
+
+// open opens the Go plugin at path, looking up its plugin.
+func open(path string) (Plugin, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin: %v", err)
+	}
+	symbol, err := p.Lookup(pluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: %v", path, err)
+	}
+	// Exported variables are looked up as pointers to them
+	switch plugin := symbol.(type) {
+	case *Plugin:
+		if *plugin != nil {
+			return *plugin, nil
+		}
+	case Plugin:
+		return plugin, nil
+	}
+	return nil, fmt.Errorf("plugin %s: %s is not a plugins.Plugin", path, pluginSymbol)
+}
+
+// initPlugin checks the plugin is compatible and unique, then initializes it.
+func initPlugin(stack *node.Node, backend Backend, p Plugin, path string, loaded []*Info) (*Info, error) {
+	info := &Info{Path: path}
+	err := guard(path, func() error {
+		info.Name, info.APIVersion = p.Name(), p.APIVersion()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if info.APIVersion != APIVersion {
+		return nil, fmt.Errorf("built for plugin API version %d, node provides %d", info.APIVersion, APIVersion)
+	}
+	if info.Name == "" {
+		return nil, errors.New("no plugin name")
+	}
+	for _, other := range loaded {
+		if other.Name == info.Name {
+			return nil, fmt.Errorf("plugin %q already loaded from %s", info.Name, other.Path)
+		}
+	}
+	h := &host{
+		name:    info.Name,
+		stack:   stack,
+		backend: backend,
+		log:     log.New("plugin", info.Name),
+	}
+	if err := guard(info.Name, func() error { return p.Init(h) }); err != nil {
+		return nil, err
+	}
+	log.Info("Loaded plugin", "name", info.Name, "path", path)
+	return info, nil
+}
+
+// guard runs the plugin code, turning its panics into errors.
+func guard(plugin string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pluginPanicMeter.Mark(1)
+			log.Error("Plugin panicked", "plugin", plugin, "err", r, "stack", string(debug.Stack()))
+			err = fmt.Errorf("plugin %s panicked: %v", plugin, r)
+		}
+	}()
+	return fn()
+}
+
+// host implements Host for a plugin, guarding the extensions it registers.
+type host struct {
+	name    string
+	stack   *node.Node
+	backend Backend
+	log     log.Logger
+}
+
+func (h *host) ChainConfig() *params.ChainConfig { return h.backend.BlockChain().Config() }
+func (h *host) Chain() *core.BlockChain          { return h.backend.BlockChain() }
+func (h *host) TxPool() *core.TxPool             { return h.backend.TxPool() }
+func (h *host) Log() log.Logger                  { return h.log }
+
+// RegisterAPIs implements Host. The RPC server already recovers from panics of
+// the methods.
+func (h *host) RegisterAPIs(apis []rpc.API) {
+	h.stack.RegisterAPIs(apis)
+}
+
+func (h *host) RegisterTracer(name string, ctor tracers.NativeTracerCtor) {
+	tracers.RegisterNative(name, func(ctx *tracers.Context) (tracers.NativeTracer, error) {
+		var tracer tracers.NativeTracer
+		err := guard(h.name, func() (err error) {
+			tracer, err = ctor(ctx)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &guardedTracer{plugin: h.name, tracer: tracer}, nil
+	})
+}
+
+// RegisterTxFilter implements Host. Transactions are rejected when the filter
+// panics.
+func (h *host) RegisterTxFilter(filter core.TxFilter) {
+	h.backend.TxPool().AddFilter(func(tx *types.Transaction, local bool) error {
+		return guard(h.name, func() error { return filter(tx, local) })
+	})
+}
+
+func (h *host) RegisterLifecycle(lifecycle node.Lifecycle) {
+	h.stack.RegisterLifecycle(&guardedLifecycle{plugin: h.name, lifecycle: lifecycle})
+}
+
+// guardedLifecycle is a service of a plugin, failing to start or stop when it
+// panics.
+type guardedLifecycle struct {
+	plugin    string
+	lifecycle node.Lifecycle
+}
+
+func (l *guardedLifecycle) Start() error { return guard(l.plugin, l.lifecycle.Start) }
+func (l *guardedLifecycle) Stop() error  { return guard(l.plugin, l.lifecycle.Stop) }
+
+// guardedTracer is a native tracer of a plugin. Once it panics it is no longer
+// called and the trace fails.
+type guardedTracer struct {
+	plugin string
+	tracer tracers.NativeTracer
+	err    error // panic of the tracer, only accessed by the tracing goroutine
+}
+
+// capture calls the tracer unless it panicked before.
+func (t *guardedTracer) capture(fn func()) {
+	if t.err != nil {
+		return
+	}
+	t.err = guard(t.plugin, func() error {
+		fn()
+		return nil
+	})
+}
+
+func (t *guardedTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.capture(func() { t.tracer.CaptureStart(env, from, to, create, input, gas, value) })
+}
+
+func (t *guardedTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	t.capture(func() { t.tracer.CaptureState(env, pc, op, gas, cost, scope, rData, depth, err) })
+}
+
+func (t *guardedTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.capture(func() { t.tracer.CaptureEnter(typ, from, to, input, gas, value) })
+}
+
+func (t *guardedTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.capture(func() { t.tracer.CaptureExit(output, gasUsed, err) })
+}
+
+func (t *guardedTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	t.capture(func() { t.tracer.CaptureFault(env, pc, op, gas, cost, scope, depth, err) })
+}
+
+func (t *guardedTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {
+	t.capture(func() { t.tracer.CaptureEnd(output, gasUsed, d, err) })
+}
+
+func (t *guardedTracer) GetResult() (json.RawMessage, error) {
+	if t.err != nil {
+		return nil, t.err
+	}
+	var res json.RawMessage
+	err := guard(t.plugin, func() (err error) {
+		res, err = t.tracer.GetResult()
+		return err
+	})
+	return res, err
+}
+
+// Stop implements tracers.NativeTracer. It is called concurrently with the
+// tracing, a panic is only logged.
+func (t *guardedTracer) Stop(err error) {
+	guard(t.plugin, func() error {
+		t.tracer.Stop(err)
+		return nil
+	})
+}
+
+// API lists the loaded plugins.
+type API struct {
+	plugins []*Info
+}
+
+// List returns the loaded plugins.
+func (api *API) List() []*Info {
+	return api.plugins
+}
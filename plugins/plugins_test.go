@@ -0,0 +1,109 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core/vm"
+)
+
+type testPlugin struct {
+	name    string
+	version int
+	init    func(host Host) error
+}
+
+func (p *testPlugin) Name() string         { return p.name }
+func (p *testPlugin) APIVersion() int      { return p.version }
+func (p *testPlugin) Init(host Host) error { return p.init(host) }
+func noInit(host Host) error               { return nil }
+func panicInit(host Host) error            { panic("boom") }
+func failInit(host Host) error             { return errors.New("not configured") }
+func newTestPlugin(name string) *testPlugin {
+	return &testPlugin{name: name, version: APIVersion, init: noInit}
+}
+
+func TestInitPlugin(t *testing.T) {
+	loaded := []*Info{{Name: "loaded", Path: "loaded.so", APIVersion: APIVersion}}
+
+	tests := []struct {
+		plugin *testPlugin
+		err    string
+	}{
+		{newTestPlugin("fresh"), ""},
+		{&testPlugin{name: "old", version: APIVersion - 1, init: noInit}, "plugin API version"},
+		{newTestPlugin(""), "no plugin name"},
+		{newTestPlugin("loaded"), "already loaded"},
+		{&testPlugin{name: "failing", version: APIVersion, init: failInit}, "not configured"},
+		{&testPlugin{name: "panicking", version: APIVersion, init: panicInit}, "panicked: boom"},
+	}
+	for _, tt := range tests {
+		info, err := initPlugin(nil, nil, tt.plugin, tt.plugin.name+".so", loaded)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("plugin %q: unexpected error: %v", tt.plugin.name, err)
+			} else if info.Name != tt.plugin.name || info.APIVersion != APIVersion {
+				t.Errorf("plugin %q: info mismatch: %+v", tt.plugin.name, info)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("plugin %q: error mismatch: have %v, want %q", tt.plugin.name, err, tt.err)
+		}
+	}
+}
+
+// panicTracer panics on the given number of opcode steps.
+type panicTracer struct {
+	steps   int
+	panicAt int
+}
+
+func (t *panicTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+func (t *panicTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.steps++; t.steps == t.panicAt {
+		panic("bad step")
+	}
+}
+func (t *panicTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+func (t *panicTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+func (t *panicTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+func (t *panicTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {}
+func (t *panicTracer) GetResult() (json.RawMessage, error) {
+	return json.Marshal(t.steps)
+}
+func (t *panicTracer) Stop(err error) { panic("bad stop") }
+
+func TestGuardedTracer(t *testing.T) {
+	// A well behaved trace returns the result of the tracer
+	inner := &panicTracer{}
+	tracer := &guardedTracer{plugin: "test", tracer: inner}
+	for i := 0; i < 3; i++ {
+		tracer.CaptureState(nil, 0, vm.STOP, 0, 0, nil, nil, 0, nil)
+	}
+	if res, err := tracer.GetResult(); err != nil || string(res) != "3" {
+		t.Fatalf("result mismatch: have %s, %v, want 3", res, err)
+	}
+	// A panicking tracer is no longer called and fails the trace
+	inner = &panicTracer{panicAt: 2}
+	tracer = &guardedTracer{plugin: "test", tracer: inner}
+	for i := 0; i < 3; i++ {
+		tracer.CaptureState(nil, 0, vm.STOP, 0, 0, nil, nil, 0, nil)
+	}
+	if inner.steps != 2 {
+		t.Errorf("steps mismatch: have %d, want 2", inner.steps)
+	}
+	if _, err := tracer.GetResult(); err == nil || !strings.Contains(err.Error(), "bad step") {
+		t.Errorf("error mismatch: have %v, want panic", err)
+	}
+	// Stopping doesn't propagate panics
+	tracer.Stop(errors.New("timeout"))
+}
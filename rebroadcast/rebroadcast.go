@@ -0,0 +1,288 @@
+// Package rebroadcast implements an opt-in service periodically announcing the
+// node's own pending transactions to its peers again, optionally replacing the
+// ones pending for too long with gas price bumped copies.
+package rebroadcast
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/accounts"
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+const (
+	// DefaultInterval is the default time between rebroadcast rounds.
+	DefaultInterval = time.Minute
+
+	// DefaultBumpPercent is the default gas price increase of a replacement,
+	// matching the minimum price bump of the transaction pool.
+	DefaultBumpPercent = 10
+)
+
+// Event types reported to the subscribers.
+const (
+	EventRebroadcast = "rebroadcast" // transaction announced to the peers again
+	EventBumped      = "bumped"      // transaction replaced by a gas price bumped copy
+	EventBumpFailed  = "bumpFailed"  // transaction could not be replaced
+)
+
+var errBumpCapped = errors.New("bumped gas price exceeds the configured maximum")
+
+// Config contains the settings of the rebroadcast service.
+type Config struct {
+	Interval    time.Duration // time between rebroadcast rounds
+	BumpAfter   time.Duration // time pending before a transaction is bumped, 0 disables bumping
+	BumpPercent uint64        // gas price increase of a replacement
+	MaxGasPrice *big.Int      // highest gas price (or fee cap) a replacement may pay, nil for no limit
+}
+
+// backend is the node access needed by the rebroadcast service.
+type backend interface {
+	TxPool() *core.TxPool
+	BlockChain() *core.BlockChain
+	AccountManager() *accounts.Manager
+	RebroadcastTransactions(txs types.Transactions)
+}
+
+// Event reports an action taken on a local pending transaction.
+type Event struct {
+	Type     string         `json:"type"`
+	Hash     common.Hash    `json:"hash"`
+	Replaces *common.Hash   `json:"replaces,omitempty"` // hash of the bumped transaction
+	From     common.Address `json:"from"`
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	GasPrice *hexutil.Big   `json:"gasPrice"` // gas price, or fee cap of dynamic fee transactions
+	Pending  float64        `json:"pending"`  // seconds the transaction has been pending
+	Error    string         `json:"error,omitempty"`
+}
+
+// Service rebroadcasts and bumps the local pending transactions.
+type Service struct {
+	config  Config
+	backend backend
+	signer  types.Signer
+
+	seen map[common.Hash]time.Time // first time each local pending transaction was seen, only accessed by the loop
+	feed event.Feed
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates the rebroadcast service and registers it with the node.
+func New(stack *node.Node, backend backend, config Config) *Service {
+	if config.Interval <= 0 {
+		config.Interval = DefaultInterval
+	}
+	if config.BumpPercent < core.DefaultTxPoolConfig.PriceBump {
+		log.Warn("Sanitizing rebroadcast price bump", "provided", config.BumpPercent, "updated", core.DefaultTxPoolConfig.PriceBump)
+		config.BumpPercent = core.DefaultTxPoolConfig.PriceBump
+	}
+	s := &Service{
+		config:  config,
+		backend: backend,
+		signer:  types.LatestSigner(backend.BlockChain().Config()),
+		seen:    make(map[common.Hash]time.Time),
+		quit:    make(chan struct{}),
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "txpool",
+		Version:   "1.0",
+		Service:   &PublicRebroadcastAPI{s},
+		Public:    true,
+	}})
+	stack.RegisterLifecycle(s)
+	return s
+}
+
+// Start implements node.Lifecycle, starting the rebroadcast loop.
+func (s *Service) Start() error {
+	s.wg.Add(1)
+	go s.loop()
+
+	log.Info("Transaction rebroadcaster started", "interval", s.config.Interval, "bumpafter", s.config.BumpAfter, "bump", s.config.BumpPercent)
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the rebroadcast loop.
+func (s *Service) Stop() error {
+	close(s.quit)
+	s.wg.Wait()
+	log.Info("Transaction rebroadcaster stopped")
+	return nil
+}
+
+func (s *Service) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.round(time.Now())
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// round rebroadcasts the local pending transactions seen in an earlier round,
+// replacing the ones pending longer than the bump timeout.
+func (s *Service) round(now time.Time) {
+	var (
+		pool    = s.backend.TxPool()
+		current = make(map[common.Hash]time.Time)
+		resend  types.Transactions
+		events  []*Event
+	)
+	for _, addr := range pool.Locals() {
+		pending, _ := pool.ContentFrom(addr)
+		for _, tx := range pending {
+			first, ok := s.seen[tx.Hash()]
+			if !ok {
+				// Freshly added transactions were just broadcast by the pool
+				current[tx.Hash()] = now
+				continue
+			}
+			age := now.Sub(first)
+			if s.config.BumpAfter > 0 && age >= s.config.BumpAfter {
+				bumped, err := s.bump(addr, tx)
+				if err == nil {
+					current[bumped.Hash()] = now
+					replaced := tx.Hash()
+					events = append(events, s.event(EventBumped, addr, bumped, age, &replaced, nil))
+					continue
+				}
+				log.Debug("Failed to bump stuck transaction", "hash", tx.Hash(), "err", err)
+				events = append(events, s.event(EventBumpFailed, addr, tx, age, nil, err))
+			}
+			current[tx.Hash()] = first
+			resend = append(resend, tx)
+			events = append(events, s.event(EventRebroadcast, addr, tx, age, nil, nil))
+		}
+	}
+	s.seen = current
+
+	if len(resend) > 0 {
+		s.backend.RebroadcastTransactions(resend)
+		log.Debug("Rebroadcast local transactions", "count", len(resend))
+	}
+	for _, ev := range events {
+		s.feed.Send(ev)
+	}
+}
+
+// bump replaces a transaction with a copy paying a higher gas price, signed by
+// the wallet holding the sender account.
+func (s *Service) bump(from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	var (
+		percent = new(big.Int).SetUint64(100 + s.config.BumpPercent)
+		raise   = func(price *big.Int) *big.Int {
+			bumped := new(big.Int).Mul(price, percent)
+			bumped.Div(bumped, big.NewInt(100))
+			return bumped.Add(bumped, common.Big1)
+		}
+		data types.TxData
+	)
+	switch tx.Type() {
+	case types.LegacyTxType:
+		data = &types.LegacyTx{Nonce: tx.Nonce(), GasPrice: raise(tx.GasPrice()), Gas: tx.Gas(), To: tx.To(), Value: tx.Value(), Data: tx.Data()}
+	case types.AccessListTxType:
+		data = &types.AccessListTx{ChainID: tx.ChainId(), Nonce: tx.Nonce(), GasPrice: raise(tx.GasPrice()), Gas: tx.Gas(), To: tx.To(), Value: tx.Value(), Data: tx.Data(), AccessList: tx.AccessList()}
+	case types.DynamicFeeTxType:
+		data = &types.DynamicFeeTx{ChainID: tx.ChainId(), Nonce: tx.Nonce(), GasTipCap: raise(tx.GasTipCap()), GasFeeCap: raise(tx.GasFeeCap()), Gas: tx.Gas(), To: tx.To(), Value: tx.Value(), Data: tx.Data(), AccessList: tx.AccessList()}
+	case types.ExpiringTxType:
+		data = &types.ExpiringTx{ChainID: tx.ChainId(), Nonce: tx.Nonce(), GasTipCap: raise(tx.GasTipCap()), GasFeeCap: raise(tx.GasFeeCap()), Gas: tx.Gas(), To: tx.To(), Value: tx.Value(), Data: tx.Data(), AccessList: tx.AccessList(), ValidUntil: tx.ValidUntil()}
+	case types.ReviveTxType:
+		revived, proof := tx.Revival()
+		data = &types.ReviveTx{ChainID: tx.ChainId(), Nonce: tx.Nonce(), GasTipCap: raise(tx.GasTipCap()), GasFeeCap: raise(tx.GasFeeCap()), Gas: tx.Gas(), Account: *revived, Proof: proof}
+	default:
+		return nil, types.ErrTxTypeNotSupported
+	}
+	unsigned := types.NewTx(data)
+	if s.config.MaxGasPrice != nil && unsigned.GasFeeCap().Cmp(s.config.MaxGasPrice) > 0 {
+		return nil, errBumpCapped
+	}
+	account := accounts.Account{Address: from}
+	wallet, err := s.backend.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := wallet.SignTx(account, unsigned, s.signer.ChainID())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.backend.TxPool().AddLocal(signed); err != nil {
+		return nil, err
+	}
+	log.Info("Replaced stuck transaction", "hash", tx.Hash(), "replacement", signed.Hash(), "nonce", tx.Nonce(), "feecap", signed.GasFeeCap())
+	return signed, nil
+}
+
+// event assembles the report of an action taken on a transaction.
+func (s *Service) event(typ string, from common.Address, tx *types.Transaction, age time.Duration, replaces *common.Hash, err error) *Event {
+	ev := &Event{
+		Type:     typ,
+		Hash:     tx.Hash(),
+		Replaces: replaces,
+		From:     from,
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		GasPrice: (*hexutil.Big)(tx.GasFeeCap()),
+		Pending:  age.Seconds(),
+	}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	return ev
+}
+
+// SubscribeEvents subscribes to the actions taken on local pending transactions.
+func (s *Service) SubscribeEvents(ch chan<- *Event) event.Subscription {
+	return s.feed.Subscribe(ch)
+}
+
+// PublicRebroadcastAPI offers the rebroadcast events over RPC subscriptions.
+type PublicRebroadcastAPI struct {
+	s *Service
+}
+
+// Rebroadcasts creates a subscription notified whenever a local pending
+// transaction is rebroadcast, bumped or fails to be bumped.
+func (api *PublicRebroadcastAPI) Rebroadcasts(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan *Event, 64)
+		feedSub := api.s.SubscribeEvents(events)
+		defer feedSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(sub.ID, ev)
+			case <-sub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
@@ -0,0 +1,155 @@
+package release
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jedisct1/go-minisign"
+)
+
+// Fork announces an upcoming hard fork of one of the chains in the hierarchy.
+type Fork struct {
+	Name    string `json:"name"`
+	Context int    `json:"context"` // params.PRIME, params.REGION or params.ZONE
+	Block   uint64 `json:"block"`   // block number in the given context
+	Version string `json:"version"` // first release supporting the fork
+}
+
+// Manifest is the signed release document published by the maintainers.
+type Manifest struct {
+	Latest  string `json:"latest"`  // most recent release
+	Minimum string `json:"minimum"` // oldest release still considered safe to run
+	Notes   string `json:"notes"`   // link to the release notes
+	Forks   []Fork `json:"forks"`
+}
+
+// parseManifest verifies the minisign signature of a manifest against the
+// trusted public keys and decodes it.
+func parseManifest(pubkeys []string, data, sigdata []byte) (*Manifest, error) {
+	sig, err := minisign.DecodeSignature(string(sigdata))
+	if err != nil {
+		return nil, err
+	}
+	var key *minisign.PublicKey
+	for _, pubkey := range pubkeys {
+		pub, err := minisign.NewPublicKey(pubkey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid release public key %q: %v", pubkey, err)
+		}
+		if pub.KeyId == sig.KeyId {
+			key = &pub
+			break
+		}
+	}
+	if key == nil {
+		return nil, errors.New("manifest signed by untrusted key")
+	}
+	if ok, err := key.Verify(data, sig); !ok || err != nil {
+		return nil, errors.New("manifest signature could not be verified")
+	}
+	manifest := new(Manifest)
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// version is a parsed major.minor.patch release number.
+type version [3]int
+
+// parseVersion parses release numbers in the "v1.2.3-meta" form, ignoring the
+// optional "v" prefix and any metadata suffix.
+func parseVersion(s string) (version, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return version{}, fmt.Errorf("invalid version %q", s)
+	}
+	var v version
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return version{}, fmt.Errorf("invalid version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// cmp returns -1, 0 or 1 if v is older, equal or newer than o.
+func (v version) cmp(o version) int {
+	for i := range v {
+		switch {
+		case v[i] < o[i]:
+			return -1
+		case v[i] > o[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// Status is the outcome of evaluating a manifest against the running node.
+type Status struct {
+	Current string `json:"current"`
+	Latest  string `json:"latest"`
+	Notes   string `json:"notes,omitempty"`
+
+	Outdated bool `json:"outdated"` // a newer release is available
+	Insecure bool `json:"insecure"` // the running release is below the minimum
+
+	// UnsupportedFork is the closest fork in the local context that the running
+	// release does not support, Distance the number of blocks until it activates.
+	UnsupportedFork *Fork  `json:"unsupportedFork,omitempty"`
+	Distance        uint64 `json:"distance,omitempty"`
+}
+
+// evaluate compares the manifest with the running release and the current
+// head number of the local context.
+func evaluate(manifest *Manifest, current string, context int, head uint64) (*Status, error) {
+	cur, err := parseVersion(current)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Current: current, Latest: manifest.Latest, Notes: manifest.Notes}
+	if manifest.Latest != "" {
+		latest, err := parseVersion(manifest.Latest)
+		if err != nil {
+			return nil, err
+		}
+		status.Outdated = cur.cmp(latest) < 0
+	}
+	if manifest.Minimum != "" {
+		minimum, err := parseVersion(manifest.Minimum)
+		if err != nil {
+			return nil, err
+		}
+		status.Insecure = cur.cmp(minimum) < 0
+	}
+	for i := range manifest.Forks {
+		fork := manifest.Forks[i]
+		if fork.Context != context {
+			continue
+		}
+		required, err := parseVersion(fork.Version)
+		if err != nil {
+			return nil, err
+		}
+		if cur.cmp(required) >= 0 {
+			continue
+		}
+		if status.UnsupportedFork == nil || fork.Block < status.UnsupportedFork.Block {
+			status.UnsupportedFork = &fork
+		}
+	}
+	if fork := status.UnsupportedFork; fork != nil && fork.Block > head {
+		status.Distance = fork.Block - head
+	}
+	return status, nil
+}
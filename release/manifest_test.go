@@ -0,0 +1,76 @@
+package release
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input string
+		want  version
+		fail  bool
+	}{
+		{input: "1.10.10", want: version{1, 10, 10}},
+		{input: "v0.2.3-stable", want: version{0, 2, 3}},
+		{input: "2.0.0+abcdef", want: version{2, 0, 0}},
+		{input: "1.2", fail: true},
+		{input: "1.x.3", fail: true},
+	}
+	for _, tt := range tests {
+		have, err := parseVersion(tt.input)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.input, err)
+		} else if have != tt.want {
+			t.Errorf("%q: version mismatch: have %v, want %v", tt.input, have, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	manifest := &Manifest{
+		Latest:  "1.2.0",
+		Minimum: "1.1.0",
+		Forks: []Fork{
+			{Name: "supported", Context: 2, Block: 50, Version: "1.0.0"},
+			{Name: "later", Context: 2, Block: 300, Version: "1.2.0"},
+			{Name: "next", Context: 2, Block: 200, Version: "1.2.0"},
+			{Name: "prime", Context: 0, Block: 100, Version: "1.2.0"},
+		},
+	}
+	status, err := evaluate(manifest, "1.1.5", 2, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Outdated || status.Insecure {
+		t.Errorf("release flags mismatch: outdated %v, insecure %v", status.Outdated, status.Insecure)
+	}
+	if status.UnsupportedFork == nil || status.UnsupportedFork.Name != "next" {
+		t.Fatalf("unsupported fork mismatch: have %+v, want next", status.UnsupportedFork)
+	}
+	if status.Distance != 50 {
+		t.Errorf("fork distance mismatch: have %d, want 50", status.Distance)
+	}
+
+	status, err = evaluate(manifest, "1.0.0", 0, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Insecure {
+		t.Error("expected release below minimum to be insecure")
+	}
+	if status.UnsupportedFork == nil || status.Distance != 0 {
+		t.Errorf("expected activated prime fork, have %+v at distance %d", status.UnsupportedFork, status.Distance)
+	}
+
+	status, err = evaluate(manifest, "1.2.0", 2, 150)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Outdated || status.UnsupportedFork != nil {
+		t.Errorf("latest release reported outdated %v or unsupported fork %+v", status.Outdated, status.UnsupportedFork)
+	}
+}
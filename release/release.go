@@ -0,0 +1,293 @@
+// Package release implements the opt-in release checker, which periodically
+// fetches a signed release manifest and warns the operator when the running
+// version is outdated or a hard fork it does not support is approaching.
+package release
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// forkWarnDistance is the number of blocks before an unsupported fork at
+	// which every new head is accompanied by a warning.
+	forkWarnDistance = 1000
+)
+
+var (
+	outdatedGauge     = metrics.NewRegisteredGauge("release/outdated", nil)
+	insecureGauge     = metrics.NewRegisteredGauge("release/insecure", nil)
+	forkDistanceGauge = metrics.NewRegisteredGauge("release/fork/distance", nil)
+	checkFailMeter    = metrics.NewRegisteredMeter("release/check/fail", nil)
+
+	// errUnsupportedFork is returned by the seal guard for blocks at or past a
+	// fork which the running release does not support.
+	errUnsupportedFork = errors.New("block is past a fork unsupported by this release")
+)
+
+// Config contains the settings of the release checker.
+type Config struct {
+	URL      string        // location of the manifest, the signature is at URL + ".minisig"
+	PubKeys  []string      // minisign public keys trusted to sign the manifest
+	Interval time.Duration // time between manifest checks
+
+	// RefuseUnsupportedFork makes SealGuard reject work at or past a fork
+	// the running release does not support.
+	RefuseUnsupportedFork bool
+}
+
+// backend is the chain access needed by the release checker.
+type backend interface {
+	CurrentHeader() *types.Header
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Service periodically checks the release manifest and reports the outcome via
+// logs, metrics and the quai_releaseStatus RPC method.
+type Service struct {
+	config  Config
+	backend backend
+	current string
+
+	lock      sync.RWMutex
+	status    *Status
+	lastCheck time.Time
+	lastErr   error
+
+	headSub event.Subscription
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New creates the release checker and registers it with the node.
+func New(stack *node.Node, backend backend, config Config) (*Service, error) {
+	if config.URL == "" {
+		return nil, errors.New("release manifest URL not set")
+	}
+	if len(config.PubKeys) == 0 {
+		return nil, errors.New("no trusted release public keys")
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+	s := &Service{
+		config:  config,
+		backend: backend,
+		current: params.Version,
+		quit:    make(chan struct{}),
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "quai",
+		Version:   "1.0",
+		Service:   &PublicReleaseAPI{s},
+		Public:    true,
+	}})
+	stack.RegisterLifecycle(s)
+	return s, nil
+}
+
+// Start implements node.Lifecycle, starting the check loop.
+func (s *Service) Start() error {
+	headCh := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	s.headSub = s.backend.SubscribeChainHeadEvent(headCh)
+
+	s.wg.Add(1)
+	go s.loop(headCh)
+
+	log.Info("Release checker started", "url", s.config.URL, "interval", s.config.Interval)
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the check loop.
+func (s *Service) Stop() error {
+	s.headSub.Unsubscribe()
+	close(s.quit)
+	s.wg.Wait()
+	log.Info("Release checker stopped")
+	return nil
+}
+
+func (s *Service) loop(headCh chan core.ChainHeadEvent) {
+	defer s.wg.Done()
+
+	var (
+		manifest *Manifest
+		ticker   = time.NewTicker(s.config.Interval)
+	)
+	defer ticker.Stop()
+
+	check := func() {
+		m, err := s.fetch()
+		if err != nil {
+			checkFailMeter.Mark(1)
+			log.Warn("Release check failed", "url", s.config.URL, "err", err)
+
+			s.lock.Lock()
+			s.lastCheck, s.lastErr = time.Now(), err
+			s.lock.Unlock()
+			return
+		}
+		manifest = m
+		s.update(manifest, s.backend.CurrentHeader(), true)
+	}
+	check()
+
+	for {
+		select {
+		case <-ticker.C:
+			check()
+		case ev := <-headCh:
+			if manifest != nil {
+				s.update(manifest, ev.Block.Header(), false)
+			}
+		case <-s.headSub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// fetch retrieves the manifest and its signature and verifies them.
+func (s *Service) fetch() (*Manifest, error) {
+	data, err := fetch(s.config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve manifest: %w", err)
+	}
+	sig, err := fetch(s.config.URL + ".minisig")
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve signature: %w", err)
+	}
+	return parseManifest(s.config.PubKeys, data, sig)
+}
+
+// update evaluates the manifest against the given head, publishing the result.
+// Release notices are logged only on fresh checks, fork warnings on every head
+// within forkWarnDistance of the unsupported fork.
+func (s *Service) update(manifest *Manifest, head *types.Header, checked bool) {
+	var number uint64
+	if head != nil {
+		number = head.Number[types.QuaiNetworkContext].Uint64()
+	}
+	status, err := evaluate(manifest, s.current, types.QuaiNetworkContext, number)
+	if err != nil {
+		log.Warn("Invalid release manifest", "err", err)
+		return
+	}
+	s.lock.Lock()
+	s.status = status
+	if checked {
+		s.lastCheck, s.lastErr = time.Now(), nil
+	}
+	s.lock.Unlock()
+
+	outdatedGauge.Update(boolGauge(status.Outdated))
+	insecureGauge.Update(boolGauge(status.Insecure))
+	if fork := status.UnsupportedFork; fork != nil {
+		forkDistanceGauge.Update(int64(status.Distance))
+		if status.Distance == 0 {
+			log.Error("Unsupported fork activated, upgrade required", "fork", fork.Name, "block", fork.Block, "required", fork.Version, "current", s.current)
+		} else if checked || status.Distance <= forkWarnDistance {
+			log.Warn("Unsupported fork approaching, upgrade required", "fork", fork.Name, "block", fork.Block, "remaining", status.Distance, "required", fork.Version, "current", s.current)
+		}
+	} else {
+		forkDistanceGauge.Update(-1)
+	}
+	if !checked {
+		return
+	}
+	switch {
+	case status.Insecure:
+		log.Error("Running release is no longer supported", "current", s.current, "minimum", manifest.Minimum, "latest", status.Latest, "notes", status.Notes)
+	case status.Outdated:
+		log.Warn("New release available", "current", s.current, "latest", status.Latest, "notes", status.Notes)
+	}
+}
+
+// SealGuard rejects sealing work at or past a fork the running release does
+// not support, if the checker was configured to do so. It is meant to be
+// installed with miner.AddSealGuard.
+func (s *Service) SealGuard(header *types.Header) error {
+	if !s.config.RefuseUnsupportedFork {
+		return nil
+	}
+	s.lock.RLock()
+	status := s.status
+	s.lock.RUnlock()
+
+	if status == nil || status.UnsupportedFork == nil {
+		return nil
+	}
+	if header.Number[types.QuaiNetworkContext].Uint64() >= status.UnsupportedFork.Block {
+		return fmt.Errorf("%w: %s at block %d requires %s", errUnsupportedFork, status.UnsupportedFork.Name, status.UnsupportedFork.Block, status.UnsupportedFork.Version)
+	}
+	return nil
+}
+
+// PublicReleaseAPI offers the release checker status over RPC.
+type PublicReleaseAPI struct {
+	s *Service
+}
+
+// StatusResult is the response of quai_releaseStatus.
+type StatusResult struct {
+	*Status
+	LastCheck time.Time `json:"lastCheck"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// ReleaseStatus returns the outcome of the latest release manifest check.
+func (api *PublicReleaseAPI) ReleaseStatus() *StatusResult {
+	api.s.lock.RLock()
+	defer api.s.lock.RUnlock()
+
+	result := &StatusResult{Status: api.s.status, LastCheck: api.s.lastCheck}
+	if result.Status == nil {
+		result.Status = &Status{Current: api.s.current}
+	}
+	if api.s.lastErr != nil {
+		result.Error = api.s.lastErr.Error()
+	}
+	return result
+}
+
+func boolGauge(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// fetch makes an HTTP request to the given url and returns the response body.
+func fetch(url string) ([]byte, error) {
+	if filep := strings.TrimPrefix(url, "file://"); filep != url {
+		return ioutil.ReadFile(filep)
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
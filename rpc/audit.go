@@ -0,0 +1,303 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/metrics"
+)
+
+// auditedNamespaces are the namespaces of the privileged methods recorded in
+// the audit log.
+var auditedNamespaces = map[string]bool{
+	"admin":    true,
+	"debug":    true,
+	"miner":    true,
+	"personal": true,
+}
+
+// auditRedacted lists the positions of the secret parameters of the audited
+// methods, which are replaced in the audit log.
+var auditRedacted = map[string][]int{
+	"personal_openWallet":             {1},
+	"personal_newAccount":             {0},
+	"personal_importRawKey":           {0, 1},
+	"personal_unlockAccount":          {1},
+	"personal_sendTransaction":        {1},
+	"personal_signTransaction":        {1},
+	"personal_signAndSendTransaction": {1},
+	"personal_sign":                   {2},
+	"personal_unpair":                 {1},
+}
+
+const redactedParam = `"[redacted]"`
+
+var auditFailureMeter = metrics.NewRegisteredMeter("rpc/audit/failures", nil)
+
+var (
+	auditLock sync.RWMutex
+	auditLog  *AuditLog
+)
+
+// SetAuditLog sets the audit log privileged calls of all servers are recorded
+// in, nil disabling the auditing.
+func SetAuditLog(audit *AuditLog) {
+	auditLock.Lock()
+	defer auditLock.Unlock()
+
+	auditLog = audit
+}
+
+// activeAuditLog returns the audit log set, if any.
+func activeAuditLog() *AuditLog {
+	auditLock.RLock()
+	defer auditLock.RUnlock()
+
+	return auditLog
+}
+
+// callerIdentity identifies the caller of a method. The credentials are not
+// verified by the node, they are expected to be authenticated by the proxy in
+// front of it.
+type callerIdentity struct {
+	Remote    string `json:"remote"`
+	Subject   string `json:"subject,omitempty"` // Subject of the JWT bearer token
+	Key       string `json:"key,omitempty"`     // Fingerprint of the API key or opaque bearer token
+	UserAgent string `json:"userAgent,omitempty"`
+	Origin    string `json:"origin,omitempty"`
+}
+
+// identityFromHeader extracts the caller identity from the headers of the
+// HTTP request or websocket handshake.
+func identityFromHeader(header http.Header) callerIdentity {
+	id := callerIdentity{
+		UserAgent: header.Get("User-Agent"),
+		Origin:    header.Get("Origin"),
+	}
+	if key := header.Get("X-API-Key"); key != "" {
+		id.Key = keyFingerprint(key)
+	}
+	auth := header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		token := strings.TrimSpace(auth[7:])
+		if subject, ok := jwtSubject(token); ok {
+			id.Subject = subject
+		} else if id.Key == "" {
+			id.Key = keyFingerprint(token)
+		}
+	}
+	return id
+}
+
+// jwtSubject decodes the subject claim of the JWT, without verifying it.
+func jwtSubject(token string) (string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", false
+	}
+	var claims struct {
+		Subject string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return "", false
+	}
+	return claims.Subject, true
+}
+
+// keyFingerprint identifies a secret credential in the audit log without
+// revealing it.
+func keyFingerprint(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:8])
+}
+
+// auditEntry is a privileged call recorded in the audit log.
+type auditEntry struct {
+	Time     time.Time       `json:"time"`
+	Caller   callerIdentity  `json:"caller"`
+	Method   string          `json:"method"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	Outcome  string          `json:"outcome"` // "ok" or "error"
+	Error    *jsonError      `json:"error,omitempty"`
+	Duration string          `json:"duration"`
+}
+
+// redactParams replaces the secret parameters of the method. The parameters
+// of methods taking secrets are dropped entirely if they can't be decoded.
+func redactParams(method string, params json.RawMessage) json.RawMessage {
+	positions, ok := auditRedacted[method]
+	if !ok {
+		return params
+	}
+	var args []json.RawMessage
+	if err := json.Unmarshal(params, &args); err != nil {
+		return json.RawMessage(redactedParam)
+	}
+	for _, pos := range positions {
+		if pos < len(args) {
+			args[pos] = json.RawMessage(redactedParam)
+		}
+	}
+	redacted, err := json.Marshal(args)
+	if err != nil {
+		return json.RawMessage(redactedParam)
+	}
+	return redacted
+}
+
+// AuditLog is an append-only log of privileged RPC calls, one JSON object per
+// line. The file is rotated once it exceeds its maximum size, keeping a number
+// of rotated files.
+type AuditLog struct {
+	path    string
+	maxSize int64 // Size above which the file is rotated (0 = never rotated)
+	backups int   // Number of rotated files kept
+
+	lock   sync.Mutex
+	file   *os.File // Current file, nil if it failed to reopen
+	size   int64
+	closed bool
+}
+
+// OpenAuditLog opens the audit log at the path for appending.
+func OpenAuditLog(path string, maxSize int64, backups int) (*AuditLog, error) {
+	a := &AuditLog{path: path, maxSize: maxSize, backups: backups}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// open opens the current file of the log, the lock must be held.
+func (a *AuditLog) open() error {
+	file, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	a.file, a.size = file, info.Size()
+	return nil
+}
+
+// rotate moves the current file to the first backup, shifting the older ones
+// and dropping the oldest, then opens a new one. The file is reopened even if
+// the rotation fails, so entries keep being appended. The lock must be held.
+func (a *AuditLog) rotate() error {
+	a.file.Close()
+	err := a.shift()
+	if openErr := a.open(); openErr != nil {
+		a.file = nil
+		return openErr
+	}
+	return err
+}
+
+// shift moves the current file and the backups one place up, dropping the
+// oldest.
+func (a *AuditLog) shift() error {
+	if a.backups == 0 {
+		return os.Remove(a.path)
+	}
+	for i := a.backups - 1; i > 0; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", a.path, i), fmt.Sprintf("%s.%d", a.path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(a.path, a.path+".1")
+}
+
+// write appends the entry to the log, rotating the file beforehand if the
+// entry would exceed its maximum size.
+func (a *AuditLog) write(entry *auditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.closed {
+		return os.ErrClosed
+	}
+	if a.file == nil {
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+	if a.maxSize > 0 && a.size > 0 && a.size+int64(len(data)) > a.maxSize {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := a.file.Write(data)
+	a.size += int64(n)
+	return err
+}
+
+// record appends the call and its outcome to the log.
+func (a *AuditLog) record(caller callerIdentity, msg *jsonrpcMessage, resp *jsonrpcMessage, elapsed time.Duration) {
+	entry := &auditEntry{
+		Time:     time.Now().UTC(),
+		Caller:   caller,
+		Method:   msg.Method,
+		Params:   redactParams(msg.Method, msg.Params),
+		Outcome:  "ok",
+		Duration: elapsed.String(),
+	}
+	if resp != nil && resp.Error != nil {
+		entry.Outcome, entry.Error = "error", resp.Error
+	}
+	if err := a.write(entry); err != nil {
+		auditFailureMeter.Mark(1)
+		log.Error("Failed to write RPC audit log", "method", msg.Method, "err", err)
+	}
+}
+
+// Close closes the audit log.
+func (a *AuditLog) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.closed = true
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
@@ -0,0 +1,164 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuditIdentity(t *testing.T) {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"custodian-7"}`))
+	header := http.Header{}
+	header.Set("Authorization", "Bearer e30."+claims+".sig")
+	header.Set("User-Agent", "ops-tool")
+	if id := identityFromHeader(header); id.Subject != "custodian-7" || id.Key != "" || id.UserAgent != "ops-tool" {
+		t.Errorf("JWT identity mismatch: %+v", id)
+	}
+	header = http.Header{}
+	header.Set("X-API-Key", "secret")
+	id := identityFromHeader(header)
+	if id.Key != keyFingerprint("secret") || strings.Contains(id.Key, "secret") {
+		t.Errorf("API key identity mismatch: %+v", id)
+	}
+}
+
+func TestAuditRedactParams(t *testing.T) {
+	tests := []struct {
+		method, params, want string
+	}{
+		{"admin_addPeer", `["enode://x"]`, `["enode://x"]`},
+		{"personal_unlockAccount", `["0x01","hunter2",300]`, `["0x01","[redacted]",300]`},
+		{"personal_importRawKey", `["abcd","pw"]`, `["[redacted]","[redacted]"]`},
+		{"personal_sign", `["0xdead","0x01"]`, `["0xdead","0x01"]`},
+		{"personal_newAccount", `{"password":"pw"}`, `"[redacted]"`},
+	}
+	for _, tt := range tests {
+		if have := string(redactParams(tt.method, json.RawMessage(tt.params))); have != tt.want {
+			t.Errorf("%s: have %s, want %s", tt.method, have, tt.want)
+		}
+	}
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	audit, err := OpenAuditLog(path, 200, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+
+	msg := &jsonrpcMessage{Method: "admin_nodeInfo", Params: json.RawMessage(`[]`)}
+	for i := 0; i < 10; i++ {
+		audit.record(callerIdentity{Remote: "local"}, msg, &jsonrpcMessage{}, time.Millisecond)
+	}
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Fatalf("missing audit file: %v", err)
+		}
+		if info.Size() > 200 {
+			t.Errorf("%s exceeds the maximum size: %d", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("more backups kept than configured: %v", err)
+	}
+}
+
+func TestAuditHTTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rpc-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	audit, err := OpenAuditLog(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetAuditLog(audit)
+	defer SetAuditLog(nil)
+	defer audit.Close()
+
+	server := newTestServer()
+	if err := server.RegisterName("debug", new(testService)); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	client, err := DialHTTP(httpsrv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.SetHeader("X-API-Key", "ops")
+
+	var result echoResult
+	if err := client.Call(&result, "test_echo", "x", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(&result, "debug_echo", "x", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Call(nil, "debug_returnError"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var entries []auditEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid audit entry %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("audit entry count mismatch: have %d, want 2", len(entries))
+	}
+	if e := entries[0]; e.Method != "debug_echo" || e.Outcome != "ok" || e.Caller.Key != keyFingerprint("ops") || e.Caller.Remote == "" {
+		t.Errorf("call entry mismatch: %+v", e)
+	}
+	if e := entries[1]; e.Method != "debug_returnError" || e.Outcome != "error" || e.Error == nil {
+		t.Errorf("failed call entry mismatch: %+v", e)
+	}
+}
@@ -22,12 +22,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"reflect"
 	"strconv"
 	"sync/atomic"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/spruce-solutions/go-quai/log"
 )
 
@@ -195,6 +197,31 @@ func DialContext(ctx context.Context, rawurl string) (*Client, error) {
 	}
 }
 
+// DialContextWithTransport creates a new RPC client, just like DialContext, with the
+// connections of the HTTP and websocket transports established by transport. Websocket
+// connections use its dial function and TLS configuration.
+func DialContextWithTransport(ctx context.Context, rawurl string, transport *http.Transport) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return DialHTTPWithClient(rawurl, &http.Client{Transport: transport})
+	case "ws", "wss":
+		dialer := websocket.Dialer{
+			NetDialContext:  transport.DialContext,
+			TLSClientConfig: transport.TLSClientConfig,
+			ReadBufferSize:  wsReadBuffer,
+			WriteBufferSize: wsWriteBuffer,
+			WriteBufferPool: wsBufferPool,
+		}
+		return DialWebsocketWithDialer(ctx, rawurl, "", dialer)
+	default:
+		return nil, fmt.Errorf("no network transport for URL scheme %q", u.Scheme)
+	}
+}
+
 // Client retrieves the client from the context, if any. This can be used to perform
 // 'reverse calls' in a handler method.
 func ClientFromContext(ctx context.Context) (*Client, bool) {
@@ -291,11 +291,13 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	start := time.Now()
 	switch {
 	case msg.isNotification():
-		h.handleCall(ctx, msg)
+		resp := h.handleCall(ctx, msg)
+		h.audit(msg, resp, start)
 		h.log.Debug("Served "+msg.Method, "t", time.Since(start))
 		return nil
 	case msg.isCall():
 		resp := h.handleCall(ctx, msg)
+		h.audit(msg, resp, start)
 		var ctx []interface{}
 		ctx = append(ctx, "reqid", idForLog{msg.ID}, "t", time.Since(start))
 		if resp.Error != nil {
@@ -315,6 +317,22 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 	}
 }
 
+// audit records the call of a privileged method in the audit log, if any.
+func (h *handler) audit(msg *jsonrpcMessage, resp *jsonrpcMessage, start time.Time) {
+	audit := activeAuditLog()
+	if audit == nil || !auditedNamespaces[msg.namespace()] {
+		return
+	}
+	caller := callerIdentity{Remote: h.conn.remoteAddr()}
+	if codec, ok := h.conn.(interface{ caller() callerIdentity }); ok {
+		caller = codec.caller()
+	}
+	if caller.Remote == "" {
+		caller.Remote = "local"
+	}
+	audit.record(caller, msg, resp, time.Since(start))
+}
+
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
 	if msg.isSubscribe() {
@@ -345,8 +363,17 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage
 		} else {
 			successfulRequestGauge.Inc(1)
 		}
-		rpcServingTimer.UpdateSince(start)
-		newRPCServingTimer(msg.Method, answer.Error == nil).UpdateSince(start)
+		elapsed := time.Since(start)
+		rpcServingTimer.Update(elapsed)
+		newRPCServingTimer(msg.Method, answer.Error == nil).Update(elapsed)
+		newRPCLatencyHistogram(msg.Method).Update(elapsed.Microseconds())
+		if answer.Error != nil {
+			newRPCErrorCounter(msg.Method, answer.Error.Code).Inc(1)
+		}
+		if isSlowRequest(elapsed) {
+			newRPCSlowMeter(msg.Method).Mark(1)
+			h.log.Warn("Slow RPC request", "method", msg.Method, "reqid", idForLog{msg.ID}, "t", elapsed, "params", truncateParams(msg.Params))
+		}
 	}
 	return answer
 }
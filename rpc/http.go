@@ -211,7 +211,9 @@ type httpServerConn struct {
 func newHTTPServerConn(r *http.Request, w http.ResponseWriter) ServerCodec {
 	body := io.LimitReader(r.Body, maxRequestContentLength)
 	conn := &httpServerConn{Reader: body, Writer: w, r: r}
-	return NewCodec(conn)
+	codec := NewCodec(conn)
+	codec.(*jsonCodec).header = r.Header
+	return codec
 }
 
 // Close does nothing and always returns nil.
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"reflect"
 	"strings"
 	"sync"
@@ -165,6 +166,7 @@ type ConnRemoteAddr interface {
 // support for parsing arguments and serializing (result) objects.
 type jsonCodec struct {
 	remote  string
+	header  http.Header               // headers of the HTTP request or websocket handshake, if any
 	closer  sync.Once                 // close closed channel once
 	closeCh chan interface{}          // closed on Close
 	decode  func(v interface{}) error // decoder to allow multiple transports
@@ -202,6 +204,13 @@ func (c *jsonCodec) remoteAddr() string {
 	return c.remote
 }
 
+// caller returns the identity of the peer of the connection.
+func (c *jsonCodec) caller() callerIdentity {
+	id := identityFromHeader(c.header)
+	id.Remote = c.remote
+	return id
+}
+
 func (c *jsonCodec) readBatch() (messages []*jsonrpcMessage, batch bool, err error) {
 	// Decode the next JSON object in the input stream.
 	// This verifies basic syntax, etc.
@@ -17,11 +17,28 @@
 package rpc
 
 import (
+	"encoding/json"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/spruce-solutions/go-quai/metrics"
 )
 
+const (
+	// DefaultSlowRequestThreshold is the serving time above which requests are
+	// logged as slow by default.
+	DefaultSlowRequestThreshold = 5 * time.Second
+
+	// maxLoggedParams is the number of bytes of the parameters of a slow
+	// request logged.
+	maxLoggedParams = 256
+)
+
+// slowRequestThreshold is the serving time above which requests are logged as
+// slow, in nanoseconds. Zero disables the log.
+var slowRequestThreshold = int64(DefaultSlowRequestThreshold)
+
 var (
 	rpcRequestGauge        = metrics.NewRegisteredGauge("rpc/requests", nil)
 	successfulRequestGauge = metrics.NewRegisteredGauge("rpc/success", nil)
@@ -37,3 +54,45 @@ func newRPCServingTimer(method string, valid bool) metrics.Timer {
 	m := fmt.Sprintf("rpc/duration/%s/%s", method, flag)
 	return metrics.GetOrRegisterTimer(m, nil)
 }
+
+// newRPCLatencyHistogram returns the histogram of the serving times of the
+// method in microseconds, successful and failed calls alike.
+func newRPCLatencyHistogram(method string) metrics.Histogram {
+	m := fmt.Sprintf("rpc/latency/%s", method)
+	return metrics.GetOrRegisterHistogram(m, nil, metrics.NewExpDecaySample(1028, 0.015))
+}
+
+// newRPCErrorCounter returns the counter of the calls of the method failing
+// with the error code.
+func newRPCErrorCounter(method string, code int) metrics.Counter {
+	m := fmt.Sprintf("rpc/errors/%s/%d", method, code)
+	return metrics.GetOrRegisterCounter(m, nil)
+}
+
+// newRPCSlowMeter returns the meter of the calls of the method logged as slow.
+func newRPCSlowMeter(method string) metrics.Meter {
+	m := fmt.Sprintf("rpc/slow/%s", method)
+	return metrics.GetOrRegisterMeter(m, nil)
+}
+
+// SetSlowRequestThreshold sets the serving time above which requests are logged
+// as slow along with their truncated parameters, which serve as exemplars of
+// the latency histograms. Zero disables the log.
+func SetSlowRequestThreshold(threshold time.Duration) {
+	atomic.StoreInt64(&slowRequestThreshold, int64(threshold))
+}
+
+// isSlowRequest reports whether a request served in the given time is slow.
+func isSlowRequest(elapsed time.Duration) bool {
+	threshold := atomic.LoadInt64(&slowRequestThreshold)
+	return threshold > 0 && int64(elapsed) >= threshold
+}
+
+// truncateParams returns the parameters of a request for logging, cut down to
+// maxLoggedParams bytes.
+func truncateParams(params json.RawMessage) string {
+	if len(params) <= maxLoggedParams {
+		return string(params)
+	}
+	return fmt.Sprintf("%s... (%d bytes)", params[:maxLoggedParams], len(params))
+}
@@ -0,0 +1,39 @@
+package rpc
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowRequestThreshold(t *testing.T) {
+	defer SetSlowRequestThreshold(DefaultSlowRequestThreshold)
+
+	SetSlowRequestThreshold(time.Second)
+	if isSlowRequest(999 * time.Millisecond) {
+		t.Error("request below the threshold reported slow")
+	}
+	if !isSlowRequest(time.Second) {
+		t.Error("request at the threshold not reported slow")
+	}
+	SetSlowRequestThreshold(0)
+	if isSlowRequest(time.Hour) {
+		t.Error("request reported slow with the log disabled")
+	}
+}
+
+func TestTruncateParams(t *testing.T) {
+	short := json.RawMessage(`["0x1",true]`)
+	if have := truncateParams(short); have != string(short) {
+		t.Errorf("short params changed: have %s, want %s", have, short)
+	}
+	long := json.RawMessage(`["` + strings.Repeat("a", 2*maxLoggedParams) + `"]`)
+	have := truncateParams(long)
+	if !strings.HasPrefix(have, string(long[:maxLoggedParams])) {
+		t.Errorf("truncated params lost their prefix: %s", have)
+	}
+	if want := "... (516 bytes)"; !strings.HasSuffix(have, want) {
+		t.Errorf("truncated params suffix mismatch: have %s, want suffix %s", have, want)
+	}
+}
@@ -127,7 +127,7 @@ func (t *BlockTest) Run(snapshotter bool) error {
 		cache.SnapshotLimit = 1
 		cache.SnapshotWait = true
 	}
-	chain, err := core.NewBlockChain(db, cache, config, engine, vm.Config{}, nil, nil)
+	chain, err := core.NewBlockChain(db, cache, config, "", nil, engine, vm.Config{}, nil, nil)
 	if err != nil {
 		return err
 	}
@@ -158,19 +158,19 @@ func (t *BlockTest) Run(snapshotter bool) error {
 }
 
 func (t *BlockTest) genesis(config *params.ChainConfig) *core.Genesis {
+	genesis := t.json.Genesis
 	return &core.Genesis{
 		Config:     config,
-		Nonce:      t.json.Genesis.Nonce.Uint64(),
-		Timestamp:  t.json.Genesis.Timestamp,
-		ParentHash: t.json.Genesis.ParentHash,
-		ExtraData:  t.json.Genesis.ExtraData,
-		GasLimit:   t.json.Genesis.GasLimit,
-		GasUsed:    t.json.Genesis.GasUsed,
-		Difficulty: t.json.Genesis.Difficulty,
-		Mixhash:    t.json.Genesis.MixHash,
-		Coinbase:   t.json.Genesis.Coinbase,
+		Nonce:      genesis.Nonce.Uint64(),
+		Timestamp:  genesis.Timestamp,
+		ParentHash: []common.Hash{genesis.ParentHash, genesis.ParentHash, genesis.ParentHash},
+		ExtraData:  [][]byte{genesis.ExtraData, genesis.ExtraData, genesis.ExtraData},
+		GasLimit:   []uint64{genesis.GasLimit, genesis.GasLimit, genesis.GasLimit},
+		GasUsed:    []uint64{genesis.GasUsed, genesis.GasUsed, genesis.GasUsed},
+		Difficulty: []*big.Int{genesis.Difficulty, genesis.Difficulty, genesis.Difficulty},
+		Coinbase:   []common.Address{genesis.Coinbase, genesis.Coinbase, genesis.Coinbase},
 		Alloc:      t.json.Pre,
-		BaseFee:    t.json.Genesis.BaseFeePerGas,
+		BaseFee:    []*big.Int{genesis.BaseFeePerGas, genesis.BaseFeePerGas, genesis.BaseFeePerGas},
 	}
 }
 
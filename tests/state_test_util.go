@@ -229,7 +229,7 @@ func (t *StateTest) RunNoVerify(subtest StateSubtest, vmconfig vm.Config, snapsh
 	}
 
 	// Commit block
-	statedb.Commit(config.IsEIP158(block.Number()))
+	statedb.Commit(config.DeleteEmptyAccounts(block.Number()))
 	// Add 0-value mining reward. This only makes a difference in the cases
 	// where
 	// - the coinbase suicided, or
@@ -237,7 +237,7 @@ func (t *StateTest) RunNoVerify(subtest StateSubtest, vmconfig vm.Config, snapsh
 	//   the coinbase gets no txfee, so isn't created, and thus needs to be touched
 	statedb.AddBalance(block.Coinbase(), new(big.Int))
 	// And _now_ get the state root
-	root := statedb.IntermediateRoot(config.IsEIP158(block.Number()))
+	root := statedb.IntermediateRoot(config.DeleteEmptyAccounts(block.Number()))
 	return snaps, statedb, root, nil
 }
 
@@ -268,13 +268,15 @@ func MakePreState(db ethdb.Database, accounts core.GenesisAlloc, snapshotter boo
 }
 
 func (t *StateTest) genesis(config *params.ChainConfig) *core.Genesis {
+	env := t.json.Env
+	number := new(big.Int).SetUint64(env.Number)
 	return &core.Genesis{
 		Config:     config,
-		Coinbase:   t.json.Env.Coinbase,
-		Difficulty: t.json.Env.Difficulty,
-		GasLimit:   t.json.Env.GasLimit,
-		Number:     t.json.Env.Number,
-		Timestamp:  t.json.Env.Timestamp,
+		Coinbase:   []common.Address{env.Coinbase, env.Coinbase, env.Coinbase},
+		Difficulty: []*big.Int{env.Difficulty, env.Difficulty, env.Difficulty},
+		GasLimit:   []uint64{env.GasLimit, env.GasLimit, env.GasLimit},
+		Number:     []*big.Int{number, number, number},
+		Timestamp:  env.Timestamp,
 		Alloc:      t.json.Pre,
 	}
 }
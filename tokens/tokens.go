@@ -0,0 +1,441 @@
+// Package tokens implements an indexer of the ERC-20 and ERC-721 Transfer
+// events of the local chain, maintaining the token balances and transfer
+// history of every address so explorers can serve basic token views over RPC
+// without a separate indexing stack.
+package tokens
+
+import (
+	"encoding/binary"
+	"math/big"
+	"sync"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/common/hexutil"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/crypto"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/log"
+	"github.com/spruce-solutions/go-quai/node"
+	"github.com/spruce-solutions/go-quai/params"
+	"github.com/spruce-solutions/go-quai/rlp"
+	"github.com/spruce-solutions/go-quai/rpc"
+)
+
+const (
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// DefaultDepth is the default number of confirmations after which a height
+	// is indexed, so reorgs rarely invalidate the index.
+	DefaultDepth = 7
+
+	// defaultLimit is the number of transfers returned by queries asking for none.
+	defaultLimit = 100
+
+	// maxLimit is the highest number of transfers returned by a single query.
+	maxLimit = 10000
+)
+
+// Token standards told apart by the layout of their Transfer events.
+const (
+	ERC20  = "erc20"  // Transfer(address indexed, address indexed, uint256)
+	ERC721 = "erc721" // Transfer(address indexed, address indexed, uint256 indexed)
+)
+
+var (
+	// transferTopic is the signature hash of the standard Transfer event.
+	transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+	// The index lives in its own table of the chain database:
+	//   headKey                                          -> next height to index
+	//   balancePrefix + holder + token                   -> balance
+	//   transferPrefix + holder + ^number + ^tx + ^log   -> transfer
+	// Transfer keys hold inverted positions, so iterating yields newest first.
+	tablePrefix    = "tokens-"
+	headKey        = []byte("Head")
+	balancePrefix  = []byte("b")
+	transferPrefix = []byte("t")
+)
+
+// Config contains the settings of the token indexer.
+type Config struct {
+	Depth uint64 // confirmations before a height is indexed
+}
+
+// backend is the chain access needed by the token indexer.
+type backend interface {
+	ChainDb() ethdb.Database
+	ChainConfig() *params.ChainConfig
+	CurrentHeader() *types.Header
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// Balance is the holdings of an address in a token, as implied by the indexed
+// Transfer events. For ERC-721 tokens it is the number of tokens owned.
+type Balance struct {
+	Token    common.Address `json:"token"`
+	Standard string         `json:"standard"`
+	Balance  *hexutil.Big   `json:"balance"`
+}
+
+// Transfer is an indexed Transfer event.
+type Transfer struct {
+	Token       common.Address `json:"token"`
+	Standard    string         `json:"standard"`
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	Value       *hexutil.Big   `json:"value,omitempty"`   // amount of ERC-20 transfers
+	TokenID     *hexutil.Big   `json:"tokenId,omitempty"` // token of ERC-721 transfers
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	TxIndex     hexutil.Uint   `json:"transactionIndex"`
+	LogIndex    hexutil.Uint   `json:"logIndex"`
+}
+
+// storedBalance is the database form of a balance.
+type storedBalance struct {
+	Standard string
+	Balance  *big.Int
+}
+
+// storedTransfer is the database form of a transfer.
+type storedTransfer struct {
+	Token     common.Address
+	Standard  string
+	From      common.Address
+	To        common.Address
+	Amount    *big.Int // value or token id, depending on the standard
+	Number    uint64
+	BlockHash common.Hash
+	TxHash    common.Hash
+	TxIndex   uint
+	LogIndex  uint
+}
+
+// decodeTransfer decodes a log into a transfer if it is a standard Transfer
+// event, telling the standards apart by the number of indexed arguments.
+func decodeTransfer(l *types.Log) *storedTransfer {
+	if len(l.Topics) == 0 || l.Topics[0] != transferTopic {
+		return nil
+	}
+	transfer := &storedTransfer{
+		Token:     l.Address,
+		Number:    l.BlockNumber,
+		BlockHash: l.BlockHash,
+		TxHash:    l.TxHash,
+		TxIndex:   l.TxIndex,
+		LogIndex:  l.Index,
+	}
+	switch {
+	case len(l.Topics) == 3 && len(l.Data) == 32:
+		transfer.Standard = ERC20
+		transfer.Amount = new(big.Int).SetBytes(l.Data)
+	case len(l.Topics) == 4 && len(l.Data) == 0:
+		transfer.Standard = ERC721
+		transfer.Amount = l.Topics[3].Big()
+	default:
+		return nil
+	}
+	transfer.From = common.BytesToAddress(l.Topics[1][:])
+	transfer.To = common.BytesToAddress(l.Topics[2][:])
+	return transfer
+}
+
+// balanceKey = balancePrefix + holder + token
+func balanceKey(holder, token common.Address) []byte {
+	return append(append(append([]byte{}, balancePrefix...), holder[:]...), token[:]...)
+}
+
+// transferKey = transferPrefix + holder + ^number + ^tx index + ^log index
+func transferKey(holder common.Address, t *storedTransfer) []byte {
+	key := make([]byte, len(transferPrefix)+common.AddressLength+16)
+	n := copy(key, transferPrefix)
+	n += copy(key[n:], holder[:])
+	binary.BigEndian.PutUint64(key[n:], ^t.Number)
+	binary.BigEndian.PutUint32(key[n+8:], ^uint32(t.TxIndex))
+	binary.BigEndian.PutUint32(key[n+12:], ^uint32(t.LogIndex))
+	return key
+}
+
+// Indexer decodes the Transfer events of confirmed canonical blocks into token
+// balances and transfer histories.
+type Indexer struct {
+	config  Config
+	backend backend
+	db      ethdb.Database
+
+	lock sync.Mutex // serializes index updates
+	next uint64     // next height to index
+
+	headSub event.Subscription
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// New creates the token indexer and registers it with the node.
+func New(stack *node.Node, backend backend, config Config) *Indexer {
+	if config.Depth == 0 {
+		config.Depth = DefaultDepth
+	}
+	idx := &Indexer{
+		config:  config,
+		backend: backend,
+		db:      rawdb.NewTable(backend.ChainDb(), tablePrefix),
+		quit:    make(chan struct{}),
+	}
+	stack.RegisterAPIs([]rpc.API{{
+		Namespace: "quai",
+		Version:   "1.0",
+		Service:   &PublicTokenAPI{idx},
+		Public:    true,
+	}})
+	stack.RegisterLifecycle(idx)
+	return idx
+}
+
+// Start implements node.Lifecycle, starting the indexing loop.
+func (idx *Indexer) Start() error {
+	if blob, _ := idx.db.Get(headKey); len(blob) == 8 {
+		idx.next = binary.BigEndian.Uint64(blob)
+	}
+	headCh := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	idx.headSub = idx.backend.SubscribeChainHeadEvent(headCh)
+
+	idx.wg.Add(1)
+	go idx.loop(headCh)
+
+	log.Info("Token indexer started", "next", idx.next, "depth", idx.config.Depth)
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the indexing loop.
+func (idx *Indexer) Stop() error {
+	idx.headSub.Unsubscribe()
+	close(idx.quit)
+	idx.wg.Wait()
+	log.Info("Token indexer stopped")
+	return nil
+}
+
+func (idx *Indexer) loop(headCh chan core.ChainHeadEvent) {
+	defer idx.wg.Done()
+
+	if head := idx.backend.CurrentHeader(); head != nil {
+		idx.index(head.Number[types.QuaiNetworkContext].Uint64())
+	}
+	for {
+		select {
+		case ev := <-headCh:
+			idx.index(ev.Block.Header().Number[types.QuaiNetworkContext].Uint64())
+		case <-idx.headSub.Err():
+			return
+		case <-idx.quit:
+			return
+		}
+	}
+}
+
+// index processes every height with enough confirmations below the given head.
+func (idx *Indexer) index(head uint64) {
+	if head < idx.config.Depth {
+		return
+	}
+	for idx.next <= head-idx.config.Depth {
+		select {
+		case <-idx.quit:
+			return
+		default:
+		}
+		if err := idx.process(idx.next); err != nil {
+			log.Error("Failed to index token transfers", "number", idx.next, "err", err)
+			return
+		}
+	}
+}
+
+// process indexes the transfers of the canonical block at the given height and
+// advances the index head, atomically.
+func (idx *Indexer) process(number uint64) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	batch := idx.db.NewBatch()
+	if hash := rawdb.ReadCanonicalHash(idx.backend.ChainDb(), number); hash != (common.Hash{}) {
+		balances := make(map[string]*storedBalance)
+		for _, receipt := range rawdb.ReadReceipts(idx.backend.ChainDb(), hash, number, idx.backend.ChainConfig()) {
+			for _, l := range receipt.Logs {
+				transfer := decodeTransfer(l)
+				if transfer == nil {
+					continue
+				}
+				if err := idx.apply(batch, balances, transfer); err != nil {
+					return err
+				}
+			}
+		}
+		for key, balance := range balances {
+			blob, err := rlp.EncodeToBytes(balance)
+			if err != nil {
+				return err
+			}
+			if err := batch.Put([]byte(key), blob); err != nil {
+				return err
+			}
+		}
+	}
+	var head [8]byte
+	binary.BigEndian.PutUint64(head[:], number+1)
+	if err := batch.Put(headKey, head[:]); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	idx.next = number + 1
+	return nil
+}
+
+// apply records a transfer in the histories of both parties and moves the
+// balance between them. Minted and burned amounts are not tracked for the zero
+// address. Balances a non-standard token moved without events are clamped at
+// zero rather than going negative.
+func (idx *Indexer) apply(batch ethdb.Batch, balances map[string]*storedBalance, transfer *storedTransfer) error {
+	blob, err := rlp.EncodeToBytes(transfer)
+	if err != nil {
+		return err
+	}
+	amount := transfer.Amount
+	if transfer.Standard == ERC721 {
+		amount = big.NewInt(1)
+	}
+	for _, holder := range []common.Address{transfer.From, transfer.To} {
+		if holder == (common.Address{}) {
+			continue
+		}
+		if err := batch.Put(transferKey(holder, transfer), blob); err != nil {
+			return err
+		}
+		if transfer.From == transfer.To {
+			break
+		}
+		balance, err := idx.balance(balances, holder, transfer.Token, transfer.Standard)
+		if err != nil {
+			return err
+		}
+		if holder == transfer.From {
+			balance.Balance.Sub(balance.Balance, amount)
+			if balance.Balance.Sign() < 0 {
+				balance.Balance.SetUint64(0)
+			}
+		} else {
+			balance.Balance.Add(balance.Balance, amount)
+		}
+	}
+	return nil
+}
+
+// balance returns the balance of a holder being updated in the current block,
+// loading it from the database on first access.
+func (idx *Indexer) balance(balances map[string]*storedBalance, holder, token common.Address, standard string) (*storedBalance, error) {
+	key := string(balanceKey(holder, token))
+	if balance, ok := balances[key]; ok {
+		return balance, nil
+	}
+	balance := &storedBalance{Standard: standard, Balance: new(big.Int)}
+	if blob, _ := idx.db.Get([]byte(key)); len(blob) > 0 {
+		if err := rlp.DecodeBytes(blob, balance); err != nil {
+			return nil, err
+		}
+	}
+	balances[key] = balance
+	return balance, nil
+}
+
+// Balances returns the non-zero token balances of a holder.
+func (idx *Indexer) Balances(holder common.Address) ([]*Balance, error) {
+	prefix := append(append([]byte{}, balancePrefix...), holder[:]...)
+	it := idx.db.NewIterator(prefix, nil)
+	defer it.Release()
+
+	balances := []*Balance{}
+	for it.Next() {
+		var stored storedBalance
+		if err := rlp.DecodeBytes(it.Value(), &stored); err != nil {
+			return nil, err
+		}
+		if stored.Balance.Sign() == 0 {
+			continue
+		}
+		balances = append(balances, &Balance{
+			Token:    common.BytesToAddress(it.Key()[len(prefix):]),
+			Standard: stored.Standard,
+			Balance:  (*hexutil.Big)(stored.Balance),
+		})
+	}
+	return balances, it.Error()
+}
+
+// Transfers returns up to limit of the most recent transfers a holder took
+// part in, newest first, optionally restricted to a single token.
+func (idx *Indexer) Transfers(holder common.Address, token *common.Address, limit int) ([]*Transfer, error) {
+	it := idx.db.NewIterator(append(append([]byte{}, transferPrefix...), holder[:]...), nil)
+	defer it.Release()
+
+	transfers := []*Transfer{}
+	for it.Next() && len(transfers) < limit {
+		var stored storedTransfer
+		if err := rlp.DecodeBytes(it.Value(), &stored); err != nil {
+			return nil, err
+		}
+		if token != nil && stored.Token != *token {
+			continue
+		}
+		transfer := &Transfer{
+			Token:       stored.Token,
+			Standard:    stored.Standard,
+			From:        stored.From,
+			To:          stored.To,
+			BlockNumber: hexutil.Uint64(stored.Number),
+			BlockHash:   stored.BlockHash,
+			TxHash:      stored.TxHash,
+			TxIndex:     hexutil.Uint(stored.TxIndex),
+			LogIndex:    hexutil.Uint(stored.LogIndex),
+		}
+		if stored.Standard == ERC721 {
+			transfer.TokenID = (*hexutil.Big)(stored.Amount)
+		} else {
+			transfer.Value = (*hexutil.Big)(stored.Amount)
+		}
+		transfers = append(transfers, transfer)
+	}
+	return transfers, it.Error()
+}
+
+// PublicTokenAPI offers the token index over RPC.
+type PublicTokenAPI struct {
+	idx *Indexer
+}
+
+// GetTokenBalances returns the ERC-20 and ERC-721 holdings of an address, as
+// implied by the Transfer events indexed so far.
+func (api *PublicTokenAPI) GetTokenBalances(holder common.Address) ([]*Balance, error) {
+	return api.idx.Balances(holder)
+}
+
+// GetTokenTransfers returns the most recent token transfers an address took
+// part in, newest first, optionally restricted to a single token. The limit
+// defaults to 100.
+func (api *PublicTokenAPI) GetTokenTransfers(holder common.Address, token *common.Address, limit *int) ([]*Transfer, error) {
+	n := defaultLimit
+	if limit != nil && *limit > 0 {
+		n = *limit
+	}
+	if n > maxLimit {
+		n = maxLimit
+	}
+	return api.idx.Transfers(holder, token, n)
+}
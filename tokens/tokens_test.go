@@ -0,0 +1,113 @@
+package tokens
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/spruce-solutions/go-quai/common"
+	"github.com/spruce-solutions/go-quai/core"
+	"github.com/spruce-solutions/go-quai/core/rawdb"
+	"github.com/spruce-solutions/go-quai/core/types"
+	"github.com/spruce-solutions/go-quai/ethdb"
+	"github.com/spruce-solutions/go-quai/event"
+	"github.com/spruce-solutions/go-quai/params"
+)
+
+type testBackend struct {
+	db ethdb.Database
+}
+
+func (b *testBackend) ChainDb() ethdb.Database          { return b.db }
+func (b *testBackend) ChainConfig() *params.ChainConfig { return params.TestChainConfig }
+func (b *testBackend) CurrentHeader() *types.Header     { return nil }
+func (b *testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return nil
+}
+
+// writeBlock stores a canonical block with one transaction emitting the logs.
+func writeBlock(db ethdb.Database, number uint64, logs ...*types.Log) {
+	header := types.NewEmptyHeader()
+	for i := 0; i < types.ContextDepth; i++ {
+		header.Number[i] = new(big.Int).SetUint64(number)
+		header.Difficulty[i] = big.NewInt(1)
+		header.NetworkDifficulty[i] = big.NewInt(1)
+		header.BaseFee[i] = big.NewInt(0)
+	}
+	tx := types.NewTransaction(number, common.Address{0xff}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	block := types.NewBlockWithHeader(header).WithBody([]*types.Transaction{tx}, nil)
+
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteReceipts(db, block.Hash(), number, types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: logs}})
+	rawdb.WriteCanonicalHash(db, block.Hash(), number)
+}
+
+func erc20Transfer(token, from, to common.Address, value int64) *types.Log {
+	return &types.Log{
+		Address: token,
+		Topics:  []common.Hash{transferTopic, from.Hash(), to.Hash()},
+		Data:    common.LeftPadBytes(big.NewInt(value).Bytes(), 32),
+	}
+}
+
+func erc721Transfer(token, from, to common.Address, id int64) *types.Log {
+	return &types.Log{
+		Address: token,
+		Topics:  []common.Hash{transferTopic, from.Hash(), to.Hash(), common.BigToHash(big.NewInt(id))},
+	}
+}
+
+func TestIndexer(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		coin   = common.Address{0xc0}
+		nft    = common.Address{0xc1}
+		alice  = common.Address{0x0a}
+		bob    = common.Address{0x0b}
+		minter = common.Address{}
+	)
+	writeBlock(db, 0)
+	writeBlock(db, 1, erc20Transfer(coin, minter, alice, 100), erc721Transfer(nft, minter, alice, 7))
+	writeBlock(db, 2, erc20Transfer(coin, alice, bob, 30), erc721Transfer(nft, alice, bob, 7))
+	writeBlock(db, 3, &types.Log{Address: coin, Topics: []common.Hash{transferTopic}}) // malformed
+
+	idx := &Indexer{config: Config{Depth: 1}, backend: &testBackend{db}, db: rawdb.NewTable(db, tablePrefix)}
+	idx.index(4)
+	if idx.next != 4 {
+		t.Fatalf("index head mismatch: have %d, want 4", idx.next)
+	}
+	balances, err := idx.Balances(alice)
+	if err != nil {
+		t.Fatalf("failed to read balances: %v", err)
+	}
+	if len(balances) != 1 || balances[0].Token != coin || balances[0].Balance.ToInt().Int64() != 70 {
+		t.Fatalf("alice balances mismatch: %+v", balances)
+	}
+	balances, _ = idx.Balances(bob)
+	if len(balances) != 2 {
+		t.Fatalf("bob balance count mismatch: have %d, want 2", len(balances))
+	}
+	for _, balance := range balances {
+		if want := map[common.Address]int64{coin: 30, nft: 1}[balance.Token]; balance.Balance.ToInt().Int64() != want {
+			t.Errorf("bob balance of %x mismatch: have %v, want %d", balance.Token, balance.Balance, want)
+		}
+	}
+	transfers, err := idx.Transfers(alice, nil, 10)
+	if err != nil {
+		t.Fatalf("failed to read transfers: %v", err)
+	}
+	if len(transfers) != 4 {
+		t.Fatalf("alice transfer count mismatch: have %d, want 4", len(transfers))
+	}
+	if transfers[0].BlockNumber != 2 || transfers[0].Standard != ERC721 || transfers[0].TokenID.ToInt().Int64() != 7 {
+		t.Errorf("newest transfer mismatch: %+v", transfers[0])
+	}
+	transfers, _ = idx.Transfers(alice, &coin, 1)
+	if len(transfers) != 1 || transfers[0].Value.ToInt().Int64() != 30 || transfers[0].To != bob {
+		t.Errorf("filtered transfers mismatch: %+v", transfers)
+	}
+	// The progress is persisted for restarts
+	restarted := &Indexer{config: Config{Depth: 1}, backend: &testBackend{db}, db: rawdb.NewTable(db, tablePrefix)}
+	if blob, _ := restarted.db.Get(headKey); len(blob) != 8 || blob[7] != 4 {
+		t.Errorf("persisted head mismatch: %x", blob)
+	}
+}
@@ -20,9 +20,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"reflect"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/VictoriaMetrics/fastcache"
@@ -69,7 +71,8 @@ var (
 type Database struct {
 	diskdb ethdb.KeyValueStore // Persistent storage for matured trie nodes
 
-	cleans  *fastcache.Cache            // GC friendly memory cache of clean node RLPs
+	cleans  atomic.Value                // GC friendly memory cache of clean node RLPs (*fastcache.Cache)
+	warming uint32                      // Flag whether the clean cache journal is being loaded
 	dirties map[common.Hash]*cachedNode // Data and references relationships of dirty trie nodes
 	oldest  common.Hash                 // Oldest tracked node, flush-list head
 	newest  common.Hash                 // Newest tracked node, flush-list tail
@@ -290,27 +293,63 @@ func NewDatabase(diskdb ethdb.KeyValueStore) *Database {
 // before its written out to disk or garbage collected. It also acts as a read cache
 // for nodes loaded from disk.
 func NewDatabaseWithConfig(diskdb ethdb.KeyValueStore, config *Config) *Database {
-	var cleans *fastcache.Cache
-	if config != nil && config.Cache > 0 {
-		if config.Journal == "" {
-			cleans = fastcache.New(config.Cache * 1024 * 1024)
-		} else {
-			cleans = fastcache.LoadFromFileOrNew(config.Journal, config.Cache*1024*1024)
-		}
-	}
 	db := &Database{
 		diskdb: diskdb,
-		cleans: cleans,
 		dirties: map[common.Hash]*cachedNode{{}: {
 			children: make(map[common.Hash]uint16),
 		}},
 	}
+	if config != nil && config.Cache > 0 {
+		db.cleans.Store(fastcache.New(config.Cache * 1024 * 1024))
+
+		// Loading a large journal takes minutes, warm the cache up in the
+		// background instead of delaying the startup
+		if config.Journal != "" {
+			if _, err := os.Stat(config.Journal); err == nil {
+				atomic.StoreUint32(&db.warming, 1)
+				go db.loadCache(config.Journal, config.Cache*1024*1024)
+			}
+		}
+	}
 	if config == nil || config.Preimages { // TODO(karalabe): Flip to default off in the future
 		db.preimages = make(map[common.Hash][]byte)
 	}
 	return db
 }
 
+// cleanCache returns the clean node cache, nil if caching is disabled.
+func (db *Database) cleanCache() *fastcache.Cache {
+	cleans, _ := db.cleans.Load().(*fastcache.Cache)
+	return cleans
+}
+
+// loadCache loads the clean cache journal and swaps it in place of the cache
+// used meanwhile. Nodes cached while loading are dropped, they are reloaded
+// from disk on their next access.
+func (db *Database) loadCache(journal string, maxBytes int) {
+	defer atomic.StoreUint32(&db.warming, 0)
+
+	start := time.Now()
+	cleans := fastcache.LoadFromFileOrNew(journal, maxBytes)
+
+	var stats fastcache.Stats
+	cleans.UpdateStats(&stats)
+	if stats.EntriesCount == 0 {
+		// The journal was empty, corrupted or for another cache size
+		log.Debug("Discarded clean trie cache journal", "path", journal)
+		return
+	}
+	old := db.cleanCache()
+	db.cleans.Store(cleans)
+	old.Reset()
+	log.Info("Loaded clean trie cache journal", "path", journal, "nodes", stats.EntriesCount, "elapsed", common.PrettyDuration(time.Since(start)))
+}
+
+// Warming reports whether the clean cache journal is still being loaded.
+func (db *Database) Warming() bool {
+	return atomic.LoadUint32(&db.warming) == 1
+}
+
 // DiskDB retrieves the persistent storage backing the trie database.
 func (db *Database) DiskDB() ethdb.KeyValueStore {
 	return db.diskdb
@@ -371,8 +410,9 @@ func (db *Database) insertPreimage(hash common.Hash, preimage []byte) {
 // found in the memory cache.
 func (db *Database) node(hash common.Hash) node {
 	// Retrieve the node from the clean cache if available
-	if db.cleans != nil {
-		if enc := db.cleans.Get(nil, hash[:]); enc != nil {
+	cleans := db.cleanCache()
+	if cleans != nil {
+		if enc := cleans.Get(nil, hash[:]); enc != nil {
 			memcacheCleanHitMeter.Mark(1)
 			memcacheCleanReadMeter.Mark(int64(len(enc)))
 			return mustDecodeNode(hash[:], enc)
@@ -395,8 +435,8 @@ func (db *Database) node(hash common.Hash) node {
 	if err != nil || enc == nil {
 		return nil
 	}
-	if db.cleans != nil {
-		db.cleans.Set(hash[:], enc)
+	if cleans != nil {
+		cleans.Set(hash[:], enc)
 		memcacheCleanMissMeter.Mark(1)
 		memcacheCleanWriteMeter.Mark(int64(len(enc)))
 	}
@@ -411,8 +451,9 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 		return nil, errors.New("not found")
 	}
 	// Retrieve the node from the clean cache if available
-	if db.cleans != nil {
-		if enc := db.cleans.Get(nil, hash[:]); enc != nil {
+	cleans := db.cleanCache()
+	if cleans != nil {
+		if enc := cleans.Get(nil, hash[:]); enc != nil {
 			memcacheCleanHitMeter.Mark(1)
 			memcacheCleanReadMeter.Mark(int64(len(enc)))
 			return enc, nil
@@ -433,8 +474,8 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 	// Content unavailable in memory, attempt to retrieve from disk
 	enc := rawdb.ReadTrieNode(db.diskdb, hash)
 	if len(enc) != 0 {
-		if db.cleans != nil {
-			db.cleans.Set(hash[:], enc)
+		if cleans != nil {
+			cleans.Set(hash[:], enc)
 			memcacheCleanMissMeter.Mark(1)
 			memcacheCleanWriteMeter.Mark(int64(len(enc)))
 		}
@@ -823,8 +864,8 @@ func (c *cleaner) Put(key []byte, rlp []byte) error {
 		c.db.dirtiesSize -= common.StorageSize(cachedNodeChildrenSize + len(node.children)*(common.HashLength+2))
 	}
 	// Move the flushed node into the clean cache to prevent insta-reloads
-	if c.db.cleans != nil {
-		c.db.cleans.Set(hash[:], rlp)
+	if cleans := c.db.cleanCache(); cleans != nil {
+		cleans.Set(hash[:], rlp)
 		memcacheCleanWriteMeter.Mark(int64(len(rlp)))
 	}
 	return nil
@@ -851,13 +892,19 @@ func (db *Database) Size() (common.StorageSize, common.StorageSize) {
 // saveCache saves clean state cache to given directory path
 // using specified CPU cores.
 func (db *Database) saveCache(dir string, threads int) error {
-	if db.cleans == nil {
+	cleans := db.cleanCache()
+	if cleans == nil {
+		return nil
+	}
+	// Don't overwrite the journal with the partial cache used while loading it
+	if db.Warming() {
+		log.Warn("Skipping clean trie cache persistence, journal still loading", "path", dir)
 		return nil
 	}
 	log.Info("Writing clean trie cache to disk", "path", dir, "threads", threads)
 
 	start := time.Now()
-	err := db.cleans.SaveToFileConcurrent(dir, threads)
+	err := cleans.SaveToFileConcurrent(dir, threads)
 	if err != nil {
 		log.Error("Failed to persist clean trie cache", "error", err)
 		return err
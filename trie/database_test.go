@@ -17,7 +17,11 @@
 package trie
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/spruce-solutions/go-quai/common"
 	"github.com/spruce-solutions/go-quai/ethdb/memorydb"
@@ -31,3 +35,37 @@ func TestDatabaseMetarootFetch(t *testing.T) {
 		t.Fatalf("metaroot retrieval succeeded")
 	}
 }
+
+// Tests that the clean cache journal is loaded in the background and swapped in
+// once complete.
+func TestDatabaseCacheWarmup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trie-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	journal := filepath.Join(dir, "triecache")
+
+	diskdb := memorydb.New()
+	hash, blob := common.HexToHash("0x01"), []byte{0xc0}
+	diskdb.Put(hash[:], blob)
+
+	db := NewDatabaseWithConfig(diskdb, &Config{Cache: 1})
+	if _, err := db.Node(hash); err != nil {
+		t.Fatalf("failed to retrieve node: %v", err)
+	}
+	if err := db.SaveCache(journal); err != nil {
+		t.Fatalf("failed to save cache: %v", err)
+	}
+	diskdb.Delete(hash[:])
+
+	db = NewDatabaseWithConfig(diskdb, &Config{Cache: 1, Journal: journal})
+	for deadline := time.Now().Add(5 * time.Second); db.Warming(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("clean cache journal not loaded")
+		}
+	}
+	if enc, err := db.Node(hash); err != nil || string(enc) != string(blob) {
+		t.Fatalf("warmed up node mismatch: have %x, %v, want %x", enc, err, blob)
+	}
+}